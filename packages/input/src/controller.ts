@@ -479,6 +479,7 @@ export class Controller {
       const detachMsg = createMessage(
         create(ProtoControllerDetachSchema, {
           sessionSlot: this.gamepad.index,
+          sessionId: this.wrtc.getSessionID(),
         }),
         "controllerInput",
       );
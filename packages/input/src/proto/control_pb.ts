@@ -0,0 +1,412 @@
+// @generated by protoc-gen-es v2.10.1 with parameter "target=ts"
+// @generated from file control.proto (package proto, syntax proto3)
+/* eslint-disable */
+
+import type { GenFile, GenMessage, GenService } from "@bufbuild/protobuf/codegenv2";
+import { fileDesc, messageDesc, serviceDesc } from "@bufbuild/protobuf/codegenv2";
+import type { ProtoRoomSettings, ProtoStreamStats, ProtoStreamStatsSchema } from "./types_pb";
+import { file_types } from "./types_pb";
+import type { Message } from "@bufbuild/protobuf";
+/**
+ * Describes the file control.proto.
+ */
+export const file_control: GenFile = /*@__PURE__*/
+  fileDesc("Cg1jb250cm9sLnByb3RvEgVwcm90byIOCgxDb250cm9sRW1wdHkidQoSQ29udHJvbFBhcnRpY2lwYW50EgoKAmlkGAEgASgJEhIKCnNlc3Npb25faWQYAiABKAkSDwoHcGVlcl9pZBgDIAEoCRIWCg5xdWV1ZV9kZWxheV9tcxgEIAEoDRIWCg5kcm9wcGVkX2ZyYW1lcxgFIAEoBCKMAgoLQ29udHJvbFJvb20SCgoCaWQYASABKAkSDAoEbmFtZRgCIAEoCRIQCghvd25lcl9pZBgDIAEoCRIqCghzZXR0aW5ncxgEIAEoCzIYLnByb3RvLlByb3RvUm9vbVNldHRpbmdzEg8KB3ZpZXdlcnMYBSABKAUSDgoGb25saW5lGAYgASgIEhMKC3Vwc3RyZWFtX2lkGAcgASgJEhYKDmhvcF9sYXRlbmN5X21zGAggASgNEiYKBXN0YXRzGAkgASgLMhcucHJvdG8uUHJvdG9TdHJlYW1TdGF0cxIvCgxwYXJ0aWNpcGFudHMYCiADKAsyGS5wcm90by5Db250cm9sUGFydGljaXBhbnQiGQoXQ29udHJvbExpc3RSb29tc1JlcXVlc3QiPQoYQ29udHJvbExpc3RSb29tc1Jlc3BvbnNlEiEKBXJvb21zGAEgAygLMhIucHJvdG8uQ29udHJvbFJvb20iJwoSQ29udHJvbFJvb21SZXF1ZXN0EhEKCXJvb21fbmFtZRgBIAEoCSJGChlDb250cm9sUGFydGljaXBhbnRSZXF1ZXN0EhEKCXJvb21fbmFtZRgBIAEoCRIWCg5wYXJ0aWNpcGFudF9pZBgCIAEoCSJ+CgtDb250cm9sUGVlchIKCgJpZBgBIAEoCRINCgVhZGRycxgCIAMoCRIRCgljb25uZWN0ZWQYAyABKAgSEgoKbGF0ZW5jeV9tcxgEIAEoDRIWCg5sYXN0X3NlZW5fdW5peBgFIAEoAxIVCg1kaWFsX2ZhaWx1cmVzGAYgASgFIhkKF0NvbnRyb2xMaXN0UGVlcnNSZXF1ZXN0Ij0KGENvbnRyb2xMaXN0UGVlcnNSZXNwb25zZRIhCgVwZWVycxgBIAMoCzISLnByb3RvLkNvbnRyb2xQZWVyIiUKEkNvbnRyb2xQZWVyUmVxdWVzdBIPCgdwZWVyX2lkGAEgASgJIkIKGENvbnRyb2xXYXRjaFN0YXRzUmVxdWVzdBIRCglyb29tX25hbWUYASABKAkSEwoLaW50ZXJ2YWxfbXMYAiABKA0y9wMKDFJlbGF5Q29udHJvbBJMCglMaXN0Um9vbXMSHi5wcm90by5Db250cm9sTGlzdFJvb21zUmVxdWVzdBofLnByb3RvLkNvbnRyb2xMaXN0Um9vbXNSZXNwb25zZRI4CgdHZXRSb29tEhkucHJvdG8uQ29udHJvbFJvb21SZXF1ZXN0GhIucHJvdG8uQ29udHJvbFJvb20SOwoJQ2xvc2VSb29tEhkucHJvdG8uQ29udHJvbFJvb21SZXF1ZXN0GhMucHJvdG8uQ29udHJvbEVtcHR5EkgKD0tpY2tQYXJ0aWNpcGFudBIgLnByb3RvLkNvbnRyb2xQYXJ0aWNpcGFudFJlcXVlc3QaEy5wcm90by5Db250cm9sRW1wdHkSTAoJTGlzdFBlZXJzEh4ucHJvdG8uQ29udHJvbExpc3RQZWVyc1JlcXVlc3QaHy5wcm90by5Db250cm9sTGlzdFBlZXJzUmVzcG9uc2USQAoORGlzY29ubmVjdFBlZXISGS5wcm90by5Db250cm9sUGVlclJlcXVlc3QaEy5wcm90by5Db250cm9sRW1wdHkSSAoKV2F0Y2hTdGF0cxIfLnByb3RvLkNvbnRyb2xXYXRjaFN0YXRzUmVxdWVzdBoXLnByb3RvLlByb3RvU3RyZWFtU3RhdHMwAUIWWhRyZWxheS9pbnRlcm5hbC9wcm90b2IGcHJvdG8z", [file_types]);
+
+/**
+ * ControlEmpty message
+ *
+ * @generated from message proto.ControlEmpty
+ */
+export type ControlEmpty = Message<"proto.ControlEmpty"> & {
+};
+
+/**
+ * Describes the message proto.ControlEmpty.
+ * Use `create(ControlEmptySchema)` to create a new message.
+ */
+export const ControlEmptySchema: GenMessage<ControlEmpty> = /*@__PURE__*/
+  messageDesc(file_control, 0);
+
+/**
+ * ControlParticipant message
+ *
+ * @generated from message proto.ControlParticipant
+ */
+export type ControlParticipant = Message<"proto.ControlParticipant"> & {
+  /**
+   * @generated from field: string id = 1;
+   */
+  id: string;
+
+  /**
+   * @generated from field: string session_id = 2;
+   */
+  sessionId: string;
+
+  /**
+   * @generated from field: string peer_id = 3;
+   */
+  peerId: string;
+
+  /**
+   * @generated from field: uint32 queue_delay_ms = 4;
+   */
+  queueDelayMs: number;
+
+  /**
+   * @generated from field: uint64 dropped_frames = 5;
+   */
+  droppedFrames: bigint;
+};
+
+/**
+ * Describes the message proto.ControlParticipant.
+ * Use `create(ControlParticipantSchema)` to create a new message.
+ */
+export const ControlParticipantSchema: GenMessage<ControlParticipant> = /*@__PURE__*/
+  messageDesc(file_control, 1);
+
+/**
+ * ControlRoom message
+ *
+ * @generated from message proto.ControlRoom
+ */
+export type ControlRoom = Message<"proto.ControlRoom"> & {
+  /**
+   * @generated from field: string id = 1;
+   */
+  id: string;
+
+  /**
+   * @generated from field: string name = 2;
+   */
+  name: string;
+
+  /**
+   * @generated from field: string owner_id = 3;
+   */
+  ownerId: string;
+
+  /**
+   * @generated from field: proto.ProtoRoomSettings settings = 4;
+   */
+  settings?: ProtoRoomSettings;
+
+  /**
+   * @generated from field: int32 viewers = 5;
+   */
+  viewers: number;
+
+  /**
+   * @generated from field: bool online = 6;
+   */
+  online: boolean;
+
+  /**
+   * Relay the room is pulled from, empty if pushed here
+   *
+   * @generated from field: string upstream_id = 7;
+   */
+  upstreamId: string;
+
+  /**
+   * @generated from field: uint32 hop_latency_ms = 8;
+   */
+  hopLatencyMs: number;
+
+  /**
+   * @generated from field: proto.ProtoStreamStats stats = 9;
+   */
+  stats?: ProtoStreamStats;
+
+  /**
+   * Only set by GetRoom
+   *
+   * @generated from field: repeated proto.ControlParticipant participants = 10;
+   */
+  participants: ControlParticipant[];
+};
+
+/**
+ * Describes the message proto.ControlRoom.
+ * Use `create(ControlRoomSchema)` to create a new message.
+ */
+export const ControlRoomSchema: GenMessage<ControlRoom> = /*@__PURE__*/
+  messageDesc(file_control, 2);
+
+/**
+ * ControlListRoomsRequest message
+ *
+ * @generated from message proto.ControlListRoomsRequest
+ */
+export type ControlListRoomsRequest = Message<"proto.ControlListRoomsRequest"> & {
+};
+
+/**
+ * Describes the message proto.ControlListRoomsRequest.
+ * Use `create(ControlListRoomsRequestSchema)` to create a new message.
+ */
+export const ControlListRoomsRequestSchema: GenMessage<ControlListRoomsRequest> = /*@__PURE__*/
+  messageDesc(file_control, 3);
+
+/**
+ * ControlListRoomsResponse message
+ *
+ * @generated from message proto.ControlListRoomsResponse
+ */
+export type ControlListRoomsResponse = Message<"proto.ControlListRoomsResponse"> & {
+  /**
+   * @generated from field: repeated proto.ControlRoom rooms = 1;
+   */
+  rooms: ControlRoom[];
+};
+
+/**
+ * Describes the message proto.ControlListRoomsResponse.
+ * Use `create(ControlListRoomsResponseSchema)` to create a new message.
+ */
+export const ControlListRoomsResponseSchema: GenMessage<ControlListRoomsResponse> = /*@__PURE__*/
+  messageDesc(file_control, 4);
+
+/**
+ * ControlRoomRequest message
+ *
+ * @generated from message proto.ControlRoomRequest
+ */
+export type ControlRoomRequest = Message<"proto.ControlRoomRequest"> & {
+  /**
+   * @generated from field: string room_name = 1;
+   */
+  roomName: string;
+};
+
+/**
+ * Describes the message proto.ControlRoomRequest.
+ * Use `create(ControlRoomRequestSchema)` to create a new message.
+ */
+export const ControlRoomRequestSchema: GenMessage<ControlRoomRequest> = /*@__PURE__*/
+  messageDesc(file_control, 5);
+
+/**
+ * ControlParticipantRequest message
+ *
+ * @generated from message proto.ControlParticipantRequest
+ */
+export type ControlParticipantRequest = Message<"proto.ControlParticipantRequest"> & {
+  /**
+   * @generated from field: string room_name = 1;
+   */
+  roomName: string;
+
+  /**
+   * @generated from field: string participant_id = 2;
+   */
+  participantId: string;
+};
+
+/**
+ * Describes the message proto.ControlParticipantRequest.
+ * Use `create(ControlParticipantRequestSchema)` to create a new message.
+ */
+export const ControlParticipantRequestSchema: GenMessage<ControlParticipantRequest> = /*@__PURE__*/
+  messageDesc(file_control, 6);
+
+/**
+ * ControlPeer message
+ *
+ * @generated from message proto.ControlPeer
+ */
+export type ControlPeer = Message<"proto.ControlPeer"> & {
+  /**
+   * @generated from field: string id = 1;
+   */
+  id: string;
+
+  /**
+   * @generated from field: repeated string addrs = 2;
+   */
+  addrs: string[];
+
+  /**
+   * @generated from field: bool connected = 3;
+   */
+  connected: boolean;
+
+  /**
+   * @generated from field: uint32 latency_ms = 4;
+   */
+  latencyMs: number;
+
+  /**
+   * @generated from field: int64 last_seen_unix = 5;
+   */
+  lastSeenUnix: bigint;
+
+  /**
+   * @generated from field: int32 dial_failures = 6;
+   */
+  dialFailures: number;
+};
+
+/**
+ * Describes the message proto.ControlPeer.
+ * Use `create(ControlPeerSchema)` to create a new message.
+ */
+export const ControlPeerSchema: GenMessage<ControlPeer> = /*@__PURE__*/
+  messageDesc(file_control, 7);
+
+/**
+ * ControlListPeersRequest message
+ *
+ * @generated from message proto.ControlListPeersRequest
+ */
+export type ControlListPeersRequest = Message<"proto.ControlListPeersRequest"> & {
+};
+
+/**
+ * Describes the message proto.ControlListPeersRequest.
+ * Use `create(ControlListPeersRequestSchema)` to create a new message.
+ */
+export const ControlListPeersRequestSchema: GenMessage<ControlListPeersRequest> = /*@__PURE__*/
+  messageDesc(file_control, 8);
+
+/**
+ * ControlListPeersResponse message
+ *
+ * @generated from message proto.ControlListPeersResponse
+ */
+export type ControlListPeersResponse = Message<"proto.ControlListPeersResponse"> & {
+  /**
+   * @generated from field: repeated proto.ControlPeer peers = 1;
+   */
+  peers: ControlPeer[];
+};
+
+/**
+ * Describes the message proto.ControlListPeersResponse.
+ * Use `create(ControlListPeersResponseSchema)` to create a new message.
+ */
+export const ControlListPeersResponseSchema: GenMessage<ControlListPeersResponse> = /*@__PURE__*/
+  messageDesc(file_control, 9);
+
+/**
+ * ControlPeerRequest message
+ *
+ * @generated from message proto.ControlPeerRequest
+ */
+export type ControlPeerRequest = Message<"proto.ControlPeerRequest"> & {
+  /**
+   * @generated from field: string peer_id = 1;
+   */
+  peerId: string;
+};
+
+/**
+ * Describes the message proto.ControlPeerRequest.
+ * Use `create(ControlPeerRequestSchema)` to create a new message.
+ */
+export const ControlPeerRequestSchema: GenMessage<ControlPeerRequest> = /*@__PURE__*/
+  messageDesc(file_control, 10);
+
+/**
+ * ControlWatchStatsRequest message
+ *
+ * @generated from message proto.ControlWatchStatsRequest
+ */
+export type ControlWatchStatsRequest = Message<"proto.ControlWatchStatsRequest"> & {
+  /**
+   * Empty for all local online rooms
+   *
+   * @generated from field: string room_name = 1;
+   */
+  roomName: string;
+
+  /**
+   * Defaults to stats report interval
+   *
+   * @generated from field: uint32 interval_ms = 2;
+   */
+  intervalMs: number;
+};
+
+/**
+ * Describes the message proto.ControlWatchStatsRequest.
+ * Use `create(ControlWatchStatsRequestSchema)` to create a new message.
+ */
+export const ControlWatchStatsRequestSchema: GenMessage<ControlWatchStatsRequest> = /*@__PURE__*/
+  messageDesc(file_control, 11);
+
+/**
+ * RelayControl is the programmatic relay control service for the control plane
+ *
+ * @generated from service proto.RelayControl
+ */
+export const RelayControl: GenService<{
+  /**
+   * Rooms
+   *
+   * @generated from rpc proto.RelayControl.ListRooms
+   */
+  listRooms: {
+    methodKind: "unary";
+    input: typeof ControlListRoomsRequestSchema;
+    output: typeof ControlListRoomsResponseSchema;
+  },
+  /**
+   * @generated from rpc proto.RelayControl.GetRoom
+   */
+  getRoom: {
+    methodKind: "unary";
+    input: typeof ControlRoomRequestSchema;
+    output: typeof ControlRoomSchema;
+  },
+  /**
+   * @generated from rpc proto.RelayControl.CloseRoom
+   */
+  closeRoom: {
+    methodKind: "unary";
+    input: typeof ControlRoomRequestSchema;
+    output: typeof ControlEmptySchema;
+  },
+  /**
+   * Participants
+   *
+   * @generated from rpc proto.RelayControl.KickParticipant
+   */
+  kickParticipant: {
+    methodKind: "unary";
+    input: typeof ControlParticipantRequestSchema;
+    output: typeof ControlEmptySchema;
+  },
+  /**
+   * Mesh peers
+   *
+   * @generated from rpc proto.RelayControl.ListPeers
+   */
+  listPeers: {
+    methodKind: "unary";
+    input: typeof ControlListPeersRequestSchema;
+    output: typeof ControlListPeersResponseSchema;
+  },
+  /**
+   * @generated from rpc proto.RelayControl.DisconnectPeer
+   */
+  disconnectPeer: {
+    methodKind: "unary";
+    input: typeof ControlPeerRequestSchema;
+    output: typeof ControlEmptySchema;
+  },
+  /**
+   * Live stats, streamed at interval until cancelled
+   *
+   * @generated from rpc proto.RelayControl.WatchStats
+   */
+  watchStats: {
+    methodKind: "server_streaming";
+    input: typeof ControlWatchStatsRequestSchema;
+    output: typeof ProtoStreamStatsSchema;
+  },
+}> = /*@__PURE__*/
+  serviceDesc(file_control, 0);
+
@@ -4,7 +4,7 @@
 
 import type { GenFile, GenMessage } from "@bufbuild/protobuf/codegenv2";
 import { fileDesc, messageDesc } from "@bufbuild/protobuf/codegenv2";
-import type { ProtoClientDisconnected, ProtoClientRequestRoomStream, ProtoControllerAttach, ProtoControllerDetach, ProtoControllerRumble, ProtoControllerStateBatch, ProtoICE, ProtoKeyDown, ProtoKeyUp, ProtoMouseKeyDown, ProtoMouseKeyUp, ProtoMouseMove, ProtoMouseMoveAbs, ProtoMouseWheel, ProtoRaw, ProtoSDP, ProtoServerPushStream } from "./types_pb";
+import type { ProtoAudioLevel, ProtoBetterRelay, ProtoChatMessage, ProtoClientDisconnected, ProtoClientRequestRoomStream, ProtoControllerAttach, ProtoControllerDetach, ProtoControllerRumble, ProtoControllerStateBatch, ProtoDirectoryQuery, ProtoDirectoryResult, ProtoICE, ProtoInputLatency, ProtoInvalidMessage, ProtoKeyDown, ProtoKeyUp, ProtoMeshRoomTracks, ProtoModeration, ProtoMouseKeyDown, ProtoMouseKeyUp, ProtoMouseMove, ProtoMouseMoveAbs, ProtoMouseWheel, ProtoPushChallenge, ProtoPushChallengeResponse, ProtoQuotaExceeded, ProtoRaw, ProtoRelayNotice, ProtoRelayOverload, ProtoRoomFull, ProtoRoomMetadata, ProtoRoomVariants, ProtoSDP, ProtoServerPushStream, ProtoSignalingProgress, ProtoStreamClock, ProtoStreamPathInfo, ProtoStreamStats, ProtoThrottled, ProtoVariantSwitch, ProtoViewerCount } from "./types_pb";
 import { file_types } from "./types_pb";
 import type { ProtoLatencyTracker } from "./latency_tracker_pb";
 import { file_latency_tracker } from "./latency_tracker_pb";
@@ -14,7 +14,7 @@ import type { Message } from "@bufbuild/protobuf";
  * Describes the file messages.proto.
  */
 export const file_messages: GenFile = /*@__PURE__*/
-  fileDesc("Cg5tZXNzYWdlcy5wcm90bxIFcHJvdG8iVQoQUHJvdG9NZXNzYWdlQmFzZRIUCgxwYXlsb2FkX3R5cGUYASABKAkSKwoHbGF0ZW5jeRgCIAEoCzIaLnByb3RvLlByb3RvTGF0ZW5jeVRyYWNrZXIipQcKDFByb3RvTWVzc2FnZRItCgxtZXNzYWdlX2Jhc2UYASABKAsyFy5wcm90by5Qcm90b01lc3NhZ2VCYXNlEisKCm1vdXNlX21vdmUYAiABKAsyFS5wcm90by5Qcm90b01vdXNlTW92ZUgAEjIKDm1vdXNlX21vdmVfYWJzGAMgASgLMhgucHJvdG8uUHJvdG9Nb3VzZU1vdmVBYnNIABItCgttb3VzZV93aGVlbBgEIAEoCzIWLnByb3RvLlByb3RvTW91c2VXaGVlbEgAEjIKDm1vdXNlX2tleV9kb3duGAUgASgLMhgucHJvdG8uUHJvdG9Nb3VzZUtleURvd25IABIuCgxtb3VzZV9rZXlfdXAYBiABKAsyFi5wcm90by5Qcm90b01vdXNlS2V5VXBIABInCghrZXlfZG93bhgHIAEoCzITLnByb3RvLlByb3RvS2V5RG93bkgAEiMKBmtleV91cBgIIAEoCzIRLnByb3RvLlByb3RvS2V5VXBIABI5ChFjb250cm9sbGVyX2F0dGFjaBgJIAEoCzIcLnByb3RvLlByb3RvQ29udHJvbGxlckF0dGFjaEgAEjkKEWNvbnRyb2xsZXJfZGV0YWNoGAogASgLMhwucHJvdG8uUHJvdG9Db250cm9sbGVyRGV0YWNoSAASOQoRY29udHJvbGxlcl9ydW1ibGUYCyABKAsyHC5wcm90by5Qcm90b0NvbnRyb2xsZXJSdW1ibGVIABJCChZjb250cm9sbGVyX3N0YXRlX2JhdGNoGAwgASgLMiAucHJvdG8uUHJvdG9Db250cm9sbGVyU3RhdGVCYXRjaEgAEh4KA2ljZRgUIAEoCzIPLnByb3RvLlByb3RvSUNFSAASHgoDc2RwGBUgASgLMg8ucHJvdG8uUHJvdG9TRFBIABIeCgNyYXcYFiABKAsyDy5wcm90by5Qcm90b1Jhd0gAEkkKGmNsaWVudF9yZXF1ZXN0X3Jvb21fc3RyZWFtGBcgASgLMiMucHJvdG8uUHJvdG9DbGllbnRSZXF1ZXN0Um9vbVN0cmVhbUgAEj0KE2NsaWVudF9kaXNjb25uZWN0ZWQYGCABKAsyHi5wcm90by5Qcm90b0NsaWVudERpc2Nvbm5lY3RlZEgAEjoKEnNlcnZlcl9wdXNoX3N0cmVhbRgZIAEoCzIcLnByb3RvLlByb3RvU2VydmVyUHVzaFN0cmVhbUgAQgkKB3BheWxvYWRCFloUcmVsYXkvaW50ZXJuYWwvcHJvdG9iBnByb3RvMw", [file_types, file_latency_tracker]);
+  fileDesc("Cg5tZXNzYWdlcy5wcm90bxIFcHJvdG8irQEKEFByb3RvTWVzc2FnZUJhc2USFAoMcGF5bG9hZF90eXBlGAEgASgJEisKB2xhdGVuY3kYAiABKAsyGi5wcm90by5Qcm90b0xhdGVuY3lUcmFja2VyEhgKEHByb3RvY29sX3ZlcnNpb24YAyABKA0SEAoIc2VxdWVuY2UYBCABKAQSFAoMc3RyZWFtX25vbmNlGAUgASgEEhQKDGNhcGFiaWxpdGllcxgGIAMoCSL+EAoMUHJvdG9NZXNzYWdlEi0KDG1lc3NhZ2VfYmFzZRgBIAEoCzIXLnByb3RvLlByb3RvTWVzc2FnZUJhc2USKwoKbW91c2VfbW92ZRgCIAEoCzIVLnByb3RvLlByb3RvTW91c2VNb3ZlSAASMgoObW91c2VfbW92ZV9hYnMYAyABKAsyGC5wcm90by5Qcm90b01vdXNlTW92ZUFic0gAEi0KC21vdXNlX3doZWVsGAQgASgLMhYucHJvdG8uUHJvdG9Nb3VzZVdoZWVsSAASMgoObW91c2Vfa2V5X2Rvd24YBSABKAsyGC5wcm90by5Qcm90b01vdXNlS2V5RG93bkgAEi4KDG1vdXNlX2tleV91cBgGIAEoCzIWLnByb3RvLlByb3RvTW91c2VLZXlVcEgAEicKCGtleV9kb3duGAcgASgLMhMucHJvdG8uUHJvdG9LZXlEb3duSAASIwoGa2V5X3VwGAggASgLMhEucHJvdG8uUHJvdG9LZXlVcEgAEjkKEWNvbnRyb2xsZXJfYXR0YWNoGAkgASgLMhwucHJvdG8uUHJvdG9Db250cm9sbGVyQXR0YWNoSAASOQoRY29udHJvbGxlcl9kZXRhY2gYCiABKAsyHC5wcm90by5Qcm90b0NvbnRyb2xsZXJEZXRhY2hIABI5ChFjb250cm9sbGVyX3J1bWJsZRgLIAEoCzIcLnByb3RvLlByb3RvQ29udHJvbGxlclJ1bWJsZUgAEkIKFmNvbnRyb2xsZXJfc3RhdGVfYmF0Y2gYDCABKAsyIC5wcm90by5Qcm90b0NvbnRyb2xsZXJTdGF0ZUJhdGNoSAASHgoDaWNlGBQgASgLMg8ucHJvdG8uUHJvdG9JQ0VIABIeCgNzZHAYFSABKAsyDy5wcm90by5Qcm90b1NEUEgAEh4KA3JhdxgWIAEoCzIPLnByb3RvLlByb3RvUmF3SAASSQoaY2xpZW50X3JlcXVlc3Rfcm9vbV9zdHJlYW0YFyABKAsyIy5wcm90by5Qcm90b0NsaWVudFJlcXVlc3RSb29tU3RyZWFtSAASPQoTY2xpZW50X2Rpc2Nvbm5lY3RlZBgYIAEoCzIeLnByb3RvLlByb3RvQ2xpZW50RGlzY29ubmVjdGVkSAASOgoSc2VydmVyX3B1c2hfc3RyZWFtGBkgASgLMhwucHJvdG8uUHJvdG9TZXJ2ZXJQdXNoU3RyZWFtSAASNQoPZGlyZWN0b3J5X3F1ZXJ5GBogASgLMhoucHJvdG8uUHJvdG9EaXJlY3RvcnlRdWVyeUgAEjcKEGRpcmVjdG9yeV9yZXN1bHQYGyABKAsyGy5wcm90by5Qcm90b0RpcmVjdG9yeVJlc3VsdEgAEjYKEHN0cmVhbV9wYXRoX2luZm8YHCABKAsyGi5wcm90by5Qcm90b1N0cmVhbVBhdGhJbmZvSAASLwoMc3RyZWFtX3N0YXRzGB0gASgLMhcucHJvdG8uUHJvdG9TdHJlYW1TdGF0c0gAEi8KDHJlbGF5X25vdGljZRgeIAEoCzIXLnByb3RvLlByb3RvUmVsYXlOb3RpY2VIABI7ChJzaWduYWxpbmdfcHJvZ3Jlc3MYHyABKAsyHS5wcm90by5Qcm90b1NpZ25hbGluZ1Byb2dyZXNzSAASNgoQbWVzaF9yb29tX3RyYWNrcxggIAEoCzIaLnByb3RvLlByb3RvTWVzaFJvb21UcmFja3NIABIpCglyb29tX2Z1bGwYISABKAsyFC5wcm90by5Qcm90b1Jvb21GdWxsSAASLAoKbW9kZXJhdGlvbhgiIAEoCzIWLnByb3RvLlByb3RvTW9kZXJhdGlvbkgAEicKBGNoYXQYIyABKAsyFy5wcm90by5Qcm90b0NoYXRNZXNzYWdlSAASMQoNcm9vbV9tZXRhZGF0YRgkIAEoCzIYLnByb3RvLlByb3RvUm9vbU1ldGFkYXRhSAASMQoNcm9vbV92YXJpYW50cxglIAEoCzIYLnByb3RvLlByb3RvUm9vbVZhcmlhbnRzSAASMwoOdmFyaWFudF9zd2l0Y2gYJiABKAsyGS5wcm90by5Qcm90b1ZhcmlhbnRTd2l0Y2hIABIvCgx2aWV3ZXJfY291bnQYJyABKAsyFy5wcm90by5Qcm90b1ZpZXdlckNvdW50SAASKgoJdGhyb3R0bGVkGCggASgLMhUucHJvdG8uUHJvdG9UaHJvdHRsZWRIABIzCg5xdW90YV9leGNlZWRlZBgpIAEoCzIZLnByb3RvLlByb3RvUXVvdGFFeGNlZWRlZEgAEjMKDnJlbGF5X292ZXJsb2FkGCogASgLMhkucHJvdG8uUHJvdG9SZWxheU92ZXJsb2FkSAASNQoPaW52YWxpZF9tZXNzYWdlGCsgASgLMhoucHJvdG8uUHJvdG9JbnZhbGlkTWVzc2FnZUgAEjMKDnB1c2hfY2hhbGxlbmdlGCwgASgLMhkucHJvdG8uUHJvdG9QdXNoQ2hhbGxlbmdlSAASRAoXcHVzaF9jaGFsbGVuZ2VfcmVzcG9uc2UYLSABKAsyIS5wcm90by5Qcm90b1B1c2hDaGFsbGVuZ2VSZXNwb25zZUgAEi0KC2F1ZGlvX2xldmVsGC4gASgLMhYucHJvdG8uUHJvdG9BdWRpb0xldmVsSAASLwoMYmV0dGVyX3JlbGF5GC8gASgLMhcucHJvdG8uUHJvdG9CZXR0ZXJSZWxheUgAEi8KDHN0cmVhbV9jbG9jaxgwIAEoCzIXLnByb3RvLlByb3RvU3RyZWFtQ2xvY2tIABIxCg1pbnB1dF9sYXRlbmN5GDEgASgLMhgucHJvdG8uUHJvdG9JbnB1dExhdGVuY3lIAEIJCgdwYXlsb2FkQhZaFHJlbGF5L2ludGVybmFsL3Byb3RvYgZwcm90bzM", [file_types, file_latency_tracker]);
 
 /**
  * @generated from message proto.ProtoMessageBase
@@ -29,6 +29,34 @@ export type ProtoMessageBase = Message<"proto.ProtoMessageBase"> & {
    * @generated from field: proto.ProtoLatencyTracker latency = 2;
    */
   latency?: ProtoLatencyTracker;
+
+  /**
+   * Message protocol version of the sender, 0 if not announced
+   *
+   * @generated from field: uint32 protocol_version = 3;
+   */
+  protocolVersion: number;
+
+  /**
+   * Increases with each message the sender writes to a stream, starting at 1, 0 if not sequenced
+   *
+   * @generated from field: uint64 sequence = 4;
+   */
+  sequence: bigint;
+
+  /**
+   * Random per stream of the sender, sequenced messages carrying another nonce were replayed
+   *
+   * @generated from field: uint64 stream_nonce = 5;
+   */
+  streamNonce: bigint;
+
+  /**
+   * Optional features the sender supports, announced with its first message on a stream
+   *
+   * @generated from field: repeated string capabilities = 6;
+   */
+  capabilities: string[];
 };
 
 /**
@@ -160,6 +188,192 @@ export type ProtoMessage = Message<"proto.ProtoMessage"> & {
      */
     value: ProtoServerPushStream;
     case: "serverPushStream";
+  } | {
+    /**
+     * Directory types
+     *
+     * @generated from field: proto.ProtoDirectoryQuery directory_query = 26;
+     */
+    value: ProtoDirectoryQuery;
+    case: "directoryQuery";
+  } | {
+    /**
+     * @generated from field: proto.ProtoDirectoryResult directory_result = 27;
+     */
+    value: ProtoDirectoryResult;
+    case: "directoryResult";
+  } | {
+    /**
+     * Mesh path types
+     *
+     * @generated from field: proto.ProtoStreamPathInfo stream_path_info = 28;
+     */
+    value: ProtoStreamPathInfo;
+    case: "streamPathInfo";
+  } | {
+    /**
+     * Stats types
+     *
+     * @generated from field: proto.ProtoStreamStats stream_stats = 29;
+     */
+    value: ProtoStreamStats;
+    case: "streamStats";
+  } | {
+    /**
+     * Relay notices
+     *
+     * @generated from field: proto.ProtoRelayNotice relay_notice = 30;
+     */
+    value: ProtoRelayNotice;
+    case: "relayNotice";
+  } | {
+    /**
+     * Signaling progress
+     *
+     * @generated from field: proto.ProtoSignalingProgress signaling_progress = 31;
+     */
+    value: ProtoSignalingProgress;
+    case: "signalingProgress";
+  } | {
+    /**
+     * Mesh link types
+     *
+     * @generated from field: proto.ProtoMeshRoomTracks mesh_room_tracks = 32;
+     */
+    value: ProtoMeshRoomTracks;
+    case: "meshRoomTracks";
+  } | {
+    /**
+     * Room admission
+     *
+     * @generated from field: proto.ProtoRoomFull room_full = 33;
+     */
+    value: ProtoRoomFull;
+    case: "roomFull";
+  } | {
+    /**
+     * Moderation
+     *
+     * @generated from field: proto.ProtoModeration moderation = 34;
+     */
+    value: ProtoModeration;
+    case: "moderation";
+  } | {
+    /**
+     * Room chat
+     *
+     * @generated from field: proto.ProtoChatMessage chat = 35;
+     */
+    value: ProtoChatMessage;
+    case: "chat";
+  } | {
+    /**
+     * Room metadata updates
+     *
+     * @generated from field: proto.ProtoRoomMetadata room_metadata = 36;
+     */
+    value: ProtoRoomMetadata;
+    case: "roomMetadata";
+  } | {
+    /**
+     * Quality variants
+     *
+     * @generated from field: proto.ProtoRoomVariants room_variants = 37;
+     */
+    value: ProtoRoomVariants;
+    case: "roomVariants";
+  } | {
+    /**
+     * @generated from field: proto.ProtoVariantSwitch variant_switch = 38;
+     */
+    value: ProtoVariantSwitch;
+    case: "variantSwitch";
+  } | {
+    /**
+     * Viewer counts
+     *
+     * @generated from field: proto.ProtoViewerCount viewer_count = 39;
+     */
+    value: ProtoViewerCount;
+    case: "viewerCount";
+  } | {
+    /**
+     * Rate limiting
+     *
+     * @generated from field: proto.ProtoThrottled throttled = 40;
+     */
+    value: ProtoThrottled;
+    case: "throttled";
+  } | {
+    /**
+     * Bandwidth quotas
+     *
+     * @generated from field: proto.ProtoQuotaExceeded quota_exceeded = 41;
+     */
+    value: ProtoQuotaExceeded;
+    case: "quotaExceeded";
+  } | {
+    /**
+     * Backpressure
+     *
+     * @generated from field: proto.ProtoRelayOverload relay_overload = 42;
+     */
+    value: ProtoRelayOverload;
+    case: "relayOverload";
+  } | {
+    /**
+     * Validation
+     *
+     * @generated from field: proto.ProtoInvalidMessage invalid_message = 43;
+     */
+    value: ProtoInvalidMessage;
+    case: "invalidMessage";
+  } | {
+    /**
+     * Push authentication
+     *
+     * @generated from field: proto.ProtoPushChallenge push_challenge = 44;
+     */
+    value: ProtoPushChallenge;
+    case: "pushChallenge";
+  } | {
+    /**
+     * @generated from field: proto.ProtoPushChallengeResponse push_challenge_response = 45;
+     */
+    value: ProtoPushChallengeResponse;
+    case: "pushChallengeResponse";
+  } | {
+    /**
+     * Audio levels
+     *
+     * @generated from field: proto.ProtoAudioLevel audio_level = 46;
+     */
+    value: ProtoAudioLevel;
+    case: "audioLevel";
+  } | {
+    /**
+     * Viewer steering
+     *
+     * @generated from field: proto.ProtoBetterRelay better_relay = 47;
+     */
+    value: ProtoBetterRelay;
+    case: "betterRelay";
+  } | {
+    /**
+     * Playback clock
+     *
+     * @generated from field: proto.ProtoStreamClock stream_clock = 48;
+     */
+    value: ProtoStreamClock;
+    case: "streamClock";
+  } | {
+    /**
+     * Input latency
+     *
+     * @generated from field: proto.ProtoInputLatency input_latency = 49;
+     */
+    value: ProtoInputLatency;
+    case: "inputLatency";
   } | { case: undefined; value?: undefined };
 };
 
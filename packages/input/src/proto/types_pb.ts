@@ -10,7 +10,7 @@ import type { Message } from "@bufbuild/protobuf";
  * Describes the file types.proto.
  */
 export const file_types: GenFile = /*@__PURE__*/
-  fileDesc("Cgt0eXBlcy5wcm90bxIFcHJvdG8iJgoOUHJvdG9Nb3VzZU1vdmUSCQoBeBgBIAEoBRIJCgF5GAIgASgFIikKEVByb3RvTW91c2VNb3ZlQWJzEgkKAXgYASABKAUSCQoBeRgCIAEoBSInCg9Qcm90b01vdXNlV2hlZWwSCQoBeBgBIAEoBRIJCgF5GAIgASgFIiAKEVByb3RvTW91c2VLZXlEb3duEgsKA2tleRgBIAEoBSIeCg9Qcm90b01vdXNlS2V5VXASCwoDa2V5GAEgASgFIhsKDFByb3RvS2V5RG93bhILCgNrZXkYASABKAUiGQoKUHJvdG9LZXlVcBILCgNrZXkYASABKAUiTQoVUHJvdG9Db250cm9sbGVyQXR0YWNoEgoKAmlkGAEgASgJEhQKDHNlc3Npb25fc2xvdBgCIAEoBRISCgpzZXNzaW9uX2lkGAMgASgJIkEKFVByb3RvQ29udHJvbGxlckRldGFjaBIUCgxzZXNzaW9uX3Nsb3QYASABKAUSEgoKc2Vzc2lvbl9pZBgCIAEoCSKCAQoVUHJvdG9Db250cm9sbGVyUnVtYmxlEhQKDHNlc3Npb25fc2xvdBgBIAEoBRISCgpzZXNzaW9uX2lkGAIgASgJEhUKDWxvd19mcmVxdWVuY3kYAyABKAUSFgoOaGlnaF9mcmVxdWVuY3kYBCABKAUSEAoIZHVyYXRpb24YBSABKAUi0AUKGVByb3RvQ29udHJvbGxlclN0YXRlQmF0Y2gSFAoMc2Vzc2lvbl9zbG90GAEgASgFEhIKCnNlc3Npb25faWQYAiABKAkSQAoLdXBkYXRlX3R5cGUYAyABKA4yKy5wcm90by5Qcm90b0NvbnRyb2xsZXJTdGF0ZUJhdGNoLlVwZGF0ZVR5cGUSEAoIc2VxdWVuY2UYBCABKA0SVAoTYnV0dG9uX2NoYW5nZWRfbWFzaxgFIAMoCzI3LnByb3RvLlByb3RvQ29udHJvbGxlclN0YXRlQmF0Y2guQnV0dG9uQ2hhbmdlZE1hc2tFbnRyeRIZCgxsZWZ0X3N0aWNrX3gYBiABKAVIAIgBARIZCgxsZWZ0X3N0aWNrX3kYByABKAVIAYgBARIaCg1yaWdodF9zdGlja194GAggASgFSAKIAQESGgoNcmlnaHRfc3RpY2tfeRgJIAEoBUgDiAEBEhkKDGxlZnRfdHJpZ2dlchgKIAEoBUgEiAEBEhoKDXJpZ2h0X3RyaWdnZXIYCyABKAVIBYgBARITCgZkcGFkX3gYDCABKAVIBogBARITCgZkcGFkX3kYDSABKAVIB4gBARIbCg5jaGFuZ2VkX2ZpZWxkcxgOIAEoDUgIiAEBGjgKFkJ1dHRvbkNoYW5nZWRNYXNrRW50cnkSCwoDa2V5GAEgASgFEg0KBXZhbHVlGAIgASgIOgI4ASInCgpVcGRhdGVUeXBlEg4KCkZVTExfU1RBVEUQABIJCgVERUxUQRABQg8KDV9sZWZ0X3N0aWNrX3hCDwoNX2xlZnRfc3RpY2tfeUIQCg5fcmlnaHRfc3RpY2tfeEIQCg5fcmlnaHRfc3RpY2tfeUIPCg1fbGVmdF90cmlnZ2VyQhAKDl9yaWdodF90cmlnZ2VyQgkKB19kcGFkX3hCCQoHX2RwYWRfeUIRCg9fY2hhbmdlZF9maWVsZHMiqgEKE1JUQ0ljZUNhbmRpZGF0ZUluaXQSEQoJY2FuZGlkYXRlGAEgASgJEhoKDXNkcE1MaW5lSW5kZXgYAiABKA1IAIgBARITCgZzZHBNaWQYAyABKAlIAYgBARIdChB1c2VybmFtZUZyYWdtZW50GAQgASgJSAKIAQFCEAoOX3NkcE1MaW5lSW5kZXhCCQoHX3NkcE1pZEITChFfdXNlcm5hbWVGcmFnbWVudCI2ChlSVENTZXNzaW9uRGVzY3JpcHRpb25Jbml0EgsKA3NkcBgBIAEoCRIMCgR0eXBlGAIgASgJIjkKCFByb3RvSUNFEi0KCWNhbmRpZGF0ZRgBIAEoCzIaLnByb3RvLlJUQ0ljZUNhbmRpZGF0ZUluaXQiOQoIUHJvdG9TRFASLQoDc2RwGAEgASgLMiAucHJvdG8uUlRDU2Vzc2lvbkRlc2NyaXB0aW9uSW5pdCIYCghQcm90b1JhdxIMCgRkYXRhGAEgASgJIkUKHFByb3RvQ2xpZW50UmVxdWVzdFJvb21TdHJlYW0SEQoJcm9vbV9uYW1lGAEgASgJEhIKCnNlc3Npb25faWQYAiABKAkiRwoXUHJvdG9DbGllbnREaXNjb25uZWN0ZWQSEgoKc2Vzc2lvbl9pZBgBIAEoCRIYChBjb250cm9sbGVyX3Nsb3RzGAIgAygFIioKFVByb3RvU2VydmVyUHVzaFN0cmVhbRIRCglyb29tX25hbWUYASABKAlCFloUcmVsYXkvaW50ZXJuYWwvcHJvdG9iBnByb3RvMw");
+  fileDesc("Cgt0eXBlcy5wcm90bxIFcHJvdG8iJgoOUHJvdG9Nb3VzZU1vdmUSCQoBeBgBIAEoBRIJCgF5GAIgASgFIikKEVByb3RvTW91c2VNb3ZlQWJzEgkKAXgYASABKAUSCQoBeRgCIAEoBSInCg9Qcm90b01vdXNlV2hlZWwSCQoBeBgBIAEoBRIJCgF5GAIgASgFIiAKEVByb3RvTW91c2VLZXlEb3duEgsKA2tleRgBIAEoBSIeCg9Qcm90b01vdXNlS2V5VXASCwoDa2V5GAEgASgFIhsKDFByb3RvS2V5RG93bhILCgNrZXkYASABKAUiGQoKUHJvdG9LZXlVcBILCgNrZXkYASABKAUiTQoVUHJvdG9Db250cm9sbGVyQXR0YWNoEgoKAmlkGAEgASgJEhQKDHNlc3Npb25fc2xvdBgCIAEoBRISCgpzZXNzaW9uX2lkGAMgASgJIkEKFVByb3RvQ29udHJvbGxlckRldGFjaBIUCgxzZXNzaW9uX3Nsb3QYASABKAUSEgoKc2Vzc2lvbl9pZBgCIAEoCSKCAQoVUHJvdG9Db250cm9sbGVyUnVtYmxlEhQKDHNlc3Npb25fc2xvdBgBIAEoBRISCgpzZXNzaW9uX2lkGAIgASgJEhUKDWxvd19mcmVxdWVuY3kYAyABKAUSFgoOaGlnaF9mcmVxdWVuY3kYBCABKAUSEAoIZHVyYXRpb24YBSABKAUi0AUKGVByb3RvQ29udHJvbGxlclN0YXRlQmF0Y2gSFAoMc2Vzc2lvbl9zbG90GAEgASgFEhIKCnNlc3Npb25faWQYAiABKAkSQAoLdXBkYXRlX3R5cGUYAyABKA4yKy5wcm90by5Qcm90b0NvbnRyb2xsZXJTdGF0ZUJhdGNoLlVwZGF0ZVR5cGUSEAoIc2VxdWVuY2UYBCABKA0SVAoTYnV0dG9uX2NoYW5nZWRfbWFzaxgFIAMoCzI3LnByb3RvLlByb3RvQ29udHJvbGxlclN0YXRlQmF0Y2guQnV0dG9uQ2hhbmdlZE1hc2tFbnRyeRIZCgxsZWZ0X3N0aWNrX3gYBiABKAVIAIgBARIZCgxsZWZ0X3N0aWNrX3kYByABKAVIAYgBARIaCg1yaWdodF9zdGlja194GAggASgFSAKIAQESGgoNcmlnaHRfc3RpY2tfeRgJIAEoBUgDiAEBEhkKDGxlZnRfdHJpZ2dlchgKIAEoBUgEiAEBEhoKDXJpZ2h0X3RyaWdnZXIYCyABKAVIBYgBARITCgZkcGFkX3gYDCABKAVIBogBARITCgZkcGFkX3kYDSABKAVIB4gBARIbCg5jaGFuZ2VkX2ZpZWxkcxgOIAEoDUgIiAEBGjgKFkJ1dHRvbkNoYW5nZWRNYXNrRW50cnkSCwoDa2V5GAEgASgFEg0KBXZhbHVlGAIgASgIOgI4ASInCgpVcGRhdGVUeXBlEg4KCkZVTExfU1RBVEUQABIJCgVERUxUQRABQg8KDV9sZWZ0X3N0aWNrX3hCDwoNX2xlZnRfc3RpY2tfeUIQCg5fcmlnaHRfc3RpY2tfeEIQCg5fcmlnaHRfc3RpY2tfeUIPCg1fbGVmdF90cmlnZ2VyQhAKDl9yaWdodF90cmlnZ2VyQgkKB19kcGFkX3hCCQoHX2RwYWRfeUIRCg9fY2hhbmdlZF9maWVsZHMiqgEKE1JUQ0ljZUNhbmRpZGF0ZUluaXQSEQoJY2FuZGlkYXRlGAEgASgJEhoKDXNkcE1MaW5lSW5kZXgYAiABKA1IAIgBARITCgZzZHBNaWQYAyABKAlIAYgBARIdChB1c2VybmFtZUZyYWdtZW50GAQgASgJSAKIAQFCEAoOX3NkcE1MaW5lSW5kZXhCCQoHX3NkcE1pZEITChFfdXNlcm5hbWVGcmFnbWVudCI2ChlSVENTZXNzaW9uRGVzY3JpcHRpb25Jbml0EgsKA3NkcBgBIAEoCRIMCgR0eXBlGAIgASgJIjkKCFByb3RvSUNFEi0KCWNhbmRpZGF0ZRgBIAEoCzIaLnByb3RvLlJUQ0ljZUNhbmRpZGF0ZUluaXQiOQoIUHJvdG9TRFASLQoDc2RwGAEgASgLMiAucHJvdG8uUlRDU2Vzc2lvbkRlc2NyaXB0aW9uSW5pdCIYCghQcm90b1JhdxIMCgRkYXRhGAEgASgJIoYBChxQcm90b0NsaWVudFJlcXVlc3RSb29tU3RyZWFtEhEKCXJvb21fbmFtZRgBIAEoCRISCgpzZXNzaW9uX2lkGAIgASgJEhkKEWV4cGVyaW1lbnRfb3B0X2luGAMgASgIEg0KBXRva2VuGAQgASgJEhUKDWFjY2Vzc19zZWNyZXQYBSABKAkiRwoXUHJvdG9DbGllbnREaXNjb25uZWN0ZWQSEgoKc2Vzc2lvbl9pZBgBIAEoCRIYChBjb250cm9sbGVyX3Nsb3RzGAIgAygFIsoBChVQcm90b1NlcnZlclB1c2hTdHJlYW0SEQoJcm9vbV9uYW1lGAEgASgJEioKCHNldHRpbmdzGAIgASgLMhgucHJvdG8uUHJvdG9Sb29tU2V0dGluZ3MSEQoJdGltZXN0YW1wGAMgASgDEhEKCXNpZ25hdHVyZRgEIAEoCRIqCghtZXRhZGF0YRgFIAEoCzIYLnByb3RvLlByb3RvUm9vbU1ldGFkYXRhEg8KB3ZhcmlhbnQYBiABKAkSDwoHc3RhbmRieRgHIAEoCCKEAgoRUHJvdG9Sb29tU2V0dGluZ3MSEgoKYXVkaW9fb25seRgBIAEoCBIZChFsYXRlbmN5X2J1ZGdldF9tcxgCIAEoDRIWCg5zdHJpY3RfbGF0ZW5jeRgDIAEoCBIYChBtYXhfZnJhbWVfYWdlX21zGAQgASgNEhUKDWFjY2Vzc19zZWNyZXQYBSABKAkSEwoLbWF4X3ZpZXdlcnMYBiABKA0SEgoKcXVldWVfc2l6ZRgHIAEoDRIcChRxdWV1ZV9oaWdoX3dhdGVybWFyaxgIIAEoDRIbChNxdWV1ZV9sb3dfd2F0ZXJtYXJrGAkgASgNEhMKC2Ryb3BfcG9saWN5GAogASgJIosBChFQcm90b1Jvb21NZXRhZGF0YRINCgV0aXRsZRgBIAEoCRIMCgRnYW1lGAIgASgJEg0KBXdpZHRoGAMgASgNEg4KBmhlaWdodBgEIAEoDRISCgpmcmFtZV9yYXRlGAUgASgNEg8KB3ByaXZhdGUYBiABKAgSFQoNdGh1bWJuYWlsX3VybBgHIAEoCSJEChNQcm90b0RpcmVjdG9yeVF1ZXJ5Eg4KBnByZWZpeBgBIAEoCRIOCgZjdXJzb3IYAiABKAkSDQoFbGltaXQYAyABKA0ijQEKElByb3RvRGlyZWN0b3J5Um9vbRIKCgJpZBgBIAEoCRIMCgRuYW1lGAIgASgJEhAKCG93bmVyX2lkGAMgASgJEg8KB3ZpZXdlcnMYBCABKA0SDgoGb25saW5lGAUgASgIEioKCG1ldGFkYXRhGAYgASgLMhgucHJvdG8uUHJvdG9Sb29tTWV0YWRhdGEiVQoUUHJvdG9EaXJlY3RvcnlSZXN1bHQSKAoFcm9vbXMYASADKAsyGS5wcm90by5Qcm90b0RpcmVjdG9yeVJvb20SEwoLbmV4dF9jdXJzb3IYAiABKAkiTwoTUHJvdG9TdHJlYW1QYXRoSW5mbxIRCglyb29tX25hbWUYASABKAkSDAoEaG9wcxgCIAEoDRIXCg9wYXRoX2xhdGVuY3lfdXMYAyABKAQihgEKD1Byb3RvVHJhY2tTdGF0cxIMCgRraW5kGAEgASgJEhMKC2JpdHJhdGVfYnBzGAIgASgEEhIKCmZyYW1lX3JhdGUYAyABKAESHAoUa2V5ZnJhbWVfaW50ZXJ2YWxfbXMYBCABKA0SDwoHcGFja2V0cxgFIAEoBBINCgVieXRlcxgGIAEoBCJ+ChBQcm90b1N0cmVhbVN0YXRzEhEKCXJvb21fbmFtZRgBIAEoCRImCgZ0cmFja3MYAiADKAsyFi5wcm90by5Qcm90b1RyYWNrU3RhdHMSLwoNaW5wdXRfbGF0ZW5jeRgDIAEoCzIYLnByb3RvLlByb3RvSW5wdXRMYXRlbmN5Ii8KEFByb3RvUmVsYXlOb3RpY2USDAoEdGV4dBgBIAEoCRINCgVsZXZlbBgCIAEoCSJeChZQcm90b1NpZ25hbGluZ1Byb2dyZXNzEhEKCXJvb21fbmFtZRgBIAEoCRINCgVzdGFnZRgCIAEoCRIOCgZkZXRhaWwYAyABKAkSEgoKZWxhcHNlZF9tcxgEIAEoDSJOChNQcm90b01lc2hSb29tVHJhY2tzEhEKCXJvb21fbmFtZRgBIAEoCRIRCglhdWRpb19taWQYAiABKAkSEQoJdmlkZW9fbWlkGAMgASgJImUKDVByb3RvUm9vbUZ1bGwSEQoJcm9vbV9uYW1lGAEgASgJEhQKDHZpZXdlcl9jb3VudBgCIAEoDRITCgttYXhfdmlld2VycxgDIAEoDRIWCg5xdWV1ZV9wb3NpdGlvbhgEIAEoDSJeCg9Qcm90b01vZGVyYXRpb24SEQoJcm9vbV9uYW1lGAEgASgJEg4KBmFjdGlvbhgCIAEoCRIOCgZyZWFzb24YAyABKAkSGAoQYmFuX2V4cGlyZXNfdW5peBgEIAEoAyKKAQoQUHJvdG9DaGF0TWVzc2FnZRIRCglyb29tX25hbWUYASABKAkSDAoEdGV4dBgCIAEoCRIRCglzZW5kZXJfaWQYAyABKAkSEwoLc2VuZGVyX25hbWUYBCABKAkSFwoPc2VuZGVyX2lkZW50aXR5GAUgASgJEhQKDHNlbnRfdW5peF9tcxgGIAEoAyJ2ChBQcm90b1Jvb21WYXJpYW50EgwKBG5hbWUYASABKAkSEQoJcm9vbV9uYW1lGAIgASgJEg0KBXdpZHRoGAMgASgNEg4KBmhlaWdodBgEIAEoDRISCgpmcmFtZV9yYXRlGAUgASgNEg4KBm9ubGluZRgGIAEoCCJiChFQcm90b1Jvb21WYXJpYW50cxIRCglyb29tX25hbWUYASABKAkSDwoHY3VycmVudBgCIAEoCRIpCgh2YXJpYW50cxgDIAMoCzIXLnByb3RvLlByb3RvUm9vbVZhcmlhbnQiNAoSUHJvdG9WYXJpYW50U3dpdGNoEg8KB3ZhcmlhbnQYASABKAkSDQoFZXJyb3IYAiABKAkiNgoQUHJvdG9WaWV3ZXJDb3VudBIRCglyb29tX25hbWUYASABKAkSDwoHdmlld2VycxgCIAEoDSI3Cg5Qcm90b1Rocm90dGxlZBINCgVzY29wZRgBIAEoCRIWCg5yZXRyeV9hZnRlcl9tcxgCIAEoDSJzChJQcm90b1F1b3RhRXhjZWVkZWQSEQoJcm9vbV9uYW1lGAEgASgJEg0KBXNjb3BlGAIgASgJEhIKCnVzZWRfYnl0ZXMYAyABKAQSEwoLcXVvdGFfYnl0ZXMYBCABKAQSEgoKcmVzZXRfdW5peBgFIAEoAyJyChJQcm90b1JlbGF5T3ZlcmxvYWQSEAoIcmVsYXlfaWQYASABKAkSEgoKb3ZlcmxvYWRlZBgCIAEoCBIOCgZyZWFzb24YAyABKAkSEwoLY3B1X3BlcmNlbnQYBCABKA0SEQoJZHJvcF9yYXRlGAUgASgNIkoKE1Byb3RvSW52YWxpZE1lc3NhZ2USFAoMcGF5bG9hZF90eXBlGAEgASgJEg0KBWZpZWxkGAIgASgJEg4KBnJlYXNvbhgDIAEoCSI2ChJQcm90b1B1c2hDaGFsbGVuZ2USEQoJcm9vbV9uYW1lGAEgASgJEg0KBW5vbmNlGAIgASgJIlYKGlByb3RvUHVzaENoYWxsZW5nZVJlc3BvbnNlEhEKCXJvb21fbmFtZRgBIAEoCRISCgpwdWJsaWNfa2V5GAIgASgJEhEKCXNpZ25hdHVyZRgDIAEoCSJCCg9Qcm90b0F1ZGlvTGV2ZWwSEQoJcm9vbV9uYW1lGAEgASgJEg0KBWxldmVsGAIgASgNEg0KBXZvaWNlGAMgASgIIkUKEFByb3RvQmV0dGVyUmVsYXkSEQoJcm9vbV9uYW1lGAEgASgJEg8KB3BlZXJfaWQYAiABKAkSDQoFYWRkcnMYAyADKAkiaAoQUHJvdG9TdHJlYW1DbG9jaxIRCglyb29tX25hbWUYASABKAkSFQoNcnRwX3RpbWVzdGFtcBgCIAEoDRIWCg5zdHJlYW1fdGltZV91cxgDIAEoAxISCgpjbG9ja19yYXRlGAQgASgNIngKEVByb3RvSW5wdXRMYXRlbmN5EhEKCXJvb21fbmFtZRgBIAEoCRIPCgdsYXN0X21zGAIgASgNEg4KBnA1MF9tcxgDIAEoDRIOCgZwOTVfbXMYBCABKA0SDgoGcDk5X21zGAUgASgNEg8KB3NhbXBsZXMYBiABKARCFloUcmVsYXkvaW50ZXJuYWwvcHJvdG9iBnByb3RvMw");
 
 /**
  * MouseMove message
@@ -536,6 +536,27 @@ export type ProtoClientRequestRoomStream = Message<"proto.ProtoClientRequestRoom
    * @generated from field: string session_id = 2;
    */
   sessionId: string;
+
+  /**
+   * Viewer consents to being moved into encoder experiments
+   *
+   * @generated from field: bool experiment_opt_in = 3;
+   */
+  experimentOptIn: boolean;
+
+  /**
+   * Signed viewer token issued by the platform, required when relay enforces viewer authorization
+   *
+   * @generated from field: string token = 4;
+   */
+  token: string;
+
+  /**
+   * Room password or invite token, required for rooms pushed with an access secret
+   *
+   * @generated from field: string access_secret = 5;
+   */
+  accessSecret: string;
 };
 
 /**
@@ -579,6 +600,48 @@ export type ProtoServerPushStream = Message<"proto.ProtoServerPushStream"> & {
    * @generated from field: string room_name = 1;
    */
   roomName: string;
+
+  /**
+   * Optional room settings, applied when room is created
+   *
+   * @generated from field: proto.ProtoRoomSettings settings = 2;
+   */
+  settings?: ProtoRoomSettings;
+
+  /**
+   * Unix seconds the push was signed at, required when relay enforces push authentication
+   *
+   * @generated from field: int64 timestamp = 3;
+   */
+  timestamp: bigint;
+
+  /**
+   * Hex HMAC-SHA256 of "<room_name>\n<timestamp>" with the push secret
+   *
+   * @generated from field: string signature = 4;
+   */
+  signature: string;
+
+  /**
+   * Optional room metadata for room lists, can be updated later with "room-metadata"
+   *
+   * @generated from field: proto.ProtoRoomMetadata metadata = 5;
+   */
+  metadata?: ProtoRoomMetadata;
+
+  /**
+   * Quality variant like "720p30" pushed alongside the main stream of the room, empty for the main stream
+   *
+   * @generated from field: string variant = 6;
+   */
+  variant: string;
+
+  /**
+   * Push as standby of the online room, forwarded from only once the pushed stream fails
+   *
+   * @generated from field: bool standby = 7;
+   */
+  standby: boolean;
 };
 
 /**
@@ -588,3 +651,1135 @@ export type ProtoServerPushStream = Message<"proto.ProtoServerPushStream"> & {
 export const ProtoServerPushStreamSchema: GenMessage<ProtoServerPushStream> = /*@__PURE__*/
   messageDesc(file_types, 18);
 
+/**
+ * ProtoRoomSettings message
+ *
+ * @generated from message proto.ProtoRoomSettings
+ */
+export type ProtoRoomSettings = Message<"proto.ProtoRoomSettings"> & {
+  /**
+   * Room carries only audio (voice rooms), no video tracks are allocated
+   *
+   * @generated from field: bool audio_only = 1;
+   */
+  audioOnly: boolean;
+
+  /**
+   * End-to-end latency budget for mesh forwarding paths, 0 uses relay default
+   *
+   * @generated from field: uint32 latency_budget_ms = 2;
+   */
+  latencyBudgetMs: number;
+
+  /**
+   * Drop whole video frames which are late for a viewer instead of delivering them
+   *
+   * @generated from field: bool strict_latency = 3;
+   */
+  strictLatency: boolean;
+
+  /**
+   * Max age of video frame since ingest in strict latency mode, 0 uses relay default
+   *
+   * @generated from field: uint32 max_frame_age_ms = 4;
+   */
+  maxFrameAgeMs: number;
+
+  /**
+   * Password or invite token viewers must present, empty for public rooms. Only sent with pushes, never echoed back
+   *
+   * @generated from field: string access_secret = 5;
+   */
+  accessSecret: string;
+
+  /**
+   * Max participants the room admits, 0 uses relay config or no limit
+   *
+   * @generated from field: uint32 max_viewers = 6;
+   */
+  maxViewers: number;
+
+  /**
+   * Packets queued per viewer, 0 uses relay default
+   *
+   * @generated from field: uint32 queue_size = 7;
+   */
+  queueSize: number;
+
+  /**
+   * Queue depth at which "video" and "keyframe" drop policies start dropping video, 0 uses 80% of queue size
+   *
+   * @generated from field: uint32 queue_high_watermark = 8;
+   */
+  queueHighWatermark: number;
+
+  /**
+   * Queue depth at which dropped video resumes, 0 uses half of queue size
+   *
+   * @generated from field: uint32 queue_low_watermark = 9;
+   */
+  queueLowWatermark: number;
+
+  /**
+   * "newest", "oldest", "video" or "keyframe", empty uses relay config or "newest"
+   *
+   * @generated from field: string drop_policy = 10;
+   */
+  dropPolicy: string;
+};
+
+/**
+ * Describes the message proto.ProtoRoomSettings.
+ * Use `create(ProtoRoomSettingsSchema)` to create a new message.
+ */
+export const ProtoRoomSettingsSchema: GenMessage<ProtoRoomSettings> = /*@__PURE__*/
+  messageDesc(file_types, 19);
+
+/**
+ * ProtoRoomMetadata message
+ *
+ * @generated from message proto.ProtoRoomMetadata
+ */
+export type ProtoRoomMetadata = Message<"proto.ProtoRoomMetadata"> & {
+  /**
+   * Room title shown in room lists
+   *
+   * @generated from field: string title = 1;
+   */
+  title: string;
+
+  /**
+   * Name of the game being streamed
+   *
+   * @generated from field: string game = 2;
+   */
+  game: string;
+
+  /**
+   * Stream resolution width in pixels
+   *
+   * @generated from field: uint32 width = 3;
+   */
+  width: number;
+
+  /**
+   * Stream resolution height in pixels
+   *
+   * @generated from field: uint32 height = 4;
+   */
+  height: number;
+
+  /**
+   * Stream frames per second
+   *
+   * @generated from field: uint32 frame_rate = 5;
+   */
+  frameRate: number;
+
+  /**
+   * Room is left out of room directories, still joinable by name
+   *
+   * @generated from field: bool private = 6;
+   */
+  private: boolean;
+
+  /**
+   * URL of latest keyframe thumbnail of the room, empty if none
+   *
+   * @generated from field: string thumbnail_url = 7;
+   */
+  thumbnailUrl: string;
+};
+
+/**
+ * Describes the message proto.ProtoRoomMetadata.
+ * Use `create(ProtoRoomMetadataSchema)` to create a new message.
+ */
+export const ProtoRoomMetadataSchema: GenMessage<ProtoRoomMetadata> = /*@__PURE__*/
+  messageDesc(file_types, 20);
+
+/**
+ * ProtoDirectoryQuery message
+ *
+ * @generated from message proto.ProtoDirectoryQuery
+ */
+export type ProtoDirectoryQuery = Message<"proto.ProtoDirectoryQuery"> & {
+  /**
+   * Room name prefix to match, empty matches all rooms
+   *
+   * @generated from field: string prefix = 1;
+   */
+  prefix: string;
+
+  /**
+   * Cursor from previous result page, empty for first page
+   *
+   * @generated from field: string cursor = 2;
+   */
+  cursor: string;
+
+  /**
+   * Max rooms per page, relay applies default and cap
+   *
+   * @generated from field: uint32 limit = 3;
+   */
+  limit: number;
+};
+
+/**
+ * Describes the message proto.ProtoDirectoryQuery.
+ * Use `create(ProtoDirectoryQuerySchema)` to create a new message.
+ */
+export const ProtoDirectoryQuerySchema: GenMessage<ProtoDirectoryQuery> = /*@__PURE__*/
+  messageDesc(file_types, 21);
+
+/**
+ * ProtoDirectoryRoom message
+ *
+ * @generated from message proto.ProtoDirectoryRoom
+ */
+export type ProtoDirectoryRoom = Message<"proto.ProtoDirectoryRoom"> & {
+  /**
+   * @generated from field: string id = 1;
+   */
+  id: string;
+
+  /**
+   * @generated from field: string name = 2;
+   */
+  name: string;
+
+  /**
+   * @generated from field: string owner_id = 3;
+   */
+  ownerId: string;
+
+  /**
+   * Viewer count as known by the answering relay
+   *
+   * @generated from field: uint32 viewers = 4;
+   */
+  viewers: number;
+
+  /**
+   * @generated from field: bool online = 5;
+   */
+  online: boolean;
+
+  /**
+   * @generated from field: proto.ProtoRoomMetadata metadata = 6;
+   */
+  metadata?: ProtoRoomMetadata;
+};
+
+/**
+ * Describes the message proto.ProtoDirectoryRoom.
+ * Use `create(ProtoDirectoryRoomSchema)` to create a new message.
+ */
+export const ProtoDirectoryRoomSchema: GenMessage<ProtoDirectoryRoom> = /*@__PURE__*/
+  messageDesc(file_types, 22);
+
+/**
+ * ProtoDirectoryResult message
+ *
+ * @generated from message proto.ProtoDirectoryResult
+ */
+export type ProtoDirectoryResult = Message<"proto.ProtoDirectoryResult"> & {
+  /**
+   * @generated from field: repeated proto.ProtoDirectoryRoom rooms = 1;
+   */
+  rooms: ProtoDirectoryRoom[];
+
+  /**
+   * Empty when there are no more pages
+   *
+   * @generated from field: string next_cursor = 2;
+   */
+  nextCursor: string;
+};
+
+/**
+ * Describes the message proto.ProtoDirectoryResult.
+ * Use `create(ProtoDirectoryResultSchema)` to create a new message.
+ */
+export const ProtoDirectoryResultSchema: GenMessage<ProtoDirectoryResult> = /*@__PURE__*/
+  messageDesc(file_types, 23);
+
+/**
+ * ProtoStreamPathInfo message
+ *
+ * @generated from message proto.ProtoStreamPathInfo
+ */
+export type ProtoStreamPathInfo = Message<"proto.ProtoStreamPathInfo"> & {
+  /**
+   * @generated from field: string room_name = 1;
+   */
+  roomName: string;
+
+  /**
+   * Relay hops between room owner and the serving relay
+   *
+   * @generated from field: uint32 hops = 2;
+   */
+  hops: number;
+
+  /**
+   * Cumulative measured latency up to and including the serving relay
+   *
+   * @generated from field: uint64 path_latency_us = 3;
+   */
+  pathLatencyUs: bigint;
+};
+
+/**
+ * Describes the message proto.ProtoStreamPathInfo.
+ * Use `create(ProtoStreamPathInfoSchema)` to create a new message.
+ */
+export const ProtoStreamPathInfoSchema: GenMessage<ProtoStreamPathInfo> = /*@__PURE__*/
+  messageDesc(file_types, 24);
+
+/**
+ * ProtoTrackStats message
+ *
+ * @generated from message proto.ProtoTrackStats
+ */
+export type ProtoTrackStats = Message<"proto.ProtoTrackStats"> & {
+  /**
+   * "audio" or "video"
+   *
+   * @generated from field: string kind = 1;
+   */
+  kind: string;
+
+  /**
+   * Forwarded bitrate
+   *
+   * @generated from field: uint64 bitrate_bps = 2;
+   */
+  bitrateBps: bigint;
+
+  /**
+   * Frames per second, counted from RTP marker bits (video only)
+   *
+   * @generated from field: double frame_rate = 3;
+   */
+  frameRate: number;
+
+  /**
+   * Time between last two keyframes (video only), 0 if unknown
+   *
+   * @generated from field: uint32 keyframe_interval_ms = 4;
+   */
+  keyframeIntervalMs: number;
+
+  /**
+   * Total forwarded packets
+   *
+   * @generated from field: uint64 packets = 5;
+   */
+  packets: bigint;
+
+  /**
+   * Total forwarded payload bytes
+   *
+   * @generated from field: uint64 bytes = 6;
+   */
+  bytes: bigint;
+};
+
+/**
+ * Describes the message proto.ProtoTrackStats.
+ * Use `create(ProtoTrackStatsSchema)` to create a new message.
+ */
+export const ProtoTrackStatsSchema: GenMessage<ProtoTrackStats> = /*@__PURE__*/
+  messageDesc(file_types, 25);
+
+/**
+ * ProtoStreamStats message
+ *
+ * @generated from message proto.ProtoStreamStats
+ */
+export type ProtoStreamStats = Message<"proto.ProtoStreamStats"> & {
+  /**
+   * @generated from field: string room_name = 1;
+   */
+  roomName: string;
+
+  /**
+   * @generated from field: repeated proto.ProtoTrackStats tracks = 2;
+   */
+  tracks: ProtoTrackStats[];
+
+  /**
+   * Input round trip of the viewer, unset before any was measured
+   *
+   * @generated from field: proto.ProtoInputLatency input_latency = 3;
+   */
+  inputLatency?: ProtoInputLatency;
+};
+
+/**
+ * Describes the message proto.ProtoStreamStats.
+ * Use `create(ProtoStreamStatsSchema)` to create a new message.
+ */
+export const ProtoStreamStatsSchema: GenMessage<ProtoStreamStats> = /*@__PURE__*/
+  messageDesc(file_types, 26);
+
+/**
+ * ProtoRelayNotice message
+ *
+ * @generated from message proto.ProtoRelayNotice
+ */
+export type ProtoRelayNotice = Message<"proto.ProtoRelayNotice"> & {
+  /**
+   * @generated from field: string text = 1;
+   */
+  text: string;
+
+  /**
+   * "info", "warning" or "critical"
+   *
+   * @generated from field: string level = 2;
+   */
+  level: string;
+};
+
+/**
+ * Describes the message proto.ProtoRelayNotice.
+ * Use `create(ProtoRelayNoticeSchema)` to create a new message.
+ */
+export const ProtoRelayNoticeSchema: GenMessage<ProtoRelayNotice> = /*@__PURE__*/
+  messageDesc(file_types, 27);
+
+/**
+ * ProtoSignalingProgress message
+ *
+ * @generated from message proto.ProtoSignalingProgress
+ */
+export type ProtoSignalingProgress = Message<"proto.ProtoSignalingProgress"> & {
+  /**
+   * @generated from field: string room_name = 1;
+   */
+  roomName: string;
+
+  /**
+   * "session-assigned", "offer-sent", "ice-connected", "dtls-connected", "first-frame-forwarded" or a failure stage
+   *
+   * @generated from field: string stage = 2;
+   */
+  stage: string;
+
+  /**
+   * Reason of a failure stage, empty otherwise
+   *
+   * @generated from field: string detail = 3;
+   */
+  detail: string;
+
+  /**
+   * Time since the stream request was received
+   *
+   * @generated from field: uint32 elapsed_ms = 4;
+   */
+  elapsedMs: number;
+};
+
+/**
+ * Describes the message proto.ProtoSignalingProgress.
+ * Use `create(ProtoSignalingProgressSchema)` to create a new message.
+ */
+export const ProtoSignalingProgressSchema: GenMessage<ProtoSignalingProgress> = /*@__PURE__*/
+  messageDesc(file_types, 28);
+
+/**
+ * ProtoMeshRoomTracks message
+ *
+ * @generated from message proto.ProtoMeshRoomTracks
+ */
+export type ProtoMeshRoomTracks = Message<"proto.ProtoMeshRoomTracks"> & {
+  /**
+   * @generated from field: string room_name = 1;
+   */
+  roomName: string;
+
+  /**
+   * MID of room audio on the shared mesh PeerConnection
+   *
+   * @generated from field: string audio_mid = 2;
+   */
+  audioMid: string;
+
+  /**
+   * MID of room video, empty for audio-only rooms
+   *
+   * @generated from field: string video_mid = 3;
+   */
+  videoMid: string;
+};
+
+/**
+ * Describes the message proto.ProtoMeshRoomTracks.
+ * Use `create(ProtoMeshRoomTracksSchema)` to create a new message.
+ */
+export const ProtoMeshRoomTracksSchema: GenMessage<ProtoMeshRoomTracks> = /*@__PURE__*/
+  messageDesc(file_types, 29);
+
+/**
+ * ProtoRoomFull message
+ *
+ * @generated from message proto.ProtoRoomFull
+ */
+export type ProtoRoomFull = Message<"proto.ProtoRoomFull"> & {
+  /**
+   * @generated from field: string room_name = 1;
+   */
+  roomName: string;
+
+  /**
+   * Participants currently in the room
+   *
+   * @generated from field: uint32 viewer_count = 2;
+   */
+  viewerCount: number;
+
+  /**
+   * Participants the room admits
+   *
+   * @generated from field: uint32 max_viewers = 3;
+   */
+  maxViewers: number;
+
+  /**
+   * Position in the room waiting list, 0 if the requester isn't queued
+   *
+   * @generated from field: uint32 queue_position = 4;
+   */
+  queuePosition: number;
+};
+
+/**
+ * Describes the message proto.ProtoRoomFull.
+ * Use `create(ProtoRoomFullSchema)` to create a new message.
+ */
+export const ProtoRoomFullSchema: GenMessage<ProtoRoomFull> = /*@__PURE__*/
+  messageDesc(file_types, 30);
+
+/**
+ * ProtoModeration message
+ *
+ * @generated from message proto.ProtoModeration
+ */
+export type ProtoModeration = Message<"proto.ProtoModeration"> & {
+  /**
+   * @generated from field: string room_name = 1;
+   */
+  roomName: string;
+
+  /**
+   * "kicked" or "banned"
+   *
+   * @generated from field: string action = 2;
+   */
+  action: string;
+
+  /**
+   * Moderator given reason, may be empty
+   *
+   * @generated from field: string reason = 3;
+   */
+  reason: string;
+
+  /**
+   * When the ban ends, 0 for kicks
+   *
+   * @generated from field: int64 ban_expires_unix = 4;
+   */
+  banExpiresUnix: bigint;
+};
+
+/**
+ * Describes the message proto.ProtoModeration.
+ * Use `create(ProtoModerationSchema)` to create a new message.
+ */
+export const ProtoModerationSchema: GenMessage<ProtoModeration> = /*@__PURE__*/
+  messageDesc(file_types, 31);
+
+/**
+ * ProtoChatMessage message
+ *
+ * @generated from message proto.ProtoChatMessage
+ */
+export type ProtoChatMessage = Message<"proto.ProtoChatMessage"> & {
+  /**
+   * Set by relay
+   *
+   * @generated from field: string room_name = 1;
+   */
+  roomName: string;
+
+  /**
+   * @generated from field: string text = 2;
+   */
+  text: string;
+
+  /**
+   * Participant ID of sender, set by relay
+   *
+   * @generated from field: string sender_id = 3;
+   */
+  senderId: string;
+
+  /**
+   * Display name chosen by sender, unverified
+   *
+   * @generated from field: string sender_name = 4;
+   */
+  senderName: string;
+
+  /**
+   * Viewer token subject of sender, set by relay, empty without viewer authorization
+   *
+   * @generated from field: string sender_identity = 5;
+   */
+  senderIdentity: string;
+
+  /**
+   * When the relay accepted the message
+   *
+   * @generated from field: int64 sent_unix_ms = 6;
+   */
+  sentUnixMs: bigint;
+};
+
+/**
+ * Describes the message proto.ProtoChatMessage.
+ * Use `create(ProtoChatMessageSchema)` to create a new message.
+ */
+export const ProtoChatMessageSchema: GenMessage<ProtoChatMessage> = /*@__PURE__*/
+  messageDesc(file_types, 32);
+
+/**
+ * ProtoRoomVariant message
+ *
+ * @generated from message proto.ProtoRoomVariant
+ */
+export type ProtoRoomVariant = Message<"proto.ProtoRoomVariant"> & {
+  /**
+   * Variant name like "720p30", empty for the main stream
+   *
+   * @generated from field: string name = 1;
+   */
+  name: string;
+
+  /**
+   * Sibling room carrying the variant
+   *
+   * @generated from field: string room_name = 2;
+   */
+  roomName: string;
+
+  /**
+   * From room metadata of the variant push, 0 if unknown
+   *
+   * @generated from field: uint32 width = 3;
+   */
+  width: number;
+
+  /**
+   * @generated from field: uint32 height = 4;
+   */
+  height: number;
+
+  /**
+   * @generated from field: uint32 frame_rate = 5;
+   */
+  frameRate: number;
+
+  /**
+   * @generated from field: bool online = 6;
+   */
+  online: boolean;
+};
+
+/**
+ * Describes the message proto.ProtoRoomVariant.
+ * Use `create(ProtoRoomVariantSchema)` to create a new message.
+ */
+export const ProtoRoomVariantSchema: GenMessage<ProtoRoomVariant> = /*@__PURE__*/
+  messageDesc(file_types, 33);
+
+/**
+ * ProtoRoomVariants message
+ *
+ * @generated from message proto.ProtoRoomVariants
+ */
+export type ProtoRoomVariants = Message<"proto.ProtoRoomVariants"> & {
+  /**
+   * Main room the variants belong to
+   *
+   * @generated from field: string room_name = 1;
+   */
+  roomName: string;
+
+  /**
+   * Variant the viewer currently receives, empty for the main stream
+   *
+   * @generated from field: string current = 2;
+   */
+  current: string;
+
+  /**
+   * @generated from field: repeated proto.ProtoRoomVariant variants = 3;
+   */
+  variants: ProtoRoomVariant[];
+};
+
+/**
+ * Describes the message proto.ProtoRoomVariants.
+ * Use `create(ProtoRoomVariantsSchema)` to create a new message.
+ */
+export const ProtoRoomVariantsSchema: GenMessage<ProtoRoomVariants> = /*@__PURE__*/
+  messageDesc(file_types, 34);
+
+/**
+ * ProtoVariantSwitch message
+ *
+ * @generated from message proto.ProtoVariantSwitch
+ */
+export type ProtoVariantSwitch = Message<"proto.ProtoVariantSwitch"> & {
+  /**
+   * Variant to receive, empty for the main stream
+   *
+   * @generated from field: string variant = 1;
+   */
+  variant: string;
+
+  /**
+   * Why the switch failed, set by relay in "variant-switch-failed"
+   *
+   * @generated from field: string error = 2;
+   */
+  error: string;
+};
+
+/**
+ * Describes the message proto.ProtoVariantSwitch.
+ * Use `create(ProtoVariantSwitchSchema)` to create a new message.
+ */
+export const ProtoVariantSwitchSchema: GenMessage<ProtoVariantSwitch> = /*@__PURE__*/
+  messageDesc(file_types, 35);
+
+/**
+ * ProtoViewerCount message
+ *
+ * @generated from message proto.ProtoViewerCount
+ */
+export type ProtoViewerCount = Message<"proto.ProtoViewerCount"> & {
+  /**
+   * Main room, viewers of its quality variants are included
+   *
+   * @generated from field: string room_name = 1;
+   */
+  roomName: string;
+
+  /**
+   * Viewers across the mesh, relays pulling the room aren't counted
+   *
+   * @generated from field: uint32 viewers = 2;
+   */
+  viewers: number;
+};
+
+/**
+ * Describes the message proto.ProtoViewerCount.
+ * Use `create(ProtoViewerCountSchema)` to create a new message.
+ */
+export const ProtoViewerCountSchema: GenMessage<ProtoViewerCount> = /*@__PURE__*/
+  messageDesc(file_types, 36);
+
+/**
+ * ProtoThrottled message
+ *
+ * @generated from message proto.ProtoThrottled
+ */
+export type ProtoThrottled = Message<"proto.ProtoThrottled"> & {
+  /**
+   * "stream" when opening a signaling stream was refused, "message" when messages are dropped
+   *
+   * @generated from field: string scope = 1;
+   */
+  scope: string;
+
+  /**
+   * When the next stream or message would be accepted
+   *
+   * @generated from field: uint32 retry_after_ms = 2;
+   */
+  retryAfterMs: number;
+};
+
+/**
+ * Describes the message proto.ProtoThrottled.
+ * Use `create(ProtoThrottledSchema)` to create a new message.
+ */
+export const ProtoThrottledSchema: GenMessage<ProtoThrottled> = /*@__PURE__*/
+  messageDesc(file_types, 37);
+
+/**
+ * ProtoQuotaExceeded message
+ *
+ * @generated from message proto.ProtoQuotaExceeded
+ */
+export type ProtoQuotaExceeded = Message<"proto.ProtoQuotaExceeded"> & {
+  /**
+   * Requested or cut room
+   *
+   * @generated from field: string room_name = 1;
+   */
+  roomName: string;
+
+  /**
+   * "daily" or "monthly"
+   *
+   * @generated from field: string scope = 2;
+   */
+  scope: string;
+
+  /**
+   * Bytes sent to the peer within the quota period
+   *
+   * @generated from field: uint64 used_bytes = 3;
+   */
+  usedBytes: bigint;
+
+  /**
+   * Bytes the peer may receive within the quota period
+   *
+   * @generated from field: uint64 quota_bytes = 4;
+   */
+  quotaBytes: bigint;
+
+  /**
+   * When the quota period ends and streams are served again
+   *
+   * @generated from field: int64 reset_unix = 5;
+   */
+  resetUnix: bigint;
+};
+
+/**
+ * Describes the message proto.ProtoQuotaExceeded.
+ * Use `create(ProtoQuotaExceededSchema)` to create a new message.
+ */
+export const ProtoQuotaExceededSchema: GenMessage<ProtoQuotaExceeded> = /*@__PURE__*/
+  messageDesc(file_types, 38);
+
+/**
+ * ProtoRelayOverload message
+ *
+ * @generated from message proto.ProtoRelayOverload
+ */
+export type ProtoRelayOverload = Message<"proto.ProtoRelayOverload"> & {
+  /**
+   * Peer ID of the advising relay
+   *
+   * @generated from field: string relay_id = 1;
+   */
+  relayId: string;
+
+  /**
+   * True while the relay is overloaded, false once it recovered
+   *
+   * @generated from field: bool overloaded = 2;
+   */
+  overloaded: boolean;
+
+  /**
+   * "cpu" or "drops" when overloaded
+   *
+   * @generated from field: string reason = 3;
+   */
+  reason: string;
+
+  /**
+   * Busy share of relay CPU over the last check
+   *
+   * @generated from field: uint32 cpu_percent = 4;
+   */
+  cpuPercent: number;
+
+  /**
+   * Packets per second dropped from viewer queues over the last check
+   *
+   * @generated from field: uint32 drop_rate = 5;
+   */
+  dropRate: number;
+};
+
+/**
+ * Describes the message proto.ProtoRelayOverload.
+ * Use `create(ProtoRelayOverloadSchema)` to create a new message.
+ */
+export const ProtoRelayOverloadSchema: GenMessage<ProtoRelayOverload> = /*@__PURE__*/
+  messageDesc(file_types, 39);
+
+/**
+ * ProtoInvalidMessage message
+ *
+ * @generated from message proto.ProtoInvalidMessage
+ */
+export type ProtoInvalidMessage = Message<"proto.ProtoInvalidMessage"> & {
+  /**
+   * Payload type of the rejected message
+   *
+   * @generated from field: string payload_type = 1;
+   */
+  payloadType: string;
+
+  /**
+   * Offending field, empty if the payload was missing
+   *
+   * @generated from field: string field = 2;
+   */
+  field: string;
+
+  /**
+   * "missing", "too-long", "invalid-utf8" or "invalid-value"
+   *
+   * @generated from field: string reason = 3;
+   */
+  reason: string;
+};
+
+/**
+ * Describes the message proto.ProtoInvalidMessage.
+ * Use `create(ProtoInvalidMessageSchema)` to create a new message.
+ */
+export const ProtoInvalidMessageSchema: GenMessage<ProtoInvalidMessage> = /*@__PURE__*/
+  messageDesc(file_types, 40);
+
+/**
+ * ProtoPushChallenge message
+ *
+ * @generated from message proto.ProtoPushChallenge
+ */
+export type ProtoPushChallenge = Message<"proto.ProtoPushChallenge"> & {
+  /**
+   * Room the push was requested for
+   *
+   * @generated from field: string room_name = 1;
+   */
+  roomName: string;
+
+  /**
+   * Hex random nonce the pushing node signs with its push key
+   *
+   * @generated from field: string nonce = 2;
+   */
+  nonce: string;
+};
+
+/**
+ * Describes the message proto.ProtoPushChallenge.
+ * Use `create(ProtoPushChallengeSchema)` to create a new message.
+ */
+export const ProtoPushChallengeSchema: GenMessage<ProtoPushChallenge> = /*@__PURE__*/
+  messageDesc(file_types, 41);
+
+/**
+ * ProtoPushChallengeResponse message
+ *
+ * @generated from message proto.ProtoPushChallengeResponse
+ */
+export type ProtoPushChallengeResponse = Message<"proto.ProtoPushChallengeResponse"> & {
+  /**
+   * Room of the answered challenge
+   *
+   * @generated from field: string room_name = 1;
+   */
+  roomName: string;
+
+  /**
+   * Hex Ed25519 public key of the pushing node
+   *
+   * @generated from field: string public_key = 2;
+   */
+  publicKey: string;
+
+  /**
+   * Hex Ed25519 signature of the challenge, bound to relay peer ID
+   *
+   * @generated from field: string signature = 3;
+   */
+  signature: string;
+};
+
+/**
+ * Describes the message proto.ProtoPushChallengeResponse.
+ * Use `create(ProtoPushChallengeResponseSchema)` to create a new message.
+ */
+export const ProtoPushChallengeResponseSchema: GenMessage<ProtoPushChallengeResponse> = /*@__PURE__*/
+  messageDesc(file_types, 42);
+
+/**
+ * ProtoAudioLevel message
+ *
+ * @generated from message proto.ProtoAudioLevel
+ */
+export type ProtoAudioLevel = Message<"proto.ProtoAudioLevel"> & {
+  /**
+   * Room whose audio the level was read from
+   *
+   * @generated from field: string room_name = 1;
+   */
+  roomName: string;
+
+  /**
+   * Loudest level since the previous event as -dBov, 0 is loudest and 127 silence (RFC 6464)
+   *
+   * @generated from field: uint32 level = 2;
+   */
+  level: number;
+
+  /**
+   * Sender flagged voice activity since the previous event
+   *
+   * @generated from field: bool voice = 3;
+   */
+  voice: boolean;
+};
+
+/**
+ * Describes the message proto.ProtoAudioLevel.
+ * Use `create(ProtoAudioLevelSchema)` to create a new message.
+ */
+export const ProtoAudioLevelSchema: GenMessage<ProtoAudioLevel> = /*@__PURE__*/
+  messageDesc(file_types, 43);
+
+/**
+ * ProtoBetterRelay message
+ *
+ * @generated from message proto.ProtoBetterRelay
+ */
+export type ProtoBetterRelay = Message<"proto.ProtoBetterRelay"> & {
+  /**
+   * Room the viewer requested
+   *
+   * @generated from field: string room_name = 1;
+   */
+  roomName: string;
+
+  /**
+   * Relay serving the room closer to the viewer
+   *
+   * @generated from field: string peer_id = 2;
+   */
+  peerId: string;
+
+  /**
+   * Multiaddrs the viewer can dial the relay at, with its peer ID
+   *
+   * @generated from field: repeated string addrs = 3;
+   */
+  addrs: string[];
+};
+
+/**
+ * Describes the message proto.ProtoBetterRelay.
+ * Use `create(ProtoBetterRelaySchema)` to create a new message.
+ */
+export const ProtoBetterRelaySchema: GenMessage<ProtoBetterRelay> = /*@__PURE__*/
+  messageDesc(file_types, 44);
+
+/**
+ * ProtoStreamClock message
+ *
+ * @generated from message proto.ProtoStreamClock
+ */
+export type ProtoStreamClock = Message<"proto.ProtoStreamClock"> & {
+  /**
+   * Room whose video the clock maps
+   *
+   * @generated from field: string room_name = 1;
+   */
+  roomName: string;
+
+  /**
+   * Video RTP timestamp as received by the viewer
+   *
+   * @generated from field: uint32 rtp_timestamp = 2;
+   */
+  rtpTimestamp: number;
+
+  /**
+   * Stream time of that timestamp, Unix microseconds the room's relay received the frame at
+   *
+   * @generated from field: int64 stream_time_us = 3;
+   */
+  streamTimeUs: bigint;
+
+  /**
+   * Video RTP clock rate in Hz
+   *
+   * @generated from field: uint32 clock_rate = 4;
+   */
+  clockRate: number;
+};
+
+/**
+ * Describes the message proto.ProtoStreamClock.
+ * Use `create(ProtoStreamClockSchema)` to create a new message.
+ */
+export const ProtoStreamClockSchema: GenMessage<ProtoStreamClock> = /*@__PURE__*/
+  messageDesc(file_types, 45);
+
+/**
+ * ProtoInputLatency message
+ *
+ * @generated from message proto.ProtoInputLatency
+ */
+export type ProtoInputLatency = Message<"proto.ProtoInputLatency"> & {
+  /**
+   * @generated from field: string room_name = 1;
+   */
+  roomName: string;
+
+  /**
+   * Round trip of the latest measured input, from the viewer's relay to the server and back
+   *
+   * @generated from field: uint32 last_ms = 2;
+   */
+  lastMs: number;
+
+  /**
+   * Median input round trip of the viewer
+   *
+   * @generated from field: uint32 p50_ms = 3;
+   */
+  p50Ms: number;
+
+  /**
+   * @generated from field: uint32 p95_ms = 4;
+   */
+  p95Ms: number;
+
+  /**
+   * @generated from field: uint32 p99_ms = 5;
+   */
+  p99Ms: number;
+
+  /**
+   * Input round trips measured so far
+   *
+   * @generated from field: uint64 samples = 6;
+   */
+  samples: bigint;
+};
+
+/**
+ * Describes the message proto.ProtoInputLatency.
+ * Use `create(ProtoInputLatencySchema)` to create a new message.
+ */
+export const ProtoInputLatencySchema: GenMessage<ProtoInputLatency> = /*@__PURE__*/
+  messageDesc(file_types, 46);
+
@@ -12,11 +12,49 @@ type MessageHandler = (
   base: ProtoMessageBase,
 ) => void | Promise<void>;
 
+// How many sequence numbers behind the highest one seen are still accepted out of order
+const REPLAY_WINDOW_SIZE = 64n;
+
+// Tracks sequence numbers received on a stream, detecting replayed and duplicated messages.
+// Messages without a sequence number come from senders not sequencing their messages.
+class ReplayWindow {
+  private nonce: bigint | null = null;
+  private highest = 0n;
+  private seen = 0n; // Bit n set if highest-n was received
+
+  // Records a received sequence number, returning the reason for dropping it if seen before
+  public check(nonce: bigint, sequence: bigint): string | null {
+    if (sequence === 0n) return null;
+    if (this.nonce === null) this.nonce = nonce;
+    else if (this.nonce !== nonce) return "nonce";
+
+    if (sequence > this.highest) {
+      const shift = sequence - this.highest;
+      this.seen =
+        shift >= REPLAY_WINDOW_SIZE
+          ? 0n
+          : (this.seen << shift) & ((1n << REPLAY_WINDOW_SIZE) - 1n);
+      this.seen |= 1n;
+      this.highest = sequence;
+      return null;
+    }
+    if (this.highest - sequence >= REPLAY_WINDOW_SIZE) return "too-old";
+    const bit = 1n << (this.highest - sequence);
+    if (this.seen & bit) return "duplicate";
+    this.seen |= bit;
+    return null;
+  }
+}
+
 export class P2PMessageStream {
   private pb: ProtobufStream;
   private handlers = new Map<string, MessageHandler[]>();
   private closed = false;
   private readLoopRunning = false;
+  // Messages we send are sequenced under our nonce, so the relay can drop replays
+  private nonce = crypto.getRandomValues(new BigUint64Array(1))[0]!;
+  private sequence = 0n;
+  private replay = new ReplayWindow();
 
   constructor(stream: Stream) {
     this.pb = pbStream(stream);
@@ -42,6 +80,14 @@ export class P2PMessageStream {
         );
 
         const payloadType = msg.messageBase?.payloadType;
+        const replayed = this.replay.check(
+          msg.messageBase?.streamNonce ?? 0n,
+          msg.messageBase?.sequence ?? 0n,
+        );
+        if (replayed) {
+          console.warn(`Dropping replayed ${payloadType} message (${replayed})`);
+          continue;
+        }
         if (payloadType && this.handlers.has(payloadType)) {
           const handlers = this.handlers.get(payloadType)!;
           if (msg.payload.value) {
@@ -71,6 +117,10 @@ export class P2PMessageStream {
     if (this.closed)
       throw new Error("Cannot write to closed stream");
 
+    if (message.messageBase) {
+      message.messageBase.sequence = ++this.sequence;
+      message.messageBase.streamNonce = this.nonce;
+    }
     await this.pb.write(message, bufbuildAdapter(ProtoMessageSchema), options);
   }
 
@@ -50,6 +50,12 @@ export function addLatencyTimestamp(
   };
 }
 
+// Message protocol version announced in every message base
+export const PROTOCOL_VERSION = 2;
+
+// Optional protocol features announced in every message base
+export const CAPABILITIES = ["better-relay"];
+
 interface CreateMessageOptions {
   sequenceId?: string;
 }
@@ -86,6 +92,8 @@ export function createMessage(
       latency: options?.sequenceId
         ? createLatencyTracker(options.sequenceId)
         : undefined,
+      protocolVersion: PROTOCOL_VERSION,
+      capabilities: CAPABILITIES,
     }),
     payload: {
       case: payloadCase,
@@ -8,15 +8,25 @@ import { multiaddr } from "@multiformats/multiaddr";
 import { Connection } from "@libp2p/interface";
 import { ping } from "@libp2p/ping";
 import { createMessage } from "./utils";
-import { create } from "@bufbuild/protobuf";
+import { create, toBinary } from "@bufbuild/protobuf";
+import { ProtoMessageSchema } from "./proto/messages_pb";
 import {
+  ProtoBetterRelay,
+  ProtoChatMessageSchema,
   ProtoClientRequestRoomStream,
   ProtoClientRequestRoomStreamSchema,
   ProtoICE,
   ProtoICESchema,
+  ProtoInvalidMessage,
+  ProtoModeration,
+  ProtoQuotaExceeded,
   ProtoRaw,
+  ProtoRoomFull,
   ProtoSDP,
   ProtoSDPSchema,
+  ProtoSignalingProgress,
+  ProtoThrottled,
+  ProtoVariantSwitchSchema,
 } from "./proto/types_pb";
 import { P2PMessageStream } from "./streamwrapper";
 
@@ -39,11 +49,20 @@ export class WebRTCStream {
   private _roomName: string | undefined = undefined;
   private _isConnected: boolean = false;
   private _dataChannelCallbacks: Array<(data: any) => void> = [];
+  private _progressCallbacks: Array<
+    (progress: ProtoSignalingProgress) => void
+  > = [];
+  private _experimentOptIn: boolean = false;
+  private _token: string = "";
+  private _accessSecret: string = "";
 
   constructor(
     serverURL: string,
     roomName: string,
     connectedCallback: (stream: MediaStream | null) => void,
+    experimentOptIn: boolean = false,
+    token: string = "",
+    accessSecret: string = "",
   ) {
     if (roomName.length <= 0) {
       console.error("Room name not provided");
@@ -51,6 +70,9 @@ export class WebRTCStream {
     }
 
     this._onConnected = connectedCallback;
+    this._experimentOptIn = experimentOptIn;
+    this._token = token;
+    this._accessSecret = accessSecret;
     this._serverURL = serverURL;
     this._roomName = roomName;
     this._setup(serverURL, roomName).catch(console.error);
@@ -172,11 +194,100 @@ export class WebRTCStream {
           await this._msgStream?.write(answerMsg);
         });
 
+        this._msgStream.on(
+          "signaling-progress",
+          (data: ProtoSignalingProgress) => {
+            console.debug(
+              "Signaling progress:",
+              data.stage,
+              data.detail,
+              `(${data.elapsedMs}ms)`,
+            );
+            this._progressCallbacks.forEach((callback) => {
+              try {
+                callback(data);
+              } catch (err) {
+                console.error("Error in progress callback:", err);
+              }
+            });
+          },
+        );
+
         this._msgStream.on("request-stream-offline", (msg: ProtoRaw) => {
           console.warn("Stream is offline for room:", msg.data);
           this._onConnected?.(null);
         });
 
+        // Relay keeps the request waiting, an offer follows once the room is online
+        this._msgStream.on("request-stream-online", (msg: ProtoRaw) => {
+          console.log("Stream came online for room:", msg.data);
+        });
+
+        this._msgStream.on("request-stream-unauthorized", (msg: ProtoRaw) => {
+          console.warn("Not authorized to view room:", msg.data);
+          this._onConnected?.(null);
+        });
+
+        this._msgStream.on("request-stream-banned", (data: ProtoModeration) => {
+          console.warn(
+            "Banned from room:",
+            data.roomName,
+            data.reason,
+            `(until ${new Date(Number(data.banExpiresUnix) * 1000).toISOString()})`,
+          );
+          this._onConnected?.(null);
+        });
+
+        // Relay closes the stream when refusing to open it, dropped messages only get noticed once
+        this._msgStream.on("throttled", (data: ProtoThrottled) => {
+          console.warn(
+            "Signaling is rate limited by relay:",
+            data.scope,
+            `(retry after ${data.retryAfterMs}ms)`,
+          );
+          if (data.scope === "stream") this._onConnected?.(null);
+        });
+
+        this._msgStream.on("room-full", (data: ProtoRoomFull) => {
+          console.warn(
+            "Room is full:",
+            data.roomName,
+            `(${data.viewerCount}/${data.maxViewers} viewers)`,
+          );
+          this._onConnected?.(null);
+        });
+
+        // Relay stops serving this peer until the quota period ends
+        this._msgStream.on("quota-exceeded", (data: ProtoQuotaExceeded) => {
+          console.warn(
+            "Bandwidth quota exceeded on relay:",
+            data.scope,
+            `(${data.usedBytes}/${data.quotaBytes} bytes, resets ${new Date(Number(data.resetUnix) * 1000).toISOString()})`,
+          );
+          this._onConnected?.(null);
+        });
+
+        // Another relay serves the room closer to us, request it there instead
+        this._msgStream.on("better-relay", (data: ProtoBetterRelay) => {
+          console.log(
+            "Steered to closer relay:",
+            data.peerId,
+            "for room:",
+            data.roomName,
+          );
+          this._moveToRelay(data.addrs).catch(console.error);
+        });
+
+        // Relay skipped a message of ours failing its validation
+        this._msgStream.on("invalid-message", (data: ProtoInvalidMessage) => {
+          console.warn(
+            "Relay rejected invalid message:",
+            data.payloadType,
+            data.field,
+            data.reason,
+          );
+        });
+
         const clientId = this.getSessionID();
         if (clientId) {
           console.debug("Using existing session ID:", clientId);
@@ -187,6 +298,9 @@ export class WebRTCStream {
           create(ProtoClientRequestRoomStreamSchema, {
             roomName: roomName,
             sessionId: clientId ?? "",
+            experimentOptIn: this._experimentOptIn,
+            token: this._token,
+            accessSecret: this._accessSecret,
           }),
           "request-stream-room",
         );
@@ -195,6 +309,28 @@ export class WebRTCStream {
     }
   }
 
+  // Reconnects to another relay at the first of its addresses we can dial, staying on the current one if none works
+  private async _moveToRelay(addrs: string[]) {
+    const previousURL = this._serverURL;
+    this.disconnect();
+    await this._p2p?.stop();
+    this._p2p = undefined;
+    for (const addr of addrs) {
+      try {
+        await this._setup(addr, this._roomName!);
+        this._serverURL = addr;
+        return;
+      } catch (err) {
+        console.warn("Failed to connect to relay at:", addr, err);
+        await this._p2p?.stop();
+        this._p2p = undefined;
+      }
+    }
+    if (previousURL) {
+      await this._setup(previousURL, this._roomName!);
+    }
+  }
+
   public getSessionID(): string | null {
     if (this._sessionId === null)
       this._sessionId = localStorage.getItem("nestri-session-id");
@@ -383,6 +519,21 @@ export class WebRTCStream {
     );
   }
 
+  // Progress callbacks receive relay signaling stages, useful for showing connection progress
+  public addProgressCallback(
+    callback: (progress: ProtoSignalingProgress) => void,
+  ) {
+    this._progressCallbacks.push(callback);
+  }
+
+  public removeProgressCallback(
+    callback: (progress: ProtoSignalingProgress) => void,
+  ) {
+    this._progressCallbacks = this._progressCallbacks.filter(
+      (cb) => cb !== callback,
+    );
+  }
+
   private _setupDataChannelEvents() {
     if (!this._dataChannel) return;
 
@@ -437,6 +588,30 @@ export class WebRTCStream {
     else console.log("Data channel not open or not established.");
   }
 
+  // Send a chat message to everyone in the room, relay stamps sender identity
+  public sendChat(text: string, senderName: string = "") {
+    const chatMsg = createMessage(
+      create(ProtoChatMessageSchema, {
+        text: text,
+        senderName: senderName,
+      }),
+      "chat",
+    );
+    this.sendBinary(toBinary(ProtoMessageSchema, chatMsg));
+  }
+
+  // Switch to another quality variant of the room without renegotiation, empty variant is the main stream.
+  // Relay answers with "variant-switched" or "variant-switch-failed" over the data channel
+  public switchVariant(variant: string = "") {
+    const switchMsg = createMessage(
+      create(ProtoVariantSwitchSchema, {
+        variant: variant,
+      }),
+      "switch-variant",
+    );
+    this.sendBinary(toBinary(ProtoMessageSchema, switchMsg));
+  }
+
   public disconnect() {
     this._clearConnectionTimer();
     this._cleanupPeerConnection();
@@ -0,0 +1,403 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const usage = `Usage: relayctl [flags] <command> [args]
+
+Commands:
+  rooms list                              List local rooms
+  rooms get <room>                        Show room with participants
+  rooms close <room>                      Close room, disconnecting viewers
+  peers list                              List known mesh peers
+  peers connect <multiaddr>               Connect to a relay
+  peers disconnect <peer-id>              Disconnect a relay
+  peers latencies                         Show round trip times between mesh relays
+  directory <peer-id> [prefix]            List public rooms a mesh relay knows, queried over the mesh
+  participant kick <room> <id> [reason]   Kick a participant, its session can't be resumed
+  participant kick-all <room>             Kick all participants of room
+  participant move <room> <to-room>       Move all participants to another room
+  participant notify <room> <text>        Send a notice to all participants of room
+  participant input <room> <id> on|off    Allow or block input of a participant
+  bans list <room>                        List active bans of room
+  bans add <room> <duration> participant|session|peer <id> [reason]
+                                          Ban from room, gossiped to mesh relays
+  bans lift <room> <ban-id>               Lift a ban early
+  jobs list                               List bulk operation jobs
+  jobs get <id>                           Show bulk operation progress
+  drain [-off] [-wait] [-timeout d]       Stop accepting viewers and pushes
+  peerstore save                          Persist peer store to disk
+  peerstore export [file]                 Export peer store as JSON, printed if no file is given
+  peerstore import <file>                 Merge peers of a JSON peer store file into peer store
+  snapshot save                           Save relay state snapshot on relay, restored with -restoreSnapshot
+  snapshot get [file]                     Download relay state snapshot, printed if no file is given
+  usage                                   Show cumulative stream usage
+  config reload                           Reload config file without restarting
+
+Flags:
+`
+
+// client talks to relay admin API
+type client struct {
+	base  string
+	token string
+	http  *http.Client
+}
+
+func main() {
+	addr := flag.String("addr", getEnv("RELAYCTL_ADDR", "http://127.0.0.1:8090"), "Relay admin API address")
+	token := flag.String("token", getEnv("RELAYCTL_TOKEN", os.Getenv("ADMIN_TOKEN")), "Relay admin API token")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	c := &client{
+		base:  strings.TrimSuffix(*addr, "/"),
+		token: *token,
+		http:  &http.Client{Timeout: 30 * time.Second},
+	}
+	if err := c.run(flag.Arg(0), flag.Args()[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "relayctl:", err)
+		os.Exit(1)
+	}
+}
+
+func getEnv(key, def string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return def
+}
+
+var errUsage = errors.New("invalid arguments, see relayctl -h")
+
+func (c *client) run(cmd string, args []string) error {
+	sub := ""
+	if len(args) > 0 {
+		sub, args = args[0], args[1:]
+	}
+	path := url.PathEscape
+
+	switch {
+	case cmd == "rooms" && sub == "list":
+		return c.listRooms()
+	case cmd == "rooms" && sub == "get" && len(args) == 1:
+		return c.printJSON(http.MethodGet, "/admin/rooms/"+path(args[0]), nil)
+	case cmd == "rooms" && sub == "close" && len(args) == 1:
+		return c.do(http.MethodDelete, "/admin/rooms/"+path(args[0]), nil, nil)
+	case cmd == "peers" && sub == "list":
+		return c.listPeers()
+	case cmd == "peers" && sub == "connect" && len(args) == 1:
+		return c.do(http.MethodPost, "/admin/peers", map[string]string{"addr": args[0]}, nil)
+	case cmd == "peers" && sub == "disconnect" && len(args) == 1:
+		return c.do(http.MethodDelete, "/admin/peers/"+path(args[0]), nil, nil)
+	case cmd == "peers" && sub == "latencies":
+		return c.printJSON(http.MethodGet, "/admin/peers/latencies", nil)
+	case cmd == "directory" && len(sub) > 0 && len(args) <= 1:
+		prefix := ""
+		if len(args) > 0 {
+			prefix = args[0]
+		}
+		return c.listDirectory(sub, prefix)
+	case cmd == "participant" && sub == "kick" && len(args) >= 2:
+		query := ""
+		if len(args) > 2 {
+			query = "?reason=" + url.QueryEscape(strings.Join(args[2:], " "))
+		}
+		return c.do(http.MethodDelete, "/admin/rooms/"+path(args[0])+"/participants/"+path(args[1])+query, nil, nil)
+	case cmd == "participant" && sub == "kick-all" && len(args) == 1:
+		return c.printJSON(http.MethodPost, "/admin/rooms/"+path(args[0])+"/participants/kick", struct{}{})
+	case cmd == "participant" && sub == "move" && len(args) == 2:
+		return c.printJSON(http.MethodPost, "/admin/rooms/"+path(args[0])+"/participants/move", map[string]string{"to": args[1]})
+	case cmd == "participant" && sub == "notify" && len(args) >= 2:
+		return c.printJSON(http.MethodPost, "/admin/rooms/"+path(args[0])+"/participants/notify", map[string]string{"text": strings.Join(args[1:], " ")})
+	case cmd == "participant" && sub == "input" && len(args) == 3:
+		if args[2] != "on" && args[2] != "off" {
+			return errUsage
+		}
+		return c.do(http.MethodPut, "/admin/rooms/"+path(args[0])+"/participants/"+path(args[1])+"/input", map[string]bool{"allowed": args[2] == "on"}, nil)
+	case cmd == "bans" && sub == "list" && len(args) == 1:
+		return c.printJSON(http.MethodGet, "/admin/rooms/"+path(args[0])+"/bans", nil)
+	case cmd == "bans" && sub == "add" && len(args) >= 4:
+		return c.ban(args[0], args[1], args[2], args[3], strings.Join(args[4:], " "))
+	case cmd == "bans" && sub == "lift" && len(args) == 2:
+		return c.do(http.MethodDelete, "/admin/rooms/"+path(args[0])+"/bans/"+path(args[1]), nil, nil)
+	case cmd == "jobs" && sub == "list":
+		return c.printJSON(http.MethodGet, "/admin/jobs", nil)
+	case cmd == "jobs" && sub == "get" && len(args) == 1:
+		return c.printJSON(http.MethodGet, "/admin/jobs/"+path(args[0]), nil)
+	case cmd == "drain":
+		if len(sub) > 0 {
+			args = append([]string{sub}, args...)
+		}
+		return c.drain(args)
+	case cmd == "peerstore" && sub == "save":
+		return c.do(http.MethodPost, "/admin/peerstore/save", nil, nil)
+	case cmd == "peerstore" && sub == "export" && len(args) <= 1:
+		if len(args) == 0 {
+			return c.printJSON(http.MethodGet, "/admin/peerstore", nil)
+		}
+		return c.saveJSON(http.MethodGet, "/admin/peerstore", args[0])
+	case cmd == "peerstore" && sub == "import" && len(args) == 1:
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+		return c.printJSON(http.MethodPost, "/admin/peerstore/import", json.RawMessage(data))
+	case cmd == "snapshot" && sub == "save":
+		return c.printJSON(http.MethodPost, "/admin/snapshot", nil)
+	case cmd == "snapshot" && sub == "get" && len(args) <= 1:
+		if len(args) == 0 {
+			return c.printJSON(http.MethodGet, "/admin/snapshot", nil)
+		}
+		return c.saveJSON(http.MethodGet, "/admin/snapshot", args[0])
+	case cmd == "usage":
+		return c.printJSON(http.MethodGet, "/admin/usage", nil)
+	case cmd == "config" && sub == "reload":
+		return c.printJSON(http.MethodPost, "/admin/config/reload", nil)
+	}
+	return errUsage
+}
+
+// do sends a request with optional JSON body, decoding JSON response into out if given
+func (c *client) do(method, path string, body, out any) error {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.base+path, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&apiErr) == nil && len(apiErr.Error) > 0 {
+			return fmt.Errorf("%s: %s", resp.Status, apiErr.Error)
+		}
+		return errors.New(resp.Status)
+	}
+	if out != nil && resp.StatusCode != http.StatusNoContent {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+func (c *client) printJSON(method, path string, body any) error {
+	var out json.RawMessage
+	if err := c.do(method, path, body, &out); err != nil {
+		return err
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, out, "", "  "); err != nil {
+		return err
+	}
+	fmt.Println(indented.String())
+	return nil
+}
+
+// saveJSON writes JSON response to a file, as the relay sent it
+func (c *client) saveJSON(method, path, file string) error {
+	var out json.RawMessage
+	if err := c.do(method, path, nil, &out); err != nil {
+		return err
+	}
+	if err := os.WriteFile(file, out, 0600); err != nil {
+		return err
+	}
+	fmt.Println("Saved to", file)
+	return nil
+}
+
+func (c *client) listRooms() error {
+	var rooms []struct {
+		Name       string        `json:"name"`
+		OwnerID    string        `json:"owner_id"`
+		Online     bool          `json:"online"`
+		Viewers    int           `json:"viewers"`
+		UpstreamID string        `json:"upstream_id"`
+		HopLatency time.Duration `json:"hop_latency"`
+		Metadata   struct {
+			Title   string `json:"title"`
+			Game    string `json:"game"`
+			Private bool   `json:"private"`
+		} `json:"metadata"`
+	}
+	if err := c.do(http.MethodGet, "/admin/rooms", nil, &rooms); err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tONLINE\tVIEWERS\tHOP LATENCY\tOWNER\tUPSTREAM\tTITLE\tGAME\tPRIVATE")
+	for _, room := range rooms {
+		fmt.Fprintf(tw, "%s\t%t\t%d\t%s\t%s\t%s\t%s\t%s\t%t\n", room.Name, room.Online, room.Viewers, room.HopLatency, room.OwnerID, orDash(room.UpstreamID),
+			orDash(room.Metadata.Title), orDash(room.Metadata.Game), room.Metadata.Private)
+	}
+	return tw.Flush()
+}
+
+func (c *client) listPeers() error {
+	var peers []struct {
+		ID           string        `json:"id"`
+		Connected    bool          `json:"connected"`
+		Latency      time.Duration `json:"latency"`
+		LastSeen     time.Time     `json:"last_seen"`
+		DialFailures int           `json:"dial_failures"`
+		Region       string        `json:"region"`
+		Zone         string        `json:"zone"`
+	}
+	if err := c.do(http.MethodGet, "/admin/peers", nil, &peers); err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tCONNECTED\tLATENCY\tLAST SEEN\tDIAL FAILURES\tREGION\tZONE")
+	for _, p := range peers {
+		lastSeen := "-"
+		if !p.LastSeen.IsZero() {
+			lastSeen = time.Since(p.LastSeen).Round(time.Second).String() + " ago"
+		}
+		fmt.Fprintf(tw, "%s\t%t\t%s\t%s\t%d\t%s\t%s\n", p.ID, p.Connected, p.Latency, lastSeen, p.DialFailures, orDash(p.Region), orDash(p.Zone))
+	}
+	return tw.Flush()
+}
+
+// listDirectory pages through rooms a mesh relay answers directory queries with
+func (c *client) listDirectory(peerID, prefix string) error {
+	type directoryPage struct {
+		Rooms []struct {
+			Name     string `json:"name"`
+			OwnerID  string `json:"owner_id"`
+			Viewers  int    `json:"viewers"`
+			Online   bool   `json:"online"`
+			Metadata struct {
+				Title string `json:"title"`
+				Game  string `json:"game"`
+			} `json:"metadata"`
+		} `json:"rooms"`
+		NextCursor string `json:"next_cursor"`
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tONLINE\tVIEWERS\tOWNER\tTITLE\tGAME")
+	cursor := ""
+	for {
+		query := url.Values{"prefix": {prefix}, "cursor": {cursor}}
+		var page directoryPage
+		if err := c.do(http.MethodGet, "/admin/peers/"+url.PathEscape(peerID)+"/directory?"+query.Encode(), nil, &page); err != nil {
+			return err
+		}
+		for _, room := range page.Rooms {
+			fmt.Fprintf(tw, "%s\t%t\t%d\t%s\t%s\t%s\n", room.Name, room.Online, room.Viewers, room.OwnerID,
+				orDash(room.Metadata.Title), orDash(room.Metadata.Game))
+		}
+		if len(page.NextCursor) <= 0 {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	return tw.Flush()
+}
+
+type drainStatus struct {
+	Draining     bool `json:"draining"`
+	Rooms        int  `json:"rooms"`
+	Participants int  `json:"participants"`
+}
+
+// drain toggles drain mode, optionally waiting for viewers to leave
+func (c *client) drain(args []string) error {
+	fs := flag.NewFlagSet("drain", flag.ContinueOnError)
+	off := fs.Bool("off", false, "Leave drain mode")
+	wait := fs.Bool("wait", false, "Wait until all participants have left")
+	timeout := fs.Duration("timeout", 0, "Give up waiting after this long, 0 waits forever")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	var status drainStatus
+	if err := c.do(http.MethodPost, "/admin/drain", map[string]bool{"draining": !*off}, &status); err != nil {
+		return err
+	}
+	fmt.Printf("draining=%t rooms=%d participants=%d\n", status.Draining, status.Rooms, status.Participants)
+	if *off || !*wait {
+		return nil
+	}
+
+	var deadline time.Time
+	if *timeout > 0 {
+		deadline = time.Now().Add(*timeout)
+	}
+	for status.Participants > 0 {
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return fmt.Errorf("timed out with %d participants remaining", status.Participants)
+		}
+		time.Sleep(2 * time.Second)
+		if err := c.do(http.MethodGet, "/admin/drain", nil, &status); err != nil {
+			return err
+		}
+		fmt.Printf("participants=%d\n", status.Participants)
+	}
+	fmt.Println("drained")
+	return nil
+}
+
+// ban bans a participant, session or peer from a room for given duration
+func (c *client) ban(room, durationStr, kind, id, reason string) error {
+	duration, err := time.ParseDuration(durationStr)
+	if err != nil || duration < time.Second {
+		return fmt.Errorf("invalid ban duration '%s'", durationStr)
+	}
+	body := map[string]any{"duration": int(duration.Seconds()), "reason": reason}
+	switch kind {
+	case "participant":
+		body["participant_id"] = id
+	case "session":
+		body["session_id"] = id
+	case "peer":
+		body["peer_id"] = id
+	default:
+		return errUsage
+	}
+	return c.printJSON(http.MethodPost, "/admin/rooms/"+url.PathEscape(room)+"/bans", body)
+}
+
+func orDash(s string) string {
+	if len(s) <= 0 {
+		return "-"
+	}
+	return s
+}
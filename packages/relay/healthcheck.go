@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"relay/internal/common"
+	"relay/internal/core"
+	"strconv"
+	"time"
+)
+
+// healthcheckTimeout bounds the whole check, container runtimes kill slower checks anyway
+const healthcheckTimeout = 5 * time.Second
+
+// runHealthcheck checks the relay running with the same configuration, for container HEALTHCHECK without curl.
+// Uses the admin health endpoint if admin API is enabled, otherwise checks the TCP listen port accepts connections.
+func runHealthcheck() int {
+	flags := common.GetFlags()
+	if err := checkHealth(flags); err != nil {
+		fmt.Fprintln(os.Stderr, "unhealthy:", err)
+		return 1
+	}
+	return 0
+}
+
+func checkHealth(flags *common.Flags) error {
+	if flags.AdminPort <= 0 {
+		ports, err := core.ListenPortsFromFlags()
+		if err != nil {
+			return err
+		}
+		if len(ports.TCP) <= 0 {
+			return errors.New("no admin API or TCP port to check")
+		}
+		conn, err := net.DialTimeout("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(ports.TCP[0])), healthcheckTimeout)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+
+	client := &http.Client{Timeout: healthcheckTimeout}
+	res, err := client.Get(fmt.Sprintf("http://127.0.0.1:%d/healthz", flags.AdminPort))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("health endpoint returned %s", res.Status)
+	}
+	return nil
+}
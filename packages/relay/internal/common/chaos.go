@@ -0,0 +1,251 @@
+//go:build chaos
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/pion/webrtc/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.yaml.in/yaml/v3"
+)
+
+// Fault injection for testing recovery paths such as mesh reconnection and room cleanup, only built with the chaos tag
+
+// Fault actions of a chaos scenario
+const (
+	chaosDrop        = "drop"         // Drop signaling messages we send
+	chaosDelay       = "delay"        // Hold signaling messages we send, later ones may overtake them
+	chaosResetStream = "reset-stream" // Reset libp2p streams on schedule
+	chaosKillPC      = "kill-pc"      // Close PeerConnections on schedule
+)
+
+// chaosFault is one fault of a scenario, drop and delay apply to messages sent between after and until,
+// reset-stream and kill-pc fire at after and then every period until until
+type chaosFault struct {
+	Action      string        `yaml:"action"`
+	PayloadType string        `yaml:"payload_type"` // Payload type of messages dropped or delayed, empty matches all
+	Probability float64       `yaml:"probability"`  // Chance a matching message is dropped or delayed, 0 is always
+	Delay       time.Duration `yaml:"delay"`        // How long delayed messages are held
+	Protocol    string        `yaml:"protocol"`     // Protocol ID of streams reset, without version it matches all versions, empty matches all
+	Count       int           `yaml:"count"`        // Streams reset or PeerConnections closed each time, 0 is all
+	After       time.Duration `yaml:"after"`        // Time since start the fault begins at
+	Until       time.Duration `yaml:"until"`        // Time since start the fault ends at, 0 never ends
+	Every       time.Duration `yaml:"every"`        // Period scheduled faults repeat at, 0 fires once
+}
+
+// chaosScenario is the scenario file, a seed makes which messages and connections are hit reproducible
+type chaosScenario struct {
+	Seed   uint64       `yaml:"seed"`
+	Faults []chaosFault `yaml:"faults"`
+}
+
+var chaosFaultsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "nestri_relay_chaos_faults_total",
+	Help: "Faults injected from chaos scenario",
+}, []string{"action"})
+
+var chaos struct {
+	mtx     sync.Mutex
+	started time.Time
+	faults  []chaosFault // Message faults, scheduled ones run on their own goroutine
+	rand    *rand.Rand
+	pcs     map[*webrtc.PeerConnection]struct{}
+}
+
+func loadChaosScenario(path string) (*chaosScenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var scenario chaosScenario
+	if err = yaml.Unmarshal(data, &scenario); err != nil {
+		return nil, err
+	}
+	for i, fault := range scenario.Faults {
+		switch fault.Action {
+		case chaosDrop, chaosResetStream, chaosKillPC:
+		case chaosDelay:
+			if fault.Delay <= 0 {
+				return nil, fmt.Errorf("fault %d: delay must be positive", i)
+			}
+		default:
+			return nil, fmt.Errorf("fault %d: unknown action %q", i, fault.Action)
+		}
+		if fault.Probability < 0 || fault.Probability > 1 {
+			return nil, fmt.Errorf("fault %d: probability must be between 0 and 1", i)
+		}
+		if fault.Until > 0 && fault.Until <= fault.After {
+			return nil, fmt.Errorf("fault %d: until must be after after", i)
+		}
+	}
+	return &scenario, nil
+}
+
+// StartChaos runs faults of the scenario file at path against this relay until ctx is done, empty path does nothing
+func StartChaos(ctx context.Context, h host.Host, path string) error {
+	if len(path) <= 0 {
+		return nil
+	}
+	scenario, err := loadChaosScenario(path)
+	if err != nil {
+		return fmt.Errorf("failed to load chaos scenario: %w", err)
+	}
+	seed := scenario.Seed
+	if seed == 0 {
+		seed = uint64(time.Now().UnixNano())
+	}
+	prometheus.MustRegister(chaosFaultsCounter)
+
+	chaos.mtx.Lock()
+	chaos.started = time.Now()
+	chaos.rand = rand.New(rand.NewPCG(seed, seed))
+	for _, fault := range scenario.Faults {
+		switch fault.Action {
+		case chaosDrop, chaosDelay:
+			chaos.faults = append(chaos.faults, fault)
+		default:
+			go runChaosFault(ctx, h, fault)
+		}
+	}
+	chaos.mtx.Unlock()
+
+	slog.Warn("Injecting faults from chaos scenario", "file", path, "faults", len(scenario.Faults), "seed", seed)
+	return nil
+}
+
+// chaosActive returns true if fault applies at elapsed time since start
+func chaosActive(fault chaosFault, elapsed time.Duration) bool {
+	return elapsed >= fault.After && (fault.Until <= 0 || elapsed < fault.Until)
+}
+
+// chaosMessage applies message faults to a signaling message about to be sent, returns false if it's dropped
+func chaosMessage(payloadType string) bool {
+	chaos.mtx.Lock()
+	if chaos.rand == nil {
+		chaos.mtx.Unlock()
+		return true
+	}
+	elapsed := time.Since(chaos.started)
+	var delay time.Duration
+	for _, fault := range chaos.faults {
+		if len(fault.PayloadType) > 0 && fault.PayloadType != payloadType {
+			continue
+		}
+		if !chaosActive(fault, elapsed) || (fault.Probability > 0 && chaos.rand.Float64() >= fault.Probability) {
+			continue
+		}
+		if fault.Action == chaosDrop {
+			chaos.mtx.Unlock()
+			chaosFaultsCounter.WithLabelValues(chaosDrop).Inc()
+			slog.Warn("Chaos dropping message", "payload_type", payloadType)
+			return false
+		}
+		delay += fault.Delay
+	}
+	chaos.mtx.Unlock()
+
+	if delay > 0 {
+		chaosFaultsCounter.WithLabelValues(chaosDelay).Inc()
+		slog.Warn("Chaos delaying message", "payload_type", payloadType, "delay", delay)
+		time.Sleep(delay)
+	}
+	return true
+}
+
+// chaosTrackPeerConnection makes pc a candidate for kill-pc faults, forgetting closed ones
+func chaosTrackPeerConnection(pc *webrtc.PeerConnection) {
+	chaos.mtx.Lock()
+	defer chaos.mtx.Unlock()
+	if chaos.pcs == nil {
+		chaos.pcs = make(map[*webrtc.PeerConnection]struct{})
+	}
+	for tracked := range chaos.pcs {
+		if tracked.ConnectionState() == webrtc.PeerConnectionStateClosed {
+			delete(chaos.pcs, tracked)
+		}
+	}
+	chaos.pcs[pc] = struct{}{}
+}
+
+func runChaosFault(ctx context.Context, h host.Host, fault chaosFault) {
+	timer := time.NewTimer(fault.After)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		switch fault.Action {
+		case chaosResetStream:
+			chaosResetStreams(h, fault)
+		case chaosKillPC:
+			chaosKillPeerConnections(fault)
+		}
+
+		chaos.mtx.Lock()
+		elapsed := time.Since(chaos.started)
+		chaos.mtx.Unlock()
+		if fault.Every <= 0 || !chaosActive(fault, elapsed+fault.Every) {
+			return
+		}
+		timer.Reset(fault.Every)
+	}
+}
+
+// chaosPick shuffles candidates and returns count of them, 0 count returns all
+func chaosPick[T any](candidates []T, count int) []T {
+	chaos.mtx.Lock()
+	chaos.rand.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+	chaos.mtx.Unlock()
+	if count > 0 && count < len(candidates) {
+		return candidates[:count]
+	}
+	return candidates
+}
+
+func chaosResetStreams(h host.Host, fault chaosFault) {
+	var streams []network.Stream
+	for _, conn := range h.Network().Conns() {
+		for _, stream := range conn.GetStreams() {
+			if len(fault.Protocol) <= 0 || string(stream.Protocol()) == fault.Protocol || strings.HasPrefix(string(stream.Protocol()), fault.Protocol+"/") {
+				streams = append(streams, stream)
+			}
+		}
+	}
+	for _, stream := range chaosPick(streams, fault.Count) {
+		slog.Warn("Chaos resetting stream", "protocol", stream.Protocol(), "peer", stream.Conn().RemotePeer())
+		chaosFaultsCounter.WithLabelValues(chaosResetStream).Inc()
+		_ = stream.Reset()
+	}
+}
+
+func chaosKillPeerConnections(fault chaosFault) {
+	var pcs []*webrtc.PeerConnection
+	chaos.mtx.Lock()
+	for pc := range chaos.pcs {
+		if pc.ConnectionState() != webrtc.PeerConnectionStateClosed {
+			pcs = append(pcs, pc)
+		}
+	}
+	chaos.mtx.Unlock()
+	for _, pc := range chaosPick(pcs, fault.Count) {
+		slog.Warn("Chaos closing PeerConnection", "state", pc.ConnectionState())
+		chaosFaultsCounter.WithLabelValues(chaosKillPC).Inc()
+		// Closing runs the close handler of the connection, as for a failed one
+		_ = pc.Close()
+	}
+}
@@ -0,0 +1,23 @@
+//go:build !chaos
+
+package common
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/pion/webrtc/v4"
+)
+
+// StartChaos does nothing, fault injection is only built with the chaos tag
+func StartChaos(_ context.Context, _ host.Host, path string) error {
+	if len(path) > 0 {
+		slog.Warn("Ignoring chaos scenario, relay was built without chaos tag", "file", path)
+	}
+	return nil
+}
+
+func chaosMessage(string) bool { return true }
+
+func chaosTrackPeerConnection(*webrtc.PeerConnection) {}
@@ -5,6 +5,7 @@ import (
 	"github.com/pion/interceptor/pkg/nack"
 	"log/slog"
 	"strconv"
+	"time"
 
 	"github.com/libp2p/go-reuseport"
 	"github.com/pion/ice/v4"
@@ -19,6 +20,14 @@ var globalWebRTCConfig = webrtc.Configuration{
 	SDPSemantics:       webrtc.SDPSemanticsUnifiedPlan,
 }
 
+// mappedWebRTCIP is external IP of a router port mapping of the WebRTC UDP mux port, empty if not mapped
+var mappedWebRTCIP string
+
+// SetMappedWebRTCIP sets external IP the WebRTC UDP mux port is mapped on, must be called before InitWebRTCAPI
+func SetMappedWebRTCIP(ip string) {
+	mappedWebRTCIP = ip
+}
+
 func InitWebRTCAPI() error {
 	var err error
 	flags := GetFlags()
@@ -43,7 +52,7 @@ func InitWebRTCAPI() error {
 		}
 	}
 
-	videoRTCPFeedback := []webrtc.RTCPFeedback{{"nack", ""}, {"nack", "pli"}}
+	videoRTCPFeedback := []webrtc.RTCPFeedback{{Type: "nack"}, {Type: "nack", Parameter: "pli"}}
 	for _, codec := range []webrtc.RTPCodecParameters{
 		{
 			RTPCodecCapability: webrtc.RTPCodecCapability{
@@ -146,6 +155,14 @@ func InitWebRTCAPI() error {
 	if err = webrtc.ConfigureRTCPReports(interceptorRegistry); err != nil {
 		return err
 	}
+	// Fills inbound RTP stats, quality of mesh links is sampled from them
+	if err = webrtc.ConfigureStatsInterceptor(interceptorRegistry); err != nil {
+		return err
+	}
+	// Adds TWCC sequence numbers, only on connections that negotiated it
+	if err = webrtc.ConfigureTWCCHeaderExtensionSender(mediaEngine, interceptorRegistry); err != nil {
+		return err
+	}
 
 	// Setting engine
 	settingEngine := webrtc.SettingEngine{}
@@ -157,6 +174,10 @@ func InitWebRTCAPI() error {
 	if len(nat11IP) > 0 {
 		settingEngine.SetNAT1To1IPs([]string{nat11IP}, webrtc.ICECandidateTypeHost)
 		slog.Info("Using NAT 1:1 IP for WebRTC", "nat11_ip", nat11IP)
+	} else if len(mappedWebRTCIP) > 0 {
+		// Mapped IP is offered next to host candidates, LAN clients keep connecting directly
+		settingEngine.SetNAT1To1IPs([]string{mappedWebRTCIP}, webrtc.ICECandidateTypeSrflx)
+		slog.Info("Using mapped external IP for WebRTC", "ip", mappedWebRTCIP)
 	}
 
 	muxPort := GetFlags().UDPMuxPort
@@ -167,6 +188,14 @@ func InitWebRTCAPI() error {
 			return fmt.Errorf("failed to create WebRTC muxed UDP listener: %w", err)
 		}
 
+		// Fan-out writes queue up and leave in one sendmmsg call, partial batches wait at most the batch delay.
+		// Packets of a frame to one viewer are coalesced into UDP GSO sends where the kernel supports it.
+		if flags.UDPBatchSize > 0 {
+			delay := time.Duration(max(flags.UDPBatchDelay, 1)) * time.Microsecond
+			pktListener = newUDPBatchConn(pktListener, flags.UDPBatchSize, delay)
+			slog.Info("Batching WebRTC UDP mux writes", "size", flags.UDPBatchSize, "delay", delay)
+		}
+
 		mux := ice.NewMultiUDPMuxDefault(ice.NewUDPMuxDefault(ice.UDPMuxParams{
 			UDPConn: pktListener,
 		}))
@@ -194,10 +223,13 @@ func InitWebRTCAPI() error {
 
 // CreatePeerConnection sets up a new peer connection
 func CreatePeerConnection(onClose func()) (*webrtc.PeerConnection, error) {
-	pc, err := globalWebRTCAPI.NewPeerConnection(globalWebRTCConfig)
+	config := globalWebRTCConfig
+	config.ICEServers = GetFlags().ICEServers()
+	pc, err := globalWebRTCAPI.NewPeerConnection(config)
 	if err != nil {
 		return nil, err
 	}
+	chaosTrackPeerConnection(pc)
 
 	// Log connection state changes and handle failed/disconnected connections
 	pc.OnConnectionStateChange(func(connectionState webrtc.PeerConnectionState) {
@@ -0,0 +1,160 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// roomsConfigKey is the config file section holding per-room defaults
+const roomsConfigKey = "rooms"
+
+// RoomConfig holds per-room configuration from config file, settings are used when the pushing node doesn't set them
+type RoomConfig struct {
+	LatencyBudget int    `yaml:"latency_budget"` // End-to-end latency budget in milliseconds, 0 uses relay default
+	StrictLatency bool   `yaml:"strict_latency"` // Drop late video frames for viewers
+	MaxFrameAge   int    `yaml:"max_frame_age"`  // Max video frame age in milliseconds, 0 uses relay default
+	MaxViewers    int    `yaml:"max_viewers"`    // Max participants of this room, 0 uses relay default
+	PushSecret    string `yaml:"push_secret"`    // Secret authenticating pushes of this room, overrides relay-wide push secret
+
+	QueueSize          int    `yaml:"queue_size"`           // Packets queued per viewer, 0 uses relay default
+	QueueHighWatermark int    `yaml:"queue_high_watermark"` // Queue depth at which video starts being dropped by "video" and "keyframe" policies
+	QueueLowWatermark  int    `yaml:"queue_low_watermark"`  // Queue depth at which dropped video resumes
+	DropPolicy         string `yaml:"drop_policy"`          // "newest", "oldest", "video" or "keyframe"
+
+	// Resource budget of the room on this relay, 0 uses relay-wide limits
+	Goroutines int `yaml:"goroutines"` // Goroutines for participants and DataChannel messages
+	Tracks     int `yaml:"tracks"`     // Local tracks allocated for participants
+	QueuedKB   int `yaml:"queued_kb"`  // Kilobytes of packets queued for participants
+	MsgRate    int `yaml:"msg_rate"`   // DataChannel messages handled per second
+}
+
+// fileConfig holds option values from config file, keyed by lowercase environment variable name
+type fileConfig struct {
+	values map[string]string
+	used   map[string]bool
+	rooms  map[string]RoomConfig
+}
+
+var globalConfig *fileConfig
+
+// configPathFromArgs finds the config file flag before flags are parsed, as it provides their defaults
+func configPathFromArgs(args []string) string {
+	path := getEnvAsString("CONFIG_FILE", "")
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		name := strings.TrimLeft(arg, "-")
+		if len(name) == len(arg) {
+			continue
+		}
+		if value, ok := strings.CutPrefix(name, "config="); ok {
+			path = value
+		} else if name == "config" && i+1 < len(args) {
+			path = args[i+1]
+			i++
+		}
+	}
+	return path
+}
+
+// loadConfigFile reads a YAML config file, options use environment variable names in lowercase
+func loadConfigFile(path string) (*fileConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var raw map[string]any
+	if err = yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	var sections struct {
+		Rooms map[string]RoomConfig `yaml:"rooms"`
+	}
+	if err = yaml.Unmarshal(data, &sections); err != nil {
+		return nil, fmt.Errorf("failed to parse rooms in config file: %w", err)
+	}
+
+	cfg := &fileConfig{
+		values: make(map[string]string, len(raw)),
+		used:   make(map[string]bool, len(raw)),
+		rooms:  sections.Rooms,
+	}
+	for key, value := range raw {
+		if key == roomsConfigKey {
+			continue
+		}
+		str, err := configValueString(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid config option '%s': %w", key, err)
+		}
+		cfg.values[strings.ToLower(key)] = str
+	}
+	return cfg, nil
+}
+
+// configValueString converts a config value to its flag string form, lists become comma separated
+func configValueString(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string, bool, int, float64:
+		return fmt.Sprint(v), nil
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			str, err := configValueString(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, str)
+		}
+		return strings.Join(items, ","), nil
+	default:
+		return "", errors.New("expected a value or list of values")
+	}
+}
+
+// lookup returns config file value of option with given environment variable name
+func (cfg *fileConfig) lookup(envName string) (string, bool) {
+	if cfg == nil {
+		return "", false
+	}
+	key := strings.ToLower(envName)
+	cfg.used[key] = true
+	value, ok := cfg.values[key]
+	return value, ok
+}
+
+// unknownKeys returns config options not matching any flag, likely typos
+func (cfg *fileConfig) unknownKeys() []string {
+	unknown := make([]string, 0)
+	for key := range cfg.values {
+		if !cfg.used[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// lookupSetting returns value of an option from environment, falling back to config file
+func lookupSetting(envName string) (string, bool) {
+	if value := os.Getenv(envName); len(value) > 0 {
+		return value, true
+	}
+	return globalConfig.lookup(envName)
+}
+
+// RoomDefaults returns per-room setting defaults from config file for given room
+func (flags *Flags) RoomDefaults(roomName string) (RoomConfig, bool) {
+	cfg, ok := flags.Rooms[roomName]
+	return cfg, ok
+}
@@ -1,14 +1,23 @@
 package common
 
 import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
 	"crypto/ed25519"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/oklog/ulid/v2"
+	"golang.org/x/crypto/scrypt"
 )
 
 func NewULID() (ulid.ULID, error) {
@@ -49,3 +58,251 @@ func LoadED25519Key(filePath string) (ed25519.PrivateKey, error) {
 	}
 	return data, nil
 }
+
+// Encrypted key files are the magic, scrypt salt, AES-GCM nonce and sealed key, in that order
+var encryptedKeyMagic = []byte("NSTRKEY1")
+
+const (
+	encryptedKeySaltSize = 16
+	// scrypt cost, around 100ms and 32MB per derivation
+	encryptedKeyScryptN = 1 << 15
+	encryptedKeyScryptR = 8
+	encryptedKeyScryptP = 1
+)
+
+// ErrKeyDecrypt is returned when an encrypted key file can't be opened with given passphrase
+var ErrKeyDecrypt = errors.New("wrong passphrase or corrupted key file")
+
+// IsEncryptedKeyFile checks whether a key file was saved with SaveEncryptedED25519Key
+func IsEncryptedKeyFile(filePath string) (bool, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read key from %s: %w", filePath, err)
+	}
+	return bytes.HasPrefix(data, encryptedKeyMagic), nil
+}
+
+// SaveEncryptedED25519Key saves an ED25519 private key to a path encrypted with a passphrase,
+// replacing the file atomically so a crash won't lose the key
+func SaveEncryptedED25519Key(privateKey ed25519.PrivateKey, filePath string, passphrase string) error {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return errors.New("private key must be exactly 64 bytes for ED25519")
+	}
+	if len(passphrase) <= 0 {
+		return errors.New("passphrase cannot be empty")
+	}
+
+	salt := make([]byte, encryptedKeySaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return fmt.Errorf("failed to generate salt: %w", err)
+	}
+	aead, err := keyFileCipher(passphrase, salt)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	data := append(append(append([]byte{}, encryptedKeyMagic...), salt...), nonce...)
+	data = aead.Seal(data, nonce, privateKey, encryptedKeyMagic)
+
+	tmpPath := filePath + ".tmp"
+	if err = os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to save encrypted ED25519 key to %s: %w", filePath, err)
+	}
+	if err = os.Rename(tmpPath, filePath); err != nil {
+		return fmt.Errorf("failed to replace ED25519 key file %s: %w", filePath, err)
+	}
+	return nil
+}
+
+// LoadEncryptedED25519Key loads an ED25519 private key file saved with SaveEncryptedED25519Key
+func LoadEncryptedED25519Key(filePath string, passphrase string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ED25519 key from %s: %w", filePath, err)
+	}
+	if !bytes.HasPrefix(data, encryptedKeyMagic) {
+		return nil, fmt.Errorf("%s is not an encrypted key file", filePath)
+	}
+	data = data[len(encryptedKeyMagic):]
+	if len(data) < encryptedKeySaltSize {
+		return nil, ErrKeyDecrypt
+	}
+	salt, data := data[:encryptedKeySaltSize], data[encryptedKeySaltSize:]
+
+	aead, err := keyFileCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, ErrKeyDecrypt
+	}
+	nonce, sealed := data[:aead.NonceSize()], data[aead.NonceSize():]
+	key, err := aead.Open(nil, nonce, sealed, encryptedKeyMagic)
+	if err != nil {
+		return nil, ErrKeyDecrypt
+	}
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("ED25519 key must be exactly %d bytes, got %d", ed25519.PrivateKeySize, len(key))
+	}
+	return key, nil
+}
+
+// keyFileCipher derives the AES-GCM cipher of a key file from passphrase and salt
+func keyFileCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	derived, err := scrypt.Key([]byte(passphrase), salt, encryptedKeyScryptN, encryptedKeyScryptR, encryptedKeyScryptP, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key file cipher: %w", err)
+	}
+	block, err := aes.NewCipher(derived)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// pushSignatureMessage is the message signed to authenticate a stream push
+func pushSignatureMessage(roomName string, timestamp int64) []byte {
+	return []byte(roomName + "\n" + strconv.FormatInt(timestamp, 10))
+}
+
+// SignPush returns hex HMAC-SHA256 of room name and timestamp, authenticating a stream push
+func SignPush(secret, roomName string, timestamp int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(pushSignatureMessage(roomName, timestamp))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyPush checks push signature and that it was made within maxSkew of now
+func VerifyPush(secret, roomName string, timestamp int64, signature string, now time.Time, maxSkew time.Duration) error {
+	if timestamp == 0 || len(signature) <= 0 {
+		return errors.New("push is not signed")
+	}
+	signedAt := time.Unix(timestamp, 0)
+	if signedAt.Before(now.Add(-maxSkew)) || signedAt.After(now.Add(maxSkew)) {
+		return fmt.Errorf("push signature time %s is too far from now", signedAt.UTC().Format(time.RFC3339))
+	}
+	given, err := hex.DecodeString(signature)
+	if err != nil {
+		return errors.New("push signature is not hex encoded")
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(pushSignatureMessage(roomName, timestamp))
+	if !hmac.Equal(given, mac.Sum(nil)) {
+		return errors.New("push signature mismatch")
+	}
+	return nil
+}
+
+// pushChallengeMessage is the message a pushing node signs to prove it holds a push key, bound to the relay so
+// a challenge can't be passed on to another relay
+func pushChallengeMessage(relayID, roomName, nonce string) []byte {
+	return []byte("nestri-push-challenge\n" + relayID + "\n" + roomName + "\n" + nonce)
+}
+
+// ParsePushKey decodes a hex Ed25519 public key trusted to push streams
+func ParsePushKey(publicKey string) (ed25519.PublicKey, error) {
+	key, err := hex.DecodeString(strings.TrimSpace(publicKey))
+	if err != nil {
+		return nil, errors.New("push key is not hex encoded")
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("push key must be exactly %d bytes, got %d", ed25519.PublicKeySize, len(key))
+	}
+	return key, nil
+}
+
+// SignPushChallenge returns hex Ed25519 signature of a push challenge issued by relay for room
+func SignPushChallenge(key ed25519.PrivateKey, relayID, roomName, nonce string) string {
+	return hex.EncodeToString(ed25519.Sign(key, pushChallengeMessage(relayID, roomName, nonce)))
+}
+
+// VerifyPushChallenge checks push challenge signature was made by the hex Ed25519 public key
+func VerifyPushChallenge(publicKey, relayID, roomName, nonce, signature string) error {
+	key, err := ParsePushKey(publicKey)
+	if err != nil {
+		return err
+	}
+	given, err := hex.DecodeString(signature)
+	if err != nil {
+		return errors.New("push challenge signature is not hex encoded")
+	}
+	if !ed25519.Verify(key, pushChallengeMessage(relayID, roomName, nonce), given) {
+		return errors.New("push challenge signature mismatch")
+	}
+	return nil
+}
+
+// meshProofMessage is the message signed to prove mesh membership of a relay
+func meshProofMessage(peerID string) []byte {
+	return []byte("nestri-mesh\n" + peerID)
+}
+
+// SignMeshPeer returns hex HMAC-SHA256 of peer ID, proving the relay knows the mesh secret. Relay status is
+// published signed by the relay's own key, so the proof can't be replayed by other peers.
+func SignMeshPeer(secret, peerID string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(meshProofMessage(peerID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyMeshPeer checks mesh membership proof of peer ID
+func VerifyMeshPeer(secret, peerID, proof string) bool {
+	given, err := hex.DecodeString(proof)
+	if err != nil || len(given) <= 0 {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(meshProofMessage(peerID))
+	return hmac.Equal(given, mac.Sum(nil))
+}
+
+// Room access hashes are "scrypt$<salt>$<key>" in hex, they are gossiped so every relay of the mesh can check viewers
+const (
+	accessHashPrefix   = "scrypt$"
+	accessHashSaltSize = 16
+	// Derived on every access attempt, cheaper than key files but still slow to brute force (around 50ms and 16MB)
+	accessHashScryptN = 1 << 14
+)
+
+// accessHashKey derives key of a room access secret, bound to room name so hashes can't be moved between rooms
+func accessHashKey(roomName, secret string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte("nestri-room-access\n"+roomName+"\n"+secret), salt, accessHashScryptN, encryptedKeyScryptR, encryptedKeyScryptP, 32)
+}
+
+// HashRoomAccess hashes room access secret with a random salt
+func HashRoomAccess(roomName, secret string) (string, error) {
+	salt := make([]byte, accessHashSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+	key, err := accessHashKey(roomName, secret, salt)
+	if err != nil {
+		return "", fmt.Errorf("failed to derive access hash: %w", err)
+	}
+	return accessHashPrefix + hex.EncodeToString(salt) + "$" + hex.EncodeToString(key), nil
+}
+
+// VerifyRoomAccess checks secret against access hash of room, hashes of unknown format never match
+func VerifyRoomAccess(roomName, secret, hash string) bool {
+	saltHex, keyHex, ok := strings.Cut(strings.TrimPrefix(hash, accessHashPrefix), "$")
+	if !ok || !strings.HasPrefix(hash, accessHashPrefix) {
+		return false
+	}
+	salt, err := hex.DecodeString(saltHex)
+	if err != nil || len(salt) <= 0 {
+		return false
+	}
+	want, err := hex.DecodeString(keyHex)
+	if err != nil {
+		return false
+	}
+	key, err := accessHashKey(roomName, secret, salt)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(key, want)
+}
@@ -1,45 +1,135 @@
 package common
 
-import "github.com/pion/webrtc/v4"
+import (
+	"github.com/pion/rtp"
+	"github.com/pion/sdp/v3"
+	"github.com/pion/webrtc/v4"
+)
 
 const (
-	ExtensionPlayoutDelay string = "http://www.webrtc.org/experiments/rtp-hdrext/playout-delay"
+	ExtensionPlayoutDelay   string = "http://www.webrtc.org/experiments/rtp-hdrext/playout-delay"
+	ExtensionAbsCaptureTime string = "http://www.webrtc.org/experiments/rtp-hdrext/abs-capture-time"
+	ExtensionAbsSendTime    string = "http://www.webrtc.org/experiments/rtp-hdrext/abs-send-time"
+	ExtensionTWCC           string = "http://www.ietf.org/id/draft-holmer-rmcat-transport-wide-cc-extensions-01"
+	ExtensionAudioLevel     string = "urn:ietf:params:rtp-hdrext:ssrc-audio-level"
 )
 
-// ExtensionMap maps audio/video extension URIs to their IDs based on registration order
-var ExtensionMap = map[webrtc.RTPCodecType]map[string]uint8{}
+// PlayoutDelayPayload is the marshalled zero playout-delay extension, asking clients to render immediately
+var PlayoutDelayPayload, _ = (&rtp.PlayoutDelayExtension{MinDelay: 0, MaxDelay: 0}).Marshal()
 
 func RegisterExtensions(mediaEngine *webrtc.MediaEngine) error {
-	// Register additional header extensions to reduce latency
-	// Playout Delay (Video)
-	if err := mediaEngine.RegisterHeaderExtension(webrtc.RTPHeaderExtensionCapability{
-		URI: ExtensionPlayoutDelay,
-	}, webrtc.RTPCodecTypeVideo); err != nil {
-		return err
+	// Register additional header extensions to reduce latency and let receivers estimate bandwidth,
+	// these are only offered, IDs are negotiated per connection. Transport-wide CC is registered with its interceptor.
+	for _, uri := range []string{ExtensionPlayoutDelay, ExtensionAbsCaptureTime, ExtensionAbsSendTime} {
+		for _, codecType := range []webrtc.RTPCodecType{webrtc.RTPCodecTypeVideo, webrtc.RTPCodecTypeAudio} {
+			if err := mediaEngine.RegisterHeaderExtension(webrtc.RTPHeaderExtensionCapability{
+				URI: uri,
+			}, codecType); err != nil {
+				return err
+			}
+		}
 	}
-	// Playout Delay (Audio)
-	if err := mediaEngine.RegisterHeaderExtension(webrtc.RTPHeaderExtensionCapability{
-		URI: ExtensionPlayoutDelay,
-	}, webrtc.RTPCodecTypeAudio); err != nil {
-		return err
+	// Audio levels are read from ingest and forwarded, so viewers can tell who is making noise without decoding
+	return mediaEngine.RegisterHeaderExtension(webrtc.RTPHeaderExtensionCapability{
+		URI: ExtensionAudioLevel,
+	}, webrtc.RTPCodecTypeAudio)
+}
+
+// NegotiatedExtensions holds the header extension IDs a connection negotiated for one track kind, 0 if not negotiated
+type NegotiatedExtensions struct {
+	PlayoutDelay   uint8
+	AbsCaptureTime uint8
+	AbsSendTime    uint8
+	AudioLevel     uint8
+	TWCC           uint8 // Stamped by the TWCC interceptor, not by us
+}
+
+// Any returns true if any extension was negotiated
+func (e NegotiatedExtensions) Any() bool {
+	return e.PlayoutDelay != 0 || e.AbsCaptureTime != 0 || e.AbsSendTime != 0 || e.AudioLevel != 0 || e.TWCC != 0
+}
+
+// set records ID of a known extension URI, others are ignored
+func (e *NegotiatedExtensions) set(uri string, id int) {
+	switch uri {
+	case ExtensionPlayoutDelay:
+		e.PlayoutDelay = uint8(id)
+	case ExtensionAbsCaptureTime:
+		e.AbsCaptureTime = uint8(id)
+	case ExtensionAbsSendTime:
+		e.AbsSendTime = uint8(id)
+	case ExtensionAudioLevel:
+		e.AudioLevel = uint8(id)
+	case ExtensionTWCC:
+		e.TWCC = uint8(id)
 	}
+}
 
-	// Register the extension IDs for both audio and video
-	ExtensionMap[webrtc.RTPCodecTypeAudio] = map[string]uint8{
-		ExtensionPlayoutDelay: 1,
+// GetNegotiatedExtensions returns header extensions negotiated for given sender of pc. IDs come from the
+// media section of the sender in the remote description, so every connection uses the mapping its peer
+// answered with. Senders without a negotiated media section fall back to what pion negotiated for pc.
+func GetNegotiatedExtensions(pc *webrtc.PeerConnection, sender *webrtc.RTPSender) NegotiatedExtensions {
+	var exts NegotiatedExtensions
+	if sender == nil {
+		return exts
+	}
+	if pc != nil {
+		if remote := pc.CurrentRemoteDescription(); remote != nil {
+			for _, transceiver := range pc.GetTransceivers() {
+				if transceiver.Sender() != sender || len(transceiver.Mid()) <= 0 {
+					continue
+				}
+				if parsed, ok := ParseNegotiatedExtensions(remote.SDP, transceiver.Mid()); ok {
+					return parsed
+				}
+			}
+		}
 	}
-	ExtensionMap[webrtc.RTPCodecTypeVideo] = map[string]uint8{
-		ExtensionPlayoutDelay: 1,
+	for _, ext := range sender.GetParameters().HeaderExtensions {
+		exts.set(ext.URI, ext.ID)
 	}
+	return exts
+}
 
-	return nil
+// GetReceivedExtensions returns header extensions negotiated for packets read from given receiver
+func GetReceivedExtensions(receiver *webrtc.RTPReceiver) NegotiatedExtensions {
+	var exts NegotiatedExtensions
+	if receiver == nil {
+		return exts
+	}
+	for _, ext := range receiver.GetParameters().HeaderExtensions {
+		exts.set(ext.URI, ext.ID)
+	}
+	return exts
 }
 
-func GetExtension(codecType webrtc.RTPCodecType, extURI string) (uint8, bool) {
-	cType, ok := ExtensionMap[codecType]
-	if !ok {
-		return 0, false
+// ParseNegotiatedExtensions returns header extensions mapped in the media section of mid of an SDP,
+// false if there is no such section. Extensions the peer only sends are left out, we mustn't send them.
+func ParseNegotiatedExtensions(desc, mid string) (NegotiatedExtensions, bool) {
+	var exts NegotiatedExtensions
+	var parsed sdp.SessionDescription
+	err := parsed.UnmarshalString(desc)
+	if err != nil {
+		return exts, false
+	}
+	for _, media := range parsed.MediaDescriptions {
+		if value, ok := media.Attribute(sdp.AttrKeyMID); !ok || value != mid {
+			continue
+		}
+		for _, attr := range media.Attributes {
+			if attr.Key != sdp.AttrKeyExtMap {
+				continue
+			}
+			var extMap sdp.ExtMap
+			if err = extMap.Unmarshal(attr.String()); err != nil || extMap.URI == nil {
+				continue
+			}
+			if extMap.Direction == sdp.DirectionSendOnly || extMap.Direction == sdp.DirectionInactive {
+				continue
+			}
+			exts.set(extMap.URI.String(), extMap.Value)
+		}
+		return exts, true
 	}
-	extID, ok := cType[extURI]
-	return extID, ok
+	return exts, false
 }
@@ -0,0 +1,49 @@
+package common
+
+import (
+	"strings"
+	"testing"
+)
+
+// answerSDP is an answer mapping extensions differently per media section, as browsers may
+var answerSDP = strings.Join([]string{
+	"v=0",
+	"o=- 1 1 IN IP4 0.0.0.0",
+	"s=-",
+	"t=0 0",
+	"m=audio 9 UDP/TLS/RTP/SAVPF 111",
+	"c=IN IP4 0.0.0.0",
+	"a=mid:0",
+	"a=recvonly",
+	"a=extmap:4 " + ExtensionAbsCaptureTime,
+	"a=extmap:5 " + ExtensionAudioLevel,
+	"a=rtpmap:111 opus/48000/2",
+	"m=video 9 UDP/TLS/RTP/SAVPF 96",
+	"c=IN IP4 0.0.0.0",
+	"a=mid:1",
+	"a=recvonly",
+	"a=extmap:6 " + ExtensionPlayoutDelay,
+	"a=extmap:7/sendonly " + ExtensionAbsCaptureTime,
+	"a=extmap:8 " + ExtensionTWCC,
+	"a=extmap:9 " + ExtensionAbsSendTime,
+	"a=rtpmap:96 H264/90000",
+	"",
+}, "\r\n")
+
+func TestParseNegotiatedExtensions(t *testing.T) {
+	tests := []struct {
+		mid  string
+		want NegotiatedExtensions
+		ok   bool
+	}{
+		{mid: "0", want: NegotiatedExtensions{AbsCaptureTime: 4, AudioLevel: 5}, ok: true},
+		{mid: "1", want: NegotiatedExtensions{PlayoutDelay: 6, AbsSendTime: 9, TWCC: 8}, ok: true},
+		{mid: "2"},
+	}
+	for _, tt := range tests {
+		got, ok := ParseNegotiatedExtensions(answerSDP, tt.mid)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("mid %s: got %+v %t, want %+v %t", tt.mid, got, ok, tt.want, tt.ok)
+		}
+	}
+}
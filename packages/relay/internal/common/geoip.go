@@ -0,0 +1,139 @@
+package common
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/netip"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// --- GeoIP ---
+//
+// GeoIP databases are CSV files with a header row naming network, latitude and longitude columns and optionally
+// a region column, so GeoLite2 City blocks CSV can be used as is. Networks must not overlap.
+
+// earthRadiusKm is mean radius of Earth used for distances between locations
+const earthRadiusKm = 6371.0
+
+// GeoLocation is where an address is, as told by a GeoIP database
+type GeoLocation struct {
+	Region    string  `json:"region,omitempty"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// DistanceKm returns great-circle distance to another location in kilometers
+func (l GeoLocation) DistanceKm(other GeoLocation) float64 {
+	lat1, lat2 := l.Latitude*math.Pi/180, other.Latitude*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (other.Longitude - l.Longitude) * math.Pi / 180
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// geoNetwork is a network of a GeoIP database and its location
+type geoNetwork struct {
+	prefix   netip.Prefix
+	location GeoLocation
+}
+
+// GeoIP locates addresses, networks are sorted by first address for lookups
+type GeoIP struct {
+	networks []geoNetwork
+}
+
+// LoadGeoIP reads a CSV GeoIP database, rows without coordinates are skipped
+func LoadGeoIP(path string) (*GeoIP, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GeoIP database: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	return parseGeoIP(file)
+}
+
+func parseGeoIP(r io.Reader) (*GeoIP, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GeoIP database header: %w", err)
+	}
+	columns := map[string]int{"region": -1}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	networkCol, okNetwork := columns["network"]
+	latCol, okLat := columns["latitude"]
+	lonCol, okLon := columns["longitude"]
+	if !okNetwork || !okLat || !okLon {
+		return nil, errors.New("GeoIP database needs network, latitude and longitude columns")
+	}
+	regionCol := columns["region"]
+
+	geoIP := &GeoIP{}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read GeoIP database line %d: %w", line, err)
+		}
+		field := func(col int) string {
+			if col < 0 || col >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[col])
+		}
+		if len(field(latCol)) <= 0 || len(field(lonCol)) <= 0 {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(field(networkCol))
+		if err != nil {
+			return nil, fmt.Errorf("invalid network on GeoIP database line %d: %w", line, err)
+		}
+		lat, latErr := strconv.ParseFloat(field(latCol), 64)
+		lon, lonErr := strconv.ParseFloat(field(lonCol), 64)
+		if latErr != nil || lonErr != nil {
+			return nil, fmt.Errorf("invalid coordinates on GeoIP database line %d", line)
+		}
+		geoIP.networks = append(geoIP.networks, geoNetwork{
+			prefix:   prefix.Masked(),
+			location: GeoLocation{Region: field(regionCol), Latitude: lat, Longitude: lon},
+		})
+	}
+	slices.SortFunc(geoIP.networks, func(a, b geoNetwork) int {
+		return a.prefix.Addr().Compare(b.prefix.Addr())
+	})
+	return geoIP, nil
+}
+
+// Len returns count of networks in the database
+func (g *GeoIP) Len() int {
+	return len(g.networks)
+}
+
+// Lookup returns location of an address, false if no network of the database contains it
+func (g *GeoIP) Lookup(addr netip.Addr) (GeoLocation, bool) {
+	addr = addr.Unmap()
+	// Last network starting at or before the address is the only one which may contain it
+	i, found := slices.BinarySearchFunc(g.networks, addr, func(n geoNetwork, target netip.Addr) int {
+		return n.prefix.Addr().Compare(target)
+	})
+	if !found {
+		i--
+	}
+	if i < 0 || !g.networks[i].prefix.Contains(addr) {
+		return GeoLocation{}, false
+	}
+	return g.networks[i].location, true
+}
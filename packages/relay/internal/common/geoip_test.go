@@ -0,0 +1,58 @@
+package common
+
+import (
+	"math"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+// geoIPCSV is a database in GeoLite2 City blocks layout with an extra region column
+var geoIPCSV = strings.Join([]string{
+	"network,geoname_id,latitude,longitude,accuracy_radius,region",
+	"203.0.113.0/24,1,60.1699,24.9384,10,eu-north",
+	"198.51.100.0/24,2,40.7128,-74.0060,10,us-east",
+	"192.0.2.0/24,3,,,,",
+	"2001:db8::/32,4,35.6762,139.6503,10,ap-northeast",
+	"",
+}, "\n")
+
+func TestGeoIPLookup(t *testing.T) {
+	geoIP, err := parseGeoIP(strings.NewReader(geoIPCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if geoIP.Len() != 3 {
+		t.Fatalf("loaded %d networks, want 3 as rows without coordinates are skipped", geoIP.Len())
+	}
+
+	tests := []struct {
+		addr   string
+		region string
+		ok     bool
+	}{
+		{addr: "203.0.113.7", region: "eu-north", ok: true},
+		{addr: "::ffff:198.51.100.200", region: "us-east", ok: true},
+		{addr: "2001:db8::1", region: "ap-northeast", ok: true},
+		{addr: "192.0.2.1"},
+		{addr: "203.0.114.1"},
+		{addr: "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		location, ok := geoIP.Lookup(netip.MustParseAddr(tt.addr))
+		if ok != tt.ok || location.Region != tt.region {
+			t.Errorf("%s: got %q %t, want %q %t", tt.addr, location.Region, ok, tt.region, tt.ok)
+		}
+	}
+}
+
+func TestGeoLocationDistance(t *testing.T) {
+	helsinki := GeoLocation{Latitude: 60.1699, Longitude: 24.9384}
+	newYork := GeoLocation{Latitude: 40.7128, Longitude: -74.0060}
+	if d := helsinki.DistanceKm(newYork); math.Abs(d-6618) > 50 {
+		t.Fatalf("Helsinki to New York is %.0f km, want about 6618 km", d)
+	}
+	if d := helsinki.DistanceKm(helsinki); d != 0 {
+		t.Fatalf("distance to same location is %f km", d)
+	}
+}
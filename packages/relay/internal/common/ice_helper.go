@@ -2,6 +2,7 @@ package common
 
 import (
 	"log/slog"
+	"sync"
 
 	"github.com/pion/webrtc/v4"
 )
@@ -9,6 +10,7 @@ import (
 // ICEHelper holds webrtc.ICECandidateInit(s) until remote candidate is set for given webrtc.PeerConnection
 // Held candidates should be flushed at the end of negotiation to ensure all are available for connection
 type ICEHelper struct {
+	mtx        sync.Mutex // Negotiation may continue outside the signaling loop, like for requests waiting on a room
 	candidates []webrtc.ICECandidateInit
 	pc         *webrtc.PeerConnection
 }
@@ -21,10 +23,14 @@ func NewICEHelper(pc *webrtc.PeerConnection) *ICEHelper {
 }
 
 func (ice *ICEHelper) SetPeerConnection(pc *webrtc.PeerConnection) {
+	ice.mtx.Lock()
+	defer ice.mtx.Unlock()
 	ice.pc = pc
 }
 
 func (ice *ICEHelper) AddCandidate(c webrtc.ICECandidateInit) {
+	ice.mtx.Lock()
+	defer ice.mtx.Unlock()
 	if ice.pc != nil {
 		if ice.pc.RemoteDescription() != nil {
 			// Add immediately if remote is set
@@ -32,7 +38,7 @@ func (ice *ICEHelper) AddCandidate(c webrtc.ICECandidateInit) {
 				slog.Error("Failed to add ICE candidate", "err", err)
 			}
 			// Also flush held candidates automatically
-			ice.FlushHeldCandidates()
+			ice.flushHeldCandidates()
 		} else {
 			// Hold in slice until remote is set
 			ice.candidates = append(ice.candidates, c)
@@ -41,6 +47,12 @@ func (ice *ICEHelper) AddCandidate(c webrtc.ICECandidateInit) {
 }
 
 func (ice *ICEHelper) FlushHeldCandidates() {
+	ice.mtx.Lock()
+	defer ice.mtx.Unlock()
+	ice.flushHeldCandidates()
+}
+
+func (ice *ICEHelper) flushHeldCandidates() {
 	if ice.pc != nil && len(ice.candidates) > 0 {
 		for _, heldCandidate := range ice.candidates {
 			if err := ice.pc.AddICECandidate(heldCandidate); err != nil {
@@ -0,0 +1,157 @@
+package common
+
+import (
+	"log/slog"
+	gen "relay/internal/proto"
+)
+
+// Limits of viewer input, out of range values are clamped so a misbehaving client can't throw the game off
+const (
+	maxInputKeyCode      = 0x2ff  // Highest Linux input event code, KEY_MAX
+	maxMouseDelta        = 4096   // Pixels a relative mouse move may cover per message
+	maxMouseCoordinate   = 32767  // Largest absolute mouse coordinate
+	maxWheelDelta        = 2400   // Wheel delta per message, a few notches of a high resolution wheel
+	maxControllerSlots   = 4      // Controller slots a session may attach
+	maxControllerButtons = 64     // Buttons one controller state update may change
+	minAxisValue         = -32768 // Lowest analog stick and trigger value
+	maxAxisValue         = 32767  // Highest analog stick and trigger value
+)
+
+// controllerTypes are the controllers the server can emulate
+var controllerTypes = map[string]bool{"ps": true, "xbox": true, "switch": true}
+
+// SanitizeInput checks viewer "input" and "controllerInput" messages have a payload of their kind the server
+// handles, with key codes, slots and session fields it accepts. Values out of range are clamped in place,
+// messages which can't be made valid are rejected. Other payload types are left alone.
+func SanitizeInput(msg *gen.ProtoMessage) error {
+	payloadType := msg.GetMessageBase().GetPayloadType()
+	var invalid *InvalidMessageError
+	switch payloadType {
+	case "input":
+		invalid = sanitizeInput(msg)
+	case "controllerInput":
+		invalid = sanitizeControllerInput(msg)
+	default:
+		return nil
+	}
+	if invalid == nil {
+		return nil
+	}
+	invalid.PayloadType = payloadType
+	if len(invalid.Reason) <= 0 {
+		invalid.Reason = InvalidMissing
+	}
+	protocolInvalidCounter.WithLabelValues(payloadType, invalid.Reason).Inc()
+	// Input comes at high rates, rejecting it is only worth a debug log
+	slog.Debug("Rejecting invalid input", "payload_type", payloadType, "field", invalid.Field, "reason", invalid.Reason)
+	return invalid
+}
+
+func sanitizeInput(msg *gen.ProtoMessage) *InvalidMessageError {
+	switch payload := msg.Payload.(type) {
+	case *gen.ProtoMessage_MouseMove:
+		if payload.MouseMove == nil {
+			return &InvalidMessageError{}
+		}
+		payload.MouseMove.X = clamp(payload.MouseMove.X, -maxMouseDelta, maxMouseDelta)
+		payload.MouseMove.Y = clamp(payload.MouseMove.Y, -maxMouseDelta, maxMouseDelta)
+	case *gen.ProtoMessage_MouseMoveAbs:
+		if payload.MouseMoveAbs == nil {
+			return &InvalidMessageError{}
+		}
+		payload.MouseMoveAbs.X = clamp(payload.MouseMoveAbs.X, 0, maxMouseCoordinate)
+		payload.MouseMoveAbs.Y = clamp(payload.MouseMoveAbs.Y, 0, maxMouseCoordinate)
+	case *gen.ProtoMessage_MouseWheel:
+		if payload.MouseWheel == nil {
+			return &InvalidMessageError{}
+		}
+		payload.MouseWheel.X = clamp(payload.MouseWheel.X, -maxWheelDelta, maxWheelDelta)
+		payload.MouseWheel.Y = clamp(payload.MouseWheel.Y, -maxWheelDelta, maxWheelDelta)
+	case *gen.ProtoMessage_MouseKeyDown:
+		return checkKeyCode("key", payload.MouseKeyDown.GetKey())
+	case *gen.ProtoMessage_MouseKeyUp:
+		return checkKeyCode("key", payload.MouseKeyUp.GetKey())
+	case *gen.ProtoMessage_KeyDown:
+		return checkKeyCode("key", payload.KeyDown.GetKey())
+	case *gen.ProtoMessage_KeyUp:
+		return checkKeyCode("key", payload.KeyUp.GetKey())
+	default:
+		return &InvalidMessageError{}
+	}
+	return nil
+}
+
+// sanitizeControllerInput checks controller messages viewers send, rumble goes from the server to viewers only
+func sanitizeControllerInput(msg *gen.ProtoMessage) *InvalidMessageError {
+	switch payload := msg.Payload.(type) {
+	case *gen.ProtoMessage_ControllerAttach:
+		attach := payload.ControllerAttach
+		if attach == nil {
+			return &InvalidMessageError{}
+		}
+		if !controllerTypes[attach.Id] {
+			return &InvalidMessageError{Field: "id", Reason: InvalidValue}
+		}
+		return checkController(attach.SessionSlot, attach.SessionId)
+	case *gen.ProtoMessage_ControllerDetach:
+		if payload.ControllerDetach == nil {
+			return &InvalidMessageError{}
+		}
+		return checkController(payload.ControllerDetach.SessionSlot, payload.ControllerDetach.SessionId)
+	case *gen.ProtoMessage_ControllerStateBatch:
+		return sanitizeControllerState(payload.ControllerStateBatch)
+	default:
+		return &InvalidMessageError{}
+	}
+}
+
+func sanitizeControllerState(state *gen.ProtoControllerStateBatch) *InvalidMessageError {
+	if state == nil {
+		return &InvalidMessageError{}
+	}
+	if err := checkController(state.SessionSlot, state.SessionId); err != nil {
+		return err
+	}
+	if _, ok := gen.ProtoControllerStateBatch_UpdateType_name[int32(state.UpdateType)]; !ok {
+		return &InvalidMessageError{Field: "update_type", Reason: InvalidValue}
+	}
+	if len(state.ButtonChangedMask) > maxControllerButtons {
+		return &InvalidMessageError{Field: "button_changed_mask", Reason: InvalidTooLong}
+	}
+	for code := range state.ButtonChangedMask {
+		if err := checkKeyCode("button_changed_mask", code); err != nil {
+			return err
+		}
+	}
+	for _, axis := range []*int32{state.LeftStickX, state.LeftStickY, state.RightStickX, state.RightStickY, state.LeftTrigger, state.RightTrigger} {
+		if axis != nil {
+			*axis = clamp(*axis, minAxisValue, maxAxisValue)
+		}
+	}
+	for _, dpad := range []*int32{state.DpadX, state.DpadY} {
+		if dpad != nil {
+			*dpad = clamp(*dpad, -1, 1)
+		}
+	}
+	return nil
+}
+
+// checkController requires a slot the server has and a session ID within limits
+func checkController(slot int32, sessionID string) *InvalidMessageError {
+	if slot < 0 || slot >= maxControllerSlots {
+		return &InvalidMessageError{Field: "session_slot", Reason: InvalidValue}
+	}
+	return checkString("session_id", sessionID, maxSessionIDLength)
+}
+
+// checkKeyCode requires a Linux input event code
+func checkKeyCode(field string, code int32) *InvalidMessageError {
+	if code < 0 || code > maxInputKeyCode {
+		return &InvalidMessageError{Field: field, Reason: InvalidValue}
+	}
+	return nil
+}
+
+func clamp(v, lo, hi int32) int32 {
+	return min(max(v, lo), hi)
+}
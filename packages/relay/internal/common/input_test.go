@@ -0,0 +1,121 @@
+package common
+
+import (
+	"errors"
+	gen "relay/internal/proto"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+)
+
+func TestSanitizeInput(t *testing.T) {
+	base := func(payloadType string) *gen.ProtoMessageBase {
+		return &gen.ProtoMessageBase{PayloadType: payloadType}
+	}
+	tests := []struct {
+		name   string
+		msg    *gen.ProtoMessage
+		field  string
+		reason string
+	}{
+		{
+			name: "key down",
+			msg:  &gen.ProtoMessage{MessageBase: base("input"), Payload: &gen.ProtoMessage_KeyDown{KeyDown: &gen.ProtoKeyDown{Key: 30}}},
+		},
+		{
+			name:   "key beyond event codes",
+			msg:    &gen.ProtoMessage{MessageBase: base("input"), Payload: &gen.ProtoMessage_KeyUp{KeyUp: &gen.ProtoKeyUp{Key: maxInputKeyCode + 1}}},
+			field:  "key",
+			reason: InvalidValue,
+		},
+		{
+			name:   "input carrying controller payload",
+			msg:    &gen.ProtoMessage{MessageBase: base("input"), Payload: &gen.ProtoMessage_ControllerAttach{ControllerAttach: &gen.ProtoControllerAttach{Id: "xbox"}}},
+			reason: InvalidMissing,
+		},
+		{
+			name: "controller attach",
+			msg: &gen.ProtoMessage{MessageBase: base("controllerInput"), Payload: &gen.ProtoMessage_ControllerAttach{
+				ControllerAttach: &gen.ProtoControllerAttach{Id: "ps", SessionSlot: 3, SessionId: "session"},
+			}},
+		},
+		{
+			name: "unknown controller",
+			msg: &gen.ProtoMessage{MessageBase: base("controllerInput"), Payload: &gen.ProtoMessage_ControllerAttach{
+				ControllerAttach: &gen.ProtoControllerAttach{Id: "steam"},
+			}},
+			field:  "id",
+			reason: InvalidValue,
+		},
+		{
+			name: "controller detach from missing slot",
+			msg: &gen.ProtoMessage{MessageBase: base("controllerInput"), Payload: &gen.ProtoMessage_ControllerDetach{
+				ControllerDetach: &gen.ProtoControllerDetach{SessionSlot: maxControllerSlots},
+			}},
+			field:  "session_slot",
+			reason: InvalidValue,
+		},
+		{
+			name: "rumble from viewer",
+			msg: &gen.ProtoMessage{MessageBase: base("controllerInput"), Payload: &gen.ProtoMessage_ControllerRumble{
+				ControllerRumble: &gen.ProtoControllerRumble{},
+			}},
+			reason: InvalidMissing,
+		},
+		{
+			name: "controller state with unknown button",
+			msg: &gen.ProtoMessage{MessageBase: base("controllerInput"), Payload: &gen.ProtoMessage_ControllerStateBatch{
+				ControllerStateBatch: &gen.ProtoControllerStateBatch{ButtonChangedMask: map[int32]bool{-1: true}},
+			}},
+			field:  "button_changed_mask",
+			reason: InvalidValue,
+		},
+		{
+			name: "controller state with unknown update type",
+			msg: &gen.ProtoMessage{MessageBase: base("controllerInput"), Payload: &gen.ProtoMessage_ControllerStateBatch{
+				ControllerStateBatch: &gen.ProtoControllerStateBatch{UpdateType: 7},
+			}},
+			field:  "update_type",
+			reason: InvalidValue,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := SanitizeInput(tt.msg)
+			if len(tt.reason) <= 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var invalid *InvalidMessageError
+			if !errors.As(err, &invalid) {
+				t.Fatalf("expected InvalidMessageError, got %v", err)
+			}
+			if invalid.Field != tt.field || invalid.Reason != tt.reason {
+				t.Fatalf("got field %q reason %q, want field %q reason %q", invalid.Field, invalid.Reason, tt.field, tt.reason)
+			}
+		})
+	}
+}
+
+func TestSanitizeInputClamps(t *testing.T) {
+	move := &gen.ProtoMouseMove{X: 1 << 20, Y: -(1 << 20)}
+	if err := SanitizeInput(&gen.ProtoMessage{MessageBase: &gen.ProtoMessageBase{PayloadType: "input"}, Payload: &gen.ProtoMessage_MouseMove{MouseMove: move}}); err != nil {
+		t.Fatal(err)
+	}
+	if move.X != maxMouseDelta || move.Y != -maxMouseDelta {
+		t.Fatalf("mouse move clamped to %d,%d, want ±%d", move.X, move.Y, maxMouseDelta)
+	}
+
+	state := &gen.ProtoControllerStateBatch{LeftStickX: proto.Int32(1 << 20), DpadY: proto.Int32(-5), SessionId: "session"}
+	if err := SanitizeInput(&gen.ProtoMessage{MessageBase: &gen.ProtoMessageBase{PayloadType: "controllerInput"}, Payload: &gen.ProtoMessage_ControllerStateBatch{ControllerStateBatch: state}}); err != nil {
+		t.Fatal(err)
+	}
+	if state.GetLeftStickX() != maxAxisValue || state.GetDpadY() != -1 {
+		t.Fatalf("controller state clamped to stick %d dpad %d, want %d and -1", state.GetLeftStickX(), state.GetDpadY(), maxAxisValue)
+	}
+	if state.RightStickX != nil {
+		t.Fatal("unset axis was set by clamping")
+	}
+}
@@ -0,0 +1,113 @@
+package common
+
+import (
+	"strings"
+
+	"github.com/pion/webrtc/v4"
+)
+
+// IsKeyframe checks if RTP payload starts a keyframe for given codec, unknown codecs are never keyframes
+func IsKeyframe(mimeType string, payload []byte) bool {
+	if len(payload) < 1 {
+		return false
+	}
+	switch strings.ToLower(mimeType) {
+	case strings.ToLower(webrtc.MimeTypeH264):
+		return isH264Keyframe(payload)
+	case strings.ToLower(webrtc.MimeTypeH265):
+		return isH265Keyframe(payload)
+	case strings.ToLower(webrtc.MimeTypeVP8):
+		return isVP8Keyframe(payload)
+	case strings.ToLower(webrtc.MimeTypeVP9):
+		return isVP9Keyframe(payload)
+	case strings.ToLower(webrtc.MimeTypeAV1):
+		return isAV1Keyframe(payload)
+	}
+	return false
+}
+
+// isH264Keyframe looks for IDR slices in single NAL, STAP-A and starting FU-A packets (RFC 6184)
+func isH264Keyframe(payload []byte) bool {
+	const nalIDR = 5
+	switch nalType := payload[0] & 0x1F; nalType {
+	case nalIDR:
+		return true
+	case 24: // STAP-A
+		for i := 1; i+2 < len(payload); {
+			size := int(payload[i])<<8 | int(payload[i+1])
+			if payload[i+2]&0x1F == nalIDR {
+				return true
+			}
+			i += 2 + size
+		}
+	case 28: // FU-A
+		return len(payload) > 1 && payload[1]&0x80 != 0 && payload[1]&0x1F == nalIDR
+	}
+	return false
+}
+
+// isH265Keyframe looks for IRAP pictures in single NAL, AP and starting FU packets (RFC 7798)
+func isH265Keyframe(payload []byte) bool {
+	if len(payload) < 3 {
+		return false
+	}
+	isIRAP := func(nalType byte) bool { return nalType >= 16 && nalType <= 21 }
+	switch nalType := (payload[0] >> 1) & 0x3F; nalType {
+	case 48: // AP
+		for i := 2; i+2 < len(payload); {
+			size := int(payload[i])<<8 | int(payload[i+1])
+			if isIRAP((payload[i+2] >> 1) & 0x3F) {
+				return true
+			}
+			i += 2 + size
+		}
+		return false
+	case 49: // FU
+		return payload[2]&0x80 != 0 && isIRAP(payload[2]&0x3F)
+	default:
+		return isIRAP(nalType)
+	}
+}
+
+// isVP8Keyframe checks the P bit of first partition start (RFC 7741)
+func isVP8Keyframe(payload []byte) bool {
+	// Only start of partition 0 carries the frame header
+	if payload[0]&0x10 == 0 || payload[0]&0x0F != 0 {
+		return false
+	}
+	i := 1
+	if payload[0]&0x80 != 0 { // X
+		if len(payload) <= i {
+			return false
+		}
+		ext := payload[i]
+		i++
+		if ext&0x80 != 0 { // I
+			if len(payload) <= i {
+				return false
+			}
+			if payload[i]&0x80 != 0 {
+				i++
+			}
+			i++
+		}
+		if ext&0x40 != 0 { // L
+			i++
+		}
+		if ext&0x30 != 0 { // T or K
+			i++
+		}
+	}
+	return len(payload) > i && payload[i]&0x01 == 0
+}
+
+// isVP9Keyframe checks for a non-inter-predicted start of frame (draft-ietf-payload-vp9)
+func isVP9Keyframe(payload []byte) bool {
+	// P bit clear and B (start of frame) bit set
+	return payload[0]&0x40 == 0 && payload[0]&0x08 != 0
+}
+
+// isAV1Keyframe checks the N bit of the aggregation header, first packet of a coded video sequence
+func isAV1Keyframe(payload []byte) bool {
+	return payload[0]&0x08 != 0
+}
@@ -0,0 +1,138 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rotatedTimeFormat is appended to rotated log file names, sorts chronologically
+const rotatedTimeFormat = "20060102-150405.000"
+
+// LogFileOptions controls rotation and retention of a RotatingFile, zero values disable each limit
+type LogFileOptions struct {
+	MaxSize    int64         // Rotate once file would grow past this many bytes
+	Interval   time.Duration // Rotate once file has been written for this long
+	MaxBackups int           // Keep at most this many rotated files
+	MaxAge     time.Duration // Remove rotated files older than this
+}
+
+// RotatingFile is a log file writer rotating by size and age, keeping a limited history of rotated files
+type RotatingFile struct {
+	mtx    sync.Mutex
+	path   string
+	opts   LogFileOptions
+	file   *os.File
+	size   int64
+	opened time.Time
+}
+
+// NewRotatingFile opens or creates log file at path, appending to existing content
+func NewRotatingFile(path string, opts LogFileOptions) (*RotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	rf := &RotatingFile{path: path, opts: opts}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	rf.prune()
+	return rf, nil
+}
+
+func (rf *RotatingFile) open() error {
+	file, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+	rf.file = file
+	rf.size = info.Size()
+	rf.opened = time.Now()
+	return nil
+}
+
+// Write writes to current log file, rotating first if a limit would be exceeded
+func (rf *RotatingFile) Write(p []byte) (int, error) {
+	rf.mtx.Lock()
+	defer rf.mtx.Unlock()
+
+	if rf.file == nil {
+		return 0, os.ErrClosed
+	}
+	oversize := rf.opts.MaxSize > 0 && rf.size > 0 && rf.size+int64(len(p)) > rf.opts.MaxSize
+	expired := rf.opts.Interval > 0 && time.Since(rf.opened) >= rf.opts.Interval
+	if oversize || expired {
+		if err := rf.rotate(); err != nil {
+			// Keep logging to the old file rather than losing lines
+			fmt.Fprintln(os.Stderr, "failed to rotate log file:", err)
+		}
+	}
+
+	n, err := rf.file.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+// rotate renames current file with a timestamp and starts a new one, mutex must be held
+func (rf *RotatingFile) rotate() error {
+	if err := rf.file.Close(); err != nil {
+		return err
+	}
+	ext := filepath.Ext(rf.path)
+	rotated := strings.TrimSuffix(rf.path, ext) + "-" + time.Now().Format(rotatedTimeFormat) + ext
+	if err := os.Rename(rf.path, rotated); err != nil {
+		if reopenErr := rf.open(); reopenErr != nil {
+			return reopenErr
+		}
+		return err
+	}
+	if err := rf.open(); err != nil {
+		return err
+	}
+	rf.prune()
+	return nil
+}
+
+// prune removes rotated files past retention
+func (rf *RotatingFile) prune() {
+	ext := filepath.Ext(rf.path)
+	rotated, err := filepath.Glob(strings.TrimSuffix(rf.path, ext) + "-*" + ext)
+	if err != nil {
+		return
+	}
+	// Newest first
+	sort.Sort(sort.Reverse(sort.StringSlice(rotated)))
+
+	for i, path := range rotated {
+		remove := rf.opts.MaxBackups > 0 && i >= rf.opts.MaxBackups
+		if !remove && rf.opts.MaxAge > 0 {
+			if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > rf.opts.MaxAge {
+				remove = true
+			}
+		}
+		if remove {
+			_ = os.Remove(path)
+		}
+	}
+}
+
+// Close closes current log file
+func (rf *RotatingFile) Close() error {
+	rf.mtx.Lock()
+	defer rf.mtx.Unlock()
+	if rf.file == nil {
+		return nil
+	}
+	err := rf.file.Close()
+	rf.file = nil
+	return err
+}
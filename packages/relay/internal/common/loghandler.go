@@ -3,13 +3,30 @@ package common
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"strings"
 )
 
+// LogLevel is the level of relay log handlers, changed at runtime by configuration reload
+var LogLevel = new(slog.LevelVar)
+
+// NewLogHandler creates the log handler for given format writing to w, "text" for human readable lines or "json" for log shippers
+func NewLogHandler(format string, level slog.Leveler, w io.Writer) (slog.Handler, error) {
+	switch format {
+	case "", "text":
+		return &CustomHandler{Handler: slog.NewTextHandler(w, &slog.HandlerOptions{Level: level}), Writer: w}, nil
+	case "json":
+		return slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level}), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q, use text or json", format)
+	}
+}
+
 type CustomHandler struct {
 	Handler slog.Handler
+	Writer  io.Writer // Output of formatted lines, stdout if nil
 }
 
 func (h *CustomHandler) Enabled(_ context.Context, level slog.Level) bool {
@@ -34,15 +51,19 @@ func (h *CustomHandler) Handle(_ context.Context, r slog.Record) error {
 		msg += " " + strings.Join(attrs, " ")
 	}
 
-	// Write the formatted message to stdout
-	_, err := fmt.Fprintln(os.Stdout, msg)
+	// Write the formatted message, stdout by default
+	w := h.Writer
+	if w == nil {
+		w = os.Stdout
+	}
+	_, err := fmt.Fprintln(w, msg)
 	return err
 }
 
 func (h *CustomHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	return &CustomHandler{Handler: h.Handler.WithAttrs(attrs)}
+	return &CustomHandler{Handler: h.Handler.WithAttrs(attrs), Writer: h.Writer}
 }
 
 func (h *CustomHandler) WithGroup(name string) slog.Handler {
-	return &CustomHandler{Handler: h.Handler.WithGroup(name)}
+	return &CustomHandler{Handler: h.Handler.WithGroup(name), Writer: h.Writer}
 }
@@ -0,0 +1,127 @@
+package common
+
+import (
+	"errors"
+	"log/slog"
+	gen "relay/internal/proto"
+	"slices"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ProtocolVersion is the message protocol version of this relay, announced in every message base.
+// Version 2 added capabilities, announced with the first message on a stream and in relay status.
+const ProtocolVersion = 2
+
+// capabilitiesVersion is the first protocol version announcing capabilities
+const capabilitiesVersion = 2
+
+// Optional protocol features, peers use them only with peers announcing them
+const (
+	CapabilityMeshLink       = "mesh-link"       // Rooms can be pulled from the relay over a shared mesh link
+	CapabilityVariants       = "variants"        // Quality variant rooms are served
+	CapabilityInvalidMessage = "invalid-message" // Messages failing validation are answered with "invalid-message"
+	CapabilityLinkProbe      = "link-probe"      // Capacity of links from the relay can be probed
+	CapabilityBetterRelay    = "better-relay"    // Viewer follows "better-relay" answers to a relay closer to it
+)
+
+// Capabilities returns optional protocol features this relay supports
+func Capabilities() []string {
+	return []string{CapabilityMeshLink, CapabilityVariants, CapabilityInvalidMessage, CapabilityLinkProbe}
+}
+
+// Announcement is protocol version and capabilities announced by a peer
+type Announcement struct {
+	Version      uint32
+	Capabilities []string
+}
+
+// Legacy reports whether peer predates capabilities, such peers announce none but may still have features,
+// callers fall back to what they did before negotiation
+func (a Announcement) Legacy() bool {
+	return a.Version < capabilitiesVersion
+}
+
+// Supports reports whether peer announced a capability
+func (a Announcement) Supports(capability string) bool {
+	return slices.Contains(a.Capabilities, capability)
+}
+
+// ErrMessageRejected is returned for messages refused by strict protocol mode or as replays, the stream stays usable
+var ErrMessageRejected = errors.New("message rejected")
+
+// Reasons a received message doesn't match our protocol
+const (
+	mismatchUnknownFields = "unknown-fields"
+	mismatchNewerVersion  = "newer-version"
+)
+
+var protocolMismatchCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "nestri_relay_protocol_mismatch_total",
+	Help: "Received messages with unknown fields or from newer protocol versions",
+}, []string{"payload_type", "reason"})
+
+// RegisterProtocolMetrics registers protocol mismatch, replay and validation metrics
+func RegisterProtocolMetrics() {
+	prometheus.MustRegister(protocolMismatchCounter, protocolReplayedCounter, protocolInvalidCounter)
+}
+
+// CheckMessage looks for version skew in a received message, counting any, in strict mode such messages are rejected
+func CheckMessage(msg *gen.ProtoMessage) error {
+	payloadType := ""
+	var version uint32
+	if msg.MessageBase != nil {
+		payloadType = msg.MessageBase.PayloadType
+		version = msg.MessageBase.ProtocolVersion
+	}
+
+	reason := ""
+	if version > ProtocolVersion {
+		reason = mismatchNewerVersion
+	} else if hasUnknownFields(msg.ProtoReflect()) {
+		reason = mismatchUnknownFields
+	}
+	if len(reason) <= 0 {
+		return nil
+	}
+
+	protocolMismatchCounter.WithLabelValues(payloadType, reason).Inc()
+	if !GetFlags().StrictProtocol {
+		slog.Debug("Received message not matching our protocol", "payload_type", payloadType, "reason", reason, "version", version)
+		return nil
+	}
+	slog.Warn("Rejecting message not matching our protocol", "payload_type", payloadType, "reason", reason, "version", version)
+	return ErrMessageRejected
+}
+
+// hasUnknownFields checks message and all nested messages for fields not in our descriptors
+func hasUnknownFields(m protoreflect.Message) bool {
+	if len(m.GetUnknown()) > 0 {
+		return true
+	}
+	found := false
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.Message() == nil {
+			return true
+		}
+		switch {
+		case fd.IsList():
+			list := v.List()
+			for i := 0; i < list.Len() && !found; i++ {
+				found = hasUnknownFields(list.Get(i).Message())
+			}
+		case fd.IsMap():
+			if fd.MapValue().Message() != nil {
+				v.Map().Range(func(_ protoreflect.MapKey, value protoreflect.Value) bool {
+					found = hasUnknownFields(value.Message())
+					return !found
+				})
+			}
+		default:
+			found = hasUnknownFields(v.Message())
+		}
+		return !found
+	})
+	return found
+}
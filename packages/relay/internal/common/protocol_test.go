@@ -0,0 +1,63 @@
+package common
+
+import (
+	"bufio"
+	"net"
+	gen "relay/internal/proto"
+	"testing"
+)
+
+// TestStreamAnnouncement sends messages over a stream, only the first carries capabilities
+func TestStreamAnnouncement(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+	sender := NewSafeBufioRW(bufio.NewReadWriter(bufio.NewReader(a), bufio.NewWriter(a)))
+	receiver := NewSafeBufioRW(bufio.NewReadWriter(bufio.NewReader(b), bufio.NewWriter(b)))
+
+	if !receiver.Peer().Legacy() {
+		t.Fatal("peer announced before its first message")
+	}
+
+	sent := make(chan error, 1)
+	go func() {
+		for range 2 {
+			msg, err := CreateMessage(&gen.ProtoRaw{Data: "room"}, "mesh-release-room", nil)
+			if err != nil {
+				sent <- err
+				return
+			}
+			if err = sender.SendProto(msg); err != nil {
+				sent <- err
+				return
+			}
+		}
+		sent <- nil
+	}()
+
+	for i := range 2 {
+		var msg gen.ProtoMessage
+		if err := receiver.ReceiveProto(&msg); err != nil {
+			t.Fatal(err)
+		}
+		if announced := len(msg.GetMessageBase().GetCapabilities()) > 0; announced != (i == 0) {
+			t.Fatalf("message %d announced capabilities: %t", i, announced)
+		}
+	}
+	if err := <-sent; err != nil {
+		t.Fatal(err)
+	}
+
+	peer := receiver.Peer()
+	if peer.Legacy() || peer.Version != ProtocolVersion {
+		t.Fatalf("peer announced version %d", peer.Version)
+	}
+	for _, capability := range Capabilities() {
+		if !peer.Supports(capability) {
+			t.Fatalf("peer didn't announce %s", capability)
+		}
+	}
+	if peer.Supports("simulcast") {
+		t.Fatal("peer supports capability it didn't announce")
+	}
+}
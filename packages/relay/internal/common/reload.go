@@ -0,0 +1,86 @@
+package common
+
+import (
+	"flag"
+	"io"
+	"maps"
+	"os"
+	"sync"
+)
+
+// reloadMtx serializes reloads, config file values are parsed through package state
+var reloadMtx sync.Mutex
+
+// reloadableOption is a flag that can change at runtime, apply copies it and reports if it changed
+type reloadableOption struct {
+	name  string
+	apply func(dst, src *Flags) bool
+}
+
+// reloadableOptions are applied by ReloadFlags, other options need a restart
+var reloadableOptions = []reloadableOption{
+	{"verbose", func(dst, src *Flags) bool { return reloadValue(&dst.Verbose, src.Verbose) }},
+	{"debug", func(dst, src *Flags) bool { return reloadValue(&dst.Debug, src.Debug) }},
+	{"stunServer", func(dst, src *Flags) bool { return reloadValue(&dst.STUNServer, src.STUNServer) }},
+	{"latencyBudget", func(dst, src *Flags) bool { return reloadValue(&dst.LatencyBudget, src.LatencyBudget) }},
+	{"maxFrameAge", func(dst, src *Flags) bool { return reloadValue(&dst.MaxFrameAge, src.MaxFrameAge) }},
+	{"maxViewers", func(dst, src *Flags) bool { return reloadValue(&dst.MaxViewers, src.MaxViewers) }},
+	{"roomGoroutines", func(dst, src *Flags) bool { return reloadValue(&dst.RoomGoroutines, src.RoomGoroutines) }},
+	{"roomTracks", func(dst, src *Flags) bool { return reloadValue(&dst.RoomTracks, src.RoomTracks) }},
+	{"roomQueuedKB", func(dst, src *Flags) bool { return reloadValue(&dst.RoomQueuedKB, src.RoomQueuedKB) }},
+	{"roomMsgRate", func(dst, src *Flags) bool { return reloadValue(&dst.RoomMsgRate, src.RoomMsgRate) }},
+	{"roomIdleTTL", func(dst, src *Flags) bool { return reloadValue(&dst.RoomIdleTTL, src.RoomIdleTTL) }},
+	{"streamRate", func(dst, src *Flags) bool { return reloadValue(&dst.StreamRate, src.StreamRate) }},
+	{"messageRate", func(dst, src *Flags) bool { return reloadValue(&dst.MessageRate, src.MessageRate) }},
+	{"inputRate", func(dst, src *Flags) bool { return reloadValue(&dst.InputRate, src.InputRate) }},
+	{"quotaDaily", func(dst, src *Flags) bool { return reloadValue(&dst.QuotaDaily, src.QuotaDaily) }},
+	{"quotaMonthly", func(dst, src *Flags) bool { return reloadValue(&dst.QuotaMonthly, src.QuotaMonthly) }},
+	{"overloadCPU", func(dst, src *Flags) bool { return reloadValue(&dst.OverloadCPU, src.OverloadCPU) }},
+	{"overloadDrops", func(dst, src *Flags) bool { return reloadValue(&dst.OverloadDrops, src.OverloadDrops) }},
+	{"peerTTL", func(dst, src *Flags) bool { return reloadValue(&dst.PeerTTL, src.PeerTTL) }},
+	{"strictProtocol", func(dst, src *Flags) bool { return reloadValue(&dst.StrictProtocol, src.StrictProtocol) }},
+	{"pushSecret", func(dst, src *Flags) bool { return reloadValue(&dst.PushSecret, src.PushSecret) }},
+	{"rooms", func(dst, src *Flags) bool {
+		if maps.Equal(dst.Rooms, src.Rooms) {
+			return false
+		}
+		dst.Rooms = src.Rooms
+		return true
+	}},
+}
+
+func reloadValue[T comparable](dst *T, value T) bool {
+	if *dst == value {
+		return false
+	}
+	*dst = value
+	return true
+}
+
+// ReloadFlags re-reads config file and environment, applying reloadable options, returns names of changed options.
+// Command line flags keep precedence, so options given as flags can't be changed by reloading.
+func ReloadFlags() ([]string, error) {
+	reloadMtx.Lock()
+	defer reloadMtx.Unlock()
+
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fresh, err := parseFlags(fs, os.Args[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	next := *GetFlags()
+	changed := make([]string, 0)
+	for _, option := range reloadableOptions {
+		if option.apply(&next, fresh) {
+			changed = append(changed, option.name)
+		}
+	}
+	if len(changed) <= 0 {
+		return changed, nil
+	}
+	globalFlags.Store(&next)
+	LogLevel.Set(next.LogLevel())
+	return changed, nil
+}
@@ -0,0 +1,80 @@
+package common
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// replayWindowSize is how many sequence numbers behind the highest one seen are still accepted out of order
+const replayWindowSize = 64
+
+// Reasons a sequenced message is dropped as a replay
+const (
+	replayDuplicate = "duplicate"
+	replayTooOld    = "too-old"
+	replayNonce     = "nonce"
+)
+
+var protocolReplayedCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "nestri_relay_protocol_replayed_total",
+	Help: "Received messages dropped as replayed or duplicated",
+}, []string{"payload_type", "reason"})
+
+// ReplayWindow tracks sequence numbers received on a stream, detecting replayed and duplicated messages.
+// Messages without a sequence number come from senders not sequencing their messages and are always accepted.
+type ReplayWindow struct {
+	mtx     sync.Mutex
+	nonce   uint64
+	pinned  bool
+	highest uint64
+	seen    uint64 // Bit n set if highest-n was received
+}
+
+// Check records a received sequence number, returning the reason for dropping it or empty if it's new
+func (w *ReplayWindow) Check(nonce, sequence uint64) string {
+	if sequence == 0 {
+		return ""
+	}
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	if !w.pinned {
+		w.nonce = nonce
+		w.pinned = true
+	} else if nonce != w.nonce {
+		return replayNonce
+	}
+
+	switch {
+	case sequence > w.highest:
+		shift := sequence - w.highest
+		if shift >= replayWindowSize {
+			w.seen = 0
+		} else {
+			w.seen <<= shift
+		}
+		w.seen |= 1
+		w.highest = sequence
+		return ""
+	case w.highest-sequence >= replayWindowSize:
+		return replayTooOld
+	}
+	bit := uint64(1) << (w.highest - sequence)
+	if w.seen&bit != 0 {
+		return replayDuplicate
+	}
+	w.seen |= bit
+	return ""
+}
+
+// newStreamNonce returns a random nonce identifying messages we send on one stream
+func newStreamNonce() uint64 {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(b[:])
+}
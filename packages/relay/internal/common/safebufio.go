@@ -4,7 +4,9 @@ import (
 	"bufio"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"io"
+	"log/slog"
 	gen "relay/internal/proto"
 	"sync"
 
@@ -13,6 +15,41 @@ import (
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// MaxMessageSize is the largest length prefixed message ReceiveProto accepts
+const MaxMessageSize = 4 << 20
+
+// MessageTooLargeError is returned by ReceiveProto for a length prefix over MaxMessageSize, the stream is unusable after it
+type MessageTooLargeError struct {
+	Size uint64
+}
+
+func (e *MessageTooLargeError) Error() string {
+	return fmt.Sprintf("message of %d bytes exceeds limit of %d bytes", e.Size, MaxMessageSize)
+}
+
+const minReceiveBucket = 512
+
+// receiveBuffers pools receive buffers by power of two size, from minReceiveBucket up to MaxMessageSize
+var receiveBuffers = func() []sync.Pool {
+	buckets := make([]sync.Pool, 0)
+	for size := minReceiveBucket; size <= MaxMessageSize; size <<= 1 {
+		buckets = append(buckets, sync.Pool{New: func() any {
+			buf := make([]byte, size)
+			return &buf
+		}})
+	}
+	return buckets
+}()
+
+// receiveBucket returns index of the smallest bucket fitting length, which must not exceed MaxMessageSize
+func receiveBucket(length int) int {
+	bucket := 0
+	for size := minReceiveBucket; size < length; size <<= 1 {
+		bucket++
+	}
+	return bucket
+}
+
 // readUvarint reads an unsigned varint from the reader
 func readUvarint(r io.ByteReader) (uint64, error) {
 	return binary.ReadUvarint(r)
@@ -28,17 +65,52 @@ func writeUvarint(w io.Writer, x uint64) error {
 
 // SafeBufioRW wraps a bufio.ReadWriter for sending and receiving JSON and protobufs safely
 type SafeBufioRW struct {
-	brw   *bufio.ReadWriter
-	mutex sync.RWMutex
+	brw *bufio.ReadWriter
+	// Reading and writing use separate buffers, a receive blocked waiting for data must not hold up sends
+	sendMtx sync.Mutex
+	recvMtx sync.Mutex
+	// Messages we send are sequenced under our nonce, so the other side can drop replays
+	nonce   uint64
+	sendSeq uint64
+	replay  ReplayWindow
+	// Message protocol version spoken on the stream, older versions don't know later additions
+	version uint32
+	// What the other side announced with its first message, see Peer
+	peerMtx       sync.Mutex
+	peer          Announcement
+	peerAnnounced bool
 }
 
 func NewSafeBufioRW(brw *bufio.ReadWriter) *SafeBufioRW {
-	return &SafeBufioRW{brw: brw}
+	return &SafeBufioRW{brw: brw, nonce: newStreamNonce(), version: ProtocolVersion}
+}
+
+// SetMessageVersion downgrades messages sent on the stream to an older message protocol version, before first use.
+// Older versions aren't announced capabilities nor answered with "invalid-message".
+func (bu *SafeBufioRW) SetMessageVersion(version uint32) {
+	bu.version = min(version, ProtocolVersion)
 }
 
 func (bu *SafeBufioRW) SendProto(msg proto.Message) error {
-	bu.mutex.Lock()
-	defer bu.mutex.Unlock()
+	if wrapper, ok := msg.(*gen.ProtoMessage); ok && !chaosMessage(wrapper.GetMessageBase().GetPayloadType()) {
+		return nil
+	}
+
+	bu.sendMtx.Lock()
+	defer bu.sendMtx.Unlock()
+
+	if wrapper, ok := msg.(*gen.ProtoMessage); ok && wrapper.MessageBase != nil {
+		// Stamp a copy, the same message may be sent on other streams
+		bu.sendSeq++
+		base := proto.Clone(wrapper.MessageBase).(*gen.ProtoMessageBase)
+		base.Sequence = bu.sendSeq
+		base.StreamNonce = bu.nonce
+		base.ProtocolVersion = bu.version
+		if bu.sendSeq == 1 && bu.version >= capabilitiesVersion {
+			base.Capabilities = Capabilities()
+		}
+		msg = &gen.ProtoMessage{MessageBase: base, Payload: wrapper.Payload}
+	}
 
 	protoData, err := proto.Marshal(msg)
 	if err != nil {
@@ -59,8 +131,8 @@ func (bu *SafeBufioRW) SendProto(msg proto.Message) error {
 }
 
 func (bu *SafeBufioRW) ReceiveProto(msg proto.Message) error {
-	bu.mutex.RLock()
-	defer bu.mutex.RUnlock()
+	bu.recvMtx.Lock()
+	defer bu.recvMtx.Unlock()
 
 	// Read varint length prefix
 	length, err := readUvarint(bu.brw)
@@ -68,13 +140,87 @@ func (bu *SafeBufioRW) ReceiveProto(msg proto.Message) error {
 		return err
 	}
 
-	// Read the Protobuf data
-	data := make([]byte, length)
+	if length > MaxMessageSize {
+		return &MessageTooLargeError{Size: length}
+	}
+
+	// Read the Protobuf data into a pooled buffer, unmarshalling copies out of it
+	bucket := receiveBucket(int(length))
+	buf := receiveBuffers[bucket].Get().(*[]byte)
+	defer receiveBuffers[bucket].Put(buf)
+	data := (*buf)[:length]
 	if _, err := io.ReadFull(bu.brw, data); err != nil {
 		return err
 	}
 
-	return proto.Unmarshal(data, msg)
+	if err = proto.Unmarshal(data, msg); err != nil {
+		return err
+	}
+	if wrapper, ok := msg.(*gen.ProtoMessage); ok {
+		if err = bu.checkReplay(wrapper); err != nil {
+			return err
+		}
+		bu.recordPeer(wrapper.GetMessageBase())
+		if err = CheckMessage(wrapper); err != nil {
+			return err
+		}
+		if err = ValidateMessage(wrapper); err != nil {
+			if bu.version >= capabilitiesVersion {
+				bu.sendInvalid(err.(*InvalidMessageError))
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// recordPeer keeps protocol version and capabilities of the first message received
+func (bu *SafeBufioRW) recordPeer(base *gen.ProtoMessageBase) {
+	bu.peerMtx.Lock()
+	defer bu.peerMtx.Unlock()
+	if bu.peerAnnounced {
+		return
+	}
+	bu.peerAnnounced = true
+	bu.peer = Announcement{Version: base.GetProtocolVersion(), Capabilities: base.GetCapabilities()}
+	if bu.peer.Version != ProtocolVersion {
+		slog.Debug("Peer speaks another protocol version", "version", bu.peer.Version, "capabilities", bu.peer.Capabilities)
+	}
+}
+
+// Peer returns protocol version and capabilities the other side announced, zero until its first message
+func (bu *SafeBufioRW) Peer() Announcement {
+	bu.peerMtx.Lock()
+	defer bu.peerMtx.Unlock()
+	return bu.peer
+}
+
+// sendInvalid tells the sender which of its messages failed validation
+func (bu *SafeBufioRW) sendInvalid(invalid *InvalidMessageError) {
+	msg, err := CreateMessage(&gen.ProtoInvalidMessage{
+		PayloadType: invalid.PayloadType,
+		Field:       invalid.Field,
+		Reason:      invalid.Reason,
+	}, "invalid-message", nil)
+	if err != nil {
+		slog.Error("Failed to create proto message", "err", err)
+		return
+	}
+	if err = bu.SendProto(msg); err != nil {
+		slog.Debug("Failed to send invalid message response", "payload_type", invalid.PayloadType, "err", err)
+	}
+}
+
+// checkReplay drops messages already received on this stream, such as a resent answer
+func (bu *SafeBufioRW) checkReplay(msg *gen.ProtoMessage) error {
+	base := msg.GetMessageBase()
+	reason := bu.replay.Check(base.GetStreamNonce(), base.GetSequence())
+	if len(reason) <= 0 {
+		return nil
+	}
+	protocolReplayedCounter.WithLabelValues(base.GetPayloadType(), reason).Inc()
+	slog.Warn("Dropping replayed message", "payload_type", base.GetPayloadType(), "sequence", base.GetSequence(), "reason", reason)
+	return ErrMessageRejected
 }
 
 type CreateMessageOptions struct {
@@ -85,7 +231,8 @@ type CreateMessageOptions struct {
 func CreateMessage(payload proto.Message, payloadType string, opts *CreateMessageOptions) (*gen.ProtoMessage, error) {
 	msg := &gen.ProtoMessage{
 		MessageBase: &gen.ProtoMessageBase{
-			PayloadType: payloadType,
+			PayloadType:     payloadType,
+			ProtocolVersion: ProtocolVersion,
 		},
 	}
 
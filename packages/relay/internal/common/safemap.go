@@ -42,6 +42,18 @@ func (sm *SafeMap[K, V]) Set(key K, value V) {
 	sm.m[key] = value
 }
 
+// Update replaces value of an existing key with what f returns for it, atomically with respect to other map operations.
+// Returns false if key doesn't exist.
+func (sm *SafeMap[K, V]) Update(key K, f func(V) V) bool {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	v, ok := sm.m[key]
+	if ok {
+		sm.m[key] = f(v)
+	}
+	return ok
+}
+
 // Delete removes a key from the map
 func (sm *SafeMap[K, V]) Delete(key K) {
 	sm.mu.Lock()
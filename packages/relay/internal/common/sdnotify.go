@@ -0,0 +1,83 @@
+package common
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// systemd notification states
+const (
+	SdNotifyReady    = "READY=1"
+	SdNotifyStopping = "STOPPING=1"
+	SdNotifyWatchdog = "WATCHDOG=1"
+)
+
+// SdNotify sends state to systemd service manager, returns false without error when not run under systemd with Type=notify
+func SdNotify(state string) (bool, error) {
+	socketAddr := os.Getenv("NOTIFY_SOCKET")
+	if len(socketAddr) <= 0 {
+		return false, nil
+	}
+	// Leading @ means abstract namespace socket
+	if socketAddr[0] == '@' {
+		socketAddr = "\x00" + socketAddr[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socketAddr, Net: "unixgram"})
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	if _, err = conn.Write([]byte(state)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SdNotifyStatus sends state to systemd with a human readable status line, logging failures
+func SdNotifyStatus(state, status string) {
+	if _, err := SdNotify(state + "\nSTATUS=" + status); err != nil {
+		slog.Warn("Failed to notify systemd", "state", state, "err", err)
+	}
+}
+
+// sdWatchdogInterval returns watchdog timeout systemd expects pings within, 0 if watchdog is disabled for this process
+func sdWatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	// Watchdog may be meant for another process of the service
+	if pidStr := os.Getenv("WATCHDOG_PID"); len(pidStr) > 0 {
+		if pid, err := strconv.Atoi(pidStr); err != nil || pid != os.Getpid() {
+			return 0
+		}
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// RunSdWatchdog pings systemd watchdog at half its timeout until context is done, returns at once if watchdog is disabled
+func RunSdWatchdog(ctx context.Context) {
+	interval := sdWatchdogInterval()
+	if interval <= 0 {
+		return
+	}
+	slog.Info("Pinging systemd watchdog", "timeout", interval)
+
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := SdNotify(SdNotifyWatchdog); err != nil {
+				slog.Warn("Failed to ping systemd watchdog", "err", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,245 @@
+//go:build linux
+
+package common
+
+import (
+	"errors"
+	"log/slog"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+	"unsafe"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+	"golang.org/x/sys/unix"
+)
+
+// Limits of one UDP GSO send, segments the kernel accepts and payload fitting one IP packet
+const (
+	udpGSOMaxSegments = 64
+	udpGSOMaxBytes    = 65000
+)
+
+// udpBatchWriter writes several messages in one sendmmsg call, implemented by ipv4 and ipv6 PacketConn
+type udpBatchWriter interface {
+	WriteBatch(ms []ipv4.Message, flags int) (int, error)
+}
+
+// udpBatchPacket is a write waiting in a batch, buf is a copy owned by the batch
+type udpBatchPacket struct {
+	buf  []byte
+	addr net.Addr
+}
+
+// udpBatchConn queues writes to the UDP mux and sends them in one sendmmsg call once the batch is full or
+// its delay passed. With UDP GSO, consecutive equally sized packets to one address, such as the RTP packets
+// of a video frame to one viewer, leave as one message the kernel or NIC splits into datagrams.
+type udpBatchConn struct {
+	net.PacketConn
+	writer udpBatchWriter
+	size   int
+	delay  time.Duration
+
+	mtx     sync.Mutex
+	pending []udpBatchPacket
+	free    [][]byte
+	timer   *time.Timer
+	gso     bool // Cleared when the kernel or NIC turns GSO sends down
+	closed  bool
+
+	// Reused across flushes
+	msgs []ipv4.Message
+	open map[netip.AddrPort]int // Message of each address more segments may be added to
+	segs []int                  // Segment size of each message
+}
+
+// newUDPBatchConn wraps conn to batch its writes, conn is returned unchanged if it can't send batches
+func newUDPBatchConn(conn net.PacketConn, size int, delay time.Duration) net.PacketConn {
+	udpConn, ok := conn.(*net.UDPConn)
+	if !ok {
+		return conn
+	}
+	c := &udpBatchConn{
+		PacketConn: conn,
+		size:       size,
+		delay:      delay,
+		open:       make(map[netip.AddrPort]int),
+	}
+	if addr, ok := udpConn.LocalAddr().(*net.UDPAddr); ok && addr.IP.To4() != nil {
+		c.writer = ipv4.NewPacketConn(udpConn)
+	} else {
+		c.writer = ipv6.NewPacketConn(udpConn)
+	}
+	c.gso = udpGSOSupported(udpConn)
+	c.timer = time.AfterFunc(delay, func() {
+		c.mtx.Lock()
+		defer c.mtx.Unlock()
+		if err := c.flush(); err != nil {
+			slog.Debug("Failed to send batched UDP writes", "err", err)
+		}
+	})
+	c.timer.Stop()
+	return c
+}
+
+// udpGSOSupported reports whether the kernel accepts UDP GSO sends on conn
+func udpGSOSupported(conn *net.UDPConn) bool {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return false
+	}
+	supported := false
+	_ = raw.Control(func(fd uintptr) {
+		_, err := unix.GetsockoptInt(int(fd), unix.IPPROTO_UDP, unix.UDP_SEGMENT)
+		supported = err == nil
+	})
+	return supported
+}
+
+func (c *udpBatchConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.closed {
+		return 0, net.ErrClosed
+	}
+
+	var buf []byte
+	if n := len(c.free); n > 0 {
+		buf, c.free = c.free[n-1], c.free[:n-1]
+	}
+	c.pending = append(c.pending, udpBatchPacket{buf: append(buf[:0], p...), addr: addr})
+	if len(c.pending) >= c.size {
+		c.timer.Stop()
+		return len(p), c.flush()
+	}
+	if len(c.pending) == 1 {
+		c.timer.Reset(c.delay)
+	}
+	return len(p), nil
+}
+
+func (c *udpBatchConn) Close() error {
+	c.mtx.Lock()
+	c.closed = true
+	c.timer.Stop()
+	_ = c.flush()
+	c.mtx.Unlock()
+	return c.PacketConn.Close()
+}
+
+// flush sends pending packets, caller holds mtx
+func (c *udpBatchConn) flush() error {
+	if len(c.pending) <= 0 {
+		return nil
+	}
+	c.batch()
+	sent, err := c.write(c.msgs)
+	if errors.Is(err, unix.EIO) && c.gso {
+		// GSO sends fail when the NIC can't checksum them, the rest and later packets are sent one by one
+		slog.Warn("UDP GSO sends failed, batching without GSO", "err", err)
+		c.gso = false
+		var rest []ipv4.Message
+		for _, msg := range c.msgs[sent:] {
+			for _, buf := range msg.Buffers {
+				rest = append(rest, ipv4.Message{Buffers: [][]byte{buf}, Addr: msg.Addr})
+			}
+		}
+		_, err = c.write(rest)
+	}
+
+	for i := range c.pending {
+		c.free = append(c.free, c.pending[i].buf)
+		c.pending[i] = udpBatchPacket{}
+	}
+	c.pending = c.pending[:0]
+	return err
+}
+
+// batch builds messages of pending packets. With GSO, a packet joins the last message to its address if it
+// isn't larger than that message's segments, a smaller one ends the message as the kernel only allows the last
+// segment to be short.
+func (c *udpBatchConn) batch() {
+	clear(c.open)
+	for i := range c.msgs {
+		c.msgs[i].Buffers = c.msgs[i].Buffers[:0]
+		c.msgs[i].OOB = c.msgs[i].OOB[:0]
+	}
+	c.msgs = c.msgs[:0]
+	c.segs = c.segs[:0]
+
+	for _, packet := range c.pending {
+		var key netip.AddrPort
+		if addr, ok := packet.addr.(*net.UDPAddr); ok && c.gso {
+			key = addr.AddrPort()
+			if i, ok := c.open[key]; ok {
+				msg := &c.msgs[i]
+				bytes := len(packet.buf)
+				for _, b := range msg.Buffers {
+					bytes += len(b)
+				}
+				if len(packet.buf) <= c.segs[i] && len(msg.Buffers) < udpGSOMaxSegments && bytes <= udpGSOMaxBytes {
+					msg.Buffers = append(msg.Buffers, packet.buf)
+					if len(packet.buf) < c.segs[i] {
+						delete(c.open, key)
+					}
+					continue
+				}
+			}
+		}
+
+		if len(c.msgs) < cap(c.msgs) {
+			c.msgs = c.msgs[:len(c.msgs)+1]
+		} else {
+			c.msgs = append(c.msgs, ipv4.Message{})
+		}
+		msg := &c.msgs[len(c.msgs)-1]
+		msg.Buffers = append(msg.Buffers, packet.buf)
+		msg.Addr = packet.addr
+		c.segs = append(c.segs, len(packet.buf))
+		if key.IsValid() {
+			c.open[key] = len(c.msgs) - 1
+		}
+	}
+
+	for i := range c.msgs {
+		if msg := &c.msgs[i]; len(msg.Buffers) > 1 {
+			msg.OOB = appendUDPSegment(msg.OOB, c.segs[i])
+		}
+	}
+}
+
+// write sends msgs and returns how many were handled, a message the kernel refuses is skipped so one
+// unreachable viewer doesn't cost the others their packets. It stops at a failed GSO send for the caller to
+// send the rest without GSO.
+func (c *udpBatchConn) write(msgs []ipv4.Message) (int, error) {
+	var firstErr error
+	sent := 0
+	for sent < len(msgs) {
+		n, err := c.writer.WriteBatch(msgs[sent:], 0)
+		sent += max(n, 0)
+		if err != nil {
+			if c.gso && errors.Is(err, unix.EIO) {
+				return sent, err
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			sent++
+		}
+	}
+	return sent, firstErr
+}
+
+// appendUDPSegment appends a UDP_SEGMENT control message splitting a send into datagrams of size bytes
+func appendUDPSegment(oob []byte, size int) []byte {
+	start := len(oob)
+	oob = append(oob, make([]byte, unix.CmsgSpace(2))...)
+	h := (*unix.Cmsghdr)(unsafe.Pointer(&oob[start]))
+	h.Level = unix.IPPROTO_UDP
+	h.Type = unix.UDP_SEGMENT
+	h.SetLen(unix.CmsgLen(2))
+	*(*uint16)(unsafe.Pointer(&oob[start+unix.CmsgLen(0)])) = uint16(size)
+	return oob
+}
@@ -0,0 +1,161 @@
+//go:build linux
+
+package common
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// udpFramePackets and udpFrameTail shape a video frame as sent to a viewer, equally sized RTP packets and a
+// shorter last one
+const (
+	udpFramePackets = 8
+	udpPacketSize   = 1200
+	udpFrameTail    = 700
+)
+
+// udpFramePacket returns packet i of a frame, tagged with the frame and its index to check what arrives
+func udpFramePacket(frame, i int) []byte {
+	size := udpPacketSize
+	if i == udpFramePackets-1 {
+		size = udpFrameTail
+	}
+	packet := make([]byte, size)
+	copy(packet, fmt.Sprintf("%d/%d", frame, i))
+	return packet
+}
+
+// newTestBatchConn listens on loopback with writes batched, GSO only if gso is set
+func newTestBatchConn(tb testing.TB, size int, gso bool) *udpBatchConn {
+	tb.Helper()
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	batch, ok := newUDPBatchConn(conn, size, time.Millisecond).(*udpBatchConn)
+	if !ok {
+		tb.Fatal("UDP conn wasn't batched")
+	}
+	if gso && !batch.gso {
+		tb.Skip("kernel doesn't support UDP GSO")
+	}
+	batch.gso = gso
+	tb.Cleanup(func() { batch.Close() })
+	return batch
+}
+
+// TestUDPBatchConnFrames interleaves frames to two viewers, each must receive its own datagrams whole and in order
+func TestUDPBatchConnFrames(t *testing.T) {
+	for _, gso := range []bool{false, true} {
+		t.Run(fmt.Sprintf("gso=%t", gso), func(t *testing.T) {
+			conn := newTestBatchConn(t, 64, gso)
+			receivers := make([]net.PacketConn, 2)
+			for i := range receivers {
+				receiver, err := net.ListenPacket("udp4", "127.0.0.1:0")
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer receiver.Close()
+				receivers[i] = receiver
+			}
+
+			const frames = 3
+			for frame := range frames {
+				for i := range udpFramePackets {
+					for _, receiver := range receivers {
+						if _, err := conn.WriteTo(udpFramePacket(frame, i), receiver.LocalAddr()); err != nil {
+							t.Fatal(err)
+						}
+					}
+				}
+			}
+
+			buf := make([]byte, 65536)
+			for _, receiver := range receivers {
+				_ = receiver.SetReadDeadline(time.Now().Add(time.Second))
+				for frame := range frames {
+					for i := range udpFramePackets {
+						n, _, err := receiver.ReadFrom(buf)
+						if err != nil {
+							t.Fatalf("frame %d packet %d: %v", frame, i, err)
+						}
+						if want := udpFramePacket(frame, i); !bytes.Equal(buf[:n], want) {
+							t.Fatalf("frame %d packet %d: got %d bytes starting %q", frame, i, n, buf[:min(n, 8)])
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkUDPMuxWrite fans frames out to viewers over one socket, each write a syscall or batched like with
+// webrtcUDPBatchSize, with or without GSO. Writers either send whole frames to their viewer, as participant
+// writers do, or one writer interleaves viewers packet by packet, the worst case for GSO.
+func BenchmarkUDPMuxWrite(b *testing.B) {
+	const viewers = 64
+
+	// Receivers only need to exist, unread datagrams are dropped by the kernel past their buffer
+	addrs := make([]net.Addr, viewers)
+	for i := range addrs {
+		receiver, err := net.ListenPacket("udp4", "127.0.0.1:0")
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer receiver.Close()
+		addrs[i] = receiver.LocalAddr()
+	}
+
+	frame := make([][]byte, udpFramePackets)
+	for i := range frame {
+		frame[i] = udpFramePacket(0, i)
+	}
+	run := func(b *testing.B, conn net.PacketConn, interleaved bool) {
+		b.SetBytes((udpFramePackets-1)*udpPacketSize/udpFramePackets + udpFrameTail/udpFramePackets)
+		if interleaved {
+			b.ResetTimer()
+			for i := range b.N {
+				if _, err := conn.WriteTo(frame[i/viewers%udpFramePackets], addrs[i%viewers]); err != nil {
+					b.Fatal(err)
+				}
+			}
+			return
+		}
+
+		var next atomic.Int32
+		b.SetParallelism(viewers)
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			addr := addrs[int(next.Add(1)-1)%viewers]
+			for i := 0; pb.Next(); i++ {
+				if _, err := conn.WriteTo(frame[i%udpFramePackets], addr); err != nil {
+					b.Error(err)
+					return
+				}
+			}
+		})
+	}
+
+	for _, interleaved := range []bool{false, true} {
+		b.Run(fmt.Sprintf("interleaved=%t/unbatched", interleaved), func(b *testing.B) {
+			conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer conn.Close()
+			run(b, conn, interleaved)
+		})
+		for _, size := range []int{16, 64, 256} {
+			for _, gso := range []bool{false, true} {
+				b.Run(fmt.Sprintf("interleaved=%t/batch-%d/gso=%t", interleaved, size, gso), func(b *testing.B) {
+					run(b, newTestBatchConn(b, size, gso), interleaved)
+				})
+			}
+		}
+	}
+}
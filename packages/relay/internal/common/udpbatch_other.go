@@ -0,0 +1,13 @@
+//go:build !linux
+
+package common
+
+import (
+	"net"
+	"time"
+)
+
+// newUDPBatchConn returns conn unchanged, batched writes need sendmmsg which only Linux has
+func newUDPBatchConn(conn net.PacketConn, _ int, _ time.Duration) net.PacketConn {
+	return conn
+}
@@ -0,0 +1,254 @@
+package common
+
+import (
+	"fmt"
+	"log/slog"
+	gen "relay/internal/proto"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Limits of received message fields
+const (
+	MaxRoomNameLength  = 128       // Bytes of a room name
+	maxSessionIDLength = 64        // Bytes of a session ID
+	maxTokenLength     = 4096      // Bytes of a viewer token
+	maxSecretLength    = 256       // Bytes of a room access secret, push signature or push key
+	maxSDPLength       = 256 << 10 // Bytes of a session description
+	maxCandidateLength = 1024      // Bytes of an ICE candidate line
+	maxMIDLength       = 32        // Bytes of a media ID
+	maxUfragLength     = 256       // Bytes of an ICE username fragment
+	maxMetadataLength  = 256       // Bytes of a room title or game name
+	maxVariantLength   = 32        // Bytes of a variant name
+)
+
+// Reasons a received message is invalid
+const (
+	InvalidMissing = "missing"
+	InvalidTooLong = "too-long"
+	InvalidUTF8    = "invalid-utf8"
+	InvalidValue   = "invalid-value"
+)
+
+var protocolInvalidCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "nestri_relay_protocol_invalid_total",
+	Help: "Received messages rejected by validation",
+}, []string{"payload_type", "reason"})
+
+// InvalidMessageError is returned by ReceiveProto for a message failing validation, the stream stays usable
+type InvalidMessageError struct {
+	PayloadType string
+	Field       string // Empty if the payload is missing
+	Reason      string
+}
+
+func (e *InvalidMessageError) Error() string {
+	if len(e.Field) <= 0 {
+		return fmt.Sprintf("invalid %s message: payload %s", e.PayloadType, e.Reason)
+	}
+	return fmt.Sprintf("invalid %s message: %s %s", e.PayloadType, e.Field, e.Reason)
+}
+
+// Is makes invalid messages match ErrMessageRejected, receive loops skip them like other rejected messages
+func (e *InvalidMessageError) Is(target error) bool {
+	return target == ErrMessageRejected
+}
+
+// messageValidators check payloads of the payload types relays handle, other payload types aren't validated
+var messageValidators = map[string]func(msg *gen.ProtoMessage) *InvalidMessageError{
+	"request-stream-room":        validateStreamRequest,
+	"push-stream-room":           validatePush,
+	"push-challenge-response":    validatePushChallengeResponse,
+	"room-metadata":              validateRoomMetadata,
+	"ice-candidate":              validateICE,
+	"offer":                      validateSDP,
+	"answer":                     validateSDP,
+	"mesh-room-tracks":           validateMeshRoomTracks,
+	"mesh-release-room":          validateRoomRelease,
+	"request-stream-unsubscribe": validateRoomRelease,
+	"stream-path-info": func(msg *gen.ProtoMessage) *InvalidMessageError {
+		if msg.GetStreamPathInfo() == nil {
+			return &InvalidMessageError{}
+		}
+		return checkRoomName("room_name", msg.GetStreamPathInfo().RoomName)
+	},
+	"directory-query": func(msg *gen.ProtoMessage) *InvalidMessageError {
+		if msg.GetDirectoryQuery() == nil {
+			return &InvalidMessageError{}
+		}
+		if err := checkString("prefix", msg.GetDirectoryQuery().Prefix, MaxRoomNameLength); err != nil {
+			return err
+		}
+		return checkString("cursor", msg.GetDirectoryQuery().Cursor, MaxRoomNameLength)
+	},
+}
+
+// ValidateMessage checks a received message has the payload its type requires, with fields within limits.
+// Messages without a message base or of payload types we don't handle are left to receivers.
+func ValidateMessage(msg *gen.ProtoMessage) error {
+	payloadType := msg.GetMessageBase().GetPayloadType()
+	validate, ok := messageValidators[payloadType]
+	if !ok {
+		return nil
+	}
+	invalid := validate(msg)
+	if invalid == nil {
+		return nil
+	}
+	invalid.PayloadType = payloadType
+	if len(invalid.Reason) <= 0 {
+		invalid.Reason = InvalidMissing
+	}
+	protocolInvalidCounter.WithLabelValues(payloadType, invalid.Reason).Inc()
+	slog.Warn("Rejecting invalid message", "payload_type", payloadType, "field", invalid.Field, "reason", invalid.Reason)
+	return invalid
+}
+
+func validateStreamRequest(msg *gen.ProtoMessage) *InvalidMessageError {
+	req := msg.GetClientRequestRoomStream()
+	if req == nil {
+		return &InvalidMessageError{}
+	}
+	if err := checkRoomName("room_name", req.RoomName); err != nil {
+		return err
+	}
+	if err := checkString("session_id", req.SessionId, maxSessionIDLength); err != nil {
+		return err
+	}
+	if err := checkString("token", req.Token, maxTokenLength); err != nil {
+		return err
+	}
+	return checkString("access_secret", req.AccessSecret, maxSecretLength)
+}
+
+func validatePush(msg *gen.ProtoMessage) *InvalidMessageError {
+	push := msg.GetServerPushStream()
+	if push == nil {
+		return &InvalidMessageError{}
+	}
+	if err := checkRoomName("room_name", push.RoomName); err != nil {
+		return err
+	}
+	if err := checkString("signature", push.Signature, maxSecretLength); err != nil {
+		return err
+	}
+	if err := checkString("variant", push.Variant, maxVariantLength); err != nil {
+		return err
+	}
+	if err := checkString("settings.access_secret", push.GetSettings().GetAccessSecret(), maxSecretLength); err != nil {
+		return err
+	}
+	return checkMetadata(push.Metadata)
+}
+
+func validatePushChallengeResponse(msg *gen.ProtoMessage) *InvalidMessageError {
+	res := msg.GetPushChallengeResponse()
+	if res == nil {
+		return &InvalidMessageError{}
+	}
+	if err := checkRoomName("room_name", res.RoomName); err != nil {
+		return err
+	}
+	if err := checkString("public_key", res.PublicKey, maxSecretLength); err != nil {
+		return err
+	}
+	return checkString("signature", res.Signature, maxSecretLength)
+}
+
+func validateRoomRelease(msg *gen.ProtoMessage) *InvalidMessageError {
+	if msg.GetRaw() == nil {
+		return &InvalidMessageError{}
+	}
+	return checkRoomName("data", msg.GetRaw().Data)
+}
+
+func validateRoomMetadata(msg *gen.ProtoMessage) *InvalidMessageError {
+	if msg.GetRoomMetadata() == nil {
+		return &InvalidMessageError{}
+	}
+	return checkMetadata(msg.GetRoomMetadata())
+}
+
+func checkMetadata(meta *gen.ProtoRoomMetadata) *InvalidMessageError {
+	if meta == nil {
+		return nil
+	}
+	if err := checkString("metadata.title", meta.Title, maxMetadataLength); err != nil {
+		return err
+	}
+	return checkString("metadata.game", meta.Game, maxMetadataLength)
+}
+
+func validateICE(msg *gen.ProtoMessage) *InvalidMessageError {
+	cand := msg.GetIce().GetCandidate()
+	if cand == nil {
+		return &InvalidMessageError{Field: "candidate"}
+	}
+	if err := checkString("candidate.candidate", cand.Candidate, maxCandidateLength); err != nil {
+		return err
+	}
+	if cand.SdpMLineIndex != nil && *cand.SdpMLineIndex > 0xffff {
+		return &InvalidMessageError{Field: "candidate.sdpMLineIndex", Reason: InvalidValue}
+	}
+	if err := checkString("candidate.sdpMid", cand.GetSdpMid(), maxMIDLength); err != nil {
+		return err
+	}
+	return checkString("candidate.usernameFragment", cand.GetUsernameFragment(), maxUfragLength)
+}
+
+func validateSDP(msg *gen.ProtoMessage) *InvalidMessageError {
+	sdp := msg.GetSdp().GetSdp()
+	if sdp == nil {
+		return &InvalidMessageError{Field: "sdp"}
+	}
+	if sdp.Type != msg.GetMessageBase().GetPayloadType() {
+		return &InvalidMessageError{Field: "sdp.type", Reason: InvalidValue}
+	}
+	if len(sdp.Sdp) <= 0 {
+		return &InvalidMessageError{Field: "sdp.sdp"}
+	}
+	return checkString("sdp.sdp", sdp.Sdp, maxSDPLength)
+}
+
+func validateMeshRoomTracks(msg *gen.ProtoMessage) *InvalidMessageError {
+	tracks := msg.GetMeshRoomTracks()
+	if tracks == nil {
+		return &InvalidMessageError{}
+	}
+	if err := checkRoomName("room_name", tracks.RoomName); err != nil {
+		return err
+	}
+	if err := checkString("audio_mid", tracks.AudioMid, maxMIDLength); err != nil {
+		return err
+	}
+	return checkString("video_mid", tracks.VideoMid, maxMIDLength)
+}
+
+// checkRoomName requires a non-empty UTF-8 room name without control characters
+func checkRoomName(field, name string) *InvalidMessageError {
+	if len(name) <= 0 {
+		return &InvalidMessageError{Field: field, Reason: InvalidMissing}
+	}
+	if err := checkString(field, name, MaxRoomNameLength); err != nil {
+		return err
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) {
+			return &InvalidMessageError{Field: field, Reason: InvalidValue}
+		}
+	}
+	return nil
+}
+
+// checkString requires valid UTF-8 of at most maxLen bytes, empty strings pass
+func checkString(field, s string, maxLen int) *InvalidMessageError {
+	if len(s) > maxLen {
+		return &InvalidMessageError{Field: field, Reason: InvalidTooLong}
+	}
+	if !utf8.ValidString(s) {
+		return &InvalidMessageError{Field: field, Reason: InvalidUTF8}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package common
+
+import (
+	"errors"
+	gen "relay/internal/proto"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+)
+
+func TestValidateMessage(t *testing.T) {
+	base := func(payloadType string) *gen.ProtoMessageBase {
+		return &gen.ProtoMessageBase{PayloadType: payloadType}
+	}
+	tests := []struct {
+		name   string
+		msg    *gen.ProtoMessage
+		field  string
+		reason string
+	}{
+		{
+			name: "unhandled payload type",
+			msg:  &gen.ProtoMessage{MessageBase: base("session-assigned")},
+		},
+		{
+			name: "valid stream request",
+			msg: &gen.ProtoMessage{MessageBase: base("request-stream-room"), Payload: &gen.ProtoMessage_ClientRequestRoomStream{
+				ClientRequestRoomStream: &gen.ProtoClientRequestRoomStream{RoomName: "room"},
+			}},
+		},
+		{
+			name:   "stream request without payload",
+			msg:    &gen.ProtoMessage{MessageBase: base("request-stream-room")},
+			reason: InvalidMissing,
+		},
+		{
+			name: "stream request with control characters in room name",
+			msg: &gen.ProtoMessage{MessageBase: base("request-stream-room"), Payload: &gen.ProtoMessage_ClientRequestRoomStream{
+				ClientRequestRoomStream: &gen.ProtoClientRequestRoomStream{RoomName: "room\n"},
+			}},
+			field:  "room_name",
+			reason: InvalidValue,
+		},
+		{
+			name: "push with long room name",
+			msg: &gen.ProtoMessage{MessageBase: base("push-stream-room"), Payload: &gen.ProtoMessage_ServerPushStream{
+				ServerPushStream: &gen.ProtoServerPushStream{RoomName: strings.Repeat("r", MaxRoomNameLength+1)},
+			}},
+			field:  "room_name",
+			reason: InvalidTooLong,
+		},
+		{
+			name: "candidate without index",
+			msg: &gen.ProtoMessage{MessageBase: base("ice-candidate"), Payload: &gen.ProtoMessage_Ice{
+				Ice: &gen.ProtoICE{Candidate: &gen.RTCIceCandidateInit{Candidate: "candidate:1 1 udp 1 127.0.0.1 9 typ host"}},
+			}},
+		},
+		{
+			name: "candidate with out of range index",
+			msg: &gen.ProtoMessage{MessageBase: base("ice-candidate"), Payload: &gen.ProtoMessage_Ice{
+				Ice: &gen.ProtoICE{Candidate: &gen.RTCIceCandidateInit{SdpMLineIndex: proto.Uint32(1 << 16)}},
+			}},
+			field:  "candidate.sdpMLineIndex",
+			reason: InvalidValue,
+		},
+		{
+			name:   "ice without candidate",
+			msg:    &gen.ProtoMessage{MessageBase: base("ice-candidate"), Payload: &gen.ProtoMessage_Ice{Ice: &gen.ProtoICE{}}},
+			field:  "candidate",
+			reason: InvalidMissing,
+		},
+		{
+			name: "answer typed as offer",
+			msg: &gen.ProtoMessage{MessageBase: base("answer"), Payload: &gen.ProtoMessage_Sdp{
+				Sdp: &gen.ProtoSDP{Sdp: &gen.RTCSessionDescriptionInit{Sdp: "v=0", Type: "offer"}},
+			}},
+			field:  "sdp.type",
+			reason: InvalidValue,
+		},
+		{
+			name: "oversized offer",
+			msg: &gen.ProtoMessage{MessageBase: base("offer"), Payload: &gen.ProtoMessage_Sdp{
+				Sdp: &gen.ProtoSDP{Sdp: &gen.RTCSessionDescriptionInit{Sdp: strings.Repeat("a", maxSDPLength+1), Type: "offer"}},
+			}},
+			field:  "sdp.sdp",
+			reason: InvalidTooLong,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateMessage(tt.msg)
+			if len(tt.reason) <= 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var invalid *InvalidMessageError
+			if !errors.As(err, &invalid) {
+				t.Fatalf("expected InvalidMessageError, got %v", err)
+			}
+			if !errors.Is(err, ErrMessageRejected) {
+				t.Fatal("invalid message error doesn't match ErrMessageRejected")
+			}
+			if invalid.Field != tt.field || invalid.Reason != tt.reason {
+				t.Fatalf("got field %q reason %q, want field %q reason %q", invalid.Field, invalid.Reason, tt.field, tt.reason)
+			}
+		})
+	}
+}
@@ -2,7 +2,9 @@ package connections
 
 import (
 	"log/slog"
+	"relay/internal/common"
 	gen "relay/internal/proto"
+	"sync/atomic"
 
 	"github.com/pion/webrtc/v4"
 	"google.golang.org/protobuf/proto"
@@ -10,10 +12,14 @@ import (
 
 type OnMessageCallback func(data []byte)
 
+// MessageGate decides if a message gets handled, done is called once its callback returns
+type MessageGate func() (done func(), ok bool)
+
 // NestriDataChannel is a custom data channel with callbacks
 type NestriDataChannel struct {
 	*webrtc.DataChannel
 	callbacks map[string]OnMessageCallback // MessageBase type -> callback
+	gate      atomic.Pointer[MessageGate]  // Consulted before running a callback, nil handles every message
 }
 
 // NewNestriDataChannel creates a new NestriDataChannel from *webrtc.DataChannel
@@ -36,11 +42,26 @@ func NewNestriDataChannel(dc *webrtc.DataChannel) *NestriDataChannel {
 			slog.Error("failed to decode binary DataChannel message", "err", err)
 			return
 		}
+		if err := common.CheckMessage(&base); err != nil {
+			return
+		}
 
 		// Route based on PayloadType
 		if base.MessageBase != nil && len(base.MessageBase.PayloadType) > 0 {
 			if callback, ok := ndc.callbacks[base.MessageBase.PayloadType]; ok {
-				go callback(msg.Data)
+				gate := ndc.gate.Load()
+				if gate == nil {
+					go callback(msg.Data)
+					return
+				}
+				done, admitted := (*gate)()
+				if !admitted {
+					return
+				}
+				go func() {
+					defer done()
+					callback(msg.Data)
+				}()
 			}
 		}
 	})
@@ -68,6 +89,15 @@ func (ndc *NestriDataChannel) UnregisterMessageCallback(msgType string) {
 	}
 }
 
+// SetMessageGate sets gate limiting which messages get handled, nil handles every message
+func (ndc *NestriDataChannel) SetMessageGate(gate MessageGate) {
+	if gate == nil {
+		ndc.gate.Store(nil)
+		return
+	}
+	ndc.gate.Store(&gate)
+}
+
 // RegisterOnOpen registers a callback for the data channel opening
 func (ndc *NestriDataChannel) RegisterOnOpen(callback func()) {
 	ndc.OnOpen(callback)
@@ -0,0 +1,85 @@
+package core
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+
+	"github.com/libp2p/go-libp2p/core/event"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+)
+
+// --- Advertised Addresses ---
+//
+// Our addresses change as AutoNAT confirms observed ones and routers map ports, they're announced with relay
+// status whenever libp2p reports a change. Addresses identify told us of a peer are merged into its announced ones,
+// so relays announcing stale addresses still get dialed where they are.
+
+// advertisedAddrs returns our current addresses, public ones first as those work from anywhere
+func (r *Relay) advertisedAddrs() []multiaddr.Multiaddr {
+	addrs := slices.Clone(r.Host.Addrs())
+	slices.SortStableFunc(addrs, func(a, b multiaddr.Multiaddr) int {
+		switch aPublic, bPublic := manet.IsPublicAddr(a), manet.IsPublicAddr(b); {
+		case aPublic == bPublic:
+			return 0
+		case aPublic:
+			return -1
+		default:
+			return 1
+		}
+	})
+	return addrs
+}
+
+// addrsWatcher updates our announced addresses when libp2p reports a change, announcing them right away
+func (r *Relay) addrsWatcher(ctx context.Context) {
+	sub, err := r.Host.EventBus().Subscribe(new(event.EvtLocalAddressesUpdated))
+	if err != nil {
+		slog.Warn("Failed to subscribe to address changes", "err", err)
+		return
+	}
+	defer func() {
+		_ = sub.Close()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-sub.Out():
+			if !ok {
+				return
+			}
+			addrs := r.advertisedAddrs()
+			r.statusMtx.Lock()
+			changed := !slices.EqualFunc(r.PeerInfo.Addrs, addrs, multiaddr.Multiaddr.Equal)
+			r.PeerInfo.Addrs = addrs
+			r.statusMtx.Unlock()
+			if !changed {
+				continue
+			}
+			slog.Info("Relay addresses changed", "addrs", addrs)
+			if err = r.publishRelayMetrics(ctx); err != nil {
+				slog.Error("Failed to publish relay metrics on address change", "err", err)
+			}
+		}
+	}
+}
+
+// mergeIdentifiedAddrs adds public addresses identify told us of a peer to those it announced
+func (r *Relay) mergeIdentifiedAddrs(pi *PeerInfo) {
+	for _, addr := range r.Host.Peerstore().Addrs(pi.ID) {
+		if !manet.IsPublicAddr(addr) {
+			continue
+		}
+		known := slices.ContainsFunc(pi.Addrs, func(announced multiaddr.Multiaddr) bool {
+			transport, _ := peer.SplitAddr(announced)
+			return transport != nil && transport.Equal(addr)
+		})
+		if !known {
+			pi.Addrs = append(pi.Addrs, addr)
+		}
+	}
+}
@@ -0,0 +1,934 @@
+package core
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"relay/internal/common"
+	"relay/internal/shared"
+	"strconv"
+	"strings"
+	"time"
+
+	gen "relay/internal/proto"
+
+	"github.com/gorilla/websocket"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multiaddr"
+	"github.com/oklog/ulid/v2"
+)
+
+// --- Admin API Types ---
+
+type adminParticipant struct {
+	ID            ulid.ULID              `json:"id"`
+	SessionID     string                 `json:"session_id"`
+	PeerID        peer.ID                `json:"peer_id"`
+	Role          shared.ViewerRole      `json:"role"`
+	QueueDelay    time.Duration          `json:"queue_delay"`
+	DroppedFrames uint64                 `json:"dropped_frames"`
+	InputAllowed  bool                   `json:"input_allowed"`
+	DroppedInput  uint64                 `json:"dropped_input"` // Input messages dropped as not allowed
+	InputLatency  shared.LatencySnapshot `json:"input_latency"` // Input round trips from the relay to the server and back
+	BytesSent     uint64                 `json:"bytes_sent"`    // RTP payload bytes written to the participant
+}
+
+type adminRoom struct {
+	shared.RoomInfo
+	UpstreamID   peer.ID                   `json:"upstream_id,omitempty"`
+	HopLatency   time.Duration             `json:"hop_latency"`
+	AudioStats   shared.TrackStatsSnapshot `json:"audio_stats"`
+	VideoStats   shared.TrackStatsSnapshot `json:"video_stats"`
+	Waiting      int                       `json:"waiting,omitempty"` // Viewer requests waiting for the room to come online
+	Participants []adminParticipant        `json:"participants,omitempty"`
+}
+
+// adminParticipantFilter selects participants of a room for bulk operations, empty selects all
+type adminParticipantFilter struct {
+	ParticipantIDs []ulid.ULID `json:"participant_ids,omitempty"` // Only these participants, all must exist
+	PeerID         peer.ID     `json:"peer_id,omitempty"`         // Only participants connected through this peer
+	ExcludeIDs     []ulid.ULID `json:"exclude_ids,omitempty"`     // Never these participants
+}
+
+type adminBulkRequest struct {
+	adminParticipantFilter
+	To     string `json:"to,omitempty"`     // Destination room, for move
+	Text   string `json:"text,omitempty"`   // Notice text, for notify
+	Level  string `json:"level,omitempty"`  // Notice level, for notify, defaults to "info"
+	Reason string `json:"reason,omitempty"` // Shown to removed participants, for kick
+}
+
+type adminBanRequest struct {
+	ParticipantID ulid.ULID `json:"participant_id"`       // Ban session and peer of this local participant
+	SessionID     string    `json:"session_id,omitempty"` // Or ban a session
+	PeerID        peer.ID   `json:"peer_id,omitempty"`    // Or ban a peer
+	Duration      int       `json:"duration"`             // Seconds the ban lasts
+	Reason        string    `json:"reason,omitempty"`     // Shown to banned viewers
+}
+
+type adminInputRequest struct {
+	Allowed bool `json:"allowed"`
+}
+
+type adminConnectRequest struct {
+	Addr string `json:"addr"` // Multiaddr including /p2p/ peer ID
+}
+
+type adminDrainRequest struct {
+	Draining bool `json:"draining"`
+}
+
+type adminDrainStatus struct {
+	Draining     bool `json:"draining"`
+	Rooms        int  `json:"rooms"`        // Local online rooms
+	Participants int  `json:"participants"` // Viewers still connected
+}
+
+type adminRotateRequest struct {
+	Grace int `json:"grace,omitempty"` // Seconds old identity keeps serving, 0 uses default
+}
+
+type adminIdentity struct {
+	ID       peer.ID          `json:"id"`
+	Rotation *SuccessorRecord `json:"rotation,omitempty"` // Pending rotation, retiring ID at its retire time
+}
+
+type adminAddrs struct {
+	ID           peer.ID           `json:"id"`
+	Addrs        []string          `json:"addrs"`
+	Browser      []string          `json:"browser"` // Subset of addresses browsers can dial
+	WebTransport WebTransportCerts `json:"webtransport"`
+	Reachability string            `json:"reachability"` // As detected by AutoNAT
+}
+
+type adminSnapshotSaved struct {
+	Path     string `json:"path"` // On the relay, restore with restoreSnapshot flag
+	Version  int    `json:"version"`
+	Rooms    int    `json:"rooms"`
+	Sessions int    `json:"sessions"`
+}
+
+type adminHealth struct {
+	Status     string `json:"status"`
+	Draining   bool   `json:"draining"`   // Draining relays are healthy, only refusing new viewers
+	Overloaded bool   `json:"overloaded"` // Overloaded relays are healthy, advising upstream to back off
+}
+
+type adminExperimentRequest struct {
+	Shadow        string `json:"shadow,omitempty"` // Shadow room name, defaults to "<room>-shadow"
+	SamplePercent int    `json:"sample_percent"`   // Share of consenting viewers moved to shadow room
+}
+
+type adminPeer struct {
+	ID           peer.ID               `json:"id"`
+	Addrs        []multiaddr.Multiaddr `json:"addrs"`
+	Connected    bool                  `json:"connected"`
+	Latency      time.Duration         `json:"latency,omitempty"`
+	LastSeen     time.Time             `json:"last_seen,omitempty"`
+	DialFailures int                   `json:"dial_failures,omitempty"`
+	Link         *LinkQuality          `json:"link,omitempty"` // Quality of the link from peer, if we pull rooms from it
+	Region       string                `json:"region,omitempty"`
+	Zone         string                `json:"zone,omitempty"`
+	Static       bool                  `json:"static,omitempty"` // Kept connected because of the peers flag
+	HolePunch    *HolePunchStats       `json:"hole_punch,omitempty"`
+}
+
+// adminDirectoryPage is a page of rooms a mesh relay answered a directory query with
+type adminDirectoryPage struct {
+	Rooms      []adminDirectoryRoom `json:"rooms"`
+	NextCursor string               `json:"next_cursor,omitempty"` // Passed as cursor for the next page, empty on the last page
+}
+
+type adminDirectoryRoom struct {
+	ID       string              `json:"id"`
+	Name     string              `json:"name"`
+	OwnerID  string              `json:"owner_id"`
+	Viewers  int                 `json:"viewers"` // As known by the answering relay
+	Online   bool                `json:"online"`
+	Metadata shared.RoomMetadata `json:"metadata"`
+}
+
+// --- Admin API Server ---
+
+// startAdminAPI serves the admin API until context is done
+func (r *Relay) startAdminAPI(ctx context.Context) error {
+	flags := common.GetFlags()
+	if len(flags.AdminToken) <= 0 {
+		return errors.New("admin API requires a token, set adminToken")
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /admin/rooms", r.adminListRooms)
+	mux.HandleFunc("GET /admin/rooms/{name}", r.adminGetRoom)
+	mux.HandleFunc("DELETE /admin/rooms/{name}", r.adminCloseRoom)
+	mux.HandleFunc("DELETE /admin/rooms/{name}/participants/{id}", r.adminKickParticipant)
+	mux.HandleFunc("PUT /admin/rooms/{name}/participants/{id}/input", r.adminSetParticipantInput)
+	mux.HandleFunc("GET /admin/peers", r.adminListPeers)
+	mux.HandleFunc("GET /admin/peers/latencies", r.adminGetLatencies)
+	mux.HandleFunc("GET /admin/peers/{id}/directory", r.adminQueryDirectory)
+	mux.HandleFunc("POST /admin/peers", r.adminConnectPeer)
+	mux.HandleFunc("DELETE /admin/peers/{id}", r.adminDisconnectPeer)
+	mux.HandleFunc("GET /admin/drain", r.adminGetDrain)
+	mux.HandleFunc("POST /admin/drain", r.adminSetDrain)
+	mux.HandleFunc("POST /admin/peerstore/save", r.adminSavePeerstore)
+	mux.HandleFunc("GET /admin/peerstore", r.adminExportPeerstore)
+	mux.HandleFunc("POST /admin/peerstore/import", r.adminImportPeerstore)
+	mux.HandleFunc("GET /admin/snapshot", r.adminGetSnapshot)
+	mux.HandleFunc("POST /admin/snapshot", r.adminSaveSnapshot)
+	mux.HandleFunc("GET /admin/identity", r.adminGetIdentity)
+	mux.HandleFunc("POST /admin/identity/rotate", r.adminRotateIdentity)
+	mux.HandleFunc("GET /admin/addrs", r.adminGetAddrs)
+	mux.HandleFunc("GET /admin/usage", r.adminGetUsage)
+	mux.HandleFunc("GET /admin/bandwidth", r.adminGetBandwidth)
+	mux.HandleFunc("POST /admin/config/reload", r.adminReloadConfig)
+	mux.HandleFunc("POST /admin/rooms/{name}/participants/kick", r.adminBulkKick)
+	mux.HandleFunc("POST /admin/rooms/{name}/participants/move", r.adminBulkMove)
+	mux.HandleFunc("POST /admin/rooms/{name}/participants/notify", r.adminBulkNotify)
+	mux.HandleFunc("GET /admin/jobs", r.adminListJobs)
+	mux.HandleFunc("GET /admin/events", r.adminEvents)
+	mux.HandleFunc("GET /admin/experiments", r.adminListExperiments)
+	mux.HandleFunc("GET /admin/rooms/{name}/experiment", r.adminGetExperiment)
+	mux.HandleFunc("POST /admin/rooms/{name}/experiment", r.adminStartExperiment)
+	mux.HandleFunc("DELETE /admin/rooms/{name}/experiment", r.adminStopExperiment)
+	mux.HandleFunc("GET /admin/jobs/{id}", r.adminGetJob)
+	mux.HandleFunc("GET /admin/rooms/{name}/bans", r.adminListBans)
+	mux.HandleFunc("POST /admin/rooms/{name}/bans", r.adminBan)
+	mux.HandleFunc("DELETE /admin/rooms/{name}/bans/{id}", r.adminLiftBan)
+
+	// Health is left unauthenticated for container healthchecks
+	root := http.NewServeMux()
+	root.HandleFunc("GET /healthz", r.adminHealth)
+	root.Handle("/", adminAuth(flags.AdminToken, mux))
+
+	server := &http.Server{
+		Addr:              fmt.Sprintf(":%d", flags.AdminPort),
+		Handler:           root,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+	go func() {
+		slog.Info("Starting admin API server", "port", flags.AdminPort)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Admin API server failed", "err", err)
+		}
+	}()
+	return nil
+}
+
+// adminAuth requires bearer token on all requests,
+// also accepted as access_token query parameter since browsers can't set headers on WebSockets
+func adminAuth(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		given, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			given = req.URL.Query().Get("access_token")
+			ok = len(given) > 0
+		}
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			writeAdminError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+func writeAdminJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("Failed to write admin API response", "err", err)
+	}
+}
+
+func writeAdminError(w http.ResponseWriter, status int, msg string) {
+	writeAdminJSON(w, status, map[string]string{"error": msg})
+}
+
+// --- Admin API Handlers ---
+
+func (r *Relay) adminRoomView(room *shared.Room, withParticipants bool) adminRoom {
+	info := room.RoomInfo
+	info.Metadata = room.GetMetadata()
+	info.Viewers = room.ParticipantCount()
+	info.Online = room.IsOnline()
+	view := adminRoom{
+		RoomInfo:   info,
+		UpstreamID: room.UpstreamID(),
+		HopLatency: room.HopLatency(),
+		AudioStats: room.AudioStats.Snapshot(),
+		VideoStats: room.VideoStats.Snapshot(),
+		Waiting:    r.StreamProtocol.waiting.Len(room.Name),
+	}
+	if withParticipants {
+		for _, participant := range room.GetParticipants() {
+			view.Participants = append(view.Participants, adminParticipant{
+				ID:            participant.ID,
+				SessionID:     participant.SessionID,
+				PeerID:        participant.PeerID,
+				Role:          participant.Role,
+				QueueDelay:    participant.QueueDelay(),
+				DroppedFrames: participant.DroppedFrames(),
+				InputAllowed:  participant.InputAllowed(),
+				DroppedInput:  participant.DroppedInput(),
+				InputLatency:  participant.InputLatency(),
+				BytesSent:     participant.BytesSent(),
+			})
+		}
+	}
+	return view
+}
+
+func (r *Relay) adminListRooms(w http.ResponseWriter, _ *http.Request) {
+	rooms := make([]adminRoom, 0)
+	for _, room := range r.LocalRooms.Copy() {
+		rooms = append(rooms, r.adminRoomView(room, false))
+	}
+	writeAdminJSON(w, http.StatusOK, rooms)
+}
+
+func (r *Relay) adminGetRoom(w http.ResponseWriter, req *http.Request) {
+	room := r.GetRoomByName(req.PathValue("name"))
+	if room == nil {
+		writeAdminError(w, http.StatusNotFound, "room not found")
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, r.adminRoomView(room, true))
+}
+
+func (r *Relay) adminCloseRoom(w http.ResponseWriter, req *http.Request) {
+	room := r.GetRoomByName(req.PathValue("name"))
+	if room == nil {
+		writeAdminError(w, http.StatusNotFound, "room not found")
+		return
+	}
+	r.CloseRoom(room)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (r *Relay) adminKickParticipant(w http.ResponseWriter, req *http.Request) {
+	room := r.GetRoomByName(req.PathValue("name"))
+	if room == nil {
+		writeAdminError(w, http.StatusNotFound, "room not found")
+		return
+	}
+	participantID, err := ulid.Parse(req.PathValue("id"))
+	if err != nil {
+		writeAdminError(w, http.StatusBadRequest, "invalid participant ID")
+		return
+	}
+	if !r.KickParticipant(room, participantID, req.URL.Query().Get("reason")) {
+		writeAdminError(w, http.StatusNotFound, "participant not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// adminSetParticipantInput grants or revokes input of a participant, overriding what its role allows
+func (r *Relay) adminSetParticipantInput(w http.ResponseWriter, req *http.Request) {
+	room := r.GetRoomByName(req.PathValue("name"))
+	if room == nil {
+		writeAdminError(w, http.StatusNotFound, "room not found")
+		return
+	}
+	participantID, err := ulid.Parse(req.PathValue("id"))
+	if err != nil {
+		writeAdminError(w, http.StatusBadRequest, "invalid participant ID")
+		return
+	}
+	var inputReq adminInputRequest
+	if err = json.NewDecoder(req.Body).Decode(&inputReq); err != nil {
+		writeAdminError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	participant := room.GetParticipantByID(participantID)
+	if participant == nil {
+		writeAdminError(w, http.StatusNotFound, "participant not found")
+		return
+	}
+	participant.SetInputAllowed(inputReq.Allowed)
+	slog.Info("Changed participant input permission", "room", room.Name, "participant", participantID, "allowed", inputReq.Allowed)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (r *Relay) adminListBans(w http.ResponseWriter, req *http.Request) {
+	writeAdminJSON(w, http.StatusOK, r.Moderation.Active(req.PathValue("name"), time.Now()))
+}
+
+// adminBan bans from a room, which doesn't need to be local as bans are gossiped to relays serving it
+func (r *Relay) adminBan(w http.ResponseWriter, req *http.Request) {
+	roomName := req.PathValue("name")
+	var banReq adminBanRequest
+	if err := json.NewDecoder(req.Body).Decode(&banReq); err != nil {
+		writeAdminError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if banReq.ParticipantID != (ulid.ULID{}) {
+		room := r.GetRoomByName(roomName)
+		if room == nil {
+			writeAdminError(w, http.StatusNotFound, "room not found")
+			return
+		}
+		participant := room.GetParticipantByID(banReq.ParticipantID)
+		if participant == nil {
+			writeAdminError(w, http.StatusNotFound, "participant not found")
+			return
+		}
+		banReq.SessionID = participant.SessionID
+		banReq.PeerID = participant.PeerID
+	}
+	ban, err := r.BanFromRoom(roomName, banReq.SessionID, banReq.PeerID, time.Duration(banReq.Duration)*time.Second, banReq.Reason)
+	if err != nil {
+		writeAdminError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeAdminJSON(w, http.StatusCreated, ban)
+}
+
+func (r *Relay) adminLiftBan(w http.ResponseWriter, req *http.Request) {
+	banID, err := ulid.Parse(req.PathValue("id"))
+	if err != nil {
+		writeAdminError(w, http.StatusBadRequest, "invalid ban ID")
+		return
+	}
+	if !r.LiftRoomBan(req.PathValue("name"), banID) {
+		writeAdminError(w, http.StatusNotFound, "ban not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (r *Relay) adminListPeers(w http.ResponseWriter, _ *http.Request) {
+	peers := make([]adminPeer, 0)
+	for id, pi := range r.Peers.Copy() {
+		latency, _ := r.Latencies.Get(id)
+		var link *LinkQuality
+		if quality, ok := r.LinkQualities.Get(id); ok {
+			link = &quality
+		}
+		var holePunch *HolePunchStats
+		if stats, ok := r.holePunches.get(id); ok {
+			holePunch = &stats
+		}
+		peers = append(peers, adminPeer{
+			ID:           id,
+			Addrs:        pi.Addrs,
+			Connected:    r.Host.Network().Connectedness(id) == network.Connected,
+			Latency:      latency,
+			LastSeen:     pi.LastSeen,
+			DialFailures: pi.DialFailures,
+			Link:         link,
+			Region:       pi.Region,
+			Zone:         pi.Zone,
+			Static:       r.isStaticPeer(id),
+			HolePunch:    holePunch,
+		})
+	}
+	writeAdminJSON(w, http.StatusOK, peers)
+}
+
+// adminGetLatencies returns round trip times between relays of the mesh, as each relay last measured
+func (r *Relay) adminGetLatencies(w http.ResponseWriter, _ *http.Request) {
+	writeAdminJSON(w, http.StatusOK, r.LatencyMatrix())
+}
+
+// adminQueryDirectory asks a mesh relay for a page of rooms it knows, over the directory protocol
+func (r *Relay) adminQueryDirectory(w http.ResponseWriter, req *http.Request) {
+	peerID, err := peer.Decode(req.PathValue("id"))
+	if err != nil {
+		writeAdminError(w, http.StatusBadRequest, "invalid peer ID")
+		return
+	}
+	query := &gen.ProtoDirectoryQuery{
+		Prefix: req.URL.Query().Get("prefix"),
+		Cursor: req.URL.Query().Get("cursor"),
+	}
+	if limitStr := req.URL.Query().Get("limit"); len(limitStr) > 0 {
+		limit, err := strconv.ParseUint(limitStr, 10, 32)
+		if err != nil {
+			writeAdminError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		query.Limit = uint32(limit)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), directoryQueryTimeout)
+	defer cancel()
+	result, err := r.DirectoryProtocol.QueryDirectory(ctx, peerID, query)
+	if err != nil {
+		writeAdminError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	page := adminDirectoryPage{Rooms: make([]adminDirectoryRoom, 0, len(result.Rooms)), NextCursor: result.NextCursor}
+	for _, room := range result.Rooms {
+		page.Rooms = append(page.Rooms, adminDirectoryRoom{
+			ID:       room.Id,
+			Name:     room.Name,
+			OwnerID:  room.OwnerId,
+			Viewers:  int(room.Viewers),
+			Online:   room.Online,
+			Metadata: shared.RoomMetadataFromProto(room.Metadata),
+		})
+	}
+	writeAdminJSON(w, http.StatusOK, page)
+}
+
+func (r *Relay) adminConnectPeer(w http.ResponseWriter, req *http.Request) {
+	var connectReq adminConnectRequest
+	if err := json.NewDecoder(req.Body).Decode(&connectReq); err != nil {
+		writeAdminError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	addr, err := multiaddr.NewMultiaddr(connectReq.Addr)
+	if err != nil {
+		writeAdminError(w, http.StatusBadRequest, "invalid multiaddr")
+		return
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), reconnectDialTimeout)
+	defer cancel()
+	if err = r.ConnectToPeer(ctx, addr); err != nil {
+		writeAdminError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	slog.Info("Connected peer by admin request", "addr", addr)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (r *Relay) adminDisconnectPeer(w http.ResponseWriter, req *http.Request) {
+	peerID, err := peer.Decode(req.PathValue("id"))
+	if err != nil {
+		writeAdminError(w, http.StatusBadRequest, "invalid peer ID")
+		return
+	}
+	if r.Host.Network().Connectedness(peerID) != network.Connected {
+		writeAdminError(w, http.StatusNotFound, "peer not connected")
+		return
+	}
+	if err = r.Host.Network().ClosePeer(peerID); err != nil {
+		writeAdminError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	slog.Info("Disconnected peer by admin request", "peer", peerID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (r *Relay) adminSavePeerstore(w http.ResponseWriter, _ *http.Request) {
+	if err := r.SavePeerStore(); err != nil {
+		writeAdminError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// adminExportPeerstore returns the peer store in the format of JSON peer store files
+func (r *Relay) adminExportPeerstore(w http.ResponseWriter, _ *http.Request) {
+	data, err := r.ExportPeerStore()
+	if err != nil {
+		writeAdminError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+// adminImportPeerstore merges peers of a JSON peer store file into the peer store
+func (r *Relay) adminImportPeerstore(w http.ResponseWriter, req *http.Request) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, req.Body, adminPeerstoreMaxImport))
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		writeAdminError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("peer store file larger than %d bytes", maxErr.Limit))
+		return
+	}
+	if err != nil {
+		writeAdminError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	imported, err := r.ImportPeerStore(data)
+	if err != nil {
+		writeAdminError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, map[string]int{"imported": imported})
+}
+
+// adminGetSnapshot returns current relay state as a snapshot, for saving elsewhere or debugging
+func (r *Relay) adminGetSnapshot(w http.ResponseWriter, _ *http.Request) {
+	writeAdminJSON(w, http.StatusOK, r.TakeSnapshot(time.Now()))
+}
+
+// adminSaveSnapshot saves current relay state to persistent directory, for restoring it on restart
+func (r *Relay) adminSaveSnapshot(w http.ResponseWriter, _ *http.Request) {
+	path := snapshotFile()
+	snap := r.TakeSnapshot(time.Now())
+	if err := snap.SaveToFile(path); err != nil {
+		writeAdminError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, adminSnapshotSaved{Path: path, Version: snap.Version, Rooms: len(snap.Rooms), Sessions: len(snap.Sessions)})
+}
+
+func (r *Relay) adminGetUsage(w http.ResponseWriter, _ *http.Request) {
+	r.Usage.Sample(r.LocalRooms.Copy(), time.Now())
+	writeAdminJSON(w, http.StatusOK, r.Usage.Counters())
+}
+
+// adminGetBandwidth lists traffic and quota usage per peer
+func (r *Relay) adminGetBandwidth(w http.ResponseWriter, _ *http.Request) {
+	now := time.Now()
+	r.Bandwidth.Sample(now, r.isConnected)
+	writeAdminJSON(w, http.StatusOK, r.Bandwidth.Peers(now))
+}
+
+func (r *Relay) adminHealth(w http.ResponseWriter, _ *http.Request) {
+	writeAdminJSON(w, http.StatusOK, adminHealth{Status: "ok", Draining: r.IsDraining(), Overloaded: r.IsOverloaded()})
+}
+
+func (r *Relay) adminReloadConfig(w http.ResponseWriter, _ *http.Request) {
+	changed, err := r.ReloadConfig()
+	if err != nil {
+		writeAdminError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, map[string][]string{"changed": changed})
+}
+
+func (r *Relay) adminDrainStatus() adminDrainStatus {
+	status := adminDrainStatus{Draining: r.IsDraining()}
+	for _, room := range r.LocalRooms.Copy() {
+		if room.IsOnline() {
+			status.Rooms++
+		}
+		status.Participants += room.ParticipantCount()
+	}
+	return status
+}
+
+func (r *Relay) adminGetDrain(w http.ResponseWriter, _ *http.Request) {
+	writeAdminJSON(w, http.StatusOK, r.adminDrainStatus())
+}
+
+func (r *Relay) adminSetDrain(w http.ResponseWriter, req *http.Request) {
+	var drainReq adminDrainRequest
+	if err := json.NewDecoder(req.Body).Decode(&drainReq); err != nil {
+		writeAdminError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	r.SetDraining(drainReq.Draining)
+	writeAdminJSON(w, http.StatusOK, r.adminDrainStatus())
+}
+
+func (r *Relay) adminGetIdentity(w http.ResponseWriter, _ *http.Request) {
+	writeAdminJSON(w, http.StatusOK, adminIdentity{ID: r.ID, Rotation: r.rotation.Load()})
+}
+
+func (r *Relay) adminGetAddrs(w http.ResponseWriter, _ *http.Request) {
+	addrs := adminAddrs{
+		ID:           r.Host.ID(),
+		Addrs:        make([]string, 0),
+		Browser:      make([]string, 0),
+		WebTransport: currentWebTransportCerts(r.Host),
+		Reachability: r.Reachability().String(),
+	}
+	if p2pAddrs, err := peer.AddrInfoToP2pAddrs(&peer.AddrInfo{ID: r.Host.ID(), Addrs: r.Host.Addrs()}); err == nil {
+		for _, addr := range p2pAddrs {
+			addrs.Addrs = append(addrs.Addrs, addr.String())
+		}
+	}
+	for _, addr := range browserAddrs(r.Host) {
+		addrs.Browser = append(addrs.Browser, addr.String())
+	}
+	writeAdminJSON(w, http.StatusOK, addrs)
+}
+
+func (r *Relay) adminRotateIdentity(w http.ResponseWriter, req *http.Request) {
+	var rotateReq adminRotateRequest
+	if err := json.NewDecoder(req.Body).Decode(&rotateReq); err != nil && !errors.Is(err, io.EOF) {
+		writeAdminError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if rotateReq.Grace < 0 {
+		writeAdminError(w, http.StatusBadRequest, "grace must not be negative")
+		return
+	}
+	rec, err := r.RotateIdentity(req.Context(), time.Duration(rotateReq.Grace)*time.Second)
+	if errors.Is(err, errRotationPending) {
+		writeAdminError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		writeAdminError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeAdminJSON(w, http.StatusAccepted, adminIdentity{ID: r.ID, Rotation: rec})
+}
+
+// --- Admin Bulk Operations ---
+
+// selectParticipants resolves filter against room, failing if any explicitly requested participant is missing
+func selectParticipants(room *shared.Room, filter adminParticipantFilter) ([]*shared.Participant, error) {
+	excluded := make(map[ulid.ULID]struct{}, len(filter.ExcludeIDs))
+	for _, id := range filter.ExcludeIDs {
+		excluded[id] = struct{}{}
+	}
+	include := func(participant *shared.Participant) bool {
+		if _, ok := excluded[participant.ID]; ok {
+			return false
+		}
+		return len(filter.PeerID) <= 0 || participant.PeerID == filter.PeerID
+	}
+
+	var selected []*shared.Participant
+	if len(filter.ParticipantIDs) > 0 {
+		for _, id := range filter.ParticipantIDs {
+			participant := room.GetParticipantByID(id)
+			if participant == nil {
+				return nil, fmt.Errorf("participant %s not found", id)
+			}
+			if include(participant) {
+				selected = append(selected, participant)
+			}
+		}
+		return selected, nil
+	}
+	for _, participant := range room.GetParticipants() {
+		if include(participant) {
+			selected = append(selected, participant)
+		}
+	}
+	return selected, nil
+}
+
+// adminBulkTarget decodes a bulk request and resolves the room and participants it targets
+func (r *Relay) adminBulkTarget(w http.ResponseWriter, req *http.Request) (*shared.Room, []*shared.Participant, adminBulkRequest, bool) {
+	var bulkReq adminBulkRequest
+	if err := json.NewDecoder(req.Body).Decode(&bulkReq); err != nil && !errors.Is(err, io.EOF) {
+		writeAdminError(w, http.StatusBadRequest, "invalid request body")
+		return nil, nil, bulkReq, false
+	}
+	room := r.GetRoomByName(req.PathValue("name"))
+	if room == nil {
+		writeAdminError(w, http.StatusNotFound, "room not found")
+		return nil, nil, bulkReq, false
+	}
+	participants, err := selectParticipants(room, bulkReq.adminParticipantFilter)
+	if err != nil {
+		writeAdminError(w, http.StatusNotFound, err.Error())
+		return nil, nil, bulkReq, false
+	}
+	return room, participants, bulkReq, true
+}
+
+func (r *Relay) adminBulkKick(w http.ResponseWriter, req *http.Request) {
+	room, participants, bulkReq, ok := r.adminBulkTarget(w, req)
+	if !ok {
+		return
+	}
+	job := r.StartJob("kick", room.Name, len(participants), func(job *Job) {
+		for _, participant := range participants {
+			job.Progress(r.KickParticipant(room, participant.ID, bulkReq.Reason))
+		}
+		job.Finish(JobCompleted, nil)
+	})
+	writeAdminJSON(w, http.StatusAccepted, job.Snapshot())
+}
+
+func (r *Relay) adminBulkMove(w http.ResponseWriter, req *http.Request) {
+	room, participants, bulkReq, ok := r.adminBulkTarget(w, req)
+	if !ok {
+		return
+	}
+	dest := r.GetRoomByName(bulkReq.To)
+	if dest == nil {
+		writeAdminError(w, http.StatusNotFound, "destination room not found")
+		return
+	}
+	if err := r.CanMoveParticipants(room, dest); err != nil {
+		writeAdminError(w, http.StatusConflict, err.Error())
+		return
+	}
+
+	job := r.StartJob("move", room.Name, len(participants), func(job *Job) {
+		var moved []ulid.ULID
+		for _, participant := range participants {
+			// Destination going away midway moves everyone back
+			if !dest.IsOnline() {
+				for _, id := range moved {
+					r.MoveParticipant(dest, room, id)
+				}
+				job.Finish(JobRolledBack, errors.New("destination room went offline"))
+				return
+			}
+			if r.MoveParticipant(room, dest, participant.ID) {
+				moved = append(moved, participant.ID)
+				job.Progress(true)
+			} else {
+				job.Progress(false) // Left before its turn
+			}
+		}
+		job.Finish(JobCompleted, nil)
+	})
+	writeAdminJSON(w, http.StatusAccepted, job.Snapshot())
+}
+
+func (r *Relay) adminBulkNotify(w http.ResponseWriter, req *http.Request) {
+	room, participants, bulkReq, ok := r.adminBulkTarget(w, req)
+	if !ok {
+		return
+	}
+	if len(bulkReq.Text) <= 0 {
+		writeAdminError(w, http.StatusBadRequest, "notice text required")
+		return
+	}
+	level := bulkReq.Level
+	if len(level) <= 0 {
+		level = "info"
+	}
+
+	job := r.StartJob("notify", room.Name, len(participants), func(job *Job) {
+		for _, participant := range participants {
+			if err := r.SendNotice(participant, bulkReq.Text, level); err != nil {
+				slog.Debug("Failed to send notice to participant", "room", room.Name, "participant", participant.ID, "err", err)
+				job.Progress(false)
+				continue
+			}
+			job.Progress(true)
+		}
+		job.Finish(JobCompleted, nil)
+	})
+	writeAdminJSON(w, http.StatusAccepted, job.Snapshot())
+}
+
+func (r *Relay) adminListJobs(w http.ResponseWriter, _ *http.Request) {
+	jobs := make([]JobSnapshot, 0)
+	for _, job := range r.Jobs.Copy() {
+		jobs = append(jobs, job.Snapshot())
+	}
+	writeAdminJSON(w, http.StatusOK, jobs)
+}
+
+func (r *Relay) adminGetJob(w http.ResponseWriter, req *http.Request) {
+	jobID, err := ulid.Parse(req.PathValue("id"))
+	if err != nil {
+		writeAdminError(w, http.StatusBadRequest, "invalid job ID")
+		return
+	}
+	job, ok := r.Jobs.Get(jobID)
+	if !ok {
+		writeAdminError(w, http.StatusNotFound, "job not found")
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, job.Snapshot())
+}
+
+// --- Admin Experiments ---
+
+func (r *Relay) adminListExperiments(w http.ResponseWriter, _ *http.Request) {
+	reports := make([]ExperimentReport, 0)
+	for _, exp := range r.Experiments.Copy() {
+		reports = append(reports, r.experimentReport(exp))
+	}
+	writeAdminJSON(w, http.StatusOK, reports)
+}
+
+func (r *Relay) adminGetExperiment(w http.ResponseWriter, req *http.Request) {
+	exp, ok := r.Experiments.Get(req.PathValue("name"))
+	if !ok {
+		writeAdminError(w, http.StatusNotFound, "experiment not found")
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, r.experimentReport(exp))
+}
+
+func (r *Relay) adminStartExperiment(w http.ResponseWriter, req *http.Request) {
+	var expReq adminExperimentRequest
+	if err := json.NewDecoder(req.Body).Decode(&expReq); err != nil {
+		writeAdminError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	exp, err := r.StartExperiment(req.PathValue("name"), expReq.Shadow, expReq.SamplePercent)
+	if err != nil {
+		writeAdminError(w, http.StatusConflict, err.Error())
+		return
+	}
+	writeAdminJSON(w, http.StatusCreated, r.experimentReport(exp))
+}
+
+func (r *Relay) adminStopExperiment(w http.ResponseWriter, req *http.Request) {
+	if !r.StopExperiment(req.PathValue("name")) {
+		writeAdminError(w, http.StatusNotFound, "experiment not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// --- Admin Event Stream ---
+
+var adminEventUpgrader = websocket.Upgrader{
+	// Token authenticated, so dashboards may be served from any origin
+	CheckOrigin: func(*http.Request) bool { return true },
+}
+
+// adminEvents streams relay events as JSON over WebSocket, optionally filtered by ?types=a,b
+func (r *Relay) adminEvents(w http.ResponseWriter, req *http.Request) {
+	var types map[EventType]struct{}
+	if filter := req.URL.Query().Get("types"); len(filter) > 0 {
+		types = make(map[EventType]struct{})
+		for _, t := range strings.Split(filter, ",") {
+			types[EventType(strings.TrimSpace(t))] = struct{}{}
+		}
+	}
+
+	conn, err := adminEventUpgrader.Upgrade(w, req, nil)
+	if err != nil {
+		slog.Debug("Failed to upgrade admin event stream", "err", err)
+		return
+	}
+	defer conn.Close()
+
+	events, unsubscribe := r.Events.Subscribe(adminEventBuffer)
+	defer unsubscribe()
+
+	// Reader only notices the client going away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(adminEventPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-ping.C:
+			if err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(adminEventWriteTimeout)); err != nil {
+				return
+			}
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if _, want := types[event.Type]; types != nil && !want {
+				continue
+			}
+			_ = conn.SetWriteDeadline(time.Now().Add(adminEventWriteTimeout))
+			if err = conn.WriteJSON(event); err != nil {
+				slog.Debug("Admin event stream closed", "err", err)
+				return
+			}
+		}
+	}
+}
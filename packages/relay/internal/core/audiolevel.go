@@ -0,0 +1,91 @@
+package core
+
+import (
+	"context"
+	"log/slog"
+	"relay/internal/common"
+	"relay/internal/shared"
+	"time"
+
+	gen "relay/internal/proto"
+
+	"github.com/oklog/ulid/v2"
+	"google.golang.org/protobuf/proto"
+)
+
+// --- Audio Levels ---
+
+// audioLevel is an audio level event last sent for a room
+type audioLevel struct {
+	level uint8
+	voice bool
+}
+
+// changed tells if level differs enough from the last sent one for viewers to notice
+func (a audioLevel) changed(level uint8, voice bool) bool {
+	diff := int(a.level) - int(level)
+	return a.voice != voice || diff >= audioLevelStep || diff <= -audioLevelStep
+}
+
+// sendAudioLevel sends audio level of a room to its local viewers, relays pulling it read levels from RTP themselves
+func (r *Relay) sendAudioLevel(room *shared.Room, level uint8, voice bool) {
+	msg, err := common.CreateMessage(&gen.ProtoAudioLevel{
+		RoomName: room.Name,
+		Level:    uint32(level),
+		Voice:    voice,
+	}, "audio-level", nil)
+	if err != nil {
+		slog.Error("Failed to create proto message", "err", err)
+		return
+	}
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		slog.Error("Failed to marshal audio level", "err", err)
+		return
+	}
+	for _, participant := range room.GetParticipants() {
+		dc := participant.DataChannel()
+		if dc == nil || r.isMeshRelay(participant.PeerID) {
+			continue
+		}
+		if err = dc.SendBinary(data); err != nil {
+			slog.Debug("Failed to send audio level to participant", "room", room.Name, "participant", participant.ID, "err", err)
+		}
+	}
+}
+
+// audioLevelBroadcaster sends audio levels of local rooms to their viewers when they change,
+// levels are read from the ssrc-audio-level extension of incoming audio
+func (r *Relay) audioLevelBroadcaster(ctx context.Context) {
+	ticker := time.NewTicker(audioLevelInterval)
+	defer ticker.Stop()
+
+	sent := make(map[ulid.ULID]audioLevel) // room ID -> level last sent
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			seen := make(map[ulid.ULID]struct{})
+			for _, room := range r.LocalRooms.Copy() {
+				level, voice, ok := room.TakeAudioLevel()
+				if !ok {
+					continue
+				}
+				seen[room.ID] = struct{}{}
+				if last, known := sent[room.ID]; known && !last.changed(level, voice) {
+					continue
+				}
+				sent[room.ID] = audioLevel{level: level, voice: voice}
+				r.sendAudioLevel(room, level, voice)
+			}
+
+			// Rooms which stopped carrying levels start over, their next level is sent whatever it is
+			for id := range sent {
+				if _, ok := seen[id]; !ok {
+					delete(sent, id)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,397 @@
+package core
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"relay/internal/common"
+	gen "relay/internal/proto"
+	"relay/internal/shared"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	cryptopb "github.com/libp2p/go-libp2p/core/crypto/pb"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// --- Viewer Authorization ---
+
+// ViewerClaims are claims of viewer tokens issued by the platform
+type ViewerClaims struct {
+	Room string `json:"room"` // Room the token grants access to
+	Role string `json:"role"` // Granted shared.ViewerRole
+	jwt.RegisteredClaims
+}
+
+// ViewerGrant is what a stream request was authorized for
+type ViewerGrant struct {
+	Role    shared.ViewerRole
+	Subject string // Viewer token subject, empty when viewer authorization is disabled
+}
+
+// ViewerAuth validates viewer tokens of stream requests
+type ViewerAuth struct {
+	secret    []byte
+	publicKey any
+	parser    *jwt.Parser
+}
+
+// NewViewerAuth creates viewer token validation from flags, nil if viewer authorization is disabled
+func NewViewerAuth(flags *common.Flags) (*ViewerAuth, error) {
+	if !flags.ViewerAuthEnabled() {
+		return nil, nil
+	}
+	// Mesh relays are trusted to authorize their own viewers, without mesh authentication any peer could claim to be one
+	if !flags.MeshAuthEnabled() {
+		return nil, errors.New("viewer authorization requires meshSecret or meshPeers")
+	}
+
+	va := &ViewerAuth{secret: []byte(flags.AuthJWTSecret)}
+	methods := make([]string, 0)
+	if len(va.secret) > 0 {
+		methods = append(methods, "HS256", "HS384", "HS512")
+	}
+	if len(flags.AuthJWTPublicKey) > 0 {
+		key, keyMethods, err := loadPublicKeyPEM(flags.AuthJWTPublicKey)
+		if err != nil {
+			return nil, err
+		}
+		va.publicKey = key
+		methods = append(methods, keyMethods...)
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(viewerTokenLeeway),
+	}
+	if len(flags.AuthJWTIssuer) > 0 {
+		opts = append(opts, jwt.WithIssuer(flags.AuthJWTIssuer))
+	}
+	va.parser = jwt.NewParser(opts...)
+	slog.Info("Viewer authorization enabled", "methods", methods, "issuer", flags.AuthJWTIssuer)
+	return va, nil
+}
+
+// loadPublicKeyPEM reads a PKIX public key, returning signing methods it can verify
+func loadPublicKeyPEM(path string) (any, []string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read viewer token public key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, nil, errors.New("viewer token public key is not PEM encoded")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse viewer token public key: %w", err)
+	}
+	switch key.(type) {
+	case *rsa.PublicKey:
+		return key, []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512"}, nil
+	case *ecdsa.PublicKey:
+		return key, []string{"ES256", "ES384", "ES512"}, nil
+	case ed25519.PublicKey:
+		return key, []string{"EdDSA"}, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported viewer token public key type %T", key)
+	}
+}
+
+// keyFunc picks verification key by token signing method, parser already restricted methods to configured keys
+func (va *ViewerAuth) keyFunc(token *jwt.Token) (any, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		return va.secret, nil
+	}
+	return va.publicKey, nil
+}
+
+// Authorize validates token for given room, returning granted role and token subject
+func (va *ViewerAuth) Authorize(tokenString, roomName string) (ViewerGrant, error) {
+	if len(tokenString) <= 0 {
+		return ViewerGrant{}, errors.New("missing viewer token")
+	}
+	var claims ViewerClaims
+	if _, err := va.parser.ParseWithClaims(tokenString, &claims, va.keyFunc); err != nil {
+		return ViewerGrant{}, err
+	}
+	// Tokens for a room are good for its quality variants too
+	if claims.Room != shared.BaseRoomName(roomName) {
+		return ViewerGrant{}, fmt.Errorf("token is for room '%s'", claims.Room)
+	}
+	role, ok := shared.ParseViewerRole(claims.Role)
+	if !ok {
+		return ViewerGrant{}, fmt.Errorf("unknown role '%s'", claims.Role)
+	}
+	return ViewerGrant{Role: role, Subject: claims.Subject}, nil
+}
+
+// errAuthRateLimited refuses peers with too many recent failed authorizations
+var errAuthRateLimited = errors.New("too many failed attempts, try again later")
+
+// authorizeStreamRequest returns grant of a stream requester, mesh relays are trusted and authorize their own viewers.
+// Without mesh authentication any peer can claim being a relay, so they are checked like viewers and can't pull
+// rooms protected by an access secret. Peers failing too often are refused without checking until their block ends.
+func (r *Relay) authorizeStreamRequest(peerID peer.ID, reqMsg *gen.ProtoClientRequestRoomStream) (ViewerGrant, error) {
+	if r.isMeshRelay(peerID) && common.GetFlags().MeshAuthEnabled() {
+		return ViewerGrant{Role: shared.RolePlayer}, nil
+	}
+	now := time.Now()
+	if r.authFailures.blocked(peerID, now) {
+		return ViewerGrant{}, errAuthRateLimited
+	}
+	grant, err := r.authorizeViewer(reqMsg)
+	if err != nil {
+		r.authFailures.fail(peerID, now)
+		return ViewerGrant{}, err
+	}
+	return grant, nil
+}
+
+// authorizeViewer checks viewer token and room access secret of a stream request
+func (r *Relay) authorizeViewer(reqMsg *gen.ProtoClientRequestRoomStream) (ViewerGrant, error) {
+	grant := ViewerGrant{Role: shared.RolePlayer}
+	if r.viewerAuth != nil {
+		var err error
+		if grant, err = r.viewerAuth.Authorize(reqMsg.Token, reqMsg.RoomName); err != nil {
+			return ViewerGrant{}, err
+		}
+	}
+	if settings, ok := r.roomSettings(reqMsg.RoomName); ok && !settings.CheckAccess(reqMsg.RoomName, reqMsg.AccessSecret) {
+		return ViewerGrant{}, errors.New("wrong room access secret")
+	}
+	return grant, nil
+}
+
+// roomSettings returns settings of a local room or one known from mesh routes
+func (r *Relay) roomSettings(roomName string) (shared.RoomSettings, bool) {
+	if room := r.GetRoomByName(roomName); room != nil {
+		return room.GetSettings(), true
+	}
+	if routes, ok := r.Routes.Get(roomName); ok {
+		for _, info := range routes.Copy() {
+			return info.Settings, true
+		}
+	}
+	return shared.RoomSettings{}, false
+}
+
+// authLimiter counts failed authorizations per peer, blocking peers which fail too often
+type authLimiter struct {
+	mtx   sync.Mutex
+	peers map[peer.ID]*authFailures
+}
+
+type authFailures struct {
+	count        int       // Failures since windowStart
+	windowStart  time.Time // Start of current counting window
+	blockedUntil time.Time // Requests are refused without checking until then
+}
+
+func newAuthLimiter() *authLimiter {
+	return &authLimiter{peers: make(map[peer.ID]*authFailures)}
+}
+
+// blocked returns true if peer is refused because of recent failures
+func (al *authLimiter) blocked(peerID peer.ID, now time.Time) bool {
+	al.mtx.Lock()
+	defer al.mtx.Unlock()
+	failures, ok := al.peers[peerID]
+	return ok && now.Before(failures.blockedUntil)
+}
+
+// fail records a failed authorization, blocking peer once it reaches the limit within the window
+func (al *authLimiter) fail(peerID peer.ID, now time.Time) {
+	al.mtx.Lock()
+	defer al.mtx.Unlock()
+
+	// Forget peers whose window and block are over, keeps map from growing with one-off peers
+	for id, failures := range al.peers {
+		if now.Sub(failures.windowStart) > authFailureWindow && now.After(failures.blockedUntil) {
+			delete(al.peers, id)
+		}
+	}
+
+	failures, ok := al.peers[peerID]
+	if !ok {
+		failures = &authFailures{windowStart: now}
+		al.peers[peerID] = failures
+	}
+	failures.count++
+	if failures.count >= authFailureLimit {
+		slog.Warn("Blocking peer after repeated failed authorizations", "peer", peerID, "failures", failures.count, "until", now.Add(authBlockDuration))
+		failures.blockedUntil = now.Add(authBlockDuration)
+		failures.count = 0
+		failures.windowStart = now
+	}
+}
+
+// authorizePush checks push signature if the room requires authenticated pushes
+func (r *Relay) authorizePush(pushMsg *gen.ProtoServerPushStream) error {
+	secret := common.GetFlags().PushSecretFor(pushMsg.RoomName)
+	if len(secret) <= 0 {
+		return nil
+	}
+	return common.VerifyPush(secret, pushMsg.RoomName, pushMsg.Timestamp, pushMsg.Signature, time.Now(), pushSignatureMaxAge)
+}
+
+// --- Push Authentication ---
+
+// pushKeysResponse is the trusted push key listing served by the platform
+type pushKeysResponse struct {
+	Keys []string `json:"keys"` // Hex Ed25519 public keys
+}
+
+// PushKeyring holds public keys trusted to push streams and the key each room was first pushed with, so once a
+// room was pushed no other key can take it over. Bindings last until the relay restarts or the bound key is no
+// longer trusted.
+type PushKeyring struct {
+	mtx        sync.RWMutex
+	configured map[string]bool   // Hex keys from flags and the relay's own identity key
+	fetched    map[string]bool   // Hex keys last fetched from platform
+	bindings   map[string]string // Hex key by main room name
+
+	url    string
+	client *http.Client
+}
+
+// NewPushKeyring creates push keyring from flags, nil if push challenges are disabled. Own identity key of the
+// relay is trusted too, so the test room can be pushed by the relay itself.
+func NewPushKeyring(flags *common.Flags, identity crypto.PubKey) (*PushKeyring, error) {
+	if !flags.PushChallengeEnabled() {
+		return nil, nil
+	}
+	pk := &PushKeyring{
+		configured: make(map[string]bool),
+		fetched:    make(map[string]bool),
+		bindings:   make(map[string]string),
+		url:        flags.PushKeysURL,
+		client:     &http.Client{Timeout: pushKeysFetchTimeout},
+	}
+	for _, key := range strings.Split(flags.PushKeys, ",") {
+		if len(strings.TrimSpace(key)) <= 0 {
+			continue
+		}
+		parsed, err := common.ParsePushKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid push key '%s': %w", key, err)
+		}
+		pk.configured[hex.EncodeToString(parsed)] = true
+	}
+	if identity != nil && identity.Type() == cryptopb.KeyType_Ed25519 {
+		if raw, err := identity.Raw(); err == nil {
+			pk.configured[hex.EncodeToString(raw)] = true
+		}
+	}
+	slog.Info("Push challenges enabled", "keys", len(pk.configured), "url", pk.url)
+	return pk, nil
+}
+
+// run refreshes trusted keys from platform until ctx is done, keeping the last fetched keys if a refresh fails
+func (pk *PushKeyring) run(ctx context.Context) {
+	if len(pk.url) <= 0 {
+		return
+	}
+	ticker := time.NewTicker(pushKeysRefreshInterval)
+	defer ticker.Stop()
+	for {
+		if err := pk.refresh(ctx); err != nil {
+			slog.Warn("Failed to fetch trusted push keys", "url", pk.url, "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refresh replaces fetched keys with the ones currently listed by the platform
+func (pk *PushKeyring) refresh(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pk.url, nil)
+	if err != nil {
+		return err
+	}
+	res, err := pk.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", res.Status)
+	}
+	var listing pushKeysResponse
+	if err = json.NewDecoder(io.LimitReader(res.Body, pushKeysMaxResponse)).Decode(&listing); err != nil {
+		return fmt.Errorf("failed to decode push keys: %w", err)
+	}
+
+	fetched := make(map[string]bool, len(listing.Keys))
+	for _, key := range listing.Keys {
+		parsed, err := common.ParsePushKey(key)
+		if err != nil {
+			slog.Warn("Ignoring invalid push key from platform", "key", key, "err", err)
+			continue
+		}
+		fetched[hex.EncodeToString(parsed)] = true
+	}
+	pk.mtx.Lock()
+	pk.fetched = fetched
+	pk.mtx.Unlock()
+	slog.Debug("Fetched trusted push keys", "keys", len(fetched))
+	return nil
+}
+
+// trusted returns true if hex key is configured or listed by the platform, caller holds the lock
+func (pk *PushKeyring) trusted(key string) bool {
+	return pk.configured[key] || pk.fetched[key]
+}
+
+// Authorize checks a push challenge response was signed by a trusted key for the relay, room and nonce, binding
+// the room to the key unless it is bound to another trusted key
+func (pk *PushKeyring) Authorize(relayID peer.ID, roomName, nonce string, res *gen.ProtoPushChallengeResponse) error {
+	parsed, err := common.ParsePushKey(res.PublicKey)
+	if err != nil {
+		return err
+	}
+	key := hex.EncodeToString(parsed)
+	if err = common.VerifyPushChallenge(key, relayID.String(), roomName, nonce, res.Signature); err != nil {
+		return err
+	}
+
+	pk.mtx.Lock()
+	defer pk.mtx.Unlock()
+	if !pk.trusted(key) {
+		return errors.New("push key is not trusted")
+	}
+	// Keys no longer trusted release their rooms, so revoking a key lets its rooms move to a new one
+	if bound, ok := pk.bindings[roomName]; ok && bound != key && pk.trusted(bound) {
+		return errors.New("room is bound to another push key")
+	}
+	pk.bindings[roomName] = key
+	return nil
+}
+
+// newPushChallenge returns a random hex nonce for a pushing node to sign
+func newPushChallenge() (string, error) {
+	nonce := make([]byte, pushChallengeNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate push challenge: %w", err)
+	}
+	return hex.EncodeToString(nonce), nil
+}
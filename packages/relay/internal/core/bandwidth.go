@@ -0,0 +1,319 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"relay/internal/common"
+	gen "relay/internal/proto"
+	"relay/internal/shared"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/metrics"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/proto"
+)
+
+// --- Bandwidth Accounting ---
+
+// Quota scopes, sent with "quota-exceeded" messages
+const (
+	quotaScopeDaily   = "daily"
+	quotaScopeMonthly = "monthly"
+)
+
+// Period keys quota usage is counted under, in UTC
+const (
+	quotaDayLayout   = "2006-01-02"
+	quotaMonthLayout = "2006-01"
+)
+
+var quotaRejectedCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "nestri_relay_quota_rejected_total",
+	Help: "Streams refused or cut as the peer exceeded its bandwidth quota",
+}, []string{"scope"})
+
+// PeerBandwidth is traffic exchanged with a peer, media and libp2p streams together
+type PeerBandwidth struct {
+	In         uint64 `json:"in"`          // Bytes received from the peer, persisted across restarts
+	Out        uint64 `json:"out"`         // Bytes sent to the peer, persisted across restarts
+	Day        string `json:"day"`         // UTC day DayBytes were counted on
+	DayBytes   uint64 `json:"day_bytes"`   // Bytes sent to the peer on Day
+	Month      string `json:"month"`       // UTC month MonthBytes were counted in
+	MonthBytes uint64 `json:"month_bytes"` // Bytes sent to the peer in Month
+}
+
+// current returns bandwidth with quota periods rolled over to given time
+func (pb PeerBandwidth) current(now time.Time) PeerBandwidth {
+	now = now.UTC()
+	if day := now.Format(quotaDayLayout); pb.Day != day {
+		pb.Day, pb.DayBytes = day, 0
+	}
+	if month := now.Format(quotaMonthLayout); pb.Month != month {
+		pb.Month, pb.MonthBytes = month, 0
+	}
+	return pb
+}
+
+// peerBandwidth is accounted bandwidth of a peer and the meter counting its traffic
+type peerBandwidth struct {
+	usage   PeerBandwidth
+	meter   *shared.ByteMeter
+	lastIn  uint64 // Meter counts at last sample
+	lastOut uint64
+}
+
+// Bandwidth accounts traffic per peer from meters shared by its participants, pulled rooms and libp2p streams
+type Bandwidth struct {
+	mtx   sync.Mutex
+	peers map[peer.ID]*peerBandwidth
+
+	// Reporter meters libp2p streams per peer, given to host as its bandwidth reporter
+	Reporter *bandwidthReporter
+}
+
+func NewBandwidth() *Bandwidth {
+	b := &Bandwidth{peers: make(map[peer.ID]*peerBandwidth)}
+	b.Reporter = &bandwidthReporter{BandwidthCounter: metrics.NewBandwidthCounter(), bandwidth: b}
+	return b
+}
+
+// Meter returns the meter counting traffic of a peer
+func (b *Bandwidth) Meter(id peer.ID) *shared.ByteMeter {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.peer(id).meter
+}
+
+// peer returns accounted bandwidth of a peer, adding it if new, mutex must be held
+func (b *Bandwidth) peer(id peer.ID) *peerBandwidth {
+	pb, ok := b.peers[id]
+	if !ok {
+		pb = &peerBandwidth{meter: &shared.ByteMeter{}}
+		b.peers[id] = pb
+	}
+	return pb
+}
+
+// Usage returns accounted bandwidth of a peer as of last sample
+func (b *Bandwidth) Usage(id peer.ID, now time.Time) PeerBandwidth {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if pb, ok := b.peers[id]; ok {
+		return pb.usage.current(now)
+	}
+	return PeerBandwidth{}.current(now)
+}
+
+// Peers returns accounted bandwidth of all peers as of last sample
+func (b *Bandwidth) Peers(now time.Time) map[peer.ID]PeerBandwidth {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	peers := make(map[peer.ID]PeerBandwidth, len(b.peers))
+	for id, pb := range b.peers {
+		peers[id] = pb.usage.current(now)
+	}
+	return peers
+}
+
+// Sample accounts meter counts since previous sample. Peers without traffic since and not active are forgotten,
+// unless they used an enabled quota in its current period.
+func (b *Bandwidth) Sample(now time.Time, active func(peer.ID) bool) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	flags := common.GetFlags()
+	for id, pb := range b.peers {
+		in, out := pb.meter.Load()
+		pb.usage = pb.usage.current(now)
+		pb.usage.In += in - pb.lastIn
+		pb.usage.Out += out - pb.lastOut
+		pb.usage.DayBytes += out - pb.lastOut
+		pb.usage.MonthBytes += out - pb.lastOut
+		idle := in == pb.lastIn && out == pb.lastOut
+		pb.lastIn, pb.lastOut = in, out
+
+		quotaUsed := (flags.QuotaDaily > 0 && pb.usage.DayBytes > 0) || (flags.QuotaMonthly > 0 && pb.usage.MonthBytes > 0)
+		if idle && !quotaUsed && !active(id) {
+			delete(b.peers, id)
+		}
+	}
+	b.Reporter.TrimIdle(now.Add(-bandwidthStreamIdle))
+}
+
+// Exceeded returns the rejection for a peer over a bandwidth quota as of last sample, nil if within quotas
+func (b *Bandwidth) Exceeded(id peer.ID, now time.Time) *gen.ProtoQuotaExceeded {
+	flags := common.GetFlags()
+	usage := b.Usage(id, now)
+	now = now.UTC()
+	if quota := uint64(flags.QuotaMonthly) << 20; quota > 0 && usage.MonthBytes >= quota {
+		return &gen.ProtoQuotaExceeded{
+			Scope:      quotaScopeMonthly,
+			UsedBytes:  usage.MonthBytes,
+			QuotaBytes: quota,
+			ResetUnix:  time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC).Unix(),
+		}
+	}
+	if quota := uint64(flags.QuotaDaily) << 20; quota > 0 && usage.DayBytes >= quota {
+		return &gen.ProtoQuotaExceeded{
+			Scope:      quotaScopeDaily,
+			UsedBytes:  usage.DayBytes,
+			QuotaBytes: quota,
+			ResetUnix:  time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC).Unix(),
+		}
+	}
+	return nil
+}
+
+// restore adds previously persisted bandwidth of peers
+func (b *Bandwidth) restore(persisted map[peer.ID]PeerBandwidth) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	for id, usage := range persisted {
+		pb := b.peer(id)
+		pb.usage.In += usage.In
+		pb.usage.Out += usage.Out
+		if pb.usage.Day == usage.Day || len(pb.usage.Day) <= 0 {
+			pb.usage.Day = usage.Day
+			pb.usage.DayBytes += usage.DayBytes
+		}
+		if pb.usage.Month == usage.Month || len(pb.usage.Month) <= 0 {
+			pb.usage.Month = usage.Month
+			pb.usage.MonthBytes += usage.MonthBytes
+		}
+	}
+}
+
+// Save saves bandwidth of peers to store
+func (b *Bandwidth) Save(store Store) error {
+	data, err := json.Marshal(b.Peers(time.Now()))
+	if err != nil {
+		return errors.New("failed to marshal bandwidth data: " + err.Error())
+	}
+	if err = store.PutDocument(storeDocBandwidth, data); err != nil {
+		return errors.New("failed to save bandwidth: " + err.Error())
+	}
+	slog.Debug("Bandwidth saved")
+	return nil
+}
+
+// Load restores bandwidth of peers from store
+func (b *Bandwidth) Load(store Store) error {
+	data, err := store.GetDocument(storeDocBandwidth)
+	if err != nil {
+		return errors.New("failed to read bandwidth: " + err.Error())
+	}
+	if data == nil {
+		slog.Info("No bandwidth saved, starting accounting from zero")
+		return nil
+	}
+
+	var persisted map[peer.ID]PeerBandwidth
+	if err = json.Unmarshal(data, &persisted); err != nil {
+		return errors.New("failed to unmarshal bandwidth data: " + err.Error())
+	}
+	b.restore(persisted)
+
+	slog.Info("Bandwidth loaded", "peers", len(persisted))
+	return nil
+}
+
+// Describe implements prometheus.Collector
+func (b *Bandwidth) Describe(ch chan<- *prometheus.Desc) {
+	ch <- peerBytesDesc
+}
+
+// Collect implements prometheus.Collector
+func (b *Bandwidth) Collect(ch chan<- prometheus.Metric) {
+	for id, usage := range b.Peers(time.Now()) {
+		ch <- prometheus.MustNewConstMetric(peerBytesDesc, prometheus.CounterValue, float64(usage.In), id.String(), "in")
+		ch <- prometheus.MustNewConstMetric(peerBytesDesc, prometheus.CounterValue, float64(usage.Out), id.String(), "out")
+	}
+}
+
+var peerBytesDesc = prometheus.NewDesc("nestri_relay_peer_bytes_total",
+	"Bytes exchanged with a peer over media and libp2p streams, persisted across restarts",
+	[]string{"peer", "direction"}, nil)
+
+// bandwidthReporter meters libp2p streams into peer meters, next to the counters libp2p keeps itself
+type bandwidthReporter struct {
+	*metrics.BandwidthCounter
+	bandwidth *Bandwidth
+}
+
+func (br *bandwidthReporter) LogSentMessageStream(size int64, proto protocol.ID, p peer.ID) {
+	br.BandwidthCounter.LogSentMessageStream(size, proto, p)
+	br.bandwidth.Meter(p).AddOut(int(size))
+}
+
+func (br *bandwidthReporter) LogRecvMessageStream(size int64, proto protocol.ID, p peer.ID) {
+	br.BandwidthCounter.LogRecvMessageStream(size, proto, p)
+	br.bandwidth.Meter(p).AddIn(int(size))
+}
+
+// --- Quota Enforcement ---
+
+// enforceQuotas cuts participants of peers which exceeded a bandwidth quota
+func (r *Relay) enforceQuotas(now time.Time) {
+	flags := common.GetFlags()
+	if flags.QuotaDaily <= 0 && flags.QuotaMonthly <= 0 {
+		return
+	}
+	for _, room := range r.LocalRooms.Copy() {
+		for _, participant := range room.GetParticipants() {
+			exceeded := r.Bandwidth.Exceeded(participant.PeerID, now)
+			if exceeded == nil {
+				continue
+			}
+			exceeded.RoomName = room.Name
+			quotaRejectedCounter.WithLabelValues(exceeded.Scope).Inc()
+			if err := sendQuotaExceededToParticipant(participant, exceeded); err != nil {
+				slog.Debug("Failed to send quota exceeded to participant", "room", room.Name, "participant", participant.ID, "err", err)
+			}
+			room.RemoveParticipantByID(participant.ID)
+			participant.Close()
+			slog.Warn("Cut participant over bandwidth quota", "room", room.Name, "participant", participant.ID, "peer", participant.PeerID, "scope", exceeded.Scope)
+		}
+	}
+}
+
+// refuseOverQuota checks bandwidth quotas of a requesting peer, refusing its stream request if over one
+func (r *Relay) refuseOverQuota(safeBRW *common.SafeBufioRW, id peer.ID, roomName string) bool {
+	exceeded := r.Bandwidth.Exceeded(id, time.Now())
+	if exceeded == nil {
+		return false
+	}
+	exceeded.RoomName = roomName
+	quotaRejectedCounter.WithLabelValues(exceeded.Scope).Inc()
+	slog.Warn("Refusing stream request over bandwidth quota", "room", roomName, "peer", id, "scope", exceeded.Scope, "used", exceeded.UsedBytes)
+
+	quotaMsg, err := common.CreateMessage(exceeded, "quota-exceeded", nil)
+	if err != nil {
+		slog.Error("Failed to create proto message", "err", err)
+		return true
+	}
+	if err = safeBRW.SendProto(quotaMsg); err != nil {
+		slog.Error("Failed to send quota exceeded", "room", roomName, "err", err)
+	}
+	return true
+}
+
+// sendQuotaExceededToParticipant tells a participant its stream is cut over its data channel
+func sendQuotaExceededToParticipant(participant *shared.Participant, exceeded *gen.ProtoQuotaExceeded) error {
+	dc := participant.DataChannel()
+	if dc == nil {
+		return errors.New("participant has no data channel")
+	}
+	quotaMsg, err := common.CreateMessage(exceeded, "quota-exceeded", nil)
+	if err != nil {
+		return err
+	}
+	data, err := proto.Marshal(quotaMsg)
+	if err != nil {
+		return err
+	}
+	return dc.SendBinary(data)
+}
@@ -0,0 +1,118 @@
+package core
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"relay/internal/common"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multiaddr"
+	madns "github.com/multiformats/go-multiaddr-dns"
+)
+
+// --- DNS Bootstrap ---
+//
+// Bootstrap relays may be listed in dnsaddr TXT records ("dnsaddr=/ip4/.../p2p/<id>" under _dnsaddr.<domain>),
+// so fleets rotate them by updating DNS. Records are resolved at startup and again periodically.
+
+// bootstrapDNSAddrs returns /dnsaddr addresses of the bootstrap DNS flag, which takes domains or full addresses
+func bootstrapDNSAddrs() []multiaddr.Multiaddr {
+	var addrs []multiaddr.Multiaddr
+	for _, entry := range strings.Split(common.GetFlags().BootstrapDNS, ",") {
+		entry = strings.TrimSpace(entry)
+		if len(entry) <= 0 {
+			continue
+		}
+		if !strings.HasPrefix(entry, "/") {
+			entry = "/dnsaddr/" + entry
+		}
+		addr, err := multiaddr.NewMultiaddr(entry)
+		if err != nil {
+			slog.Warn("Invalid bootstrap DNS entry", "entry", entry, "err", err)
+			continue
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs
+}
+
+// resolveBootstrapDNS resolves dnsaddr records into relays, following records pointing to other dnsaddr names
+func resolveBootstrapDNS(ctx context.Context, addrs []multiaddr.Multiaddr) []peer.AddrInfo {
+	var resolved []multiaddr.Multiaddr
+	for depth := 0; len(addrs) > 0 && depth < bootstrapDNSMaxDepth; depth++ {
+		var next []multiaddr.Multiaddr
+		for _, addr := range addrs {
+			if first, _ := multiaddr.SplitFirst(addr); first == nil || first.Protocol().Code != multiaddr.P_DNSADDR {
+				resolved = append(resolved, addr)
+				continue
+			}
+			results, err := madns.DefaultResolver.Resolve(ctx, addr)
+			if err != nil {
+				slog.Warn("Failed to resolve bootstrap DNS", "addr", addr, "err", err)
+				continue
+			}
+			next = append(next, results...)
+		}
+		addrs = next
+	}
+
+	var withID []multiaddr.Multiaddr
+	for _, addr := range resolved {
+		if _, id := peer.SplitAddr(addr); len(id) > 0 {
+			withID = append(withID, addr)
+		}
+	}
+	infos, err := peer.AddrInfosFromP2pAddrs(withID...)
+	if err != nil {
+		slog.Warn("Failed to parse bootstrap DNS addresses", "err", err)
+		return nil
+	}
+	return infos
+}
+
+// bootstrapFromDNS dials relays listed in bootstrap DNS records we aren't connected to
+func (r *Relay) bootstrapFromDNS(ctx context.Context, addrs []multiaddr.Multiaddr) {
+	resolveCtx, cancel := context.WithTimeout(ctx, bootstrapDNSTimeout)
+	infos := resolveBootstrapDNS(resolveCtx, addrs)
+	cancel()
+	slog.Debug("Resolved bootstrap DNS", "relays", len(infos))
+
+	var wg sync.WaitGroup
+	for _, info := range infos {
+		if info.ID == r.ID || r.isConnected(info.ID) {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := r.connectToPeer(ctx, &info); err != nil {
+				slog.Warn("Failed to connect to bootstrap relay from DNS", "peer", info.ID, "err", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// dnsBootstrapper resolves bootstrap DNS records at startup and periodically, so rotated relays are picked up
+func (r *Relay) dnsBootstrapper(ctx context.Context) {
+	addrs := bootstrapDNSAddrs()
+	if len(addrs) <= 0 {
+		return
+	}
+	r.bootstrapFromDNS(ctx, addrs)
+
+	ticker := time.NewTicker(bootstrapDNSInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.bootstrapFromDNS(ctx, addrs)
+		}
+	}
+}
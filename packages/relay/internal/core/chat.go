@@ -0,0 +1,137 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"relay/internal/common"
+	"relay/internal/connections"
+	"relay/internal/shared"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	gen "relay/internal/proto"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/protobuf/proto"
+)
+
+// --- Room Chat ---
+
+// registerChat relays chat messages a participant sends on its DataChannel to everyone in its room,
+// the relay stamps sender identity so participants can't impersonate each other
+func (sp *StreamProtocol) registerChat(ndc *connections.NestriDataChannel, participant *shared.Participant) {
+	limiter := rate.NewLimiter(rate.Every(chatRateInterval), chatRateBurst)
+	ndc.RegisterMessageCallback("chat", func(data []byte) {
+		var msgWrapper gen.ProtoMessage
+		if err := proto.Unmarshal(data, &msgWrapper); err != nil {
+			slog.Error("Failed to unmarshal chat message", "err", err)
+			return
+		}
+		chatMsg := msgWrapper.GetChat()
+		if chatMsg == nil {
+			slog.Error("Could not GetChat from chat")
+			return
+		}
+		room := participant.Room()
+		if room == nil {
+			return
+		}
+
+		text := strings.TrimSpace(chatMsg.Text)
+		if len(text) <= 0 {
+			return
+		}
+		if !limiter.Allow() {
+			sp.relay.chatRejected(participant, "Sending chat messages too fast")
+			return
+		}
+		if !utf8.ValidString(text) || utf8.RuneCountInString(text) > chatMaxLength {
+			sp.relay.chatRejected(participant, "Chat message too long")
+			return
+		}
+
+		stamped := &gen.ProtoChatMessage{
+			RoomName:       shared.BaseRoomName(room.Name),
+			Text:           text,
+			SenderId:       participant.ID.String(),
+			SenderName:     truncateRunes(strings.ToValidUTF8(strings.TrimSpace(chatMsg.SenderName), ""), chatMaxNameLength),
+			SenderIdentity: participant.Identity,
+			SentUnixMs:     time.Now().UnixMilli(),
+		}
+		sp.relay.deliverChat(stamped)
+		if err := sp.relay.publishChat(context.Background(), stamped); err != nil {
+			slog.Error("Failed to publish chat message", "room", room.Name, "err", err)
+		}
+	})
+}
+
+// chatRejected lets a participant know its chat message was dropped
+func (r *Relay) chatRejected(participant *shared.Participant, reason string) {
+	if err := r.SendNotice(participant, reason, "warning"); err != nil {
+		slog.Debug("Failed to send chat rejection", "participant", participant.ID, "err", err)
+	}
+}
+
+// deliverChat sends a chat message to local participants of its room and quality variants,
+// relays get it over the chat topic instead
+func (r *Relay) deliverChat(chatMsg *gen.ProtoChatMessage) {
+	rooms := r.roomFamily(chatMsg.RoomName)
+	if len(rooms) <= 0 {
+		return
+	}
+	msg, err := common.CreateMessage(chatMsg, "chat", nil)
+	if err != nil {
+		slog.Error("Failed to create proto message", "err", err)
+		return
+	}
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		slog.Error("Failed to marshal chat message", "err", err)
+		return
+	}
+	for _, room := range rooms {
+		for _, participant := range room.GetParticipants() {
+			dc := participant.DataChannel()
+			if dc == nil || r.isMeshRelay(participant.PeerID) {
+				continue
+			}
+			if err = dc.SendBinary(data); err != nil {
+				slog.Debug("Failed to send chat message to participant", "room", room.Name, "participant", participant.ID, "err", err)
+			}
+		}
+	}
+}
+
+// validRelayedChat checks chat gossiped by a relay as strictly as chat of local participants, it could be
+// sent by a misbehaving relay
+func validRelayedChat(chatMsg *gen.ProtoChatMessage) bool {
+	if len(chatMsg.RoomName) <= 0 || len(strings.TrimSpace(chatMsg.Text)) <= 0 {
+		return false
+	}
+	if !utf8.ValidString(chatMsg.Text) || utf8.RuneCountInString(chatMsg.Text) > chatMaxLength {
+		return false
+	}
+	return utf8.ValidString(chatMsg.SenderName) && utf8.RuneCountInString(chatMsg.SenderName) <= chatMaxNameLength
+}
+
+// publishChat sends a chat message to mesh relays, which deliver it to their participants of the room
+func (r *Relay) publishChat(ctx context.Context, chatMsg *gen.ProtoChatMessage) error {
+	if r.pubTopicChat == nil {
+		return errors.New("chat topic is nil")
+	}
+	data, err := proto.Marshal(chatMsg)
+	if err != nil {
+		return err
+	}
+	return r.pubTopicChat.Publish(ctx, data)
+}
+
+// truncateRunes cuts a string to at most n runes
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
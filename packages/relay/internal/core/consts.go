@@ -5,9 +5,107 @@ import "time"
 // --- Constants ---
 const (
 	// PubSub Topics
-	roomStateTopicName    = "room-states"
-	relayMetricsTopicName = "relay-metrics"
+	roomStateTopicName      = "room-states"
+	relayMetricsTopicName   = "relay-metrics"
+	roomBansTopicName       = "room-bans"
+	roomChatTopicName       = "room-chat"
+	relaySuccessorTopicName = "relay-successors"
 
 	// Timers and Intervals
-	metricsPublishInterval = 15 * time.Second // How often to publish own metrics
+	latencyPingTimeout        = 10 * time.Second // Timeout of a single latency ping
+	streamPullTimeout         = 10 * time.Second // How long to wait for a requested stream from a single peer
+	reconnectCheckInterval    = 2 * time.Second  // How often reconnect supervisor checks for peers due a dial
+	reconnectDialTimeout      = 15 * time.Second // Timeout of a single reconnect dial
+	statsSampleInterval       = 2 * time.Second  // How often track statistics rates are computed
+	statsReportInterval       = 5 * time.Second  // How often stream-stats are sent to viewers
+	jobRetention              = 1 * time.Hour    // How long finished admin jobs are kept for progress queries
+	experimentCheckInterval   = 2 * time.Second  // How often experiments sample new viewers into shadow rooms
+	adminEventPingInterval    = 30 * time.Second // How often admin event streams are pinged to keep proxies happy
+	adminEventWriteTimeout    = 10 * time.Second // Write deadline for admin event stream messages
+	usageSampleInterval       = 10 * time.Second // How often usage of local rooms is accounted
+	usageSnapshotInterval     = 1 * time.Minute  // How often usage totals are saved to persistent directory
+	roomGCInterval            = 30 * time.Second // How often local rooms are checked for idle TTL expiry
+	pullLingerCheckInterval   = 1 * time.Second  // How often pulled rooms are checked for local participants
+	viewerCountInterval       = 5 * time.Second  // How often viewer counts are sent to pushing nodes and viewers
+	viewerCountCoalesce       = 1 * time.Second  // Joins and leaves within this are sent as one viewer count update
+	audioLevelInterval        = time.Second / 5  // How often changed audio levels are sent to viewers
+	viewerTokenLeeway         = 30 * time.Second // Clock skew tolerated on viewer token expiry and not-before
+	pushSignatureMaxAge       = 5 * time.Minute  // How far push signature time may be from now, bounds clock skew and replays
+	pushKeysRefreshInterval   = 5 * time.Minute  // How often trusted push keys are fetched from platform
+	pushKeysFetchTimeout      = 10 * time.Second // Timeout of a trusted push key fetch
+	authFailureWindow         = 1 * time.Minute  // Window failed stream request authorizations of a peer are counted in
+	authBlockDuration         = 1 * time.Minute  // How long a peer is refused after too many failed authorizations
+	kickedSessionTTL          = 1 * time.Hour    // How long session ID of a kicked participant can't be resumed
+	sessionResumeTTL          = 1 * time.Minute  // How long RTP numbering of a departed viewer session is kept for it to resume
+	roomBanRetention          = 24 * time.Hour   // How long expired and lifted bans are kept, stops stale gossip reviving them
+	roomBanMaxDuration        = 720 * time.Hour  // Longest ban issued, gossiped bans are cut down to it
+	chatRateInterval          = 1 * time.Second  // Sustained rate of chat messages per participant, one per interval
+	signalingBucketIdle       = 10 * time.Minute // Signaling rate limit state of a peer or IP unused this long is forgotten
+	identityPassphraseTimeout = 30 * time.Second // How long identity passphrase command may run
+	identityRotationGrace     = 10 * time.Minute // Default time a rotated identity keeps serving before it's retired
+	successorAnnounceInterval = 30 * time.Second // How often a pending identity rotation is announced to the mesh
+	rotationCheckInterval     = 1 * time.Second  // How often a pending identity rotation is checked for retirement
+	certReloadInterval        = 1 * time.Minute  // How often secure WebSocket certificate files are checked for changes
+	certHashCheckInterval     = 1 * time.Hour    // How often WebTransport certificate hashes are checked for rollover
+	bandwidthStreamIdle       = 10 * time.Minute // libp2p stream counters of peers idle this long are trimmed
+	successorClockSkew        = 30 * time.Second // Peers disconnecting this close to their retire time are followed to their successor
+	overloadCheckInterval     = 2 * time.Second  // How often relay load is checked against overload thresholds
+	overloadAdviseInterval    = 10 * time.Second // How often an overload advisory is repeated while overloaded
+	overloadRecoverDelay      = 10 * time.Second // How long load must stay below recovery thresholds before advising recovery
+	overloadAdvisoryTTL       = 30 * time.Second // How long an overload advisory of a mesh relay is honored without being repeated
+	linkProbeTimeout          = 10 * time.Second // Timeout of a mesh link capacity probe
+	linkProbeTTL              = 5 * time.Minute  // How long a mesh link probe is used for routing without being repeated
+	bootstrapDNSInterval      = 10 * time.Minute // How often bootstrap DNS records are resolved again
+	bootstrapDNSTimeout       = 10 * time.Second // Timeout of resolving all bootstrap DNS records
+	staticPeerBackoffMax      = 1 * time.Minute  // Upper bound for dial backoff of static peers, they are never given up
+	portMappingTimeout        = 10 * time.Second // Timeout of finding a UPnP or NAT-PMP router and mapping a port on it
+	holePunchPinDuration      = 30 * time.Minute // How long a peer hole punches keep failing with is kept on circuit relay
+	roomLeaseDuration         = 45 * time.Second // How long a room owner's claim holds without being announced again
+	roomEpochRetention        = 24 * time.Hour   // How long ownership epoch of a room name is kept after it was last seen
+	sharedStateTimeout        = 2 * time.Second  // Timeout of one shared state operation, relays fall back to their own state past it
+	roomDirectoryTimeout      = 2 * time.Second  // Timeout of one room directory backend operation
+	roomDirectoryCacheTTL     = 10 * time.Second // How long a room directory answer is used before asking again
+	thumbnailDecodeTimeout    = 10 * time.Second // How long decoding a keyframe to a thumbnail may take
+	inputLatencyProbeInterval = 1 * time.Second  // How often input of a viewer is stamped to measure its round trip
+	directoryQueryTimeout     = 10 * time.Second // Timeout of a room directory query to a mesh relay
+
+	// Stream clock
+	streamClockInterval  = 2 * time.Second        // How often stream clocks of rooms are sent to participants
+	streamClockMaxDrift  = 250 * time.Millisecond // Video arriving this much later than its stream clock anchors it again
+	streamClockMinAdjust = 5 * time.Millisecond   // Video arriving this much earlier than its stream clock anchors it again
+
+	// Buffers
+	adminEventBuffer       = 64 // Events buffered per admin event stream before dropping
+	viewerCountEventBuffer = 64 // Events buffered for viewer count updates before dropping, periodic updates catch up
+	thumbnailEventBuffer   = 64 // Events buffered for forgetting thumbnails of closed rooms before dropping
+
+	// Limits
+	authFailureLimit     = 5   // Failed authorizations within authFailureWindow before a peer is blocked
+	chatMaxLength        = 500 // Max characters of a chat message
+	chatMaxNameLength    = 32  // Max characters of a chat sender name, longer names are cut
+	chatRateBurst        = 5   // Chat messages a participant may send at once before being rate limited
+	inputRateBurst       = 100 // Input messages a viewer may send at once before being rate limited
+	roomMetadataMaxTitle = 128 // Max characters of a room title, longer titles are cut
+	roomMetadataMaxGame  = 128 // Max characters of a room game name, longer names are cut
+	roomWaitingListMax   = 256 // Viewer requests waiting for an offline room, later requests are only refused
+	streamRateBurst      = 10  // Signaling streams a peer may open at once before being rate limited
+	messageRateBurst     = 100 // Signaling messages a peer may send at once, covers ICE candidate bursts
+	signalingIPShare     = 10  // Peers behind one IP together get this many times the signaling budget of a peer
+	upstreamRetryMax     = 5   // Attempts to pull a room again after its upstream ended, while local participants wait
+	audioLevelStep       = 3   // Audio level change in dB sent to viewers, smaller ones are left out
+	bootstrapDNSMaxDepth = 4   // Levels of dnsaddr records pointing to other dnsaddr names followed
+	holePunchMaxFailures = 3   // Consecutive failed direct connection attempts before a peer is pinned to circuit relay
+
+	// Push authentication
+	pushChallengeNonceSize = 32      // Random bytes of a push challenge nonce
+	pushKeysMaxResponse    = 1 << 20 // Bytes of a platform push key listing read at most
+
+	// Admin API
+	adminPeerstoreMaxImport = 8 << 20 // Bytes of a peer store file accepted for import at most
+
+	// Thumbnails
+	thumbnailMaxPackets = 1024 // RTP packets of a keyframe collected for a thumbnail at most, larger keyframes are skipped
+
+	// Ratios
+	overloadRecoverShare = 0.8 // Share of overload thresholds load must fall below to recover
 )
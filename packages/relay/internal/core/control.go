@@ -0,0 +1,254 @@
+package core
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"relay/internal/common"
+	"relay/internal/shared"
+	"strconv"
+	"strings"
+	"time"
+
+	gen "relay/internal/proto"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/oklog/ulid/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// --- gRPC Control Service ---
+
+// controlService implements RelayControl over gRPC for the control plane
+type controlService struct {
+	gen.UnimplementedRelayControlServer
+	relay *Relay
+}
+
+// startControlService serves the gRPC control service until context is done
+func (r *Relay) startControlService(ctx context.Context) error {
+	flags := common.GetFlags()
+	if len(flags.AdminToken) <= 0 {
+		return errors.New("gRPC control service requires a token, set adminToken")
+	}
+
+	// The admin token is sent with every call, it must not cross the network in cleartext
+	var creds []grpc.ServerOption
+	if len(flags.GRPCCertFile) > 0 || len(flags.GRPCKeyFile) > 0 {
+		reloader, err := newCertReloader(flags.GRPCCertFile, flags.GRPCKeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load gRPC control service certificate: %w", err)
+		}
+		creds = append(creds, grpc.Creds(credentials.NewTLS(&tls.Config{
+			GetCertificate: reloader.GetCertificate,
+			MinVersion:     tls.VersionTLS12,
+		})))
+	} else if !isLoopbackHost(flags.GRPCHost) {
+		return errors.New("gRPC control service on a non-loopback address requires TLS, set grpcCertFile and grpcKeyFile")
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(flags.GRPCHost, strconv.Itoa(flags.GRPCPort)))
+	if err != nil {
+		return fmt.Errorf("failed to listen for gRPC control service: %w", err)
+	}
+
+	server := grpc.NewServer(append(creds,
+		grpc.UnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			if err := controlAuth(ctx, flags.AdminToken); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := controlAuth(ss.Context(), flags.AdminToken); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}),
+	)...)
+	gen.RegisterRelayControlServer(server, &controlService{relay: r})
+
+	go func() {
+		<-ctx.Done()
+		server.Stop()
+	}()
+	go func() {
+		slog.Info("Starting gRPC control service", "addr", listener.Addr(), "tls", len(creds) > 0)
+		if err := server.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			slog.Error("gRPC control service failed", "err", err)
+		}
+	}()
+	return nil
+}
+
+// isLoopbackHost reports whether host only accepts connections from this machine
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// controlAuth requires the admin bearer token in request metadata
+func controlAuth(ctx context.Context, token string) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		given, ok := strings.CutPrefix(value, "Bearer ")
+		if ok && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "unauthorized")
+}
+
+func (s *controlService) controlRoom(room *shared.Room, withParticipants bool) *gen.ControlRoom {
+	view := &gen.ControlRoom{
+		Id:           room.ID.String(),
+		Name:         room.Name,
+		OwnerId:      room.OwnerID.String(),
+		Settings:     room.GetSettings().ToProto(),
+		Viewers:      int32(room.ParticipantCount()),
+		Online:       room.IsOnline(),
+		HopLatencyMs: uint32(room.HopLatency().Milliseconds()),
+		Stats:        streamStatsMessage(room),
+	}
+	if upstreamID := room.UpstreamID(); len(upstreamID) > 0 {
+		view.UpstreamId = upstreamID.String()
+	}
+	if withParticipants {
+		for _, participant := range room.GetParticipants() {
+			view.Participants = append(view.Participants, &gen.ControlParticipant{
+				Id:            participant.ID.String(),
+				SessionId:     participant.SessionID,
+				PeerId:        participant.PeerID.String(),
+				QueueDelayMs:  uint32(participant.QueueDelay().Milliseconds()),
+				DroppedFrames: participant.DroppedFrames(),
+			})
+		}
+	}
+	return view
+}
+
+func (s *controlService) roomByName(name string) (*shared.Room, error) {
+	room := s.relay.GetRoomByName(name)
+	if room == nil {
+		return nil, status.Error(codes.NotFound, "room not found")
+	}
+	return room, nil
+}
+
+func (s *controlService) ListRooms(context.Context, *gen.ControlListRoomsRequest) (*gen.ControlListRoomsResponse, error) {
+	resp := &gen.ControlListRoomsResponse{}
+	for _, room := range s.relay.LocalRooms.Copy() {
+		resp.Rooms = append(resp.Rooms, s.controlRoom(room, false))
+	}
+	return resp, nil
+}
+
+func (s *controlService) GetRoom(_ context.Context, req *gen.ControlRoomRequest) (*gen.ControlRoom, error) {
+	room, err := s.roomByName(req.RoomName)
+	if err != nil {
+		return nil, err
+	}
+	return s.controlRoom(room, true), nil
+}
+
+func (s *controlService) CloseRoom(_ context.Context, req *gen.ControlRoomRequest) (*gen.ControlEmpty, error) {
+	room, err := s.roomByName(req.RoomName)
+	if err != nil {
+		return nil, err
+	}
+	s.relay.CloseRoom(room)
+	return &gen.ControlEmpty{}, nil
+}
+
+func (s *controlService) KickParticipant(_ context.Context, req *gen.ControlParticipantRequest) (*gen.ControlEmpty, error) {
+	room, err := s.roomByName(req.RoomName)
+	if err != nil {
+		return nil, err
+	}
+	participantID, err := ulid.Parse(req.ParticipantId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid participant ID")
+	}
+	if !s.relay.KickParticipant(room, participantID, "") {
+		return nil, status.Error(codes.NotFound, "participant not found")
+	}
+	return &gen.ControlEmpty{}, nil
+}
+
+func (s *controlService) ListPeers(context.Context, *gen.ControlListPeersRequest) (*gen.ControlListPeersResponse, error) {
+	resp := &gen.ControlListPeersResponse{}
+	for id, pi := range s.relay.Peers.Copy() {
+		latency, _ := s.relay.Latencies.Get(id)
+		controlPeer := &gen.ControlPeer{
+			Id:           id.String(),
+			Connected:    s.relay.Host.Network().Connectedness(id) == network.Connected,
+			LatencyMs:    uint32(latency.Milliseconds()),
+			DialFailures: int32(pi.DialFailures),
+		}
+		if !pi.LastSeen.IsZero() {
+			controlPeer.LastSeenUnix = pi.LastSeen.Unix()
+		}
+		for _, addr := range pi.Addrs {
+			controlPeer.Addrs = append(controlPeer.Addrs, addr.String())
+		}
+		resp.Peers = append(resp.Peers, controlPeer)
+	}
+	return resp, nil
+}
+
+func (s *controlService) DisconnectPeer(_ context.Context, req *gen.ControlPeerRequest) (*gen.ControlEmpty, error) {
+	peerID, err := peer.Decode(req.PeerId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid peer ID")
+	}
+	if s.relay.Host.Network().Connectedness(peerID) != network.Connected {
+		return nil, status.Error(codes.NotFound, "peer not connected")
+	}
+	if err = s.relay.Host.Network().ClosePeer(peerID); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	slog.Info("Disconnected peer by control request", "peer", peerID)
+	return &gen.ControlEmpty{}, nil
+}
+
+func (s *controlService) WatchStats(req *gen.ControlWatchStatsRequest, stream grpc.ServerStreamingServer[gen.ProtoStreamStats]) error {
+	interval := statsReportInterval
+	if req.IntervalMs > 0 {
+		interval = max(time.Duration(req.IntervalMs)*time.Millisecond, statsSampleInterval)
+	}
+	if len(req.RoomName) > 0 {
+		if _, err := s.roomByName(req.RoomName); err != nil {
+			return err
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-ticker.C:
+			for _, room := range s.relay.LocalRooms.Copy() {
+				if !room.IsOnline() || (len(req.RoomName) > 0 && room.Name != req.RoomName) {
+					continue
+				}
+				if err := stream.Send(streamStatsMessage(room)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
@@ -2,14 +2,16 @@ package core
 
 import (
 	"context"
-	"crypto/ed25519"
 	"fmt"
 	"log"
 	"log/slog"
 	"net/http"
-	"os"
 	"relay/internal/common"
 	"relay/internal/shared"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/libp2p/go-libp2p"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
@@ -18,11 +20,12 @@ import (
 	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/peer"
 	rcmgr "github.com/libp2p/go-libp2p/p2p/host/resource-manager"
+	"github.com/libp2p/go-libp2p/p2p/protocol/holepunch"
 	"github.com/libp2p/go-libp2p/p2p/protocol/ping"
-	"github.com/libp2p/go-libp2p/p2p/security/noise"
 	p2pquic "github.com/libp2p/go-libp2p/p2p/transport/quic"
 	"github.com/libp2p/go-libp2p/p2p/transport/quicreuse"
 	"github.com/libp2p/go-libp2p/p2p/transport/tcp"
+	"github.com/libp2p/go-libp2p/p2p/transport/websocket"
 	webtransport "github.com/libp2p/go-libp2p/p2p/transport/webtransport"
 	"github.com/multiformats/go-multiaddr"
 	"github.com/oklog/ulid/v2"
@@ -40,6 +43,7 @@ var globalRelay *Relay
 // Relay structure enhanced with metrics and state
 type Relay struct {
 	*PeerInfo
+	statusMtx sync.RWMutex // Guards announced addresses of PeerInfo, changing while relay status is published
 
 	Host        host.Host      // libp2p host for peer-to-peer networking
 	PubSub      *pubsub.PubSub // PubSub for state synchronization
@@ -49,28 +53,100 @@ type Relay struct {
 	LocalRooms           *common.SafeMap[ulid.ULID, *shared.Room]         // room ID -> local Room struct (hosted by this relay)
 	LocalMeshConnections *common.SafeMap[peer.ID, *webrtc.PeerConnection] // peer ID -> PeerConnection (connected to this relay)
 
+	// Mesh
+	Routes         *common.SafeMap[string, *common.SafeMap[peer.ID, shared.RoomInfo]] // room name -> (serving peer ID -> announced RoomInfo)
+	reconnectPeers *common.SafeMap[peer.ID, *PeerInfo]                                // peer ID -> PeerInfo (dropped mesh peers to reconnect)
+	staticPeers    *common.SafeMap[peer.ID, *staticPeer]                              // peer ID -> relay from peers flag, kept connected
+	meshViewers    *common.SafeMap[peer.ID, map[string]int]                           // peer ID -> (room name -> viewers announced by peer)
+	successors     *common.SafeMap[peer.ID, *SuccessorRecord]                         // peer ID -> successor announced by rotating peer
+	linkProbes     *common.SafeMap[peer.ID, LinkProbe]                                // peer ID -> capacity of link from peer, as last probed
+	roomEpochs     *common.SafeMap[string, roomEpoch]                                 // room name -> highest ownership epoch seen
+	linkSamples    linkSamples                                                        // Stats totals of links from peers, as last sampled
+	holePunches    holePunches                                                        // Outcomes of direct connection attempts to relayed peers
+	lifecycle      peerLifecycle                                                      // Cleanups of departed peers in progress
+
+	// Events
+	Events *EventBus // Local relay state changes
+
+	draining atomic.Bool // Refusing new viewers and pushes, for maintenance
+
+	reachability atomic.Int32 // network.Reachability detected by AutoNAT
+
+	// Backpressure
+	overloaded      atomic.Bool                         // Advising upstream that this relay is overloaded
+	overloadedPeers *common.SafeMap[peer.ID, time.Time] // peer ID -> expiry of overload advisory from mesh relay
+
+	rotation atomic.Pointer[SuccessorRecord] // Pending rotation of our identity, nil if none
+	stop     context.CancelFunc              // Stops the relay, to restart on a successor identity
+
+	// Admin jobs
+	Jobs *common.SafeMap[ulid.ULID, *Job] // Bulk admin operations, kept for progress queries
+
+	// Encoder experiments
+	Experiments *common.SafeMap[string, *Experiment] // Room name -> experiment running on it
+
+	// Persistence
+	Store Store // Peer store and usage totals in persistent directory, nil for relays not created by InitRelay
+
+	// Cluster state
+	Shared      SharedState                              // Sessions, bans and room directory shared with relays of a cluster, nil if not enabled
+	sharedRooms *common.SafeMap[string, shared.RoomInfo] // Room ID -> room of cluster directory, refreshed from shared state
+
+	// Room directory of the platform
+	Directory      RoomDirectory                            // Authoritative rooms and their access policy, nil lets relays serve any room
+	directoryCache *common.SafeMap[string, directoryLookup] // Room name -> last room directory answer
+
+	// Room thumbnails
+	thumbnailDecoder ThumbnailDecoder                   // Decodes grabbed keyframes, nil if thumbnails are disabled
+	thumbnails       *common.SafeMap[string, Thumbnail] // Room name -> latest thumbnail of owned room
+
+	// Usage accounting
+	Usage     *Usage     // Cumulative stream usage, persisted across restarts
+	Bandwidth *Bandwidth // Traffic and quota usage per peer, persisted across restarts
+
+	// Moderation
+	Moderation *Moderation // Room bans and sessions invalidated by kicks
+
+	wsProxyFront   *wsProxyFront     // WebSocket front for reverse proxied clients, nil if not enabled
+	viewerAuth     *ViewerAuth       // Viewer token validation, nil if viewer authorization is disabled
+	geoIP          *common.GeoIP     // GeoIP database for viewer steering, nil if not enabled
+	pushKeys       *PushKeyring      // Keys trusted to push and rooms bound to them, nil if push challenges are disabled
+	authFailures   *authLimiter      // Failed stream request authorizations per peer
+	streamLimiter  *signalingLimiter // Signaling stream openings per peer and IP
+	messageLimiter *signalingLimiter // Signaling messages per peer and IP
+
 	// Protocols
 	ProtocolRegistry
 
 	// PubSub Topics
 	pubTopicState        *pubsub.Topic // topic for room states
 	pubTopicRelayMetrics *pubsub.Topic // topic for relay metrics/status
+	pubTopicBans         *pubsub.Topic // topic for room bans
+	pubTopicChat         *pubsub.Topic // topic for room chat
+	pubTopicSuccessors   *pubsub.Topic // topic for identity successors of rotating relays
 }
 
-func NewRelay(ctx context.Context, port int, identityKey crypto.PrivKey) (*Relay, error) {
+func NewRelay(ctx context.Context, ports ListenPorts, identityKey crypto.PrivKey) (*Relay, error) {
+	viewerAuth, err := NewViewerAuth(common.GetFlags())
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up viewer authorization: %w", err)
+	}
+
 	// If metrics are enabled, start the metrics server first
 	metricsOpts := make([]libp2p.Option, 0)
 	var rmgr network.ResourceManager
 	if common.GetFlags().Metrics {
 		go func() {
 			slog.Info("Starting prometheus metrics server at '/debug/metrics/prometheus'", "port", common.GetFlags().MetricsPort)
-			http.Handle("/debug/metrics/prometheus", promhttp.Handler())
-			if err := http.ListenAndServe(fmt.Sprintf(":%d", common.GetFlags().MetricsPort), nil); err != nil {
+			mux := newMetricsMux(promhttp.Handler(), common.GetFlags().DebugEndpoints)
+			if err := http.ListenAndServe(fmt.Sprintf(":%d", common.GetFlags().MetricsPort), mux); err != nil {
 				slog.Error("Failed to start metrics server", "err", err)
 			}
 		}()
 
 		rcmgr.MustRegisterWith(prometheus.DefaultRegisterer)
+		common.RegisterProtocolMetrics()
+		prometheus.MustRegister(signalingThrottledCounter, quotaRejectedCounter, relayOverloadedGauge, ingestFailoverCounter, inputThrottledCounter, controllerFeedbackDroppedCounter, inputLatencyHistogram, peerLatencySummary, relayReachabilityGauge, holePunchCounter)
 
 		str, err := rcmgr.NewStatsTraceReporter()
 		if err != nil {
@@ -86,47 +162,115 @@ func NewRelay(ctx context.Context, port int, identityKey crypto.PrivKey) (*Relay
 		metricsOpts = append(metricsOpts, libp2p.PrometheusRegisterer(prometheus.DefaultRegisterer))
 	} else {
 		rmgr = nil
+		if common.GetFlags().DebugEndpoints {
+			slog.Warn("Debug endpoints are served on metrics server, ignoring as metrics are disabled")
+		}
 	}
 
-	listenAddrs := []string{
-		fmt.Sprintf("/ip4/0.0.0.0/tcp/%d", port),                      // IPv4 - Raw TCP
-		fmt.Sprintf("/ip6/::/tcp/%d", port),                           // IPv6 - Raw TCP
-		fmt.Sprintf("/ip4/0.0.0.0/udp/%d/quic-v1/webtransport", port), // IPv4 - UDP QUIC WebTransport
-		fmt.Sprintf("/ip6/::/udp/%d/quic-v1/webtransport", port),      // IPv6 - UDP QUIC WebTransport
-		fmt.Sprintf("/ip4/0.0.0.0/udp/%d/quic-v1", port),              // IPv4 - UDP Raw QUIC
-		fmt.Sprintf("/ip6/::/udp/%d/quic-v1", port),                   // IPv6 - UDP Raw QUIC
+	muAddrs, err := ports.multiaddrs()
+	if err != nil {
+		return nil, err
+	}
+	securityOpt, err := securityOptions(common.GetFlags().Security)
+	if err != nil {
+		return nil, err
 	}
 
-	var muAddrs []multiaddr.Multiaddr
-	for _, addr := range listenAddrs {
-		multiAddr, err := multiaddr.NewMultiaddr(addr)
+	// Secure WebSocket terminates TLS itself, announcing ACME domains so browsers can validate certificates
+	wsTransport := libp2p.Transport(websocket.New)
+	addrsFactory := libp2p.ChainOptions()
+	if len(ports.WSS) > 0 {
+		tlsConf, err := wssTLSConfig(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse multiaddr '%s': %w", addr, err)
+			return nil, fmt.Errorf("failed to set up secure WebSocket: %w", err)
+		}
+		wsTransport = libp2p.Transport(websocket.New, websocket.WithTLSConfig(tlsConf))
+		if domainAddrs := wssDomainAddrs(ports.WSS); len(domainAddrs) > 0 {
+			addrsFactory = libp2p.AddrsFactory(func(addrs []multiaddr.Multiaddr) []multiaddr.Multiaddr {
+				return append(addrs, domainAddrs...)
+			})
 		}
-		muAddrs = append(muAddrs, multiAddr)
 	}
 
-	// Initialize libp2p host
+	// Router maps libp2p listen ports, announced with their external addresses
+	portMapping := libp2p.ChainOptions()
+	if common.GetFlags().PortMapping {
+		portMapping = libp2p.NATPortMap()
+	}
+
+	// Initialize libp2p host, metering streams per peer. Public relays serve circuit relay slots, private ones
+	// reserve slots on mesh relays once the relay is set up.
+	bandwidth := NewBandwidth()
+	relaySource := new(atomic.Pointer[Relay])
+	holePunchOpt := holepunch.WithTracer(holePunchTracer{relay: relaySource})
+	if common.GetFlags().Metrics {
+		holePunchOpt = holepunch.WithMetricsAndEventTracer(holepunch.NewMetricsTracer(holepunch.WithRegisterer(prometheus.DefaultRegisterer)), holePunchTracer{relay: relaySource})
+	}
 	p2pHost, err := libp2p.New(
 		libp2p.ChainOptions(metricsOpts...),
+		libp2p.BandwidthReporter(bandwidth.Reporter),
 		libp2p.Identity(identityKey),
 		// Enable required transports
 		libp2p.Transport(tcp.NewTCPTransport),
+		wsTransport,
 		libp2p.Transport(webtransport.New),
 		libp2p.Transport(p2pquic.NewTransport),
 		// Other options
 		libp2p.ListenAddrs(muAddrs...),
-		libp2p.Security(noise.ID, noise.New),
+		securityOpt,
+		addrsFactory,
 		libp2p.EnableRelay(),
-		libp2p.EnableHolePunching(),
+		libp2p.EnableRelayService(),
+		libp2p.EnableAutoRelayWithPeerSource(circuitRelaySource(relaySource)),
+		libp2p.EnableHolePunching(holePunchOpt),
 		libp2p.EnableNATService(),
 		libp2p.EnableAutoNATv2(),
 		libp2p.ShareTCPListener(),
 		libp2p.QUICReuse(quicreuse.NewConnManager),
+		portMapping,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create libp2p host for relay: %w", err)
 	}
+	r, err := newRelay(ctx, p2pHost, bandwidth, viewerAuth, ports, true)
+	if err != nil {
+		return nil, err
+	}
+	relaySource.Store(r)
+	return r, nil
+}
+
+// NewRelayOnHost sets up a relay on an existing libp2p host, such as one of a mocknet, without discovery, metrics
+// or listeners of its own
+func NewRelayOnHost(ctx context.Context, p2pHost host.Host) (*Relay, error) {
+	viewerAuth, err := NewViewerAuth(common.GetFlags())
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up viewer authorization: %w", err)
+	}
+	return newRelay(ctx, p2pHost, NewBandwidth(), viewerAuth, ListenPorts{}, false)
+}
+
+// newRelay sets up relay state, protocols and background tasks on p2pHost
+func newRelay(ctx context.Context, p2pHost host.Host, bandwidth *Bandwidth, viewerAuth *ViewerAuth, ports ListenPorts, discovery bool) (*Relay, error) {
+	pushKeys, err := NewPushKeyring(common.GetFlags(), p2pHost.Peerstore().PubKey(p2pHost.ID()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up push challenges: %w", err)
+	}
+
+	var geoIP *common.GeoIP
+	if path := common.GetFlags().GeoIPDatabase; len(path) > 0 {
+		if geoIP, err = common.LoadGeoIP(path); err != nil {
+			return nil, err
+		}
+		slog.Info("Loaded GeoIP database", "path", path, "networks", geoIP.Len())
+	}
+
+	var thumbnailDecoder ThumbnailDecoder
+	if flags := common.GetFlags(); flags.ThumbnailInterval > 0 {
+		if thumbnailDecoder, err = OpenThumbnailDecoder(flags.ThumbnailDecoder, flags.ThumbnailFormat, flags.ThumbnailWidth); err != nil {
+			return nil, fmt.Errorf("failed to set up thumbnails: %w", err)
+		}
+	}
 
 	// Set up pubsub
 	p2pPubsub, err := pubsub.NewGossipSub(ctx, p2pHost)
@@ -138,12 +282,52 @@ func NewRelay(ctx context.Context, port int, identityKey crypto.PrivKey) (*Relay
 	pingSvc := ping.NewPingService(p2pHost)
 
 	r := &Relay{
-		PeerInfo:             NewPeerInfo(p2pHost.ID(), p2pHost.Addrs()),
+		PeerInfo:             NewPeerInfo(p2pHost.ID(), nil),
 		Host:                 p2pHost,
 		PubSub:               p2pPubsub,
 		PingService:          pingSvc,
 		LocalRooms:           common.NewSafeMap[ulid.ULID, *shared.Room](),
 		LocalMeshConnections: common.NewSafeMap[peer.ID, *webrtc.PeerConnection](),
+		Routes:               common.NewSafeMap[string, *common.SafeMap[peer.ID, shared.RoomInfo]](),
+		reconnectPeers:       common.NewSafeMap[peer.ID, *PeerInfo](),
+		staticPeers:          common.NewSafeMap[peer.ID, *staticPeer](),
+		meshViewers:          common.NewSafeMap[peer.ID, map[string]int](),
+		successors:           common.NewSafeMap[peer.ID, *SuccessorRecord](),
+		linkProbes:           common.NewSafeMap[peer.ID, LinkProbe](),
+		roomEpochs:           common.NewSafeMap[string, roomEpoch](),
+		sharedRooms:          common.NewSafeMap[string, shared.RoomInfo](),
+		directoryCache:       common.NewSafeMap[string, directoryLookup](),
+		thumbnailDecoder:     thumbnailDecoder,
+		thumbnails:           common.NewSafeMap[string, Thumbnail](),
+		overloadedPeers:      common.NewSafeMap[peer.ID, time.Time](),
+		Events:               NewEventBus(),
+		Jobs:                 common.NewSafeMap[ulid.ULID, *Job](),
+		Experiments:          common.NewSafeMap[string, *Experiment](),
+		Usage:                NewUsage(),
+		Bandwidth:            bandwidth,
+		Moderation:           NewModeration(),
+		viewerAuth:           viewerAuth,
+		geoIP:                geoIP,
+		pushKeys:             pushKeys,
+		authFailures:         newAuthLimiter(),
+		streamLimiter:        newSignalingLimiter(),
+		messageLimiter:       newSignalingLimiter(),
+	}
+
+	r.PeerInfo.Addrs = r.advertisedAddrs()
+	r.PeerInfo.ProtocolVersion = common.ProtocolVersion
+	r.PeerInfo.Capabilities = common.Capabilities()
+	if secret := common.GetFlags().MeshSecret; len(secret) > 0 {
+		r.PeerInfo.MeshProof = common.SignMeshPeer(secret, p2pHost.ID().String())
+	}
+	r.PeerInfo.Region = common.GetFlags().Region
+	r.PeerInfo.Zone = common.GetFlags().Zone
+	if geoIP != nil {
+		if r.PeerInfo.Location = r.locateSelf(); r.PeerInfo.Location != nil {
+			slog.Info("Relay located with GeoIP", "region", r.PeerInfo.Location.Region, "latitude", r.PeerInfo.Location.Latitude, "longitude", r.PeerInfo.Location.Longitude)
+		} else {
+			slog.Warn("GeoIP database doesn't locate any public address of relay, viewers won't be steered")
+		}
 	}
 
 	// Add network notifier after relay is initialized
@@ -160,14 +344,73 @@ func NewRelay(ctx context.Context, port int, identityKey crypto.PrivKey) (*Relay
 
 	// Initialize Protocol Registry
 	r.ProtocolRegistry = NewProtocolRegistry(r)
+	r.setVersionedHandler(protocolLinkProbe, r.handleLinkProbe)
 
 	// Start discovery features
-	if err = startMDNSDiscovery(r); err != nil {
-		slog.Warn("Failed to initialize mDNS discovery, continuing without..", "error", err)
+	if discovery && common.GetFlags().MDNS {
+		if err = startMDNSDiscovery(ctx, r); err != nil {
+			slog.Warn("Failed to initialize mDNS discovery, continuing without..", "err", err)
+		}
+	}
+	if discovery && common.GetFlags().DNSSD {
+		if err = startDNSSDAnnouncement(ctx, r); err != nil {
+			slog.Warn("Failed to announce relay over DNS-SD, continuing without..", "err", err)
+		}
+	}
+
+	// Start WebSocket reverse proxy front if enabled
+	if ports.WSProxied {
+		if err = r.startWSProxyFront(ctx, ports.WS); err != nil {
+			slog.Warn("Failed to start WebSocket proxy front, continuing without..", "err", err)
+		}
+	}
+
+	// Start admin API if enabled
+	if common.GetFlags().AdminPort > 0 {
+		if err = r.startAdminAPI(ctx); err != nil {
+			slog.Warn("Failed to start admin API, continuing without..", "err", err)
+		}
+	}
+
+	// Start gRPC control service if enabled
+	if common.GetFlags().GRPCPort > 0 {
+		if err = r.startControlService(ctx); err != nil {
+			slog.Warn("Failed to start gRPC control service, continuing without..", "err", err)
+		}
+	}
+
+	// Serve room thumbnails if enabled
+	if common.GetFlags().ThumbnailInterval > 0 {
+		if common.GetFlags().ThumbnailPort > 0 {
+			r.startThumbnailServer(ctx)
+		}
+		go r.thumbnailSweeper(ctx)
 	}
 
 	// Start background tasks
 	go r.periodicMetricsPublisher(ctx)
+	go r.latencyMonitor(ctx)
+	go r.reconnectSupervisor(ctx)
+	go r.periodicStatsSampler(ctx)
+	go r.experimentSupervisor(ctx)
+	go r.periodicUsageSnapshot(ctx)
+	go r.roomGarbageCollector(ctx)
+	go r.pullReaper(ctx)
+	go r.roomClaimSweeper(ctx)
+	go r.viewerCountBroadcaster(ctx)
+	go r.audioLevelBroadcaster(ctx)
+	go r.streamClockBroadcaster(ctx)
+	go r.linkProber(ctx)
+	go r.overloadMonitor(ctx)
+	go r.webTransportCertWatcher(ctx)
+	go r.dnsBootstrapper(ctx)
+	r.loadStaticPeers()
+	go r.staticPeerSupervisor(ctx)
+	go r.reachabilityWatcher(ctx)
+	go r.addrsWatcher(ctx)
+	if r.pushKeys != nil {
+		go r.pushKeys.run(ctx)
+	}
 
 	printConnectInstructions(p2pHost)
 
@@ -175,82 +418,138 @@ func NewRelay(ctx context.Context, port int, identityKey crypto.PrivKey) (*Relay
 }
 
 func InitRelay(ctx context.Context, ctxCancel context.CancelFunc) (*Relay, error) {
-	var err error
 	persistentDir := common.GetFlags().PersistDir
 
 	// Load or generate identity key
-	var identityKey crypto.PrivKey
-	var privKey ed25519.PrivateKey
-	// First check if we need to generate identity
-	hasIdentity := len(persistentDir) > 0 && common.GetFlags().RegenIdentity == false
-	if hasIdentity {
-		_, err = os.Stat(persistentDir + "/identity.key")
-		if err != nil && !os.IsNotExist(err) {
-			return nil, fmt.Errorf("failed to check identity key file: %w", err)
-		} else if os.IsNotExist(err) {
-			hasIdentity = false
-		}
-	}
-	if !hasIdentity {
-		// Make sure the persistent directory exists
-		if err = os.MkdirAll(persistentDir, 0700); err != nil {
-			return nil, fmt.Errorf("failed to create persistent data directory: %w", err)
-		}
-		// Generate
-		slog.Info("Generating new identity for relay")
-		privKey, err = common.GenerateED25519Key()
-		if err != nil {
-			return nil, fmt.Errorf("failed to generate new identity: %w", err)
-		}
-		// Save the key
-		if err = common.SaveED25519Key(privKey, persistentDir+"/identity.key"); err != nil {
-			return nil, fmt.Errorf("failed to save identity key: %w", err)
-		}
-		slog.Info("New identity generated and saved", "path", persistentDir+"/identity.key")
-	} else {
-		slog.Info("Loading existing identity for relay", "path", persistentDir+"/identity.key")
-		// Load the key
-		privKey, err = common.LoadED25519Key(persistentDir + "/identity.key")
-		if err != nil {
-			return nil, fmt.Errorf("failed to load identity key: %w", err)
-		}
+	privKey, err := loadIdentity(ctx, persistentDir)
+	if err != nil {
+		return nil, err
 	}
 
 	// Convert to libp2p crypto.PrivKey
-	identityKey, err = crypto.UnmarshalEd25519PrivateKey(privKey)
+	identityKey, err := crypto.UnmarshalEd25519PrivateKey(privKey)
 	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal ED25519 private key: %w", err)
 	}
 
-	globalRelay, err = NewRelay(ctx, common.GetFlags().EndpointPort, identityKey)
+	listenPorts, err := ListenPortsFromFlags()
+	if err != nil {
+		return nil, err
+	}
+
+	globalRelay, err = NewRelay(ctx, listenPorts, identityKey)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create relay: %w", err)
 	}
 
+	if common.GetFlags().PortMapping {
+		mapWebRTCPort(ctx)
+	}
 	if err = common.InitWebRTCAPI(); err != nil {
 		return nil, err
 	}
 
+	// Pending identity rotation retires us by stopping the relay
+	globalRelay.stop = ctxCancel
+	globalRelay.resumeIdentityRotation(persistentDir)
+	go globalRelay.identityRotationSupervisor(ctx)
+
 	slog.Info("Relay initialized", "id", globalRelay.ID)
 
-	// Load previous peers on startup
-	defaultFile := common.GetFlags().PersistDir + "/peerstore.json"
-	if err = globalRelay.LoadFromFile(defaultFile); err != nil {
-		slog.Warn("Failed to load previous peer store", "error", err)
-	} else {
-		globalRelay.Peers.Range(func(id peer.ID, pi *PeerInfo) bool {
-			if len(pi.Addrs) <= 0 {
-				slog.Warn("Peer from peer store has no addresses", "peer", id)
-				return true
-			}
+	// Host and WebRTC API are up, tell systemd we're ready and keep its watchdog fed
+	common.SdNotifyStatus(common.SdNotifyReady, "Relay running")
+	go common.RunSdWatchdog(ctx)
 
-			// Connect to first address only
-			if err = globalRelay.ConnectToPeer(context.Background(), pi.Addrs[0]); err != nil {
-				slog.Error("Failed to connect to peer from peer store", "peer", id, "error", err)
+	// Restore peer store and usage totals from previous runs
+	if err = globalRelay.openStore(); err != nil {
+		return nil, fmt.Errorf("failed to open store: %w", err)
+	}
+	if err = globalRelay.Usage.Load(globalRelay.Store); err != nil {
+		slog.Warn("Failed to load previous usage", "err", err)
+	}
+	if err = globalRelay.Bandwidth.Load(globalRelay.Store); err != nil {
+		slog.Warn("Failed to load previous bandwidth", "err", err)
+	}
+
+	// Share sessions, bans and room directory with relays of our cluster, and follow the platform's room directory
+	if err = globalRelay.openSharedState(ctx); err != nil {
+		return nil, fmt.Errorf("failed to open shared state: %w", err)
+	}
+	if err = globalRelay.openRoomDirectory(ctx); err != nil {
+		return nil, fmt.Errorf("failed to open room directory: %w", err)
+	}
+
+	// Restore snapshot, its peers are dialed along with stored ones
+	if snapshotPath := common.GetFlags().RestoreSnapshotPath(); len(snapshotPath) > 0 {
+		if snap, err := LoadSnapshot(snapshotPath); err != nil {
+			slog.Warn("Failed to restore relay snapshot", "path", snapshotPath, "err", err)
+		} else {
+			globalRelay.RestoreSnapshot(snap)
+		}
+	}
+
+	// Dial peers of previous runs
+	if pruned := globalRelay.prunePeers(time.Duration(common.GetFlags().PeerTTL) * time.Hour); pruned > 0 {
+		slog.Info("Pruned stale peers from peer store", "count", pruned)
+	}
+
+	var wg sync.WaitGroup
+	now := time.Now()
+	for id, pi := range globalRelay.Peers.Copy() {
+		if len(pi.Addrs) <= 0 {
+			slog.Warn("Peer from peer store has no addresses", "peer", id)
+			continue
+		}
+		if !pi.canDial(now) {
+			slog.Debug("Skipping peer in dial backoff", "peer", id, "failures", pi.DialFailures, "next_dial", pi.NextDialAt)
+			globalRelay.scheduleReconnect(pi)
+			continue
+		}
+
+		// Dial all addresses of all peers concurrently, a stale address won't hold up the others
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := globalRelay.ConnectToKnownPeer(context.Background(), pi); err != nil {
+				slog.Error("Failed to connect to peer from peer store", "peer", id, "err", err)
+				globalRelay.scheduleReconnect(pi)
 			}
-			return true
-		})
+		}()
 	}
+	wg.Wait()
 
 	return globalRelay, nil
 }
+
+// SetDraining toggles drain mode, in which new viewers and pushes are refused and forwarded rooms not announced
+func (r *Relay) SetDraining(draining bool) {
+	if r.draining.Swap(draining) != draining {
+		slog.Info("Relay drain mode changed", "draining", draining)
+		if draining {
+			common.SdNotifyStatus(common.SdNotifyStopping, "Relay draining")
+		} else {
+			common.SdNotifyStatus(common.SdNotifyReady, "Relay running")
+		}
+	}
+}
+
+// IsDraining returns true if the relay is in drain mode
+func (r *Relay) IsDraining() bool {
+	return r.draining.Load()
+}
+
+// ReloadConfig re-reads config file and environment, applying options changeable without restart
+func (r *Relay) ReloadConfig() ([]string, error) {
+	changed, err := common.ReloadFlags()
+	if err != nil {
+		slog.Error("Failed to reload configuration, keeping current", "err", err)
+		return nil, err
+	}
+	slog.Info("Configuration reloaded", "changed", changed)
+	if len(changed) > 0 {
+		r.Events.Publish(Event{Type: EventConfigReloaded, Attrs: map[string]string{
+			"changed": strings.Join(changed, ","),
+		}})
+	}
+	return changed, nil
+}
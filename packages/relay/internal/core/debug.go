@@ -0,0 +1,44 @@
+package core
+
+import (
+	"expvar"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+)
+
+// newMetricsMux builds handler of the metrics server, with debug endpoints if enabled.
+// Uses own mux as importing pprof and expvar registers them on the default one unconditionally.
+func newMetricsMux(metrics http.Handler, debugEndpoints bool) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/debug/metrics/prometheus", metrics)
+	if !debugEndpoints {
+		return mux
+	}
+
+	slog.Warn("Debug endpoints enabled on metrics server, do not expose it publicly")
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc("/debug/goroutines", handleGoroutineDump)
+	return mux
+}
+
+// handleGoroutineDump writes stack traces of all goroutines as plain text
+func handleGoroutineDump(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	// Grow until the dump fits, a full dump can be large with many participants
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			_, _ = w.Write(buf[:n])
+			return
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
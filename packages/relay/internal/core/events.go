@@ -0,0 +1,90 @@
+package core
+
+import (
+	"relay/internal/common"
+	"sync/atomic"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// --- Relay Events ---
+
+// EventType names a relay state change
+type EventType string
+
+const (
+	EventPeerConnected    EventType = "peer-connected"
+	EventPeerDisconnected EventType = "peer-disconnected"
+	EventPeerReconnecting EventType = "peer-reconnecting"
+	EventPeerGaveUp       EventType = "peer-gave-up"
+	EventPeerSuccessor    EventType = "peer-successor"
+
+	EventRoomCreated    EventType = "room-created"
+	EventRoomClosed     EventType = "room-closed"
+	EventRoomExpired    EventType = "room-expired"
+	EventRoomOnline     EventType = "room-online"
+	EventRoomOffline    EventType = "room-offline"
+	EventRoomFailover   EventType = "room-failover"
+	EventViewerJoined   EventType = "viewer-joined"
+	EventViewerLeft     EventType = "viewer-left"
+	EventBitrateChanged EventType = "bitrate-changed"
+	EventRoomSuperseded EventType = "room-superseded"
+	EventViewerBanned   EventType = "viewer-banned"
+	EventBanLifted      EventType = "ban-lifted"
+
+	EventConfigReloaded   EventType = "config-reloaded"
+	EventIdentityRotating EventType = "identity-rotating"
+	EventRelayOverloaded  EventType = "relay-overloaded"
+	EventRelayRecovered   EventType = "relay-recovered"
+
+	EventReachabilityChanged EventType = "reachability-changed"
+)
+
+// Event is a relay state change, passed to all subscribers
+type Event struct {
+	Type   EventType         `json:"type"`
+	PeerID peer.ID           `json:"peer_id,omitempty"`
+	Room   string            `json:"room,omitempty"`
+	Time   time.Time         `json:"time"`
+	Attrs  map[string]string `json:"attrs,omitempty"`
+}
+
+// EventBus fans out relay events to local subscribers, slow subscribers miss events instead of blocking
+type EventBus struct {
+	subs   *common.SafeMap[uint64, chan Event]
+	nextID atomic.Uint64
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{
+		subs: common.NewSafeMap[uint64, chan Event](),
+	}
+}
+
+// Subscribe returns a channel of events and a function to unsubscribe
+func (eb *EventBus) Subscribe(buffer int) (<-chan Event, func()) {
+	id := eb.nextID.Add(1)
+	ch := make(chan Event, buffer)
+	eb.subs.Set(id, ch)
+	return ch, func() {
+		if eb.subs.Has(id) {
+			eb.subs.Delete(id)
+			close(ch)
+		}
+	}
+}
+
+// Publish sends event to all subscribers without blocking
+func (eb *EventBus) Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	eb.subs.Range(func(_ uint64, ch chan Event) bool {
+		select {
+		case ch <- event:
+		default:
+		}
+		return true
+	})
+}
@@ -0,0 +1,223 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"log/slog"
+	"relay/internal/shared"
+	"sync"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+// --- Encoder Experiments ---
+
+// Experiment mirrors a sample of consenting viewers of a room into a shadow room,
+// which the server feeds with a second push using different encoder settings
+type Experiment struct {
+	Room          string
+	Shadow        string
+	SamplePercent int
+	Created       time.Time
+
+	mtx     sync.Mutex
+	decided map[ulid.ULID]bool // Participant -> sampled into shadow, each viewer is decided once
+}
+
+// ExperimentGroup holds QoE metrics of one side of an experiment
+type ExperimentGroup struct {
+	Room          string                    `json:"room"`
+	Online        bool                      `json:"online"`
+	Viewers       int                       `json:"viewers"`
+	QueueDelay    time.Duration             `json:"queue_delay"`    // Mean viewer packet queueing delay
+	DroppedFrames uint64                    `json:"dropped_frames"` // Late video frames dropped across current viewers
+	AudioStats    shared.TrackStatsSnapshot `json:"audio_stats"`
+	VideoStats    shared.TrackStatsSnapshot `json:"video_stats"`
+}
+
+// ExperimentReport compares control and shadow groups of an experiment
+type ExperimentReport struct {
+	Room          string          `json:"room"`
+	Shadow        string          `json:"shadow"`
+	SamplePercent int             `json:"sample_percent"`
+	Created       time.Time       `json:"created"`
+	Control       ExperimentGroup `json:"control"`
+	Treatment     ExperimentGroup `json:"treatment"`
+}
+
+// StartExperiment creates a shadow room for an owned room and starts sampling consenting viewers into it
+func (r *Relay) StartExperiment(roomName, shadowName string, samplePercent int) (*Experiment, error) {
+	room := r.GetRoomByName(roomName)
+	if room == nil {
+		return nil, errors.New("room not found")
+	}
+	if room.OwnerID != r.ID {
+		return nil, errors.New("experiments need the room pushed to this relay")
+	}
+	if samplePercent <= 0 || samplePercent > 100 {
+		return nil, errors.New("sample percent must be within 1-100")
+	}
+	if len(shadowName) <= 0 {
+		shadowName = roomName + "-shadow"
+	}
+	if r.Experiments.Has(roomName) {
+		return nil, errors.New("room already has an experiment")
+	}
+	if r.GetRoomByName(shadowName) != nil {
+		return nil, errors.New("shadow room already exists")
+	}
+
+	shadow := r.CreateRoom(shadowName)
+	shadow.SetSettings(room.GetSettings())
+
+	exp := &Experiment{
+		Room:          roomName,
+		Shadow:        shadowName,
+		SamplePercent: samplePercent,
+		Created:       time.Now(),
+		decided:       make(map[ulid.ULID]bool),
+	}
+	r.Experiments.Set(roomName, exp)
+	slog.Info("Started encoder experiment, awaiting shadow push", "room", roomName, "shadow", shadowName, "sample_percent", samplePercent)
+	return exp, nil
+}
+
+// StopExperiment returns sampled viewers to the original room and closes the shadow room
+func (r *Relay) StopExperiment(roomName string) bool {
+	exp, ok := r.Experiments.Get(roomName)
+	if !ok {
+		return false
+	}
+	r.Experiments.Delete(roomName)
+
+	room := r.GetRoomByName(exp.Room)
+	shadow := r.GetRoomByName(exp.Shadow)
+	if shadow != nil {
+		if room != nil && r.CanMoveParticipants(shadow, room) == nil {
+			for _, participant := range shadow.GetParticipants() {
+				r.MoveParticipant(shadow, room, participant.ID)
+			}
+		}
+		r.CloseRoom(shadow)
+	}
+	slog.Info("Stopped encoder experiment", "room", exp.Room, "shadow", exp.Shadow)
+	return true
+}
+
+// experimentSupervisor keeps experiment groups sampled until context is done
+func (r *Relay) experimentSupervisor(ctx context.Context) {
+	ticker := time.NewTicker(experimentCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Stopping experiment supervisor")
+			return
+		case <-ticker.C:
+			for _, exp := range r.Experiments.Copy() {
+				r.updateExperiment(exp)
+			}
+		}
+	}
+}
+
+// updateExperiment moves newly sampled viewers into shadow room, or all back while shadow is offline
+func (r *Relay) updateExperiment(exp *Experiment) {
+	room := r.GetRoomByName(exp.Room)
+	if room == nil {
+		slog.Info("Experiment room is gone, stopping experiment", "room", exp.Room)
+		r.StopExperiment(exp.Room)
+		return
+	}
+	shadow := r.GetRoomByName(exp.Shadow)
+	if shadow == nil {
+		r.Experiments.Delete(exp.Room)
+		return
+	}
+
+	exp.mtx.Lock()
+	defer exp.mtx.Unlock()
+
+	// Forget viewers that left both rooms
+	present := make(map[ulid.ULID]struct{})
+	for _, participant := range append(room.GetParticipants(), shadow.GetParticipants()...) {
+		present[participant.ID] = struct{}{}
+	}
+	for id := range exp.decided {
+		if _, ok := present[id]; !ok {
+			delete(exp.decided, id)
+		}
+	}
+
+	// Shadow push dropped, fall back so sampled viewers keep watching
+	if !shadow.IsOnline() {
+		if room.IsOnline() && r.CanMoveParticipants(shadow, room) == nil {
+			for _, participant := range shadow.GetParticipants() {
+				r.MoveParticipant(shadow, room, participant.ID)
+			}
+		}
+		return
+	}
+	if err := r.CanMoveParticipants(room, shadow); err != nil {
+		slog.Debug("Cannot sample viewers into shadow room", "room", exp.Room, "shadow", exp.Shadow, "err", err)
+		return
+	}
+
+	for _, participant := range room.GetParticipants() {
+		if !participant.ExperimentOptIn {
+			continue
+		}
+		sampled, ok := exp.decided[participant.ID]
+		if !ok {
+			sampled = sampleParticipant(participant.ID, exp.SamplePercent)
+			exp.decided[participant.ID] = sampled
+		}
+		if sampled {
+			r.MoveParticipant(room, shadow, participant.ID)
+		}
+	}
+}
+
+// sampleParticipant deterministically picks a participant by ID for given percentage
+func sampleParticipant(id ulid.ULID, percent int) bool {
+	h := fnv.New32a()
+	_, _ = h.Write(id[:])
+	return int(h.Sum32()%100) < percent
+}
+
+// experimentReport returns QoE comparison of experiment groups
+func (r *Relay) experimentReport(exp *Experiment) ExperimentReport {
+	group := func(name string) ExperimentGroup {
+		g := ExperimentGroup{Room: name}
+		room := r.GetRoomByName(name)
+		if room == nil {
+			return g
+		}
+		g.Online = room.IsOnline()
+		g.AudioStats = room.AudioStats.Snapshot()
+		g.VideoStats = room.VideoStats.Snapshot()
+
+		var totalDelay time.Duration
+		for _, participant := range room.GetParticipants() {
+			g.Viewers++
+			totalDelay += participant.QueueDelay()
+			g.DroppedFrames += participant.DroppedFrames()
+		}
+		if g.Viewers > 0 {
+			g.QueueDelay = totalDelay / time.Duration(g.Viewers)
+		}
+		return g
+	}
+
+	return ExperimentReport{
+		Room:          exp.Room,
+		Shadow:        exp.Shadow,
+		SamplePercent: exp.SamplePercent,
+		Created:       exp.Created,
+		Control:       group(exp.Room),
+		Treatment:     group(exp.Shadow),
+	}
+}
@@ -0,0 +1,146 @@
+package core
+
+import (
+	"log/slog"
+	"math"
+	"net"
+	"net/netip"
+	"relay/internal/common"
+	"strings"
+
+	gen "relay/internal/proto"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	manet "github.com/multiformats/go-multiaddr/net"
+)
+
+// --- Geo-aware Viewer Steering ---
+//
+// With a GeoIP database relays announce where they are, and viewers requesting a room served by another relay
+// much closer to them are told to go there with "better-relay" instead of having the room pulled here.
+
+// geoSteerMarginKm is how much closer to the viewer another relay must be for the viewer to be sent there
+const geoSteerMarginKm = 500
+
+// locate returns location of an IP in the GeoIP database, false if disabled or unknown
+func (r *Relay) locate(ip net.IP) (common.GeoLocation, bool) {
+	addr, ok := netip.AddrFromSlice(ip)
+	if r.geoIP == nil || !ok {
+		return common.GeoLocation{}, false
+	}
+	return r.geoIP.Lookup(addr)
+}
+
+// locateSelf returns location of the first of our public IPs the GeoIP database knows, NAT 1 to 1 IPs first
+func (r *Relay) locateSelf() *common.GeoLocation {
+	var ips []net.IP
+	for _, ip := range strings.Split(common.GetFlags().NAT11IP, ",") {
+		if parsed := net.ParseIP(strings.TrimSpace(ip)); parsed != nil {
+			ips = append(ips, parsed)
+		}
+	}
+	for _, addr := range r.Host.Addrs() {
+		if !manet.IsPublicAddr(addr) {
+			continue
+		}
+		if ip, err := manet.ToIP(addr); err == nil {
+			ips = append(ips, ip)
+		}
+	}
+	for _, ip := range ips {
+		if location, ok := r.locate(ip); ok {
+			return &location
+		}
+	}
+	return nil
+}
+
+// peerBrowserAddrs returns full public addresses of a relay browsers can dial, as announced by the relay
+func peerBrowserAddrs(pi *PeerInfo) []string {
+	addrs, err := peer.AddrInfoToP2pAddrs(&peer.AddrInfo{ID: pi.ID, Addrs: pi.Addrs})
+	if err != nil {
+		return nil
+	}
+	var browser []string
+	for _, addr := range addrs {
+		if manet.IsPublicAddr(addr) && (isWebTransportAddr(addr) || isSecureWebSocketAddr(addr)) {
+			browser = append(browser, addr.String())
+		}
+	}
+	return browser
+}
+
+// betterRelay returns a relay serving a room of another relay closer to the viewer on conn by geoSteerMarginKm,
+// nil if we are about as close or locations aren't known. Under prefer-same-zone policy relays labelled with the
+// viewer's GeoIP region come first, viewers in our region stay.
+func (r *Relay) betterRelay(roomName string, conn network.Conn) *gen.ProtoBetterRelay {
+	if r.geoIP == nil {
+		return nil
+	}
+	if room := r.GetRoomByName(roomName); room != nil && room.OwnerID == r.ID {
+		return nil
+	}
+	viewer, ok := r.locate(r.RemoteIP(conn))
+	if !ok {
+		return nil
+	}
+	byRegion := common.GetFlags().SteeringPolicy == common.SteeringPreferSameZone && len(viewer.Region) > 0
+	if byRegion && r.PeerInfo.Region == viewer.Region {
+		return nil
+	}
+	routes, ok := r.Routes.Get(roomName)
+	if !ok {
+		return nil
+	}
+
+	var best *gen.ProtoBetterRelay
+	bestInRegion := false
+	bestDistance := math.Inf(1)
+	if r.PeerInfo.Location != nil {
+		bestDistance = r.PeerInfo.Location.DistanceKm(viewer) - geoSteerMarginKm
+	}
+	for relayID, info := range routes.Copy() {
+		if relayID == r.ID || !info.Online || !r.hasConnectedPeer(relayID) || r.isPeerOverloaded(relayID) {
+			continue
+		}
+		pi, ok := r.Peers.Get(relayID)
+		if !ok {
+			continue
+		}
+		inRegion := byRegion && pi.Region == viewer.Region
+		distance := math.Inf(1)
+		if pi.Location != nil {
+			distance = pi.Location.DistanceKm(viewer)
+		}
+		// Relays of the viewer's region win over others whatever the distance, others must be closer than us
+		switch {
+		case inRegion != bestInRegion:
+			if !inRegion {
+				continue
+			}
+		case !inRegion && r.PeerInfo.Location == nil:
+			continue
+		case distance >= bestDistance:
+			continue
+		}
+		if addrs := peerBrowserAddrs(pi); len(addrs) > 0 {
+			best = &gen.ProtoBetterRelay{RoomName: roomName, PeerId: relayID.String(), Addrs: addrs}
+			bestInRegion = inRegion
+			bestDistance = distance
+		}
+	}
+	return best
+}
+
+// sendBetterRelay tells a viewer to request the room from a relay closer to it
+func sendBetterRelay(safeBRW *common.SafeBufioRW, better *gen.ProtoBetterRelay) {
+	betterMsg, err := common.CreateMessage(better, "better-relay", nil)
+	if err != nil {
+		slog.Error("Failed to create proto message", "err", err)
+		return
+	}
+	if err = safeBRW.SendProto(betterMsg); err != nil {
+		slog.Error("Failed to send better relay", "room", better.RoomName, "err", err)
+	}
+}
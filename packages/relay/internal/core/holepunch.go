@@ -0,0 +1,115 @@
+package core
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/p2p/protocol/holepunch"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// --- Hole Punching ---
+//
+// Outcomes of DCUtR hole punches are counted per peer. A peer direct connectivity keeps failing with is pinned to
+// its circuit relay connection for a while, protected from trimming, rather than punching again on every redial.
+
+const holePunchPinTag = "hole-punch-fallback"
+
+var holePunchCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "nestri_relay_holepunch_total",
+	Help: "DCUtR hole punches and direct dials to peers connected over circuit relay, by outcome",
+}, []string{"kind", "outcome"})
+
+// HolePunchStats are outcomes of direct connection attempts to a peer we are connected to over circuit relay
+type HolePunchStats struct {
+	Attempts    int       `json:"attempts"`
+	Successes   int       `json:"successes"`
+	Failures    int       `json:"failures"` // Consecutive failures since the last success
+	LastAt      time.Time `json:"last_at"`
+	PinnedUntil time.Time `json:"pinned_until,omitzero"` // Circuit relay connection is kept until then
+}
+
+// holePunchTracer records hole punch outcomes on the relay of its host, once it's set up
+type holePunchTracer struct {
+	relay *atomic.Pointer[Relay]
+}
+
+func (t holePunchTracer) Trace(evt *holepunch.Event) {
+	r := t.relay.Load()
+	if r == nil {
+		return
+	}
+	switch e := evt.Evt.(type) {
+	case *holepunch.DirectDialEvt:
+		r.recordHolePunch(evt.Remote, "direct-dial", e.Success)
+	case *holepunch.EndHolePunchEvt:
+		r.recordHolePunch(evt.Remote, "hole-punch", e.Success)
+	}
+}
+
+// holePunches keeps hole punch outcomes per peer
+type holePunches struct {
+	mtx   sync.Mutex
+	peers map[peer.ID]HolePunchStats
+}
+
+// get returns hole punch outcomes of a peer, false if it was never connected over circuit relay
+func (h *holePunches) get(peerID peer.ID) (HolePunchStats, bool) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	stats, ok := h.peers[peerID]
+	return stats, ok
+}
+
+// recordHolePunch counts a direct connection attempt, pinning the circuit relay connection after repeated failures
+func (r *Relay) recordHolePunch(peerID peer.ID, kind string, success bool) {
+	outcome := "failure"
+	if success {
+		outcome = "success"
+	}
+	holePunchCounter.WithLabelValues(kind, outcome).Inc()
+
+	r.holePunches.mtx.Lock()
+	defer r.holePunches.mtx.Unlock()
+	if r.holePunches.peers == nil {
+		r.holePunches.peers = make(map[peer.ID]HolePunchStats)
+	}
+	stats := r.holePunches.peers[peerID]
+	now := time.Now()
+	stats.Attempts++
+	stats.LastAt = now
+	if success {
+		stats.Successes++
+		stats.Failures = 0
+		if !stats.PinnedUntil.IsZero() {
+			r.Host.ConnManager().Unprotect(peerID, holePunchPinTag)
+			stats.PinnedUntil = time.Time{}
+			slog.Info("Direct connection to peer established, unpinning circuit relay connection", "peer", peerID)
+		}
+	} else {
+		stats.Failures++
+		if stats.Failures >= holePunchMaxFailures && stats.PinnedUntil.Before(now) {
+			r.Host.ConnManager().Protect(peerID, holePunchPinTag)
+			stats.PinnedUntil = now.Add(holePunchPinDuration)
+			time.AfterFunc(holePunchPinDuration, func() { r.unpinHolePunch(peerID) })
+			slog.Warn("Direct connection to peer keeps failing, pinning circuit relay connection", "peer", peerID, "failures", stats.Failures, "until", stats.PinnedUntil)
+		}
+	}
+	r.holePunches.peers[peerID] = stats
+}
+
+// unpinHolePunch releases circuit relay connection of a peer once its pin expired, so direct connections are tried again
+func (r *Relay) unpinHolePunch(peerID peer.ID) {
+	r.holePunches.mtx.Lock()
+	defer r.holePunches.mtx.Unlock()
+	stats, ok := r.holePunches.peers[peerID]
+	if !ok || stats.PinnedUntil.IsZero() || time.Now().Before(stats.PinnedUntil) {
+		return
+	}
+	r.Host.ConnManager().Unprotect(peerID, holePunchPinTag)
+	stats.PinnedUntil = time.Time{}
+	r.holePunches.peers[peerID] = stats
+}
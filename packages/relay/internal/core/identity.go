@@ -0,0 +1,126 @@
+package core
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"relay/internal/common"
+	"strings"
+	"time"
+)
+
+// --- Relay Identity ---
+
+// identityKeyPath returns path of relay identity key file
+func identityKeyPath(persistentDir string) string {
+	return filepath.Join(persistentDir, "identity.key")
+}
+
+// loadIdentity loads relay identity key from persistent directory, generating and saving a new one if missing.
+// With a passphrase configured the key is kept encrypted, existing unencrypted keys are encrypted in place.
+func loadIdentity(ctx context.Context, persistentDir string) (ed25519.PrivateKey, error) {
+	keyPath := identityKeyPath(persistentDir)
+	passphrase, err := identityPassphrase(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Rotation may have come due while we were down
+	if len(persistentDir) > 0 {
+		if _, err = retirePendingIdentity(persistentDir, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+
+	// First check if we need to generate identity
+	hasIdentity := len(persistentDir) > 0 && common.GetFlags().RegenIdentity == false
+	if hasIdentity {
+		_, err = os.Stat(keyPath)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to check identity key file: %w", err)
+		} else if os.IsNotExist(err) {
+			hasIdentity = false
+		}
+	}
+	if !hasIdentity {
+		// Make sure the persistent directory exists
+		if err = os.MkdirAll(persistentDir, 0700); err != nil {
+			return nil, fmt.Errorf("failed to create persistent data directory: %w", err)
+		}
+		// Generate
+		slog.Info("Generating new identity for relay")
+		privKey, err := common.GenerateED25519Key()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate new identity: %w", err)
+		}
+		// Save the key
+		if err = saveIdentity(privKey, keyPath, passphrase); err != nil {
+			return nil, fmt.Errorf("failed to save identity key: %w", err)
+		}
+		slog.Info("New identity generated and saved", "path", keyPath, "encrypted", len(passphrase) > 0)
+		return privKey, nil
+	}
+
+	slog.Info("Loading existing identity for relay", "path", keyPath)
+	encrypted, err := common.IsEncryptedKeyFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load identity key: %w", err)
+	}
+	if encrypted {
+		if len(passphrase) <= 0 {
+			return nil, errors.New("identity key is encrypted, set IDENTITY_PASSPHRASE or IDENTITY_PASSPHRASE_COMMAND to load it")
+		}
+		privKey, err := common.LoadEncryptedED25519Key(keyPath, passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt identity key: %w", err)
+		}
+		return privKey, nil
+	}
+
+	privKey, err := common.LoadED25519Key(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load identity key: %w", err)
+	}
+	if len(passphrase) > 0 {
+		if err = common.SaveEncryptedED25519Key(privKey, keyPath, passphrase); err != nil {
+			return nil, fmt.Errorf("failed to encrypt identity key: %w", err)
+		}
+		slog.Info("Encrypted existing identity key with passphrase", "path", keyPath)
+	}
+	return privKey, nil
+}
+
+// saveIdentity saves relay identity key, encrypted if a passphrase is given
+func saveIdentity(privKey ed25519.PrivateKey, keyPath string, passphrase string) error {
+	if len(passphrase) > 0 {
+		return common.SaveEncryptedED25519Key(privKey, keyPath, passphrase)
+	}
+	return common.SaveED25519Key(privKey, keyPath)
+}
+
+// identityPassphrase returns passphrase identity key is encrypted with, running passphrase command if set,
+// empty if identity key is kept unencrypted
+func identityPassphrase(ctx context.Context) (string, error) {
+	flags := common.GetFlags()
+	if len(flags.IdentityPassphrase) > 0 || len(flags.IdentityPassphraseCommand) <= 0 {
+		return flags.IdentityPassphrase, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, identityPassphraseTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", flags.IdentityPassphraseCommand)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("identity passphrase command failed: %w", err)
+	}
+	passphrase := strings.TrimRight(string(out), "\r\n")
+	if len(passphrase) <= 0 {
+		return "", errors.New("identity passphrase command printed an empty passphrase")
+	}
+	return passphrase, nil
+}
@@ -0,0 +1,179 @@
+package core
+
+import (
+	"fmt"
+	"log/slog"
+	"relay/internal/common"
+	"relay/internal/shared"
+	"strings"
+	"sync"
+	"time"
+
+	gen "relay/internal/proto"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/proto"
+)
+
+// --- Input Latency ---
+//
+// The relay serving a viewer stamps some of its input with a latency tracker on the way to the room, nestri-server
+// sends trackers of input it handled back as "input-latency" on the room DataChannel. Relays in between pass echoes
+// on towards viewers, the relay which stamped the input measures its round trip on its own clock, keeps a histogram
+// of it per participant and tells the viewer.
+
+const (
+	inputLatencyStageIn  = "relay-in"  // Stamped when viewer input is forwarded upstream
+	inputLatencyStageOut = "relay-out" // Stamped when its echo came back
+	inputLatencyPrefix   = "input/"    // Sequence IDs of stamped input are input/<participant ID>/<number>
+)
+
+var inputLatencyHistogram = prometheus.NewHistogram(prometheus.HistogramOpts{
+	Name:    "nestri_relay_input_latency_seconds",
+	Help:    "Round trip of viewer input from its relay to the server and back",
+	Buckets: []float64{.002, .005, .01, .02, .05, .1, .2, .5, 1},
+})
+
+// inputProbe picks input of a participant to stamp for measuring its round trip
+type inputProbe struct {
+	participant *shared.Participant
+	mtx         sync.Mutex
+	next        time.Time // Input before this isn't stamped unless the viewer asked for it
+	seq         uint64
+}
+
+// stamp attaches a latency tracker to an input message when a measurement is due, or replaces the tracker the viewer
+// sent with one of ours keeping its timestamps. Echoes only carry trackers the relay stamped so viewers can't forge them.
+func (p *inputProbe) stamp(msg *gen.ProtoMessage, now time.Time) {
+	base := msg.GetMessageBase()
+	if base == nil {
+		return
+	}
+	p.mtx.Lock()
+	if base.Latency == nil && now.Before(p.next) {
+		p.mtx.Unlock()
+		return
+	}
+	p.next = now.Add(inputLatencyProbeInterval)
+	p.seq++
+	seq := p.seq
+	p.mtx.Unlock()
+
+	tracker := common.NewLatencyTracker("")
+	if base.Latency != nil {
+		tracker = common.LatencyTrackerFromProto(base.Latency)
+	}
+	tracker.SequenceID = fmt.Sprintf("%s%s/%d", inputLatencyPrefix, p.participant.ID, seq)
+	tracker.AddTimestamp(inputLatencyStageIn)
+	base.Latency = tracker.ToProto()
+}
+
+// inputLatencyParticipant returns participant whose input a sequence ID was stamped on
+func inputLatencyParticipant(sequenceID string) (ulid.ULID, bool) {
+	rest, ok := strings.CutPrefix(sequenceID, inputLatencyPrefix)
+	if !ok {
+		return ulid.ULID{}, false
+	}
+	idStr, _, ok := strings.Cut(rest, "/")
+	if !ok {
+		return ulid.ULID{}, false
+	}
+	id, err := ulid.Parse(idStr)
+	return id, err == nil
+}
+
+// receiveInputLatency handles an input echo received from upstream of a room, measuring it if its input was stamped
+// for a participant here and passing it on to relays the room is served to otherwise
+func (r *Relay) receiveInputLatency(room *shared.Room, data []byte) {
+	var msgWrapper gen.ProtoMessage
+	if err := proto.Unmarshal(data, &msgWrapper); err != nil {
+		slog.Debug("Failed to unmarshal input latency", "room", room.Name, "err", err)
+		return
+	}
+	tracker := msgWrapper.GetMessageBase().GetLatency()
+	id, ok := inputLatencyParticipant(tracker.GetSequenceId())
+	if !ok {
+		slog.Debug("Ignoring input latency without stamped sequence ID", "room", room.Name)
+		return
+	}
+
+	// Viewers may have switched to a quality variant of the room since their input was stamped
+	family := r.roomFamily(shared.BaseRoomName(room.Name))
+	for _, familyRoom := range family {
+		for _, participant := range familyRoom.GetParticipants() {
+			if participant.ID == id && !r.isMeshRelay(participant.PeerID) {
+				r.deliverInputLatency(familyRoom, participant, tracker)
+				return
+			}
+		}
+	}
+	for _, familyRoom := range family {
+		for _, participant := range familyRoom.GetParticipants() {
+			dc := participant.DataChannel()
+			if dc == nil || !r.isMeshRelay(participant.PeerID) {
+				continue
+			}
+			if err := dc.SendBinary(data); err != nil {
+				slog.Debug("Failed to pass input latency to mesh relay", "room", familyRoom.Name, "peer", participant.PeerID, "err", err)
+			}
+		}
+	}
+}
+
+// deliverInputLatency measures round trip of an echoed input of participant and sends the viewer its input latency,
+// with the echoed tracker so it can add the time its input took to reach the relay
+func (r *Relay) deliverInputLatency(room *shared.Room, participant *shared.Participant, protoTracker *gen.ProtoLatencyTracker) {
+	tracker := common.LatencyTrackerFromProto(protoTracker)
+	tracker.AddTimestamp(inputLatencyStageOut)
+	if !hasStage(tracker, inputLatencyStageIn) {
+		slog.Debug("Ignoring input latency without relay stamp", "room", room.Name, "participant", participant.ID)
+		return
+	}
+	roundTrip, err := tracker.StageLatency(inputLatencyStageIn, inputLatencyStageOut)
+	if err != nil || roundTrip < 0 {
+		return
+	}
+	participant.ObserveInputLatency(roundTrip)
+	inputLatencyHistogram.Observe(roundTrip.Seconds())
+
+	dc := participant.DataChannel()
+	if dc == nil {
+		return
+	}
+	msg, err := common.CreateMessage(inputLatencyMessage(room.Name, participant.InputLatency()), "input-latency",
+		&common.CreateMessageOptions{Latency: tracker.ToProto()})
+	if err != nil {
+		slog.Error("Failed to create proto message", "err", err)
+		return
+	}
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		slog.Error("Failed to marshal input latency", "err", err)
+		return
+	}
+	if err = dc.SendBinary(data); err != nil {
+		slog.Debug("Failed to send input latency to participant", "room", room.Name, "participant", participant.ID, "err", err)
+	}
+}
+
+// inputLatencyMessage builds an input-latency payload of input round trips measured for a participant
+func inputLatencyMessage(roomName string, latency shared.LatencySnapshot) *gen.ProtoInputLatency {
+	return &gen.ProtoInputLatency{
+		RoomName: roomName,
+		LastMs:   uint32(latency.Last.Milliseconds()),
+		P50Ms:    uint32(latency.P50.Milliseconds()),
+		P95Ms:    uint32(latency.P95.Milliseconds()),
+		P99Ms:    uint32(latency.P99.Milliseconds()),
+		Samples:  latency.Samples,
+	}
+}
+
+func hasStage(tracker *common.LatencyTracker, stage string) bool {
+	for _, ts := range tracker.Timestamps {
+		if ts.Stage == stage {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package core
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+// --- Admin Jobs ---
+
+type JobState string
+
+const (
+	JobRunning    JobState = "running"
+	JobCompleted  JobState = "completed"
+	JobFailed     JobState = "failed"
+	JobRolledBack JobState = "rolled-back"
+)
+
+// Job tracks progress of a bulk admin operation
+type Job struct {
+	mtx      sync.Mutex
+	ID       ulid.ULID
+	Type     string
+	Room     string
+	State    JobState
+	Total    int
+	Done     int
+	Failed   int
+	Error    string
+	Created  time.Time
+	Finished time.Time
+}
+
+// JobSnapshot is a point in time copy of Job progress
+type JobSnapshot struct {
+	ID       ulid.ULID `json:"id"`
+	Type     string    `json:"type"`
+	Room     string    `json:"room"`
+	State    JobState  `json:"state"`
+	Total    int       `json:"total"`
+	Done     int       `json:"done"`
+	Failed   int       `json:"failed"`
+	Error    string    `json:"error,omitempty"`
+	Created  time.Time `json:"created"`
+	Finished time.Time `json:"finished,omitzero"`
+}
+
+// Snapshot returns current progress of Job
+func (j *Job) Snapshot() JobSnapshot {
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+	return JobSnapshot{
+		ID:       j.ID,
+		Type:     j.Type,
+		Room:     j.Room,
+		State:    j.State,
+		Total:    j.Total,
+		Done:     j.Done,
+		Failed:   j.Failed,
+		Error:    j.Error,
+		Created:  j.Created,
+		Finished: j.Finished,
+	}
+}
+
+// Progress records one processed item
+func (j *Job) Progress(ok bool) {
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+	if ok {
+		j.Done++
+	} else {
+		j.Failed++
+	}
+}
+
+// Finish ends Job with given state and optional error
+func (j *Job) Finish(state JobState, err error) {
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+	j.State = state
+	j.Finished = time.Now()
+	if err != nil {
+		j.Error = err.Error()
+	}
+	slog.Info("Admin job finished", "job", j.ID, "type", j.Type, "room", j.Room, "state", state, "done", j.Done, "failed", j.Failed)
+}
+
+// StartJob registers a new running job and runs it in background
+func (r *Relay) StartJob(jobType, room string, total int, run func(job *Job)) *Job {
+	r.pruneJobs()
+	job := &Job{
+		ID:      ulid.Make(),
+		Type:    jobType,
+		Room:    room,
+		State:   JobRunning,
+		Total:   total,
+		Created: time.Now(),
+	}
+	r.Jobs.Set(job.ID, job)
+	slog.Info("Admin job started", "job", job.ID, "type", jobType, "room", room, "total", total)
+	go run(job)
+	return job
+}
+
+// pruneJobs forgets finished jobs past retention
+func (r *Relay) pruneJobs() {
+	for id, job := range r.Jobs.Copy() {
+		snapshot := job.Snapshot()
+		if snapshot.State != JobRunning && time.Since(snapshot.Finished) > jobRetention {
+			r.Jobs.Delete(id)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package core
+
+import (
+	"context"
+	"relay/internal/common"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// --- Mesh Latencies ---
+
+var peerLatencySummary = prometheus.NewSummaryVec(prometheus.SummaryOpts{
+	Name:       "nestri_relay_peer_latency_seconds",
+	Help:       "Round trip time of pings to mesh relays",
+	Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
+}, []string{"peer"})
+
+// latencyMonitor keeps latencies to connected mesh relays fresh, for routing and the latency matrix
+func (r *Relay) latencyMonitor(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(common.GetFlags().LatencyPing) * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.checkAllPeerLatencies(ctx)
+		}
+	}
+}
+
+// forgetLatency drops latency of a peer which left, it's measured again once the peer is back
+func (r *Relay) forgetLatency(peerID peer.ID) {
+	r.Latencies.Delete(peerID)
+	peerLatencySummary.DeleteLabelValues(peerID.String())
+}
+
+// LatencyMatrix returns latencies between relays of the mesh, from ours and those connected relays announced,
+// keyed by relay measuring and relay measured
+func (r *Relay) LatencyMatrix() map[peer.ID]map[peer.ID]time.Duration {
+	matrix := map[peer.ID]map[peer.ID]time.Duration{r.ID: r.Latencies.Copy()}
+	for peerID, pi := range r.Peers.Copy() {
+		if pi.Latencies == nil || !r.isConnected(peerID) || !r.isMeshRelay(peerID) {
+			continue
+		}
+		matrix[peerID] = pi.Latencies.Copy()
+	}
+	return matrix
+}
@@ -0,0 +1,102 @@
+package core
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// --- Peer Lifecycle ---
+//
+// Every subsystem holding state of a peer registers a cleanup step here, run in order when the peer disconnects.
+// Steps marked gone only run once no connection to the peer is left, as peers may keep another connection open.
+
+// peerCleanupStep releases state one subsystem holds of a departed peer
+type peerCleanupStep struct {
+	name string
+	gone bool // Run only when no connection to the peer is left
+	run  func(r *Relay, peerID peer.ID)
+}
+
+// peerCleanupSteps returns steps run in order, routes go before streams so pulled rooms are pulled again over other
+// routes. Built per call, as steps reach back into onPeerDisconnected and a package variable would cycle.
+func peerCleanupSteps() []peerCleanupStep {
+	return []peerCleanupStep{
+		{name: "reconnect", gone: true, run: (*Relay).reconnectDeparted},
+		{name: "peer store", run: func(r *Relay, peerID peer.ID) { r.Peers.Delete(peerID) }},
+		{name: "routes", run: (*Relay).removeRoomRoutes},
+		{name: "mesh viewers", run: func(r *Relay, peerID peer.ID) { r.meshViewers.Delete(peerID) }},
+		{name: "latency", gone: true, run: (*Relay).forgetLatency},
+		{name: "owned rooms", gone: true, run: (*Relay).forgetOwnedRooms},
+		{name: "streams", gone: true, run: func(r *Relay, peerID peer.ID) { r.StreamProtocol.dropPeer(peerID) }},
+		{name: "pushes", gone: true, run: func(r *Relay, peerID peer.ID) { r.StreamProtocol.dropPushes(peerID) }},
+		{name: "participants", gone: true, run: (*Relay).dropParticipants},
+		{name: "waiting list", gone: true, run: func(r *Relay, peerID peer.ID) { r.StreamProtocol.waiting.RemovePeer(peerID) }},
+	}
+}
+
+// peerLifecycle keeps cleanups of a peer from interleaving, a disconnect during a cleanup runs it again afterwards
+type peerLifecycle struct {
+	mtx     sync.Mutex
+	running map[peer.ID]bool // peer ID -> cleanup requested again while running
+}
+
+// begin claims cleanup of a peer, false if one is running and will run again instead
+func (l *peerLifecycle) begin(peerID peer.ID) bool {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if l.running == nil {
+		l.running = make(map[peer.ID]bool)
+	}
+	if _, ok := l.running[peerID]; ok {
+		l.running[peerID] = true
+		return false
+	}
+	l.running[peerID] = false
+	return true
+}
+
+// finish releases cleanup of a peer, true if it was requested again meanwhile and must run once more
+func (l *peerLifecycle) finish(peerID peer.ID) bool {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if l.running[peerID] {
+		l.running[peerID] = false
+		return true
+	}
+	delete(l.running, peerID)
+	return false
+}
+
+// onPeerDisconnected cleans up state of a peer across subsystems, connection drops and dead streams all end here
+func (r *Relay) onPeerDisconnected(peerID peer.ID) {
+	if !r.lifecycle.begin(peerID) {
+		return
+	}
+	for again := true; again; again = r.lifecycle.finish(peerID) {
+		gone := !r.isConnected(peerID)
+		slog.Info("Mesh peer disconnected, cleaning up its state", "peer", peerID, "gone", gone)
+		r.Events.Publish(Event{Type: EventPeerDisconnected, PeerID: peerID})
+		for _, step := range peerCleanupSteps() {
+			if step.gone && !gone {
+				continue
+			}
+			if err := r.runCleanupStep(step, peerID); err != nil {
+				slog.Error("Peer cleanup step failed, continuing with the rest", "peer", peerID, "step", step.name, "err", err)
+			}
+		}
+	}
+}
+
+// runCleanupStep runs a cleanup step, a panicking step doesn't keep the others from running
+func (r *Relay) runCleanupStep(step peerCleanupStep, peerID peer.ID) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+	step.run(r, peerID)
+	return nil
+}
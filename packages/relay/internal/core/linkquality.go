@@ -0,0 +1,119 @@
+package core
+
+import (
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/pion/webrtc/v4"
+)
+
+// --- Mesh Link Quality ---
+//
+// Quality of links from mesh relays is sampled from stats of PeerConnections we pull rooms over,
+// and published with relay status so every relay sees which mesh edges are unhealthy.
+
+// LinkQuality is quality of media received from a mesh relay since the previous sample
+type LinkQuality struct {
+	Loss       float64       `json:"loss"`       // Fraction of RTP packets lost
+	RTT        time.Duration `json:"rtt"`        // Round trip time of the worst connection to the relay
+	Throughput uint64        `json:"throughput"` // Bits per second received
+	At         time.Time     `json:"at"`
+}
+
+// linkCounters are cumulative totals of PeerConnections from a mesh relay, deltas of them make a sample
+type linkCounters struct {
+	received uint64
+	lost     uint64
+	bytes    uint64
+	rtt      time.Duration
+	at       time.Time
+}
+
+// linkSamples keeps totals of the previous sample per peer
+type linkSamples struct {
+	mtx  sync.Mutex
+	last map[peer.ID]linkCounters
+}
+
+// collectLinkCounters sums stats of given PeerConnections from one peer
+func collectLinkCounters(pcs []*webrtc.PeerConnection) linkCounters {
+	counters := linkCounters{at: time.Now()}
+	for _, pc := range pcs {
+		for _, s := range pc.GetStats() {
+			switch stat := s.(type) {
+			case webrtc.InboundRTPStreamStats:
+				counters.received += uint64(stat.PacketsReceived)
+				counters.lost += uint64(max(stat.PacketsLost, 0)) // Duplicates make it negative
+				counters.bytes += stat.BytesReceived
+			case webrtc.ICECandidatePairStats:
+				if !stat.Nominated {
+					continue
+				}
+				counters.rtt = max(counters.rtt, time.Duration(stat.CurrentRoundTripTime*float64(time.Second)))
+			}
+		}
+	}
+	return counters
+}
+
+// meshPeerConnections returns PeerConnections rooms are pulled over, per relay serving them
+func (sp *StreamProtocol) meshPeerConnections() map[peer.ID][]*webrtc.PeerConnection {
+	pcs := make(map[peer.ID][]*webrtc.PeerConnection)
+	seen := make(map[*webrtc.PeerConnection]struct{}) // Rooms pulled over a mesh link share its PeerConnection
+	for roomName, conn := range sp.requestedConns.Copy() {
+		room := sp.relay.GetRoomByName(roomName)
+		if room == nil || conn.pc == nil {
+			continue
+		}
+		upstreamID := room.UpstreamID()
+		if upstreamID == "" {
+			continue
+		}
+		if _, ok := seen[conn.pc]; ok {
+			continue
+		}
+		seen[conn.pc] = struct{}{}
+		pcs[upstreamID] = append(pcs[upstreamID], conn.pc)
+	}
+	return pcs
+}
+
+// sampleLinkQuality updates quality of links from mesh relays we pull rooms from, dropping links no longer used.
+// A link whose totals went back, as its PeerConnections were replaced, starts over from its new totals.
+func (r *Relay) sampleLinkQuality() {
+	if r.StreamProtocol == nil {
+		return
+	}
+	pcs := r.StreamProtocol.meshPeerConnections()
+
+	r.linkSamples.mtx.Lock()
+	defer r.linkSamples.mtx.Unlock()
+	if r.linkSamples.last == nil {
+		r.linkSamples.last = make(map[peer.ID]linkCounters)
+	}
+	for peerID := range r.linkSamples.last {
+		if _, ok := pcs[peerID]; !ok {
+			delete(r.linkSamples.last, peerID)
+			r.LinkQualities.Delete(peerID)
+		}
+	}
+
+	for peerID, peerPCs := range pcs {
+		counters := collectLinkCounters(peerPCs)
+		last, known := r.linkSamples.last[peerID]
+		r.linkSamples.last[peerID] = counters
+		if !known || counters.received < last.received || counters.lost < last.lost || counters.bytes < last.bytes {
+			continue
+		}
+
+		quality := LinkQuality{RTT: counters.rtt, At: counters.at}
+		if packets := (counters.received - last.received) + (counters.lost - last.lost); packets > 0 {
+			quality.Loss = float64(counters.lost-last.lost) / float64(packets)
+		}
+		if elapsed := counters.at.Sub(last.at); elapsed > 0 {
+			quality.Throughput = uint64(float64((counters.bytes-last.bytes)*8) / elapsed.Seconds())
+		}
+		r.LinkQualities.Set(peerID, quality)
+	}
+}
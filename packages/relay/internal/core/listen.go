@@ -0,0 +1,117 @@
+package core
+
+import (
+	"fmt"
+	"relay/internal/common"
+	"strconv"
+	"strings"
+
+	"github.com/multiformats/go-multiaddr"
+)
+
+// ListenPorts holds the ports each transport listens on, empty list disables the transport
+type ListenPorts struct {
+	TCP          []int
+	WS           []int
+	WebTransport []int
+	QUIC         []int
+	WSS          []int
+
+	WSProxied bool // WS ports are served by the proxy-aware front, libp2p listens on loopback only
+}
+
+// ListenPortsFromFlags builds per-transport ports from flags, falling back to endpoint port
+func ListenPortsFromFlags() (ListenPorts, error) {
+	flags := common.GetFlags()
+	var lp ListenPorts
+	var err error
+	if lp.TCP, err = parsePortList(flags.TCPPorts, flags.EndpointPort); err != nil {
+		return lp, fmt.Errorf("invalid TCP ports: %w", err)
+	}
+	if lp.WS, err = parsePortList(flags.WSPorts, 0); err != nil {
+		return lp, fmt.Errorf("invalid WS ports: %w", err)
+	}
+	if lp.WebTransport, err = parsePortList(flags.WebTransportPorts, flags.EndpointPort); err != nil {
+		return lp, fmt.Errorf("invalid WebTransport ports: %w", err)
+	}
+	if lp.QUIC, err = parsePortList(flags.QUICPorts, flags.EndpointPort); err != nil {
+		return lp, fmt.Errorf("invalid QUIC ports: %w", err)
+	}
+	if lp.WSS, err = parsePortList(flags.WSSPorts, 0); err != nil {
+		return lp, fmt.Errorf("invalid WSS ports: %w", err)
+	}
+	lp.WSProxied = len(lp.WS) > 0 && (len(flags.WSPathPrefix) > 0 || len(flags.WSTrustedProxies) > 0 || flags.WSProxyProtocol)
+	return lp, nil
+}
+
+// parsePortList parses comma separated ports, empty list gives fallback port (or none if fallback is 0)
+func parsePortList(list string, fallback int) ([]int, error) {
+	if len(strings.TrimSpace(list)) <= 0 {
+		if fallback <= 0 {
+			return nil, nil
+		}
+		return []int{fallback}, nil
+	}
+
+	var ports []int
+	for _, part := range strings.Split(list, ",") {
+		port, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("'%s' is not a port: %w", part, err)
+		}
+		if port <= 0 || port > 65535 {
+			return nil, fmt.Errorf("port %d out of range", port)
+		}
+		ports = append(ports, port)
+	}
+	return ports, nil
+}
+
+// multiaddrs returns IPv4 and IPv6 listen addresses of all transports
+func (lp ListenPorts) multiaddrs() ([]multiaddr.Multiaddr, error) {
+	var listenAddrs []string
+	for _, port := range lp.TCP {
+		listenAddrs = append(listenAddrs,
+			fmt.Sprintf("/ip4/0.0.0.0/tcp/%d", port), // IPv4 - Raw TCP
+			fmt.Sprintf("/ip6/::/tcp/%d", port),      // IPv6 - Raw TCP
+		)
+	}
+	if lp.WSProxied {
+		listenAddrs = append(listenAddrs, "/ip4/127.0.0.1/tcp/0/ws") // Loopback - WebSocket behind proxy-aware front
+	} else {
+		for _, port := range lp.WS {
+			listenAddrs = append(listenAddrs,
+				fmt.Sprintf("/ip4/0.0.0.0/tcp/%d/ws", port), // IPv4 - WebSocket
+				fmt.Sprintf("/ip6/::/tcp/%d/ws", port),      // IPv6 - WebSocket
+			)
+		}
+	}
+	for _, port := range lp.WSS {
+		listenAddrs = append(listenAddrs,
+			fmt.Sprintf("/ip4/0.0.0.0/tcp/%d/tls/ws", port), // IPv4 - Secure WebSocket
+			fmt.Sprintf("/ip6/::/tcp/%d/tls/ws", port),      // IPv6 - Secure WebSocket
+		)
+	}
+	for _, port := range lp.WebTransport {
+		listenAddrs = append(listenAddrs,
+			fmt.Sprintf("/ip4/0.0.0.0/udp/%d/quic-v1/webtransport", port), // IPv4 - UDP QUIC WebTransport
+			fmt.Sprintf("/ip6/::/udp/%d/quic-v1/webtransport", port),      // IPv6 - UDP QUIC WebTransport
+		)
+	}
+	for _, port := range lp.QUIC {
+		listenAddrs = append(listenAddrs,
+			fmt.Sprintf("/ip4/0.0.0.0/udp/%d/quic-v1", port), // IPv4 - UDP Raw QUIC
+			fmt.Sprintf("/ip6/::/udp/%d/quic-v1", port),      // IPv6 - UDP Raw QUIC
+		)
+	}
+
+	var muAddrs []multiaddr.Multiaddr
+	for _, addr := range listenAddrs {
+		multiAddr, err := multiaddr.NewMultiaddr(addr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse multiaddr '%s': %w", addr, err)
+		}
+		muAddrs = append(muAddrs, multiAddr)
+	}
+	return muAddrs, nil
+}
@@ -4,13 +4,20 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"strconv"
+	"time"
+
+	"relay/internal/common"
 
 	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/libp2p/go-libp2p/p2p/discovery/mdns"
+	"github.com/libp2p/zeroconf/v2"
 )
 
 const (
-	mdnsDiscoveryRendezvous = "/nestri-relay/mdns-discovery/1.0.0" // Shared string for mDNS discovery
+	dnssdServiceType = "_nestri-relay._tcp" // DNS-SD service type LAN clients browse for relays
+	dnssdDomain      = "local."
+	dnssdMaxText     = 255 // Max length of a TXT record string
 )
 
 type discoveryNotifee struct {
@@ -18,21 +25,70 @@ type discoveryNotifee struct {
 }
 
 func (d *discoveryNotifee) HandlePeerFound(pi peer.AddrInfo) {
-	if d.relay != nil {
+	if d.relay != nil && !d.relay.isConnected(pi.ID) {
 		if err := d.relay.connectToPeer(context.Background(), &pi); err != nil {
-			slog.Error("failed to connect to discovered relay", "peer", pi.ID, "error", err)
+			slog.Error("failed to connect to discovered relay", "peer", pi.ID, "err", err)
 		}
 	}
 }
 
-func startMDNSDiscovery(relay *Relay) error {
+// startMDNSDiscovery finds relays on the LAN over mDNS, restarting the service every mDNS interval so
+// the LAN is queried again and found relays which dropped are redialed
+func startMDNSDiscovery(ctx context.Context, relay *Relay) error {
+	flags := common.GetFlags()
 	d := &discoveryNotifee{
 		relay: relay,
 	}
 
-	service := mdns.NewMdnsService(relay.Host, mdnsDiscoveryRendezvous, d)
+	service := mdns.NewMdnsService(relay.Host, flags.MDNSServiceTag, d)
 	if err := service.Start(); err != nil {
 		return fmt.Errorf("failed to start mDNS discovery: %w", err)
 	}
+	go func() {
+		var tick <-chan time.Time
+		if flags.MDNSInterval > 0 {
+			ticker := time.NewTicker(time.Duration(flags.MDNSInterval) * time.Second)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				_ = service.Close()
+				return
+			case <-tick:
+				_ = service.Close()
+				service = mdns.NewMdnsService(relay.Host, flags.MDNSServiceTag, d)
+				if err := service.Start(); err != nil {
+					slog.Warn("Failed to restart mDNS discovery", "err", err)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// startDNSSDAnnouncement announces the relay endpoint over DNS-SD, so LAN clients can find it without libp2p.
+// TXT records carry the peer ID, protocol version and addresses browsers can dial.
+func startDNSSDAnnouncement(ctx context.Context, relay *Relay) error {
+	text := []string{
+		"id=" + relay.ID.String(),
+		"version=" + strconv.Itoa(common.ProtocolVersion),
+	}
+	for _, addr := range browserAddrs(relay.Host) {
+		if entry := "addr=" + addr.String(); len(entry) <= dnssdMaxText {
+			text = append(text, entry)
+		}
+	}
+
+	server, err := zeroconf.Register(relay.ID.String(), dnssdServiceType, dnssdDomain, common.GetFlags().EndpointPort, text, nil)
+	if err != nil {
+		return fmt.Errorf("failed to register DNS-SD service: %w", err)
+	}
+	slog.Info("Announcing relay over DNS-SD", "service", dnssdServiceType, "port", common.GetFlags().EndpointPort)
+	go func() {
+		<-ctx.Done()
+		server.Shutdown()
+	}()
 	return nil
 }
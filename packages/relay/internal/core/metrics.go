@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"relay/internal/common"
 	"sync"
 	"time"
 
@@ -15,7 +16,7 @@ import (
 
 // periodicMetricsPublisher periodically gathers local metrics and publishes them.
 func (r *Relay) periodicMetricsPublisher(ctx context.Context) {
-	ticker := time.NewTicker(metricsPublishInterval)
+	ticker := time.NewTicker(time.Duration(common.GetFlags().StatusInterval) * time.Millisecond)
 	defer ticker.Stop()
 
 	// Publish immediately on start
@@ -32,6 +33,10 @@ func (r *Relay) periodicMetricsPublisher(ctx context.Context) {
 			if err := r.publishRelayMetrics(ctx); err != nil {
 				slog.Error("Failed to publish relay metrics", "err", err)
 			}
+			// Keep room states (viewer counts, online status) fresh for directory queries
+			if err := r.publishRoomStates(ctx); err != nil {
+				slog.Error("Failed to publish room states", "err", err)
+			}
 		}
 	}
 }
@@ -43,10 +48,12 @@ func (r *Relay) publishRelayMetrics(ctx context.Context) error {
 		return nil
 	}
 
-	// Check all peer latencies
-	r.checkAllPeerLatencies(ctx)
+	// Latencies are kept fresh by latencyMonitor
+	r.sampleLinkQuality()
 
+	r.statusMtx.RLock()
 	data, err := json.Marshal(r.PeerInfo)
+	r.statusMtx.RUnlock()
 	if err != nil {
 		return fmt.Errorf("failed to marshal relay status: %w", err)
 	}
@@ -58,18 +65,18 @@ func (r *Relay) publishRelayMetrics(ctx context.Context) error {
 	return nil
 }
 
-// checkAllPeerLatencies measures latency to all currently connected peers.
+// checkAllPeerLatencies measures latency to all currently connected mesh relays.
 func (r *Relay) checkAllPeerLatencies(ctx context.Context) {
 	var wg sync.WaitGroup
 	for _, p := range r.Host.Network().Peers() {
-		if p == r.ID {
-			continue // Skip self
+		if p == r.ID || !r.isMeshRelay(p) {
+			continue // Skip self and viewers
 		}
 		wg.Add(1)
 		// Run checks concurrently
 		go func(peerID peer.ID) {
 			defer wg.Done()
-			go r.measureLatencyToPeer(ctx, peerID)
+			r.measureLatencyToPeer(ctx, peerID)
 		}(p)
 	}
 	wg.Wait() // Wait for all latency checks to complete
@@ -83,7 +90,7 @@ func (r *Relay) measureLatencyToPeer(ctx context.Context, peerID peer.ID) {
 	}
 
 	// Create a context for the ping operation
-	pingCtx, cancel := context.WithCancel(ctx)
+	pingCtx, cancel := context.WithTimeout(ctx, latencyPingTimeout)
 	defer cancel()
 
 	// Use the PingService instance stored in the Relay struct
@@ -124,5 +131,6 @@ func (r *Relay) measureLatencyToPeer(ctx context.Context, peerID peer.ID) {
 		}
 
 		r.PeerInfo.Latencies.Set(peerID, latency)
+		peerLatencySummary.WithLabelValues(peerID.String()).Observe(latency.Seconds())
 	}
 }
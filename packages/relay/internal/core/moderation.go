@@ -0,0 +1,350 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"relay/internal/common"
+	"relay/internal/shared"
+	"sync"
+	"time"
+
+	gen "relay/internal/proto"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/oklog/ulid/v2"
+	"google.golang.org/protobuf/proto"
+)
+
+// --- Moderation ---
+
+// Moderation actions, sent to affected viewers
+const (
+	moderationKicked = "kicked"
+	moderationBanned = "banned"
+)
+
+// RoomBan keeps a session or peer out of a room until it expires, lifting a ban expires it at once
+type RoomBan struct {
+	ID        ulid.ULID `json:"id"`
+	Room      string    `json:"room"`
+	SessionID string    `json:"session_id,omitempty"`
+	PeerID    peer.ID   `json:"peer_id,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	Expires   time.Time `json:"expires"`
+}
+
+// Matches checks if ban applies to given session or peer in given room
+func (b RoomBan) Matches(roomName, sessionID string, peerID peer.ID) bool {
+	if b.Room != roomName {
+		return false
+	}
+	return (len(b.SessionID) > 0 && b.SessionID == sessionID) || (len(b.PeerID) > 0 && b.PeerID == peerID)
+}
+
+// Moderation holds room bans, gossiped between relays, and sessions invalidated by kicks
+type Moderation struct {
+	mtx     sync.Mutex
+	bans    map[ulid.ULID]RoomBan
+	revoked map[string]time.Time // session ID -> invalid until
+}
+
+func NewModeration() *Moderation {
+	return &Moderation{
+		bans:    make(map[ulid.ULID]RoomBan),
+		revoked: make(map[string]time.Time),
+	}
+}
+
+// Merge applies a ban, for a known ban the earlier expiry wins so lifted bans stay lifted.
+// Returns true if ban was new or changed.
+func (m *Moderation) Merge(ban RoomBan, now time.Time) bool {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.prune(now)
+
+	// Expired bans are kept until retention ends, so stale gossip can't bring back a lifted ban
+	if now.Sub(ban.Expires) > roomBanRetention {
+		return false
+	}
+	if prev, ok := m.bans[ban.ID]; ok && !ban.Expires.Before(prev.Expires) {
+		return false
+	}
+	m.bans[ban.ID] = ban
+	return true
+}
+
+// Banned returns the active ban matching given session or peer in a room
+func (m *Moderation) Banned(roomName, sessionID string, peerID peer.ID, now time.Time) (RoomBan, bool) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	for _, ban := range m.bans {
+		if now.Before(ban.Expires) && ban.Matches(roomName, sessionID, peerID) {
+			return ban, true
+		}
+	}
+	return RoomBan{}, false
+}
+
+// Get returns a ban of a room by ID, including expired ones still retained
+func (m *Moderation) Get(roomName string, id ulid.ULID) (RoomBan, bool) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	ban, ok := m.bans[id]
+	return ban, ok && ban.Room == roomName
+}
+
+// Active returns the active bans of a room
+func (m *Moderation) Active(roomName string, now time.Time) []RoomBan {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	bans := make([]RoomBan, 0)
+	for _, ban := range m.bans {
+		if ban.Room == roomName && now.Before(ban.Expires) {
+			bans = append(bans, ban)
+		}
+	}
+	return bans
+}
+
+// Snapshot returns all retained bans for gossiping, lifted ones included
+func (m *Moderation) Snapshot(now time.Time) []RoomBan {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.prune(now)
+	bans := make([]RoomBan, 0, len(m.bans))
+	for _, ban := range m.bans {
+		bans = append(bans, ban)
+	}
+	return bans
+}
+
+// RevokeSession makes a session ID unusable for resuming until given time
+func (m *Moderation) RevokeSession(sessionID string, until time.Time) {
+	if len(sessionID) <= 0 {
+		return
+	}
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.revoked[sessionID] = until
+}
+
+// SessionRevoked checks if a session ID was invalidated by a kick
+func (m *Moderation) SessionRevoked(sessionID string, now time.Time) bool {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	until, ok := m.revoked[sessionID]
+	return ok && now.Before(until)
+}
+
+// RevokedSessions returns session IDs still unusable for resuming and until when
+func (m *Moderation) RevokedSessions(now time.Time) map[string]time.Time {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.prune(now)
+	revoked := make(map[string]time.Time, len(m.revoked))
+	maps.Copy(revoked, m.revoked)
+	return revoked
+}
+
+// prune forgets bans past retention and sessions no longer revoked, caller holds lock
+func (m *Moderation) prune(now time.Time) {
+	for id, ban := range m.bans {
+		if now.Sub(ban.Expires) > roomBanRetention {
+			delete(m.bans, id)
+		}
+	}
+	for sessionID, until := range m.revoked {
+		if now.After(until) {
+			delete(m.revoked, sessionID)
+		}
+	}
+}
+
+// --- Relay Moderation ---
+
+// BanFromRoom bans a session or peer from a room for given duration, disconnecting matching local participants
+// and gossiping the ban to mesh relays
+func (r *Relay) BanFromRoom(roomName, sessionID string, peerID peer.ID, duration time.Duration, reason string) (RoomBan, error) {
+	if len(sessionID) <= 0 && len(peerID) <= 0 {
+		return RoomBan{}, errors.New("ban needs a session or peer ID")
+	}
+	if duration <= 0 {
+		return RoomBan{}, errors.New("ban duration must be positive")
+	}
+	if duration > roomBanMaxDuration {
+		return RoomBan{}, fmt.Errorf("ban duration exceeds %s", roomBanMaxDuration)
+	}
+	id, err := common.NewULID()
+	if err != nil {
+		return RoomBan{}, fmt.Errorf("failed to create ban ID: %w", err)
+	}
+	now := time.Now()
+	ban := RoomBan{
+		ID:        id,
+		Room:      roomName,
+		SessionID: sessionID,
+		PeerID:    peerID,
+		Reason:    reason,
+		Expires:   now.Add(duration),
+	}
+	r.Moderation.Merge(ban, now)
+	r.shareBan(ban)
+	r.enforceRoomBan(ban)
+	slog.Info("Banned from room", "room", roomName, "session", sessionID, "peer", peerID, "until", ban.Expires)
+
+	if err = r.publishRoomBans(context.Background(), []RoomBan{ban}); err != nil {
+		slog.Error("Failed to publish room ban", "room", roomName, "err", err)
+	}
+	return ban, nil
+}
+
+// LiftRoomBan ends a ban early, returns false if room has no such active ban
+func (r *Relay) LiftRoomBan(roomName string, id ulid.ULID) bool {
+	now := time.Now()
+	r.loadSharedBans(roomName, now)
+	ban, ok := r.Moderation.Get(roomName, id)
+	if !ok || !now.Before(ban.Expires) {
+		return false
+	}
+	ban.Expires = now
+	r.Moderation.Merge(ban, now)
+	r.shareBan(ban)
+	slog.Info("Lifted room ban", "room", roomName, "ban", id)
+	r.Events.Publish(Event{Type: EventBanLifted, Room: roomName, PeerID: ban.PeerID, Attrs: map[string]string{
+		"ban": id.String(),
+	}})
+
+	if err := r.publishRoomBans(context.Background(), []RoomBan{ban}); err != nil {
+		slog.Error("Failed to publish lifted room ban", "room", roomName, "err", err)
+	}
+	return true
+}
+
+// isRoomOwner checks if peer owns a local room or one known from mesh routes
+func (r *Relay) isRoomOwner(roomName string, peerID peer.ID) bool {
+	if room := r.GetRoomByName(roomName); room != nil {
+		return room.OwnerID == peerID
+	}
+	if routes, ok := r.Routes.Get(roomName); ok {
+		for _, info := range routes.Copy() {
+			if info.OwnerID == peerID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// enforceRoomBan disconnects local participants a ban applies to
+func (r *Relay) enforceRoomBan(ban RoomBan) {
+	r.Events.Publish(Event{Type: EventViewerBanned, Room: ban.Room, PeerID: ban.PeerID, Attrs: map[string]string{
+		"ban":     ban.ID.String(),
+		"session": ban.SessionID,
+		"expires": ban.Expires.Format(time.RFC3339),
+	}})
+	// Bans of a room apply to its quality variants too
+	for _, room := range r.roomFamily(ban.Room) {
+		for _, participant := range room.GetParticipants() {
+			if !ban.Matches(ban.Room, participant.SessionID, participant.PeerID) {
+				continue
+			}
+			r.removeParticipant(room, participant, &gen.ProtoModeration{
+				RoomName:       ban.Room,
+				Action:         moderationBanned,
+				Reason:         ban.Reason,
+				BanExpiresUnix: ban.Expires.Unix(),
+			})
+		}
+	}
+}
+
+// removeParticipant tells a participant why it is removed and disconnects it, its session can't be resumed
+func (r *Relay) removeParticipant(room *shared.Room, participant *shared.Participant, moderation *gen.ProtoModeration) {
+	r.revokeSession(participant.SessionID, time.Now().Add(kickedSessionTTL))
+	if err := sendModeration(participant, moderation); err != nil {
+		slog.Debug("Failed to send moderation to participant", "room", room.Name, "participant", participant.ID, "err", err)
+	}
+	room.RemoveParticipantByID(participant.ID)
+	participant.Close()
+	slog.Info("Removed participant from room", "room", room.Name, "participant", participant.ID, "action", moderation.Action)
+}
+
+// sendModeration sends a moderation action to a participant over its data channel
+func sendModeration(participant *shared.Participant, moderation *gen.ProtoModeration) error {
+	dc := participant.DataChannel()
+	if dc == nil {
+		return errors.New("participant has no data channel")
+	}
+	modMsg, err := common.CreateMessage(moderation, "participant-"+moderation.Action, nil)
+	if err != nil {
+		return err
+	}
+	data, err := proto.Marshal(modMsg)
+	if err != nil {
+		return err
+	}
+	return dc.SendBinary(data)
+}
+
+// sendBanRefusal tells a banned requester its stream request is refused and until when
+func sendBanRefusal(safeBRW *common.SafeBufioRW, ban RoomBan) {
+	banMsg, err := common.CreateMessage(&gen.ProtoModeration{
+		RoomName:       ban.Room,
+		Action:         moderationBanned,
+		Reason:         ban.Reason,
+		BanExpiresUnix: ban.Expires.Unix(),
+	}, "request-stream-banned", nil)
+	if err != nil {
+		slog.Error("Failed to create proto message", "err", err)
+		return
+	}
+	if err = safeBRW.SendProto(banMsg); err != nil {
+		slog.Error("Failed to send ban refusal", "room", ban.Room, "err", err)
+	}
+}
+
+// --- Ban Gossip ---
+
+// publishRoomBans gossips bans to mesh relays
+func (r *Relay) publishRoomBans(ctx context.Context, bans []RoomBan) error {
+	if r.pubTopicBans == nil {
+		slog.Warn("Cannot publish room bans: topic is nil")
+		return nil
+	}
+	if len(bans) <= 0 {
+		return nil
+	}
+	data, err := json.Marshal(bans)
+	if err != nil {
+		return fmt.Errorf("failed to marshal room bans: %w", err)
+	}
+	if pubErr := r.pubTopicBans.Publish(ctx, data); pubErr != nil {
+		slog.Error("Failed to publish room bans message", "err", pubErr)
+	}
+	return nil
+}
+
+// applyRoomBans merges bans gossiped by a mesh relay, enforcing new ones locally.
+// Only verified mesh relays and owners of the banned room are listened to, any peer can publish to the topic.
+func (r *Relay) applyRoomBans(from peer.ID, bans []RoomBan) {
+	now := time.Now()
+	trustedRelay := r.isMeshRelay(from)
+	for _, ban := range bans {
+		if !trustedRelay && !r.isRoomOwner(ban.Room, from) {
+			slog.Warn("Ignoring room ban from untrusted peer", "room", ban.Room, "ban", ban.ID, "from", from)
+			continue
+		}
+		if maxExpires := now.Add(roomBanMaxDuration); ban.Expires.After(maxExpires) {
+			ban.Expires = maxExpires
+		}
+		if !r.Moderation.Merge(ban, now) || !now.Before(ban.Expires) {
+			continue
+		}
+		slog.Info("Received room ban from mesh", "room", ban.Room, "ban", ban.ID, "from", from)
+		r.enforceRoomBan(ban)
+	}
+}
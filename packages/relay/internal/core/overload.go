@@ -0,0 +1,227 @@
+package core
+
+import (
+	"context"
+	"log/slog"
+	"relay/internal/common"
+	"runtime/metrics"
+	"time"
+
+	gen "relay/internal/proto"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/proto"
+)
+
+// --- Overload Advisories ---
+
+// Reasons sent with overload advisories
+const (
+	overloadReasonCPU   = "cpu"
+	overloadReasonDrops = "drops"
+)
+
+var relayOverloadedGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "nestri_relay_overloaded",
+	Help: "1 while the relay advises upstream that it is overloaded",
+})
+
+// overloadSample is relay load measured over one check interval
+type overloadSample struct {
+	cpuPercent float64
+	dropRate   float64
+}
+
+// reason returns why sample is over given thresholds, empty if it is not, 0 thresholds are disabled
+func (s overloadSample) reason(cpuPercent, dropRate float64) string {
+	if cpuPercent > 0 && s.cpuPercent >= cpuPercent {
+		return overloadReasonCPU
+	}
+	if dropRate > 0 && s.dropRate >= dropRate {
+		return overloadReasonDrops
+	}
+	return ""
+}
+
+// loadSampler measures CPU busy share and viewer queue drops between calls
+type loadSampler struct {
+	cpu      []metrics.Sample
+	busy     float64
+	total    float64
+	drops    uint64
+	sampleAt time.Time
+}
+
+func newLoadSampler() *loadSampler {
+	return &loadSampler{cpu: []metrics.Sample{
+		{Name: "/cpu/classes/total:cpu-seconds"},
+		{Name: "/cpu/classes/idle:cpu-seconds"},
+	}}
+}
+
+// sample returns load since previous sample, the first sample only sets the baseline
+func (ls *loadSampler) sample(drops uint64) overloadSample {
+	metrics.Read(ls.cpu)
+	total := ls.cpu[0].Value.Float64()
+	busy := total - ls.cpu[1].Value.Float64()
+	now := time.Now()
+
+	var s overloadSample
+	if !ls.sampleAt.IsZero() {
+		if elapsed := total - ls.total; elapsed > 0 {
+			s.cpuPercent = (busy - ls.busy) / elapsed * 100
+		}
+		// Closed rooms take their counters with them
+		if seconds := now.Sub(ls.sampleAt).Seconds(); seconds > 0 && drops > ls.drops {
+			s.dropRate = float64(drops-ls.drops) / seconds
+		}
+	}
+	ls.busy, ls.total, ls.drops, ls.sampleAt = busy, total, drops, now
+	return s
+}
+
+// queueDrops sums packets dropped from participant queues of local rooms
+func (r *Relay) queueDrops() uint64 {
+	var drops uint64
+	for _, room := range r.LocalRooms.Copy() {
+		counters := room.Counters()
+		drops += counters.DroppedQueueFull + counters.DroppedEvicted + counters.DroppedShed
+	}
+	return drops
+}
+
+// IsOverloaded returns true while the relay advises upstream that it is overloaded
+func (r *Relay) IsOverloaded() bool {
+	return r.overloaded.Load()
+}
+
+// overloadMonitor checks relay load, advising pushing nodes and mesh relays when it becomes or stops being overloaded
+func (r *Relay) overloadMonitor(ctx context.Context) {
+	ticker := time.NewTicker(overloadCheckInterval)
+	defer ticker.Stop()
+
+	sampler := newLoadSampler()
+	sampler.sample(r.queueDrops())
+
+	var calmSince, advisedAt time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		flags := common.GetFlags()
+		cpuLimit, dropLimit := float64(flags.OverloadCPU), float64(flags.OverloadDrops)
+		sample := sampler.sample(r.queueDrops())
+
+		if reason := sample.reason(cpuLimit, dropLimit); len(reason) > 0 {
+			calmSince = time.Time{}
+			if !r.overloaded.Swap(true) {
+				slog.Warn("Relay overloaded, advising upstream", "reason", reason, "cpuPercent", int(sample.cpuPercent), "dropRate", int(sample.dropRate))
+				r.Events.Publish(Event{Type: EventRelayOverloaded, Attrs: map[string]string{"reason": reason}})
+				relayOverloadedGauge.Set(1)
+			} else if time.Since(advisedAt) < overloadAdviseInterval {
+				continue
+			}
+			r.broadcastOverload(true, reason, sample)
+			advisedAt = time.Now()
+			continue
+		}
+
+		if !r.overloaded.Load() {
+			continue
+		}
+		// Recover only once load stayed clearly below thresholds, so advisories don't flap
+		if len(sample.reason(cpuLimit*overloadRecoverShare, dropLimit*overloadRecoverShare)) > 0 {
+			calmSince = time.Time{}
+			continue
+		}
+		if calmSince.IsZero() {
+			calmSince = time.Now()
+		}
+		if time.Since(calmSince) < overloadRecoverDelay {
+			continue
+		}
+		r.overloaded.Store(false)
+		slog.Info("Relay recovered from overload", "cpuPercent", int(sample.cpuPercent), "dropRate", int(sample.dropRate))
+		r.Events.Publish(Event{Type: EventRelayRecovered})
+		relayOverloadedGauge.Set(0)
+		r.broadcastOverload(false, "", sample)
+	}
+}
+
+// broadcastOverload sends an overload advisory to pushing nodes of local rooms and relays on both ends of mesh links
+func (r *Relay) broadcastOverload(overloaded bool, reason string, sample overloadSample) {
+	msg, err := common.CreateMessage(&gen.ProtoRelayOverload{
+		RelayId:    r.ID.String(),
+		Overloaded: overloaded,
+		Reason:     reason,
+		CpuPercent: uint32(max(sample.cpuPercent, 0)),
+		DropRate:   uint32(max(sample.dropRate, 0)),
+	}, "relay-overload", nil)
+	if err != nil {
+		slog.Error("Failed to create proto message", "err", err)
+		return
+	}
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		slog.Error("Failed to marshal overload advisory", "err", err)
+		return
+	}
+
+	for _, room := range r.LocalRooms.Copy() {
+		// Pulled rooms have the upstream relay on their DataChannel, it hears over the mesh link
+		if dc := room.DataChannel(); len(room.UpstreamID()) <= 0 && dc != nil {
+			if err = dc.SendBinary(data); err != nil {
+				slog.Debug("Failed to send overload advisory to pushing node", "room", room.Name, "err", err)
+			}
+		}
+	}
+
+	sp := r.StreamProtocol
+	for peerID, link := range sp.meshLinks.Copy() {
+		if err = link.safeBRW.SendProto(msg); err != nil {
+			slog.Debug("Failed to send overload advisory to upstream relay", "peer", peerID, "err", err)
+		}
+	}
+	for peerID, link := range sp.servedMeshLinks.Copy() {
+		if err = link.safeBRW.SendProto(msg); err != nil {
+			slog.Debug("Failed to send overload advisory to downstream relay", "peer", peerID, "err", err)
+		}
+	}
+}
+
+// handleOverloadAdvisory records overload state advised by a mesh relay, overloaded relays are pulled from last
+func (r *Relay) handleOverloadAdvisory(peerID peer.ID, advisory *gen.ProtoRelayOverload) {
+	if advisory == nil {
+		return
+	}
+	if !advisory.Overloaded {
+		if r.overloadedPeers.Has(peerID) {
+			r.overloadedPeers.Delete(peerID)
+			slog.Info("Mesh relay recovered from overload", "peer", peerID)
+		}
+		return
+	}
+	if !r.overloadedPeers.Has(peerID) {
+		slog.Warn("Mesh relay is overloaded", "peer", peerID, "reason", advisory.Reason,
+			"cpuPercent", advisory.CpuPercent, "dropRate", advisory.DropRate)
+	}
+	// Advisories are repeated while overloaded, a relay which went away stops being avoided once it expires
+	r.overloadedPeers.Set(peerID, time.Now().Add(overloadAdvisoryTTL))
+}
+
+// isPeerOverloaded returns true if peer advised it is overloaded and the advisory hasn't expired
+func (r *Relay) isPeerOverloaded(peerID peer.ID) bool {
+	expires, ok := r.overloadedPeers.Get(peerID)
+	if !ok {
+		return false
+	}
+	if time.Now().After(expires) {
+		r.overloadedPeers.Delete(peerID)
+		return false
+	}
+	return true
+}
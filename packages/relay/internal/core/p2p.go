@@ -5,11 +5,16 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
 	"time"
 
+	"github.com/libp2p/go-libp2p"
 	"github.com/libp2p/go-libp2p/core/host"
 	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/p2p/net/swarm"
+	"github.com/libp2p/go-libp2p/p2p/security/noise"
+	tls "github.com/libp2p/go-libp2p/p2p/security/tls"
 	"github.com/multiformats/go-multiaddr"
 )
 
@@ -23,6 +28,7 @@ type networkNotifier struct {
 // Connected is called when a connection is established
 func (n *networkNotifier) Connected(net network.Network, conn network.Conn) {
 	if n.relay != nil {
+		slog.Debug("Connection established", "peer", conn.RemotePeer(), "remote_ip", n.relay.RemoteIP(conn))
 		n.relay.onPeerConnected(conn.RemotePeer())
 	}
 }
@@ -41,6 +47,33 @@ func (n *networkNotifier) Listen(net network.Network, addr multiaddr.Multiaddr)
 // ListenClose is called when the node stops listening on an address
 func (n *networkNotifier) ListenClose(net network.Network, addr multiaddr.Multiaddr) {}
 
+// --- Security Transports ---
+
+// securityOptions returns libp2p security transports listed in spec, peers negotiate the first one both support
+func securityOptions(spec string) (libp2p.Option, error) {
+	opts := make([]libp2p.Option, 0, 2)
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if len(name) <= 0 || seen[name] {
+			continue
+		}
+		seen[name] = true
+		switch name {
+		case "noise":
+			opts = append(opts, libp2p.Security(noise.ID, noise.New))
+		case "tls":
+			opts = append(opts, libp2p.Security(tls.ID, tls.New))
+		default:
+			return nil, fmt.Errorf("unknown security transport %q", name)
+		}
+	}
+	if len(opts) <= 0 {
+		return nil, errors.New("no security transport enabled")
+	}
+	return libp2p.ChainOptions(opts...), nil
+}
+
 // --- PubSub Setup ---
 
 // setupPubSub initializes PubSub topics and subscriptions.
@@ -69,6 +102,39 @@ func (r *Relay) setupPubSub(ctx context.Context) error {
 	}
 	go r.handleRelayMetricsMessages(ctx, metricsSub) // Handler in relay_state.go
 
+	// Room Bans Topic
+	r.pubTopicBans, err = r.PubSub.Join(roomBansTopicName)
+	if err != nil {
+		return fmt.Errorf("failed to join room bans topic '%s': %w", roomBansTopicName, err)
+	}
+	bansSub, err := r.pubTopicBans.Subscribe()
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to room bans topic '%s': %w", roomBansTopicName, err)
+	}
+	go r.handleRoomBanMessages(ctx, bansSub)
+
+	// Room Chat Topic
+	r.pubTopicChat, err = r.PubSub.Join(roomChatTopicName)
+	if err != nil {
+		return fmt.Errorf("failed to join room chat topic '%s': %w", roomChatTopicName, err)
+	}
+	chatSub, err := r.pubTopicChat.Subscribe()
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to room chat topic '%s': %w", roomChatTopicName, err)
+	}
+	go r.handleChatMessages(ctx, chatSub)
+
+	// Relay Successors Topic
+	r.pubTopicSuccessors, err = r.PubSub.Join(relaySuccessorTopicName)
+	if err != nil {
+		return fmt.Errorf("failed to join relay successor topic '%s': %w", relaySuccessorTopicName, err)
+	}
+	successorSub, err := r.pubTopicSuccessors.Subscribe()
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to relay successor topic '%s': %w", relaySuccessorTopicName, err)
+	}
+	go r.handleSuccessorMessages(ctx, successorSub)
+
 	slog.Info("PubSub topics joined and subscriptions started")
 	return nil
 }
@@ -104,6 +170,69 @@ func (r *Relay) ConnectToPeer(ctx context.Context, addr multiaddr.Multiaddr) err
 	return r.connectToPeer(ctx, peerInfo)
 }
 
+// ConnectToKnownPeer dials known addresses of a peer ranked QUIC, TCP, then relayed. Addresses which kept failing
+// are only dialed once the others failed, each tier is dialed in parallel with libp2p ranking the dials.
+func (r *Relay) ConnectToKnownPeer(ctx context.Context, pi *PeerInfo) error {
+	if len(pi.DialedAddr) > 0 {
+		pi.promoteAddr(pi.DialedAddr)
+	}
+	var transports []multiaddr.Multiaddr
+	for _, addr := range pi.Addrs {
+		// Stored addresses may or may not carry the /p2p component
+		transport, id := peer.SplitAddr(addr)
+		if transport == nil || (len(id) > 0 && id != pi.ID) {
+			continue
+		}
+		transports = append(transports, transport)
+	}
+	if len(transports) <= 0 {
+		return fmt.Errorf("peer %s has no usable addresses", pi.ID)
+	}
+
+	fresh, stale := pi.dialTiers(transports)
+	var err error
+	for _, tier := range [][]multiaddr.Multiaddr{fresh, stale} {
+		if len(tier) <= 0 {
+			continue
+		}
+		// Dial only this tier, not addresses libp2p still remembers from earlier attempts
+		if !r.isConnected(pi.ID) {
+			r.Host.Peerstore().ClearAddrs(pi.ID)
+		}
+		if err = r.connectToPeer(ctx, &peer.AddrInfo{ID: pi.ID, Addrs: tier}); err == nil {
+			break
+		}
+		pi.recordAddrDials(failedDialAddrs(err, tier), nil)
+	}
+	if err != nil {
+		pi.recordDialFailure()
+		return err
+	}
+
+	// Remember which address worked, so it's ordered first next time
+	if conns := r.Host.Network().ConnsToPeer(pi.ID); len(conns) > 0 {
+		pi.recordDialSuccess(conns[0].RemoteMultiaddr())
+		pi.recordAddrDials(nil, conns[0].RemoteMultiaddr())
+		slog.Debug("Connected to peer through address", "peer", pi.ID, "addr", pi.DialedAddr)
+	} else {
+		pi.recordDialSuccess(nil)
+	}
+	return nil
+}
+
+// failedDialAddrs returns addresses a failed dial tried, all dialed ones if libp2p didn't tell which failed
+func failedDialAddrs(err error, dialed []multiaddr.Multiaddr) []multiaddr.Multiaddr {
+	var dialErr *swarm.DialError
+	if !errors.As(err, &dialErr) || len(dialErr.DialErrors) <= 0 {
+		return dialed
+	}
+	failed := make([]multiaddr.Multiaddr, 0, len(dialErr.DialErrors))
+	for _, transportErr := range dialErr.DialErrors {
+		failed = append(failed, transportErr.Address)
+	}
+	return failed
+}
+
 // printConnectInstructions logs the multiaddresses for connecting to this relay.
 func printConnectInstructions(p2pHost host.Host) {
 	peerInfo := peer.AddrInfo{
@@ -120,4 +249,17 @@ func printConnectInstructions(p2pHost host.Host) {
 	for _, addr := range addrs {
 		slog.Info(fmt.Sprintf("> %s", addr.String()))
 	}
+
+	// Browsers can only dial WebTransport and secure WebSocket, print those separately for copy-pasting
+	if browser := browserAddrs(p2pHost); len(browser) > 0 {
+		slog.Info("Browser connection addresses:")
+		for _, addr := range browser {
+			slog.Info(fmt.Sprintf("> %s", addr.String()))
+		}
+	}
+}
+
+// isConnected returns true if host has a connection to given peer
+func (r *Relay) isConnected(id peer.ID) bool {
+	return r.Host.Network().Connectedness(id) == network.Connected
 }
@@ -1,9 +1,6 @@
 package core
 
 import (
-	"errors"
-	"log/slog"
-	"os"
 	"relay/internal/common"
 	"relay/internal/shared"
 	"time"
@@ -19,6 +16,27 @@ type PeerInfo struct {
 	Peers     *common.SafeMap[peer.ID, *PeerInfo]      // Peers connected to this peer
 	Latencies *common.SafeMap[peer.ID, time.Duration]  // Latencies to other peers from this peer
 	Rooms     *common.SafeMap[string, shared.RoomInfo] // Rooms this peer is part of or owner of
+	MeshProof string                                   `json:",omitempty"` // Mesh secret proof of ID, see common.SignMeshPeer
+
+	// Announced so peers use only features we support, zero for relays predating announcements
+	ProtocolVersion uint32   `json:",omitempty"`
+	Capabilities    []string `json:",omitempty"`
+
+	// Where the relay is, from GeoIP database of its public address and operator labels
+	Location *common.GeoLocation `json:",omitempty"` // Nil if unknown
+	Region   string              `json:",omitempty"`
+	Zone     string              `json:",omitempty"`
+
+	// Quality of links from mesh relays this peer pulls rooms from, nil for relays predating it
+	LinkQualities *common.SafeMap[peer.ID, LinkQuality] `json:",omitempty"`
+
+	// Local peer store metadata, never taken from what peers tell about themselves
+	DialedAddr   multiaddr.Multiaddr `json:",omitempty"` // Address we last successfully dialed this peer at
+	LastSeen     time.Time           `json:",omitempty"` // Last time we were connected to this peer
+	DialFailures int                 `json:",omitempty"` // Consecutive failed dials
+	NextDialAt   time.Time           `json:",omitempty"` // Dials are skipped before this time (backoff)
+	StatusAt     time.Time           `json:",omitempty"` // Last relay status received, zero for peers which aren't relays (viewers)
+	AddrFailures map[string]int      `json:",omitempty"` // Transport address -> consecutive failed dials of it
 }
 
 func NewPeerInfo(id peer.ID, addrs []multiaddr.Multiaddr) *PeerInfo {
@@ -28,50 +46,25 @@ func NewPeerInfo(id peer.ID, addrs []multiaddr.Multiaddr) *PeerInfo {
 		Peers:     common.NewSafeMap[peer.ID, *PeerInfo](),
 		Latencies: common.NewSafeMap[peer.ID, time.Duration](),
 		Rooms:     common.NewSafeMap[string, shared.RoomInfo](),
-	}
-}
-
-// SaveToFile saves the peer store to a JSON file in persistent path
-func (pi *PeerInfo) SaveToFile(filePath string) error {
-	if len(filePath) <= 0 {
-		return errors.New("filepath is not set")
-	}
 
-	// Marshal the peer store to JSON array (we don't need to store IDs..)
-	data, err := pi.Peers.MarshalJSON()
-	if err != nil {
-		return errors.New("failed to marshal peer store data: " + err.Error())
+		LinkQualities: common.NewSafeMap[peer.ID, LinkQuality](),
 	}
-
-	// Save the data to a file
-	if err = os.WriteFile(filePath, data, 0644); err != nil {
-		return errors.New("failed to save peer store to file: " + err.Error())
-	}
-
-	slog.Info("PeerStore saved to file", "path", filePath)
-	return nil
 }
 
-// LoadFromFile loads the peer store from a JSON file in persistent path
-func (pi *PeerInfo) LoadFromFile(filePath string) error {
-	if len(filePath) <= 0 {
-		return errors.New("filepath is not set")
-	}
+// announcement returns protocol version and capabilities the peer announced in its relay status
+func (pi *PeerInfo) announcement() common.Announcement {
+	return common.Announcement{Version: pi.ProtocolVersion, Capabilities: pi.Capabilities}
+}
 
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			slog.Info("PeerStore file does not exist, starting with empty store")
-			return nil // No peers to load
+// promoteAddr moves given address to the front of known addresses, adding it if unknown
+func (pi *PeerInfo) promoteAddr(addr multiaddr.Multiaddr) {
+	addrs := make([]multiaddr.Multiaddr, 0, len(pi.Addrs)+1)
+	addrs = append(addrs, addr)
+	for _, known := range pi.Addrs {
+		if transport, _ := peer.SplitAddr(known); transport != nil && transport.Equal(addr) {
+			continue
 		}
-		return errors.New("failed to read peer store file: " + err.Error())
-	}
-
-	// Unmarshal the JSON data into the peer store
-	if err = pi.Peers.UnmarshalJSON(data); err != nil {
-		return errors.New("failed to unmarshal peer store data: " + err.Error())
+		addrs = append(addrs, known)
 	}
-
-	slog.Info("PeerStore loaded from file", "path", filePath)
-	return nil
+	pi.Addrs = addrs
 }
@@ -0,0 +1,182 @@
+package core
+
+import (
+	"log/slog"
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multiaddr"
+)
+
+// --- Peer Store Policy ---
+
+const (
+	peerDialBackoffBase = 5 * time.Second // Backoff after first failed dial, doubled for each further failure
+	peerDialBackoffMax  = 1 * time.Hour   // Upper bound for dial backoff
+	peerMaxDialFailures = 10              // Consecutive failures before peer is pruned from peer store
+	addrMaxDialFailures = 3               // Consecutive failures before an address is only dialed after the others
+)
+
+// peerDialBackoff returns how long to wait before dialing again after given amount of consecutive failures
+func peerDialBackoff(failures int) time.Duration {
+	if failures <= 0 {
+		return 0
+	}
+	backoff := peerDialBackoffBase
+	for i := 1; i < failures; i++ {
+		backoff *= 2
+		if backoff >= peerDialBackoffMax {
+			return peerDialBackoffMax
+		}
+	}
+	return backoff
+}
+
+// withJitter spreads a backoff between half and full duration, so peers don't redial in lockstep
+func withJitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	return d/2 + rand.N(d/2)
+}
+
+// canDial checks if peer is out of dial backoff
+func (pi *PeerInfo) canDial(now time.Time) bool {
+	return !now.Before(pi.NextDialAt)
+}
+
+// recordDialSuccess resets backoff and remembers the working address
+func (pi *PeerInfo) recordDialSuccess(addr multiaddr.Multiaddr) {
+	pi.LastSeen = time.Now()
+	pi.DialFailures = 0
+	pi.NextDialAt = time.Time{}
+	if len(addr) > 0 {
+		pi.DialedAddr = addr
+		pi.promoteAddr(addr)
+	}
+}
+
+// recordDialFailure increases backoff for the peer
+func (pi *PeerInfo) recordDialFailure() {
+	pi.DialFailures++
+	pi.NextDialAt = time.Now().Add(withJitter(peerDialBackoff(pi.DialFailures)))
+}
+
+// mergeLocalMeta carries over our local peer store metadata from previous entry of same peer
+func (pi *PeerInfo) mergeLocalMeta(prev *PeerInfo) {
+	if prev == nil {
+		return
+	}
+	if len(prev.DialedAddr) > 0 {
+		pi.DialedAddr = prev.DialedAddr
+		pi.promoteAddr(prev.DialedAddr)
+	}
+	pi.LastSeen = prev.LastSeen
+	pi.DialFailures = prev.DialFailures
+	pi.NextDialAt = prev.NextDialAt
+	pi.StatusAt = prev.StatusAt
+	pi.AddrFailures = prev.AddrFailures
+}
+
+// addrKey returns transport part of an address, stored addresses may or may not carry the /p2p component
+func addrKey(addr multiaddr.Multiaddr) string {
+	if transport, _ := peer.SplitAddr(addr); transport != nil {
+		return transport.String()
+	}
+	return addr.String()
+}
+
+// addrDialClass ranks transports for dialing, QUIC first, then TCP, relayed last
+func addrDialClass(addr multiaddr.Multiaddr) int {
+	if _, err := addr.ValueForProtocol(multiaddr.P_CIRCUIT); err == nil {
+		return 2
+	}
+	if _, err := addr.ValueForProtocol(multiaddr.P_QUIC_V1); err == nil {
+		return 0
+	}
+	return 1
+}
+
+// dialTiers ranks transport addresses of the peer, those which kept failing are split off to be dialed last
+func (pi *PeerInfo) dialTiers(addrs []multiaddr.Multiaddr) (fresh, stale []multiaddr.Multiaddr) {
+	for _, addr := range addrs {
+		if pi.AddrFailures[addrKey(addr)] >= addrMaxDialFailures {
+			stale = append(stale, addr)
+		} else {
+			fresh = append(fresh, addr)
+		}
+	}
+	byRank := func(a, b multiaddr.Multiaddr) int {
+		if c := addrDialClass(a) - addrDialClass(b); c != 0 {
+			return c
+		}
+		return pi.AddrFailures[addrKey(a)] - pi.AddrFailures[addrKey(b)]
+	}
+	slices.SortStableFunc(fresh, byRank)
+	slices.SortStableFunc(stale, byRank)
+	return fresh, stale
+}
+
+// recordAddrDials counts failed dials of addresses and resets the one which worked, forgetting unknown addresses.
+// The map is replaced rather than changed, as the previous one may be read concurrently.
+func (pi *PeerInfo) recordAddrDials(failed []multiaddr.Multiaddr, worked multiaddr.Multiaddr) {
+	failures := make(map[string]int)
+	for _, addr := range pi.Addrs {
+		if count := pi.AddrFailures[addrKey(addr)]; count > 0 {
+			failures[addrKey(addr)] = count
+		}
+	}
+	for _, addr := range failed {
+		failures[addrKey(addr)]++
+	}
+	if len(worked) > 0 {
+		delete(failures, addrKey(worked))
+	}
+	if len(failures) <= 0 {
+		failures = nil
+	}
+	pi.AddrFailures = failures
+}
+
+// prunePeers removes peers not seen within ttl or failing too often, returns count of removed peers
+func (pi *PeerInfo) prunePeers(ttl time.Duration) int {
+	now := time.Now()
+	pruned := 0
+	for id, p := range pi.Peers.Copy() {
+		// Entries from before metadata existed start their TTL now
+		if p.LastSeen.IsZero() {
+			p = p.withLastSeen(now)
+			pi.Peers.Set(id, p)
+		}
+
+		reason := ""
+		if ttl > 0 && now.Sub(p.LastSeen) > ttl {
+			reason = "ttl expired"
+		} else if p.DialFailures >= peerMaxDialFailures {
+			reason = "too many failed dials"
+		}
+		if len(reason) > 0 {
+			slog.Info("Pruning peer from peer store", "peer", id, "reason", reason, "last_seen", p.LastSeen, "failures", p.DialFailures)
+			pi.Peers.Delete(id)
+			pruned++
+		}
+	}
+	return pruned
+}
+
+// touchPeer marks a peer as seen now, replacing its entry as the previous one may be read concurrently
+func (pi *PeerInfo) touchPeer(id peer.ID) {
+	now := time.Now()
+	pi.Peers.Update(id, func(p *PeerInfo) *PeerInfo {
+		return p.withLastSeen(now)
+	})
+}
+
+// withLastSeen returns a copy of peer entry seen at given time
+func (pi *PeerInfo) withLastSeen(seen time.Time) *PeerInfo {
+	updated := *pi
+	updated.LastSeen = seen
+	return &updated
+}
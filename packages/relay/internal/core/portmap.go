@@ -0,0 +1,54 @@
+package core
+
+import (
+	"context"
+	"log/slog"
+
+	"relay/internal/common"
+
+	"github.com/libp2p/go-libp2p/p2p/net/nat"
+)
+
+// --- Router Port Mapping ---
+//
+// Home-hosted relays map their ports on the router over UPnP or NAT-PMP instead of needing manual port forwarding.
+// libp2p maps its listen ports and announces the external addresses itself, the WebRTC UDP mux port is mapped here
+// and its external IP offered to WebRTC peers.
+
+// mapWebRTCPort maps the WebRTC UDP mux port on the router, keeping the mapping renewed until context is done
+func mapWebRTCPort(ctx context.Context) {
+	muxPort := common.GetFlags().UDPMuxPort
+	if muxPort <= 0 {
+		slog.Warn("Port mapping needs a WebRTC UDP mux port, WebRTC ports aren't mapped")
+		return
+	}
+
+	discoverCtx, cancel := context.WithTimeout(ctx, portMappingTimeout)
+	defer cancel()
+	natDevice, err := nat.DiscoverNAT(discoverCtx)
+	if err != nil {
+		slog.Warn("No UPnP or NAT-PMP router found, ports aren't mapped", "err", err)
+		return
+	}
+	go func() {
+		<-ctx.Done()
+		_ = natDevice.Close()
+	}()
+
+	if err = natDevice.AddMapping(discoverCtx, "udp", muxPort); err != nil {
+		slog.Warn("Failed to map WebRTC UDP mux port on router", "port", muxPort, "err", err)
+		return
+	}
+	mapped, ok := natDevice.GetMapping("udp", muxPort)
+	if !ok {
+		slog.Warn("Router didn't map WebRTC UDP mux port", "port", muxPort)
+		return
+	}
+	if int(mapped.Port()) != muxPort {
+		// Candidates are announced with the internal port, a different external one isn't reachable
+		slog.Warn("Router mapped WebRTC UDP mux port to another port, WebRTC won't be reachable through it", "port", muxPort, "external", mapped)
+		return
+	}
+	slog.Info("Mapped WebRTC UDP mux port on router", "port", muxPort, "external", mapped)
+	common.SetMappedWebRTCIP(mapped.Addr().String())
+}
@@ -0,0 +1,208 @@
+package core
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"log/slog"
+	"relay/internal/common"
+	"relay/internal/shared"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// --- Link Probing ---
+//
+// Mesh relays measure capacity of links from their peers by asking for a burst of bytes on a dedicated stream.
+// The request is the burst size as big-endian uint32, the peer answers with that many bytes and closes.
+
+// --- Protocol IDs ---
+const (
+	protocolLinkProbe = "/nestri-relay/link-probe" // For measuring capacity of the link from a mesh relay
+)
+
+// linkProbeBytes is the size of a probe burst, also the most a probe is answered with
+const linkProbeBytes = 1 << 20
+
+// LinkProbe is capacity of the link from a mesh relay to this relay, as last measured
+type LinkProbe struct {
+	Throughput uint64    `json:"throughput"` // Bits per second the probe burst arrived at
+	Load       uint64    `json:"load"`       // Bits per second of rooms pulled from the relay while probing
+	At         time.Time `json:"at"`
+}
+
+// Capacity returns bits per second the link carried while probed, media and probe burst together
+func (lp LinkProbe) Capacity() uint64 {
+	return lp.Throughput + lp.Load
+}
+
+// roomBitrate returns incoming bitrate of a room in bits per second, as last sampled
+func roomBitrate(room *shared.Room) uint64 {
+	return room.AudioStats.Snapshot().Bitrate + room.VideoStats.Snapshot().Bitrate
+}
+
+// upstreamLoad returns bits per second of rooms pulled from given peer
+func (r *Relay) upstreamLoad(peerID peer.ID) uint64 {
+	var load uint64
+	for _, room := range r.LocalRooms.Copy() {
+		if room.UpstreamID() == peerID {
+			load += roomBitrate(room)
+		}
+	}
+	return load
+}
+
+// LinkProbe returns last probe of the link from given peer, false if it wasn't probed within linkProbeTTL
+func (r *Relay) LinkProbe(peerID peer.ID) (LinkProbe, bool) {
+	probe, ok := r.linkProbes.Get(peerID)
+	if !ok || time.Since(probe.At) > linkProbeTTL {
+		return LinkProbe{}, false
+	}
+	return probe, true
+}
+
+// linkCanCarry tells if the link from a peer has capacity left for a room of given bitrate beyond rooms we pull from
+// it already. Links not probed recently are assumed to.
+func (r *Relay) linkCanCarry(peerID peer.ID, bitrate uint64) bool {
+	probe, ok := r.LinkProbe(peerID)
+	if !ok {
+		return true
+	}
+	return r.upstreamLoad(peerID)+bitrate <= probe.Capacity()
+}
+
+// handleLinkProbe answers a probe of a mesh relay with the burst it asked for
+func (r *Relay) handleLinkProbe(stream network.Stream) {
+	peerID := stream.Conn().RemotePeer()
+	defer func() {
+		_ = stream.Close()
+	}()
+	if !r.isMeshRelay(peerID) {
+		slog.Debug("Refusing link probe of peer which isn't a mesh relay", "peer", peerID)
+		_ = stream.Reset()
+		return
+	}
+
+	_ = stream.SetDeadline(time.Now().Add(linkProbeTimeout))
+	var size uint32
+	if err := binary.Read(stream, binary.BigEndian, &size); err != nil {
+		slog.Debug("Failed to read link probe request", "peer", peerID, "err", err)
+		_ = stream.Reset()
+		return
+	}
+	remaining := int(min(size, linkProbeBytes))
+
+	meter := r.Bandwidth.Meter(peerID)
+	burst := make([]byte, 16*1024)
+	for remaining > 0 {
+		n, err := stream.Write(burst[:min(remaining, len(burst))])
+		meter.AddOut(n)
+		if err != nil {
+			slog.Debug("Failed to write link probe burst", "peer", peerID, "err", err)
+			_ = stream.Reset()
+			return
+		}
+		remaining -= n
+	}
+}
+
+// probeLink measures throughput of the link from a mesh relay, timed from the first byte of the burst so
+// stream setup and round trip don't count
+func (r *Relay) probeLink(ctx context.Context, peerID peer.ID) (LinkProbe, error) {
+	ctx, cancel := context.WithTimeout(ctx, linkProbeTimeout)
+	defer cancel()
+
+	stream, err := r.newVersionedStream(ctx, peerID, protocolLinkProbe)
+	if err != nil {
+		return LinkProbe{}, err
+	}
+	defer func() {
+		_ = stream.Close()
+	}()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = stream.SetDeadline(deadline)
+	}
+
+	if err = binary.Write(stream, binary.BigEndian, uint32(linkProbeBytes)); err != nil {
+		return LinkProbe{}, err
+	}
+	if err = stream.CloseWrite(); err != nil {
+		return LinkProbe{}, err
+	}
+
+	load := r.upstreamLoad(peerID)
+	meter := r.Bandwidth.Meter(peerID)
+	buf := make([]byte, 16*1024)
+	var first time.Time
+	var received int
+	for {
+		n, readErr := stream.Read(buf)
+		meter.AddIn(n)
+		if n > 0 && first.IsZero() {
+			first = time.Now()
+		} else {
+			received += n
+		}
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+		if readErr != nil {
+			return LinkProbe{}, readErr
+		}
+	}
+	elapsed := time.Since(first)
+	if first.IsZero() || received <= 0 || elapsed <= 0 {
+		return LinkProbe{}, errors.New("link probe burst was too short to time")
+	}
+	return LinkProbe{
+		Throughput: uint64(float64(received*8) / elapsed.Seconds()),
+		Load:       load,
+		At:         time.Now(),
+	}, nil
+}
+
+// linkProber probes links from connected mesh relays one at a time, so probes don't compete with each other
+func (r *Relay) linkProber(ctx context.Context) {
+	interval := time.Duration(common.GetFlags().LinkProbe) * time.Second
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for peerID := range r.Peers.Copy() {
+				if peerID == r.ID || !r.isConnected(peerID) || !r.isMeshRelay(peerID) {
+					continue
+				}
+				if !r.peerSupports(peerID, common.CapabilityLinkProbe, protocolLinkProbe) {
+					continue
+				}
+				probe, err := r.probeLink(ctx, peerID)
+				if err != nil {
+					if ctx.Err() != nil {
+						return
+					}
+					slog.Debug("Failed to probe mesh link", "peer", peerID, "err", err)
+					continue
+				}
+				r.linkProbes.Set(peerID, probe)
+				slog.Debug("Probed mesh link", "peer", peerID, "throughput_bps", probe.Throughput, "load_bps", probe.Load)
+			}
+
+			// Probes of peers which left go stale, drop them
+			for peerID, probe := range r.linkProbes.Copy() {
+				if time.Since(probe.At) > linkProbeTTL {
+					r.linkProbes.Delete(peerID)
+				}
+			}
+		}
+	}
+}
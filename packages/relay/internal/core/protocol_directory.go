@@ -0,0 +1,204 @@
+package core
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"relay/internal/common"
+	"relay/internal/shared"
+	"slices"
+	"sort"
+	"strings"
+
+	gen "relay/internal/proto"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// --- Protocol IDs ---
+const (
+	protocolDirectory = "/nestri-relay/directory" // For querying rooms known by relay
+)
+
+const (
+	directoryDefaultLimit = 50  // Page size when query doesn't set a limit
+	directoryMaxLimit     = 200 // Upper bound for page size
+)
+
+// DirectoryProtocol answers room directory queries from relays and clients
+type DirectoryProtocol struct {
+	relay *Relay
+}
+
+func NewDirectoryProtocol(relay *Relay) *DirectoryProtocol {
+	protocol := &DirectoryProtocol{
+		relay: relay,
+	}
+
+	protocol.relay.setVersionedHandler(protocolDirectory, protocol.handleDirectoryQuery)
+
+	return protocol
+}
+
+// --- Protocol Stream Handlers ---
+
+// handleDirectoryQuery answers one or more directory queries on a stream
+func (dp *DirectoryProtocol) handleDirectoryQuery(stream network.Stream) {
+	safeBRW := newStreamRW(stream)
+
+	for {
+		var msgWrapper gen.ProtoMessage
+		err := safeBRW.ReceiveProto(&msgWrapper)
+		if errors.Is(err, common.ErrMessageRejected) {
+			continue
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, network.ErrReset) {
+				slog.Debug("Directory query connection closed by peer", "peer", stream.Conn().RemotePeer())
+				return
+			}
+
+			slog.Error("Failed to receive directory query", "err", err)
+			_ = stream.Reset()
+			return
+		}
+
+		if msgWrapper.MessageBase == nil || msgWrapper.MessageBase.PayloadType != "directory-query" {
+			slog.Error("Unexpected message in directory query stream", "peer", stream.Conn().RemotePeer())
+			_ = stream.Reset()
+			return
+		}
+
+		query := msgWrapper.GetDirectoryQuery()
+		if query == nil {
+			slog.Error("Could not GetDirectoryQuery from directory-query")
+			continue
+		}
+
+		resMsg, err := common.CreateMessage(dp.relay.QueryRooms(query), "directory-result", nil)
+		if err != nil {
+			slog.Error("Failed to create proto message", "err", err)
+			continue
+		}
+		if err = safeBRW.SendProto(resMsg); err != nil {
+			slog.Error("Failed to send directory result", "peer", stream.Conn().RemotePeer(), "err", err)
+			return
+		}
+	}
+}
+
+// --- Public Usable Methods ---
+
+// QueryDirectory asks another relay for a page of rooms matching the query
+func (dp *DirectoryProtocol) QueryDirectory(ctx context.Context, peerID peer.ID, query *gen.ProtoDirectoryQuery) (*gen.ProtoDirectoryResult, error) {
+	stream, err := dp.relay.newVersionedStream(ctx, peerID, protocolDirectory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stream: %w", err)
+	}
+	defer func() {
+		_ = stream.Close()
+	}()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = stream.SetDeadline(deadline)
+	}
+
+	safeBRW := newStreamRW(stream)
+
+	reqMsg, err := common.CreateMessage(query, "directory-query", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create proto message: %w", err)
+	}
+	if err = safeBRW.SendProto(reqMsg); err != nil {
+		return nil, fmt.Errorf("failed to send directory query: %w", err)
+	}
+
+	var resWrapper gen.ProtoMessage
+	if err = safeBRW.ReceiveProto(&resWrapper); err != nil {
+		return nil, fmt.Errorf("failed to receive directory result: %w", err)
+	}
+	result := resWrapper.GetDirectoryResult()
+	if result == nil {
+		return nil, errors.New("response did not contain a directory result")
+	}
+	return result, nil
+}
+
+// QueryRooms returns a page of rooms known to this relay (local and mesh) matching the query
+func (r *Relay) QueryRooms(query *gen.ProtoDirectoryQuery) *gen.ProtoDirectoryResult {
+	// Merge local rooms with mesh room states, local view wins for rooms we host.
+	// Private rooms are left out, viewers join them by name only. Quality variants are offered to
+	// viewers of their room instead of listed.
+	rooms := make(map[string]*gen.ProtoDirectoryRoom)
+	// Rooms of cluster relays outside our mesh go first, so fresher mesh room states replace them
+	infos := slices.Collect(maps.Values(r.sharedRooms.Copy()))
+	infos = append(infos, slices.Collect(maps.Values(r.Rooms.Copy()))...)
+	for _, info := range infos {
+		if info.Metadata.Private || info.Name != shared.BaseRoomName(info.Name) {
+			continue
+		}
+		rooms[info.Name] = &gen.ProtoDirectoryRoom{
+			Id:       info.ID.String(),
+			Name:     info.Name,
+			OwnerId:  info.OwnerID.String(),
+			Viewers:  uint32(info.Viewers),
+			Online:   info.Online,
+			Metadata: info.Metadata.ToProto(),
+		}
+	}
+	for _, room := range r.LocalRooms.Copy() {
+		if room.Name != shared.BaseRoomName(room.Name) {
+			continue
+		}
+		metadata := room.GetMetadata()
+		if metadata.Private {
+			delete(rooms, room.Name)
+			continue
+		}
+		rooms[room.Name] = &gen.ProtoDirectoryRoom{
+			Id:       room.ID.String(),
+			Name:     room.Name,
+			OwnerId:  room.OwnerID.String(),
+			Viewers:  uint32(room.ParticipantCount()),
+			Online:   room.IsOnline(),
+			Metadata: metadata.ToProto(),
+		}
+	}
+
+	// Cursor holds the last room name of previous page
+	after := ""
+	if len(query.Cursor) > 0 {
+		if decoded, err := base64.RawURLEncoding.DecodeString(query.Cursor); err == nil {
+			after = string(decoded)
+		}
+	}
+
+	names := make([]string, 0, len(rooms))
+	for name := range rooms {
+		if strings.HasPrefix(name, query.Prefix) && name > after {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	limit := int(query.Limit)
+	if limit <= 0 {
+		limit = directoryDefaultLimit
+	} else if limit > directoryMaxLimit {
+		limit = directoryMaxLimit
+	}
+
+	result := &gen.ProtoDirectoryResult{}
+	for i, name := range names {
+		if i >= limit {
+			result.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(result.Rooms[len(result.Rooms)-1].Name))
+			break
+		}
+		result.Rooms = append(result.Rooms, rooms[name])
+	}
+	return result
+}
@@ -0,0 +1,994 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"relay/internal/common"
+	"relay/internal/connections"
+	"relay/internal/shared"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	gen "relay/internal/proto"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/pion/webrtc/v4"
+)
+
+// --- Mesh Link Multiplexing ---
+//
+// Rooms pulled from the same relay share one PeerConnection (a mesh link) instead of one each.
+// Serving relay offers tracks of all requested rooms on the link, before every offer it sends
+// "mesh-room-tracks" mapping each room to MIDs of its tracks. Each room gets its own DataChannel
+// labelled with the room name. Rooms join with "request-stream-room" and leave with "mesh-release-room",
+// serving relay renegotiates the link on every change.
+
+// protocolStreamMesh is for pulling multiple room streams over one PeerConnection
+const protocolStreamMesh = "/nestri-relay/stream-mesh"
+
+var errMeshLinkClosed = errors.New("mesh link closed")
+
+// meshPullRoom is a room requested over a mesh link
+type meshPullRoom struct {
+	room     *shared.Room
+	signal   func(error) // Result of the request, until active
+	expected int32       // Tracks to receive before room is usable
+	received atomic.Int32
+	active   bool // Room is online through the link
+}
+
+// meshLink is the requesting side of a mesh link, pulling rooms from one relay
+type meshLink struct {
+	sp        *StreamProtocol
+	peerID    peer.ID
+	stream    network.Stream
+	safeBRW   *common.SafeBufioRW
+	iceHelper *common.ICEHelper
+	pc        *webrtc.PeerConnection // Created on first offer
+
+	mtx    sync.Mutex
+	rooms  map[string]*meshPullRoom // room name -> pulled room
+	mids   map[string]string        // MID -> room name
+	closed bool
+}
+
+// requestMeshStream pulls room over the shared mesh link to peer, returns once tracks are flowing
+func (sp *StreamProtocol) requestMeshStream(ctx context.Context, room *shared.Room, peerID peer.ID) error {
+	// Link may close between lookup and request when its last room leaves, retry once on a new link
+	for attempt := 0; ; attempt++ {
+		link, err := sp.getMeshLink(ctx, peerID)
+		if err != nil {
+			return err
+		}
+
+		result := make(chan error, 1)
+		signal := func(err error) {
+			select {
+			case result <- err:
+			default:
+			}
+		}
+		if err = link.addRoom(room, signal); err != nil {
+			if errors.Is(err, errMeshLinkClosed) && attempt == 0 {
+				continue
+			}
+			return err
+		}
+
+		select {
+		case err = <-result:
+			if err == nil {
+				err = link.activateRoom(room)
+			}
+			if err != nil {
+				link.releaseRoom(room.Name)
+			}
+			return err
+		case <-ctx.Done():
+			link.releaseRoom(room.Name)
+			return ctx.Err()
+		}
+	}
+}
+
+// getMeshLink returns open mesh link to peer, opening one if needed
+func (sp *StreamProtocol) getMeshLink(ctx context.Context, peerID peer.ID) (*meshLink, error) {
+	sp.meshMtx.Lock()
+	defer sp.meshMtx.Unlock()
+
+	if link, ok := sp.meshLinks.Get(peerID); ok && !link.isClosed() {
+		return link, nil
+	}
+
+	stream, err := sp.relay.newVersionedStream(ctx, peerID, protocolStreamMesh)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stream: %w", err)
+	}
+	link := &meshLink{
+		sp:        sp,
+		peerID:    peerID,
+		stream:    stream,
+		safeBRW:   newStreamRW(stream),
+		iceHelper: common.NewICEHelper(nil),
+		rooms:     make(map[string]*meshPullRoom),
+		mids:      make(map[string]string),
+	}
+	sp.meshLinks.Set(peerID, link)
+	go link.run()
+
+	slog.Debug("Opened mesh link", "peer", peerID)
+	return link, nil
+}
+
+func (l *meshLink) isClosed() bool {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.closed
+}
+
+// addRoom requests room over the link, signal gets result of the request
+func (l *meshLink) addRoom(room *shared.Room, signal func(error)) error {
+	expected := int32(2)
+	if room.GetSettings().AudioOnly {
+		expected = 1
+	}
+
+	l.mtx.Lock()
+	if l.closed {
+		l.mtx.Unlock()
+		return errMeshLinkClosed
+	}
+	if _, ok := l.rooms[room.Name]; ok {
+		l.mtx.Unlock()
+		return fmt.Errorf("room %s is already pulled over mesh link", room.Name)
+	}
+	l.rooms[room.Name] = &meshPullRoom{room: room, signal: signal, expected: expected}
+	l.mtx.Unlock()
+
+	reqMsg, err := common.CreateMessage(
+		&gen.ProtoClientRequestRoomStream{
+			RoomName: room.Name,
+		},
+		"request-stream-room", nil,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create proto message: %w", err)
+	}
+	if err = l.safeBRW.SendProto(reqMsg); err != nil {
+		l.close()
+		return fmt.Errorf("failed to send stream request: %w", err)
+	}
+	return nil
+}
+
+// activateRoom puts a room online through the link once its tracks are flowing
+func (l *meshLink) activateRoom(room *shared.Room) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	pr, ok := l.rooms[room.Name]
+	if l.closed || !ok {
+		return errMeshLinkClosed
+	}
+	pr.active = true
+
+	// Room is online from now on, pulled through peer
+	room.SetUpstreamID(l.peerID)
+	room.SetSharedPeerConnection(l.pc, func() {
+		l.releaseRoom(room.Name)
+	})
+	l.sp.requestedConns.Set(room.Name, &StreamConnection{
+		pc:  l.pc,
+		ndc: room.DataChannel(),
+	})
+	slog.Info("Pulling room over mesh link", "room", room.Name, "peer", l.peerID)
+	return nil
+}
+
+// forgetRoom removes room from the link, returning it if it was there and whether the link is now unused
+func (l *meshLink) forgetRoom(roomName string) (*meshPullRoom, bool) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	pr := l.rooms[roomName]
+	delete(l.rooms, roomName)
+	for mid, name := range l.mids {
+		if name == roomName {
+			delete(l.mids, mid)
+		}
+	}
+	return pr, len(l.rooms) == 0 && !l.closed
+}
+
+// releaseRoom stops pulling room over the link, closing the link once no rooms are left
+func (l *meshLink) releaseRoom(roomName string) {
+	pr, unused := l.forgetRoom(roomName)
+	if pr != nil && !l.isClosed() {
+		if pr.active {
+			l.sp.requestedConns.Delete(roomName)
+		}
+		releaseMsg, err := common.CreateMessage(
+			&gen.ProtoRaw{
+				Data: roomName,
+			},
+			"mesh-release-room", nil,
+		)
+		if err != nil {
+			slog.Error("Failed to create proto message", "err", err)
+		} else if err = l.safeBRW.SendProto(releaseMsg); err != nil {
+			slog.Debug("Failed to send mesh room release", "room", roomName, "peer", l.peerID, "err", err)
+		}
+	}
+	if unused {
+		l.close()
+	}
+}
+
+// refuseRoom handles serving relay refusing or dropping a room
+func (l *meshLink) refuseRoom(roomName string, reason error) {
+	pr, unused := l.forgetRoom(roomName)
+	if pr != nil {
+		if pr.active {
+			slog.Info("Room stream ended on mesh link", "room", roomName, "peer", l.peerID, "err", reason)
+			l.sp.requestedConns.Delete(roomName)
+			pr.room.Close()
+			l.sp.upstreamEnded(pr.room)
+		} else {
+			pr.signal(reason)
+		}
+	}
+	if unused {
+		l.close()
+	}
+}
+
+// close tears down the link, pending requests fail and active rooms go offline
+func (l *meshLink) close() {
+	l.mtx.Lock()
+	if l.closed {
+		l.mtx.Unlock()
+		return
+	}
+	l.closed = true
+	pc := l.pc
+	rooms := l.rooms
+	l.rooms = make(map[string]*meshPullRoom)
+	l.mids = make(map[string]string)
+	l.mtx.Unlock()
+
+	slog.Debug("Closing mesh link", "peer", l.peerID, "rooms", len(rooms))
+	l.sp.meshMtx.Lock()
+	if current, ok := l.sp.meshLinks.Get(l.peerID); ok && current == l {
+		l.sp.meshLinks.Delete(l.peerID)
+	}
+	l.sp.meshMtx.Unlock()
+
+	if pc != nil {
+		if err := pc.Close(); err != nil {
+			slog.Error("Failed to close mesh link PeerConnection", "peer", l.peerID, "err", err)
+		}
+	}
+	_ = l.stream.Reset()
+
+	for name, pr := range rooms {
+		if pr.active {
+			l.sp.requestedConns.Delete(name)
+			pr.room.Close()
+			l.sp.upstreamEnded(pr.room)
+		} else {
+			pr.signal(errMeshLinkClosed)
+		}
+	}
+}
+
+// run handles messages from serving relay until the link closes
+func (l *meshLink) run() {
+	defer l.close()
+
+	for {
+		var msgWrapper gen.ProtoMessage
+		err := l.safeBRW.ReceiveProto(&msgWrapper)
+		if errors.Is(err, common.ErrMessageRejected) {
+			continue
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, network.ErrReset) {
+				slog.Debug("Mesh link closed by peer", "peer", l.peerID)
+			} else if !l.isClosed() {
+				slog.Error("Failed to receive data for mesh link", "peer", l.peerID, "err", err)
+			}
+			return
+		}
+
+		if msgWrapper.MessageBase == nil {
+			slog.Error("No MessageBase in mesh link")
+			continue
+		}
+
+		switch msgWrapper.MessageBase.PayloadType {
+		case "session-assigned", "signaling-progress":
+			// Relays don't need sessions or progress
+		case "request-stream-offline":
+			l.refuseRoom(msgWrapper.GetRaw().GetData(), errStreamOffline)
+		case "request-stream-over-budget":
+			l.refuseRoom(msgWrapper.GetRaw().GetData(), errStreamOverBudget)
+		case "request-stream-draining":
+			l.refuseRoom(msgWrapper.GetRaw().GetData(), errStreamDraining)
+		case "quota-exceeded":
+			l.refuseRoom(msgWrapper.GetQuotaExceeded().GetRoomName(), errStreamOverQuota)
+		case "relay-overload":
+			l.sp.relay.handleOverloadAdvisory(l.peerID, msgWrapper.GetRelayOverload())
+		case "mesh-room-tracks":
+			tracksMsg := msgWrapper.GetMeshRoomTracks()
+			if tracksMsg == nil {
+				slog.Error("Could not GetMeshRoomTracks from mesh-room-tracks")
+				continue
+			}
+			l.mtx.Lock()
+			if _, ok := l.rooms[tracksMsg.RoomName]; ok {
+				for _, mid := range []string{tracksMsg.AudioMid, tracksMsg.VideoMid} {
+					if len(mid) > 0 {
+						l.mids[mid] = tracksMsg.RoomName
+					}
+				}
+			}
+			l.mtx.Unlock()
+		case "stream-path-info":
+			pathMsg := msgWrapper.GetStreamPathInfo()
+			if pathMsg == nil {
+				slog.Error("Could not GetStreamPathInfo from stream-path-info")
+				continue
+			}
+			if pr := l.getRoom(pathMsg.RoomName); pr != nil {
+				pr.room.SetUpstreamPath(int(pathMsg.Hops), time.Duration(pathMsg.PathLatencyUs)*time.Microsecond)
+			}
+		case "ice-candidate":
+			iceMsg := msgWrapper.GetIce()
+			if iceMsg == nil {
+				slog.Error("Could not GetIce from ice-candidate")
+				continue
+			}
+			l.iceHelper.AddCandidate(iceCandidateFromProto(iceMsg))
+		case "offer":
+			offerMsg := msgWrapper.GetSdp()
+			if offerMsg == nil {
+				slog.Error("Could not GetSdp from offer")
+				continue
+			}
+			if err = l.answerOffer(offerMsg); err != nil {
+				slog.Error("Failed to answer mesh link offer", "peer", l.peerID, "err", err)
+				return
+			}
+		}
+	}
+}
+
+func (l *meshLink) getRoom(roomName string) *meshPullRoom {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.rooms[roomName]
+}
+
+// roomForReceiver returns the room mapped to MID of receiver's transceiver
+func (l *meshLink) roomForReceiver(receiver *webrtc.RTPReceiver) (*meshPullRoom, string) {
+	mid := ""
+	for _, transceiver := range l.pc.GetTransceivers() {
+		if transceiver.Receiver() == receiver {
+			mid = transceiver.Mid()
+			break
+		}
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.rooms[l.mids[mid]], mid
+}
+
+// answerOffer applies an offer of serving relay, creating link PeerConnection on first one
+func (l *meshLink) answerOffer(offerMsg *gen.ProtoSDP) error {
+	if l.pc == nil {
+		if err := l.createPeerConnection(); err != nil {
+			return err
+		}
+	}
+
+	if err := l.pc.SetRemoteDescription(webrtc.SessionDescription{
+		SDP:  offerMsg.Sdp.Sdp,
+		Type: webrtc.NewSDPType(offerMsg.Sdp.Type),
+	}); err != nil {
+		return fmt.Errorf("failed to set remote description: %w", err)
+	}
+	l.iceHelper.FlushHeldCandidates()
+
+	answer, err := l.pc.CreateAnswer(nil)
+	if err != nil {
+		return fmt.Errorf("failed to create answer: %w", err)
+	}
+	if err = l.pc.SetLocalDescription(answer); err != nil {
+		return fmt.Errorf("failed to set local description: %w", err)
+	}
+	return sendSDP(l.safeBRW, answer, "answer")
+}
+
+func (l *meshLink) createPeerConnection() error {
+	pc, err := common.CreatePeerConnection(func() {
+		slog.Info("PeerConnection closed for mesh link", "peer", l.peerID)
+		l.close()
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create PeerConnection: %w", err)
+	}
+	l.mtx.Lock()
+	if l.closed {
+		l.mtx.Unlock()
+		_ = pc.Close()
+		return errMeshLinkClosed
+	}
+	l.pc = pc
+	l.mtx.Unlock()
+	l.iceHelper.SetPeerConnection(pc)
+
+	// Each room has a DataChannel labelled with its name
+	pc.OnDataChannel(func(dc *webrtc.DataChannel) {
+		pr := l.getRoom(dc.Label())
+		if pr == nil {
+			slog.Warn("Received DataChannel for unknown room on mesh link", "room", dc.Label(), "peer", l.peerID)
+			_ = dc.Close()
+			return
+		}
+		room := pr.room
+		ndc := connections.NewNestriDataChannel(dc)
+		room.SetDataChannel(ndc)
+		ndc.SetMessageGate(room.AdmitMessage)
+		ndc.RegisterOnOpen(func() {
+			slog.Debug("DataChannel opened for mesh link room", "room", room.Name)
+		})
+		ndc.RegisterOnClose(func() {
+			slog.Debug("DataChannel closed for mesh link room", "room", room.Name)
+		})
+		// Controller feedback from upstream goes to our viewers owning its controller
+		ndc.RegisterMessageCallback("controllerInput", func(data []byte) {
+			l.sp.forwardControllerFeedback(room, data)
+		})
+		ndc.RegisterMessageCallback("stream-clock", func(data []byte) {
+			l.sp.relay.receiveStreamClock(room, data)
+		})
+		ndc.RegisterMessageCallback("input-latency", func(data []byte) {
+			l.sp.relay.receiveInputLatency(room, data)
+		})
+
+		if conn, ok := l.sp.requestedConns.Get(room.Name); ok {
+			conn.ndc = ndc
+		}
+	})
+
+	pc.OnICECandidate(func(candidate *webrtc.ICECandidate) {
+		if candidate == nil {
+			return
+		}
+		if err := sendICECandidate(l.safeBRW, candidate); err != nil {
+			slog.Error("Failed to send ICE candidate message for mesh link", "peer", l.peerID, "err", err)
+		}
+	})
+
+	// Renegotiation gives a new receiver for every new track, so the MID mapping is resolved once per track
+	pc.OnTrack(func(remoteTrack *webrtc.TrackRemote, receiver *webrtc.RTPReceiver) {
+		pr, mid := l.roomForReceiver(receiver)
+		if pr == nil {
+			slog.Warn("Received track without room mapping on mesh link", "peer", l.peerID, "mid", mid)
+			return
+		}
+		room := pr.room
+
+		room.SetCodec(remoteTrack.Kind(), remoteTrack.Codec().RTPCodecCapability)
+		if remoteTrack.Kind() == webrtc.RTPCodecTypeVideo {
+			room.SetVideoSSRC(uint32(remoteTrack.SSRC()))
+		} else {
+			room.SetAudioLevelExtension(common.GetReceivedExtensions(receiver).AudioLevel)
+		}
+		if pr.received.Add(1) >= pr.expected {
+			pr.signal(nil)
+		}
+
+		meter := l.sp.relay.Bandwidth.Meter(l.peerID)
+		for {
+			rtpPacket, _, err := remoteTrack.ReadRTP()
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					slog.Error("Failed to read RTP from mesh link track", "room", room.Name, "err", err)
+				}
+				break
+			}
+			meter.AddIn(len(rtpPacket.Payload))
+			room.BroadcastPacket(remoteTrack.Kind(), rtpPacket)
+		}
+
+		slog.Debug("Mesh link track closed for room", "room", room.Name, "mid", mid, "track_kind", remoteTrack.Kind().String())
+	})
+
+	return nil
+}
+
+// meshServedLink is the serving side of a mesh link, offering rooms to one relay
+type meshServedLink struct {
+	sp        *StreamProtocol
+	peerID    peer.ID
+	stream    network.Stream
+	safeBRW   *common.SafeBufioRW
+	iceHelper *common.ICEHelper
+	pc        *webrtc.PeerConnection
+
+	mtx          sync.Mutex
+	participants map[string]*shared.Participant // room name -> participant feeding the link
+	negotiating  bool                           // Offer sent, waiting for answer
+	pending      bool                           // Rooms changed while negotiating, renegotiate after answer
+	closed       bool
+}
+
+// handleStreamMesh serves rooms to another relay over one shared PeerConnection
+func (sp *StreamProtocol) handleStreamMesh(stream network.Stream) {
+	link := &meshServedLink{
+		sp:           sp,
+		peerID:       stream.Conn().RemotePeer(),
+		stream:       stream,
+		safeBRW:      newStreamRW(stream),
+		iceHelper:    common.NewICEHelper(nil),
+		participants: make(map[string]*shared.Participant),
+	}
+
+	pc, err := common.CreatePeerConnection(func() {
+		slog.Info("PeerConnection closed for served mesh link", "peer", link.peerID)
+		link.close()
+	})
+	if err != nil {
+		slog.Error("Failed to create PeerConnection for served mesh link", "peer", link.peerID, "err", err)
+		_ = stream.Reset()
+		return
+	}
+	link.pc = pc
+	link.iceHelper.SetPeerConnection(pc)
+
+	pc.OnICECandidate(func(candidate *webrtc.ICECandidate) {
+		if candidate == nil {
+			return
+		}
+		if err := sendICECandidate(link.safeBRW, candidate); err != nil {
+			slog.Error("Failed to send ICE candidate message for served mesh link", "peer", link.peerID, "err", err)
+		}
+	})
+	sp.servedMeshLinks.Set(link.peerID, link)
+	defer link.close()
+	for {
+		var msgWrapper gen.ProtoMessage
+		err := link.safeBRW.ReceiveProto(&msgWrapper)
+		if errors.Is(err, common.ErrMessageRejected) {
+			continue
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, network.ErrReset) {
+				slog.Debug("Served mesh link closed by peer", "peer", link.peerID)
+			} else if !link.isClosed() {
+				slog.Error("Failed to receive data for served mesh link", "peer", link.peerID, "err", err)
+			}
+			return
+		}
+
+		if msgWrapper.MessageBase == nil {
+			slog.Error("No MessageBase in served mesh link")
+			continue
+		}
+
+		switch msgWrapper.MessageBase.PayloadType {
+		case "request-stream-room":
+			reqMsg := msgWrapper.GetClientRequestRoomStream()
+			if reqMsg == nil {
+				slog.Error("Could not get ClientRequestRoomStream for mesh link request")
+				continue
+			}
+			// Resolving may pull the room from further upstream, don't block signaling of other rooms
+			go link.serveRoom(reqMsg)
+		case "mesh-release-room":
+			roomName := msgWrapper.GetRaw().GetData()
+			link.mtx.Lock()
+			participant := link.participants[roomName]
+			link.mtx.Unlock()
+			if participant != nil {
+				slog.Debug("Mesh link released room", "room", roomName, "peer", link.peerID)
+				link.removeParticipant(participant)
+			}
+		case "relay-overload":
+			sp.relay.handleOverloadAdvisory(link.peerID, msgWrapper.GetRelayOverload())
+		case "ice-candidate":
+			iceMsg := msgWrapper.GetIce()
+			if iceMsg == nil {
+				slog.Error("Could not GetIce from ice-candidate")
+				continue
+			}
+			link.iceHelper.AddCandidate(iceCandidateFromProto(iceMsg))
+		case "answer":
+			answerMsg := msgWrapper.GetSdp()
+			if answerMsg == nil {
+				slog.Warn("Could not GetSdp from answer")
+				continue
+			}
+			if pc.SignalingState() != webrtc.SignalingStateHaveLocalOffer {
+				slog.Debug("Ignoring answer without outstanding offer on served mesh link", "peer", link.peerID)
+				continue
+			}
+			if err = pc.SetRemoteDescription(webrtc.SessionDescription{
+				SDP:  answerMsg.Sdp.Sdp,
+				Type: webrtc.NewSDPType(answerMsg.Sdp.Type),
+			}); err != nil {
+				slog.Error("Failed to set remote description for served mesh link", "peer", link.peerID, "err", err)
+				return
+			}
+			// Extensions are known once the requester's answer is applied
+			for _, participant := range link.getParticipants() {
+				participant.UpdateExtensions()
+			}
+			// Flush held candidates now if missed before (race-condition)
+			link.iceHelper.FlushHeldCandidates()
+
+			link.mtx.Lock()
+			link.negotiating = false
+			renegotiate := link.pending
+			link.pending = false
+			link.mtx.Unlock()
+			if renegotiate {
+				link.negotiate()
+			}
+		}
+	}
+}
+
+func (l *meshServedLink) isClosed() bool {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.closed
+}
+
+func (l *meshServedLink) getParticipants() []*shared.Participant {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	participants := make([]*shared.Participant, 0, len(l.participants))
+	for _, participant := range l.participants {
+		participants = append(participants, participant)
+	}
+	return participants
+}
+
+// serveRoom adds tracks and DataChannel of a requested room to the link
+func (l *meshServedLink) serveRoom(reqMsg *gen.ProtoClientRequestRoomStream) {
+	roomName := reqMsg.RoomName
+	slog.Info("Received mesh link request for room", "room", roomName, "peer", l.peerID)
+
+	grant, err := l.sp.relay.authorizeStreamRequest(l.peerID, reqMsg)
+	if err != nil {
+		slog.Warn("Refusing unauthorized mesh link request", "room", roomName, "peer", l.peerID, "err", err)
+		sendRoomRefusal(l.safeBRW, roomName, "request-stream-unauthorized")
+		return
+	}
+
+	if l.sp.relay.refuseOverQuota(l.safeBRW, l.peerID, roomName) {
+		return
+	}
+
+	room, refusal := l.sp.resolveServedRoom(roomName, l.peerID)
+	if room == nil {
+		sendRoomRefusal(l.safeBRW, roomName, refusal)
+		return
+	}
+
+	l.mtx.Lock()
+	_, served := l.participants[roomName]
+	closed := l.closed
+	l.mtx.Unlock()
+	if closed || served {
+		return
+	}
+
+	participant, err := shared.NewParticipant(reqMsg.SessionId, l.peerID, room.ParticipantQueueSize(), l.sp.relay.Bandwidth.Meter(l.peerID))
+	if err != nil {
+		slog.Error("Failed to create participant", "room", roomName, "err", err)
+		sendRoomRefusal(l.safeBRW, roomName, "request-stream-offline")
+		return
+	}
+	participant.SetSharedPeerConnection(l.pc, func() {
+		l.release(roomName, participant)
+	})
+	participant.MaxVideoAge = room.MaxVideoAge()
+	participant.ExperimentOptIn = reqMsg.ExperimentOptIn
+	participant.SetRole(grant.Role)
+	participant.Identity = grant.Subject
+
+	// Participant may be moved to another room by admin, follow it
+	upstreamRoom := func() *shared.Room {
+		if current := participant.Room(); current != nil {
+			return current
+		}
+		return room
+	}
+
+	// Add audio/video tracks
+	kinds := []webrtc.RTPCodecType{webrtc.RTPCodecTypeAudio}
+	if !room.GetSettings().AudioOnly {
+		kinds = append(kinds, webrtc.RTPCodecTypeVideo)
+	}
+	for _, kind := range kinds {
+		codec := room.AudioCodec()
+		if kind == webrtc.RTPCodecTypeVideo {
+			codec = room.VideoCodec()
+		}
+		localTrack, err := webrtc.NewTrackLocalStaticRTP(
+			codec,
+			"participant-"+participant.ID.String(),
+			"participant-"+participant.ID.String()+"-"+kind.String(),
+		)
+		if err != nil {
+			slog.Error("Failed to create track for mesh link room", "room", roomName, "err", err)
+			participant.Close()
+			return
+		}
+		participant.SetTrack(kind, localTrack)
+	}
+
+	// DataChannel labelled with room name, so requester can tell them apart
+	settingOrdered := true
+	settingMaxRetransmits := uint16(2)
+	dc, err := l.pc.CreateDataChannel(roomName, &webrtc.DataChannelInit{
+		Ordered:        &settingOrdered,
+		MaxRetransmits: &settingMaxRetransmits,
+	})
+	if err != nil {
+		slog.Error("Failed to create DataChannel for mesh link room", "room", roomName, "err", err)
+		participant.Close()
+		return
+	}
+	ndc := connections.NewNestriDataChannel(dc)
+	ndc.SetMessageGate(participant.AdmitMessage)
+	participant.SetDataChannel(ndc)
+	l.sp.registerInputForwarding(ndc, roomName, participant, upstreamRoom)
+
+	l.mtx.Lock()
+	if l.closed {
+		l.mtx.Unlock()
+		participant.Close()
+		return
+	}
+	l.participants[roomName] = participant
+	l.mtx.Unlock()
+
+	room.AddParticipant(participant)
+	l.sp.relay.Events.Publish(Event{Type: EventViewerJoined, Room: room.Name, PeerID: participant.PeerID, Attrs: map[string]string{
+		"participant": participant.ID.String(),
+	}})
+
+	roomMap, ok := l.sp.servedConns.Get(roomName)
+	if !ok {
+		roomMap = common.NewSafeMap[peer.ID, *StreamConnection]()
+		l.sp.servedConns.Set(roomName, roomMap)
+	}
+	roomMap.Set(l.peerID, &StreamConnection{
+		pc:  l.pc,
+		ndc: ndc,
+	})
+
+	// Let requester know our path, so it can account it's own hop
+	hops, pathLatency := l.sp.relay.roomPath(room)
+	pathMsg, err := common.CreateMessage(
+		&gen.ProtoStreamPathInfo{
+			RoomName:      roomName,
+			Hops:          uint32(hops),
+			PathLatencyUs: uint64(pathLatency.Microseconds()),
+		},
+		"stream-path-info", nil,
+	)
+	if err != nil {
+		slog.Error("Failed to create proto message", "err", err)
+	} else if err = l.safeBRW.SendProto(pathMsg); err != nil {
+		slog.Error("Failed to send path info for mesh link room", "room", roomName, "err", err)
+	}
+
+	l.negotiate()
+}
+
+// removeParticipant takes participant out of its room and closes it, releasing its tracks from the link
+func (l *meshServedLink) removeParticipant(participant *shared.Participant) {
+	if current := participant.Room(); current != nil {
+		current.RemoveParticipantByID(participant.ID)
+		l.sp.relay.Events.Publish(Event{Type: EventViewerLeft, Room: current.Name, PeerID: participant.PeerID, Attrs: map[string]string{
+			"participant": participant.ID.String(),
+		}})
+	}
+	participant.Close()
+}
+
+// release removes tracks of a closed participant from the link, telling requester the room is gone
+func (l *meshServedLink) release(roomName string, participant *shared.Participant) {
+	l.mtx.Lock()
+	if l.participants[roomName] == participant {
+		delete(l.participants, roomName)
+	}
+	closed := l.closed
+	l.mtx.Unlock()
+
+	if roomMap, ok := l.sp.servedConns.Get(roomName); ok {
+		if conn, ok := roomMap.Get(l.peerID); ok && conn.pc == l.pc {
+			roomMap.Delete(l.peerID)
+		}
+		if roomMap.Len() == 0 {
+			l.sp.servedConns.Delete(roomName)
+		}
+	}
+	if closed {
+		return
+	}
+
+	for _, sender := range []*webrtc.RTPSender{participant.AudioSender(), participant.VideoSender()} {
+		if sender == nil {
+			continue
+		}
+		if err := l.pc.RemoveTrack(sender); err != nil {
+			slog.Debug("Failed to remove track from served mesh link", "room", roomName, "err", err)
+		}
+	}
+	// No-op if requester released the room itself
+	sendRoomRefusal(l.safeBRW, roomName, "request-stream-offline")
+	l.negotiate()
+}
+
+// negotiate offers current tracks of the link, after sending room MID mappings
+func (l *meshServedLink) negotiate() {
+	l.mtx.Lock()
+	if l.closed {
+		l.mtx.Unlock()
+		return
+	}
+	if l.negotiating {
+		l.pending = true
+		l.mtx.Unlock()
+		return
+	}
+	l.negotiating = true
+	l.mtx.Unlock()
+
+	offer, err := l.pc.CreateOffer(nil)
+	if err == nil {
+		err = l.pc.SetLocalDescription(offer)
+	}
+	if err != nil {
+		slog.Error("Failed to create offer for served mesh link", "peer", l.peerID, "err", err)
+		l.close()
+		return
+	}
+
+	// MIDs are assigned by setting local description
+	mids := make(map[*webrtc.RTPSender]string)
+	for _, transceiver := range l.pc.GetTransceivers() {
+		if sender := transceiver.Sender(); sender != nil {
+			mids[sender] = transceiver.Mid()
+		}
+	}
+	for roomName, participant := range l.getParticipantMap() {
+		tracksMsg, err := common.CreateMessage(
+			&gen.ProtoMeshRoomTracks{
+				RoomName: roomName,
+				AudioMid: mids[participant.AudioSender()],
+				VideoMid: mids[participant.VideoSender()],
+			},
+			"mesh-room-tracks", nil,
+		)
+		if err != nil {
+			slog.Error("Failed to create proto message", "err", err)
+			continue
+		}
+		if err = l.safeBRW.SendProto(tracksMsg); err != nil {
+			slog.Error("Failed to send mesh room tracks", "room", roomName, "peer", l.peerID, "err", err)
+			l.close()
+			return
+		}
+	}
+
+	if err = sendSDP(l.safeBRW, offer, "offer"); err != nil {
+		slog.Error("Failed to send offer for served mesh link", "peer", l.peerID, "err", err)
+		l.close()
+	}
+}
+
+func (l *meshServedLink) getParticipantMap() map[string]*shared.Participant {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	participants := make(map[string]*shared.Participant, len(l.participants))
+	for roomName, participant := range l.participants {
+		participants[roomName] = participant
+	}
+	return participants
+}
+
+// close tears down the link, removing all its participants from their rooms
+func (l *meshServedLink) close() {
+	l.mtx.Lock()
+	if l.closed {
+		l.mtx.Unlock()
+		return
+	}
+	l.closed = true
+	participants := l.participants
+	l.participants = make(map[string]*shared.Participant)
+	l.mtx.Unlock()
+
+	if current, ok := l.sp.servedMeshLinks.Get(l.peerID); ok && current == l {
+		l.sp.servedMeshLinks.Delete(l.peerID)
+	}
+
+	slog.Debug("Closing served mesh link", "peer", l.peerID, "rooms", len(participants))
+	for _, participant := range participants {
+		l.removeParticipant(participant)
+	}
+	if err := l.pc.Close(); err != nil {
+		slog.Error("Failed to close served mesh link PeerConnection", "peer", l.peerID, "err", err)
+	}
+	_ = l.stream.Reset()
+}
+
+// --- Signaling Helpers ---
+
+// sendSDP sends a local session description as given payload type
+func sendSDP(safeBRW *common.SafeBufioRW, desc webrtc.SessionDescription, payloadType string) error {
+	sdpMsg, err := common.CreateMessage(
+		&gen.ProtoSDP{
+			Sdp: &gen.RTCSessionDescriptionInit{
+				Sdp:  desc.SDP,
+				Type: desc.Type.String(),
+			},
+		},
+		payloadType, nil,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create proto message: %w", err)
+	}
+	return safeBRW.SendProto(sdpMsg)
+}
+
+// sendICECandidate sends a local ICE candidate
+func sendICECandidate(safeBRW *common.SafeBufioRW, candidate *webrtc.ICECandidate) error {
+	candInit := candidate.ToJSON()
+	var sdpMLineIndex *uint32
+	if candInit.SDPMLineIndex != nil {
+		idx := uint32(*candInit.SDPMLineIndex)
+		sdpMLineIndex = &idx
+	}
+	iceMsg, err := common.CreateMessage(
+		&gen.ProtoICE{
+			Candidate: &gen.RTCIceCandidateInit{
+				Candidate:     candInit.Candidate,
+				SdpMLineIndex: sdpMLineIndex,
+				SdpMid:        candInit.SDPMid,
+			},
+		},
+		"ice-candidate", nil,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create proto message: %w", err)
+	}
+	return safeBRW.SendProto(iceMsg)
+}
+
+// iceCandidateFromProto converts a received ICE candidate message
+func iceCandidateFromProto(iceMsg *gen.ProtoICE) webrtc.ICECandidateInit {
+	cand := webrtc.ICECandidateInit{
+		Candidate:        iceMsg.Candidate.Candidate,
+		SDPMid:           iceMsg.Candidate.SdpMid,
+		UsernameFragment: iceMsg.Candidate.UsernameFragment,
+	}
+	if iceMsg.Candidate.SdpMLineIndex != nil {
+		smollified := uint16(*iceMsg.Candidate.SdpMLineIndex)
+		cand.SDPMLineIndex = &smollified
+	}
+	return cand
+}
@@ -1,7 +1,6 @@
 package core
 
 import (
-	"bufio"
 	"context"
 	"errors"
 	"fmt"
@@ -10,6 +9,10 @@ import (
 	"relay/internal/common"
 	"relay/internal/connections"
 	"relay/internal/shared"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	gen "relay/internal/proto"
 
@@ -17,47 +20,113 @@ import (
 
 	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/peer"
-	"github.com/pion/rtp"
 	"github.com/pion/webrtc/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
 )
 
 // TODO:s
-// TODO: When disconnecting with stream open, causes crash on requester
-// TODO: Need to trigger stream request if remote room is online and there are participants in local waiting
-// TODO: Cleanup local room state when stream is closed upstream
 
 // --- Protocol IDs ---
 const (
-	protocolStreamRequest = "/nestri-relay/stream-request/1.0.0" // For requesting a stream from relay
-	protocolStreamPush    = "/nestri-relay/stream-push/1.0.0"    // For pushing a stream to relay
+	protocolStreamRequest = "/nestri-relay/stream-request" // For requesting a stream from relay
+	protocolStreamPush    = "/nestri-relay/stream-push"    // For pushing a stream to relay
 )
 
+// Protocol IDs for clients outside this package, in the latest version
+const (
+	ProtocolStreamRequest = protocolStreamRequest + "/" + latestProtocolVersion // Viewers request room streams with it
+	ProtocolStreamPush    = protocolStreamPush + "/" + latestProtocolVersion    // Pushing nodes push room streams with it
+)
+
+// --- Signaling Progress Stages ---
+const (
+	progressSessionAssigned     = "session-assigned"
+	progressOfferSent           = "offer-sent"
+	progressICEConnected        = "ice-connected"
+	progressDTLSConnected       = "dtls-connected"
+	progressFirstFrameForwarded = "first-frame-forwarded"
+
+	// Failure stages, detail carries the reason
+	progressOfferFailed      = "offer-failed"
+	progressICEFailed        = "ice-failed"
+	progressConnectionFailed = "connection-failed"
+)
+
+// --- Protocol Errors ---
+var (
+	errNoRoomRoute      = errors.New("no mesh route for room")
+	errStreamOffline    = errors.New("requested stream is offline")
+	errStreamOverBudget = errors.New("requested stream path is over latency budget")
+	errStreamDraining   = errors.New("serving relay is draining")
+	errStreamOverQuota  = errors.New("bandwidth quota on serving relay is exceeded")
+)
+
+var ingestFailoverCounter = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "nestri_relay_ingest_failovers_total",
+	Help: "Pushed streams which ended and were taken over by a standby push",
+})
+
+var inputThrottledCounter = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "nestri_relay_input_throttled_total",
+	Help: "Viewer input messages dropped by the input rate limit",
+})
+
+var controllerFeedbackDroppedCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "nestri_relay_controller_feedback_dropped_total",
+	Help: "Controller feedback from the server which couldn't be delivered to the participant owning its slot",
+}, []string{"reason"})
+
 // --- Protocol Types ---
 
 // StreamConnection is a connection between two relays for stream protocol
 type StreamConnection struct {
-	pc  *webrtc.PeerConnection
-	ndc *connections.NestriDataChannel
+	pc          *webrtc.PeerConnection
+	ndc         *connections.NestriDataChannel
+	participant *shared.Participant // Served participant, nil for pushed and requested streams
+	unsubscribe func()              // Tells serving relay to stop serving a requested stream, nil for other streams
+	pusher      peer.ID             // Pushing node of a pushed stream, empty for other streams
+}
+
+// roomPull is a pull of a room stream from the mesh, shared by local requesters of the room while in flight
+type roomPull struct {
+	done chan struct{} // Closed once the pull finished, room and err are set then
+	room *shared.Room
+	err  error
+	refs int // Requesters waiting for the pull, guarded by pullMtx
 }
 
 // StreamProtocol deals with meshed stream forwarding
 type StreamProtocol struct {
-	relay          *Relay
-	servedConns    *common.SafeMap[string, *common.SafeMap[peer.ID, *StreamConnection]] // room name -> (peer ID -> StreamConnection) (for served streams)
-	incomingConns  *common.SafeMap[string, *StreamConnection]                           // room name -> StreamConnection (for incoming pushed streams)
-	requestedConns *common.SafeMap[string, *StreamConnection]                           // room name -> StreamConnection (for requested streams from other relays)
+	relay           *Relay
+	servedConns     *common.SafeMap[string, *common.SafeMap[peer.ID, *StreamConnection]] // room name -> (peer ID -> StreamConnection) (for served streams)
+	incomingConns   *common.SafeMap[string, *StreamConnection]                           // room name -> StreamConnection (for incoming pushed streams)
+	requestedConns  *common.SafeMap[string, *StreamConnection]                           // room name -> StreamConnection (for requested streams from other relays)
+	meshLinks       *common.SafeMap[peer.ID, *meshLink]                                  // peer ID -> mesh link (for rooms pulled over a shared PeerConnection)
+	servedMeshLinks *common.SafeMap[peer.ID, *meshServedLink]                            // peer ID -> served mesh link (for rooms other relays pull from us)
+	waiting         *RoomWaitingList                                                     // Viewer requests waiting for offline rooms
+	meshMtx         sync.Mutex                                                           // Use only for opening/closing mesh links
+	pulls           map[string]*roomPull                                                 // room name -> pull in flight, one upstream is requested per room
+	pullMtx         sync.Mutex                                                           // Guards pulls
+	resumable       *common.SafeMap[string, resumableSession]                            // session ID -> RTP numbering of its departed participant
 }
 
 func NewStreamProtocol(relay *Relay) *StreamProtocol {
 	protocol := &StreamProtocol{
-		relay:          relay,
-		servedConns:    common.NewSafeMap[string, *common.SafeMap[peer.ID, *StreamConnection]](),
-		incomingConns:  common.NewSafeMap[string, *StreamConnection](),
-		requestedConns: common.NewSafeMap[string, *StreamConnection](),
+		relay:           relay,
+		servedConns:     common.NewSafeMap[string, *common.SafeMap[peer.ID, *StreamConnection]](),
+		incomingConns:   common.NewSafeMap[string, *StreamConnection](),
+		requestedConns:  common.NewSafeMap[string, *StreamConnection](),
+		meshLinks:       common.NewSafeMap[peer.ID, *meshLink](),
+		servedMeshLinks: common.NewSafeMap[peer.ID, *meshServedLink](),
+		waiting:         NewRoomWaitingList(),
+		pulls:           make(map[string]*roomPull),
+		resumable:       common.NewSafeMap[string, resumableSession](),
 	}
 
-	protocol.relay.Host.SetStreamHandler(protocolStreamRequest, protocol.handleStreamRequest)
-	protocol.relay.Host.SetStreamHandler(protocolStreamPush, protocol.handleStreamPush)
+	protocol.relay.setVersionedHandler(protocolStreamRequest, protocol.handleStreamRequest)
+	protocol.relay.setVersionedHandler(protocolStreamPush, protocol.handleStreamPush)
+	protocol.relay.setVersionedHandler(protocolStreamMesh, protocol.handleStreamMesh)
 
 	return protocol
 }
@@ -66,14 +135,22 @@ func NewStreamProtocol(relay *Relay) *StreamProtocol {
 
 // handleStreamRequest manages a request from another relay for a stream hosted locally
 func (sp *StreamProtocol) handleStreamRequest(stream network.Stream) {
-	brw := bufio.NewReadWriter(bufio.NewReader(stream), bufio.NewWriter(stream))
-	safeBRW := common.NewSafeBufioRW(brw)
+	safeBRW := newStreamRW(stream)
+
+	if sp.relay.refuseThrottledStream(stream, safeBRW) {
+		return
+	}
+	throttle := newMessageThrottle(sp.relay, stream, safeBRW)
 
 	var currentRoomName string // Track the current room for this stream
 	iceHelper := common.NewICEHelper(nil)
+	defer sp.waiting.Remove(stream)
 	for {
 		var msgWrapper gen.ProtoMessage
 		err := safeBRW.ReceiveProto(&msgWrapper)
+		if errors.Is(err, common.ErrMessageRejected) {
+			continue
+		}
 		if err != nil {
 			if errors.Is(err, io.EOF) || errors.Is(err, network.ErrReset) {
 				slog.Debug("Stream request connection closed by peer", "peer", stream.Conn().RemotePeer())
@@ -91,16 +168,34 @@ func (sp *StreamProtocol) handleStreamRequest(stream network.Stream) {
 			_ = stream.Reset()
 			return
 		}
+		if throttle.drop() {
+			continue
+		}
 
 		switch msgWrapper.MessageBase.PayloadType {
 		case "request-stream-room":
 			reqMsg := msgWrapper.GetClientRequestRoomStream()
 			if reqMsg != nil {
 				currentRoomName = reqMsg.RoomName
+				sp.waiting.Remove(stream) // New request replaces one still waiting for its room
+
+				if ban, banned := sp.relay.RoomBanned(shared.BaseRoomName(reqMsg.RoomName), reqMsg.SessionId, stream.Conn().RemotePeer()); banned {
+					slog.Warn("Refusing stream request of banned viewer", "room", reqMsg.RoomName, "session", reqMsg.SessionId, "peer", stream.Conn().RemotePeer(), "ban", ban.ID)
+					sendBanRefusal(safeBRW, ban)
+					continue
+				}
+				if _, err := sp.relay.directoryRoom(reqMsg.RoomName); err != nil {
+					slog.Warn("Refusing stream request for room refused by room directory", "room", reqMsg.RoomName, "err", err)
+					sendRoomRefusal(safeBRW, reqMsg.RoomName, "request-stream-unauthorized")
+					continue
+				}
+				if sp.relay.refuseOverQuota(safeBRW, stream.Conn().RemotePeer(), reqMsg.RoomName) {
+					continue
+				}
 
-				// Generate session ID if not provided (first connection)
+				// Generate session ID if not provided (first connection) or invalidated by a kick
 				sessionID := reqMsg.SessionId
-				if sessionID == "" {
+				if sessionID == "" || sp.relay.sessionRevoked(sessionID) {
 					ulid, err := common.NewULID()
 					if err != nil {
 						slog.Error("Failed to generate session ID", "err", err)
@@ -111,6 +206,11 @@ func (sp *StreamProtocol) handleStreamRequest(stream network.Stream) {
 
 				slog.Info("Client session requested room stream", "session", sessionID, "room", reqMsg.RoomName)
 
+				requested := time.Now()
+				progress := func(stage, detail string) {
+					sendSignalingProgress(safeBRW, reqMsg.RoomName, stage, detail, requested)
+				}
+
 				// Send session ID back to client
 				sesMsg, err := common.CreateMessage(
 					&gen.ProtoClientRequestRoomStream{SessionId: sessionID, RoomName: reqMsg.RoomName},
@@ -123,234 +223,46 @@ func (sp *StreamProtocol) handleStreamRequest(stream network.Stream) {
 				if err = safeBRW.SendProto(sesMsg); err != nil {
 					slog.Error("Failed to send session assignment", "err", err)
 				}
+				progress(progressSessionAssigned, "")
 
 				slog.Info("Received stream request for room", "room", reqMsg.RoomName)
 
-				room := sp.relay.GetRoomByName(reqMsg.RoomName)
-				if room == nil || !room.IsOnline() || room.OwnerID != sp.relay.ID {
-					// TODO: Allow forward requests to other relays from here?
-					slog.Debug("Cannot provide stream for nil, offline or non-owned room", "room", reqMsg.RoomName, "is_online", room != nil && room.IsOnline(), "is_owner", room != nil && room.OwnerID == sp.relay.ID)
-					// Respond with "request-stream-offline" message with room name
-					// TODO: Store the peer and send "online" message when the room comes online
-					rawMsg, err := common.CreateMessage(
-						&gen.ProtoRaw{
-							Data: reqMsg.RoomName,
-						},
-						"request-stream-offline", nil,
-					)
-					if err != nil {
-						slog.Error("Failed to create proto message", "err", err)
-						continue
-					}
-					if err = safeBRW.SendProto(rawMsg); err != nil {
-						slog.Error("Failed to send request stream offline message", "room", reqMsg.RoomName, "err", err)
-					}
-					continue
-				}
-
-				pc, err := common.CreatePeerConnection(func() {
-					slog.Info("PeerConnection closed for requested stream", "room", reqMsg.RoomName)
-					// Cleanup the stream connection
-					if roomMap, ok := sp.servedConns.Get(reqMsg.RoomName); ok {
-						roomMap.Delete(stream.Conn().RemotePeer())
-						// If the room map is empty, delete it
-						if roomMap.Len() == 0 {
-							sp.servedConns.Delete(reqMsg.RoomName)
-						}
-					}
-				})
-				if err != nil {
-					slog.Error("Failed to create PeerConnection for requested stream", "room", reqMsg.RoomName, "err", err)
-					continue
-				}
-
-				// Create participant for this viewer
-				participant, err := shared.NewParticipant(
-					sessionID,
-					stream.Conn().RemotePeer(),
-				)
-				if err != nil {
-					slog.Error("Failed to create participant", "room", reqMsg.RoomName, "err", err)
-					continue
-				}
-
-				// Assign peer connection
-				participant.PeerConnection = pc
-				iceHelper.SetPeerConnection(pc)
-
-				// Add audio/video tracks
-				{
-					localTrack, err := webrtc.NewTrackLocalStaticRTP(
-						room.AudioCodec,
-						"participant-"+participant.ID.String(),
-						"participant-"+participant.ID.String()+"-audio",
-					)
-					if err != nil {
-						slog.Error("Failed to create track for stream request", "err", err)
-						return
-					}
-					participant.SetTrack(webrtc.RTPCodecTypeAudio, localTrack)
-					slog.Debug("Set audio track for requested stream", "room", room.Name)
-				}
-				{
-					localTrack, err := webrtc.NewTrackLocalStaticRTP(
-						room.VideoCodec,
-						"participant-"+participant.ID.String(),
-						"participant-"+participant.ID.String()+"-video",
-					)
-					if err != nil {
-						slog.Error("Failed to create track for stream request", "err", err)
-						return
-					}
-					participant.SetTrack(webrtc.RTPCodecTypeVideo, localTrack)
-					slog.Debug("Set video track for requested stream", "room", room.Name)
-				}
-
-				// Cleanup on disconnect
-				cleanupParticipantID := participant.ID
-				pc.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
-					if state == webrtc.PeerConnectionStateClosed ||
-						state == webrtc.PeerConnectionStateFailed ||
-						state == webrtc.PeerConnectionStateDisconnected {
-						slog.Info("Participant disconnected from room", "room", reqMsg.RoomName, "participant", cleanupParticipantID)
-						room.RemoveParticipantByID(cleanupParticipantID)
-						participant.Close()
-					} else if state == webrtc.PeerConnectionStateConnected {
-						// Add participant to room when connection is established
-						room.AddParticipant(participant)
-					}
-				})
-
-				// DataChannel setup
-				settingOrdered := true
-				settingMaxRetransmits := uint16(2)
-				dc, err := pc.CreateDataChannel("relay-data", &webrtc.DataChannelInit{
-					Ordered:        &settingOrdered,
-					MaxRetransmits: &settingMaxRetransmits,
-				})
+				grant, err := sp.relay.authorizeStreamRequest(stream.Conn().RemotePeer(), reqMsg)
 				if err != nil {
-					slog.Error("Failed to create DataChannel for requested stream", "room", reqMsg.RoomName, "err", err)
+					slog.Warn("Refusing unauthorized stream request", "room", reqMsg.RoomName, "session", sessionID, "err", err)
+					sendRoomRefusal(safeBRW, reqMsg.RoomName, "request-stream-unauthorized")
 					continue
 				}
-				ndc := connections.NewNestriDataChannel(dc)
-
-				ndc.RegisterOnOpen(func() {
-					slog.Debug("Relay DataChannel opened for requested stream", "room", reqMsg.RoomName)
-				})
-				ndc.RegisterOnClose(func() {
-					slog.Debug("Relay DataChannel closed for requested stream", "room", reqMsg.RoomName)
-				})
-				ndc.RegisterMessageCallback("input", func(data []byte) {
-					if room.DataChannel != nil {
-						if err = room.DataChannel.SendBinary(data); err != nil {
-							slog.Error("Failed to forward input message from mesh to upstream room", "room", reqMsg.RoomName, "err", err)
-						}
-					}
-				})
-				// Track controller input separately
-				ndc.RegisterMessageCallback("controllerInput", func(data []byte) {
-					// Parse the message to track controller slots for client sessions
-					var controllerMsgWrapper gen.ProtoMessage
-					if err = proto.Unmarshal(data, &controllerMsgWrapper); err != nil {
-						slog.Error("Failed to unmarshal controller input", "err", err)
-					}
-
-					// Forward to upstream room
-					if room.DataChannel != nil {
-						if err = room.DataChannel.SendBinary(data); err != nil {
-							slog.Error("Failed to forward controller input from mesh to upstream room", "room", reqMsg.RoomName, "err", err)
-						}
-					}
-				})
 
-				// ICE Candidate handling
-				pc.OnICECandidate(func(candidate *webrtc.ICECandidate) {
-					if candidate == nil {
-						return
-					}
-
-					candInit := candidate.ToJSON()
-					var sdpMLineIndex *uint32
-					if candInit.SDPMLineIndex != nil {
-						idx := uint32(*candInit.SDPMLineIndex)
-						sdpMLineIndex = &idx
-					}
-					iceMsg, err := common.CreateMessage(
-						&gen.ProtoICE{
-							Candidate: &gen.RTCIceCandidateInit{
-								Candidate:     candInit.Candidate,
-								SdpMLineIndex: sdpMLineIndex,
-								SdpMid:        candInit.SDPMid,
-							},
-						},
-						"ice-candidate", nil,
-					)
-					if err != nil {
-						slog.Error("Failed to create proto message", "err", err)
-						return
-					}
-					if err = safeBRW.SendProto(iceMsg); err != nil {
-						slog.Error("Failed to send ICE candidate message for requested stream", "room", reqMsg.RoomName, "err", err)
-						return
+				// Viewers of rooms another relay serves much closer to them are sent there
+				if safeBRW.Peer().Supports(common.CapabilityBetterRelay) && !sp.relay.isMeshRelay(stream.Conn().RemotePeer()) {
+					if better := sp.relay.betterRelay(reqMsg.RoomName, stream.Conn()); better != nil {
+						slog.Info("Steering viewer to closer relay", "room", reqMsg.RoomName, "session", sessionID, "relay", better.PeerId)
+						sendBetterRelay(safeBRW, better)
+						continue
 					}
-				})
-
-				// Create offer
-				offer, err := pc.CreateOffer(nil)
-				if err != nil {
-					slog.Error("Failed to create offer for requested stream", "room", reqMsg.RoomName, "err", err)
-					continue
 				}
-				if err = pc.SetLocalDescription(offer); err != nil {
-					slog.Error("Failed to set local description for requested stream", "room", reqMsg.RoomName, "err", err)
-					continue
-				}
-				offerMsg, err := common.CreateMessage(
-					&gen.ProtoSDP{
-						Sdp: &gen.RTCSessionDescriptionInit{
-							Sdp:  offer.SDP,
-							Type: offer.Type.String(),
-						},
-					},
-					"offer", nil,
-				)
-				if err != nil {
-					slog.Error("Failed to create proto message", "err", err)
-					continue
-				}
-				if err = safeBRW.SendProto(offerMsg); err != nil {
-					slog.Error("Failed to send offer for requested stream", "room", reqMsg.RoomName, "err", err)
-					continue
-				}
-
-				// Store the connection
-				roomMap, ok := sp.servedConns.Get(reqMsg.RoomName)
-				if !ok {
-					roomMap = common.NewSafeMap[peer.ID, *StreamConnection]()
-					sp.servedConns.Set(reqMsg.RoomName, roomMap)
-				}
-				roomMap.Set(stream.Conn().RemotePeer(), &StreamConnection{
-					pc:  pc,
-					ndc: ndc,
-				})
 
-				slog.Debug("Sent offer for requested stream")
+				sp.serveStreamRequest(stream, safeBRW, iceHelper, reqMsg, sessionID, grant, progress)
 			} else {
 				slog.Error("Could not get ClientRequestRoomStream for stream request")
 			}
+		case "request-stream-unsubscribe":
+			// Requesting relay has no local participants left for the room
+			roomName := msgWrapper.GetRaw().GetData()
+			if roomMap, ok := sp.servedConns.Get(roomName); ok {
+				if conn, ok := roomMap.Get(stream.Conn().RemotePeer()); ok {
+					slog.Debug("Requester unsubscribed from room", "room", roomName, "peer", stream.Conn().RemotePeer())
+					// Closing the PeerConnection removes the participant
+					if err = conn.pc.Close(); err != nil {
+						slog.Error("Failed to close PeerConnection of unsubscribed stream", "room", roomName, "err", err)
+					}
+				}
+			}
 		case "ice-candidate":
 			iceMsg := msgWrapper.GetIce()
 			if iceMsg != nil {
-				cand := webrtc.ICECandidateInit{
-					Candidate:        iceMsg.Candidate.Candidate,
-					SDPMid:           iceMsg.Candidate.SdpMid,
-					UsernameFragment: iceMsg.Candidate.UsernameFragment,
-				}
-				if iceMsg.Candidate.SdpMLineIndex != nil {
-					smollified := uint16(*iceMsg.Candidate.SdpMLineIndex)
-					cand.SDPMLineIndex = &smollified
-				}
-				iceHelper.AddCandidate(cand)
+				iceHelper.AddCandidate(iceCandidateFromProto(iceMsg))
 			} else {
 				slog.Error("Could not GetIce from ice-candidate")
 			}
@@ -365,11 +277,20 @@ func (sp *StreamProtocol) handleStreamRequest(stream network.Stream) {
 				if len(currentRoomName) > 0 {
 					if roomMap, ok := sp.servedConns.Get(currentRoomName); ok {
 						if conn, ok := roomMap.Get(stream.Conn().RemotePeer()); ok {
+							// An answer without our offer outstanding was resent, applying it would fail negotiation
+							if conn.pc.SignalingState() != webrtc.SignalingStateHaveLocalOffer {
+								slog.Debug("Ignoring answer without outstanding offer", "room", currentRoomName)
+								continue
+							}
 							if err = conn.pc.SetRemoteDescription(ansSdp); err != nil {
 								slog.Error("Failed to set remote description for answer", "err", err)
 								continue
 							}
 							slog.Debug("Set remote description for answer")
+							// Extensions are known once the viewer's answer is applied
+							if conn.participant != nil {
+								conn.participant.UpdateExtensions()
+							}
 							// Flush held candidates now if missed before (race-condition)
 							iceHelper.FlushHeldCandidates()
 						} else {
@@ -386,22 +307,318 @@ func (sp *StreamProtocol) handleStreamRequest(stream network.Stream) {
 	}
 }
 
+// serveStreamRequest sets up a participant for an authorized stream request and sends the offer,
+// requests for offline rooms are put on the room waiting list and served once the room comes online
+func (sp *StreamProtocol) serveStreamRequest(stream network.Stream, safeBRW *common.SafeBufioRW, iceHelper *common.ICEHelper, reqMsg *gen.ProtoClientRequestRoomStream, sessionID string, grant ViewerGrant, progress func(stage, detail string)) {
+	room, refusal := sp.resolveServedRoom(reqMsg.RoomName, stream.Conn().RemotePeer())
+	if room == nil {
+		sendRoomRefusal(safeBRW, reqMsg.RoomName, refusal)
+		// Relays pulling the room try other routes themselves, viewers wait for the room to come online
+		if refusal == "request-stream-offline" && !sp.relay.isMeshRelay(stream.Conn().RemotePeer()) {
+			sp.waitForRoom(stream, reqMsg.RoomName, sessionID, func() {
+				sendRoomRefusal(safeBRW, reqMsg.RoomName, "request-stream-online")
+				// Access secret of a room isn't known before it's first pushed, and bans may have come while waiting
+				if ban, banned := sp.relay.RoomBanned(shared.BaseRoomName(reqMsg.RoomName), sessionID, stream.Conn().RemotePeer()); banned {
+					slog.Warn("Refusing waiting stream request of banned viewer", "room", reqMsg.RoomName, "session", sessionID, "ban", ban.ID)
+					sendBanRefusal(safeBRW, ban)
+					return
+				}
+				grant, err := sp.relay.authorizeStreamRequest(stream.Conn().RemotePeer(), reqMsg)
+				if err != nil {
+					slog.Warn("Refusing unauthorized waiting stream request", "room", reqMsg.RoomName, "session", sessionID, "err", err)
+					sendRoomRefusal(safeBRW, reqMsg.RoomName, "request-stream-unauthorized")
+					return
+				}
+				sp.serveStreamRequest(stream, safeBRW, iceHelper, reqMsg, sessionID, grant, progress)
+			})
+		}
+		return
+	}
+
+	// Relays pulling the room forward it to their own viewers, they enforce the limit there
+	if full := sp.relay.roomFull(room); full != nil && !sp.relay.isMeshRelay(stream.Conn().RemotePeer()) {
+		slog.Warn("Refusing stream request to full room", "room", reqMsg.RoomName, "session", sessionID, "viewers", full.ViewerCount, "max", full.MaxViewers)
+		sendRoomFull(safeBRW, full)
+		return
+	}
+
+	// Cleanup the stream connection
+	forgetServed := func() {
+		if roomMap, ok := sp.servedConns.Get(reqMsg.RoomName); ok {
+			roomMap.Delete(stream.Conn().RemotePeer())
+			// If the room map is empty, delete it
+			if roomMap.Len() == 0 {
+				sp.servedConns.Delete(reqMsg.RoomName)
+			}
+		}
+	}
+	pc, err := common.CreatePeerConnection(func() {
+		slog.Info("PeerConnection closed for requested stream", "room", reqMsg.RoomName)
+		forgetServed()
+	})
+	if err != nil {
+		slog.Error("Failed to create PeerConnection for requested stream", "room", reqMsg.RoomName, "err", err)
+		return
+	}
+
+	// Create participant for this viewer
+	participant, err := shared.NewParticipant(
+		sessionID,
+		stream.Conn().RemotePeer(),
+		room.ParticipantQueueSize(),
+		sp.relay.Bandwidth.Meter(stream.Conn().RemotePeer()),
+	)
+	if err != nil {
+		slog.Error("Failed to create participant", "room", reqMsg.RoomName, "err", err)
+		return
+	}
+
+	// Assign peer connection
+	participant.PeerConnection = pc
+	participant.MaxVideoAge = room.MaxVideoAge()
+	participant.ExperimentOptIn = reqMsg.ExperimentOptIn
+	participant.SetRole(grant.Role)
+	participant.Identity = grant.Subject
+	participant.OnFirstFrame = func() {
+		progress(progressFirstFrameForwarded, "")
+	}
+	iceHelper.SetPeerConnection(pc)
+
+	// Participant may be moved to another room by admin, follow it
+	upstreamRoom := func() *shared.Room {
+		if current := participant.Room(); current != nil {
+			return current
+		}
+		return room
+	}
+
+	// Add audio/video tracks
+	{
+		localTrack, err := webrtc.NewTrackLocalStaticRTP(
+			room.AudioCodec(),
+			"participant-"+participant.ID.String(),
+			"participant-"+participant.ID.String()+"-audio",
+		)
+		if err != nil {
+			slog.Error("Failed to create track for stream request", "err", err)
+			return
+		}
+		participant.SetTrack(webrtc.RTPCodecTypeAudio, localTrack)
+		slog.Debug("Set audio track for requested stream", "room", room.Name)
+	}
+	if !room.GetSettings().AudioOnly {
+		localTrack, err := webrtc.NewTrackLocalStaticRTP(
+			room.VideoCodec(),
+			"participant-"+participant.ID.String(),
+			"participant-"+participant.ID.String()+"-video",
+		)
+		if err != nil {
+			slog.Error("Failed to create track for stream request", "err", err)
+			return
+		}
+		participant.SetTrack(webrtc.RTPCodecTypeVideo, localTrack)
+		slog.Debug("Set video track for requested stream", "room", room.Name)
+	}
+
+	pc.OnICEConnectionStateChange(func(state webrtc.ICEConnectionState) {
+		switch state {
+		case webrtc.ICEConnectionStateConnected:
+			progress(progressICEConnected, "")
+		case webrtc.ICEConnectionStateFailed:
+			progress(progressICEFailed, "no working candidate pair")
+		default:
+		}
+	})
+
+	// Cleanup on disconnect
+	cleanupParticipantID := participant.ID
+	pc.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
+		if state == webrtc.PeerConnectionStateClosed ||
+			state == webrtc.PeerConnectionStateFailed ||
+			state == webrtc.PeerConnectionStateDisconnected {
+			slog.Info("Participant disconnected from room", "room", reqMsg.RoomName, "participant", cleanupParticipantID)
+			if state == webrtc.PeerConnectionStateFailed {
+				progress(progressConnectionFailed, "peer connection failed")
+			}
+			// Replaces the handler of CreatePeerConnection, served connection is forgotten here too
+			forgetServed()
+			if current := participant.Room(); current != nil {
+				current.RemoveParticipantByID(cleanupParticipantID)
+				sp.rememberSession(participant)
+				sp.relay.Events.Publish(Event{Type: EventViewerLeft, Room: current.Name, PeerID: participant.PeerID, Attrs: map[string]string{
+					"participant": cleanupParticipantID.String(),
+				}})
+			}
+			participant.Close()
+		} else if state == webrtc.PeerConnectionStateConnected {
+			// Connected state means ICE and DTLS are both up
+			progress(progressDTLSConnected, "")
+			// Add participant to room when connection is established, continuing RTP numbering of a resumed session
+			sp.resumeSession(reqMsg.RoomName, participant)
+			room.AddParticipant(participant)
+			sp.relay.Events.Publish(Event{Type: EventViewerJoined, Room: room.Name, PeerID: participant.PeerID, Attrs: map[string]string{
+				"participant": cleanupParticipantID.String(),
+			}})
+		}
+	})
+
+	// DataChannel setup
+	settingOrdered := true
+	settingMaxRetransmits := uint16(2)
+	dc, err := pc.CreateDataChannel("relay-data", &webrtc.DataChannelInit{
+		Ordered:        &settingOrdered,
+		MaxRetransmits: &settingMaxRetransmits,
+	})
+	if err != nil {
+		slog.Error("Failed to create DataChannel for requested stream", "room", reqMsg.RoomName, "err", err)
+		return
+	}
+	ndc := connections.NewNestriDataChannel(dc)
+	ndc.SetMessageGate(participant.AdmitMessage)
+	participant.SetDataChannel(ndc)
+
+	ndc.RegisterOnOpen(func() {
+		slog.Debug("Relay DataChannel opened for requested stream", "room", reqMsg.RoomName)
+		if !sp.relay.isMeshRelay(stream.Conn().RemotePeer()) {
+			sp.relay.offerRoomVariants(participant, upstreamRoom().Name)
+		}
+	})
+	ndc.RegisterOnClose(func() {
+		slog.Debug("Relay DataChannel closed for requested stream", "room", reqMsg.RoomName)
+	})
+	sp.registerInputForwarding(ndc, reqMsg.RoomName, participant, upstreamRoom)
+	// Chat and variant switching are for viewers, relays pulling the room get chat over the mesh chat topic instead
+	if !sp.relay.isMeshRelay(stream.Conn().RemotePeer()) {
+		sp.registerChat(ndc, participant)
+		sp.registerVariantSwitching(ndc, participant)
+	}
+
+	// ICE Candidate handling
+	pc.OnICECandidate(func(candidate *webrtc.ICECandidate) {
+		if candidate == nil {
+			return
+		}
+
+		candInit := candidate.ToJSON()
+		var sdpMLineIndex *uint32
+		if candInit.SDPMLineIndex != nil {
+			idx := uint32(*candInit.SDPMLineIndex)
+			sdpMLineIndex = &idx
+		}
+		iceMsg, err := common.CreateMessage(
+			&gen.ProtoICE{
+				Candidate: &gen.RTCIceCandidateInit{
+					Candidate:     candInit.Candidate,
+					SdpMLineIndex: sdpMLineIndex,
+					SdpMid:        candInit.SDPMid,
+				},
+			},
+			"ice-candidate", nil,
+		)
+		if err != nil {
+			slog.Error("Failed to create proto message", "err", err)
+			return
+		}
+		if err = safeBRW.SendProto(iceMsg); err != nil {
+			slog.Error("Failed to send ICE candidate message for requested stream", "room", reqMsg.RoomName, "err", err)
+			return
+		}
+	})
+
+	// Store the connection before offering, requests served off the waiting list get answers concurrently
+	roomMap, ok := sp.servedConns.Get(reqMsg.RoomName)
+	if !ok {
+		roomMap = common.NewSafeMap[peer.ID, *StreamConnection]()
+		sp.servedConns.Set(reqMsg.RoomName, roomMap)
+	}
+	roomMap.Set(stream.Conn().RemotePeer(), &StreamConnection{
+		pc:          pc,
+		ndc:         ndc,
+		participant: participant,
+	})
+
+	// Create offer
+	offer, err := pc.CreateOffer(nil)
+	if err != nil {
+		slog.Error("Failed to create offer for requested stream", "room", reqMsg.RoomName, "err", err)
+		progress(progressOfferFailed, err.Error())
+		return
+	}
+	if err = pc.SetLocalDescription(offer); err != nil {
+		slog.Error("Failed to set local description for requested stream", "room", reqMsg.RoomName, "err", err)
+		progress(progressOfferFailed, err.Error())
+		return
+	}
+	offerMsg, err := common.CreateMessage(
+		&gen.ProtoSDP{
+			Sdp: &gen.RTCSessionDescriptionInit{
+				Sdp:  offer.SDP,
+				Type: offer.Type.String(),
+			},
+		},
+		"offer", nil,
+	)
+	if err != nil {
+		slog.Error("Failed to create proto message", "err", err)
+		return
+	}
+	if err = safeBRW.SendProto(offerMsg); err != nil {
+		slog.Error("Failed to send offer for requested stream", "room", reqMsg.RoomName, "err", err)
+		return
+	}
+	progress(progressOfferSent, "")
+
+	// Let requester know our path, so it can account it's own hop
+	hops, pathLatency := sp.relay.roomPath(room)
+	pathMsg, err := common.CreateMessage(
+		&gen.ProtoStreamPathInfo{
+			RoomName:      reqMsg.RoomName,
+			Hops:          uint32(hops),
+			PathLatencyUs: uint64(pathLatency.Microseconds()),
+		},
+		"stream-path-info", nil,
+	)
+	if err != nil {
+		slog.Error("Failed to create proto message", "err", err)
+		return
+	}
+	if err = safeBRW.SendProto(pathMsg); err != nil {
+		slog.Error("Failed to send path info for requested stream", "room", reqMsg.RoomName, "err", err)
+		return
+	}
+
+	// Encoder health for the viewer
+	go sendStreamStats(safeBRW, upstreamRoom, participant, pc)
+
+	slog.Debug("Sent offer for requested stream")
+}
+
 // handleStreamPush manages a stream push from a node (nestri-server)
 func (sp *StreamProtocol) handleStreamPush(stream network.Stream) {
-	brw := bufio.NewReadWriter(bufio.NewReader(stream), bufio.NewWriter(stream))
-	safeBRW := common.NewSafeBufioRW(brw)
+	safeBRW := newStreamRW(stream)
+
+	if sp.relay.refuseThrottledStream(stream, safeBRW) {
+		return
+	}
+	throttle := newMessageThrottle(sp.relay, stream, safeBRW)
 
 	var room *shared.Room
+	var ingest *webrtc.PeerConnection          // Of the pushed stream, set by "offer"
+	var standby bool                           // Push stands by for the pushed stream of the room
+	var pendingPush *gen.ProtoServerPushStream // Push waiting for its challenge response
+	var challenge string                       // Nonce of the last push challenge
 	iceHelper := common.NewICEHelper(nil)
 	for {
 		var msgWrapper gen.ProtoMessage
 		err := safeBRW.ReceiveProto(&msgWrapper)
+		if errors.Is(err, common.ErrMessageRejected) {
+			continue
+		}
 		if err != nil {
 			if errors.Is(err, io.EOF) || errors.Is(err, network.ErrReset) {
-				slog.Debug("Stream push connection closed by peer", "peer", stream.Conn().RemotePeer(), "error", err)
+				slog.Debug("Stream push connection closed by peer", "peer", stream.Conn().RemotePeer(), "err", err)
 				if room != nil {
-					room.Close()
-					sp.incomingConns.Delete(room.Name)
+					sp.endPush(room, ingest, standby)
 				}
 				return
 			}
@@ -409,8 +626,7 @@ func (sp *StreamProtocol) handleStreamPush(stream network.Stream) {
 			slog.Error("Failed to receive data for stream push", "err", err)
 			_ = stream.Reset()
 			if room != nil {
-				room.Close()
-				sp.incomingConns.Delete(room.Name)
+				sp.endPush(room, ingest, standby)
 			}
 			return
 		}
@@ -419,6 +635,9 @@ func (sp *StreamProtocol) handleStreamPush(stream network.Stream) {
 			slog.Error("No MessageBase in stream push")
 			continue
 		}
+		if throttle.drop() {
+			continue
+		}
 
 		switch msgWrapper.MessageBase.PayloadType {
 		case "push-stream-room":
@@ -426,50 +645,68 @@ func (sp *StreamProtocol) handleStreamPush(stream network.Stream) {
 			if pushMsg != nil {
 				slog.Info("Received stream push request for room", "room", pushMsg.RoomName)
 
-				room = sp.relay.GetRoomByName(pushMsg.RoomName)
-				if room != nil {
-					if room.OwnerID != sp.relay.ID {
-						slog.Error("Cannot push a stream to non-owned room", "room", room.Name, "owner_id", room.OwnerID)
-						continue
-					}
-					if room.IsOnline() {
-						slog.Error("Cannot push a stream to already online room", "room", room.Name)
+				if sp.relay.IsDraining() {
+					slog.Warn("Refusing stream push while draining", "room", pushMsg.RoomName, "peer", stream.Conn().RemotePeer())
+					sendRoomRefusal(safeBRW, pushMsg.RoomName, "push-stream-unauthorized")
+					continue
+				}
+				// Authenticate before the push can create or take over a room
+				if err = sp.relay.authorizePush(pushMsg); err != nil {
+					slog.Warn("Refusing unauthenticated stream push", "room", pushMsg.RoomName, "peer", stream.Conn().RemotePeer(), "err", err)
+					sendRoomRefusal(safeBRW, pushMsg.RoomName, "push-stream-unauthorized")
+					continue
+				}
+				// Pushing nodes must prove a trusted push key before the push is accepted
+				if sp.relay.pushKeys != nil {
+					if challenge, err = sendPushChallenge(safeBRW, pushMsg.RoomName); err != nil {
+						slog.Error("Failed to send push challenge", "room", pushMsg.RoomName, "err", err)
 						continue
 					}
-				} else {
-					// Create a new room if it doesn't exist
-					room = sp.relay.CreateRoom(pushMsg.RoomName)
+					pendingPush = pushMsg
+					continue
 				}
-
-				// Respond with an OK with the room name
-				resMsg, err := common.CreateMessage(
-					&gen.ProtoServerPushStream{
-						RoomName: pushMsg.RoomName,
-					},
-					"push-stream-ok", nil,
-				)
-				if err != nil {
-					slog.Error("Failed to create proto message", "err", err)
+				if accepted := sp.acceptPush(safeBRW, pushMsg); accepted != nil {
+					room, standby = accepted, pushMsg.Standby
+				}
+			} else {
+				slog.Error("Failed to GetServerPushStream in push-stream-room")
+			}
+		case "push-challenge-response":
+			resMsg := msgWrapper.GetPushChallengeResponse()
+			if resMsg == nil || pendingPush == nil || resMsg.RoomName != pendingPush.RoomName {
+				slog.Error("Received push challenge response without pending push", "peer", stream.Conn().RemotePeer())
+				continue
+			}
+			pushMsg := pendingPush
+			pendingPush = nil
+			if err = sp.relay.pushKeys.Authorize(sp.relay.ID, pushMsg.RoomName, challenge, resMsg); err != nil {
+				slog.Warn("Refusing stream push failing push challenge", "room", pushMsg.RoomName, "peer", stream.Conn().RemotePeer(), "err", err)
+				sendRoomRefusal(safeBRW, pushMsg.RoomName, "push-stream-unauthorized")
+				continue
+			}
+			if accepted := sp.acceptPush(safeBRW, pushMsg); accepted != nil {
+				room, standby = accepted, pushMsg.Standby
+			}
+		case "room-metadata":
+			metaMsg := msgWrapper.GetRoomMetadata()
+			if metaMsg != nil {
+				// Make sure we have room set to update (set by "push-stream-room")
+				if room == nil {
+					slog.Error("Received room metadata without room set for stream push")
 					continue
 				}
-				if err = safeBRW.SendProto(resMsg); err != nil {
-					slog.Error("Failed to send push stream OK response", "room", room.Name, "err", err)
+				// Standby pushes update metadata only once they took over the room
+				if standby && (ingest == nil || room.PeerConnection() != ingest) {
 					continue
 				}
+				sp.relay.UpdateRoomMetadata(room, metaMsg)
 			} else {
-				slog.Error("Failed to GetServerPushStream in push-stream-room")
+				slog.Error("Failed to GetRoomMetadata in room-metadata")
 			}
 		case "ice-candidate":
 			iceMsg := msgWrapper.GetIce()
 			if iceMsg != nil {
-				smollified := uint16(*iceMsg.Candidate.SdpMLineIndex)
-				cand := webrtc.ICECandidateInit{
-					Candidate:        iceMsg.Candidate.Candidate,
-					SDPMid:           iceMsg.Candidate.SdpMid,
-					SDPMLineIndex:    &smollified,
-					UsernameFragment: iceMsg.Candidate.UsernameFragment,
-				}
-				iceHelper.AddCandidate(cand)
+				iceHelper.AddCandidate(iceCandidateFromProto(iceMsg))
 			} else {
 				slog.Error("Failed to GetIce in pushed stream ice-candidate")
 			}
@@ -487,58 +724,61 @@ func (sp *StreamProtocol) handleStreamPush(stream network.Stream) {
 					Type: webrtc.NewSDPType(offerMsg.Sdp.Type),
 				}
 				// Create PeerConnection for the incoming stream
-				pc, err := common.CreatePeerConnection(func() {
-					slog.Info("PeerConnection closed for pushed stream", "room", room.Name)
-					// Cleanup the stream connection
-					if ok := sp.incomingConns.Has(room.Name); ok {
-						sp.incomingConns.Delete(room.Name)
-					}
+				standbyPush := standby
+				var pc *webrtc.PeerConnection
+				pc, err = common.CreatePeerConnection(func() {
+					slog.Info("PeerConnection closed for pushed stream", "room", room.Name, "standby", standbyPush)
+					sp.endPush(room, pc, standbyPush)
 				})
 				if err != nil {
 					slog.Error("Failed to create PeerConnection for pushed stream", "room", room.Name, "err", err)
 					continue
 				}
 
-				// Assign room peer connection
-				room.PeerConnection = pc
+				// Assign room peer connection, standby pushes are only forwarded from once they took over
+				if standbyPush {
+					if !room.SetStandbyPeerConnection(pc) {
+						slog.Error("Cannot stand by for room which went offline or got another standby push", "room", room.Name)
+						_ = pc.Close()
+						continue
+					}
+				} else {
+					room.SetPeerConnection(pc)
+					sp.relay.Events.Publish(Event{Type: EventRoomOnline, Room: room.Name})
+				}
+				ingest = pc
 				iceHelper.SetPeerConnection(pc)
 
 				pc.OnDataChannel(func(dc *webrtc.DataChannel) {
 					// TODO: Is this the best way to handle DataChannel? Should we just use the map directly?
-					room.DataChannel = connections.NewNestriDataChannel(dc)
-					room.DataChannel.RegisterOnOpen(func() {
+					ndc := connections.NewNestriDataChannel(dc)
+					room.SetIngestDataChannel(pc, ndc)
+					ndc.SetMessageGate(room.AdmitMessage)
+					ndc.RegisterOnOpen(func() {
 						slog.Debug("DataChannel opened for pushed stream", "room", room.Name)
 					})
-					room.DataChannel.RegisterOnClose(func() {
+					ndc.RegisterOnClose(func() {
 						slog.Debug("DataChannel closed for pushed stream", "room", room.Name)
 					})
 					// Handle controller feedback reverse-flow (like rumble events coming from game to client)
-					room.DataChannel.RegisterMessageCallback("controllerInput", func(data []byte) {
-						// Forward controller input to all viewers
-						if roomMap, ok := sp.servedConns.Get(room.Name); ok {
-							roomMap.Range(func(peerID peer.ID, conn *StreamConnection) bool {
-								if conn.ndc != nil {
-									if err = conn.ndc.SendBinary(data); err != nil {
-										if errors.Is(err, io.ErrClosedPipe) {
-											slog.Warn("Failed to forward controller input to viewer, treating as disconnected", "err", err)
-											sp.relay.onPeerDisconnected(peerID)
-										} else {
-											slog.Error("Failed to forward controller input from pushed stream to viewer", "room", room.Name, "peer", peerID, "err", err)
-										}
-									}
-								}
-								return true
-							})
-						}
+					ndc.RegisterMessageCallback("controllerInput", func(data []byte) {
+						sp.forwardControllerFeedback(room, data)
+					})
+					ndc.RegisterMessageCallback("input-latency", func(data []byte) {
+						sp.relay.receiveInputLatency(room, data)
 					})
 
 					// Set the DataChannel in the incomingConns map
+					if room.PeerConnection() != pc {
+						return
+					}
 					if conn, ok := sp.incomingConns.Get(room.Name); ok {
-						conn.ndc = room.DataChannel
+						conn.ndc = ndc
 					} else {
 						sp.incomingConns.Set(room.Name, &StreamConnection{
-							pc:  pc,
-							ndc: room.DataChannel,
+							pc:     pc,
+							ndc:    ndc,
+							pusher: stream.Conn().RemotePeer(),
 						})
 					}
 				})
@@ -549,12 +789,16 @@ func (sp *StreamProtocol) handleStreamPush(stream network.Stream) {
 					}
 
 					candInit := candidate.ToJSON()
-					biggified := uint32(*candInit.SDPMLineIndex)
+					var sdpMLineIndex *uint32
+					if candInit.SDPMLineIndex != nil {
+						idx := uint32(*candInit.SDPMLineIndex)
+						sdpMLineIndex = &idx
+					}
 					iceMsg, err := common.CreateMessage(
 						&gen.ProtoICE{
 							Candidate: &gen.RTCIceCandidateInit{
 								Candidate:     candInit.Candidate,
-								SdpMLineIndex: &biggified,
+								SdpMLineIndex: sdpMLineIndex,
 								SdpMid:        candInit.SDPMid,
 							},
 						},
@@ -571,23 +815,40 @@ func (sp *StreamProtocol) handleStreamPush(stream network.Stream) {
 				})
 
 				pc.OnTrack(func(remoteTrack *webrtc.TrackRemote, receiver *webrtc.RTPReceiver) {
-					// Prepare PlayoutDelayExtension so we don't need to recreate it for each packet
-					playoutExt := &rtp.PlayoutDelayExtension{
-						MinDelay: 0,
-						MaxDelay: 0,
-					}
-					playoutPayload, err := playoutExt.Marshal()
-					if err != nil {
-						slog.Error("Failed to marshal PlayoutDelayExtension for room", "room", room.Name, "err", err)
+					if remoteTrack.Kind() == webrtc.RTPCodecTypeVideo && room.GetSettings().AudioOnly {
+						slog.Warn("Ignoring video track pushed to audio-only room", "room", room.Name)
+						if err := receiver.Stop(); err != nil {
+							slog.Error("Failed to stop video receiver for audio-only room", "room", room.Name, "err", err)
+						}
 						return
 					}
 
-					if remoteTrack.Kind() == webrtc.RTPCodecTypeAudio {
-						room.AudioCodec = remoteTrack.Codec().RTPCodecCapability
-					} else if remoteTrack.Kind() == webrtc.RTPCodecTypeVideo {
-						room.VideoCodec = remoteTrack.Codec().RTPCodecCapability
-					}
+					if standbyPush {
+						// Viewers negotiated codecs of the pushed stream, a standby must send the same to take over
+						if codec := room.Codec(remoteTrack.Kind()); len(codec.MimeType) > 0 && !strings.EqualFold(codec.MimeType, remoteTrack.Codec().MimeType) {
+							slog.Error("Standby push codec differs from pushed stream", "room", room.Name, "codec", remoteTrack.Codec().MimeType, "room_codec", codec.MimeType)
+							sp.endPush(room, pc, standbyPush)
+							return
+						}
+					} else {
+						if remoteTrack.Kind() == webrtc.RTPCodecTypeVideo {
+							room.SetVideoSSRC(uint32(remoteTrack.SSRC()))
+						} else {
+							room.SetAudioLevelExtension(common.GetReceivedExtensions(receiver).AudioLevel)
+						}
+						// Viewers waiting for the room need codecs of all its tracks for their offer
+						if room.SetCodec(remoteTrack.Kind(), remoteTrack.Codec().RTPCodecCapability) {
+							go sp.serveWaitingRequests(room.Name)
+						}
+					}
 
+					meter := sp.relay.Bandwidth.Meter(stream.Conn().RemotePeer())
+					forwarding := !standbyPush
+					awaitKeyframe := false // Standby took over, video is forwarded from its next keyframe
+					var thumbs *thumbnailGrabber
+					if remoteTrack.Kind() == webrtc.RTPCodecTypeVideo {
+						thumbs = sp.relay.newThumbnailGrabber(room, remoteTrack.Codec().MimeType)
+					}
 					for {
 						rtpPacket, _, err := remoteTrack.ReadRTP()
 						if err != nil {
@@ -596,16 +857,39 @@ func (sp *StreamProtocol) handleStreamPush(stream network.Stream) {
 							}
 							break
 						}
+						meter.AddIn(len(rtpPacket.Payload))
 
-						// Use PlayoutDelayExtension for low latency, if set for this track kind
-						if extID, ok := common.GetExtension(remoteTrack.Kind(), common.ExtensionPlayoutDelay); ok {
-							if err = rtpPacket.SetExtension(extID, playoutPayload); err != nil {
-								slog.Error("Failed to set PlayoutDelayExtension for room", "room", room.Name, "err", err)
+						// Standby tracks are read to stay ready and dropped until the standby took over
+						if !forwarding {
+							if room.PeerConnection() != pc {
 								continue
 							}
+							forwarding = true
+							if remoteTrack.Kind() == webrtc.RTPCodecTypeVideo {
+								room.SetVideoSSRC(uint32(remoteTrack.SSRC()))
+								awaitKeyframe = true
+								if err = room.RequestKeyframe(); err != nil {
+									slog.Warn("Failed to request keyframe from standby push", "room", room.Name, "err", err)
+								}
+							} else {
+								room.SetAudioLevelExtension(common.GetReceivedExtensions(receiver).AudioLevel)
+							}
+						}
+						if awaitKeyframe {
+							if !common.IsKeyframe(remoteTrack.Codec().MimeType, rtpPacket.Payload) {
+								continue
+							}
+							awaitKeyframe = false
+						}
+
+						if remoteTrack.Kind() == webrtc.RTPCodecTypeVideo {
+							observeStreamClock(room, rtpPacket.Timestamp, remoteTrack.Codec().ClockRate, time.Now())
+						}
+						if thumbs != nil {
+							thumbs.push(rtpPacket)
 						}
 
-						// Broadcast
+						// Broadcast, participants add extensions they negotiated
 						room.BroadcastPacket(remoteTrack.Kind(), rtpPacket)
 					}
 
@@ -648,25 +932,829 @@ func (sp *StreamProtocol) handleStreamPush(stream network.Stream) {
 					slog.Error("Failed to send answer for pushed stream", "room", room.Name, "err", err)
 				}
 
-				// Store the connection
-				sp.incomingConns.Set(room.Name, &StreamConnection{
-					pc:  pc,
-					ndc: room.DataChannel, // if it exists, if not it will be set later
-				})
-				slog.Debug("Sent answer for pushed stream", "room", room.Name)
+				// Store the connection, standby pushes are stored once they took over
+				if !standbyPush {
+					sp.incomingConns.Set(room.Name, &StreamConnection{
+						pc:     pc,
+						ndc:    room.DataChannel(), // if it exists, if not it will be set later
+						pusher: stream.Conn().RemotePeer(),
+					})
+				}
+				slog.Debug("Sent answer for pushed stream", "room", room.Name, "standby", standbyPush)
+			}
+		}
+	}
+}
+
+// --- Internal Helpers ---
+
+// forwardControllerFeedback sends controller feedback of the server only to the participant its controller was
+// attached through, a viewer or the mesh relay it's served over. Viewers moved between variants of the room are
+// followed, feedback of detached or unknown controllers is dropped.
+func (sp *StreamProtocol) forwardControllerFeedback(room *shared.Room, data []byte) {
+	var msgWrapper gen.ProtoMessage
+	if err := proto.Unmarshal(data, &msgWrapper); err != nil {
+		slog.Debug("Failed to unmarshal controller feedback", "room", room.Name, "err", err)
+		return
+	}
+	var owns func(participant *shared.Participant) bool
+	switch payload := msgWrapper.Payload.(type) {
+	case *gen.ProtoMessage_ControllerRumble:
+		slot := shared.ControllerSlot{SessionID: payload.ControllerRumble.GetSessionId(), Slot: payload.ControllerRumble.GetSessionSlot()}
+		owns = func(participant *shared.Participant) bool {
+			return participant.OwnsControllerSlot(slot)
+		}
+	case *gen.ProtoMessage_ControllerAttach:
+		// Attach replies carry the slot the server assigned, they go to the session which asked for it
+		sessionID := payload.ControllerAttach.GetSessionId()
+		owns = func(participant *shared.Participant) bool {
+			return participant.OwnsControllerSession(sessionID)
+		}
+	default:
+		slog.Debug("Ignoring unknown controller feedback", "room", room.Name)
+		return
+	}
+
+	for _, familyRoom := range sp.relay.roomFamily(shared.BaseRoomName(room.Name)) {
+		for _, participant := range familyRoom.GetParticipants() {
+			if !owns(participant) {
+				continue
+			}
+			dc := participant.DataChannel()
+			if dc == nil {
+				controllerFeedbackDroppedCounter.WithLabelValues("closed").Inc()
+				return
+			}
+			if err := dc.SendBinary(data); err != nil {
+				controllerFeedbackDroppedCounter.WithLabelValues("send").Inc()
+				if errors.Is(err, io.ErrClosedPipe) {
+					slog.Warn("Failed to forward controller feedback to participant, treating as disconnected", "err", err)
+					sp.relay.onPeerDisconnected(participant.PeerID)
+				} else {
+					slog.Error("Failed to forward controller feedback to participant", "room", familyRoom.Name, "participant", participant.ID, "err", err)
+				}
+			}
+			return
+		}
+	}
+	controllerFeedbackDroppedCounter.WithLabelValues("unowned").Inc()
+}
+
+// trackControllerSlots records which controllers are attached through a participant so feedback for them
+// reaches only it. Controller messages of viewers are addressed with their own session, mesh relays did so
+// for viewers behind them.
+func trackControllerSlots(msg *gen.ProtoMessage, participant *shared.Participant, meshRelay bool) {
+	switch payload := msg.Payload.(type) {
+	case *gen.ProtoMessage_ControllerAttach:
+		if !meshRelay {
+			payload.ControllerAttach.SessionId = participant.SessionID
+		}
+		participant.ClaimControllerSlot(shared.ControllerSlot{SessionID: payload.ControllerAttach.SessionId, Slot: payload.ControllerAttach.SessionSlot})
+	case *gen.ProtoMessage_ControllerDetach:
+		if !meshRelay {
+			payload.ControllerDetach.SessionId = participant.SessionID
+		}
+		participant.ReleaseControllerSlot(shared.ControllerSlot{SessionID: payload.ControllerDetach.SessionId, Slot: payload.ControllerDetach.SessionSlot})
+	case *gen.ProtoMessage_ControllerStateBatch:
+		if !meshRelay {
+			payload.ControllerStateBatch.SessionId = participant.SessionID
+		}
+	}
+}
+
+// registerInputForwarding forwards viewer input received on a served DataChannel to the upstream room,
+// input of participants without input permission is dropped here so spectators can't control the game.
+// Input is sanitized, rate limited and addressed to the viewer's session before it reaches the server, mesh relays
+// forward input of many viewers limited, addressed and stamped for latency measurement on their own relay so only
+// validation applies to them.
+func (sp *StreamProtocol) registerInputForwarding(ndc *connections.NestriDataChannel, roomName string, participant *shared.Participant, upstreamRoom func() *shared.Room) {
+	limiter := rate.NewLimiter(rate.Inf, inputRateBurst)
+	probe := &inputProbe{participant: participant}
+	meshRelay := sp.relay.isMeshRelay(participant.PeerID)
+	forward := func(data []byte) {
+		if !participant.AcceptInput() {
+			return
+		}
+		if perSecond := common.GetFlags().InputRate; perSecond > 0 && !meshRelay {
+			if limiter.Limit() != rate.Limit(perSecond) {
+				limiter.SetLimit(rate.Limit(perSecond))
 			}
+			if !limiter.Allow() {
+				inputThrottledCounter.Inc()
+				return
+			}
+		}
+		var msgWrapper gen.ProtoMessage
+		if err := proto.Unmarshal(data, &msgWrapper); err != nil {
+			slog.Debug("Failed to unmarshal input message", "room", roomName, "participant", participant.ID, "err", err)
+			return
+		}
+		if err := common.SanitizeInput(&msgWrapper); err != nil {
+			return
 		}
+		trackControllerSlots(&msgWrapper, participant, meshRelay)
+		if !meshRelay {
+			probe.stamp(&msgWrapper, time.Now())
+		}
+		sanitized, err := proto.Marshal(&msgWrapper)
+		if err != nil {
+			slog.Error("Failed to marshal input message", "err", err)
+			return
+		}
+		if upstreamDC := upstreamRoom().DataChannel(); upstreamDC != nil {
+			if err = upstreamDC.SendBinary(sanitized); err != nil {
+				slog.Error("Failed to forward input message to upstream room", "room", roomName, "err", err)
+			}
+		}
+	}
+	ndc.RegisterMessageCallback("input", forward)
+	ndc.RegisterMessageCallback("controllerInput", forward)
+}
+
+// resolveServedRoom finds a room to serve to requesting peer, pulling it through the mesh if needed,
+// if the room can't be served nil is returned with the refusal payload type to answer with
+func (sp *StreamProtocol) resolveServedRoom(roomName string, peerID peer.ID) (*shared.Room, string) {
+	if sp.relay.IsDraining() {
+		slog.Debug("Refusing stream request while draining", "room", roomName)
+		return nil, "request-stream-draining"
 	}
+
+	room := sp.relay.GetRoomByName(roomName)
+	if room == nil || (!room.IsOnline() && room.OwnerID != sp.relay.ID) {
+		// Pull the room through the mesh if another relay can serve it
+		if pulled, err := sp.pullRoom(context.Background(), roomName); err != nil {
+			slog.Debug("Could not pull room stream from mesh", "room", roomName, "err", err)
+		} else {
+			room = pulled
+		}
+	}
+	if room == nil || !room.IsOnline() {
+		slog.Debug("Cannot provide stream for nil or offline room", "room", roomName, "is_online", room != nil && room.IsOnline(), "is_owner", room != nil && room.OwnerID == sp.relay.ID)
+		return nil, "request-stream-offline"
+	}
+
+	// Refuse paths which would exceed the room latency budget, requester should try a shallower path
+	if sp.relay.overLatencyBudget(room, peerID) {
+		slog.Warn("Refusing stream request over room latency budget", "room", roomName, "peer", peerID)
+		return nil, "request-stream-over-budget"
+	}
+	return room, ""
+}
+
+// acceptPush creates or takes over the room of an authenticated push and answers with "push-stream-ok",
+// returning the room or nil if the push can't be accepted
+func (sp *StreamProtocol) acceptPush(safeBRW *common.SafeBufioRW, pushMsg *gen.ProtoServerPushStream) *shared.Room {
+	// Quality variants are carried by sibling rooms of the main room
+	if strings.Contains(pushMsg.RoomName, shared.VariantSeparator) {
+		slog.Error("Cannot push a stream to room name containing variant separator", "room", pushMsg.RoomName)
+		return nil
+	}
+	if len(pushMsg.Variant) > 0 && !shared.ValidVariantName(pushMsg.Variant) {
+		slog.Error("Cannot push a stream with invalid variant name", "room", pushMsg.RoomName, "variant", pushMsg.Variant)
+		return nil
+	}
+	roomName := shared.VariantRoomName(pushMsg.RoomName, pushMsg.Variant)
+
+	room := sp.relay.GetRoomByName(roomName)
+	if pushMsg.Standby {
+		// Standby pushes keep settings and metadata of the pushed stream they stand by for
+		if room == nil || room.OwnerID != sp.relay.ID || !room.IsOnline() || room.HasStandby() {
+			slog.Error("Cannot push a standby stream to room without a pushed stream to stand by for", "room", roomName)
+			return nil
+		}
+		slog.Info("Accepted standby push for room", "room", room.Name)
+		if err := sendPushOK(safeBRW, pushMsg.RoomName); err != nil {
+			slog.Error("Failed to send push stream OK response", "room", room.Name, "err", err)
+		}
+		return room
+	}
+	if room != nil {
+		if room.OwnerID != sp.relay.ID {
+			slog.Error("Cannot push a stream to non-owned room", "room", room.Name, "owner_id", room.OwnerID)
+			return nil
+		}
+		if room.IsOnline() {
+			slog.Error("Cannot push a stream to already online room", "room", room.Name)
+			return nil
+		}
+	}
+	// The platform's room directory decides which rooms exist, and orders claims of relays which never met
+	entry, err := sp.relay.directoryRoom(roomName)
+	if err != nil {
+		slog.Warn("Refusing stream push to room refused by room directory", "room", roomName, "err", err)
+		sendRoomRefusal(safeBRW, pushMsg.RoomName, "push-stream-unauthorized")
+		return nil
+	}
+	epoch := sp.relay.nextRoomEpoch(roomName, time.Now())
+	if len(pushMsg.Variant) <= 0 {
+		epoch = sp.relay.claimDirectoryRoom(roomName, epoch)
+	}
+	if room == nil {
+		// Create a new room if it doesn't exist
+		room = sp.relay.CreateRoom(roomName)
+	}
+	settings := shared.RoomSettingsFromProto(pushMsg.Settings).WithDefaults(room.Name)
+	if entry.MaxViewers > 0 {
+		settings.MaxViewers = entry.MaxViewers
+	}
+	if settings.AccessHash, err = shared.RoomAccessHash(room.Name, pushMsg.GetSettings().GetAccessSecret()); err != nil {
+		slog.Error("Failed to hash room access secret", "room", room.Name, "err", err)
+		return nil
+	}
+	room.SetSettings(settings)
+	room.SetClaim(epoch, time.Now())
+	room.ResetCodecs()
+	if settings.AudioOnly {
+		slog.Info("Room is audio-only", "room", room.Name)
+	}
+	if settings.StrictLatency {
+		slog.Info("Room is in strict latency mode", "room", room.Name, "max_frame_age", room.MaxVideoAge())
+	}
+	if len(settings.AccessHash) > 0 {
+		slog.Info("Room requires an access secret", "room", room.Name)
+	}
+	room.SetMetadata(shared.RoomMetadata{Private: entry.Private})
+	if pushMsg.Metadata != nil {
+		sp.relay.UpdateRoomMetadata(room, pushMsg.Metadata)
+	}
+	if len(pushMsg.Variant) > 0 {
+		slog.Info("Room stream is a quality variant", "room", pushMsg.RoomName, "variant", pushMsg.Variant)
+		sp.relay.announceRoomVariants(pushMsg.RoomName)
+	}
+
+	if err = sendPushOK(safeBRW, pushMsg.RoomName); err != nil {
+		slog.Error("Failed to send push stream OK response", "room", room.Name, "err", err)
+	}
+	return room
+}
+
+// sendPushOK accepts a push, responding with an OK with the room name
+func sendPushOK(safeBRW *common.SafeBufioRW, roomName string) error {
+	resMsg, err := common.CreateMessage(
+		&gen.ProtoServerPushStream{
+			RoomName: roomName,
+		},
+		"push-stream-ok", nil,
+	)
+	if err != nil {
+		return err
+	}
+	return safeBRW.SendProto(resMsg)
+}
+
+// endPush removes PeerConnection of an ended push from its room, the room fails over to its standby push if there is one
+func (sp *StreamProtocol) endPush(room *shared.Room, pc *webrtc.PeerConnection, standby bool) {
+	if pc == nil {
+		// Push ended before its offer, a standby push never took the room online
+		if !standby {
+			room.Close()
+			sp.incomingConns.Delete(room.Name)
+			sp.relay.releaseDirectoryRoom(room.Name)
+		}
+		return
+	}
+	switch room.RemoveIngest(pc) {
+	case shared.IngestStandbyRemoved:
+		slog.Info("Standby push ended", "room", room.Name)
+	case shared.IngestFailedOver:
+		slog.Warn("Pushed stream ended, standby push took over room", "room", room.Name)
+		ingestFailoverCounter.Inc()
+		sp.incomingConns.Set(room.Name, &StreamConnection{
+			pc:  room.PeerConnection(),
+			ndc: room.DataChannel(),
+		})
+		sp.relay.Events.Publish(Event{Type: EventRoomFailover, Room: room.Name})
+	case shared.IngestOffline:
+		sp.incomingConns.Delete(room.Name)
+		sp.relay.releaseDirectoryRoom(room.Name)
+		sp.relay.Events.Publish(Event{Type: EventRoomOffline, Room: room.Name})
+	}
+}
+
+// sendPushChallenge sends a new nonce a pushing node must sign with a trusted push key, returning the nonce
+func sendPushChallenge(safeBRW *common.SafeBufioRW, roomName string) (string, error) {
+	nonce, err := newPushChallenge()
+	if err != nil {
+		return "", err
+	}
+	challengeMsg, err := common.CreateMessage(
+		&gen.ProtoPushChallenge{
+			RoomName: roomName,
+			Nonce:    nonce,
+		},
+		"push-challenge", nil,
+	)
+	if err != nil {
+		return "", err
+	}
+	return nonce, safeBRW.SendProto(challengeMsg)
+}
+
+// sendRoomRefusal tells requester a room can't be served, refusal is the payload type carrying room name.
+// Also used for "request-stream-online", sent to waiting requesters before their offer
+func sendRoomRefusal(safeBRW *common.SafeBufioRW, roomName, refusal string) {
+	rawMsg, err := common.CreateMessage(
+		&gen.ProtoRaw{
+			Data: roomName,
+		},
+		refusal, nil,
+	)
+	if err != nil {
+		slog.Error("Failed to create proto message", "err", err)
+		return
+	}
+	if err = safeBRW.SendProto(rawMsg); err != nil {
+		slog.Error("Failed to send stream refusal", "room", roomName, "refusal", refusal, "err", err)
+	}
+}
+
+// sendRoomFull tells requester the room admits no more participants
+func sendRoomFull(safeBRW *common.SafeBufioRW, full *gen.ProtoRoomFull) {
+	fullMsg, err := common.CreateMessage(full, "room-full", nil)
+	if err != nil {
+		slog.Error("Failed to create proto message", "err", err)
+		return
+	}
+	if err = safeBRW.SendProto(fullMsg); err != nil {
+		slog.Error("Failed to send room full", "room", full.RoomName, "err", err)
+	}
+}
+
+// sendSignalingProgress lets a requester know how far its stream setup got
+func sendSignalingProgress(safeBRW *common.SafeBufioRW, roomName, stage, detail string, requested time.Time) {
+	progressMsg, err := common.CreateMessage(
+		&gen.ProtoSignalingProgress{
+			RoomName:  roomName,
+			Stage:     stage,
+			Detail:    detail,
+			ElapsedMs: uint32(time.Since(requested).Milliseconds()),
+		},
+		"signaling-progress", nil,
+	)
+	if err != nil {
+		slog.Error("Failed to create proto message", "err", err)
+		return
+	}
+	if err = safeBRW.SendProto(progressMsg); err != nil {
+		slog.Debug("Failed to send signaling progress", "room", roomName, "stage", stage, "err", err)
+	}
+}
+
+// pullRoom pulls a room stream from the mesh, concurrent requesters of a room share one pull so the room
+// only gets one upstream however many local viewers request it at once
+func (sp *StreamProtocol) pullRoom(ctx context.Context, roomName string) (*shared.Room, error) {
+	sp.pullMtx.Lock()
+	pull, pulling := sp.pulls[roomName]
+	if !pulling {
+		pull = &roomPull{done: make(chan struct{})}
+		sp.pulls[roomName] = pull
+	}
+	pull.refs++
+	sp.pullMtx.Unlock()
+
+	if pulling {
+		slog.Debug("Waiting for pull of room already requested upstream", "room", roomName)
+		select {
+		case <-pull.done:
+		case <-ctx.Done():
+			sp.releasePull(roomName, pull)
+			return nil, ctx.Err()
+		}
+	} else {
+		// Room may have come online over a pull which just finished
+		if room := sp.relay.GetRoomByName(roomName); room != nil && room.IsOnline() {
+			pull.room = room
+		} else {
+			pull.room, pull.err = sp.pullRoutes(ctx, roomName)
+		}
+		close(pull.done)
+	}
+	sp.releasePull(roomName, pull)
+	return pull.room, pull.err
+}
+
+// releaseUpstream stops pulling a room, the serving relay is told to stop serving it. Rooms pulled
+// over a mesh link are released from the link when closed.
+func (sp *StreamProtocol) releaseUpstream(room *shared.Room) {
+	if conn, ok := sp.requestedConns.Get(room.Name); ok && conn.unsubscribe != nil {
+		conn.unsubscribe()
+	}
+	sp.requestedConns.Delete(room.Name)
+	room.Close()
+}
+
+// upstreamEnded pulls room again after its upstream ended without us releasing it, local participants
+// and served relays stay attached to the room and get the stream once a route serves it again
+func (sp *StreamProtocol) upstreamEnded(room *shared.Room) {
+	if !sp.upstreamWanted(room) {
+		return
+	}
+	slog.Info("Upstream of room ended, pulling it again", "room", room.Name, "participants", room.ParticipantCount())
+	go func() {
+		for attempt := 1; attempt <= upstreamRetryMax; attempt++ {
+			// Pulled again by a viewer meanwhile, or nobody is left to pull it for
+			if room.IsOnline() || !sp.upstreamWanted(room) {
+				return
+			}
+			if _, err := sp.pullRoom(context.Background(), room.Name); err != nil {
+				slog.Warn("Failed to pull room again after upstream ended", "room", room.Name, "attempt", attempt, "err", err)
+				time.Sleep(time.Duration(common.GetFlags().UpstreamRetry) * time.Millisecond)
+				continue
+			}
+			slog.Info("Pulled room again after upstream ended", "room", room.Name, "attempt", attempt)
+			// Participants pick up the new stream from its next keyframe
+			if err := room.RequestKeyframe(); err != nil {
+				slog.Debug("Failed to request keyframe of room pulled again", "room", room.Name, "err", err)
+			}
+			return
+		}
+	}()
+}
+
+// upstreamWanted tells if a pulled room still has local participants or relays served from it
+func (sp *StreamProtocol) upstreamWanted(room *shared.Room) bool {
+	if room.OwnerID == sp.relay.ID {
+		return false
+	}
+	return room.ParticipantCount() > 0 || sp.servedConns.Has(room.Name)
+}
+
+// dropPeer tears down streams of a peer without connections left, instead of waiting for their
+// PeerConnections to time out. Participants of the peer leave their rooms, rooms pulled from it go offline.
+func (sp *StreamProtocol) dropPeer(peerID peer.ID) {
+	for roomName, roomMap := range sp.servedConns.Copy() {
+		conn, ok := roomMap.Get(peerID)
+		if !ok {
+			continue
+		}
+		slog.Info("Closing served stream of disconnected peer", "room", roomName, "peer", peerID)
+		// Closing the PeerConnection removes the participant
+		if err := conn.pc.Close(); err != nil {
+			slog.Error("Failed to close served PeerConnection of disconnected peer", "room", roomName, "peer", peerID, "err", err)
+		}
+	}
+	if link, ok := sp.servedMeshLinks.Get(peerID); ok {
+		link.close()
+	}
+
+	if link, ok := sp.meshLinks.Get(peerID); ok {
+		link.close()
+	}
+	for roomName, conn := range sp.requestedConns.Copy() {
+		// Rooms pulled over a mesh link went with the link
+		room := sp.relay.GetRoomByName(roomName)
+		if room == nil || room.UpstreamID() != peerID || conn.unsubscribe == nil {
+			continue
+		}
+		slog.Info("Closing requested stream of disconnected peer", "room", roomName, "peer", peerID)
+		if err := conn.pc.Close(); err != nil {
+			slog.Error("Failed to close requested PeerConnection of disconnected peer", "room", roomName, "peer", peerID, "err", err)
+		}
+	}
+}
+
+// dropPushes closes streams a departed peer pushed, their rooms fail over to standby pushes or go offline
+func (sp *StreamProtocol) dropPushes(peerID peer.ID) {
+	for roomName, conn := range sp.incomingConns.Copy() {
+		if conn.pusher != peerID || conn.pc == nil {
+			continue
+		}
+		slog.Info("Closing pushed stream of disconnected peer", "room", roomName, "peer", peerID)
+		if err := conn.pc.Close(); err != nil {
+			slog.Error("Failed to close pushed PeerConnection of disconnected peer", "room", roomName, "peer", peerID, "err", err)
+		}
+	}
+}
+
+// releasePull drops a requester of pull, the last one forgets it so later requests pull again if needed
+func (sp *StreamProtocol) releasePull(roomName string, pull *roomPull) {
+	sp.pullMtx.Lock()
+	defer sp.pullMtx.Unlock()
+	pull.refs--
+	if pull.refs <= 0 && sp.pulls[roomName] == pull {
+		delete(sp.pulls, roomName)
+	}
+}
+
+// pullRoutes requests a room stream from the mesh, trying routes within latency budget shallowest first
+func (sp *StreamProtocol) pullRoutes(ctx context.Context, roomName string) (*shared.Room, error) {
+	routes := sp.relay.selectRoomRoutes(roomName)
+	if len(routes) == 0 {
+		return nil, errNoRoomRoute
+	}
+
+	room := sp.relay.GetRoomByName(roomName)
+	if room == nil {
+		room = sp.relay.CreateRoomFromRoute(routes[0])
+	}
+
+	_, variant := shared.SplitVariant(roomName)
+	for _, route := range routes {
+		// Variants are pulled only from relays serving them, relays predating announcements are tried anyway
+		if len(variant) > 0 && !sp.relay.peerSupports(route.RelayID, common.CapabilityVariants, protocolStreamRequest) {
+			slog.Debug("Skipping route of relay not serving variants", "room", roomName, "peer", route.RelayID)
+			continue
+		}
+		pullCtx, cancel := context.WithTimeout(ctx, streamPullTimeout)
+		err := sp.RequestStream(pullCtx, room, route.RelayID)
+		cancel()
+		if err == nil {
+			return room, nil
+		}
+		slog.Warn("Failed to pull room stream from peer, trying next route", "room", roomName, "peer", route.RelayID, "hops", route.Hops, "err", err)
+	}
+
+	// Don't keep around a room we couldn't get online, unless participants wait in it
+	if !room.IsOnline() && room.OwnerID != sp.relay.ID && room.ParticipantCount() <= 0 {
+		sp.relay.LocalRooms.Delete(room.ID)
+	}
+	return nil, fmt.Errorf("no route could serve room %s", roomName)
 }
 
 // --- Public Usable Methods ---
 
-// RequestStream sends a request to get room stream from another relay
+// RequestStream sends a request to get room stream from another relay, returns once tracks are flowing
 func (sp *StreamProtocol) RequestStream(ctx context.Context, room *shared.Room, peerID peer.ID) error {
-	_, err := sp.relay.Host.NewStream(ctx, peerID, protocolStreamRequest)
+	// Share one PeerConnection for all rooms pulled from relays supporting it
+	if common.GetFlags().MeshMultiplex && sp.relay.peerSupports(peerID, common.CapabilityMeshLink, protocolStreamMesh) {
+		return sp.requestMeshStream(ctx, room, peerID)
+	}
+
+	stream, err := sp.relay.newVersionedStream(ctx, peerID, protocolStreamRequest)
 	if err != nil {
 		return fmt.Errorf("failed to create stream: %w", err)
 	}
 
-	return nil /* TODO: This? */
+	safeBRW := newStreamRW(stream)
+
+	reqMsg, err := common.CreateMessage(
+		&gen.ProtoClientRequestRoomStream{
+			RoomName: room.Name,
+		},
+		"request-stream-room", nil,
+	)
+	if err != nil {
+		_ = stream.Reset()
+		return fmt.Errorf("failed to create proto message: %w", err)
+	}
+	if err = safeBRW.SendProto(reqMsg); err != nil {
+		_ = stream.Reset()
+		return fmt.Errorf("failed to send stream request: %w", err)
+	}
+
+	// First result wins, either tracks flowing or a failure
+	result := make(chan error, 1)
+	signal := func(err error) {
+		select {
+		case result <- err:
+		default:
+		}
+	}
+
+	go sp.handleRequestedStream(stream, safeBRW, room, peerID, signal)
+
+	select {
+	case err = <-result:
+		if err != nil {
+			_ = stream.Reset()
+		}
+		return err
+	case <-ctx.Done():
+		_ = stream.Reset()
+		return ctx.Err()
+	}
+}
+
+// handleRequestedStream answers the offer of a relay serving a room we requested
+func (sp *StreamProtocol) handleRequestedStream(stream network.Stream, safeBRW *common.SafeBufioRW, room *shared.Room, peerID peer.ID, signal func(error)) {
+	expectedTracks := int32(2)
+	if room.GetSettings().AudioOnly {
+		expectedTracks = 1
+	}
+	var receivedTracks atomic.Int32
+
+	iceHelper := common.NewICEHelper(nil)
+	for {
+		var msgWrapper gen.ProtoMessage
+		err := safeBRW.ReceiveProto(&msgWrapper)
+		if errors.Is(err, common.ErrMessageRejected) {
+			continue
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, network.ErrReset) {
+				slog.Debug("Requested stream connection closed by peer", "peer", peerID, "room", room.Name)
+			} else {
+				slog.Error("Failed to receive data for requested stream", "room", room.Name, "err", err)
+				_ = stream.Reset()
+			}
+			signal(fmt.Errorf("stream closed: %w", err))
+			return
+		}
+
+		if msgWrapper.MessageBase == nil {
+			slog.Error("No MessageBase in requested stream")
+			continue
+		}
+
+		switch msgWrapper.MessageBase.PayloadType {
+		case "session-assigned":
+			// Relays don't need sessions
+		case "signaling-progress":
+			if progressMsg := msgWrapper.GetSignalingProgress(); progressMsg != nil {
+				slog.Debug("Upstream signaling progress", "room", room.Name, "peer", peerID, "stage", progressMsg.Stage, "detail", progressMsg.Detail, "elapsed_ms", progressMsg.ElapsedMs)
+			}
+		case "request-stream-offline":
+			signal(errStreamOffline)
+			return
+		case "request-stream-over-budget":
+			signal(errStreamOverBudget)
+			return
+		case "request-stream-draining":
+			signal(errStreamDraining)
+			return
+		case "quota-exceeded":
+			signal(errStreamOverQuota)
+			return
+		case "stream-path-info":
+			pathMsg := msgWrapper.GetStreamPathInfo()
+			if pathMsg != nil {
+				room.SetUpstreamPath(int(pathMsg.Hops), time.Duration(pathMsg.PathLatencyUs)*time.Microsecond)
+			} else {
+				slog.Error("Could not GetStreamPathInfo from stream-path-info")
+			}
+		case "ice-candidate":
+			iceMsg := msgWrapper.GetIce()
+			if iceMsg != nil {
+				iceHelper.AddCandidate(iceCandidateFromProto(iceMsg))
+			} else {
+				slog.Error("Could not GetIce from ice-candidate")
+			}
+		case "offer":
+			offerMsg := msgWrapper.GetSdp()
+			if offerMsg == nil {
+				slog.Error("Could not GetSdp from offer")
+				continue
+			}
+
+			var pc *webrtc.PeerConnection
+			pc, err = common.CreatePeerConnection(func() {
+				// Room may already be released or pulled again over a newer PeerConnection
+				if room.PeerConnection() != pc {
+					return
+				}
+				slog.Info("PeerConnection closed for requested room stream", "room", room.Name, "peer", peerID)
+				sp.requestedConns.Delete(room.Name)
+				room.Close()
+				sp.upstreamEnded(room)
+			})
+			if err != nil {
+				slog.Error("Failed to create PeerConnection for requested room stream", "room", room.Name, "err", err)
+				signal(err)
+				return
+			}
+			iceHelper.SetPeerConnection(pc)
+
+			pc.OnDataChannel(func(dc *webrtc.DataChannel) {
+				ndc := connections.NewNestriDataChannel(dc)
+				room.SetDataChannel(ndc)
+				ndc.SetMessageGate(room.AdmitMessage)
+				ndc.RegisterOnOpen(func() {
+					slog.Debug("DataChannel opened for requested room stream", "room", room.Name)
+				})
+				ndc.RegisterOnClose(func() {
+					slog.Debug("DataChannel closed for requested room stream", "room", room.Name)
+				})
+				// Controller feedback from upstream goes to our viewers
+				ndc.RegisterMessageCallback("controllerInput", func(data []byte) {
+					sp.forwardControllerFeedback(room, data)
+				})
+				ndc.RegisterMessageCallback("stream-clock", func(data []byte) {
+					sp.relay.receiveStreamClock(room, data)
+				})
+				ndc.RegisterMessageCallback("input-latency", func(data []byte) {
+					sp.relay.receiveInputLatency(room, data)
+				})
+
+				if conn, ok := sp.requestedConns.Get(room.Name); ok {
+					conn.ndc = ndc
+				}
+			})
+
+			pc.OnICECandidate(func(candidate *webrtc.ICECandidate) {
+				if candidate == nil {
+					return
+				}
+
+				candInit := candidate.ToJSON()
+				var sdpMLineIndex *uint32
+				if candInit.SDPMLineIndex != nil {
+					idx := uint32(*candInit.SDPMLineIndex)
+					sdpMLineIndex = &idx
+				}
+				iceMsg, err := common.CreateMessage(
+					&gen.ProtoICE{
+						Candidate: &gen.RTCIceCandidateInit{
+							Candidate:     candInit.Candidate,
+							SdpMLineIndex: sdpMLineIndex,
+							SdpMid:        candInit.SDPMid,
+						},
+					},
+					"ice-candidate", nil,
+				)
+				if err != nil {
+					slog.Error("Failed to create proto message", "err", err)
+					return
+				}
+				if err = safeBRW.SendProto(iceMsg); err != nil {
+					slog.Error("Failed to send ICE candidate message for requested room stream", "room", room.Name, "err", err)
+				}
+			})
+
+			pc.OnTrack(func(remoteTrack *webrtc.TrackRemote, receiver *webrtc.RTPReceiver) {
+				room.SetCodec(remoteTrack.Kind(), remoteTrack.Codec().RTPCodecCapability)
+				if remoteTrack.Kind() == webrtc.RTPCodecTypeVideo {
+					room.SetVideoSSRC(uint32(remoteTrack.SSRC()))
+				} else {
+					room.SetAudioLevelExtension(common.GetReceivedExtensions(receiver).AudioLevel)
+				}
+				if receivedTracks.Add(1) >= expectedTracks {
+					signal(nil)
+				}
+
+				meter := sp.relay.Bandwidth.Meter(peerID)
+				for {
+					rtpPacket, _, err := remoteTrack.ReadRTP()
+					if err != nil {
+						if !errors.Is(err, io.EOF) {
+							slog.Error("Failed to read RTP from requested room track", "room", room.Name, "err", err)
+						}
+						break
+					}
+					meter.AddIn(len(rtpPacket.Payload))
+					room.BroadcastPacket(remoteTrack.Kind(), rtpPacket)
+				}
+
+				slog.Debug("Requested track closed for room", "room", room.Name, "track_kind", remoteTrack.Kind().String())
+			})
+
+			if err = pc.SetRemoteDescription(webrtc.SessionDescription{
+				SDP:  offerMsg.Sdp.Sdp,
+				Type: webrtc.NewSDPType(offerMsg.Sdp.Type),
+			}); err != nil {
+				slog.Error("Failed to set remote description for requested room stream", "room", room.Name, "err", err)
+				signal(err)
+				return
+			}
+			iceHelper.FlushHeldCandidates()
+
+			answer, err := pc.CreateAnswer(nil)
+			if err != nil {
+				slog.Error("Failed to create answer for requested room stream", "room", room.Name, "err", err)
+				signal(err)
+				return
+			}
+			if err = pc.SetLocalDescription(answer); err != nil {
+				slog.Error("Failed to set local description for requested room stream", "room", room.Name, "err", err)
+				signal(err)
+				return
+			}
+			answerMsg, err := common.CreateMessage(
+				&gen.ProtoSDP{
+					Sdp: &gen.RTCSessionDescriptionInit{
+						Sdp:  answer.SDP,
+						Type: answer.Type.String(),
+					},
+				},
+				"answer", nil,
+			)
+			if err != nil {
+				slog.Error("Failed to create proto message", "err", err)
+				signal(err)
+				return
+			}
+			if err = safeBRW.SendProto(answerMsg); err != nil {
+				slog.Error("Failed to send answer for requested room stream", "room", room.Name, "err", err)
+				signal(err)
+				return
+			}
+
+			// Room is online from now on, pulled through peer
+			room.SetUpstreamID(peerID)
+			room.SetPeerConnection(pc)
+			sp.requestedConns.Set(room.Name, &StreamConnection{
+				pc:  pc,
+				ndc: room.DataChannel(),
+				unsubscribe: func() {
+					unsubMsg, err := common.CreateMessage(&gen.ProtoRaw{Data: room.Name}, "request-stream-unsubscribe", nil)
+					if err != nil {
+						slog.Error("Failed to create proto message", "err", err)
+					} else if err = safeBRW.SendProto(unsubMsg); err != nil {
+						slog.Debug("Failed to send stream unsubscribe", "room", room.Name, "peer", peerID, "err", err)
+					}
+					_ = stream.Close()
+				},
+			})
+			slog.Debug("Sent answer for requested room stream", "room", room.Name, "peer", peerID)
+		}
+	}
 }
@@ -0,0 +1,78 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"relay/internal/common"
+	"strings"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+)
+
+// --- Protocol Versions ---
+//
+// Stream protocols are served under every protocol ID version in protocolVersions. Dialers offer all of
+// them newest first and libp2p settles on the newest both sides speak, streams then behave as that
+// version did. A wire change adds a version here instead of replacing one, so relays and pushing nodes
+// not upgraded yet keep talking to upgraded ones over the version they know.
+
+// latestProtocolVersion is the protocol ID version of our stream protocols spoken by clients built with us
+const latestProtocolVersion = "1.1.0"
+
+// protocolVersion is a protocol ID version of stream protocols and how streams of it behave
+type protocolVersion struct {
+	id      string // Protocol ID version suffix
+	message uint32 // Message protocol version spoken on streams of this version
+}
+
+// protocolVersions are the protocol ID versions served and dialed, newest first
+var protocolVersions = []protocolVersion{
+	{id: latestProtocolVersion, message: common.ProtocolVersion}, // Capabilities and "invalid-message" answers
+	{id: "1.0.0", message: 1},
+}
+
+// versionedID returns protocol ID of base protocol in given version
+func versionedID(base, version string) protocol.ID {
+	return protocol.ID(base + "/" + version)
+}
+
+// versionedIDs returns protocol IDs of all served versions of base protocol, newest first
+func versionedIDs(base string) []protocol.ID {
+	ids := make([]protocol.ID, 0, len(protocolVersions))
+	for _, version := range protocolVersions {
+		ids = append(ids, versionedID(base, version.id))
+	}
+	return ids
+}
+
+// streamVersion returns protocol version a stream was negotiated with, the oldest one for unknown protocol IDs
+func streamVersion(stream network.Stream) protocolVersion {
+	id := string(stream.Protocol())
+	for _, version := range protocolVersions {
+		if strings.HasSuffix(id, "/"+version.id) {
+			return version
+		}
+	}
+	return protocolVersions[len(protocolVersions)-1]
+}
+
+// newStreamRW wraps a stream for signaling, speaking the message protocol version of its protocol version
+func newStreamRW(stream network.Stream) *common.SafeBufioRW {
+	safeBRW := common.NewSafeBufioRW(bufio.NewReadWriter(bufio.NewReader(stream), bufio.NewWriter(stream)))
+	safeBRW.SetMessageVersion(streamVersion(stream).message)
+	return safeBRW
+}
+
+// setVersionedHandler handles streams of all served versions of base protocol with one handler
+func (r *Relay) setVersionedHandler(base string, handler network.StreamHandler) {
+	for _, id := range versionedIDs(base) {
+		r.Host.SetStreamHandler(id, handler)
+	}
+}
+
+// newVersionedStream opens a stream of base protocol to peer, in the newest version both of us speak
+func (r *Relay) newVersionedStream(ctx context.Context, peerID peer.ID, base string) (network.Stream, error) {
+	return r.Host.NewStream(ctx, peerID, versionedIDs(base)...)
+}
@@ -2,12 +2,14 @@ package core
 
 // ProtocolRegistry is a type holding all protocols to split away the bloat
 type ProtocolRegistry struct {
-	StreamProtocol *StreamProtocol
+	StreamProtocol    *StreamProtocol
+	DirectoryProtocol *DirectoryProtocol
 }
 
 // NewProtocolRegistry initializes and returns a new protocol registry
 func NewProtocolRegistry(relay *Relay) ProtocolRegistry {
 	return ProtocolRegistry{
-		StreamProtocol: NewStreamProtocol(relay),
+		StreamProtocol:    NewStreamProtocol(relay),
+		DirectoryProtocol: NewDirectoryProtocol(relay),
 	}
 }
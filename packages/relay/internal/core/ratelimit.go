@@ -0,0 +1,172 @@
+package core
+
+import (
+	"log/slog"
+	"net"
+	"relay/internal/common"
+	"sync"
+	"time"
+
+	gen "relay/internal/proto"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+)
+
+// --- Signaling Rate Limits ---
+
+// Throttle scopes, sent with "throttled" messages
+const (
+	throttleScopeStream  = "stream"
+	throttleScopeMessage = "message"
+)
+
+var signalingThrottledCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "nestri_relay_signaling_throttled_total",
+	Help: "Signaling streams and messages refused by rate limits",
+}, []string{"scope"})
+
+// signalingLimiter rate limits signaling of peers with token buckets, per peer and per IP
+// so a client can't get around the limit by making up new peer IDs
+type signalingLimiter struct {
+	mtx       sync.Mutex
+	buckets   map[string]*signalingBucket // "peer/<id>" or "ip/<address>" -> bucket
+	lastSweep time.Time
+}
+
+type signalingBucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func newSignalingLimiter() *signalingLimiter {
+	return &signalingLimiter{buckets: make(map[string]*signalingBucket)}
+}
+
+// reserve takes a token from peer and IP buckets, returning how long to wait when either is empty.
+// Nothing is taken when refused, limit is tokens per second for a peer.
+func (sl *signalingLimiter) reserve(peerID peer.ID, ip net.IP, limit rate.Limit, burst int, now time.Time) time.Duration {
+	sl.mtx.Lock()
+	defer sl.mtx.Unlock()
+
+	// Forget idle buckets now and then, keeps map from growing with one-off peers
+	if now.Sub(sl.lastSweep) > signalingBucketIdle {
+		for key, bucket := range sl.buckets {
+			if now.Sub(bucket.lastSeen) > signalingBucketIdle {
+				delete(sl.buckets, key)
+			}
+		}
+		sl.lastSweep = now
+	}
+
+	peerRes := sl.bucket("peer/"+peerID.String(), limit, burst, now).ReserveN(now, 1)
+	if delay := peerRes.DelayFrom(now); delay > 0 {
+		peerRes.CancelAt(now)
+		return delay
+	}
+	if ip == nil {
+		return 0
+	}
+	ipRes := sl.bucket("ip/"+ip.String(), limit*signalingIPShare, burst*signalingIPShare, now).ReserveN(now, 1)
+	if delay := ipRes.DelayFrom(now); delay > 0 {
+		ipRes.CancelAt(now)
+		peerRes.CancelAt(now)
+		return delay
+	}
+	return 0
+}
+
+// bucket returns bucket of key, following limit changes from config reloads
+func (sl *signalingLimiter) bucket(key string, limit rate.Limit, burst int, now time.Time) *rate.Limiter {
+	bucket, ok := sl.buckets[key]
+	if !ok {
+		bucket = &signalingBucket{limiter: rate.NewLimiter(limit, burst)}
+		sl.buckets[key] = bucket
+	} else if bucket.limiter.Limit() != limit || bucket.limiter.Burst() != burst {
+		bucket.limiter.SetLimitAt(now, limit)
+		bucket.limiter.SetBurstAt(now, burst)
+	}
+	bucket.lastSeen = now
+	return bucket.limiter
+}
+
+// throttleStream checks if remote peer may open another signaling stream, returns how long it has to wait if not.
+// Relays of the mesh aren't limited, they open streams for all rooms they pull.
+func (r *Relay) throttleStream(conn network.Conn) time.Duration {
+	perMinute := common.GetFlags().StreamRate
+	if perMinute <= 0 || r.isMeshRelay(conn.RemotePeer()) {
+		return 0
+	}
+	return r.streamLimiter.reserve(conn.RemotePeer(), r.RemoteIP(conn), rate.Limit(float64(perMinute)/60), streamRateBurst, time.Now())
+}
+
+// throttleMessage checks if remote peer may send another signaling message, returns how long it has to wait if not
+func (r *Relay) throttleMessage(conn network.Conn) time.Duration {
+	perSecond := common.GetFlags().MessageRate
+	if perSecond <= 0 || r.isMeshRelay(conn.RemotePeer()) {
+		return 0
+	}
+	return r.messageLimiter.reserve(conn.RemotePeer(), r.RemoteIP(conn), rate.Limit(perSecond), messageRateBurst, time.Now())
+}
+
+// messageThrottle drops signaling messages of a stream over the rate limit,
+// telling the peer once per run of dropped messages
+type messageThrottle struct {
+	relay    *Relay
+	stream   network.Stream
+	safeBRW  *common.SafeBufioRW
+	notified bool
+}
+
+func newMessageThrottle(relay *Relay, stream network.Stream, safeBRW *common.SafeBufioRW) *messageThrottle {
+	return &messageThrottle{relay: relay, stream: stream, safeBRW: safeBRW}
+}
+
+// drop returns true if received message is over the rate limit and must be dropped
+func (mt *messageThrottle) drop() bool {
+	wait := mt.relay.throttleMessage(mt.stream.Conn())
+	if wait <= 0 {
+		mt.notified = false
+		return false
+	}
+	signalingThrottledCounter.WithLabelValues(throttleScopeMessage).Inc()
+	if !mt.notified {
+		mt.notified = true
+		slog.Warn("Dropping signaling messages of peer over rate limit", "peer", mt.stream.Conn().RemotePeer(), "protocol", mt.stream.Protocol(), "retry_after", wait)
+		sendThrottled(mt.safeBRW, throttleScopeMessage, wait)
+	}
+	return true
+}
+
+// refuseThrottledStream checks stream opening rate of remote peer, refusing the stream if over it
+func (r *Relay) refuseThrottledStream(stream network.Stream, safeBRW *common.SafeBufioRW) bool {
+	wait := r.throttleStream(stream.Conn())
+	if wait <= 0 {
+		return false
+	}
+	signalingThrottledCounter.WithLabelValues(throttleScopeStream).Inc()
+	slog.Warn("Refusing signaling stream of peer over rate limit", "peer", stream.Conn().RemotePeer(), "protocol", stream.Protocol(), "retry_after", wait)
+	sendThrottled(safeBRW, throttleScopeStream, wait)
+	_ = stream.Close()
+	return true
+}
+
+// sendThrottled tells peer its signaling is rate limited
+func sendThrottled(safeBRW *common.SafeBufioRW, scope string, retryAfter time.Duration) {
+	throttledMsg, err := common.CreateMessage(
+		&gen.ProtoThrottled{
+			Scope:        scope,
+			RetryAfterMs: uint32(retryAfter.Milliseconds()) + 1,
+		},
+		"throttled", nil,
+	)
+	if err != nil {
+		slog.Error("Failed to create proto message", "err", err)
+		return
+	}
+	if err = safeBRW.SendProto(throttledMsg); err != nil {
+		slog.Debug("Failed to send throttled message", "scope", scope, "err", err)
+	}
+}
@@ -0,0 +1,114 @@
+package core
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+
+	"relay/internal/common"
+
+	"github.com/libp2p/go-libp2p/core/event"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	manet "github.com/multiformats/go-multiaddr/net"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// --- Reachability ---
+//
+// AutoNAT tells whether the relay is reachable from the internet. A private relay reserves circuit relay slots on
+// public mesh relays through AutoRelay, so other relays can still reach it, and warns when WebRTC has no address
+// but its LAN one to offer.
+
+var relayReachabilityGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "nestri_relay_reachability",
+	Help: "Reachability of the relay as detected by AutoNAT, 0 unknown, 1 public, 2 private",
+})
+
+// Reachability returns reachability of the relay as last detected by AutoNAT
+func (r *Relay) Reachability() network.Reachability {
+	return network.Reachability(r.reachability.Load())
+}
+
+// reachabilityWatcher follows reachability changes detected by AutoNAT until context is done
+func (r *Relay) reachabilityWatcher(ctx context.Context) {
+	sub, err := r.Host.EventBus().Subscribe(new(event.EvtLocalReachabilityChanged))
+	if err != nil {
+		slog.Warn("Failed to subscribe to reachability changes", "err", err)
+		return
+	}
+	defer func() {
+		_ = sub.Close()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-sub.Out():
+			if !ok {
+				return
+			}
+			r.setReachability(e.(event.EvtLocalReachabilityChanged).Reachability)
+		}
+	}
+}
+
+// setReachability records a reachability change and reacts to the relay becoming private
+func (r *Relay) setReachability(reachability network.Reachability) {
+	if network.Reachability(r.reachability.Swap(int32(reachability))) == reachability {
+		return
+	}
+	relayReachabilityGauge.Set(float64(reachability))
+	r.Events.Publish(Event{Type: EventReachabilityChanged, Attrs: map[string]string{
+		"reachability": reachability.String(),
+	}})
+
+	if reachability != network.ReachabilityPrivate {
+		slog.Info("Relay reachability changed", "reachability", reachability)
+		return
+	}
+	slog.Warn("Relay isn't reachable from the internet, reserving circuit relay slots on public mesh relays")
+	if flags := common.GetFlags(); len(flags.NAT11IP) <= 0 && !flags.PortMapping {
+		slog.Warn("WebRTC only offers host candidates of LAN addresses, viewers outside the LAN need STUN to connect, set webrtcNAT11IP or portMapping")
+	}
+}
+
+// circuitRelayCandidates returns connected mesh relays with public addresses, for AutoRelay to reserve slots on
+func (r *Relay) circuitRelayCandidates(num int) []peer.AddrInfo {
+	var candidates []peer.AddrInfo
+	for id, pi := range r.Peers.Copy() {
+		if len(candidates) >= num {
+			break
+		}
+		if !r.isConnected(id) || !r.isMeshRelay(id) {
+			continue
+		}
+		info := peer.AddrInfo{ID: id}
+		for _, addr := range pi.Addrs {
+			if manet.IsPublicAddr(addr) {
+				info.Addrs = append(info.Addrs, addr)
+			}
+		}
+		if len(info.Addrs) > 0 {
+			candidates = append(candidates, info)
+		}
+	}
+	return candidates
+}
+
+// circuitRelaySource feeds AutoRelay of a host with mesh relays, once the relay on it is set up
+func circuitRelaySource(relay *atomic.Pointer[Relay]) func(context.Context, int) <-chan peer.AddrInfo {
+	return func(_ context.Context, num int) <-chan peer.AddrInfo {
+		var candidates []peer.AddrInfo
+		if r := relay.Load(); r != nil {
+			candidates = r.circuitRelayCandidates(num)
+		}
+		ch := make(chan peer.AddrInfo, len(candidates))
+		for _, info := range candidates {
+			ch <- info
+		}
+		close(ch)
+		return ch
+	}
+}
@@ -0,0 +1,154 @@
+package core
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"relay/internal/common"
+	"relay/internal/shared"
+)
+
+// --- Room Reconciliation ---
+//
+// Relays cut off from each other may each accept a push of the same room, or keep announcing a room whose push they
+// lost. Owners announce ownership claims with their room states, once the mesh heals every relay keeps the claim
+// winning by shared.RoomInfo.ClaimBeats and relays holding losing ones give the room up, so viewers request it again
+// and get routed to the winning owner.
+
+// roomEpoch is the highest ownership epoch seen for a room name
+type roomEpoch struct {
+	epoch uint64
+	seen  time.Time
+}
+
+// observeRoomEpoch remembers epoch of a claim, so pushes after a partition heals outrank claims made before it
+func (r *Relay) observeRoomEpoch(roomName string, epoch uint64, now time.Time) {
+	if known, ok := r.roomEpochs.Get(roomName); !ok || known.epoch <= epoch {
+		r.roomEpochs.Set(roomName, roomEpoch{epoch: epoch, seen: now})
+	}
+}
+
+// nextRoomEpoch returns epoch for a push of a room, past every epoch known for its name
+func (r *Relay) nextRoomEpoch(roomName string, now time.Time) uint64 {
+	known, _ := r.roomEpochs.Get(roomName)
+	epoch := known.epoch
+	for _, info := range r.Rooms.Copy() {
+		if info.Name == roomName {
+			epoch = max(epoch, info.Epoch)
+		}
+	}
+	if room := r.GetRoomByName(roomName); room != nil {
+		current, _ := room.GetClaim()
+		epoch = max(epoch, current)
+	}
+	epoch++
+	r.roomEpochs.Set(roomName, roomEpoch{epoch: epoch, seen: now})
+	return epoch
+}
+
+// localClaim returns ownership claim a local room is held under, of this relay or of the owner it's pulled from
+func localClaim(room *shared.Room) shared.RoomInfo {
+	epoch, claimedAt := room.GetClaim()
+	return shared.RoomInfo{
+		ID:        room.ID,
+		Name:      room.Name,
+		OwnerID:   room.OwnerID,
+		Online:    room.IsOnline(),
+		Epoch:     epoch,
+		ClaimedAt: claimedAt,
+	}
+}
+
+// roomClaim returns the claim this relay accepted for a room of another owner
+func (r *Relay) roomClaim(roomName string, now time.Time) (shared.RoomInfo, bool) {
+	for _, info := range r.Rooms.Copy() {
+		if info.Name == roomName && !info.LeaseExpired(now) {
+			return info, true
+		}
+	}
+	return shared.RoomInfo{}, false
+}
+
+// reconcileRoomClaim settles a claim announced by a room owner against claims known for the room, returns false if
+// the claim lost and is to be ignored. Local rooms held under a losing claim are given up.
+func (r *Relay) reconcileRoomClaim(claim shared.RoomInfo, now time.Time) bool {
+	r.observeRoomEpoch(claim.Name, claim.Epoch, now)
+
+	for id, known := range r.Rooms.Copy() {
+		if known.Name != claim.Name || known.ID == claim.ID || known.LeaseExpired(now) {
+			continue
+		}
+		if known.ClaimBeats(claim) {
+			slog.Debug("Ignoring losing claim of room", "room", claim.Name, "owner_id", claim.OwnerID, "epoch", claim.Epoch, "winner", known.OwnerID)
+			return false
+		}
+		slog.Info("Claim of room lost to newer one", "room", claim.Name, "owner_id", known.OwnerID, "epoch", known.Epoch, "winner", claim.OwnerID)
+		r.Rooms.Delete(id)
+	}
+
+	// Owners creating a room again under a new ID don't conflict with themselves
+	room := r.GetRoomByName(claim.Name)
+	if room == nil || room.ID == claim.ID || room.OwnerID == claim.OwnerID {
+		return true
+	}
+	held := localClaim(room)
+	if held.ClaimBeats(claim) {
+		// Our announcements make the other owner give up its claim
+		slog.Info("Keeping room claimed by another relay as well", "room", room.Name, "owner_id", held.OwnerID, "epoch", held.Epoch, "loser", claim.OwnerID)
+		return false
+	}
+	r.yieldRoom(room, claim)
+	return true
+}
+
+// yieldRoom gives up a local room held under a losing claim, its viewers are told and disconnected to request it again
+func (r *Relay) yieldRoom(room *shared.Room, winner shared.RoomInfo) {
+	slog.Warn("Giving up room claimed by another relay", "room", room.Name, "owner_id", room.OwnerID, "winner", winner.OwnerID, "epoch", winner.Epoch)
+	r.Events.Publish(Event{Type: EventRoomSuperseded, Room: room.Name, PeerID: winner.OwnerID, Attrs: map[string]string{
+		"owner": room.OwnerID.String(),
+		"epoch": strconv.FormatUint(winner.Epoch, 10),
+	}})
+	for _, participant := range room.GetParticipants() {
+		if err := r.SendNotice(participant, "Room moved to another relay, reconnecting", "warning"); err != nil {
+			slog.Debug("Failed to send room moved notice to participant", "room", room.Name, "participant", participant.ID, "err", err)
+		}
+	}
+	if room.OwnerID != r.ID {
+		r.StreamProtocol.releaseUpstream(room)
+	}
+	r.CloseRoom(room)
+
+	// Let mesh relays drop our routes to the room without waiting for next periodic publish
+	go func() {
+		if err := r.publishRoomStates(context.Background()); err != nil {
+			slog.Error("Failed to publish room states after giving up room", "err", err)
+		}
+	}()
+}
+
+// roomClaimSweeper forgets claims owners stopped renewing, so rooms of relays we no longer hear from aren't
+// announced online forever, and epochs of rooms long gone
+func (r *Relay) roomClaimSweeper(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(common.GetFlags().StatusInterval) * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for id, info := range r.Rooms.Copy() {
+				if info.LeaseExpired(now) {
+					slog.Info("Forgetting room whose owner stopped renewing its claim", "room", info.Name, "owner_id", info.OwnerID, "online", info.Online)
+					r.Rooms.Delete(id)
+				}
+			}
+			for name, known := range r.roomEpochs.Copy() {
+				if now.Sub(known.seen) >= roomEpochRetention && r.GetRoomByName(name) == nil {
+					r.roomEpochs.Delete(name)
+				}
+			}
+		}
+	}
+}
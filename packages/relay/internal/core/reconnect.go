@@ -0,0 +1,81 @@
+package core
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// --- Mesh Reconnect Supervisor ---
+
+// scheduleReconnect adds a dropped or failed mesh peer to the reconnect supervisor, static peers have their own
+func (r *Relay) scheduleReconnect(pi *PeerInfo) {
+	if pi == nil || pi.ID == r.ID || len(pi.Addrs) <= 0 || r.isStaticPeer(pi.ID) {
+		return
+	}
+	if !r.reconnectPeers.Has(pi.ID) {
+		slog.Debug("Scheduling reconnect to mesh peer", "peer", pi.ID, "next_dial", pi.NextDialAt)
+	}
+	r.reconnectPeers.Set(pi.ID, pi)
+}
+
+// reconnectSupervisor keeps retrying dropped mesh peers with jittered backoff until they connect or we give up
+func (r *Relay) reconnectSupervisor(ctx context.Context) {
+	ticker := time.NewTicker(reconnectCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Stopping reconnect supervisor")
+			return
+		case now := <-ticker.C:
+			for id, pi := range r.reconnectPeers.Copy() {
+				if r.Host.Network().Connectedness(id) == network.Connected {
+					r.reconnectPeers.Delete(id)
+					continue
+				}
+				if pi.DialFailures >= peerMaxDialFailures {
+					slog.Warn("Giving up reconnecting to mesh peer", "peer", id, "failures", pi.DialFailures)
+					r.reconnectPeers.Delete(id)
+					r.Events.Publish(Event{Type: EventPeerGaveUp, PeerID: id, Attrs: map[string]string{
+						"failures": strconv.Itoa(pi.DialFailures),
+					}})
+					continue
+				}
+				if !pi.canDial(now) {
+					continue
+				}
+
+				// Push next attempt ahead so a slow dial isn't started twice
+				pi.NextDialAt = now.Add(reconnectDialTimeout)
+				go r.reconnectPeer(ctx, id, pi)
+			}
+		}
+	}
+}
+
+// reconnectPeer makes a single reconnect attempt
+func (r *Relay) reconnectPeer(ctx context.Context, id peer.ID, pi *PeerInfo) {
+	r.Events.Publish(Event{Type: EventPeerReconnecting, PeerID: id, Attrs: map[string]string{
+		"attempt": strconv.Itoa(pi.DialFailures + 1),
+	}})
+
+	dialCtx, cancel := context.WithTimeout(ctx, reconnectDialTimeout)
+	defer cancel()
+	if err := r.ConnectToKnownPeer(dialCtx, pi); err != nil {
+		slog.Debug("Reconnect to mesh peer failed", "peer", id, "failures", pi.DialFailures, "next_dial", pi.NextDialAt, "err", err)
+		return
+	}
+
+	slog.Info("Reconnected to mesh peer", "peer", id)
+	r.reconnectPeers.Delete(id)
+	// Keep peer in peer store with updated dial metadata
+	if !r.Peers.Has(id) {
+		r.Peers.Set(id, pi)
+	}
+}
@@ -0,0 +1,85 @@
+package core
+
+import (
+	"context"
+	"log/slog"
+	"relay/internal/shared"
+	"time"
+)
+
+// --- Session Resumption ---
+
+// resumableSession is RTP numbering a viewer session was sent before its participant left
+type resumableSession struct {
+	state shared.RTPState
+	left  time.Time
+}
+
+// rememberSession keeps RTP numbering of a leaving participant, so its session resuming soon continues it
+func (sp *StreamProtocol) rememberSession(participant *shared.Participant) {
+	now := time.Now()
+	for sessionID, session := range sp.resumable.Copy() {
+		if now.Sub(session.left) > sessionResumeTTL {
+			sp.resumable.Delete(sessionID)
+		}
+	}
+	session := resumableSession{
+		state: participant.RTPState(),
+		left:  now,
+	}
+	sp.resumable.Set(participant.SessionID, session)
+
+	// The viewer may come back over another relay of the cluster
+	go sp.relay.sharedOp("put session", func(ctx context.Context, state SharedState) error {
+		return state.PutSession(ctx, SnapshotSession{SessionID: participant.SessionID, State: session.state, Left: session.left})
+	})
+}
+
+// resumeSession continues RTP numbering of an earlier participant of the session in the room or its variants.
+// A participant of the session still served is replaced, its connection went stale if the viewer came back
+// over a new one. Otherwise numbering of one which left within sessionResumeTTL is continued.
+func (sp *StreamProtocol) resumeSession(roomName string, participant *shared.Participant) {
+	base := shared.BaseRoomName(roomName)
+	for name, roomMap := range sp.servedConns.Copy() {
+		if shared.BaseRoomName(name) != base {
+			continue
+		}
+		for peerID, conn := range roomMap.Copy() {
+			previous := conn.participant
+			if previous == nil || previous == participant || previous.SessionID != participant.SessionID {
+				continue
+			}
+			slog.Info("Session resumed over new connection, replacing its previous participant", "room", name, "session", participant.SessionID, "previous_peer", peerID)
+			participant.ResumeRTP(previous.RTPState())
+			// Controllers stay attached to the session, their feedback follows it to the new connection
+			for _, slot := range previous.ControllerSlots() {
+				participant.ClaimControllerSlot(slot)
+			}
+			// Closing the PeerConnection removes the participant
+			if err := conn.pc.Close(); err != nil {
+				slog.Error("Failed to close PeerConnection of replaced participant", "room", name, "session", participant.SessionID, "err", err)
+			}
+			sp.resumable.Delete(participant.SessionID)
+			return
+		}
+	}
+
+	// Taking the shared copy too keeps other relays of the cluster from resuming the session again
+	session, ok := sp.resumable.Get(participant.SessionID)
+	sp.relay.sharedOp("take session", func(ctx context.Context, state SharedState) error {
+		taken, found, err := state.TakeSession(ctx, participant.SessionID)
+		if found && !ok {
+			session, ok = resumableSession{state: taken.State, left: taken.Left}, true
+		}
+		return err
+	})
+	if !ok {
+		return
+	}
+	sp.resumable.Delete(participant.SessionID)
+	if time.Since(session.left) > sessionResumeTTL {
+		return
+	}
+	slog.Debug("Session resumed, continuing RTP numbering of its previous participant", "room", roomName, "session", participant.SessionID, "left", session.left)
+	participant.ResumeRTP(session.state)
+}
@@ -3,12 +3,20 @@ package core
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"relay/internal/common"
 	"relay/internal/shared"
+	"strings"
+	"time"
+
+	gen "relay/internal/proto"
 
 	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/oklog/ulid/v2"
+	"google.golang.org/protobuf/proto"
 )
 
 // --- Room Management ---
@@ -37,9 +45,178 @@ func (r *Relay) CreateRoom(name string) *shared.Room {
 	room := shared.NewRoom(name, roomID, r.ID)
 	r.LocalRooms.Set(room.ID, room)
 	slog.Debug("Created new local room", "room", name, "id", room.ID)
+	r.Events.Publish(Event{Type: EventRoomCreated, Room: name})
+	return room
+}
+
+// CreateRoomFromRoute creates a new local Room struct for a room hosted by another relay
+func (r *Relay) CreateRoomFromRoute(info shared.RoomInfo) *shared.Room {
+	room := shared.NewRoom(info.Name, info.ID, info.OwnerID)
+	room.SetClaim(info.Epoch, info.ClaimedAt)
+	room.SetSettings(info.Settings)
+	room.SetMetadata(info.Metadata)
+	r.LocalRooms.Set(room.ID, room)
+	slog.Debug("Created new local room for remote room", "room", info.Name, "id", room.ID, "owner_id", info.OwnerID)
+	r.Events.Publish(Event{Type: EventRoomCreated, Room: info.Name, PeerID: info.OwnerID})
 	return room
 }
 
+// CloseRoom disconnects all participants and the stream of a local room, and forgets it
+func (r *Relay) CloseRoom(room *shared.Room) {
+	for _, participant := range room.GetParticipants() {
+		room.RemoveParticipantByID(participant.ID)
+		participant.Close()
+	}
+	room.Close()
+	r.LocalRooms.Delete(room.ID)
+	r.Usage.Forget(room)
+	slog.Info("Closed local room", "room", room.Name, "id", room.ID)
+	r.Events.Publish(Event{Type: EventRoomClosed, Room: room.Name})
+}
+
+// KickParticipant disconnects a participant from a local room and invalidates its session, returns false if not found
+func (r *Relay) KickParticipant(room *shared.Room, participantID ulid.ULID, reason string) bool {
+	participant := room.GetParticipantByID(participantID)
+	if participant == nil {
+		return false
+	}
+	r.removeParticipant(room, participant, &gen.ProtoModeration{
+		RoomName: room.Name,
+		Action:   moderationKicked,
+		Reason:   reason,
+	})
+	return true
+}
+
+// maxViewers returns the max participants a room admits on this relay, 0 means unlimited
+func (r *Relay) maxViewers(settings shared.RoomSettings) int {
+	if settings.MaxViewers > 0 {
+		return settings.MaxViewers
+	}
+	return common.GetFlags().MaxViewers
+}
+
+// roomFull returns the response for a viewer the room can't admit anymore, nil if it has space and budget left
+func (r *Relay) roomFull(room *shared.Room) *gen.ProtoRoomFull {
+	maxViewers := r.maxViewers(room.GetSettings())
+	count := room.ParticipantCount()
+	if !room.AdmitsParticipant() {
+		// Room used up its goroutine or track budget, it admits the viewers it already has
+		slog.Warn("Room resource budget used up", "room", room.Name, "viewers", count)
+		maxViewers = count
+	} else if maxViewers <= 0 || count < maxViewers {
+		return nil
+	}
+	return &gen.ProtoRoomFull{
+		RoomName:    room.Name,
+		ViewerCount: uint32(count),
+		MaxViewers:  uint32(maxViewers),
+	}
+}
+
+// UpdateRoomMetadata sets metadata of an owned room from the pushing node, announcing it to mesh relays right away
+func (r *Relay) UpdateRoomMetadata(room *shared.Room, metadata *gen.ProtoRoomMetadata) {
+	meta := shared.RoomMetadataFromProto(metadata)
+	meta.Title = truncateRunes(strings.ToValidUTF8(strings.TrimSpace(meta.Title), ""), roomMetadataMaxTitle)
+	meta.Game = truncateRunes(strings.ToValidUTF8(strings.TrimSpace(meta.Game), ""), roomMetadataMaxGame)
+	// Rooms the platform made private stay private whatever the pushing node says
+	if entry, err := r.directoryRoom(room.Name); err == nil && entry.Private {
+		meta.Private = true
+	}
+	// Thumbnails are ours to announce, private rooms have none
+	meta.ThumbnailURL = ""
+	if meta.Private {
+		r.thumbnails.Delete(room.Name)
+	} else {
+		meta.ThumbnailURL = r.thumbnailURL(room.Name)
+	}
+	room.SetMetadata(meta)
+	slog.Debug("Updated room metadata", "room", room.Name, "title", meta.Title, "game", meta.Game, "private", meta.Private)
+
+	if err := r.publishRoomStates(context.Background()); err != nil {
+		slog.Error("Failed to publish room states after metadata update", "room", room.Name, "err", err)
+	}
+}
+
+// CanMoveParticipants checks if participants of one local room can be switched to receive another without renegotiation
+func (r *Relay) CanMoveParticipants(from, to *shared.Room) error {
+	if from.ID == to.ID {
+		return errors.New("source and destination room are the same")
+	}
+	if !to.IsOnline() {
+		return errors.New("destination room is offline")
+	}
+	if !strings.EqualFold(from.AudioCodec().MimeType, to.AudioCodec().MimeType) {
+		return fmt.Errorf("audio codec mismatch: %s != %s", from.AudioCodec().MimeType, to.AudioCodec().MimeType)
+	}
+	if !from.GetSettings().AudioOnly && !to.GetSettings().AudioOnly && !strings.EqualFold(from.VideoCodec().MimeType, to.VideoCodec().MimeType) {
+		return fmt.Errorf("video codec mismatch: %s != %s", from.VideoCodec().MimeType, to.VideoCodec().MimeType)
+	}
+	if from.GetSettings().AudioOnly && !to.GetSettings().AudioOnly {
+		return errors.New("participants of audio-only room have no video track")
+	}
+	return nil
+}
+
+// MoveParticipant switches a participant to receive another local room, rooms must pass CanMoveParticipants
+func (r *Relay) MoveParticipant(from, to *shared.Room, participantID ulid.ULID) bool {
+	participant := from.GetParticipantByID(participantID)
+	if participant == nil {
+		return false
+	}
+	from.RemoveParticipantByID(participantID)
+	participant.MaxVideoAge = to.MaxVideoAge()
+	to.AddParticipant(participant)
+
+	// Move served connection along if the viewer has nothing else left in source room
+	sp := r.StreamProtocol
+	if fromConns, ok := sp.servedConns.Get(from.Name); ok {
+		if conn, ok := fromConns.Get(participant.PeerID); ok && !roomHasPeer(from, participant.PeerID) {
+			fromConns.Delete(participant.PeerID)
+			if fromConns.Len() == 0 {
+				sp.servedConns.Delete(from.Name)
+			}
+			toConns, ok := sp.servedConns.Get(to.Name)
+			if !ok {
+				toConns = common.NewSafeMap[peer.ID, *StreamConnection]()
+				sp.servedConns.Set(to.Name, toConns)
+			}
+			if !toConns.Has(participant.PeerID) {
+				toConns.Set(participant.PeerID, conn)
+			}
+		}
+	}
+
+	slog.Info("Moved participant between rooms", "from", from.Name, "to", to.Name, "participant", participantID)
+	return true
+}
+
+// SendNotice sends a relay notice to a participant over its data channel
+func (r *Relay) SendNotice(participant *shared.Participant, text, level string) error {
+	dc := participant.DataChannel()
+	if dc == nil {
+		return errors.New("participant has no data channel")
+	}
+	noticeMsg, err := common.CreateMessage(&gen.ProtoRelayNotice{Text: text, Level: level}, "relay-notice", nil)
+	if err != nil {
+		return err
+	}
+	data, err := proto.Marshal(noticeMsg)
+	if err != nil {
+		return err
+	}
+	return dc.SendBinary(data)
+}
+
+func roomHasPeer(room *shared.Room, peerID peer.ID) bool {
+	for _, participant := range room.GetParticipants() {
+		if participant.PeerID == peerID {
+			return true
+		}
+	}
+	return false
+}
+
 // DeleteRoomIfEmpty checks if a local room struct is inactive and can be removed
 func (r *Relay) DeleteRoomIfEmpty(room *shared.Room) {
 	if room == nil {
@@ -48,17 +225,17 @@ func (r *Relay) DeleteRoomIfEmpty(room *shared.Room) {
 	if len(room.Participants) <= 0 && r.LocalRooms.Has(room.ID) {
 		slog.Debug("Deleting empty room without participants", "room", room.Name)
 		r.LocalRooms.Delete(room.ID)
-		err := room.PeerConnection.Close()
-		if err != nil {
-			slog.Error("Failed to close Room PeerConnection", "room", room.Name, "err", err)
-		}
+		r.Usage.Forget(room)
+		r.Events.Publish(Event{Type: EventRoomClosed, Room: room.Name})
+		// Close releases the PeerConnection if shared over a mesh link rather than closing it
+		room.Close()
 	}
 }
 
 // GetRemoteRoomByName returns room from mesh by name
 func (r *Relay) GetRemoteRoomByName(roomName string) *shared.RoomInfo {
 	for _, room := range r.Rooms.Copy() {
-		if room.Name == roomName && room.OwnerID != r.ID {
+		if room.Name == roomName && room.OwnerID != r.ID && !room.LeaseExpired(time.Now()) {
 			// Make sure connection is alive
 			if r.Host.Network().Connectedness(room.OwnerID) == network.Connected {
 				return &room
@@ -71,9 +248,130 @@ func (r *Relay) GetRemoteRoomByName(roomName string) *shared.RoomInfo {
 	return nil
 }
 
+// --- Room Garbage Collection ---
+
+// pullUnused returns true if a room pulled from another relay is forwarded to no local participant,
+// requests still being connected count as participants
+func (r *Relay) pullUnused(room *shared.Room) bool {
+	return room.OwnerID != r.ID && room.IsOnline() && len(room.UpstreamID()) > 0 &&
+		room.ParticipantCount() <= 0 && !r.StreamProtocol.servedConns.Has(room.Name)
+}
+
+// pullReaper stops pulling rooms which stayed without local participants longer than the pull linger,
+// so idle relays stop consuming upstream bandwidth
+func (r *Relay) pullReaper(ctx context.Context) {
+	ticker := time.NewTicker(pullLingerCheckInterval)
+	defer ticker.Stop()
+
+	unusedSince := make(map[ulid.ULID]time.Time)
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Stopping pull reaper")
+			return
+		case now := <-ticker.C:
+			linger := time.Duration(common.GetFlags().PullLinger) * time.Second
+			rooms := r.LocalRooms.Copy()
+			for id := range unusedSince {
+				if room, ok := rooms[id]; !ok || !r.pullUnused(room) {
+					delete(unusedSince, id)
+				}
+			}
+			if linger <= 0 {
+				continue
+			}
+
+			released := false
+			for id, room := range rooms {
+				if !r.pullUnused(room) {
+					continue
+				}
+				since, ok := unusedSince[id]
+				if !ok {
+					unusedSince[id] = now
+					continue
+				}
+				if unused := now.Sub(since); unused >= linger {
+					slog.Info("Releasing pulled room without local participants", "room", room.Name, "peer", room.UpstreamID(), "unused", unused)
+					r.StreamProtocol.releaseUpstream(room)
+					delete(unusedSince, id)
+					released = true
+				}
+			}
+
+			// Let mesh relays drop our routes to released rooms without waiting for next periodic publish
+			if released {
+				if err := r.publishRoomStates(ctx); err != nil {
+					slog.Error("Failed to publish room states after releasing pulled rooms", "err", err)
+				}
+			}
+		}
+	}
+}
+
+// roomIdle returns true if a local room has no stream and no participants
+func roomIdle(room *shared.Room) bool {
+	return !room.IsOnline() && room.ParticipantCount() <= 0
+}
+
+// roomGarbageCollector closes local rooms which stayed idle longer than the idle TTL,
+// rooms whose push died and never came back would otherwise linger forever
+func (r *Relay) roomGarbageCollector(ctx context.Context) {
+	ticker := time.NewTicker(roomGCInterval)
+	defer ticker.Stop()
+
+	idleSince := make(map[ulid.ULID]time.Time)
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Stopping room garbage collector")
+			return
+		case now := <-ticker.C:
+			ttl := time.Duration(common.GetFlags().RoomIdleTTL) * time.Second
+			rooms := r.LocalRooms.Copy()
+			for id := range idleSince {
+				if room, ok := rooms[id]; !ok || !roomIdle(room) {
+					delete(idleSince, id)
+				}
+			}
+			if ttl <= 0 {
+				continue
+			}
+
+			expired := false
+			for id, room := range rooms {
+				if !roomIdle(room) {
+					continue
+				}
+				since, ok := idleSince[id]
+				if !ok {
+					idleSince[id] = now
+					continue
+				}
+				if idle := now.Sub(since); idle >= ttl {
+					slog.Info("Removing idle room", "room", room.Name, "id", room.ID, "idle", idle)
+					r.Events.Publish(Event{Type: EventRoomExpired, Room: room.Name, PeerID: room.OwnerID, Attrs: map[string]string{
+						"idle": idle.Round(time.Second).String(),
+					}})
+					r.CloseRoom(room)
+					delete(idleSince, id)
+					expired = true
+				}
+			}
+
+			// Let mesh relays drop routes to removed rooms without waiting for next periodic publish
+			if expired {
+				if err := r.publishRoomStates(ctx); err != nil {
+					slog.Error("Failed to publish room states after removing idle rooms", "err", err)
+				}
+			}
+		}
+	}
+}
+
 // --- State Publishing ---
 
-// publishRoomStates publishes the state of all rooms currently owned by *this* relay
+// publishRoomStates publishes the state of all rooms currently owned or forwarded by *this* relay
 func (r *Relay) publishRoomStates(ctx context.Context) error {
 	if r.pubTopicState == nil {
 		slog.Warn("Cannot publish room states: topic is nil")
@@ -82,19 +380,33 @@ func (r *Relay) publishRoomStates(ctx context.Context) error {
 
 	var statesToPublish []shared.RoomInfo
 	r.LocalRooms.Range(func(id ulid.ULID, room *shared.Room) bool {
-		// Only publish state for rooms owned by this relay
-		if room.OwnerID == r.ID {
+		// Publish state for rooms owned by this relay, and online rooms we can forward unless draining
+		if room.OwnerID == r.ID || (room.IsOnline() && !r.IsDraining()) {
+			hops, pathLatency := r.roomPath(room)
+			epoch, claimedAt := room.GetClaim()
 			statesToPublish = append(statesToPublish, shared.RoomInfo{
-				ID:      room.ID,
-				Name:    room.Name,
-				OwnerID: r.ID,
+				ID:           room.ID,
+				Name:         room.Name,
+				OwnerID:      room.OwnerID,
+				Settings:     room.GetSettings(),
+				Metadata:     room.GetMetadata(),
+				Viewers:      room.ParticipantCount(),
+				Online:       room.IsOnline(),
+				LocalViewers: r.localViewers(room),
+				RelayID:      r.ID,
+				Hops:         hops,
+				PathLatency:  pathLatency,
+				Bitrate:      roomBitrate(room),
+				Epoch:        epoch,
+				ClaimedAt:    claimedAt,
 			})
 		}
 		return true // Continue iteration
 	})
 
-	if len(statesToPublish) == 0 {
-		return nil
+	// Publish even when empty, so peers drop routes we no longer serve
+	if statesToPublish == nil {
+		statesToPublish = []shared.RoomInfo{}
 	}
 
 	data, err := json.Marshal(statesToPublish)
@@ -0,0 +1,250 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"relay/internal/common"
+	"relay/internal/shared"
+
+	_ "github.com/lib/pq"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// --- Room Directory Backend ---
+//
+// The platform's control plane may keep the authoritative list of rooms in an external backend. Relays then only
+// serve rooms registered there, record which relay holds each room under epochs the backend issues, so claims made by
+// relays that never met still order, and apply the access policy the control plane set. Backends are picked by
+// scheme of the room directory URL.
+
+// DirectoryRoom is a room registered in the room directory backend
+type DirectoryRoom struct {
+	Name       string
+	OwnerID    peer.ID // Relay holding the room's push, empty if none
+	Epoch      uint64  // Ownership epoch of the last claim
+	Disabled   bool    // Closed by the control plane, pushes and views are refused
+	Private    bool    // Left out of room directories whatever the pushing node says
+	MaxViewers int     // Max participants per relay, 0 leaves it to the pushing node and relay default
+}
+
+// RoomDirectory is an authoritative room directory backend
+type RoomDirectory interface {
+	Lookup(ctx context.Context, roomName string) (DirectoryRoom, bool, error)                          // False if room isn't registered
+	Claim(ctx context.Context, roomName string, owner peer.ID, minEpoch uint64) (DirectoryRoom, error) // Epoch is past minEpoch and the last claim's
+	Release(ctx context.Context, roomName string, owner peer.ID) error                                 // No-op if another relay holds the room by now
+	Close() error
+}
+
+var (
+	errRoomNotRegistered = errors.New("room is not registered in room directory")
+	errRoomDisabled      = errors.New("room is disabled in room directory")
+)
+
+// roomDirectoryBackends open room directories by URL scheme
+var roomDirectoryBackends = map[string]func(ctx context.Context, url string) (RoomDirectory, error){
+	"postgres":   openPostgresDirectory,
+	"postgresql": openPostgresDirectory,
+}
+
+// RegisterRoomDirectory makes a room directory backend available for URLs of given scheme, call before relay starts
+func RegisterRoomDirectory(scheme string, open func(ctx context.Context, url string) (RoomDirectory, error)) {
+	roomDirectoryBackends[scheme] = open
+}
+
+// OpenRoomDirectory opens the room directory backend of URL's scheme
+func OpenRoomDirectory(ctx context.Context, directoryURL string) (RoomDirectory, error) {
+	parsed, err := url.Parse(directoryURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse room directory URL: %w", err)
+	}
+	open, ok := roomDirectoryBackends[parsed.Scheme]
+	if !ok {
+		return nil, fmt.Errorf("no room directory backend for scheme %q", parsed.Scheme)
+	}
+	return open(ctx, directoryURL)
+}
+
+// --- Postgres Room Directory ---
+
+// postgresDirectorySchema creates the rooms table if the control plane hasn't, rows are added by the control plane
+const postgresDirectorySchema = `CREATE TABLE IF NOT EXISTS relay_rooms (
+	name        TEXT PRIMARY KEY,
+	owner_id    TEXT,
+	epoch       BIGINT NOT NULL DEFAULT 0,
+	disabled    BOOLEAN NOT NULL DEFAULT FALSE,
+	private     BOOLEAN NOT NULL DEFAULT FALSE,
+	max_viewers INTEGER NOT NULL DEFAULT 0,
+	claimed_at  TIMESTAMPTZ
+)`
+
+// PostgresDirectory is a RoomDirectory in the relay_rooms table of a Postgres database
+type PostgresDirectory struct {
+	db *sql.DB
+}
+
+func openPostgresDirectory(ctx context.Context, directoryURL string) (RoomDirectory, error) {
+	return OpenPostgresDirectory(ctx, directoryURL)
+}
+
+// OpenPostgresDirectory connects to Postgres at URL, creating the rooms table if missing
+func OpenPostgresDirectory(ctx context.Context, directoryURL string) (*PostgresDirectory, error) {
+	db, err := sql.Open("postgres", directoryURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open room directory: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, roomDirectoryTimeout)
+	defer cancel()
+	if _, err = db.ExecContext(ctx, postgresDirectorySchema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to create room directory table: %w", err)
+	}
+	return &PostgresDirectory{db: db}, nil
+}
+
+// scanDirectoryRoom reads a room row of owner_id, epoch, disabled, private and max_viewers
+func scanDirectoryRoom(row *sql.Row, roomName string) (DirectoryRoom, error) {
+	room := DirectoryRoom{Name: roomName}
+	var owner sql.NullString
+	var epoch int64
+	if err := row.Scan(&owner, &epoch, &room.Disabled, &room.Private, &room.MaxViewers); err != nil {
+		return DirectoryRoom{}, err
+	}
+	if owner.Valid && len(owner.String) > 0 {
+		ownerID, err := peer.Decode(owner.String)
+		if err != nil {
+			return DirectoryRoom{}, fmt.Errorf("invalid owner of room %q in room directory: %w", roomName, err)
+		}
+		room.OwnerID = ownerID
+	}
+	room.Epoch = uint64(epoch)
+	return room, nil
+}
+
+func (d *PostgresDirectory) Lookup(ctx context.Context, roomName string) (DirectoryRoom, bool, error) {
+	room, err := scanDirectoryRoom(d.db.QueryRowContext(ctx,
+		"SELECT owner_id, epoch, disabled, private, max_viewers FROM relay_rooms WHERE name = $1", roomName), roomName)
+	if errors.Is(err, sql.ErrNoRows) {
+		return DirectoryRoom{}, false, nil
+	}
+	return room, err == nil, err
+}
+
+func (d *PostgresDirectory) Claim(ctx context.Context, roomName string, owner peer.ID, minEpoch uint64) (DirectoryRoom, error) {
+	room, err := scanDirectoryRoom(d.db.QueryRowContext(ctx,
+		"UPDATE relay_rooms SET owner_id = $2, epoch = GREATEST(epoch + 1, $3), claimed_at = now() WHERE name = $1 "+
+			"RETURNING owner_id, epoch, disabled, private, max_viewers", roomName, owner.String(), int64(minEpoch)), roomName)
+	if errors.Is(err, sql.ErrNoRows) {
+		return DirectoryRoom{}, errRoomNotRegistered
+	}
+	return room, err
+}
+
+func (d *PostgresDirectory) Release(ctx context.Context, roomName string, owner peer.ID) error {
+	_, err := d.db.ExecContext(ctx, "UPDATE relay_rooms SET owner_id = NULL WHERE name = $1 AND owner_id = $2", roomName, owner.String())
+	return err
+}
+
+func (d *PostgresDirectory) Close() error {
+	return d.db.Close()
+}
+
+// --- Relay Room Directory ---
+
+// directoryLookup is a room directory answer kept for roomDirectoryCacheTTL
+type directoryLookup struct {
+	room  DirectoryRoom
+	found bool
+	at    time.Time
+}
+
+// UseRoomDirectory makes the relay serve only rooms registered in a room directory backend
+func (r *Relay) UseRoomDirectory(directory RoomDirectory) {
+	r.Directory = directory
+}
+
+// openRoomDirectory opens the room directory backend configured by flags, if any
+func (r *Relay) openRoomDirectory(ctx context.Context) error {
+	directoryURL := common.GetFlags().RoomDirectory
+	if len(directoryURL) <= 0 {
+		return nil
+	}
+	directory, err := OpenRoomDirectory(ctx, directoryURL)
+	if err != nil {
+		return err
+	}
+	slog.Info("Serving rooms registered in room directory")
+	r.UseRoomDirectory(directory)
+	return nil
+}
+
+// directoryRoom returns a room's directory entry, refusing rooms the directory doesn't register or disabled.
+// Quality variants share the entry of their room. Relays without a room directory allow every room, and a directory
+// out of reach allows rooms it wasn't asked about yet so streaming goes on while the control plane is down.
+func (r *Relay) directoryRoom(roomName string) (DirectoryRoom, error) {
+	if r.Directory == nil {
+		return DirectoryRoom{}, nil
+	}
+	roomName = shared.BaseRoomName(roomName)
+	now := time.Now()
+	cached, ok := r.directoryCache.Get(roomName)
+	if !ok || now.Sub(cached.at) >= roomDirectoryCacheTTL {
+		ctx, cancel := context.WithTimeout(context.Background(), roomDirectoryTimeout)
+		room, found, err := r.Directory.Lookup(ctx, roomName)
+		cancel()
+		if err != nil {
+			slog.Warn("Failed to look up room in room directory, using last answer", "room", roomName, "known", ok, "err", err)
+			if !ok {
+				return DirectoryRoom{Name: roomName}, nil
+			}
+		} else {
+			cached = directoryLookup{room: room, found: found, at: now}
+			r.directoryCache.Set(roomName, cached)
+		}
+	}
+	if !cached.found {
+		return DirectoryRoom{}, errRoomNotRegistered
+	}
+	if cached.room.Disabled {
+		return DirectoryRoom{}, errRoomDisabled
+	}
+	return cached.room, nil
+}
+
+// claimDirectoryRoom records this relay holding a pushed room, returns ownership epoch the directory issued.
+// Without a reachable directory the locally chosen epoch is used.
+func (r *Relay) claimDirectoryRoom(roomName string, epoch uint64) uint64 {
+	if r.Directory == nil {
+		return epoch
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), roomDirectoryTimeout)
+	defer cancel()
+	room, err := r.Directory.Claim(ctx, roomName, r.ID, epoch)
+	if err != nil {
+		slog.Warn("Failed to claim room in room directory, using own epoch", "room", roomName, "epoch", epoch, "err", err)
+		return epoch
+	}
+	r.directoryCache.Set(roomName, directoryLookup{room: room, found: true, at: time.Now()})
+	r.observeRoomEpoch(roomName, room.Epoch, time.Now())
+	return room.Epoch
+}
+
+// releaseDirectoryRoom records a room pushed to this relay going offline, in the background
+func (r *Relay) releaseDirectoryRoom(roomName string) {
+	// Quality variants aren't claimed, their room is
+	if r.Directory == nil || roomName != shared.BaseRoomName(roomName) {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), roomDirectoryTimeout)
+		defer cancel()
+		if err := r.Directory.Release(ctx, roomName, r.ID); err != nil {
+			slog.Warn("Failed to release room in room directory", "room", roomName, "err", err)
+		}
+	}()
+}
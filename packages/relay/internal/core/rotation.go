@@ -0,0 +1,339 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"relay/internal/common"
+	"time"
+
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multiaddr"
+)
+
+// --- Identity Rotation ---
+
+// errRotationPending is returned when rotating identity while a previous rotation hasn't retired yet
+var errRotationPending = errors.New("identity rotation is already pending")
+
+// SuccessorRecord announces the peer ID a relay rotates its identity to, signed by both old and new identity.
+// The old identity keeps serving until RetireAt, mesh peers then reconnect to the successor.
+type SuccessorRecord struct {
+	OldID        peer.ID               `json:"old_id"`
+	NewID        peer.ID               `json:"new_id"`
+	Addrs        []multiaddr.Multiaddr `json:"addrs"` // Where successor will listen, a dial hint
+	IssuedAt     time.Time             `json:"issued_at"`
+	RetireAt     time.Time             `json:"retire_at"`
+	OldSignature []byte                `json:"old_signature"` // Old identity hands over to new one
+	NewSignature []byte                `json:"new_signature"` // New identity proves it holds its key
+}
+
+// signedData returns what both identities sign, addresses are only hints and left out
+func (rec *SuccessorRecord) signedData() []byte {
+	return fmt.Appendf(nil, "nestri-relay-successor\n%s\n%s\n%d\n%d", rec.OldID, rec.NewID, rec.IssuedAt.Unix(), rec.RetireAt.Unix())
+}
+
+// Verify checks both signatures against public keys of old and new peer IDs
+func (rec *SuccessorRecord) Verify() error {
+	if rec.OldID == rec.NewID {
+		return errors.New("successor has the same peer ID")
+	}
+	if !rec.RetireAt.After(rec.IssuedAt) {
+		return errors.New("successor retires old identity before it was issued")
+	}
+	data := rec.signedData()
+	for _, check := range []struct {
+		id        peer.ID
+		signature []byte
+	}{{rec.OldID, rec.OldSignature}, {rec.NewID, rec.NewSignature}} {
+		pubKey, err := check.id.ExtractPublicKey()
+		if err != nil {
+			return fmt.Errorf("failed to extract public key of %s: %w", check.id, err)
+		}
+		ok, err := pubKey.Verify(data, check.signature)
+		if err != nil || !ok {
+			return fmt.Errorf("invalid signature of %s", check.id)
+		}
+	}
+	return nil
+}
+
+// nextIdentityKeyPath returns path of the key a pending rotation switches to
+func nextIdentityKeyPath(persistentDir string) string {
+	return filepath.Join(persistentDir, "identity.next.key")
+}
+
+// successorRecordPath returns path of the record of a pending rotation
+func successorRecordPath(persistentDir string) string {
+	return filepath.Join(persistentDir, "identity.successor.json")
+}
+
+// loadSuccessorRecord loads record of a pending rotation, nil if there is none
+func loadSuccessorRecord(persistentDir string) (*SuccessorRecord, error) {
+	data, err := os.ReadFile(successorRecordPath(persistentDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read successor record: %w", err)
+	}
+	var rec SuccessorRecord
+	if err = json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal successor record: %w", err)
+	}
+	return &rec, nil
+}
+
+// saveSuccessorRecord saves record of a pending rotation, replacing it atomically
+func saveSuccessorRecord(persistentDir string, rec *SuccessorRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal successor record: %w", err)
+	}
+	filePath := successorRecordPath(persistentDir)
+	tmpPath := filePath + ".tmp"
+	if err = os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to save successor record: %w", err)
+	}
+	if err = os.Rename(tmpPath, filePath); err != nil {
+		return fmt.Errorf("failed to replace successor record: %w", err)
+	}
+	return nil
+}
+
+// retirePendingIdentity replaces identity key with successor key once rotation grace period is over,
+// returning true if it did. Old key is overwritten, it must not be usable once retired.
+func retirePendingIdentity(persistentDir string, now time.Time) (bool, error) {
+	rec, err := loadSuccessorRecord(persistentDir)
+	if err != nil || rec == nil || now.Before(rec.RetireAt) {
+		return false, err
+	}
+	if err = os.Rename(nextIdentityKeyPath(persistentDir), identityKeyPath(persistentDir)); err != nil {
+		return false, fmt.Errorf("failed to replace identity key with successor: %w", err)
+	}
+	if err = os.Remove(successorRecordPath(persistentDir)); err != nil {
+		return true, fmt.Errorf("failed to remove successor record: %w", err)
+	}
+	slog.Info("Retired old identity, switching to successor", "old", rec.OldID, "new", rec.NewID)
+	return true, nil
+}
+
+// RotateIdentity generates a successor identity and announces it to the mesh. This relay keeps serving
+// on its current identity for grace period, then retires it and stops to be restarted on the successor.
+func (r *Relay) RotateIdentity(ctx context.Context, grace time.Duration) (*SuccessorRecord, error) {
+	persistentDir := common.GetFlags().PersistDir
+	if len(persistentDir) <= 0 {
+		return nil, errors.New("identity rotation needs a persistent directory")
+	}
+	if grace <= 0 {
+		grace = identityRotationGrace
+	}
+	if pending := r.rotation.Load(); pending != nil {
+		return nil, fmt.Errorf("%w, to %s", errRotationPending, pending.NewID)
+	}
+
+	oldKey := r.Host.Peerstore().PrivKey(r.ID)
+	if oldKey == nil {
+		return nil, errors.New("private key of current identity is unavailable")
+	}
+	passphrase, err := identityPassphrase(ctx)
+	if err != nil {
+		return nil, err
+	}
+	newPrivKey, err := common.GenerateED25519Key()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate successor identity: %w", err)
+	}
+	newKey, err := crypto.UnmarshalEd25519PrivateKey(newPrivKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal successor key: %w", err)
+	}
+	newID, err := peer.IDFromPrivateKey(newKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive successor peer ID: %w", err)
+	}
+
+	now := time.Now()
+	rec := &SuccessorRecord{
+		OldID:    r.ID,
+		NewID:    newID,
+		Addrs:    r.Host.Addrs(),
+		IssuedAt: now,
+		RetireAt: now.Add(grace),
+	}
+	if rec.OldSignature, err = oldKey.Sign(rec.signedData()); err != nil {
+		return nil, fmt.Errorf("failed to sign successor record: %w", err)
+	}
+	if rec.NewSignature, err = newKey.Sign(rec.signedData()); err != nil {
+		return nil, fmt.Errorf("failed to sign successor record: %w", err)
+	}
+
+	if !r.rotation.CompareAndSwap(nil, rec) {
+		return nil, errRotationPending
+	}
+	// Key first, a record without its key would retire us into nothing
+	if err = saveIdentity(newPrivKey, nextIdentityKeyPath(persistentDir), passphrase); err != nil {
+		r.rotation.Store(nil)
+		return nil, fmt.Errorf("failed to save successor key: %w", err)
+	}
+	if err = saveSuccessorRecord(persistentDir, rec); err != nil {
+		r.rotation.Store(nil)
+		return nil, err
+	}
+
+	slog.Info("Rotating relay identity", "old", rec.OldID, "new", rec.NewID, "retire_at", rec.RetireAt)
+	r.Events.Publish(Event{Type: EventIdentityRotating, PeerID: rec.NewID, Attrs: map[string]string{
+		"retire_at": rec.RetireAt.UTC().Format(time.RFC3339),
+	}})
+	if err = r.publishSuccessor(ctx, rec); err != nil {
+		slog.Error("Failed to publish successor record", "err", err)
+	}
+	return rec, nil
+}
+
+// resumeIdentityRotation picks up a rotation pending from before a restart
+func (r *Relay) resumeIdentityRotation(persistentDir string) {
+	rec, err := loadSuccessorRecord(persistentDir)
+	if err != nil {
+		slog.Error("Failed to load pending identity rotation", "err", err)
+		return
+	}
+	if rec == nil || rec.OldID != r.ID {
+		return
+	}
+	slog.Info("Resuming pending identity rotation", "new", rec.NewID, "retire_at", rec.RetireAt)
+	r.rotation.Store(rec)
+}
+
+// identityRotationSupervisor keeps announcing a pending rotation and retires the old identity once it's due
+func (r *Relay) identityRotationSupervisor(ctx context.Context) {
+	ticker := time.NewTicker(rotationCheckInterval)
+	defer ticker.Stop()
+
+	var announcedAt time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Stopping identity rotation supervisor")
+			return
+		case now := <-ticker.C:
+			rec := r.rotation.Load()
+			if rec == nil {
+				continue
+			}
+			if now.Before(rec.RetireAt) {
+				if now.Sub(announcedAt) < successorAnnounceInterval {
+					continue
+				}
+				announcedAt = now
+				if err := r.publishSuccessor(ctx, rec); err != nil {
+					slog.Error("Failed to publish successor record", "err", err)
+				}
+				continue
+			}
+
+			retired, err := retirePendingIdentity(common.GetFlags().PersistDir, now)
+			if err != nil {
+				slog.Error("Failed to retire old identity", "err", err)
+			}
+			if retired && r.stop != nil {
+				slog.Info("Stopping relay to restart on successor identity", "new", rec.NewID)
+				r.stop()
+				return
+			}
+		}
+	}
+}
+
+// publishSuccessor announces a successor record to mesh peers
+func (r *Relay) publishSuccessor(ctx context.Context, rec *SuccessorRecord) error {
+	if r.pubTopicSuccessors == nil {
+		return errors.New("successor topic is nil")
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return r.pubTopicSuccessors.Publish(ctx, data)
+}
+
+// handleSuccessorMessages processes identity rotations announced by peers.
+func (r *Relay) handleSuccessorMessages(ctx context.Context, sub *pubsub.Subscription) {
+	slog.Debug("Starting relay successor message handler...")
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Stopping relay successor message handler")
+			return
+		default:
+			msg, err := sub.Next(ctx)
+			if err != nil {
+				if errors.Is(err, context.Canceled) || errors.Is(err, pubsub.ErrSubscriptionCancelled) || errors.Is(err, context.DeadlineExceeded) {
+					slog.Info("Relay successor subscription ended", "err", err)
+					return
+				}
+				slog.Error("Error receiving relay successor message", "err", err)
+				time.Sleep(1 * time.Second)
+				continue
+			}
+			if msg.GetFrom() == r.Host.ID() {
+				continue
+			}
+
+			var rec SuccessorRecord
+			if err = json.Unmarshal(msg.Data, &rec); err != nil {
+				slog.Error("Failed to unmarshal successor record", "from", msg.GetFrom(), "data_len", len(msg.Data), "err", err)
+				continue
+			}
+			r.onSuccessorRecord(&rec)
+		}
+	}
+}
+
+// onSuccessorRecord remembers a verified successor of a mesh peer, so we follow it once the old identity retires
+func (r *Relay) onSuccessorRecord(rec *SuccessorRecord) {
+	if rec.OldID == r.ID {
+		return
+	}
+	if err := rec.Verify(); err != nil {
+		slog.Warn("Ignoring invalid successor record", "old", rec.OldID, "new", rec.NewID, "err", err)
+		return
+	}
+	if known, ok := r.successors.Get(rec.OldID); ok && known.NewID == rec.NewID {
+		return
+	}
+	slog.Info("Mesh peer announced identity successor", "old", rec.OldID, "new", rec.NewID, "retire_at", rec.RetireAt)
+	r.successors.Set(rec.OldID, rec)
+	r.Events.Publish(Event{Type: EventPeerSuccessor, PeerID: rec.OldID, Attrs: map[string]string{
+		"successor": rec.NewID.String(),
+		"retire_at": rec.RetireAt.UTC().Format(time.RFC3339),
+	}})
+}
+
+// successorToDial returns peer info of the successor of a disconnected peer whose identity retired,
+// nil if the peer has no successor or is still due to come back on its old identity
+func (r *Relay) successorToDial(peerID peer.ID, pi *PeerInfo, now time.Time) *PeerInfo {
+	rec, ok := r.successors.Get(peerID)
+	if !ok || now.Before(rec.RetireAt.Add(-successorClockSkew)) {
+		return nil
+	}
+	r.successors.Delete(peerID)
+
+	addrs := append([]multiaddr.Multiaddr{}, rec.Addrs...)
+	if pi != nil {
+		for _, addr := range pi.Addrs {
+			// Addresses of old identity may carry its /p2p component
+			if transport, _ := peer.SplitAddr(addr); transport != nil {
+				addrs = append(addrs, transport)
+			}
+		}
+	}
+	slog.Info("Following retired mesh peer to its successor", "old", peerID, "new", rec.NewID)
+	return NewPeerInfo(rec.NewID, addrs)
+}
@@ -0,0 +1,175 @@
+package core
+
+import (
+	"log/slog"
+	"relay/internal/common"
+	"relay/internal/shared"
+	"sort"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// --- Room Routes ---
+
+// latencyBudget returns the end-to-end latency budget for a room, 0 means unlimited
+func (r *Relay) latencyBudget(settings shared.RoomSettings) time.Duration {
+	if settings.LatencyBudget > 0 {
+		return settings.LatencyBudget
+	}
+	return time.Duration(common.GetFlags().LatencyBudget) * time.Millisecond
+}
+
+// linkLatency returns the one-way latency estimate to a peer, 0 if not measured yet
+func (r *Relay) linkLatency(peerID peer.ID) time.Duration {
+	if rtt, ok := r.Latencies.Get(peerID); ok {
+		return rtt / 2
+	}
+	return 0
+}
+
+// roomPath returns the relay hops and cumulative latency of a local room up to and including this relay
+func (r *Relay) roomPath(room *shared.Room) (int, time.Duration) {
+	upstreamID := room.UpstreamID()
+	if len(upstreamID) <= 0 {
+		return 0, room.HopLatency()
+	}
+	hops, latency := room.UpstreamPath()
+	return hops + 1, latency + r.linkLatency(upstreamID) + room.HopLatency()
+}
+
+// overLatencyBudget checks if serving a room to given peer would exceed the room latency budget
+func (r *Relay) overLatencyBudget(room *shared.Room, peerID peer.ID) bool {
+	budget := r.latencyBudget(room.GetSettings())
+	if budget <= 0 {
+		return false
+	}
+	_, latency := r.roomPath(room)
+	return latency+r.linkLatency(peerID) > budget
+}
+
+// zoneAffinity ranks a relay by its labels, 0 if in our zone, 1 if in our region and 2 otherwise
+func (r *Relay) zoneAffinity(peerID peer.ID) int {
+	pi, ok := r.Peers.Get(peerID)
+	switch {
+	case !ok || len(r.PeerInfo.Region) <= 0 || pi.Region != r.PeerInfo.Region:
+		return 2
+	case len(r.PeerInfo.Zone) > 0 && pi.Zone == r.PeerInfo.Zone:
+		return 0
+	default:
+		return 1
+	}
+}
+
+// updateRoomRoutes replaces routes announced by a peer with the given room states
+func (r *Relay) updateRoomRoutes(peerID peer.ID, states []shared.RoomInfo) {
+	announced := make(map[string]struct{}, len(states))
+	for _, state := range states {
+		if state.RelayID != peerID || state.OwnerID == r.ID {
+			continue
+		}
+		announced[state.Name] = struct{}{}
+
+		routes, ok := r.Routes.Get(state.Name)
+		if !ok {
+			routes = common.NewSafeMap[peer.ID, shared.RoomInfo]()
+			r.Routes.Set(state.Name, routes)
+		}
+		routes.Set(peerID, state)
+
+		// Keep path of rooms we pull from this peer up to date
+		if room := r.GetRoomByName(state.Name); room != nil && room.UpstreamID() == peerID {
+			room.SetUpstreamPath(state.Hops, state.PathLatency)
+		}
+	}
+
+	// Drop routes this peer no longer announces
+	for name, routes := range r.Routes.Copy() {
+		if _, ok := announced[name]; ok {
+			continue
+		}
+		if routes.Has(peerID) {
+			routes.Delete(peerID)
+			if routes.Len() == 0 {
+				r.Routes.Delete(name)
+			}
+		}
+	}
+}
+
+// removeRoomRoutes removes all routes served by given peer
+func (r *Relay) removeRoomRoutes(peerID peer.ID) {
+	r.updateRoomRoutes(peerID, nil)
+}
+
+// selectRoomRoutes returns routes for a room fitting within latency budget, preferring relays which aren't overloaded,
+// links with probed capacity left for the room, then relays and paths the steering policy prefers
+func (r *Relay) selectRoomRoutes(roomName string) []shared.RoomInfo {
+	routes, ok := r.Routes.Get(roomName)
+	if !ok {
+		return nil
+	}
+
+	type candidate struct {
+		info       shared.RoomInfo
+		latency    time.Duration
+		overloaded bool
+		saturated  bool // Probed capacity of the link from the relay can't carry the room on top of rooms pulled over it
+		affinity   int  // Zone affinity of the relay, only ranked by prefer-same-zone policy
+	}
+	policy := common.GetFlags().SteeringPolicy
+	winner, claimed := r.roomClaim(roomName, time.Now())
+	var candidates []candidate
+	for relayID, info := range routes.Copy() {
+		if relayID == r.ID || !info.Online || !r.hasConnectedPeer(relayID) {
+			continue
+		}
+		// Relays still forwarding the room of a losing owner lead to a stream about to be given up
+		if claimed && info.OwnerID != winner.OwnerID {
+			slog.Debug("Skipping room route of superseded owner", "room", roomName, "peer", relayID, "owner_id", info.OwnerID, "winner", winner.OwnerID)
+			continue
+		}
+
+		latency := info.PathLatency + r.linkLatency(relayID)
+		if budget := r.latencyBudget(info.Settings); budget > 0 && latency > budget {
+			slog.Debug("Skipping room route over latency budget", "room", roomName, "peer", relayID, "latency", latency, "budget", budget)
+			continue
+		}
+		c := candidate{
+			info:       info,
+			latency:    latency,
+			overloaded: r.isPeerOverloaded(relayID),
+			saturated:  !r.linkCanCarry(relayID, info.Bitrate),
+		}
+		if policy == common.SteeringPreferSameZone {
+			c.affinity = r.zoneAffinity(relayID)
+		}
+		candidates = append(candidates, c)
+	}
+
+	// Overloaded relays and saturated links are only pulled from when no other relay serves the room
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].overloaded != candidates[j].overloaded {
+			return !candidates[i].overloaded
+		}
+		if candidates[i].saturated != candidates[j].saturated {
+			return !candidates[i].saturated
+		}
+		if candidates[i].affinity != candidates[j].affinity {
+			return candidates[i].affinity < candidates[j].affinity
+		}
+		if policy == common.SteeringPreferLowestLatency && candidates[i].latency != candidates[j].latency {
+			return candidates[i].latency < candidates[j].latency
+		}
+		if candidates[i].info.Hops != candidates[j].info.Hops {
+			return candidates[i].info.Hops < candidates[j].info.Hops
+		}
+		return candidates[i].latency < candidates[j].latency
+	})
+
+	selected := make([]shared.RoomInfo, len(candidates))
+	for i, c := range candidates {
+		selected[i] = c.info
+	}
+	return selected
+}
@@ -0,0 +1,320 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"relay/internal/common"
+	"relay/internal/shared"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/redis/go-redis/v9"
+)
+
+// --- Shared State ---
+//
+// Relays of a cluster behind one load balancer see viewers reconnect to any of them. Session tokens, bans and the
+// room directory are kept in a shared backend so a session resumes and a ban holds on whichever relay the viewer
+// lands on. Relays still keep their own state, the backend is consulted when it doesn't know the answer and a
+// backend going away leaves each relay with what it knows.
+
+// SharedState is state shared by relays of a cluster
+type SharedState interface {
+	PutSession(ctx context.Context, session SnapshotSession) error                    // Kept until it can no longer resume
+	TakeSession(ctx context.Context, sessionID string) (SnapshotSession, bool, error) // Removes session, so only one relay resumes it
+	RevokeSession(ctx context.Context, sessionID string, until time.Time) error
+	SessionRevoked(ctx context.Context, sessionID string) (bool, error)
+	PutBan(ctx context.Context, ban RoomBan) error // Replaces ban of same ID, lifted bans are put with their new expiry
+	RoomBans(ctx context.Context, roomName string) ([]RoomBan, error)
+	PutRooms(ctx context.Context, rooms []shared.RoomInfo) error // Rooms are dropped once their LeaseExpiry passes
+	Rooms(ctx context.Context) ([]shared.RoomInfo, error)
+	Close() error
+}
+
+// RedisState is SharedState in Redis, keys of one cluster share a prefix
+type RedisState struct {
+	client *redis.Client
+	prefix string
+}
+
+// OpenRedisState connects to Redis at URL, failing if it can't be reached
+func OpenRedisState(ctx context.Context, url, prefix string) (*RedisState, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
+	}
+	s := &RedisState{client: redis.NewClient(opts), prefix: prefix}
+
+	pingCtx, cancel := context.WithTimeout(ctx, sharedStateTimeout)
+	defer cancel()
+	if err = s.client.Ping(pingCtx).Err(); err != nil {
+		_ = s.client.Close()
+		return nil, fmt.Errorf("failed to reach Redis: %w", err)
+	}
+	return s, nil
+}
+
+// key returns Redis key of parts under the cluster prefix
+func (s *RedisState) key(parts ...string) string {
+	return s.prefix + strings.Join(parts, ":")
+}
+
+func (s *RedisState) PutSession(ctx context.Context, session SnapshotSession) error {
+	ttl := sessionResumeTTL - time.Since(session.Left)
+	if ttl <= 0 {
+		return nil
+	}
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+	return s.client.Set(ctx, s.key("session", session.SessionID), data, ttl).Err()
+}
+
+func (s *RedisState) TakeSession(ctx context.Context, sessionID string) (SnapshotSession, bool, error) {
+	data, err := s.client.GetDel(ctx, s.key("session", sessionID)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return SnapshotSession{}, false, nil
+	} else if err != nil {
+		return SnapshotSession{}, false, err
+	}
+	var session SnapshotSession
+	if err = json.Unmarshal(data, &session); err != nil {
+		return SnapshotSession{}, false, fmt.Errorf("failed to unmarshal session: %w", err)
+	}
+	return session, true, nil
+}
+
+func (s *RedisState) RevokeSession(ctx context.Context, sessionID string, until time.Time) error {
+	ttl := time.Until(until)
+	if ttl <= 0 {
+		return nil
+	}
+	return s.client.Set(ctx, s.key("revoked", sessionID), 1, ttl).Err()
+}
+
+func (s *RedisState) SessionRevoked(ctx context.Context, sessionID string) (bool, error) {
+	n, err := s.client.Exists(ctx, s.key("revoked", sessionID)).Result()
+	return n > 0, err
+}
+
+func (s *RedisState) PutBan(ctx context.Context, ban RoomBan) error {
+	data, err := json.Marshal(ban)
+	if err != nil {
+		return fmt.Errorf("failed to marshal ban: %w", err)
+	}
+	// Every ban of the room is over after the longest one issued and its retention
+	key := s.key("bans", ban.Room)
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, ban.ID.String(), data)
+		pipe.Expire(ctx, key, roomBanMaxDuration+roomBanRetention)
+		return nil
+	})
+	return err
+}
+
+func (s *RedisState) RoomBans(ctx context.Context, roomName string) ([]RoomBan, error) {
+	fields, err := s.client.HGetAll(ctx, s.key("bans", roomName)).Result()
+	if err != nil {
+		return nil, err
+	}
+	bans := make([]RoomBan, 0, len(fields))
+	for id, data := range fields {
+		var ban RoomBan
+		if err = json.Unmarshal([]byte(data), &ban); err != nil {
+			slog.Warn("Skipping unreadable ban in shared state", "room", roomName, "ban", id, "err", err)
+			continue
+		}
+		bans = append(bans, ban)
+	}
+	return bans, nil
+}
+
+func (s *RedisState) PutRooms(ctx context.Context, rooms []shared.RoomInfo) error {
+	if len(rooms) <= 0 {
+		return nil
+	}
+	values := make([]any, 0, 2*len(rooms))
+	for _, info := range rooms {
+		data, err := json.Marshal(info)
+		if err != nil {
+			return fmt.Errorf("failed to marshal room: %w", err)
+		}
+		values = append(values, info.ID.String(), data)
+	}
+	return s.client.HSet(ctx, s.key("rooms"), values...).Err()
+}
+
+func (s *RedisState) Rooms(ctx context.Context) ([]shared.RoomInfo, error) {
+	fields, err := s.client.HGetAll(ctx, s.key("rooms")).Result()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	rooms := make([]shared.RoomInfo, 0, len(fields))
+	expired := make([]string, 0)
+	for id, data := range fields {
+		var info shared.RoomInfo
+		if err = json.Unmarshal([]byte(data), &info); err != nil || info.LeaseExpired(now) {
+			// Owners stopped renewing these, whoever reads them first cleans them up
+			expired = append(expired, id)
+			continue
+		}
+		rooms = append(rooms, info)
+	}
+	if len(expired) > 0 {
+		if err = s.client.HDel(ctx, s.key("rooms"), expired...).Err(); err != nil {
+			slog.Debug("Failed to remove expired rooms from shared state", "count", len(expired), "err", err)
+		}
+	}
+	return rooms, nil
+}
+
+func (s *RedisState) Close() error {
+	return s.client.Close()
+}
+
+// --- Relay Shared State ---
+
+// ConnectSharedState makes the relay share state of its cluster and keeps its room directory in sync
+func (r *Relay) ConnectSharedState(ctx context.Context, state SharedState) {
+	r.Shared = state
+	go r.sharedStateSync(ctx)
+}
+
+// openSharedState connects to the shared state backend configured by flags, if any
+func (r *Relay) openSharedState(ctx context.Context) error {
+	flags := common.GetFlags()
+	switch flags.StateBackend {
+	case "":
+		return nil
+	case common.StateBackendRedis:
+		state, err := OpenRedisState(ctx, flags.RedisURL, flags.RedisPrefix)
+		if err != nil {
+			return err
+		}
+		slog.Info("Sharing state with cluster over Redis", "prefix", flags.RedisPrefix)
+		r.ConnectSharedState(ctx, state)
+		return nil
+	default:
+		return fmt.Errorf("unknown state backend %q", flags.StateBackend)
+	}
+}
+
+// sharedStateSync writes rooms this relay owns to the shared directory and reads rooms of the cluster back
+func (r *Relay) sharedStateSync(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(common.GetFlags().StateSync) * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			owned := make([]shared.RoomInfo, 0)
+			for _, room := range r.LocalRooms.Copy() {
+				if room.OwnerID != r.ID {
+					continue
+				}
+				info := localClaim(room)
+				info.RelayID = r.ID
+				info.Metadata = room.GetMetadata()
+				info.Viewers = room.ParticipantCount()
+				info.LeaseExpiry = now.Add(roomLeaseDuration)
+				owned = append(owned, info)
+			}
+
+			opCtx, cancel := context.WithTimeout(ctx, sharedStateTimeout)
+			if err := r.Shared.PutRooms(opCtx, owned); err != nil {
+				slog.Warn("Failed to write rooms to shared state", "err", err)
+			}
+			rooms, err := r.Shared.Rooms(opCtx)
+			cancel()
+			if err != nil {
+				// Keep the directory read last, it's better than none while the backend is away
+				slog.Warn("Failed to read rooms from shared state", "err", err)
+				continue
+			}
+			current := make(map[string]bool, len(rooms))
+			for _, info := range rooms {
+				current[info.ID.String()] = true
+				r.sharedRooms.Set(info.ID.String(), info)
+			}
+			for id := range r.sharedRooms.Copy() {
+				if !current[id] {
+					r.sharedRooms.Delete(id)
+				}
+			}
+		}
+	}
+}
+
+// sharedOp runs an operation on shared state with timeout, a no-op without shared state
+func (r *Relay) sharedOp(name string, op func(ctx context.Context, state SharedState) error) {
+	if r.Shared == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sharedStateTimeout)
+	defer cancel()
+	if err := op(ctx, r.Shared); err != nil {
+		slog.Warn("Shared state operation failed, relying on own state", "op", name, "err", err)
+	}
+}
+
+// RoomBanned returns the active ban matching given session or peer in a room, asking shared state if we know none.
+// Bans found there are kept, so they are enforced and gossiped like our own.
+func (r *Relay) RoomBanned(roomName, sessionID string, peerID peer.ID) (RoomBan, bool) {
+	now := time.Now()
+	if ban, banned := r.Moderation.Banned(roomName, sessionID, peerID, now); banned {
+		return ban, true
+	}
+	r.loadSharedBans(roomName, now)
+	return r.Moderation.Banned(roomName, sessionID, peerID, now)
+}
+
+// loadSharedBans merges bans of a room issued on other relays of the cluster
+func (r *Relay) loadSharedBans(roomName string, now time.Time) {
+	r.sharedOp("room bans", func(ctx context.Context, state SharedState) error {
+		bans, err := state.RoomBans(ctx, roomName)
+		for _, ban := range bans {
+			r.Moderation.Merge(ban, now)
+		}
+		return err
+	})
+}
+
+// sessionRevoked checks if a session ID was invalidated by a kick on this relay or another of the cluster
+func (r *Relay) sessionRevoked(sessionID string) bool {
+	if r.Moderation.SessionRevoked(sessionID, time.Now()) {
+		return true
+	}
+	revoked := false
+	r.sharedOp("session revoked", func(ctx context.Context, state SharedState) error {
+		var err error
+		revoked, err = state.SessionRevoked(ctx, sessionID)
+		return err
+	})
+	return revoked
+}
+
+// revokeSession makes a session ID unusable for resuming until given time, on every relay of the cluster
+func (r *Relay) revokeSession(sessionID string, until time.Time) {
+	r.Moderation.RevokeSession(sessionID, until)
+	if len(sessionID) <= 0 {
+		return
+	}
+	r.sharedOp("revoke session", func(ctx context.Context, state SharedState) error {
+		return state.RevokeSession(ctx, sessionID, until)
+	})
+}
+
+// shareBan puts a ban issued or lifted on this relay into shared state
+func (r *Relay) shareBan(ban RoomBan) {
+	r.sharedOp("put ban", func(ctx context.Context, state SharedState) error {
+		return state.PutBan(ctx, ban)
+	})
+}
@@ -0,0 +1,199 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"relay/internal/common"
+	"relay/internal/shared"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/oklog/ulid/v2"
+)
+
+// --- State Snapshot ---
+//
+// A snapshot holds rooms, viewer sessions, who controls input and the peer store in one versioned file. Restoring it
+// at startup brings owned rooms back under their IDs and ownership epochs for pushing nodes to return to, lets viewers
+// resume their sessions and redials known peers. Participants can't be restored as their connections are gone, they
+// are kept for debugging production state.
+
+// snapshotVersion is bumped whenever Snapshot changes incompatibly, older snapshots are refused
+const snapshotVersion = 1
+
+// Snapshot is relay state saved for fast restarts and debugging
+type Snapshot struct {
+	Version  int                                 `json:"version"`
+	RelayID  peer.ID                             `json:"relay_id"`
+	TakenAt  time.Time                           `json:"taken_at"`
+	Rooms    []SnapshotRoom                      `json:"rooms"`
+	Sessions []SnapshotSession                   `json:"sessions"`          // Departed viewer sessions which may still resume
+	Revoked  map[string]time.Time                `json:"revoked,omitempty"` // Session ID -> unusable until, of kicked participants
+	Bans     []RoomBan                           `json:"bans"`
+	Epochs   map[string]uint64                   `json:"epochs,omitempty"` // Room name -> highest ownership epoch seen
+	Peers    *common.SafeMap[peer.ID, *PeerInfo] `json:"peers"`            // Peer store
+}
+
+// SnapshotRoom is a local room, owned or pulled from UpstreamID
+type SnapshotRoom struct {
+	shared.RoomInfo
+	UpstreamID   peer.ID               `json:"upstream_id,omitempty"`
+	Participants []SnapshotParticipant `json:"participants,omitempty"`
+}
+
+// SnapshotParticipant is a viewer of a room and whether it controls input
+type SnapshotParticipant struct {
+	ID           ulid.ULID         `json:"id"`
+	SessionID    string            `json:"session_id"`
+	PeerID       peer.ID           `json:"peer_id"`
+	Role         shared.ViewerRole `json:"role"`
+	Identity     string            `json:"identity,omitempty"`
+	InputAllowed bool              `json:"input_allowed"`
+}
+
+// SnapshotSession is RTP numbering of a departed viewer session
+type SnapshotSession struct {
+	SessionID string          `json:"session_id"`
+	State     shared.RTPState `json:"state"`
+	Left      time.Time       `json:"left"`
+}
+
+// TakeSnapshot captures current relay state
+func (r *Relay) TakeSnapshot(now time.Time) *Snapshot {
+	snap := &Snapshot{
+		Version:  snapshotVersion,
+		RelayID:  r.ID,
+		TakenAt:  now,
+		Rooms:    make([]SnapshotRoom, 0),
+		Sessions: make([]SnapshotSession, 0),
+		Revoked:  r.Moderation.RevokedSessions(now),
+		Bans:     r.Moderation.Snapshot(now),
+		Epochs:   make(map[string]uint64),
+		Peers:    common.NewSafeMap[peer.ID, *PeerInfo](),
+	}
+	for _, room := range r.LocalRooms.Copy() {
+		info := localClaim(room)
+		info.Settings = room.GetSettings()
+		info.Metadata = room.GetMetadata()
+		info.Viewers = room.ParticipantCount()
+		snapRoom := SnapshotRoom{RoomInfo: info, UpstreamID: room.UpstreamID()}
+		for _, participant := range room.GetParticipants() {
+			snapRoom.Participants = append(snapRoom.Participants, SnapshotParticipant{
+				ID:           participant.ID,
+				SessionID:    participant.SessionID,
+				PeerID:       participant.PeerID,
+				Role:         participant.Role,
+				Identity:     participant.Identity,
+				InputAllowed: participant.InputAllowed(),
+			})
+		}
+		snap.Rooms = append(snap.Rooms, snapRoom)
+	}
+	for sessionID, session := range r.StreamProtocol.resumable.Copy() {
+		snap.Sessions = append(snap.Sessions, SnapshotSession{SessionID: sessionID, State: session.state, Left: session.left})
+	}
+	for name, known := range r.roomEpochs.Copy() {
+		snap.Epochs[name] = known.epoch
+	}
+	for id, pi := range r.Peers.Copy() {
+		snap.Peers.Set(id, pi)
+	}
+	return snap
+}
+
+// SaveToFile saves the snapshot to a JSON file, replacing it atomically so a crash won't leave it truncated
+func (snap *Snapshot) SaveToFile(filePath string) error {
+	if len(filePath) <= 0 {
+		return errors.New("filepath is not set")
+	}
+
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return errors.New("failed to marshal snapshot: " + err.Error())
+	}
+
+	tmpPath := filePath + ".tmp"
+	if err = os.WriteFile(tmpPath, data, 0600); err != nil {
+		return errors.New("failed to save snapshot to file: " + err.Error())
+	}
+	if err = os.Rename(tmpPath, filePath); err != nil {
+		return errors.New("failed to replace snapshot file: " + err.Error())
+	}
+
+	slog.Info("Snapshot saved to file", "path", filePath, "rooms", len(snap.Rooms), "sessions", len(snap.Sessions))
+	return nil
+}
+
+// LoadSnapshot reads a snapshot from a JSON file, refusing versions this relay doesn't understand
+func LoadSnapshot(filePath string) (*Snapshot, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot file: %w", err)
+	}
+	var snap Snapshot
+	if err = json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal snapshot: %w", err)
+	}
+	if snap.Version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d, expected %d", snap.Version, snapshotVersion)
+	}
+	return &snap, nil
+}
+
+// snapshotFile returns path admin API saves snapshots to in persistent directory
+func snapshotFile() string {
+	return common.GetFlags().PersistDir + "/snapshot.json"
+}
+
+// RestoreSnapshot brings back state of a snapshot. Rooms the snapshot's relay owned come back offline and owned by
+// this relay, so a snapshot of another relay can be restored to debug it. Pulled rooms are left to the mesh.
+func (r *Relay) RestoreSnapshot(snap *Snapshot) {
+	now := time.Now()
+	if snap.RelayID != r.ID {
+		slog.Warn("Restoring snapshot of another relay, taking over its rooms", "snapshot_relay", snap.RelayID)
+	}
+
+	restoredRooms := 0
+	for _, snapRoom := range snap.Rooms {
+		if snapRoom.OwnerID != snap.RelayID || r.GetRoomByName(snapRoom.Name) != nil {
+			continue
+		}
+		room := shared.NewRoom(snapRoom.Name, snapRoom.ID, r.ID)
+		room.SetSettings(snapRoom.Settings)
+		room.SetMetadata(snapRoom.Metadata)
+		room.SetClaim(snapRoom.Epoch, snapRoom.ClaimedAt)
+		r.LocalRooms.Set(room.ID, room)
+		r.Events.Publish(Event{Type: EventRoomCreated, Room: room.Name})
+		restoredRooms++
+	}
+	for name, epoch := range snap.Epochs {
+		r.observeRoomEpoch(name, epoch, now)
+	}
+
+	// Sessions past resume TTL are dropped when resumed, the TTL counts from when they left
+	for _, session := range snap.Sessions {
+		r.StreamProtocol.resumable.Set(session.SessionID, resumableSession{state: session.State, left: session.Left})
+	}
+	for sessionID, until := range snap.Revoked {
+		r.Moderation.RevokeSession(sessionID, until)
+	}
+	for _, ban := range snap.Bans {
+		r.Moderation.Merge(ban, now)
+	}
+
+	restoredPeers := 0
+	if snap.Peers != nil {
+		for id, pi := range snap.Peers.Copy() {
+			if pi == nil || id == r.ID || r.Peers.Has(id) {
+				continue
+			}
+			r.Peers.Set(id, pi)
+			restoredPeers++
+		}
+	}
+	slog.Info("Restored relay snapshot", "taken_at", snap.TakenAt, "rooms", restoredRooms, "sessions", len(snap.Sessions), "bans", len(snap.Bans), "peers", restoredPeers)
+}
@@ -5,12 +5,16 @@ import (
 	"encoding/json"
 	"errors"
 	"log/slog"
+	"relay/internal/common"
 	"relay/internal/shared"
 	"time"
 
+	gen "relay/internal/proto"
+
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
 	"github.com/libp2p/go-libp2p/core/network"
 	"github.com/libp2p/go-libp2p/core/peer"
+	"google.golang.org/protobuf/proto"
 )
 
 // --- PubSub Message Handlers ---
@@ -81,11 +85,93 @@ func (r *Relay) handleRelayMetricsMessages(ctx context.Context, sub *pubsub.Subs
 				slog.Error("Peer ID mismatch in relay status", "expected", info.ID, "actual", msg.GetFrom())
 				continue
 			}
+			if !r.meshTrusted(&info) {
+				slog.Warn("Ignoring relay status of peer not proving mesh membership", "peer", info.ID)
+				continue
+			}
 			r.onPeerStatus(info)
 		}
 	}
 }
 
+// handleRoomBanMessages processes room bans gossiped by peers.
+func (r *Relay) handleRoomBanMessages(ctx context.Context, sub *pubsub.Subscription) {
+	slog.Debug("Starting room ban message handler...")
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Stopping room ban message handler")
+			return
+		default:
+			msg, err := sub.Next(ctx)
+			if err != nil {
+				if errors.Is(err, context.Canceled) || errors.Is(err, pubsub.ErrSubscriptionCancelled) || errors.Is(err, context.DeadlineExceeded) {
+					slog.Info("Room ban subscription ended", "err", err)
+					return
+				}
+				slog.Error("Error receiving room ban message", "err", err)
+				time.Sleep(1 * time.Second)
+				continue
+			}
+			if msg.GetFrom() == r.Host.ID() {
+				continue
+			}
+
+			var bans []RoomBan
+			if err := json.Unmarshal(msg.Data, &bans); err != nil {
+				slog.Error("Failed to unmarshal room bans", "from", msg.GetFrom(), "data_len", len(msg.Data), "err", err)
+				continue
+			}
+
+			r.applyRoomBans(msg.GetFrom(), bans)
+		}
+	}
+}
+
+// handleChatMessages delivers room chat from peers to local participants.
+func (r *Relay) handleChatMessages(ctx context.Context, sub *pubsub.Subscription) {
+	slog.Debug("Starting room chat message handler...")
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Stopping room chat message handler")
+			return
+		default:
+			msg, err := sub.Next(ctx)
+			if err != nil {
+				if errors.Is(err, context.Canceled) || errors.Is(err, pubsub.ErrSubscriptionCancelled) || errors.Is(err, context.DeadlineExceeded) {
+					slog.Info("Room chat subscription ended", "err", err)
+					return
+				}
+				slog.Error("Error receiving room chat message", "err", err)
+				time.Sleep(1 * time.Second)
+				continue
+			}
+			if msg.GetFrom() == r.Host.ID() {
+				continue
+			}
+
+			// Sender identity is stamped by the relay of the sender, only relays of our mesh are believed
+			if !r.isMeshRelay(msg.GetFrom()) {
+				slog.Warn("Ignoring room chat from untrusted peer", "from", msg.GetFrom())
+				continue
+			}
+
+			var chatMsg gen.ProtoChatMessage
+			if err := proto.Unmarshal(msg.Data, &chatMsg); err != nil {
+				slog.Error("Failed to unmarshal room chat", "from", msg.GetFrom(), "data_len", len(msg.Data), "err", err)
+				continue
+			}
+			if !validRelayedChat(&chatMsg) {
+				slog.Warn("Ignoring invalid room chat", "from", msg.GetFrom(), "room", chatMsg.RoomName)
+				continue
+			}
+
+			r.deliverChat(&chatMsg)
+		}
+	}
+}
+
 // --- State Check Functions ---
 // hasConnectedPeer checks if peer is in map and has a valid connection
 func (r *Relay) hasConnectedPeer(peerID peer.ID) bool {
@@ -99,19 +185,63 @@ func (r *Relay) hasConnectedPeer(peerID peer.ID) bool {
 	return true
 }
 
+// isMeshRelay checks if peer announced itself as a trusted relay, other peers are viewers
+func (r *Relay) isMeshRelay(peerID peer.ID) bool {
+	pi, ok := r.Peers.Get(peerID)
+	return ok && !pi.StatusAt.IsZero() && r.meshTrusted(pi)
+}
+
+// peerSupports checks if a relay announced support for an optional protocol feature.
+// Relays predating announcements are assumed to support it if they speak its libp2p protocol, if it has one.
+func (r *Relay) peerSupports(peerID peer.ID, capability string, legacyProtocol string) bool {
+	if pi, ok := r.Peers.Get(peerID); ok && !pi.announcement().Legacy() {
+		return pi.announcement().Supports(capability)
+	}
+	if len(legacyProtocol) <= 0 {
+		return false
+	}
+	protocols, err := r.Host.Peerstore().SupportsProtocols(peerID, versionedIDs(legacyProtocol)...)
+	return err == nil && len(protocols) > 0
+}
+
+// meshTrusted checks if peer is a relay of our mesh, by allowlist or mesh secret proof.
+// Without mesh authentication any peer announcing relay status is trusted.
+func (r *Relay) meshTrusted(pi *PeerInfo) bool {
+	flags := common.GetFlags()
+	if !flags.MeshAuthEnabled() {
+		return true
+	}
+	if flags.IsMeshPeer(pi.ID.String()) {
+		return true
+	}
+	return len(flags.MeshSecret) > 0 && common.VerifyMeshPeer(flags.MeshSecret, pi.ID.String(), pi.MeshProof)
+}
+
 // --- State Change Functions ---
 
 // onPeerStatus updates the status of a peer based on received metrics, adding local perspective
 func (r *Relay) onPeerStatus(recvInfo PeerInfo) {
+	// Keep our own peer store metadata, peers don't know which of their addresses work for us
+	if prev, ok := r.Peers.Get(recvInfo.ID); ok {
+		recvInfo.mergeLocalMeta(prev)
+	}
+	r.mergeIdentifiedAddrs(&recvInfo)
+	recvInfo.LastSeen = time.Now()
+	recvInfo.StatusAt = recvInfo.LastSeen
 	r.Peers.Set(recvInfo.ID, &recvInfo)
 }
 
 // onPeerConnected is called when a new peer connects to the relay
 func (r *Relay) onPeerConnected(peerID peer.ID) {
-	// Add to local peer map
-	r.Peers.Set(peerID, &PeerInfo{
-		ID: peerID,
-	})
+	// Add to local peer map, keeping what we already know of it
+	if !r.Peers.Has(peerID) {
+		r.Peers.Set(peerID, &PeerInfo{
+			ID: peerID,
+		})
+	}
+	r.touchPeer(peerID)
+	r.reconnectPeers.Delete(peerID)
+	r.Events.Publish(Event{Type: EventPeerConnected, PeerID: peerID})
 
 	slog.Info("Peer connected", "peer", peerID)
 
@@ -123,46 +253,87 @@ func (r *Relay) onPeerConnected(peerID peer.ID) {
 			if err = r.publishRoomStates(context.Background()); err != nil {
 				slog.Error("Failed to publish room states on connect", "err", err)
 			}
+			// New peer may have missed bans, lifted ones included
+			if err = r.publishRoomBans(context.Background(), r.Moderation.Snapshot(time.Now())); err != nil {
+				slog.Error("Failed to publish room bans on connect", "err", err)
+			}
 		}
 	}()
 }
 
-// onPeerDisconnected marks a peer as disconnected in our status view and removes latency info
-func (r *Relay) onPeerDisconnected(peerID peer.ID) {
-	// Relay peer disconnect handling
-	slog.Info("Mesh peer disconnected, deleting from local peer map", "peer", peerID)
-	if r.Peers.Has(peerID) {
-		r.Peers.Delete(peerID)
+// reconnectDeparted schedules reconnecting to a departed peer, retired peers come back as their successor and mesh
+// peers announce their addresses, those are worth reconnecting to
+func (r *Relay) reconnectDeparted(peerID peer.ID) {
+	pi, known := r.Peers.Get(peerID)
+	if successor := r.successorToDial(peerID, pi, time.Now()); successor != nil {
+		r.scheduleReconnect(successor)
+	} else if known {
+		r.scheduleReconnect(pi)
 	}
-	if r.Rooms.Has(peerID.String()) {
-		r.Rooms.Delete(peerID.String())
+}
+
+// forgetOwnedRooms drops rooms a departed peer announced owning
+func (r *Relay) forgetOwnedRooms(peerID peer.ID) {
+	for id, info := range r.Rooms.Copy() {
+		if info.OwnerID == peerID {
+			r.Rooms.Delete(id)
+		}
 	}
+}
 
-	// TODO: If any rooms were routed through this peer, handle that case
+// dropParticipants removes participants of a departed peer left in local rooms without a served stream
+func (r *Relay) dropParticipants(peerID peer.ID) {
+	for _, room := range r.LocalRooms.Copy() {
+		for _, participant := range room.GetParticipants() {
+			if participant.PeerID != peerID {
+				continue
+			}
+			slog.Info("Removing participant of disconnected peer", "room", room.Name, "participant", participant.ID, "peer", peerID)
+			room.RemoveParticipantByID(participant.ID)
+			participant.Close()
+			r.Events.Publish(Event{Type: EventViewerLeft, Room: room.Name, PeerID: peerID, Attrs: map[string]string{
+				"participant": participant.ID.String(),
+			}})
+		}
+	}
 }
 
 // updateMeshRoomStates merges received room states into the MeshRooms map
 // TODO: Wrap in another type with timestamp or another mechanism to avoid conflicts
 func (r *Relay) updateMeshRoomStates(peerID peer.ID, states []shared.RoomInfo) {
+	for i := range states {
+		// Relays without path info only announce rooms they own
+		if len(states[i].RelayID) <= 0 {
+			states[i].RelayID = states[i].OwnerID
+		}
+	}
+	r.updateRoomRoutes(peerID, states)
+	r.updateMeshViewers(peerID, states)
+
+	now := time.Now()
 	for _, state := range states {
-		if state.OwnerID == r.ID {
+		// Only owners announce the room itself, forwarding relays announce routes
+		if state.OwnerID == r.ID || state.OwnerID != peerID {
 			continue
 		}
 
-		// If previously did not exist, but does now, request a connection if participants exist for our room
-		/*existed := r.Rooms.Has(state.ID.String())
-		if !existed {
-			// Request connection to this peer if we have participants in our local room
-			if room, ok := r.LocalRooms.Get(state.ID); ok {
-				if len(room.Participants) > 0 {
-					slog.Debug("Got new remote room state, we locally have participants for, requesting stream", "room_name", room.Name, "peer", peerID)
-					if err := r.StreamProtocol.RequestStream(context.Background(), room, peerID); err != nil {
-						slog.Error("Failed to request stream for new remote room state", "room_name", room.Name, "peer", peerID, "err", err)
-					}
-				}
-			}
-		}*/
-
+		// Announcing renews the owner's claim, conflicting claims left over from a partition are settled first
+		state.LeaseExpiry = now.Add(roomLeaseDuration)
+		if !r.reconcileRoomClaim(state, now) {
+			continue
+		}
 		r.Rooms.Set(state.ID.String(), state)
+
+		// Keep metadata of rooms we pull from the owner current
+		if room := r.GetRoomByID(state.ID); room != nil && room.OwnerID == peerID {
+			room.SetMetadata(state.Metadata)
+		}
+	}
+
+	// Rooms coming online in the mesh are pulled for local viewers waiting for them
+	for _, state := range states {
+		if state.Online && state.OwnerID != r.ID {
+			r.StreamProtocol.pullWaitedRoom(state.Name)
+		}
 	}
 }
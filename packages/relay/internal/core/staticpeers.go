@@ -0,0 +1,132 @@
+package core
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"relay/internal/common"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multiaddr"
+)
+
+// --- Static Peers ---
+//
+// Relays given with the peers flag are dialed at startup and redialed with backoff whenever they drop, without
+// ever giving up as the reconnect supervisor does for peers learned from the mesh.
+
+// staticPeerTag protects connections to static peers from being trimmed by the connection manager
+const staticPeerTag = "static-peer"
+
+// staticPeer is a relay from the peers flag and its dial backoff
+type staticPeer struct {
+	mtx      sync.Mutex
+	info     peer.AddrInfo
+	failures int
+	nextDial time.Time
+	dialing  bool
+}
+
+// parseStaticPeers returns relays of the peers flag, addresses of the same relay are merged
+func parseStaticPeers() []peer.AddrInfo {
+	var addrs []multiaddr.Multiaddr
+	for _, entry := range strings.Split(common.GetFlags().Peers, ",") {
+		entry = strings.TrimSpace(entry)
+		if len(entry) <= 0 {
+			continue
+		}
+		addr, err := multiaddr.NewMultiaddr(entry)
+		if err != nil {
+			slog.Warn("Invalid static peer address", "addr", entry, "err", err)
+			continue
+		}
+		if _, id := peer.SplitAddr(addr); len(id) <= 0 {
+			slog.Warn("Static peer address has no /p2p peer ID", "addr", entry)
+			continue
+		}
+		addrs = append(addrs, addr)
+	}
+	infos, err := peer.AddrInfosFromP2pAddrs(addrs...)
+	if err != nil {
+		slog.Warn("Failed to parse static peers", "err", err)
+		return nil
+	}
+	return infos
+}
+
+// isStaticPeer returns true if peer is kept connected because of the peers flag
+func (r *Relay) isStaticPeer(id peer.ID) bool {
+	return r.staticPeers.Has(id)
+}
+
+// loadStaticPeers registers relays of the peers flag, before peers from peer store are dialed
+func (r *Relay) loadStaticPeers() {
+	for _, info := range parseStaticPeers() {
+		if info.ID == r.ID {
+			continue
+		}
+		r.staticPeers.Set(info.ID, &staticPeer{info: info})
+		r.Host.ConnManager().Protect(info.ID, staticPeerTag)
+	}
+}
+
+// staticPeerSupervisor dials static peers at startup and keeps them connected
+func (r *Relay) staticPeerSupervisor(ctx context.Context) {
+	if r.staticPeers.Len() <= 0 {
+		return
+	}
+	slog.Info("Keeping static peers connected", "count", r.staticPeers.Len())
+
+	r.dialStaticPeers(ctx, time.Now())
+	ticker := time.NewTicker(reconnectCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			r.dialStaticPeers(ctx, now)
+		}
+	}
+}
+
+// dialStaticPeers starts dials to static peers which aren't connected and are out of backoff
+func (r *Relay) dialStaticPeers(ctx context.Context, now time.Time) {
+	for id, sp := range r.staticPeers.Copy() {
+		if r.isConnected(id) {
+			continue
+		}
+		sp.mtx.Lock()
+		due := !sp.dialing && !now.Before(sp.nextDial)
+		if due {
+			sp.dialing = true
+		}
+		sp.mtx.Unlock()
+		if due {
+			go r.dialStaticPeer(ctx, sp)
+		}
+	}
+}
+
+// dialStaticPeer makes a single dial attempt, backing off further on failure
+func (r *Relay) dialStaticPeer(ctx context.Context, sp *staticPeer) {
+	err := r.connectToPeer(ctx, &sp.info)
+
+	sp.mtx.Lock()
+	defer sp.mtx.Unlock()
+	sp.dialing = false
+	if err != nil {
+		sp.failures++
+		sp.nextDial = time.Now().Add(withJitter(min(peerDialBackoff(sp.failures), staticPeerBackoffMax)))
+		slog.Warn("Failed to connect to static peer", "peer", sp.info.ID, "failures", sp.failures, "next_dial", sp.nextDial, "err", err)
+		return
+	}
+	if sp.failures > 0 {
+		slog.Info("Reconnected to static peer", "peer", sp.info.ID, "failures", sp.failures)
+	}
+	sp.failures = 0
+	sp.nextDial = time.Time{}
+}
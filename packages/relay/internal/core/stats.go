@@ -0,0 +1,221 @@
+package core
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"relay/internal/common"
+	"relay/internal/shared"
+	"strconv"
+	"time"
+
+	gen "relay/internal/proto"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/pion/webrtc/v4"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// --- Track Statistics ---
+
+var (
+	roomViewersDesc = prometheus.NewDesc("nestri_relay_room_viewers",
+		"Participants currently watching room", []string{"room"}, nil)
+	trackBitrateDesc = prometheus.NewDesc("nestri_relay_track_bitrate_bps",
+		"Ingest bitrate of room track in bits per second", []string{"room", "kind", "codec"}, nil)
+	trackFrameRateDesc = prometheus.NewDesc("nestri_relay_track_frame_rate",
+		"Forwarded video frames per second of room, counted from RTP marker bits", []string{"room", "codec"}, nil)
+	trackKeyframeIntervalDesc = prometheus.NewDesc("nestri_relay_track_keyframe_interval_seconds",
+		"Time between last two forwarded video keyframes of room", []string{"room", "codec"}, nil)
+	trackPacketsDesc = prometheus.NewDesc("nestri_relay_track_rtp_packets_received_total",
+		"RTP packets received from room stream", []string{"room", "kind", "codec"}, nil)
+	roomEgressBitrateDesc = prometheus.NewDesc("nestri_relay_room_egress_bitrate_bps",
+		"Bitrate written to all participants of room in bits per second", []string{"room"}, nil)
+	roomPacketsForwardedDesc = prometheus.NewDesc("nestri_relay_room_rtp_packets_forwarded_total",
+		"RTP packets written to participants of room", []string{"room"}, nil)
+	roomPacketsDroppedDesc = prometheus.NewDesc("nestri_relay_room_rtp_packets_dropped_total",
+		"RTP packets not written to participants of room, by reason", []string{"room", "reason"}, nil)
+	roomMessagesDroppedDesc = prometheus.NewDesc("nestri_relay_room_datachannel_messages_dropped_total",
+		"DataChannel messages of room dropped over its message rate or goroutine budget", []string{"room"}, nil)
+	roomBudgetUsageDesc = prometheus.NewDesc("nestri_relay_room_budget_usage",
+		"Resources room uses of its budget, by resource", []string{"room", "resource"}, nil)
+	roomPictureLossDesc = prometheus.NewDesc("nestri_relay_room_pli_total",
+		"Picture loss (PLI and FIR) requests from participants of room", []string{"room"}, nil)
+	participantRTTDesc = prometheus.NewDesc("nestri_relay_participant_rtt_seconds",
+		"Round trip time to participant from its RTCP receiver reports", []string{"room", "participant"}, nil)
+)
+
+// roomCollector exports streaming state of online local rooms at scrape time
+type roomCollector struct {
+	relay *Relay
+}
+
+func (c roomCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		roomViewersDesc, trackBitrateDesc, trackFrameRateDesc, trackKeyframeIntervalDesc, trackPacketsDesc,
+		roomEgressBitrateDesc, roomPacketsForwardedDesc, roomPacketsDroppedDesc, roomMessagesDroppedDesc, roomBudgetUsageDesc,
+		roomPictureLossDesc, participantRTTDesc,
+	} {
+		ch <- desc
+	}
+}
+
+func (c roomCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, room := range c.relay.LocalRooms.Copy() {
+		if !room.IsOnline() {
+			continue
+		}
+		name := room.Name
+		audioCodec, videoCodec := room.AudioCodec().MimeType, room.VideoCodec().MimeType
+
+		audio := room.AudioStats.Snapshot()
+		ch <- prometheus.MustNewConstMetric(trackBitrateDesc, prometheus.GaugeValue, float64(audio.Bitrate), name, "audio", audioCodec)
+		ch <- prometheus.MustNewConstMetric(trackPacketsDesc, prometheus.CounterValue, float64(audio.Packets), name, "audio", audioCodec)
+		if !room.GetSettings().AudioOnly {
+			video := room.VideoStats.Snapshot()
+			ch <- prometheus.MustNewConstMetric(trackBitrateDesc, prometheus.GaugeValue, float64(video.Bitrate), name, "video", videoCodec)
+			ch <- prometheus.MustNewConstMetric(trackPacketsDesc, prometheus.CounterValue, float64(video.Packets), name, "video", videoCodec)
+			ch <- prometheus.MustNewConstMetric(trackFrameRateDesc, prometheus.GaugeValue, video.FrameRate, name, videoCodec)
+			ch <- prometheus.MustNewConstMetric(trackKeyframeIntervalDesc, prometheus.GaugeValue, video.KeyframeInterval.Seconds(), name, videoCodec)
+		}
+
+		egress := room.EgressStats.Snapshot()
+		counters := room.Counters()
+		ch <- prometheus.MustNewConstMetric(roomEgressBitrateDesc, prometheus.GaugeValue, float64(egress.Bitrate), name)
+		ch <- prometheus.MustNewConstMetric(roomPacketsForwardedDesc, prometheus.CounterValue, float64(egress.Packets), name)
+		ch <- prometheus.MustNewConstMetric(roomPacketsDroppedDesc, prometheus.CounterValue, float64(counters.DroppedLate), name, "late")
+		ch <- prometheus.MustNewConstMetric(roomPacketsDroppedDesc, prometheus.CounterValue, float64(counters.DroppedQueueFull), name, "queue_full")
+		ch <- prometheus.MustNewConstMetric(roomPacketsDroppedDesc, prometheus.CounterValue, float64(counters.DroppedEvicted), name, "queue_evicted")
+		ch <- prometheus.MustNewConstMetric(roomPacketsDroppedDesc, prometheus.CounterValue, float64(counters.DroppedShed), name, "queue_shed")
+		ch <- prometheus.MustNewConstMetric(roomPacketsDroppedDesc, prometheus.CounterValue, float64(counters.DroppedBudget), name, "budget")
+		ch <- prometheus.MustNewConstMetric(roomMessagesDroppedDesc, prometheus.CounterValue, float64(counters.DroppedMessages), name)
+		usage := room.Usage()
+		ch <- prometheus.MustNewConstMetric(roomBudgetUsageDesc, prometheus.GaugeValue, float64(usage.Goroutines), name, "goroutines")
+		ch <- prometheus.MustNewConstMetric(roomBudgetUsageDesc, prometheus.GaugeValue, float64(usage.Tracks), name, "tracks")
+		ch <- prometheus.MustNewConstMetric(roomBudgetUsageDesc, prometheus.GaugeValue, float64(usage.QueuedBytes), name, "queued_bytes")
+		ch <- prometheus.MustNewConstMetric(roomPictureLossDesc, prometheus.CounterValue, float64(counters.PictureLoss), name)
+
+		participants := room.GetParticipants()
+		ch <- prometheus.MustNewConstMetric(roomViewersDesc, prometheus.GaugeValue, float64(len(participants)), name)
+		for _, participant := range participants {
+			if rtt := participant.RTT(); rtt > 0 {
+				ch <- prometheus.MustNewConstMetric(participantRTTDesc, prometheus.GaugeValue, rtt.Seconds(), name, participant.ID.String())
+			}
+		}
+	}
+}
+
+// bitrateEventThreshold is the relative change of room bitrate that triggers a bitrate-changed event
+const bitrateEventThreshold = 0.2
+
+// periodicStatsSampler samples track statistics of local rooms and reports bitrate changes
+func (r *Relay) periodicStatsSampler(ctx context.Context) {
+	if common.GetFlags().Metrics {
+		prometheus.MustRegister(roomCollector{relay: r})
+	}
+
+	ticker := time.NewTicker(statsSampleInterval)
+	defer ticker.Stop()
+
+	lastBitrate := make(map[ulid.ULID]uint64) // room ID -> bitrate last reported in event
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Stopping stats sampler")
+			return
+		case now := <-ticker.C:
+			seen := make(map[ulid.ULID]struct{})
+			r.LocalRooms.Range(func(id ulid.ULID, room *shared.Room) bool {
+				if !room.IsOnline() {
+					return true
+				}
+				seen[id] = struct{}{}
+
+				room.AudioStats.Sample(now)
+				room.VideoStats.Sample(now)
+				room.EgressStats.Sample(now)
+
+				bitrate := room.AudioStats.Snapshot().Bitrate + room.VideoStats.Snapshot().Bitrate
+				if last := lastBitrate[id]; bitrateChanged(last, bitrate) {
+					lastBitrate[id] = bitrate
+					r.Events.Publish(Event{Type: EventBitrateChanged, Room: room.Name, Attrs: map[string]string{
+						"bitrate_bps":  strconv.FormatUint(bitrate, 10),
+						"previous_bps": strconv.FormatUint(last, 10),
+					}})
+				}
+				return true
+			})
+
+			for id := range lastBitrate {
+				if _, ok := seen[id]; !ok {
+					delete(lastBitrate, id)
+				}
+			}
+		}
+	}
+}
+
+// bitrateChanged checks if bitrate moved past bitrateEventThreshold from last reported value
+func bitrateChanged(last, current uint64) bool {
+	if last == 0 {
+		return current > 0
+	}
+	diff := float64(current) - float64(last)
+	return math.Abs(diff)/float64(last) >= bitrateEventThreshold
+}
+
+// streamStatsMessage builds a stream-stats payload of room tracks
+func streamStatsMessage(room *shared.Room) *gen.ProtoStreamStats {
+	trackStats := func(kind webrtc.RTPCodecType, snapshot shared.TrackStatsSnapshot) *gen.ProtoTrackStats {
+		return &gen.ProtoTrackStats{
+			Kind:               kind.String(),
+			BitrateBps:         snapshot.Bitrate,
+			FrameRate:          snapshot.FrameRate,
+			KeyframeIntervalMs: uint32(snapshot.KeyframeInterval.Milliseconds()),
+			Packets:            snapshot.Packets,
+			Bytes:              snapshot.Bytes,
+		}
+	}
+
+	stats := &gen.ProtoStreamStats{
+		RoomName: room.Name,
+		Tracks: []*gen.ProtoTrackStats{
+			trackStats(webrtc.RTPCodecTypeAudio, room.AudioStats.Snapshot()),
+		},
+	}
+	if !room.GetSettings().AudioOnly {
+		stats.Tracks = append(stats.Tracks, trackStats(webrtc.RTPCodecTypeVideo, room.VideoStats.Snapshot()))
+	}
+	return stats
+}
+
+// sendStreamStats periodically sends stream-stats of viewer's current room and its input latency
+// until its PeerConnection closes
+func sendStreamStats(safeBRW *common.SafeBufioRW, currentRoom func() *shared.Room, participant *shared.Participant, pc *webrtc.PeerConnection) {
+	ticker := time.NewTicker(statsReportInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if pc.ConnectionState() == webrtc.PeerConnectionStateClosed {
+			return
+		}
+		if pc.ConnectionState() != webrtc.PeerConnectionStateConnected {
+			continue
+		}
+
+		room := currentRoom()
+		stats := streamStatsMessage(room)
+		if latency := participant.InputLatency(); latency.Samples > 0 {
+			stats.InputLatency = inputLatencyMessage(room.Name, latency)
+		}
+		statsMsg, err := common.CreateMessage(stats, "stream-stats", nil)
+		if err != nil {
+			slog.Error("Failed to create proto message", "err", err)
+			return
+		}
+		if err = safeBRW.SendProto(statsMsg); err != nil {
+			slog.Debug("Stopping stream stats, failed to send", "room", room.Name, "err", err)
+			return
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"relay/internal/common"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// --- Persistent Store ---
+//
+// Relay state kept across restarts lives in an SQLite database in persistent directory, written in transactions so
+// a crash never leaves it half written. Peers get a row each with columns worth querying, other state is kept as
+// named JSON documents. JSON files of earlier versions are imported once.
+
+// Store keeps relay state across restarts
+type Store interface {
+	LoadPeers() (map[peer.ID]*PeerInfo, error)
+	SavePeers(peers map[peer.ID]*PeerInfo) error // Replaces all stored peers
+	GetDocument(name string) ([]byte, error)     // Nil if never saved
+	PutDocument(name string, data []byte) error  // Replaces document of that name
+	Close() error
+}
+
+// Names of documents in Store
+const (
+	storeDocUsage     = "usage"
+	storeDocBandwidth = "bandwidth"
+)
+
+// storeMigrations upgrade the schema one version each, applied in order from the database's user_version
+var storeMigrations = []string{
+	`CREATE TABLE peers (
+		id            TEXT PRIMARY KEY,
+		info          TEXT NOT NULL,
+		last_seen     INTEGER NOT NULL DEFAULT 0,
+		dial_failures INTEGER NOT NULL DEFAULT 0,
+		relay         INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE TABLE documents (
+		name       TEXT PRIMARY KEY,
+		data       BLOB NOT NULL,
+		updated_at INTEGER NOT NULL
+	);`,
+}
+
+// SQLiteStore is a Store in an SQLite database file
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// OpenSQLiteStore opens or creates the database at path, migrating its schema to the current version
+func OpenSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite3", "file:"+path+"?_journal_mode=WAL&_busy_timeout=5000&_foreign_keys=on")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open store: %w", err)
+	}
+	// A single connection serializes writers, SQLite allows one at a time anyway
+	db.SetMaxOpenConns(1)
+
+	s := &SQLiteStore{db: db}
+	if err = s.migrate(); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// migrate applies schema migrations newer than the database, each in its own transaction
+func (s *SQLiteStore) migrate() error {
+	var version int
+	if err := s.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("failed to read store schema version: %w", err)
+	}
+	if version > len(storeMigrations) {
+		return fmt.Errorf("store schema version %d is newer than supported %d", version, len(storeMigrations))
+	}
+	for ; version < len(storeMigrations); version++ {
+		err := s.inTx(func(tx *sql.Tx) error {
+			if _, err := tx.Exec(storeMigrations[version]); err != nil {
+				return err
+			}
+			// PRAGMA doesn't take parameters
+			_, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", version+1))
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to migrate store to schema version %d: %w", version+1, err)
+		}
+		slog.Info("Migrated store schema", "version", version+1)
+	}
+	return nil
+}
+
+// inTx runs fn in a transaction, committed if fn succeeds and rolled back otherwise
+func (s *SQLiteStore) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	if err = fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// LoadPeers returns all stored peers
+func (s *SQLiteStore) LoadPeers() (map[peer.ID]*PeerInfo, error) {
+	rows, err := s.db.Query("SELECT id, info FROM peers")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query peers: %w", err)
+	}
+	defer rows.Close()
+
+	peers := make(map[peer.ID]*PeerInfo)
+	for rows.Next() {
+		var id, info string
+		if err = rows.Scan(&id, &info); err != nil {
+			return nil, fmt.Errorf("failed to read peer row: %w", err)
+		}
+		var pi PeerInfo
+		if err = json.Unmarshal([]byte(info), &pi); err != nil {
+			slog.Warn("Skipping unreadable peer in store", "peer", id, "err", err)
+			continue
+		}
+		peers[peer.ID(id)] = &pi
+	}
+	return peers, rows.Err()
+}
+
+// SavePeers replaces stored peers in one transaction
+func (s *SQLiteStore) SavePeers(peers map[peer.ID]*PeerInfo) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec("DELETE FROM peers"); err != nil {
+			return err
+		}
+		stmt, err := tx.Prepare("INSERT INTO peers (id, info, last_seen, dial_failures, relay) VALUES (?, ?, ?, ?, ?)")
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		for id, pi := range peers {
+			info, err := json.Marshal(pi)
+			if err != nil {
+				return fmt.Errorf("failed to marshal peer %s: %w", id, err)
+			}
+			if _, err = stmt.Exec(string(id), string(info), pi.LastSeen.Unix(), pi.DialFailures, !pi.StatusAt.IsZero()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetDocument returns a stored document, nil if it was never saved
+func (s *SQLiteStore) GetDocument(name string) ([]byte, error) {
+	var data []byte
+	err := s.db.QueryRow("SELECT data FROM documents WHERE name = ?", name).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// PutDocument replaces a stored document
+func (s *SQLiteStore) PutDocument(name string, data []byte) error {
+	_, err := s.db.Exec("INSERT INTO documents (name, data, updated_at) VALUES (?, ?, ?) "+
+		"ON CONFLICT (name) DO UPDATE SET data = excluded.data, updated_at = excluded.updated_at", name, data, time.Now().Unix())
+	return err
+}
+
+// Close closes the database
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// --- Relay Store ---
+
+// storeFile returns path of the store database in persistent directory
+func storeFile() string {
+	return filepath.Join(common.GetFlags().PersistDir, "relay.db")
+}
+
+// openStore opens the store of the relay and loads the peer store from it, importing JSON files of earlier versions
+func (r *Relay) openStore() error {
+	store, err := OpenSQLiteStore(storeFile())
+	if err != nil {
+		return err
+	}
+	r.Store = store
+	if err = r.LoadPeerStore(); err != nil {
+		slog.Warn("Failed to load previous peer store", "err", err)
+	}
+	r.importLegacyJSON()
+	return nil
+}
+
+// importLegacyJSON imports JSON files earlier versions persisted state to, renaming each once imported so
+// newer state in the store isn't overwritten by it on next start
+func (r *Relay) importLegacyJSON() {
+	persistDir := common.GetFlags().PersistDir
+	imported := func(path string) {
+		if err := os.Rename(path, path+".imported"); err != nil {
+			slog.Warn("Failed to rename imported JSON file", "path", path, "err", err)
+		}
+	}
+
+	peerstorePath := filepath.Join(persistDir, "peerstore.json")
+	if data, err := os.ReadFile(peerstorePath); err == nil {
+		if _, err = r.ImportPeerStore(data); err != nil {
+			slog.Warn("Failed to import JSON peer store", "path", peerstorePath, "err", err)
+		} else {
+			slog.Info("Imported JSON peer store into store", "path", peerstorePath)
+			imported(peerstorePath)
+		}
+	}
+	for doc, name := range map[string]string{storeDocUsage: "usage.json", storeDocBandwidth: "bandwidth.json"} {
+		path := filepath.Join(persistDir, name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if err = r.Store.PutDocument(doc, data); err != nil {
+			slog.Warn("Failed to import JSON file into store", "path", path, "err", err)
+			continue
+		}
+		slog.Info("Imported JSON file into store", "path", path)
+		imported(path)
+	}
+}
+
+// SavePeerStore saves the peer store, a no-op for relays without a store
+func (r *Relay) SavePeerStore() error {
+	if r.Store == nil {
+		return nil
+	}
+	if err := r.Store.SavePeers(r.Peers.Copy()); err != nil {
+		return fmt.Errorf("failed to save peer store: %w", err)
+	}
+	slog.Info("PeerStore saved", "peers", r.Peers.Len())
+	return nil
+}
+
+// LoadPeerStore adds stored peers to the peer store
+func (r *Relay) LoadPeerStore() error {
+	if r.Store == nil {
+		return nil
+	}
+	peers, err := r.Store.LoadPeers()
+	if err != nil {
+		return fmt.Errorf("failed to load peer store: %w", err)
+	}
+	for id, pi := range peers {
+		r.Peers.Set(id, pi)
+	}
+	slog.Info("PeerStore loaded", "peers", len(peers))
+	return nil
+}
+
+// ExportPeerStore returns the peer store as JSON, in the format of JSON peer store files
+func (r *Relay) ExportPeerStore() ([]byte, error) {
+	return r.Peers.MarshalJSON()
+}
+
+// ImportPeerStore merges peers of JSON peer store data into the peer store and saves it, returns peers imported
+func (r *Relay) ImportPeerStore(data []byte) (int, error) {
+	var peers map[peer.ID]*PeerInfo
+	if err := json.Unmarshal(data, &peers); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal peer store: %w", err)
+	}
+	imported := 0
+	for id, pi := range peers {
+		if pi == nil || id == r.ID {
+			continue
+		}
+		r.Peers.Set(id, pi)
+		imported++
+	}
+	return imported, r.SavePeerStore()
+}
@@ -0,0 +1,101 @@
+package core
+
+import (
+	"context"
+	"log/slog"
+	"relay/internal/common"
+	"relay/internal/shared"
+	"time"
+
+	gen "relay/internal/proto"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// --- Stream Clock ---
+//
+// Viewers of a room on different relays receive the same frames at different times and, after failovers or variant
+// switches, under different RTP numbering. The relay a room is pushed to maps its video RTP timestamps to the wall
+// clock it received them at, and every relay sends that mapping to its participants in their own numbering.
+// Viewers put the frames they show on one stream timeline with it, to line up overlays, reactions and co-op timing.
+
+// observeStreamClock anchors stream clock of a pushed room on its incoming video. The clock stays at the earliest
+// arriving frame so network jitter doesn't move it, timestamps jumping with a restarted or failed over source
+// anchor it again.
+func observeStreamClock(room *shared.Room, ts, clockRate uint32, now time.Time) {
+	if clockRate == 0 {
+		return
+	}
+	if clock, ok := room.StreamClock(); ok && clock.ClockRate == clockRate {
+		if drift := now.Sub(clock.At(ts)); drift > -streamClockMinAdjust && drift < streamClockMaxDrift {
+			return
+		}
+	}
+	room.SetStreamClock(shared.StreamClock{RTPTimestamp: ts, StreamTime: now, ClockRate: clockRate})
+}
+
+// receiveStreamClock takes stream clock of a pulled room from the relay it is pulled from, in the numbering it sends us
+func (r *Relay) receiveStreamClock(room *shared.Room, data []byte) {
+	var msgWrapper gen.ProtoMessage
+	if err := proto.Unmarshal(data, &msgWrapper); err != nil {
+		slog.Debug("Failed to unmarshal stream clock", "room", room.Name, "err", err)
+		return
+	}
+	clockMsg := msgWrapper.GetStreamClock()
+	if clockMsg == nil || clockMsg.ClockRate == 0 {
+		slog.Debug("Ignoring invalid stream clock from upstream", "room", room.Name)
+		return
+	}
+	room.SetStreamClock(shared.StreamClock{
+		RTPTimestamp: clockMsg.RtpTimestamp,
+		StreamTime:   time.UnixMicro(clockMsg.StreamTimeUs),
+		ClockRate:    clockMsg.ClockRate,
+	})
+}
+
+// sendStreamClock sends stream clock of a room to its participants, mesh relays included so they can pass it on
+func (r *Relay) sendStreamClock(room *shared.Room, clock shared.StreamClock) {
+	for _, participant := range room.GetParticipants() {
+		dc := participant.DataChannel()
+		if dc == nil {
+			continue
+		}
+		msg, err := common.CreateMessage(&gen.ProtoStreamClock{
+			RoomName:     room.Name,
+			RtpTimestamp: participant.VideoTimestamp(clock.RTPTimestamp),
+			StreamTimeUs: clock.StreamTime.UnixMicro(),
+			ClockRate:    clock.ClockRate,
+		}, "stream-clock", nil)
+		if err != nil {
+			slog.Error("Failed to create proto message", "err", err)
+			return
+		}
+		data, err := proto.Marshal(msg)
+		if err != nil {
+			slog.Error("Failed to marshal stream clock", "err", err)
+			return
+		}
+		if err = dc.SendBinary(data); err != nil {
+			slog.Debug("Failed to send stream clock to participant", "room", room.Name, "participant", participant.ID, "err", err)
+		}
+	}
+}
+
+// streamClockBroadcaster sends stream clocks of local rooms to their participants periodically,
+// so viewers joining pick it up and clocks anchored again reach everyone
+func (r *Relay) streamClockBroadcaster(ctx context.Context) {
+	ticker := time.NewTicker(streamClockInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, room := range r.LocalRooms.Copy() {
+				if clock, ok := room.StreamClock(); ok {
+					r.sendStreamClock(room, clock)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"relay/internal/common"
+	"relay/internal/shared"
+
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/webrtc/v4"
+)
+
+// --- Room Thumbnails ---
+//
+// Relays owning a room grab one keyframe of its pushed video every thumbnail interval and have it decoded to an
+// image, so room browsers and dashboards can show what's streamed without joining. The latest thumbnail of each room
+// is served over HTTP and its URL announced in room metadata. Decoders are picked by name, ffmpeg is built in.
+// Thumbnails are served to anyone, so private rooms and rooms viewers must be authorized for get none.
+
+// Thumbnail is an encoded image of a room's video
+type Thumbnail struct {
+	Data        []byte
+	ContentType string
+	CapturedAt  time.Time
+}
+
+// ThumbnailDecoder decodes a keyframe to an image, frame is the depacketized keyframe of given codec
+type ThumbnailDecoder interface {
+	Decode(ctx context.Context, mimeType string, frame []byte) (Thumbnail, error)
+}
+
+// thumbnailDecoders open thumbnail decoders by name, given image format and width
+var thumbnailDecoders = map[string]func(format string, width int) (ThumbnailDecoder, error){
+	"ffmpeg": openFFmpegDecoder,
+}
+
+// RegisterThumbnailDecoder makes a thumbnail decoder available under given name, call before relay starts
+func RegisterThumbnailDecoder(name string, open func(format string, width int) (ThumbnailDecoder, error)) {
+	thumbnailDecoders[name] = open
+}
+
+// OpenThumbnailDecoder opens the thumbnail decoder of given name
+func OpenThumbnailDecoder(name, format string, width int) (ThumbnailDecoder, error) {
+	open, ok := thumbnailDecoders[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown thumbnail decoder %q", name)
+	}
+	return open(format, width)
+}
+
+// --- FFmpeg Decoder ---
+
+// FFmpegDecoder decodes keyframes by running ffmpeg on each
+type FFmpegDecoder struct {
+	path   string
+	format string
+	width  int
+}
+
+func openFFmpegDecoder(format string, width int) (ThumbnailDecoder, error) {
+	path, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return nil, fmt.Errorf("failed to find ffmpeg: %w", err)
+	}
+	return &FFmpegDecoder{path: path, format: format, width: width}, nil
+}
+
+func (d *FFmpegDecoder) Decode(ctx context.Context, mimeType string, frame []byte) (Thumbnail, error) {
+	var input string
+	switch strings.ToLower(mimeType) {
+	case strings.ToLower(webrtc.MimeTypeH264):
+		input = "h264"
+	case strings.ToLower(webrtc.MimeTypeH265):
+		input = "hevc"
+	case strings.ToLower(webrtc.MimeTypeAV1):
+		input = "obu"
+	case strings.ToLower(webrtc.MimeTypeVP8), strings.ToLower(webrtc.MimeTypeVP9):
+		// Raw VP8 and VP9 frames have no framing ffmpeg can probe, wrap them in IVF
+		input, frame = "ivf", ivfFrame(mimeType, frame)
+	default:
+		return Thumbnail{}, fmt.Errorf("no ffmpeg input for codec %s", mimeType)
+	}
+
+	codec, contentType := "mjpeg", "image/jpeg"
+	if d.format == common.ThumbnailWebP {
+		codec, contentType = "libwebp", "image/webp"
+	}
+	cmd := exec.CommandContext(ctx, d.path, "-hide_banner", "-loglevel", "error",
+		"-f", input, "-i", "pipe:0",
+		"-frames:v", "1", "-vf", "scale="+strconv.Itoa(d.width)+":-2",
+		"-c:v", codec, "-f", "image2pipe", "pipe:1")
+	cmd.Stdin = bytes.NewReader(frame)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return Thumbnail{}, fmt.Errorf("ffmpeg failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if len(out) <= 0 {
+		return Thumbnail{}, errors.New("ffmpeg decoded no image")
+	}
+	return Thumbnail{Data: out, ContentType: contentType}, nil
+}
+
+// ivfFrame wraps a VP8 or VP9 frame into an IVF file of one frame, dimensions are left for the decoder to read
+func ivfFrame(mimeType string, frame []byte) []byte {
+	fourcc := "VP80"
+	if strings.EqualFold(mimeType, webrtc.MimeTypeVP9) {
+		fourcc = "VP90"
+	}
+	buf := make([]byte, 32+12, 32+12+len(frame))
+	copy(buf[0:], "DKIF")
+	binary.LittleEndian.PutUint16(buf[6:], 32) // Header size
+	copy(buf[8:], fourcc)
+	binary.LittleEndian.PutUint32(buf[16:], 90000) // Timebase denominator
+	binary.LittleEndian.PutUint32(buf[20:], 1)     // Timebase numerator
+	binary.LittleEndian.PutUint32(buf[24:], 1)     // Frame count
+	binary.LittleEndian.PutUint32(buf[32:], uint32(len(frame)))
+	return append(buf, frame...)
+}
+
+// --- Keyframe Grabbing ---
+
+// thumbnailGrabber collects a keyframe of a pushed video track once per thumbnail interval, used by the track's reader
+type thumbnailGrabber struct {
+	relay    *Relay
+	room     *shared.Room
+	mimeType string
+	interval time.Duration
+	due      time.Time   // Frames before this aren't collected
+	payloads [][]byte    // Payloads of frame being collected, all of one RTP timestamp
+	ts       uint32      // RTP timestamp of last packet
+	seq      uint16      // Sequence number of last packet
+	keyframe bool        // Frame being collected is a keyframe
+	decoding atomic.Bool // Previous keyframe is still being decoded
+}
+
+// newThumbnailGrabber returns a grabber for a video track pushed to a room, nil if thumbnails are disabled
+func (r *Relay) newThumbnailGrabber(room *shared.Room, mimeType string) *thumbnailGrabber {
+	interval := time.Duration(common.GetFlags().ThumbnailInterval) * time.Second
+	// Variants show the same picture as their room, rooms of relays authorizing viewers are never public
+	if r.thumbnailDecoder == nil || interval <= 0 || room.Name != shared.BaseRoomName(room.Name) || r.viewerAuth != nil {
+		return nil
+	}
+	return &thumbnailGrabber{relay: r, room: room, mimeType: mimeType, interval: interval}
+}
+
+// push collects packet if a thumbnail is due, decoding the frame in the background once it is complete
+func (g *thumbnailGrabber) push(pkt *rtp.Packet) {
+	frameStart, prevSeq := pkt.Timestamp != g.ts, g.seq
+	g.ts, g.seq = pkt.Timestamp, pkt.SequenceNumber
+	if len(g.payloads) <= 0 {
+		// Parameter sets may precede the keyframe in packets of their own, collect whole frames from their start
+		if !frameStart || time.Now().Before(g.due) || g.decoding.Load() {
+			return
+		}
+	} else if frameStart || pkt.SequenceNumber != prevSeq+1 || len(g.payloads) >= thumbnailMaxPackets {
+		// Lost or reordered packets, or a frame that never ended, start over at the next frame
+		g.payloads, g.keyframe = nil, false
+		if !frameStart {
+			return
+		}
+	}
+	g.payloads = append(g.payloads, bytes.Clone(pkt.Payload))
+	g.keyframe = g.keyframe || common.IsKeyframe(g.mimeType, pkt.Payload)
+	if !pkt.Marker {
+		return
+	}
+
+	payloads, keyframe := g.payloads, g.keyframe
+	g.payloads, g.keyframe = nil, false
+	if !keyframe {
+		return
+	}
+	g.due = time.Now().Add(g.interval)
+	g.decoding.Store(true)
+	go func() {
+		defer g.decoding.Store(false)
+		g.relay.decodeThumbnail(g.room, g.mimeType, payloads)
+	}()
+}
+
+// depacketizeKeyframe joins RTP payloads of one frame into the frame of given codec
+func depacketizeKeyframe(mimeType string, payloads [][]byte) ([]byte, error) {
+	var depacketizer rtp.Depacketizer
+	switch strings.ToLower(mimeType) {
+	case strings.ToLower(webrtc.MimeTypeH264):
+		depacketizer = &codecs.H264Packet{}
+	case strings.ToLower(webrtc.MimeTypeH265):
+		depacketizer = &codecs.H265Packet{}
+	case strings.ToLower(webrtc.MimeTypeVP8):
+		depacketizer = &codecs.VP8Packet{}
+	case strings.ToLower(webrtc.MimeTypeVP9):
+		depacketizer = &codecs.VP9Packet{}
+	case strings.ToLower(webrtc.MimeTypeAV1):
+		depacketizer = &codecs.AV1Depacketizer{}
+	default:
+		return nil, fmt.Errorf("no depacketizer for codec %s", mimeType)
+	}
+	var frame []byte
+	for _, payload := range payloads {
+		data, err := depacketizer.Unmarshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to depacketize keyframe: %w", err)
+		}
+		frame = append(frame, data...)
+	}
+	return frame, nil
+}
+
+// decodeThumbnail decodes a keyframe of a room and keeps it as the room's thumbnail
+func (r *Relay) decodeThumbnail(room *shared.Room, mimeType string, payloads [][]byte) {
+	// Room may have become protected since its video was pushed
+	if !r.thumbnailPublic(room) {
+		r.thumbnails.Delete(room.Name)
+		return
+	}
+	frame, err := depacketizeKeyframe(mimeType, payloads)
+	if err != nil {
+		slog.Debug("Failed to assemble keyframe for thumbnail", "room", room.Name, "codec", mimeType, "err", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), thumbnailDecodeTimeout)
+	defer cancel()
+	thumb, err := r.thumbnailDecoder.Decode(ctx, mimeType, frame)
+	if err != nil {
+		slog.Warn("Failed to decode room thumbnail", "room", room.Name, "codec", mimeType, "err", err)
+		return
+	}
+	thumb.CapturedAt = time.Now()
+	r.thumbnails.Set(room.Name, thumb)
+	slog.Debug("Captured room thumbnail", "room", room.Name, "bytes", len(thumb.Data))
+
+	// Announce where the thumbnail is once, later ones replace it at the same URL
+	if meta, thumbURL := room.GetMetadata(), r.thumbnailURL(room.Name); meta.ThumbnailURL != thumbURL {
+		meta.ThumbnailURL = thumbURL
+		room.SetMetadata(meta)
+		if err = r.publishRoomStates(context.Background()); err != nil {
+			slog.Error("Failed to publish room states after thumbnail", "room", room.Name, "err", err)
+		}
+	}
+}
+
+// --- Relay Thumbnails ---
+
+// UseThumbnailDecoder makes the relay grab thumbnails of owned rooms with given decoder, at the configured interval
+func (r *Relay) UseThumbnailDecoder(decoder ThumbnailDecoder) {
+	r.thumbnailDecoder = decoder
+}
+
+// thumbnailPublic returns whether thumbnails of a room may be shown to anyone, they can't be for private rooms
+// and rooms protected by an access secret or viewer tokens
+func (r *Relay) thumbnailPublic(room *shared.Room) bool {
+	if r.viewerAuth != nil || len(room.GetSettings().AccessHash) > 0 || room.GetMetadata().Private {
+		return false
+	}
+	entry, err := r.directoryRoom(room.Name)
+	return err == nil && !entry.Private
+}
+
+// thumbnailURL returns public URL of a room's thumbnail, empty if the room has none yet
+func (r *Relay) thumbnailURL(roomName string) string {
+	if !r.thumbnails.Has(roomName) {
+		return ""
+	}
+	thumbURL, err := url.JoinPath(common.GetFlags().ThumbnailURL, "thumbnails", roomName)
+	if err != nil {
+		slog.Warn("Failed to build thumbnail URL", "room", roomName, "err", err)
+		return ""
+	}
+	return thumbURL
+}
+
+// thumbnailSweeper forgets thumbnails of closed rooms
+func (r *Relay) thumbnailSweeper(ctx context.Context) {
+	events, unsubscribe := r.Events.Subscribe(thumbnailEventBuffer)
+	defer unsubscribe()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-events:
+			if event.Type == EventRoomClosed {
+				r.thumbnails.Delete(event.Room)
+			}
+		}
+	}
+}
+
+// ThumbnailHandler serves latest thumbnails of rooms at /thumbnails/{name}
+func (r *Relay) ThumbnailHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /thumbnails/{name}", func(w http.ResponseWriter, req *http.Request) {
+		name := req.PathValue("name")
+		thumb, ok := r.thumbnails.Get(name)
+		// Rooms made private or protected after their thumbnail was grabbed are checked again here
+		if room := r.GetRoomByName(name); !ok || room == nil || !r.thumbnailPublic(room) {
+			http.NotFound(w, req)
+			return
+		}
+		w.Header().Set("Content-Type", thumb.ContentType)
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", common.GetFlags().ThumbnailInterval))
+		// Dashboards may draw thumbnails on canvases of their own origin
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		http.ServeContent(w, req, "", thumb.CapturedAt, bytes.NewReader(thumb.Data))
+	})
+	return mux
+}
+
+// startThumbnailServer serves latest thumbnails of rooms until context is done
+func (r *Relay) startThumbnailServer(ctx context.Context) {
+	port := common.GetFlags().ThumbnailPort
+	server := &http.Server{
+		Addr:              fmt.Sprintf(":%d", port),
+		Handler:           r.ThumbnailHandler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+	go func() {
+		slog.Info("Starting thumbnail server", "port", port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Thumbnail server failed", "err", err)
+		}
+	}()
+}
@@ -0,0 +1,216 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"relay/internal/common"
+	"relay/internal/shared"
+	"sync"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// --- Usage Accounting ---
+
+// UsageCounters are cumulative usage totals, persisted so they survive relay restarts
+type UsageCounters struct {
+	Since         time.Time `json:"since"`          // When accounting started
+	StreamSeconds float64   `json:"stream_seconds"` // Time rooms were online on this relay
+	ViewerSeconds float64   `json:"viewer_seconds"` // Time participants were watching, summed over participants
+	IngressBytes  uint64    `json:"ingress_bytes"`  // Payload bytes received from room streams
+	EgressBytes   uint64    `json:"egress_bytes"`   // Payload bytes written to participants
+}
+
+// roomUsage remembers room counters at last sample, to account only the difference
+type roomUsage struct {
+	ingress uint64
+	egress  uint64
+}
+
+// Usage accumulates UsageCounters from local rooms
+type Usage struct {
+	mtx        sync.Mutex
+	counters   UsageCounters
+	lastSample time.Time
+	rooms      map[ulid.ULID]roomUsage
+}
+
+func NewUsage() *Usage {
+	now := time.Now()
+	return &Usage{
+		counters:   UsageCounters{Since: now},
+		lastSample: now,
+		rooms:      make(map[ulid.ULID]roomUsage),
+	}
+}
+
+// Counters returns a copy of current usage totals
+func (u *Usage) Counters() UsageCounters {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+	return u.counters
+}
+
+// Sample accounts usage of given rooms since previous sample, rooms not given anymore are forgotten
+func (u *Usage) Sample(rooms map[ulid.ULID]*shared.Room, now time.Time) {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+
+	elapsed := now.Sub(u.lastSample)
+	u.lastSample = now
+	for _, room := range rooms {
+		u.collectRoom(room, elapsed)
+	}
+	for id := range u.rooms {
+		if _, ok := rooms[id]; !ok {
+			delete(u.rooms, id)
+		}
+	}
+}
+
+// Forget accounts remaining usage of a room being removed, so nothing since last sample is lost
+func (u *Usage) Forget(room *shared.Room) {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+	u.collectRoom(room, time.Since(u.lastSample))
+	delete(u.rooms, room.ID)
+}
+
+// collectRoom adds room counters since last sample, mutex must be held
+func (u *Usage) collectRoom(room *shared.Room, elapsed time.Duration) {
+	ingress := room.AudioStats.Snapshot().Bytes + room.VideoStats.Snapshot().Bytes
+	egress := room.EgressStats.Snapshot().Bytes
+
+	last := u.rooms[room.ID]
+	// Counters only grow, a lower value means a new Room struct took over the ID
+	if ingress >= last.ingress {
+		u.counters.IngressBytes += ingress - last.ingress
+	}
+	if egress >= last.egress {
+		u.counters.EgressBytes += egress - last.egress
+	}
+	u.rooms[room.ID] = roomUsage{ingress: ingress, egress: egress}
+
+	if room.IsOnline() {
+		u.counters.StreamSeconds += elapsed.Seconds()
+		u.counters.ViewerSeconds += elapsed.Seconds() * float64(room.ParticipantCount())
+	}
+}
+
+// restore adds previously persisted totals, accounting started at the earlier time
+func (u *Usage) restore(persisted UsageCounters) {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+	if !persisted.Since.IsZero() && persisted.Since.Before(u.counters.Since) {
+		u.counters.Since = persisted.Since
+	}
+	u.counters.StreamSeconds += persisted.StreamSeconds
+	u.counters.ViewerSeconds += persisted.ViewerSeconds
+	u.counters.IngressBytes += persisted.IngressBytes
+	u.counters.EgressBytes += persisted.EgressBytes
+}
+
+// SaveUsage samples local rooms and peers, saving usage totals and peer bandwidth to store
+func (r *Relay) SaveUsage() error {
+	now := time.Now()
+	r.Usage.Sample(r.LocalRooms.Copy(), now)
+	r.Bandwidth.Sample(now, r.isConnected)
+	if r.Store == nil {
+		return nil
+	}
+	return errors.Join(r.Usage.Save(r.Store), r.Bandwidth.Save(r.Store))
+}
+
+// Save saves usage totals to store
+func (u *Usage) Save(store Store) error {
+	data, err := json.Marshal(u.Counters())
+	if err != nil {
+		return errors.New("failed to marshal usage data: " + err.Error())
+	}
+	if err = store.PutDocument(storeDocUsage, data); err != nil {
+		return errors.New("failed to save usage: " + err.Error())
+	}
+	slog.Debug("Usage saved")
+	return nil
+}
+
+// Load restores usage totals from store
+func (u *Usage) Load(store Store) error {
+	data, err := store.GetDocument(storeDocUsage)
+	if err != nil {
+		return errors.New("failed to read usage: " + err.Error())
+	}
+	if data == nil {
+		slog.Info("No usage saved, starting accounting from zero")
+		return nil
+	}
+
+	var persisted UsageCounters
+	if err = json.Unmarshal(data, &persisted); err != nil {
+		return errors.New("failed to unmarshal usage data: " + err.Error())
+	}
+	u.restore(persisted)
+
+	slog.Info("Usage loaded", "since", persisted.Since)
+	return nil
+}
+
+// periodicUsageSnapshot samples usage of local rooms and snapshots it to persistent directory
+func (r *Relay) periodicUsageSnapshot(ctx context.Context) {
+	if common.GetFlags().Metrics {
+		r.registerUsageMetrics()
+	}
+
+	ticker := time.NewTicker(usageSampleInterval)
+	defer ticker.Stop()
+
+	lastSave := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Stopping usage accounting")
+			return
+		case now := <-ticker.C:
+			r.Usage.Sample(r.LocalRooms.Copy(), now)
+			r.Bandwidth.Sample(now, r.isConnected)
+			r.enforceQuotas(now)
+			if now.Sub(lastSave) < usageSnapshotInterval {
+				continue
+			}
+			lastSave = now
+			if r.Store == nil {
+				continue
+			}
+			if err := r.Usage.Save(r.Store); err != nil {
+				slog.Error("Failed to snapshot usage", "err", err)
+			}
+			if err := r.Bandwidth.Save(r.Store); err != nil {
+				slog.Error("Failed to snapshot bandwidth", "err", err)
+			}
+		}
+	}
+}
+
+// registerUsageMetrics exposes usage totals as prometheus counters
+func (r *Relay) registerUsageMetrics() {
+	counter := func(name, help string, value func(c UsageCounters) float64) prometheus.Collector {
+		return prometheus.NewCounterFunc(prometheus.CounterOpts{Name: name, Help: help}, func() float64 {
+			return value(r.Usage.Counters())
+		})
+	}
+	prometheus.MustRegister(
+		counter("nestri_relay_usage_stream_seconds_total", "Time rooms were online on this relay, persisted across restarts",
+			func(c UsageCounters) float64 { return c.StreamSeconds }),
+		counter("nestri_relay_usage_viewer_seconds_total", "Time participants were watching summed over participants, persisted across restarts",
+			func(c UsageCounters) float64 { return c.ViewerSeconds }),
+		counter("nestri_relay_usage_ingress_bytes_total", "Payload bytes received from room streams, persisted across restarts",
+			func(c UsageCounters) float64 { return float64(c.IngressBytes) }),
+		counter("nestri_relay_usage_egress_bytes_total", "Payload bytes written to participants, persisted across restarts",
+			func(c UsageCounters) float64 { return float64(c.EgressBytes) }),
+		r.Bandwidth,
+	)
+}
@@ -0,0 +1,193 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"relay/internal/common"
+	"relay/internal/connections"
+	"relay/internal/shared"
+	"sort"
+
+	gen "relay/internal/proto"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// --- Quality Variants ---
+
+// roomFamily returns local rooms of a main room and of its quality variants
+func (r *Relay) roomFamily(baseName string) []*shared.Room {
+	rooms := make([]*shared.Room, 0)
+	for _, room := range r.LocalRooms.Copy() {
+		if shared.BaseRoomName(room.Name) == baseName {
+			rooms = append(rooms, room)
+		}
+	}
+	return rooms
+}
+
+// RoomVariants lists the main stream and quality variants of a room known locally or from mesh,
+// main stream first, then by resolution and frame rate
+func (r *Relay) RoomVariants(baseName string) []*gen.ProtoRoomVariant {
+	variants := make(map[string]*gen.ProtoRoomVariant)
+	add := func(roomName string, metadata shared.RoomMetadata, online bool) {
+		_, variant := shared.SplitVariant(roomName)
+		if _, ok := variants[roomName]; ok && !online {
+			return // Keep what the room owner or an online copy of the room told
+		}
+		variants[roomName] = &gen.ProtoRoomVariant{
+			Name:      variant,
+			RoomName:  roomName,
+			Width:     uint32(metadata.Width),
+			Height:    uint32(metadata.Height),
+			FrameRate: uint32(metadata.FrameRate),
+			Online:    online,
+		}
+	}
+	for _, info := range r.Rooms.Copy() {
+		if shared.BaseRoomName(info.Name) == baseName {
+			add(info.Name, info.Metadata, info.Online)
+		}
+	}
+	for _, room := range r.roomFamily(baseName) {
+		add(room.Name, room.GetMetadata(), room.IsOnline())
+	}
+
+	list := make([]*gen.ProtoRoomVariant, 0, len(variants))
+	for _, variant := range variants {
+		list = append(list, variant)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if (len(a.Name) == 0) != (len(b.Name) == 0) {
+			return len(a.Name) == 0
+		}
+		if a.Height != b.Height {
+			return a.Height > b.Height
+		}
+		if a.FrameRate != b.FrameRate {
+			return a.FrameRate > b.FrameRate
+		}
+		return a.Name < b.Name
+	})
+	return list
+}
+
+// offerRoomVariants lets a viewer know which variants it can switch to, rooms without variants send nothing
+func (r *Relay) offerRoomVariants(participant *shared.Participant, roomName string) {
+	baseName, current := shared.SplitVariant(roomName)
+	variants := r.RoomVariants(baseName)
+	if len(variants) <= 1 && len(current) <= 0 {
+		return
+	}
+	msg, err := common.CreateMessage(&gen.ProtoRoomVariants{
+		RoomName: baseName,
+		Current:  current,
+		Variants: variants,
+	}, "room-variants", nil)
+	if err != nil {
+		slog.Error("Failed to create proto message", "err", err)
+		return
+	}
+	if err = sendToParticipant(participant, msg); err != nil {
+		slog.Debug("Failed to send room variants", "room", roomName, "participant", participant.ID, "err", err)
+	}
+}
+
+// announceRoomVariants sends current variants to viewers of a room and its variants, after a variant was pushed
+func (r *Relay) announceRoomVariants(baseName string) {
+	for _, room := range r.roomFamily(baseName) {
+		for _, participant := range room.GetParticipants() {
+			if r.isMeshRelay(participant.PeerID) {
+				continue
+			}
+			r.offerRoomVariants(participant, room.Name)
+		}
+	}
+}
+
+// registerVariantSwitching lets a viewer switch between quality variants of its room over its DataChannel
+func (sp *StreamProtocol) registerVariantSwitching(ndc *connections.NestriDataChannel, participant *shared.Participant) {
+	ndc.RegisterMessageCallback("switch-variant", func(data []byte) {
+		var msgWrapper gen.ProtoMessage
+		if err := proto.Unmarshal(data, &msgWrapper); err != nil {
+			slog.Error("Failed to unmarshal switch-variant message", "err", err)
+			return
+		}
+		switchMsg := msgWrapper.GetVariantSwitch()
+		if switchMsg == nil {
+			slog.Error("Could not GetVariantSwitch from switch-variant")
+			return
+		}
+
+		to, err := sp.switchVariant(participant, switchMsg.Variant)
+		if err != nil {
+			slog.Warn("Failed to switch participant variant", "participant", participant.ID, "variant", switchMsg.Variant, "err", err)
+			sendVariantSwitch(participant, "variant-switch-failed", &gen.ProtoVariantSwitch{Variant: switchMsg.Variant, Error: err.Error()})
+			return
+		}
+		sendVariantSwitch(participant, "variant-switched", &gen.ProtoVariantSwitch{Variant: switchMsg.Variant})
+		sp.relay.offerRoomVariants(participant, to.Name)
+	})
+}
+
+// switchVariant moves a viewer to the room carrying another variant of its room, without renegotiation.
+// Variant rooms not on this relay are pulled through the mesh first.
+func (sp *StreamProtocol) switchVariant(participant *shared.Participant, variant string) (*shared.Room, error) {
+	from := participant.Room()
+	if from == nil {
+		return nil, errors.New("not receiving a room yet")
+	}
+	if len(variant) > 0 && !shared.ValidVariantName(variant) {
+		return nil, errors.New("invalid variant name")
+	}
+	targetName := shared.VariantRoomName(shared.BaseRoomName(from.Name), variant)
+	if targetName == from.Name {
+		return from, nil
+	}
+
+	to, refusal := sp.resolveServedRoom(targetName, participant.PeerID)
+	if to == nil {
+		return nil, fmt.Errorf("variant is not available (%s)", refusal)
+	}
+	if full := sp.relay.roomFull(to); full != nil {
+		return nil, errors.New("variant is full")
+	}
+	if err := sp.relay.CanMoveParticipants(from, to); err != nil {
+		return nil, err
+	}
+	if !sp.relay.MoveParticipant(from, to, participant.ID) {
+		return nil, errors.New("participant left the room")
+	}
+	// Viewer can't decode the new stream until its next keyframe, ask for one now
+	if err := to.RequestKeyframe(); err != nil {
+		slog.Debug("Failed to request keyframe after variant switch", "room", to.Name, "err", err)
+	}
+	return to, nil
+}
+
+// sendVariantSwitch answers a viewer's variant switch request
+func sendVariantSwitch(participant *shared.Participant, payloadType string, switchMsg *gen.ProtoVariantSwitch) {
+	msg, err := common.CreateMessage(switchMsg, payloadType, nil)
+	if err != nil {
+		slog.Error("Failed to create proto message", "err", err)
+		return
+	}
+	if err = sendToParticipant(participant, msg); err != nil {
+		slog.Debug("Failed to send variant switch result", "participant", participant.ID, "err", err)
+	}
+}
+
+// sendToParticipant sends a message to a participant over its data channel
+func sendToParticipant(participant *shared.Participant, msg *gen.ProtoMessage) error {
+	dc := participant.DataChannel()
+	if dc == nil {
+		return errors.New("participant has no data channel")
+	}
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return dc.SendBinary(data)
+}
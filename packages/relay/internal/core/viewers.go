@@ -0,0 +1,140 @@
+package core
+
+import (
+	"context"
+	"log/slog"
+	"relay/internal/common"
+	"relay/internal/shared"
+	"time"
+
+	gen "relay/internal/proto"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+	"google.golang.org/protobuf/proto"
+)
+
+// --- Viewer Counts ---
+
+// localViewers counts participants of a room which are viewers, relays pulling the room are left out
+func (r *Relay) localViewers(room *shared.Room) int {
+	count := 0
+	for _, participant := range room.GetParticipants() {
+		if !r.isMeshRelay(participant.PeerID) {
+			count++
+		}
+	}
+	return count
+}
+
+// updateMeshViewers replaces viewer counts announced by a peer with the ones in its room states
+func (r *Relay) updateMeshViewers(peerID peer.ID, states []shared.RoomInfo) {
+	counts := make(map[string]int)
+	for _, state := range states {
+		if state.RelayID == peerID && state.LocalViewers > 0 {
+			counts[state.Name] += state.LocalViewers
+		}
+	}
+	if len(counts) <= 0 {
+		r.meshViewers.Delete(peerID)
+		return
+	}
+	r.meshViewers.Set(peerID, counts)
+}
+
+// ViewerCount returns viewers of a room and its quality variants, on this relay and as announced by mesh relays
+func (r *Relay) ViewerCount(baseName string) int {
+	count := 0
+	for _, room := range r.roomFamily(baseName) {
+		count += r.localViewers(room)
+	}
+	for _, counts := range r.meshViewers.Copy() {
+		for roomName, viewers := range counts {
+			if shared.BaseRoomName(roomName) == baseName {
+				count += viewers
+			}
+		}
+	}
+	return count
+}
+
+// broadcastViewerCount sends viewer count of a room to its pushing node and local viewers
+func (r *Relay) broadcastViewerCount(baseName string) {
+	rooms := r.roomFamily(baseName)
+	if len(rooms) <= 0 {
+		return
+	}
+	msg, err := common.CreateMessage(&gen.ProtoViewerCount{
+		RoomName: baseName,
+		Viewers:  uint32(r.ViewerCount(baseName)),
+	}, "viewer-count", nil)
+	if err != nil {
+		slog.Error("Failed to create proto message", "err", err)
+		return
+	}
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		slog.Error("Failed to marshal viewer count", "err", err)
+		return
+	}
+	for _, room := range rooms {
+		// Pulled rooms have the upstream relay on their DataChannel, only pushing nodes get counts
+		if dc := room.DataChannel(); len(room.UpstreamID()) <= 0 && dc != nil {
+			if err = dc.SendBinary(data); err != nil {
+				slog.Debug("Failed to send viewer count to pushing node", "room", room.Name, "err", err)
+			}
+		}
+		for _, participant := range room.GetParticipants() {
+			dc := participant.DataChannel()
+			if dc == nil || r.isMeshRelay(participant.PeerID) {
+				continue
+			}
+			if err = dc.SendBinary(data); err != nil {
+				slog.Debug("Failed to send viewer count to participant", "room", room.Name, "participant", participant.ID, "err", err)
+			}
+		}
+	}
+}
+
+// viewerCountBroadcaster sends viewer counts of local rooms periodically and shortly after viewers join or leave,
+// counts of mesh relays arrive with their room states
+func (r *Relay) viewerCountBroadcaster(ctx context.Context) {
+	events, unsubscribe := r.Events.Subscribe(viewerCountEventBuffer)
+	defer unsubscribe()
+
+	ticker := time.NewTicker(viewerCountInterval)
+	defer ticker.Stop()
+
+	pending := make(map[string]struct{})
+	var flush <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-events:
+			if event.Type != EventViewerJoined && event.Type != EventViewerLeft {
+				continue
+			}
+			if r.isMeshRelay(event.PeerID) {
+				continue
+			}
+			pending[shared.BaseRoomName(event.Room)] = struct{}{}
+			if flush == nil {
+				flush = time.After(viewerCountCoalesce)
+			}
+		case <-flush:
+			for baseName := range pending {
+				r.broadcastViewerCount(baseName)
+			}
+			clear(pending)
+			flush = nil
+		case <-ticker.C:
+			families := make(map[string]struct{})
+			for _, room := range r.LocalRooms.Copy() {
+				families[shared.BaseRoomName(room.Name)] = struct{}{}
+			}
+			for baseName := range families {
+				r.broadcastViewerCount(baseName)
+			}
+		}
+	}
+}
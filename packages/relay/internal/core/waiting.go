@@ -0,0 +1,154 @@
+package core
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// --- Room Waiting List ---
+
+// roomWaiter is a viewer's stream request held until its room comes online
+type roomWaiter struct {
+	stream network.Stream
+	queued time.Time
+	serve  func() // Proceeds with the offer flow of the request
+}
+
+// RoomWaitingList holds viewer stream requests of offline rooms in request order,
+// a signaling stream waits for at most one room
+type RoomWaitingList struct {
+	mtx   sync.Mutex
+	rooms map[string][]*roomWaiter // room name -> waiters
+}
+
+func NewRoomWaitingList() *RoomWaitingList {
+	return &RoomWaitingList{
+		rooms: make(map[string][]*roomWaiter),
+	}
+}
+
+// Add puts a request on the waiting list of a room, returns its 1-based position or false if the list is full
+func (wl *RoomWaitingList) Add(roomName string, waiter *roomWaiter) (int, bool) {
+	wl.mtx.Lock()
+	defer wl.mtx.Unlock()
+	if len(wl.rooms[roomName]) >= roomWaitingListMax {
+		return 0, false
+	}
+	wl.rooms[roomName] = append(wl.rooms[roomName], waiter)
+	return len(wl.rooms[roomName]), true
+}
+
+// Remove drops the request of a signaling stream, if it's waiting
+func (wl *RoomWaitingList) Remove(stream network.Stream) {
+	wl.mtx.Lock()
+	defer wl.mtx.Unlock()
+	for roomName, waiters := range wl.rooms {
+		for i, waiter := range waiters {
+			if waiter.stream != stream {
+				continue
+			}
+			waiters = append(waiters[:i], waiters[i+1:]...)
+			if len(waiters) == 0 {
+				delete(wl.rooms, roomName)
+			} else {
+				wl.rooms[roomName] = waiters
+			}
+			return
+		}
+	}
+}
+
+// RemovePeer drops requests of a peer from all waiting lists, returns how many were dropped
+func (wl *RoomWaitingList) RemovePeer(peerID peer.ID) int {
+	wl.mtx.Lock()
+	defer wl.mtx.Unlock()
+	removed := 0
+	for roomName, waiters := range wl.rooms {
+		kept := waiters[:0]
+		for _, waiter := range waiters {
+			if waiter.stream.Conn().RemotePeer() == peerID {
+				removed++
+				continue
+			}
+			kept = append(kept, waiter)
+		}
+		if len(kept) == 0 {
+			delete(wl.rooms, roomName)
+		} else {
+			wl.rooms[roomName] = kept
+		}
+	}
+	return removed
+}
+
+// Take empties the waiting list of a room, returning its requests in request order
+func (wl *RoomWaitingList) Take(roomName string) []*roomWaiter {
+	wl.mtx.Lock()
+	defer wl.mtx.Unlock()
+	waiters := wl.rooms[roomName]
+	delete(wl.rooms, roomName)
+	return waiters
+}
+
+// Len returns how many requests wait for a room
+func (wl *RoomWaitingList) Len(roomName string) int {
+	wl.mtx.Lock()
+	defer wl.mtx.Unlock()
+	return len(wl.rooms[roomName])
+}
+
+// waitForRoom queues a viewer's stream request of an offline room, serve is called once the room comes online
+func (sp *StreamProtocol) waitForRoom(stream network.Stream, roomName, sessionID string, serve func()) {
+	position, ok := sp.waiting.Add(roomName, &roomWaiter{
+		stream: stream,
+		queued: time.Now(),
+		serve:  serve,
+	})
+	if !ok {
+		slog.Warn("Room waiting list is full, not queueing stream request", "room", roomName, "session", sessionID)
+		return
+	}
+	slog.Info("Stream request waiting for room to come online", "room", roomName, "session", sessionID, "position", position)
+}
+
+// serveWaitingRequests proceeds with stream requests which waited for a room that came online
+func (sp *StreamProtocol) serveWaitingRequests(roomName string) {
+	waiters := sp.waiting.Take(roomName)
+	if len(waiters) <= 0 {
+		return
+	}
+	slog.Info("Room came online, serving waiting stream requests", "room", roomName, "waiting", len(waiters))
+	for _, waiter := range waiters {
+		slog.Debug("Serving waiting stream request", "room", roomName, "peer", waiter.stream.Conn().RemotePeer(), "waited", time.Since(waiter.queued))
+		waiter.serve()
+	}
+}
+
+// pullWaitedRoom pulls a room announced online in the mesh if local viewers wait for it, either on the
+// waiting list or as participants left in the room when its upstream ended, and serves the waiting requests
+func (sp *StreamProtocol) pullWaitedRoom(roomName string) {
+	room := sp.relay.GetRoomByName(roomName)
+	if room != nil && (room.IsOnline() || room.OwnerID == sp.relay.ID) {
+		return
+	}
+	if sp.waiting.Len(roomName) <= 0 && (room == nil || !sp.upstreamWanted(room)) {
+		return
+	}
+	go func() {
+		pulled, err := sp.pullRoom(context.Background(), roomName)
+		if err != nil {
+			slog.Debug("Failed to pull room came online for waiting viewers", "room", roomName, "err", err)
+			return
+		}
+		slog.Info("Pulled room came online in mesh for waiting viewers", "room", roomName, "participants", pulled.ParticipantCount())
+		if err = pulled.RequestKeyframe(); err != nil {
+			slog.Debug("Failed to request keyframe of room pulled for waiting viewers", "room", roomName, "err", err)
+		}
+		sp.serveWaitingRequests(roomName)
+	}()
+}
@@ -0,0 +1,159 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"relay/internal/common"
+	"slices"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multiaddr"
+)
+
+// --- WebTransport certificates ---
+
+// WebTransport certificates are derived from the identity key over fixed time buckets, so they survive restarts
+// as long as identity key in persistent directory does. Hashes of the current and next certificate are advertised
+// together, clients pinning both keep connecting across a rollover. Published hashes are persisted to notice and
+// log when pinned clients need updating, either by rollover or an identity change.
+
+// WebTransportCerts are WebTransport certificate hashes and browser dialable addresses of the relay
+type WebTransportCerts struct {
+	PeerID     peer.ID   `json:"peer_id"`
+	CertHashes []string  `json:"cert_hashes"`
+	Addrs      []string  `json:"addrs"` // Full addresses with certificate hashes and peer ID, ready for browsers
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+func webTransportCertsFile() string {
+	return common.GetFlags().PersistDir + "/webtransport.json"
+}
+
+// currentWebTransportCerts returns WebTransport certificate hashes the host currently listens with
+func currentWebTransportCerts(p2pHost host.Host) WebTransportCerts {
+	certs := WebTransportCerts{
+		PeerID:     p2pHost.ID(),
+		CertHashes: make([]string, 0),
+		Addrs:      make([]string, 0),
+	}
+	for _, addr := range browserAddrs(p2pHost) {
+		if !isWebTransportAddr(addr) {
+			continue
+		}
+		certs.Addrs = append(certs.Addrs, addr.String())
+		for _, c := range addr {
+			if c.Code() == multiaddr.P_CERTHASH && !slices.Contains(certs.CertHashes, c.Value()) {
+				certs.CertHashes = append(certs.CertHashes, c.Value())
+			}
+		}
+	}
+	return certs
+}
+
+// browserAddrs returns full addresses of the host browsers can dial, WebTransport with certificate hashes
+// and secure WebSocket
+func browserAddrs(p2pHost host.Host) []multiaddr.Multiaddr {
+	addrs, err := peer.AddrInfoToP2pAddrs(&peer.AddrInfo{ID: p2pHost.ID(), Addrs: p2pHost.Addrs()})
+	if err != nil {
+		return nil
+	}
+	var browser []multiaddr.Multiaddr
+	for _, addr := range addrs {
+		if isWebTransportAddr(addr) || isSecureWebSocketAddr(addr) {
+			browser = append(browser, addr)
+		}
+	}
+	return browser
+}
+
+func isWebTransportAddr(addr multiaddr.Multiaddr) bool {
+	_, wtErr := addr.ValueForProtocol(multiaddr.P_WEBTRANSPORT)
+	_, hashErr := addr.ValueForProtocol(multiaddr.P_CERTHASH)
+	return wtErr == nil && hashErr == nil
+}
+
+func isSecureWebSocketAddr(addr multiaddr.Multiaddr) bool {
+	if _, err := addr.ValueForProtocol(multiaddr.P_WSS); err == nil {
+		return true
+	}
+	_, tlsErr := addr.ValueForProtocol(multiaddr.P_TLS)
+	_, wsErr := addr.ValueForProtocol(multiaddr.P_WS)
+	return tlsErr == nil && wsErr == nil
+}
+
+// loadWebTransportCerts loads previously published WebTransport certificate hashes
+func loadWebTransportCerts(filePath string) (*WebTransportCerts, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	var certs WebTransportCerts
+	if err = json.Unmarshal(data, &certs); err != nil {
+		return nil, errors.New("failed to unmarshal WebTransport certificates: " + err.Error())
+	}
+	return &certs, nil
+}
+
+// saveWebTransportCerts saves published WebTransport certificate hashes, replacing the file atomically
+func saveWebTransportCerts(certs WebTransportCerts, filePath string) error {
+	data, err := json.MarshalIndent(certs, "", "  ")
+	if err != nil {
+		return errors.New("failed to marshal WebTransport certificates: " + err.Error())
+	}
+	tmpPath := filePath + ".tmp"
+	if err = os.WriteFile(tmpPath, data, 0644); err != nil {
+		return errors.New("failed to save WebTransport certificates to file: " + err.Error())
+	}
+	if err = os.Rename(tmpPath, filePath); err != nil {
+		return errors.New("failed to replace WebTransport certificates file: " + err.Error())
+	}
+	return nil
+}
+
+// persistWebTransportCerts saves current WebTransport certificate hashes if they differ from persisted ones
+func (r *Relay) persistWebTransportCerts() {
+	current := currentWebTransportCerts(r.Host)
+	if len(current.CertHashes) <= 0 {
+		return
+	}
+
+	filePath := webTransportCertsFile()
+	previous, err := loadWebTransportCerts(filePath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Warn("Failed to load published WebTransport certificates", "err", err)
+	}
+	if previous != nil && previous.PeerID == current.PeerID && slices.Equal(previous.CertHashes, current.CertHashes) {
+		return
+	}
+	if previous != nil {
+		slog.Warn("WebTransport certificate hashes changed, clients pinning previous ones must update",
+			"previous", previous.CertHashes, "current", current.CertHashes)
+	}
+
+	current.UpdatedAt = time.Now()
+	if err = saveWebTransportCerts(current, filePath); err != nil {
+		slog.Error("Failed to persist WebTransport certificates", "err", err)
+	}
+}
+
+// webTransportCertWatcher persists WebTransport certificate hashes as they roll over
+func (r *Relay) webTransportCertWatcher(ctx context.Context) {
+	r.persistWebTransportCerts()
+
+	ticker := time.NewTicker(certHashCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.persistWebTransportCerts()
+		}
+	}
+}
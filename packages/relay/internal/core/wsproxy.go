@@ -0,0 +1,243 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"relay/internal/common"
+	"strings"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+	"github.com/pires/go-proxyproto"
+)
+
+// --- WebSocket Reverse Proxy Front ---
+
+// wsProxyFront accepts WebSocket connections forwarded by reverse proxies and passes them to libp2p on loopback,
+// remembering the real client address of each so logging and checks see the client instead of the proxy
+type wsProxyFront struct {
+	prefix   string
+	trusted  []*net.IPNet
+	backend  *url.URL
+	proxy    *httputil.ReverseProxy
+	clientIP *common.SafeMap[string, net.IP] // loopback backend conn addr -> real client IP
+}
+
+type clientIPKey struct{}
+
+// startWSProxyFront serves the proxy-aware WebSocket front on given ports
+func (r *Relay) startWSProxyFront(ctx context.Context, ports []int) error {
+	flags := common.GetFlags()
+
+	backendAddr, err := r.loopbackWSAddr()
+	if err != nil {
+		return err
+	}
+	trusted, err := parseTrustedProxies(flags.WSTrustedProxies)
+	if err != nil {
+		return err
+	}
+
+	front := &wsProxyFront{
+		prefix:   strings.TrimSuffix(flags.WSPathPrefix, "/"),
+		trusted:  trusted,
+		backend:  &url.URL{Scheme: "http", Host: backendAddr},
+		clientIP: common.NewSafeMap[string, net.IP](),
+	}
+	front.proxy = &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(front.backend)
+		},
+		Transport: &http.Transport{
+			DialContext:       front.dialBackend,
+			DisableKeepAlives: true,
+		},
+	}
+	r.wsProxyFront = front
+
+	for _, port := range ports {
+		listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+		if err != nil {
+			return fmt.Errorf("failed to listen on WebSocket port %d: %w", port, err)
+		}
+		if flags.WSProxyProtocol {
+			listener = &proxyproto.Listener{
+				Listener: listener,
+				Policy:   front.proxyProtocolPolicy,
+			}
+		}
+
+		server := &http.Server{
+			Handler:           front,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			<-ctx.Done()
+			_ = server.Close()
+		}()
+		go func() {
+			slog.Info("Serving WebSocket behind reverse proxy", "port", port, "prefix", front.prefix, "proxy_protocol", flags.WSProxyProtocol)
+			if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("WebSocket proxy front failed", "port", port, "err", err)
+			}
+		}()
+	}
+	return nil
+}
+
+// loopbackWSAddr finds the loopback address libp2p WebSocket transport listens on
+func (r *Relay) loopbackWSAddr() (string, error) {
+	for _, addr := range r.Host.Network().ListenAddresses() {
+		if _, err := addr.ValueForProtocol(multiaddr.P_WS); err != nil {
+			continue
+		}
+		ip, err := addr.ValueForProtocol(multiaddr.P_IP4)
+		if err != nil || ip != "127.0.0.1" {
+			continue
+		}
+		port, err := addr.ValueForProtocol(multiaddr.P_TCP)
+		if err != nil {
+			continue
+		}
+		return net.JoinHostPort(ip, port), nil
+	}
+	return "", errors.New("no loopback WebSocket listener")
+}
+
+// parseTrustedProxies parses comma separated IPs and CIDRs
+func parseTrustedProxies(list string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, part := range strings.Split(list, ",") {
+		part = strings.TrimSpace(part)
+		if len(part) <= 0 {
+			continue
+		}
+		if !strings.Contains(part, "/") {
+			if ip := net.ParseIP(part); ip != nil && ip.To4() != nil {
+				part += "/32"
+			} else {
+				part += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy '%s': %w", part, err)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+func (f *wsProxyFront) isTrusted(ip net.IP) bool {
+	for _, ipNet := range f.trusted {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// proxyProtocolPolicy uses PROXY headers from trusted proxies only
+func (f *wsProxyFront) proxyProtocolPolicy(upstream net.Addr) (proxyproto.Policy, error) {
+	if tcpAddr, ok := upstream.(*net.TCPAddr); ok && f.isTrusted(tcpAddr.IP) {
+		return proxyproto.USE, nil
+	}
+	return proxyproto.IGNORE, nil
+}
+
+// requestClientIP returns the real client IP, walking X-Forwarded-For from the right while hops are trusted
+func (f *wsProxyFront) requestClientIP(req *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return nil
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !f.isTrusted(ip) {
+		return ip
+	}
+
+	hops := strings.Split(strings.Join(req.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			break
+		}
+		ip = hop
+		if !f.isTrusted(hop) {
+			break
+		}
+	}
+	return ip
+}
+
+func (f *wsProxyFront) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if len(f.prefix) > 0 {
+		path, ok := strings.CutPrefix(req.URL.Path, f.prefix)
+		if !ok || (len(path) > 0 && path[0] != '/') {
+			http.NotFound(w, req)
+			return
+		}
+		req.URL.Path = path
+		req.URL.RawPath = ""
+	}
+
+	clientIP := f.requestClientIP(req)
+	f.proxy.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), clientIPKey{}, clientIP)))
+}
+
+// dialBackend dials libp2p loopback listener and remembers which client the connection is for
+func (f *wsProxyFront) dialBackend(ctx context.Context, network, addr string) (net.Conn, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	if ip, ok := ctx.Value(clientIPKey{}).(net.IP); ok && ip != nil {
+		f.clientIP.Set(conn.LocalAddr().String(), ip)
+		return &trackedConn{Conn: conn, onClose: func() { f.clientIP.Delete(conn.LocalAddr().String()) }}, nil
+	}
+	return conn, nil
+}
+
+// trackedConn runs onClose once connection closes
+type trackedConn struct {
+	net.Conn
+	onClose func()
+}
+
+func (c *trackedConn) Close() error {
+	if c.onClose != nil {
+		c.onClose()
+		c.onClose = nil
+	}
+	return c.Conn.Close()
+}
+
+// RemoteIP returns the IP of connection's remote end, resolving clients behind the WebSocket reverse proxy
+func (r *Relay) RemoteIP(conn network.Conn) net.IP {
+	addr, err := manet.ToNetAddr(conn.RemoteMultiaddr())
+	if err != nil {
+		return nil
+	}
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		if udpAddr, ok := addr.(*net.UDPAddr); ok {
+			return udpAddr.IP
+		}
+		return nil
+	}
+	if r.wsProxyFront != nil && tcpAddr.IP.IsLoopback() {
+		if ip, ok := r.wsProxyFront.clientIP.Get(tcpAddr.String()); ok {
+			return ip
+		}
+	}
+	return tcpAddr.IP
+}
@@ -0,0 +1,170 @@
+package core
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"relay/internal/common"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/multiformats/go-multiaddr"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// --- Secure WebSocket ---
+
+// wssTLSConfig returns TLS config of secure WebSocket listeners, serving certificate files if set,
+// otherwise certificates obtained over ACME for configured domains
+func wssTLSConfig(ctx context.Context) (*tls.Config, error) {
+	flags := common.GetFlags()
+	if len(flags.WSSCertFile) > 0 || len(flags.WSSKeyFile) > 0 {
+		reloader, err := newCertReloader(flags.WSSCertFile, flags.WSSKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		return &tls.Config{
+			GetCertificate: reloader.GetCertificate,
+			MinVersion:     tls.VersionTLS12,
+		}, nil
+	}
+
+	domains := wssDomains()
+	if len(domains) <= 0 {
+		return nil, errors.New("secure WebSocket needs a certificate file or ACME domains")
+	}
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(domains...),
+		Cache:      autocert.DirCache(filepath.Join(flags.PersistDir, "acme")),
+		Email:      flags.WSSACMEEmail,
+	}
+	if len(flags.WSSACMEDirectory) > 0 {
+		manager.Client = &acme.Client{DirectoryURL: flags.WSSACMEDirectory}
+	}
+	if flags.WSSACMEHTTPPort > 0 {
+		go serveACMEChallenges(ctx, manager, flags.WSSACMEHTTPPort)
+	}
+	slog.Info("Obtaining secure WebSocket certificates over ACME", "domains", domains)
+	return &tls.Config{
+		GetCertificate: manager.GetCertificate,
+		// WebSocket upgrades need HTTP/1.1, TLS-ALPN-01 challenges come in over their own protocol
+		NextProtos: []string{"http/1.1", acme.ALPNProto},
+		MinVersion: tls.VersionTLS12,
+	}, nil
+}
+
+// wssDomains returns domains secure WebSocket certificates are obtained for
+func wssDomains() []string {
+	var domains []string
+	for _, domain := range strings.Split(common.GetFlags().WSSDomains, ",") {
+		if domain = strings.TrimSpace(domain); len(domain) > 0 {
+			domains = append(domains, domain)
+		}
+	}
+	return domains
+}
+
+// wssDomainAddrs returns secure WebSocket addresses under ACME domains, browsers validate certificates
+// against the name they dial so these are announced next to IP addresses
+func wssDomainAddrs(ports []int) []multiaddr.Multiaddr {
+	if len(common.GetFlags().WSSCertFile) > 0 {
+		return nil
+	}
+	var addrs []multiaddr.Multiaddr
+	for _, domain := range wssDomains() {
+		for _, port := range ports {
+			addr, err := multiaddr.NewMultiaddr(fmt.Sprintf("/dns/%s/tcp/%d/tls/ws", domain, port))
+			if err != nil {
+				slog.Warn("Failed to build secure WebSocket domain address", "domain", domain, "err", err)
+				continue
+			}
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+// serveACMEChallenges answers ACME HTTP-01 challenges until context is done
+func serveACMEChallenges(ctx context.Context, manager *autocert.Manager, port int) {
+	server := &http.Server{
+		Addr:              fmt.Sprintf(":%d", port),
+		Handler:           manager.HTTPHandler(nil),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+	slog.Info("Answering ACME HTTP challenges", "port", port)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("Failed to serve ACME HTTP challenges", "port", port, "err", err)
+	}
+}
+
+// certReloader serves a certificate from files, loading it again once the files change on disk
+type certReloader struct {
+	mtx      sync.Mutex
+	certFile string
+	keyFile  string
+	cert     *tls.Certificate
+	modTime  time.Time
+	checked  time.Time
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	if len(certFile) <= 0 || len(keyFile) <= 0 {
+		return nil, errors.New("both certificate and key file must be set")
+	}
+	cr := &certReloader{certFile: certFile, keyFile: keyFile}
+	if _, err := cr.load(time.Now()); err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
+
+// GetCertificate returns current certificate, checking files for changes at most every certReloadInterval
+func (cr *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cr.mtx.Lock()
+	defer cr.mtx.Unlock()
+	now := time.Now()
+	if now.Sub(cr.checked) < certReloadInterval {
+		return cr.cert, nil
+	}
+	cert, err := cr.load(now)
+	if err != nil {
+		// Keep serving previous certificate, renewal tools may be midway writing the files
+		slog.Warn("Failed to reload certificate", "err", err)
+		return cr.cert, nil
+	}
+	return cert, nil
+}
+
+// load reads certificate files if they changed since last load, caller holds mtx or owns cr
+func (cr *certReloader) load(now time.Time) (*tls.Certificate, error) {
+	cr.checked = now
+	info, err := os.Stat(cr.certFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check certificate file: %w", err)
+	}
+	if cr.cert != nil && !info.ModTime().After(cr.modTime) {
+		return cr.cert, nil
+	}
+	cert, err := tls.LoadX509KeyPair(cr.certFile, cr.keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certificate: %w", err)
+	}
+	if cr.cert != nil {
+		slog.Info("Reloaded certificate", "path", cr.certFile)
+	}
+	cr.cert = &cert
+	cr.modTime = info.ModTime()
+	return cr.cert, nil
+}
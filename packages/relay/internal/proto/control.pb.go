@@ -0,0 +1,840 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        (unknown)
+// source: control.proto
+
+package proto
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// ControlEmpty message
+type ControlEmpty struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ControlEmpty) Reset() {
+	*x = ControlEmpty{}
+	mi := &file_control_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ControlEmpty) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ControlEmpty) ProtoMessage() {}
+
+func (x *ControlEmpty) ProtoReflect() protoreflect.Message {
+	mi := &file_control_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ControlEmpty.ProtoReflect.Descriptor instead.
+func (*ControlEmpty) Descriptor() ([]byte, []int) {
+	return file_control_proto_rawDescGZIP(), []int{0}
+}
+
+// ControlParticipant message
+type ControlParticipant struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	SessionId     string                 `protobuf:"bytes,2,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	PeerId        string                 `protobuf:"bytes,3,opt,name=peer_id,json=peerId,proto3" json:"peer_id,omitempty"`
+	QueueDelayMs  uint32                 `protobuf:"varint,4,opt,name=queue_delay_ms,json=queueDelayMs,proto3" json:"queue_delay_ms,omitempty"`
+	DroppedFrames uint64                 `protobuf:"varint,5,opt,name=dropped_frames,json=droppedFrames,proto3" json:"dropped_frames,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ControlParticipant) Reset() {
+	*x = ControlParticipant{}
+	mi := &file_control_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ControlParticipant) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ControlParticipant) ProtoMessage() {}
+
+func (x *ControlParticipant) ProtoReflect() protoreflect.Message {
+	mi := &file_control_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ControlParticipant.ProtoReflect.Descriptor instead.
+func (*ControlParticipant) Descriptor() ([]byte, []int) {
+	return file_control_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ControlParticipant) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ControlParticipant) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *ControlParticipant) GetPeerId() string {
+	if x != nil {
+		return x.PeerId
+	}
+	return ""
+}
+
+func (x *ControlParticipant) GetQueueDelayMs() uint32 {
+	if x != nil {
+		return x.QueueDelayMs
+	}
+	return 0
+}
+
+func (x *ControlParticipant) GetDroppedFrames() uint64 {
+	if x != nil {
+		return x.DroppedFrames
+	}
+	return 0
+}
+
+// ControlRoom message
+type ControlRoom struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	OwnerId       string                 `protobuf:"bytes,3,opt,name=owner_id,json=ownerId,proto3" json:"owner_id,omitempty"`
+	Settings      *ProtoRoomSettings     `protobuf:"bytes,4,opt,name=settings,proto3" json:"settings,omitempty"`
+	Viewers       int32                  `protobuf:"varint,5,opt,name=viewers,proto3" json:"viewers,omitempty"`
+	Online        bool                   `protobuf:"varint,6,opt,name=online,proto3" json:"online,omitempty"`
+	UpstreamId    string                 `protobuf:"bytes,7,opt,name=upstream_id,json=upstreamId,proto3" json:"upstream_id,omitempty"` // Relay the room is pulled from, empty if pushed here
+	HopLatencyMs  uint32                 `protobuf:"varint,8,opt,name=hop_latency_ms,json=hopLatencyMs,proto3" json:"hop_latency_ms,omitempty"`
+	Stats         *ProtoStreamStats      `protobuf:"bytes,9,opt,name=stats,proto3" json:"stats,omitempty"`
+	Participants  []*ControlParticipant  `protobuf:"bytes,10,rep,name=participants,proto3" json:"participants,omitempty"` // Only set by GetRoom
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ControlRoom) Reset() {
+	*x = ControlRoom{}
+	mi := &file_control_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ControlRoom) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ControlRoom) ProtoMessage() {}
+
+func (x *ControlRoom) ProtoReflect() protoreflect.Message {
+	mi := &file_control_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ControlRoom.ProtoReflect.Descriptor instead.
+func (*ControlRoom) Descriptor() ([]byte, []int) {
+	return file_control_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ControlRoom) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ControlRoom) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ControlRoom) GetOwnerId() string {
+	if x != nil {
+		return x.OwnerId
+	}
+	return ""
+}
+
+func (x *ControlRoom) GetSettings() *ProtoRoomSettings {
+	if x != nil {
+		return x.Settings
+	}
+	return nil
+}
+
+func (x *ControlRoom) GetViewers() int32 {
+	if x != nil {
+		return x.Viewers
+	}
+	return 0
+}
+
+func (x *ControlRoom) GetOnline() bool {
+	if x != nil {
+		return x.Online
+	}
+	return false
+}
+
+func (x *ControlRoom) GetUpstreamId() string {
+	if x != nil {
+		return x.UpstreamId
+	}
+	return ""
+}
+
+func (x *ControlRoom) GetHopLatencyMs() uint32 {
+	if x != nil {
+		return x.HopLatencyMs
+	}
+	return 0
+}
+
+func (x *ControlRoom) GetStats() *ProtoStreamStats {
+	if x != nil {
+		return x.Stats
+	}
+	return nil
+}
+
+func (x *ControlRoom) GetParticipants() []*ControlParticipant {
+	if x != nil {
+		return x.Participants
+	}
+	return nil
+}
+
+// ControlListRoomsRequest message
+type ControlListRoomsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ControlListRoomsRequest) Reset() {
+	*x = ControlListRoomsRequest{}
+	mi := &file_control_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ControlListRoomsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ControlListRoomsRequest) ProtoMessage() {}
+
+func (x *ControlListRoomsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_control_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ControlListRoomsRequest.ProtoReflect.Descriptor instead.
+func (*ControlListRoomsRequest) Descriptor() ([]byte, []int) {
+	return file_control_proto_rawDescGZIP(), []int{3}
+}
+
+// ControlListRoomsResponse message
+type ControlListRoomsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Rooms         []*ControlRoom         `protobuf:"bytes,1,rep,name=rooms,proto3" json:"rooms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ControlListRoomsResponse) Reset() {
+	*x = ControlListRoomsResponse{}
+	mi := &file_control_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ControlListRoomsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ControlListRoomsResponse) ProtoMessage() {}
+
+func (x *ControlListRoomsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_control_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ControlListRoomsResponse.ProtoReflect.Descriptor instead.
+func (*ControlListRoomsResponse) Descriptor() ([]byte, []int) {
+	return file_control_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ControlListRoomsResponse) GetRooms() []*ControlRoom {
+	if x != nil {
+		return x.Rooms
+	}
+	return nil
+}
+
+// ControlRoomRequest message
+type ControlRoomRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RoomName      string                 `protobuf:"bytes,1,opt,name=room_name,json=roomName,proto3" json:"room_name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ControlRoomRequest) Reset() {
+	*x = ControlRoomRequest{}
+	mi := &file_control_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ControlRoomRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ControlRoomRequest) ProtoMessage() {}
+
+func (x *ControlRoomRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_control_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ControlRoomRequest.ProtoReflect.Descriptor instead.
+func (*ControlRoomRequest) Descriptor() ([]byte, []int) {
+	return file_control_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ControlRoomRequest) GetRoomName() string {
+	if x != nil {
+		return x.RoomName
+	}
+	return ""
+}
+
+// ControlParticipantRequest message
+type ControlParticipantRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RoomName      string                 `protobuf:"bytes,1,opt,name=room_name,json=roomName,proto3" json:"room_name,omitempty"`
+	ParticipantId string                 `protobuf:"bytes,2,opt,name=participant_id,json=participantId,proto3" json:"participant_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ControlParticipantRequest) Reset() {
+	*x = ControlParticipantRequest{}
+	mi := &file_control_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ControlParticipantRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ControlParticipantRequest) ProtoMessage() {}
+
+func (x *ControlParticipantRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_control_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ControlParticipantRequest.ProtoReflect.Descriptor instead.
+func (*ControlParticipantRequest) Descriptor() ([]byte, []int) {
+	return file_control_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ControlParticipantRequest) GetRoomName() string {
+	if x != nil {
+		return x.RoomName
+	}
+	return ""
+}
+
+func (x *ControlParticipantRequest) GetParticipantId() string {
+	if x != nil {
+		return x.ParticipantId
+	}
+	return ""
+}
+
+// ControlPeer message
+type ControlPeer struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Addrs         []string               `protobuf:"bytes,2,rep,name=addrs,proto3" json:"addrs,omitempty"`
+	Connected     bool                   `protobuf:"varint,3,opt,name=connected,proto3" json:"connected,omitempty"`
+	LatencyMs     uint32                 `protobuf:"varint,4,opt,name=latency_ms,json=latencyMs,proto3" json:"latency_ms,omitempty"`
+	LastSeenUnix  int64                  `protobuf:"varint,5,opt,name=last_seen_unix,json=lastSeenUnix,proto3" json:"last_seen_unix,omitempty"`
+	DialFailures  int32                  `protobuf:"varint,6,opt,name=dial_failures,json=dialFailures,proto3" json:"dial_failures,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ControlPeer) Reset() {
+	*x = ControlPeer{}
+	mi := &file_control_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ControlPeer) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ControlPeer) ProtoMessage() {}
+
+func (x *ControlPeer) ProtoReflect() protoreflect.Message {
+	mi := &file_control_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ControlPeer.ProtoReflect.Descriptor instead.
+func (*ControlPeer) Descriptor() ([]byte, []int) {
+	return file_control_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ControlPeer) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ControlPeer) GetAddrs() []string {
+	if x != nil {
+		return x.Addrs
+	}
+	return nil
+}
+
+func (x *ControlPeer) GetConnected() bool {
+	if x != nil {
+		return x.Connected
+	}
+	return false
+}
+
+func (x *ControlPeer) GetLatencyMs() uint32 {
+	if x != nil {
+		return x.LatencyMs
+	}
+	return 0
+}
+
+func (x *ControlPeer) GetLastSeenUnix() int64 {
+	if x != nil {
+		return x.LastSeenUnix
+	}
+	return 0
+}
+
+func (x *ControlPeer) GetDialFailures() int32 {
+	if x != nil {
+		return x.DialFailures
+	}
+	return 0
+}
+
+// ControlListPeersRequest message
+type ControlListPeersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ControlListPeersRequest) Reset() {
+	*x = ControlListPeersRequest{}
+	mi := &file_control_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ControlListPeersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ControlListPeersRequest) ProtoMessage() {}
+
+func (x *ControlListPeersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_control_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ControlListPeersRequest.ProtoReflect.Descriptor instead.
+func (*ControlListPeersRequest) Descriptor() ([]byte, []int) {
+	return file_control_proto_rawDescGZIP(), []int{8}
+}
+
+// ControlListPeersResponse message
+type ControlListPeersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Peers         []*ControlPeer         `protobuf:"bytes,1,rep,name=peers,proto3" json:"peers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ControlListPeersResponse) Reset() {
+	*x = ControlListPeersResponse{}
+	mi := &file_control_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ControlListPeersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ControlListPeersResponse) ProtoMessage() {}
+
+func (x *ControlListPeersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_control_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ControlListPeersResponse.ProtoReflect.Descriptor instead.
+func (*ControlListPeersResponse) Descriptor() ([]byte, []int) {
+	return file_control_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ControlListPeersResponse) GetPeers() []*ControlPeer {
+	if x != nil {
+		return x.Peers
+	}
+	return nil
+}
+
+// ControlPeerRequest message
+type ControlPeerRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PeerId        string                 `protobuf:"bytes,1,opt,name=peer_id,json=peerId,proto3" json:"peer_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ControlPeerRequest) Reset() {
+	*x = ControlPeerRequest{}
+	mi := &file_control_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ControlPeerRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ControlPeerRequest) ProtoMessage() {}
+
+func (x *ControlPeerRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_control_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ControlPeerRequest.ProtoReflect.Descriptor instead.
+func (*ControlPeerRequest) Descriptor() ([]byte, []int) {
+	return file_control_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ControlPeerRequest) GetPeerId() string {
+	if x != nil {
+		return x.PeerId
+	}
+	return ""
+}
+
+// ControlWatchStatsRequest message
+type ControlWatchStatsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RoomName      string                 `protobuf:"bytes,1,opt,name=room_name,json=roomName,proto3" json:"room_name,omitempty"`        // Empty for all local online rooms
+	IntervalMs    uint32                 `protobuf:"varint,2,opt,name=interval_ms,json=intervalMs,proto3" json:"interval_ms,omitempty"` // Defaults to stats report interval
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ControlWatchStatsRequest) Reset() {
+	*x = ControlWatchStatsRequest{}
+	mi := &file_control_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ControlWatchStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ControlWatchStatsRequest) ProtoMessage() {}
+
+func (x *ControlWatchStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_control_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ControlWatchStatsRequest.ProtoReflect.Descriptor instead.
+func (*ControlWatchStatsRequest) Descriptor() ([]byte, []int) {
+	return file_control_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ControlWatchStatsRequest) GetRoomName() string {
+	if x != nil {
+		return x.RoomName
+	}
+	return ""
+}
+
+func (x *ControlWatchStatsRequest) GetIntervalMs() uint32 {
+	if x != nil {
+		return x.IntervalMs
+	}
+	return 0
+}
+
+var File_control_proto protoreflect.FileDescriptor
+
+const file_control_proto_rawDesc = "" +
+	"\n" +
+	"\rcontrol.proto\x12\x05proto\x1a\vtypes.proto\"\x0e\n" +
+	"\fControlEmpty\"\xa9\x01\n" +
+	"\x12ControlParticipant\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x02 \x01(\tR\tsessionId\x12\x17\n" +
+	"\apeer_id\x18\x03 \x01(\tR\x06peerId\x12$\n" +
+	"\x0equeue_delay_ms\x18\x04 \x01(\rR\fqueueDelayMs\x12%\n" +
+	"\x0edropped_frames\x18\x05 \x01(\x04R\rdroppedFrames\"\xe9\x02\n" +
+	"\vControlRoom\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x19\n" +
+	"\bowner_id\x18\x03 \x01(\tR\aownerId\x124\n" +
+	"\bsettings\x18\x04 \x01(\v2\x18.proto.ProtoRoomSettingsR\bsettings\x12\x18\n" +
+	"\aviewers\x18\x05 \x01(\x05R\aviewers\x12\x16\n" +
+	"\x06online\x18\x06 \x01(\bR\x06online\x12\x1f\n" +
+	"\vupstream_id\x18\a \x01(\tR\n" +
+	"upstreamId\x12$\n" +
+	"\x0ehop_latency_ms\x18\b \x01(\rR\fhopLatencyMs\x12-\n" +
+	"\x05stats\x18\t \x01(\v2\x17.proto.ProtoStreamStatsR\x05stats\x12=\n" +
+	"\fparticipants\x18\n" +
+	" \x03(\v2\x19.proto.ControlParticipantR\fparticipants\"\x19\n" +
+	"\x17ControlListRoomsRequest\"D\n" +
+	"\x18ControlListRoomsResponse\x12(\n" +
+	"\x05rooms\x18\x01 \x03(\v2\x12.proto.ControlRoomR\x05rooms\"1\n" +
+	"\x12ControlRoomRequest\x12\x1b\n" +
+	"\troom_name\x18\x01 \x01(\tR\broomName\"_\n" +
+	"\x19ControlParticipantRequest\x12\x1b\n" +
+	"\troom_name\x18\x01 \x01(\tR\broomName\x12%\n" +
+	"\x0eparticipant_id\x18\x02 \x01(\tR\rparticipantId\"\xbb\x01\n" +
+	"\vControlPeer\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05addrs\x18\x02 \x03(\tR\x05addrs\x12\x1c\n" +
+	"\tconnected\x18\x03 \x01(\bR\tconnected\x12\x1d\n" +
+	"\n" +
+	"latency_ms\x18\x04 \x01(\rR\tlatencyMs\x12$\n" +
+	"\x0elast_seen_unix\x18\x05 \x01(\x03R\flastSeenUnix\x12#\n" +
+	"\rdial_failures\x18\x06 \x01(\x05R\fdialFailures\"\x19\n" +
+	"\x17ControlListPeersRequest\"D\n" +
+	"\x18ControlListPeersResponse\x12(\n" +
+	"\x05peers\x18\x01 \x03(\v2\x12.proto.ControlPeerR\x05peers\"-\n" +
+	"\x12ControlPeerRequest\x12\x17\n" +
+	"\apeer_id\x18\x01 \x01(\tR\x06peerId\"X\n" +
+	"\x18ControlWatchStatsRequest\x12\x1b\n" +
+	"\troom_name\x18\x01 \x01(\tR\broomName\x12\x1f\n" +
+	"\vinterval_ms\x18\x02 \x01(\rR\n" +
+	"intervalMs2\xf7\x03\n" +
+	"\fRelayControl\x12L\n" +
+	"\tListRooms\x12\x1e.proto.ControlListRoomsRequest\x1a\x1f.proto.ControlListRoomsResponse\x128\n" +
+	"\aGetRoom\x12\x19.proto.ControlRoomRequest\x1a\x12.proto.ControlRoom\x12;\n" +
+	"\tCloseRoom\x12\x19.proto.ControlRoomRequest\x1a\x13.proto.ControlEmpty\x12H\n" +
+	"\x0fKickParticipant\x12 .proto.ControlParticipantRequest\x1a\x13.proto.ControlEmpty\x12L\n" +
+	"\tListPeers\x12\x1e.proto.ControlListPeersRequest\x1a\x1f.proto.ControlListPeersResponse\x12@\n" +
+	"\x0eDisconnectPeer\x12\x19.proto.ControlPeerRequest\x1a\x13.proto.ControlEmpty\x12H\n" +
+	"\n" +
+	"WatchStats\x12\x1f.proto.ControlWatchStatsRequest\x1a\x17.proto.ProtoStreamStats0\x01B\x16Z\x14relay/internal/protob\x06proto3"
+
+var (
+	file_control_proto_rawDescOnce sync.Once
+	file_control_proto_rawDescData []byte
+)
+
+func file_control_proto_rawDescGZIP() []byte {
+	file_control_proto_rawDescOnce.Do(func() {
+		file_control_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_control_proto_rawDesc), len(file_control_proto_rawDesc)))
+	})
+	return file_control_proto_rawDescData
+}
+
+var file_control_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
+var file_control_proto_goTypes = []any{
+	(*ControlEmpty)(nil),              // 0: proto.ControlEmpty
+	(*ControlParticipant)(nil),        // 1: proto.ControlParticipant
+	(*ControlRoom)(nil),               // 2: proto.ControlRoom
+	(*ControlListRoomsRequest)(nil),   // 3: proto.ControlListRoomsRequest
+	(*ControlListRoomsResponse)(nil),  // 4: proto.ControlListRoomsResponse
+	(*ControlRoomRequest)(nil),        // 5: proto.ControlRoomRequest
+	(*ControlParticipantRequest)(nil), // 6: proto.ControlParticipantRequest
+	(*ControlPeer)(nil),               // 7: proto.ControlPeer
+	(*ControlListPeersRequest)(nil),   // 8: proto.ControlListPeersRequest
+	(*ControlListPeersResponse)(nil),  // 9: proto.ControlListPeersResponse
+	(*ControlPeerRequest)(nil),        // 10: proto.ControlPeerRequest
+	(*ControlWatchStatsRequest)(nil),  // 11: proto.ControlWatchStatsRequest
+	(*ProtoRoomSettings)(nil),         // 12: proto.ProtoRoomSettings
+	(*ProtoStreamStats)(nil),          // 13: proto.ProtoStreamStats
+}
+var file_control_proto_depIdxs = []int32{
+	12, // 0: proto.ControlRoom.settings:type_name -> proto.ProtoRoomSettings
+	13, // 1: proto.ControlRoom.stats:type_name -> proto.ProtoStreamStats
+	1,  // 2: proto.ControlRoom.participants:type_name -> proto.ControlParticipant
+	2,  // 3: proto.ControlListRoomsResponse.rooms:type_name -> proto.ControlRoom
+	7,  // 4: proto.ControlListPeersResponse.peers:type_name -> proto.ControlPeer
+	3,  // 5: proto.RelayControl.ListRooms:input_type -> proto.ControlListRoomsRequest
+	5,  // 6: proto.RelayControl.GetRoom:input_type -> proto.ControlRoomRequest
+	5,  // 7: proto.RelayControl.CloseRoom:input_type -> proto.ControlRoomRequest
+	6,  // 8: proto.RelayControl.KickParticipant:input_type -> proto.ControlParticipantRequest
+	8,  // 9: proto.RelayControl.ListPeers:input_type -> proto.ControlListPeersRequest
+	10, // 10: proto.RelayControl.DisconnectPeer:input_type -> proto.ControlPeerRequest
+	11, // 11: proto.RelayControl.WatchStats:input_type -> proto.ControlWatchStatsRequest
+	4,  // 12: proto.RelayControl.ListRooms:output_type -> proto.ControlListRoomsResponse
+	2,  // 13: proto.RelayControl.GetRoom:output_type -> proto.ControlRoom
+	0,  // 14: proto.RelayControl.CloseRoom:output_type -> proto.ControlEmpty
+	0,  // 15: proto.RelayControl.KickParticipant:output_type -> proto.ControlEmpty
+	9,  // 16: proto.RelayControl.ListPeers:output_type -> proto.ControlListPeersResponse
+	0,  // 17: proto.RelayControl.DisconnectPeer:output_type -> proto.ControlEmpty
+	13, // 18: proto.RelayControl.WatchStats:output_type -> proto.ProtoStreamStats
+	12, // [12:19] is the sub-list for method output_type
+	5,  // [5:12] is the sub-list for method input_type
+	5,  // [5:5] is the sub-list for extension type_name
+	5,  // [5:5] is the sub-list for extension extendee
+	0,  // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_control_proto_init() }
+func file_control_proto_init() {
+	if File_control_proto != nil {
+		return
+	}
+	file_types_proto_init()
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_control_proto_rawDesc), len(file_control_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   12,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_control_proto_goTypes,
+		DependencyIndexes: file_control_proto_depIdxs,
+		MessageInfos:      file_control_proto_msgTypes,
+	}.Build()
+	File_control_proto = out.File
+	file_control_proto_goTypes = nil
+	file_control_proto_depIdxs = nil
+}
@@ -0,0 +1,365 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: control.proto
+
+package proto
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	RelayControl_ListRooms_FullMethodName       = "/proto.RelayControl/ListRooms"
+	RelayControl_GetRoom_FullMethodName         = "/proto.RelayControl/GetRoom"
+	RelayControl_CloseRoom_FullMethodName       = "/proto.RelayControl/CloseRoom"
+	RelayControl_KickParticipant_FullMethodName = "/proto.RelayControl/KickParticipant"
+	RelayControl_ListPeers_FullMethodName       = "/proto.RelayControl/ListPeers"
+	RelayControl_DisconnectPeer_FullMethodName  = "/proto.RelayControl/DisconnectPeer"
+	RelayControl_WatchStats_FullMethodName      = "/proto.RelayControl/WatchStats"
+)
+
+// RelayControlClient is the client API for RelayControl service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// RelayControl is the programmatic relay control service for the control plane
+type RelayControlClient interface {
+	// Rooms
+	ListRooms(ctx context.Context, in *ControlListRoomsRequest, opts ...grpc.CallOption) (*ControlListRoomsResponse, error)
+	GetRoom(ctx context.Context, in *ControlRoomRequest, opts ...grpc.CallOption) (*ControlRoom, error)
+	CloseRoom(ctx context.Context, in *ControlRoomRequest, opts ...grpc.CallOption) (*ControlEmpty, error)
+	// Participants
+	KickParticipant(ctx context.Context, in *ControlParticipantRequest, opts ...grpc.CallOption) (*ControlEmpty, error)
+	// Mesh peers
+	ListPeers(ctx context.Context, in *ControlListPeersRequest, opts ...grpc.CallOption) (*ControlListPeersResponse, error)
+	DisconnectPeer(ctx context.Context, in *ControlPeerRequest, opts ...grpc.CallOption) (*ControlEmpty, error)
+	// Live stats, streamed at interval until cancelled
+	WatchStats(ctx context.Context, in *ControlWatchStatsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ProtoStreamStats], error)
+}
+
+type relayControlClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRelayControlClient(cc grpc.ClientConnInterface) RelayControlClient {
+	return &relayControlClient{cc}
+}
+
+func (c *relayControlClient) ListRooms(ctx context.Context, in *ControlListRoomsRequest, opts ...grpc.CallOption) (*ControlListRoomsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ControlListRoomsResponse)
+	err := c.cc.Invoke(ctx, RelayControl_ListRooms_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *relayControlClient) GetRoom(ctx context.Context, in *ControlRoomRequest, opts ...grpc.CallOption) (*ControlRoom, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ControlRoom)
+	err := c.cc.Invoke(ctx, RelayControl_GetRoom_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *relayControlClient) CloseRoom(ctx context.Context, in *ControlRoomRequest, opts ...grpc.CallOption) (*ControlEmpty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ControlEmpty)
+	err := c.cc.Invoke(ctx, RelayControl_CloseRoom_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *relayControlClient) KickParticipant(ctx context.Context, in *ControlParticipantRequest, opts ...grpc.CallOption) (*ControlEmpty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ControlEmpty)
+	err := c.cc.Invoke(ctx, RelayControl_KickParticipant_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *relayControlClient) ListPeers(ctx context.Context, in *ControlListPeersRequest, opts ...grpc.CallOption) (*ControlListPeersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ControlListPeersResponse)
+	err := c.cc.Invoke(ctx, RelayControl_ListPeers_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *relayControlClient) DisconnectPeer(ctx context.Context, in *ControlPeerRequest, opts ...grpc.CallOption) (*ControlEmpty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ControlEmpty)
+	err := c.cc.Invoke(ctx, RelayControl_DisconnectPeer_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *relayControlClient) WatchStats(ctx context.Context, in *ControlWatchStatsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ProtoStreamStats], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &RelayControl_ServiceDesc.Streams[0], RelayControl_WatchStats_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ControlWatchStatsRequest, ProtoStreamStats]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RelayControl_WatchStatsClient = grpc.ServerStreamingClient[ProtoStreamStats]
+
+// RelayControlServer is the server API for RelayControl service.
+// All implementations must embed UnimplementedRelayControlServer
+// for forward compatibility.
+//
+// RelayControl is the programmatic relay control service for the control plane
+type RelayControlServer interface {
+	// Rooms
+	ListRooms(context.Context, *ControlListRoomsRequest) (*ControlListRoomsResponse, error)
+	GetRoom(context.Context, *ControlRoomRequest) (*ControlRoom, error)
+	CloseRoom(context.Context, *ControlRoomRequest) (*ControlEmpty, error)
+	// Participants
+	KickParticipant(context.Context, *ControlParticipantRequest) (*ControlEmpty, error)
+	// Mesh peers
+	ListPeers(context.Context, *ControlListPeersRequest) (*ControlListPeersResponse, error)
+	DisconnectPeer(context.Context, *ControlPeerRequest) (*ControlEmpty, error)
+	// Live stats, streamed at interval until cancelled
+	WatchStats(*ControlWatchStatsRequest, grpc.ServerStreamingServer[ProtoStreamStats]) error
+	mustEmbedUnimplementedRelayControlServer()
+}
+
+// UnimplementedRelayControlServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedRelayControlServer struct{}
+
+func (UnimplementedRelayControlServer) ListRooms(context.Context, *ControlListRoomsRequest) (*ControlListRoomsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRooms not implemented")
+}
+func (UnimplementedRelayControlServer) GetRoom(context.Context, *ControlRoomRequest) (*ControlRoom, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRoom not implemented")
+}
+func (UnimplementedRelayControlServer) CloseRoom(context.Context, *ControlRoomRequest) (*ControlEmpty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CloseRoom not implemented")
+}
+func (UnimplementedRelayControlServer) KickParticipant(context.Context, *ControlParticipantRequest) (*ControlEmpty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method KickParticipant not implemented")
+}
+func (UnimplementedRelayControlServer) ListPeers(context.Context, *ControlListPeersRequest) (*ControlListPeersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPeers not implemented")
+}
+func (UnimplementedRelayControlServer) DisconnectPeer(context.Context, *ControlPeerRequest) (*ControlEmpty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DisconnectPeer not implemented")
+}
+func (UnimplementedRelayControlServer) WatchStats(*ControlWatchStatsRequest, grpc.ServerStreamingServer[ProtoStreamStats]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchStats not implemented")
+}
+func (UnimplementedRelayControlServer) mustEmbedUnimplementedRelayControlServer() {}
+func (UnimplementedRelayControlServer) testEmbeddedByValue()                      {}
+
+// UnsafeRelayControlServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RelayControlServer will
+// result in compilation errors.
+type UnsafeRelayControlServer interface {
+	mustEmbedUnimplementedRelayControlServer()
+}
+
+func RegisterRelayControlServer(s grpc.ServiceRegistrar, srv RelayControlServer) {
+	// If the following call pancis, it indicates UnimplementedRelayControlServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&RelayControl_ServiceDesc, srv)
+}
+
+func _RelayControl_ListRooms_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ControlListRoomsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RelayControlServer).ListRooms(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RelayControl_ListRooms_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RelayControlServer).ListRooms(ctx, req.(*ControlListRoomsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RelayControl_GetRoom_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ControlRoomRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RelayControlServer).GetRoom(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RelayControl_GetRoom_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RelayControlServer).GetRoom(ctx, req.(*ControlRoomRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RelayControl_CloseRoom_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ControlRoomRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RelayControlServer).CloseRoom(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RelayControl_CloseRoom_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RelayControlServer).CloseRoom(ctx, req.(*ControlRoomRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RelayControl_KickParticipant_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ControlParticipantRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RelayControlServer).KickParticipant(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RelayControl_KickParticipant_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RelayControlServer).KickParticipant(ctx, req.(*ControlParticipantRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RelayControl_ListPeers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ControlListPeersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RelayControlServer).ListPeers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RelayControl_ListPeers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RelayControlServer).ListPeers(ctx, req.(*ControlListPeersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RelayControl_DisconnectPeer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ControlPeerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RelayControlServer).DisconnectPeer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RelayControl_DisconnectPeer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RelayControlServer).DisconnectPeer(ctx, req.(*ControlPeerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RelayControl_WatchStats_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ControlWatchStatsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RelayControlServer).WatchStats(m, &grpc.GenericServerStream[ControlWatchStatsRequest, ProtoStreamStats]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RelayControl_WatchStatsServer = grpc.ServerStreamingServer[ProtoStreamStats]
+
+// RelayControl_ServiceDesc is the grpc.ServiceDesc for RelayControl service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RelayControl_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "proto.RelayControl",
+	HandlerType: (*RelayControlServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListRooms",
+			Handler:    _RelayControl_ListRooms_Handler,
+		},
+		{
+			MethodName: "GetRoom",
+			Handler:    _RelayControl_GetRoom_Handler,
+		},
+		{
+			MethodName: "CloseRoom",
+			Handler:    _RelayControl_CloseRoom_Handler,
+		},
+		{
+			MethodName: "KickParticipant",
+			Handler:    _RelayControl_KickParticipant_Handler,
+		},
+		{
+			MethodName: "ListPeers",
+			Handler:    _RelayControl_ListPeers_Handler,
+		},
+		{
+			MethodName: "DisconnectPeer",
+			Handler:    _RelayControl_DisconnectPeer_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchStats",
+			Handler:       _RelayControl_WatchStats_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "control.proto",
+}
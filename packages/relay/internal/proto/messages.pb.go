@@ -22,11 +22,15 @@ const (
 )
 
 type ProtoMessageBase struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	PayloadType   string                 `protobuf:"bytes,1,opt,name=payload_type,json=payloadType,proto3" json:"payload_type,omitempty"`
-	Latency       *ProtoLatencyTracker   `protobuf:"bytes,2,opt,name=latency,proto3" json:"latency,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	PayloadType     string                 `protobuf:"bytes,1,opt,name=payload_type,json=payloadType,proto3" json:"payload_type,omitempty"`
+	Latency         *ProtoLatencyTracker   `protobuf:"bytes,2,opt,name=latency,proto3" json:"latency,omitempty"`
+	ProtocolVersion uint32                 `protobuf:"varint,3,opt,name=protocol_version,json=protocolVersion,proto3" json:"protocol_version,omitempty"` // Message protocol version of the sender, 0 if not announced
+	Sequence        uint64                 `protobuf:"varint,4,opt,name=sequence,proto3" json:"sequence,omitempty"`                                      // Increases with each message the sender writes to a stream, starting at 1, 0 if not sequenced
+	StreamNonce     uint64                 `protobuf:"varint,5,opt,name=stream_nonce,json=streamNonce,proto3" json:"stream_nonce,omitempty"`             // Random per stream of the sender, sequenced messages carrying another nonce were replayed
+	Capabilities    []string               `protobuf:"bytes,6,rep,name=capabilities,proto3" json:"capabilities,omitempty"`                               // Optional features the sender supports, announced with its first message on a stream
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
 func (x *ProtoMessageBase) Reset() {
@@ -73,6 +77,34 @@ func (x *ProtoMessageBase) GetLatency() *ProtoLatencyTracker {
 	return nil
 }
 
+func (x *ProtoMessageBase) GetProtocolVersion() uint32 {
+	if x != nil {
+		return x.ProtocolVersion
+	}
+	return 0
+}
+
+func (x *ProtoMessageBase) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *ProtoMessageBase) GetStreamNonce() uint64 {
+	if x != nil {
+		return x.StreamNonce
+	}
+	return 0
+}
+
+func (x *ProtoMessageBase) GetCapabilities() []string {
+	if x != nil {
+		return x.Capabilities
+	}
+	return nil
+}
+
 type ProtoMessage struct {
 	state       protoimpl.MessageState `protogen:"open.v1"`
 	MessageBase *ProtoMessageBase      `protobuf:"bytes,1,opt,name=message_base,json=messageBase,proto3" json:"message_base,omitempty"`
@@ -95,6 +127,30 @@ type ProtoMessage struct {
 	//	*ProtoMessage_ClientRequestRoomStream
 	//	*ProtoMessage_ClientDisconnected
 	//	*ProtoMessage_ServerPushStream
+	//	*ProtoMessage_DirectoryQuery
+	//	*ProtoMessage_DirectoryResult
+	//	*ProtoMessage_StreamPathInfo
+	//	*ProtoMessage_StreamStats
+	//	*ProtoMessage_RelayNotice
+	//	*ProtoMessage_SignalingProgress
+	//	*ProtoMessage_MeshRoomTracks
+	//	*ProtoMessage_RoomFull
+	//	*ProtoMessage_Moderation
+	//	*ProtoMessage_Chat
+	//	*ProtoMessage_RoomMetadata
+	//	*ProtoMessage_RoomVariants
+	//	*ProtoMessage_VariantSwitch
+	//	*ProtoMessage_ViewerCount
+	//	*ProtoMessage_Throttled
+	//	*ProtoMessage_QuotaExceeded
+	//	*ProtoMessage_RelayOverload
+	//	*ProtoMessage_InvalidMessage
+	//	*ProtoMessage_PushChallenge
+	//	*ProtoMessage_PushChallengeResponse
+	//	*ProtoMessage_AudioLevel
+	//	*ProtoMessage_BetterRelay
+	//	*ProtoMessage_StreamClock
+	//	*ProtoMessage_InputLatency
 	Payload       isProtoMessage_Payload `protobuf_oneof:"payload"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
@@ -297,6 +353,222 @@ func (x *ProtoMessage) GetServerPushStream() *ProtoServerPushStream {
 	return nil
 }
 
+func (x *ProtoMessage) GetDirectoryQuery() *ProtoDirectoryQuery {
+	if x != nil {
+		if x, ok := x.Payload.(*ProtoMessage_DirectoryQuery); ok {
+			return x.DirectoryQuery
+		}
+	}
+	return nil
+}
+
+func (x *ProtoMessage) GetDirectoryResult() *ProtoDirectoryResult {
+	if x != nil {
+		if x, ok := x.Payload.(*ProtoMessage_DirectoryResult); ok {
+			return x.DirectoryResult
+		}
+	}
+	return nil
+}
+
+func (x *ProtoMessage) GetStreamPathInfo() *ProtoStreamPathInfo {
+	if x != nil {
+		if x, ok := x.Payload.(*ProtoMessage_StreamPathInfo); ok {
+			return x.StreamPathInfo
+		}
+	}
+	return nil
+}
+
+func (x *ProtoMessage) GetStreamStats() *ProtoStreamStats {
+	if x != nil {
+		if x, ok := x.Payload.(*ProtoMessage_StreamStats); ok {
+			return x.StreamStats
+		}
+	}
+	return nil
+}
+
+func (x *ProtoMessage) GetRelayNotice() *ProtoRelayNotice {
+	if x != nil {
+		if x, ok := x.Payload.(*ProtoMessage_RelayNotice); ok {
+			return x.RelayNotice
+		}
+	}
+	return nil
+}
+
+func (x *ProtoMessage) GetSignalingProgress() *ProtoSignalingProgress {
+	if x != nil {
+		if x, ok := x.Payload.(*ProtoMessage_SignalingProgress); ok {
+			return x.SignalingProgress
+		}
+	}
+	return nil
+}
+
+func (x *ProtoMessage) GetMeshRoomTracks() *ProtoMeshRoomTracks {
+	if x != nil {
+		if x, ok := x.Payload.(*ProtoMessage_MeshRoomTracks); ok {
+			return x.MeshRoomTracks
+		}
+	}
+	return nil
+}
+
+func (x *ProtoMessage) GetRoomFull() *ProtoRoomFull {
+	if x != nil {
+		if x, ok := x.Payload.(*ProtoMessage_RoomFull); ok {
+			return x.RoomFull
+		}
+	}
+	return nil
+}
+
+func (x *ProtoMessage) GetModeration() *ProtoModeration {
+	if x != nil {
+		if x, ok := x.Payload.(*ProtoMessage_Moderation); ok {
+			return x.Moderation
+		}
+	}
+	return nil
+}
+
+func (x *ProtoMessage) GetChat() *ProtoChatMessage {
+	if x != nil {
+		if x, ok := x.Payload.(*ProtoMessage_Chat); ok {
+			return x.Chat
+		}
+	}
+	return nil
+}
+
+func (x *ProtoMessage) GetRoomMetadata() *ProtoRoomMetadata {
+	if x != nil {
+		if x, ok := x.Payload.(*ProtoMessage_RoomMetadata); ok {
+			return x.RoomMetadata
+		}
+	}
+	return nil
+}
+
+func (x *ProtoMessage) GetRoomVariants() *ProtoRoomVariants {
+	if x != nil {
+		if x, ok := x.Payload.(*ProtoMessage_RoomVariants); ok {
+			return x.RoomVariants
+		}
+	}
+	return nil
+}
+
+func (x *ProtoMessage) GetVariantSwitch() *ProtoVariantSwitch {
+	if x != nil {
+		if x, ok := x.Payload.(*ProtoMessage_VariantSwitch); ok {
+			return x.VariantSwitch
+		}
+	}
+	return nil
+}
+
+func (x *ProtoMessage) GetViewerCount() *ProtoViewerCount {
+	if x != nil {
+		if x, ok := x.Payload.(*ProtoMessage_ViewerCount); ok {
+			return x.ViewerCount
+		}
+	}
+	return nil
+}
+
+func (x *ProtoMessage) GetThrottled() *ProtoThrottled {
+	if x != nil {
+		if x, ok := x.Payload.(*ProtoMessage_Throttled); ok {
+			return x.Throttled
+		}
+	}
+	return nil
+}
+
+func (x *ProtoMessage) GetQuotaExceeded() *ProtoQuotaExceeded {
+	if x != nil {
+		if x, ok := x.Payload.(*ProtoMessage_QuotaExceeded); ok {
+			return x.QuotaExceeded
+		}
+	}
+	return nil
+}
+
+func (x *ProtoMessage) GetRelayOverload() *ProtoRelayOverload {
+	if x != nil {
+		if x, ok := x.Payload.(*ProtoMessage_RelayOverload); ok {
+			return x.RelayOverload
+		}
+	}
+	return nil
+}
+
+func (x *ProtoMessage) GetInvalidMessage() *ProtoInvalidMessage {
+	if x != nil {
+		if x, ok := x.Payload.(*ProtoMessage_InvalidMessage); ok {
+			return x.InvalidMessage
+		}
+	}
+	return nil
+}
+
+func (x *ProtoMessage) GetPushChallenge() *ProtoPushChallenge {
+	if x != nil {
+		if x, ok := x.Payload.(*ProtoMessage_PushChallenge); ok {
+			return x.PushChallenge
+		}
+	}
+	return nil
+}
+
+func (x *ProtoMessage) GetPushChallengeResponse() *ProtoPushChallengeResponse {
+	if x != nil {
+		if x, ok := x.Payload.(*ProtoMessage_PushChallengeResponse); ok {
+			return x.PushChallengeResponse
+		}
+	}
+	return nil
+}
+
+func (x *ProtoMessage) GetAudioLevel() *ProtoAudioLevel {
+	if x != nil {
+		if x, ok := x.Payload.(*ProtoMessage_AudioLevel); ok {
+			return x.AudioLevel
+		}
+	}
+	return nil
+}
+
+func (x *ProtoMessage) GetBetterRelay() *ProtoBetterRelay {
+	if x != nil {
+		if x, ok := x.Payload.(*ProtoMessage_BetterRelay); ok {
+			return x.BetterRelay
+		}
+	}
+	return nil
+}
+
+func (x *ProtoMessage) GetStreamClock() *ProtoStreamClock {
+	if x != nil {
+		if x, ok := x.Payload.(*ProtoMessage_StreamClock); ok {
+			return x.StreamClock
+		}
+	}
+	return nil
+}
+
+func (x *ProtoMessage) GetInputLatency() *ProtoInputLatency {
+	if x != nil {
+		if x, ok := x.Payload.(*ProtoMessage_InputLatency); ok {
+			return x.InputLatency
+		}
+	}
+	return nil
+}
+
 type isProtoMessage_Payload interface {
 	isProtoMessage_Payload()
 }
@@ -372,6 +644,123 @@ type ProtoMessage_ServerPushStream struct {
 	ServerPushStream *ProtoServerPushStream `protobuf:"bytes,25,opt,name=server_push_stream,json=serverPushStream,proto3,oneof"`
 }
 
+type ProtoMessage_DirectoryQuery struct {
+	// Directory types
+	DirectoryQuery *ProtoDirectoryQuery `protobuf:"bytes,26,opt,name=directory_query,json=directoryQuery,proto3,oneof"`
+}
+
+type ProtoMessage_DirectoryResult struct {
+	DirectoryResult *ProtoDirectoryResult `protobuf:"bytes,27,opt,name=directory_result,json=directoryResult,proto3,oneof"`
+}
+
+type ProtoMessage_StreamPathInfo struct {
+	// Mesh path types
+	StreamPathInfo *ProtoStreamPathInfo `protobuf:"bytes,28,opt,name=stream_path_info,json=streamPathInfo,proto3,oneof"`
+}
+
+type ProtoMessage_StreamStats struct {
+	// Stats types
+	StreamStats *ProtoStreamStats `protobuf:"bytes,29,opt,name=stream_stats,json=streamStats,proto3,oneof"`
+}
+
+type ProtoMessage_RelayNotice struct {
+	// Relay notices
+	RelayNotice *ProtoRelayNotice `protobuf:"bytes,30,opt,name=relay_notice,json=relayNotice,proto3,oneof"`
+}
+
+type ProtoMessage_SignalingProgress struct {
+	// Signaling progress
+	SignalingProgress *ProtoSignalingProgress `protobuf:"bytes,31,opt,name=signaling_progress,json=signalingProgress,proto3,oneof"`
+}
+
+type ProtoMessage_MeshRoomTracks struct {
+	// Mesh link types
+	MeshRoomTracks *ProtoMeshRoomTracks `protobuf:"bytes,32,opt,name=mesh_room_tracks,json=meshRoomTracks,proto3,oneof"`
+}
+
+type ProtoMessage_RoomFull struct {
+	// Room admission
+	RoomFull *ProtoRoomFull `protobuf:"bytes,33,opt,name=room_full,json=roomFull,proto3,oneof"`
+}
+
+type ProtoMessage_Moderation struct {
+	// Moderation
+	Moderation *ProtoModeration `protobuf:"bytes,34,opt,name=moderation,proto3,oneof"`
+}
+
+type ProtoMessage_Chat struct {
+	// Room chat
+	Chat *ProtoChatMessage `protobuf:"bytes,35,opt,name=chat,proto3,oneof"`
+}
+
+type ProtoMessage_RoomMetadata struct {
+	// Room metadata updates
+	RoomMetadata *ProtoRoomMetadata `protobuf:"bytes,36,opt,name=room_metadata,json=roomMetadata,proto3,oneof"`
+}
+
+type ProtoMessage_RoomVariants struct {
+	// Quality variants
+	RoomVariants *ProtoRoomVariants `protobuf:"bytes,37,opt,name=room_variants,json=roomVariants,proto3,oneof"`
+}
+
+type ProtoMessage_VariantSwitch struct {
+	VariantSwitch *ProtoVariantSwitch `protobuf:"bytes,38,opt,name=variant_switch,json=variantSwitch,proto3,oneof"`
+}
+
+type ProtoMessage_ViewerCount struct {
+	// Viewer counts
+	ViewerCount *ProtoViewerCount `protobuf:"bytes,39,opt,name=viewer_count,json=viewerCount,proto3,oneof"`
+}
+
+type ProtoMessage_Throttled struct {
+	// Rate limiting
+	Throttled *ProtoThrottled `protobuf:"bytes,40,opt,name=throttled,proto3,oneof"`
+}
+
+type ProtoMessage_QuotaExceeded struct {
+	// Bandwidth quotas
+	QuotaExceeded *ProtoQuotaExceeded `protobuf:"bytes,41,opt,name=quota_exceeded,json=quotaExceeded,proto3,oneof"`
+}
+
+type ProtoMessage_RelayOverload struct {
+	// Backpressure
+	RelayOverload *ProtoRelayOverload `protobuf:"bytes,42,opt,name=relay_overload,json=relayOverload,proto3,oneof"`
+}
+
+type ProtoMessage_InvalidMessage struct {
+	// Validation
+	InvalidMessage *ProtoInvalidMessage `protobuf:"bytes,43,opt,name=invalid_message,json=invalidMessage,proto3,oneof"`
+}
+
+type ProtoMessage_PushChallenge struct {
+	// Push authentication
+	PushChallenge *ProtoPushChallenge `protobuf:"bytes,44,opt,name=push_challenge,json=pushChallenge,proto3,oneof"`
+}
+
+type ProtoMessage_PushChallengeResponse struct {
+	PushChallengeResponse *ProtoPushChallengeResponse `protobuf:"bytes,45,opt,name=push_challenge_response,json=pushChallengeResponse,proto3,oneof"`
+}
+
+type ProtoMessage_AudioLevel struct {
+	// Audio levels
+	AudioLevel *ProtoAudioLevel `protobuf:"bytes,46,opt,name=audio_level,json=audioLevel,proto3,oneof"`
+}
+
+type ProtoMessage_BetterRelay struct {
+	// Viewer steering
+	BetterRelay *ProtoBetterRelay `protobuf:"bytes,47,opt,name=better_relay,json=betterRelay,proto3,oneof"`
+}
+
+type ProtoMessage_StreamClock struct {
+	// Playback clock
+	StreamClock *ProtoStreamClock `protobuf:"bytes,48,opt,name=stream_clock,json=streamClock,proto3,oneof"`
+}
+
+type ProtoMessage_InputLatency struct {
+	// Input latency
+	InputLatency *ProtoInputLatency `protobuf:"bytes,49,opt,name=input_latency,json=inputLatency,proto3,oneof"`
+}
+
 func (*ProtoMessage_MouseMove) isProtoMessage_Payload() {}
 
 func (*ProtoMessage_MouseMoveAbs) isProtoMessage_Payload() {}
@@ -406,14 +795,66 @@ func (*ProtoMessage_ClientDisconnected) isProtoMessage_Payload() {}
 
 func (*ProtoMessage_ServerPushStream) isProtoMessage_Payload() {}
 
+func (*ProtoMessage_DirectoryQuery) isProtoMessage_Payload() {}
+
+func (*ProtoMessage_DirectoryResult) isProtoMessage_Payload() {}
+
+func (*ProtoMessage_StreamPathInfo) isProtoMessage_Payload() {}
+
+func (*ProtoMessage_StreamStats) isProtoMessage_Payload() {}
+
+func (*ProtoMessage_RelayNotice) isProtoMessage_Payload() {}
+
+func (*ProtoMessage_SignalingProgress) isProtoMessage_Payload() {}
+
+func (*ProtoMessage_MeshRoomTracks) isProtoMessage_Payload() {}
+
+func (*ProtoMessage_RoomFull) isProtoMessage_Payload() {}
+
+func (*ProtoMessage_Moderation) isProtoMessage_Payload() {}
+
+func (*ProtoMessage_Chat) isProtoMessage_Payload() {}
+
+func (*ProtoMessage_RoomMetadata) isProtoMessage_Payload() {}
+
+func (*ProtoMessage_RoomVariants) isProtoMessage_Payload() {}
+
+func (*ProtoMessage_VariantSwitch) isProtoMessage_Payload() {}
+
+func (*ProtoMessage_ViewerCount) isProtoMessage_Payload() {}
+
+func (*ProtoMessage_Throttled) isProtoMessage_Payload() {}
+
+func (*ProtoMessage_QuotaExceeded) isProtoMessage_Payload() {}
+
+func (*ProtoMessage_RelayOverload) isProtoMessage_Payload() {}
+
+func (*ProtoMessage_InvalidMessage) isProtoMessage_Payload() {}
+
+func (*ProtoMessage_PushChallenge) isProtoMessage_Payload() {}
+
+func (*ProtoMessage_PushChallengeResponse) isProtoMessage_Payload() {}
+
+func (*ProtoMessage_AudioLevel) isProtoMessage_Payload() {}
+
+func (*ProtoMessage_BetterRelay) isProtoMessage_Payload() {}
+
+func (*ProtoMessage_StreamClock) isProtoMessage_Payload() {}
+
+func (*ProtoMessage_InputLatency) isProtoMessage_Payload() {}
+
 var File_messages_proto protoreflect.FileDescriptor
 
 const file_messages_proto_rawDesc = "" +
 	"\n" +
-	"\x0emessages.proto\x12\x05proto\x1a\vtypes.proto\x1a\x15latency_tracker.proto\"k\n" +
+	"\x0emessages.proto\x12\x05proto\x1a\vtypes.proto\x1a\x15latency_tracker.proto\"\xf9\x01\n" +
 	"\x10ProtoMessageBase\x12!\n" +
 	"\fpayload_type\x18\x01 \x01(\tR\vpayloadType\x124\n" +
-	"\alatency\x18\x02 \x01(\v2\x1a.proto.ProtoLatencyTrackerR\alatency\"\x9b\t\n" +
+	"\alatency\x18\x02 \x01(\v2\x1a.proto.ProtoLatencyTrackerR\alatency\x12)\n" +
+	"\x10protocol_version\x18\x03 \x01(\rR\x0fprotocolVersion\x12\x1a\n" +
+	"\bsequence\x18\x04 \x01(\x04R\bsequence\x12!\n" +
+	"\fstream_nonce\x18\x05 \x01(\x04R\vstreamNonce\x12\"\n" +
+	"\fcapabilities\x18\x06 \x03(\tR\fcapabilities\"\xc9\x15\n" +
 	"\fProtoMessage\x12:\n" +
 	"\fmessage_base\x18\x01 \x01(\v2\x17.proto.ProtoMessageBaseR\vmessageBase\x126\n" +
 	"\n" +
@@ -436,7 +877,34 @@ const file_messages_proto_rawDesc = "" +
 	"\x03raw\x18\x16 \x01(\v2\x0f.proto.ProtoRawH\x00R\x03raw\x12b\n" +
 	"\x1aclient_request_room_stream\x18\x17 \x01(\v2#.proto.ProtoClientRequestRoomStreamH\x00R\x17clientRequestRoomStream\x12Q\n" +
 	"\x13client_disconnected\x18\x18 \x01(\v2\x1e.proto.ProtoClientDisconnectedH\x00R\x12clientDisconnected\x12L\n" +
-	"\x12server_push_stream\x18\x19 \x01(\v2\x1c.proto.ProtoServerPushStreamH\x00R\x10serverPushStreamB\t\n" +
+	"\x12server_push_stream\x18\x19 \x01(\v2\x1c.proto.ProtoServerPushStreamH\x00R\x10serverPushStream\x12E\n" +
+	"\x0fdirectory_query\x18\x1a \x01(\v2\x1a.proto.ProtoDirectoryQueryH\x00R\x0edirectoryQuery\x12H\n" +
+	"\x10directory_result\x18\x1b \x01(\v2\x1b.proto.ProtoDirectoryResultH\x00R\x0fdirectoryResult\x12F\n" +
+	"\x10stream_path_info\x18\x1c \x01(\v2\x1a.proto.ProtoStreamPathInfoH\x00R\x0estreamPathInfo\x12<\n" +
+	"\fstream_stats\x18\x1d \x01(\v2\x17.proto.ProtoStreamStatsH\x00R\vstreamStats\x12<\n" +
+	"\frelay_notice\x18\x1e \x01(\v2\x17.proto.ProtoRelayNoticeH\x00R\vrelayNotice\x12N\n" +
+	"\x12signaling_progress\x18\x1f \x01(\v2\x1d.proto.ProtoSignalingProgressH\x00R\x11signalingProgress\x12F\n" +
+	"\x10mesh_room_tracks\x18  \x01(\v2\x1a.proto.ProtoMeshRoomTracksH\x00R\x0emeshRoomTracks\x123\n" +
+	"\troom_full\x18! \x01(\v2\x14.proto.ProtoRoomFullH\x00R\broomFull\x128\n" +
+	"\n" +
+	"moderation\x18\" \x01(\v2\x16.proto.ProtoModerationH\x00R\n" +
+	"moderation\x12-\n" +
+	"\x04chat\x18# \x01(\v2\x17.proto.ProtoChatMessageH\x00R\x04chat\x12?\n" +
+	"\rroom_metadata\x18$ \x01(\v2\x18.proto.ProtoRoomMetadataH\x00R\froomMetadata\x12?\n" +
+	"\rroom_variants\x18% \x01(\v2\x18.proto.ProtoRoomVariantsH\x00R\froomVariants\x12B\n" +
+	"\x0evariant_switch\x18& \x01(\v2\x19.proto.ProtoVariantSwitchH\x00R\rvariantSwitch\x12<\n" +
+	"\fviewer_count\x18' \x01(\v2\x17.proto.ProtoViewerCountH\x00R\vviewerCount\x125\n" +
+	"\tthrottled\x18( \x01(\v2\x15.proto.ProtoThrottledH\x00R\tthrottled\x12B\n" +
+	"\x0equota_exceeded\x18) \x01(\v2\x19.proto.ProtoQuotaExceededH\x00R\rquotaExceeded\x12B\n" +
+	"\x0erelay_overload\x18* \x01(\v2\x19.proto.ProtoRelayOverloadH\x00R\rrelayOverload\x12E\n" +
+	"\x0finvalid_message\x18+ \x01(\v2\x1a.proto.ProtoInvalidMessageH\x00R\x0einvalidMessage\x12B\n" +
+	"\x0epush_challenge\x18, \x01(\v2\x19.proto.ProtoPushChallengeH\x00R\rpushChallenge\x12[\n" +
+	"\x17push_challenge_response\x18- \x01(\v2!.proto.ProtoPushChallengeResponseH\x00R\x15pushChallengeResponse\x129\n" +
+	"\vaudio_level\x18. \x01(\v2\x16.proto.ProtoAudioLevelH\x00R\n" +
+	"audioLevel\x12<\n" +
+	"\fbetter_relay\x18/ \x01(\v2\x17.proto.ProtoBetterRelayH\x00R\vbetterRelay\x12<\n" +
+	"\fstream_clock\x180 \x01(\v2\x17.proto.ProtoStreamClockH\x00R\vstreamClock\x12?\n" +
+	"\rinput_latency\x181 \x01(\v2\x18.proto.ProtoInputLatencyH\x00R\finputLatencyB\t\n" +
 	"\apayloadB\x16Z\x14relay/internal/protob\x06proto3"
 
 var (
@@ -473,6 +941,30 @@ var file_messages_proto_goTypes = []any{
 	(*ProtoClientRequestRoomStream)(nil), // 17: proto.ProtoClientRequestRoomStream
 	(*ProtoClientDisconnected)(nil),      // 18: proto.ProtoClientDisconnected
 	(*ProtoServerPushStream)(nil),        // 19: proto.ProtoServerPushStream
+	(*ProtoDirectoryQuery)(nil),          // 20: proto.ProtoDirectoryQuery
+	(*ProtoDirectoryResult)(nil),         // 21: proto.ProtoDirectoryResult
+	(*ProtoStreamPathInfo)(nil),          // 22: proto.ProtoStreamPathInfo
+	(*ProtoStreamStats)(nil),             // 23: proto.ProtoStreamStats
+	(*ProtoRelayNotice)(nil),             // 24: proto.ProtoRelayNotice
+	(*ProtoSignalingProgress)(nil),       // 25: proto.ProtoSignalingProgress
+	(*ProtoMeshRoomTracks)(nil),          // 26: proto.ProtoMeshRoomTracks
+	(*ProtoRoomFull)(nil),                // 27: proto.ProtoRoomFull
+	(*ProtoModeration)(nil),              // 28: proto.ProtoModeration
+	(*ProtoChatMessage)(nil),             // 29: proto.ProtoChatMessage
+	(*ProtoRoomMetadata)(nil),            // 30: proto.ProtoRoomMetadata
+	(*ProtoRoomVariants)(nil),            // 31: proto.ProtoRoomVariants
+	(*ProtoVariantSwitch)(nil),           // 32: proto.ProtoVariantSwitch
+	(*ProtoViewerCount)(nil),             // 33: proto.ProtoViewerCount
+	(*ProtoThrottled)(nil),               // 34: proto.ProtoThrottled
+	(*ProtoQuotaExceeded)(nil),           // 35: proto.ProtoQuotaExceeded
+	(*ProtoRelayOverload)(nil),           // 36: proto.ProtoRelayOverload
+	(*ProtoInvalidMessage)(nil),          // 37: proto.ProtoInvalidMessage
+	(*ProtoPushChallenge)(nil),           // 38: proto.ProtoPushChallenge
+	(*ProtoPushChallengeResponse)(nil),   // 39: proto.ProtoPushChallengeResponse
+	(*ProtoAudioLevel)(nil),              // 40: proto.ProtoAudioLevel
+	(*ProtoBetterRelay)(nil),             // 41: proto.ProtoBetterRelay
+	(*ProtoStreamClock)(nil),             // 42: proto.ProtoStreamClock
+	(*ProtoInputLatency)(nil),            // 43: proto.ProtoInputLatency
 }
 var file_messages_proto_depIdxs = []int32{
 	2,  // 0: proto.ProtoMessageBase.latency:type_name -> proto.ProtoLatencyTracker
@@ -494,11 +986,35 @@ var file_messages_proto_depIdxs = []int32{
 	17, // 16: proto.ProtoMessage.client_request_room_stream:type_name -> proto.ProtoClientRequestRoomStream
 	18, // 17: proto.ProtoMessage.client_disconnected:type_name -> proto.ProtoClientDisconnected
 	19, // 18: proto.ProtoMessage.server_push_stream:type_name -> proto.ProtoServerPushStream
-	19, // [19:19] is the sub-list for method output_type
-	19, // [19:19] is the sub-list for method input_type
-	19, // [19:19] is the sub-list for extension type_name
-	19, // [19:19] is the sub-list for extension extendee
-	0,  // [0:19] is the sub-list for field type_name
+	20, // 19: proto.ProtoMessage.directory_query:type_name -> proto.ProtoDirectoryQuery
+	21, // 20: proto.ProtoMessage.directory_result:type_name -> proto.ProtoDirectoryResult
+	22, // 21: proto.ProtoMessage.stream_path_info:type_name -> proto.ProtoStreamPathInfo
+	23, // 22: proto.ProtoMessage.stream_stats:type_name -> proto.ProtoStreamStats
+	24, // 23: proto.ProtoMessage.relay_notice:type_name -> proto.ProtoRelayNotice
+	25, // 24: proto.ProtoMessage.signaling_progress:type_name -> proto.ProtoSignalingProgress
+	26, // 25: proto.ProtoMessage.mesh_room_tracks:type_name -> proto.ProtoMeshRoomTracks
+	27, // 26: proto.ProtoMessage.room_full:type_name -> proto.ProtoRoomFull
+	28, // 27: proto.ProtoMessage.moderation:type_name -> proto.ProtoModeration
+	29, // 28: proto.ProtoMessage.chat:type_name -> proto.ProtoChatMessage
+	30, // 29: proto.ProtoMessage.room_metadata:type_name -> proto.ProtoRoomMetadata
+	31, // 30: proto.ProtoMessage.room_variants:type_name -> proto.ProtoRoomVariants
+	32, // 31: proto.ProtoMessage.variant_switch:type_name -> proto.ProtoVariantSwitch
+	33, // 32: proto.ProtoMessage.viewer_count:type_name -> proto.ProtoViewerCount
+	34, // 33: proto.ProtoMessage.throttled:type_name -> proto.ProtoThrottled
+	35, // 34: proto.ProtoMessage.quota_exceeded:type_name -> proto.ProtoQuotaExceeded
+	36, // 35: proto.ProtoMessage.relay_overload:type_name -> proto.ProtoRelayOverload
+	37, // 36: proto.ProtoMessage.invalid_message:type_name -> proto.ProtoInvalidMessage
+	38, // 37: proto.ProtoMessage.push_challenge:type_name -> proto.ProtoPushChallenge
+	39, // 38: proto.ProtoMessage.push_challenge_response:type_name -> proto.ProtoPushChallengeResponse
+	40, // 39: proto.ProtoMessage.audio_level:type_name -> proto.ProtoAudioLevel
+	41, // 40: proto.ProtoMessage.better_relay:type_name -> proto.ProtoBetterRelay
+	42, // 41: proto.ProtoMessage.stream_clock:type_name -> proto.ProtoStreamClock
+	43, // 42: proto.ProtoMessage.input_latency:type_name -> proto.ProtoInputLatency
+	43, // [43:43] is the sub-list for method output_type
+	43, // [43:43] is the sub-list for method input_type
+	43, // [43:43] is the sub-list for extension type_name
+	43, // [43:43] is the sub-list for extension extendee
+	0,  // [0:43] is the sub-list for field type_name
 }
 
 func init() { file_messages_proto_init() }
@@ -526,6 +1042,30 @@ func file_messages_proto_init() {
 		(*ProtoMessage_ClientRequestRoomStream)(nil),
 		(*ProtoMessage_ClientDisconnected)(nil),
 		(*ProtoMessage_ServerPushStream)(nil),
+		(*ProtoMessage_DirectoryQuery)(nil),
+		(*ProtoMessage_DirectoryResult)(nil),
+		(*ProtoMessage_StreamPathInfo)(nil),
+		(*ProtoMessage_StreamStats)(nil),
+		(*ProtoMessage_RelayNotice)(nil),
+		(*ProtoMessage_SignalingProgress)(nil),
+		(*ProtoMessage_MeshRoomTracks)(nil),
+		(*ProtoMessage_RoomFull)(nil),
+		(*ProtoMessage_Moderation)(nil),
+		(*ProtoMessage_Chat)(nil),
+		(*ProtoMessage_RoomMetadata)(nil),
+		(*ProtoMessage_RoomVariants)(nil),
+		(*ProtoMessage_VariantSwitch)(nil),
+		(*ProtoMessage_ViewerCount)(nil),
+		(*ProtoMessage_Throttled)(nil),
+		(*ProtoMessage_QuotaExceeded)(nil),
+		(*ProtoMessage_RelayOverload)(nil),
+		(*ProtoMessage_InvalidMessage)(nil),
+		(*ProtoMessage_PushChallenge)(nil),
+		(*ProtoMessage_PushChallengeResponse)(nil),
+		(*ProtoMessage_AudioLevel)(nil),
+		(*ProtoMessage_BetterRelay)(nil),
+		(*ProtoMessage_StreamClock)(nil),
+		(*ProtoMessage_InputLatency)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -1008,28 +1008,1793 @@ func (x *ProtoRaw) GetData() string {
 
 // ProtoClientRequestRoomStream message
 type ProtoClientRequestRoomStream struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	RoomName        string                 `protobuf:"bytes,1,opt,name=room_name,json=roomName,proto3" json:"room_name,omitempty"`
+	SessionId       string                 `protobuf:"bytes,2,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	ExperimentOptIn bool                   `protobuf:"varint,3,opt,name=experiment_opt_in,json=experimentOptIn,proto3" json:"experiment_opt_in,omitempty"` // Viewer consents to being moved into encoder experiments
+	Token           string                 `protobuf:"bytes,4,opt,name=token,proto3" json:"token,omitempty"`                                               // Signed viewer token issued by the platform, required when relay enforces viewer authorization
+	AccessSecret    string                 `protobuf:"bytes,5,opt,name=access_secret,json=accessSecret,proto3" json:"access_secret,omitempty"`             // Room password or invite token, required for rooms pushed with an access secret
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ProtoClientRequestRoomStream) Reset() {
+	*x = ProtoClientRequestRoomStream{}
+	mi := &file_types_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProtoClientRequestRoomStream) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProtoClientRequestRoomStream) ProtoMessage() {}
+
+func (x *ProtoClientRequestRoomStream) ProtoReflect() protoreflect.Message {
+	mi := &file_types_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProtoClientRequestRoomStream.ProtoReflect.Descriptor instead.
+func (*ProtoClientRequestRoomStream) Descriptor() ([]byte, []int) {
+	return file_types_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ProtoClientRequestRoomStream) GetRoomName() string {
+	if x != nil {
+		return x.RoomName
+	}
+	return ""
+}
+
+func (x *ProtoClientRequestRoomStream) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *ProtoClientRequestRoomStream) GetExperimentOptIn() bool {
+	if x != nil {
+		return x.ExperimentOptIn
+	}
+	return false
+}
+
+func (x *ProtoClientRequestRoomStream) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+func (x *ProtoClientRequestRoomStream) GetAccessSecret() string {
+	if x != nil {
+		return x.AccessSecret
+	}
+	return ""
+}
+
+// ProtoClientDisconnected message
+type ProtoClientDisconnected struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	SessionId       string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	ControllerSlots []int32                `protobuf:"varint,2,rep,packed,name=controller_slots,json=controllerSlots,proto3" json:"controller_slots,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ProtoClientDisconnected) Reset() {
+	*x = ProtoClientDisconnected{}
+	mi := &file_types_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProtoClientDisconnected) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProtoClientDisconnected) ProtoMessage() {}
+
+func (x *ProtoClientDisconnected) ProtoReflect() protoreflect.Message {
+	mi := &file_types_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProtoClientDisconnected.ProtoReflect.Descriptor instead.
+func (*ProtoClientDisconnected) Descriptor() ([]byte, []int) {
+	return file_types_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ProtoClientDisconnected) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *ProtoClientDisconnected) GetControllerSlots() []int32 {
+	if x != nil {
+		return x.ControllerSlots
+	}
+	return nil
+}
+
+// ProtoServerPushStream message
+type ProtoServerPushStream struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RoomName      string                 `protobuf:"bytes,1,opt,name=room_name,json=roomName,proto3" json:"room_name,omitempty"`
+	Settings      *ProtoRoomSettings     `protobuf:"bytes,2,opt,name=settings,proto3" json:"settings,omitempty"`    // Optional room settings, applied when room is created
+	Timestamp     int64                  `protobuf:"varint,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"` // Unix seconds the push was signed at, required when relay enforces push authentication
+	Signature     string                 `protobuf:"bytes,4,opt,name=signature,proto3" json:"signature,omitempty"`  // Hex HMAC-SHA256 of "<room_name>\n<timestamp>" with the push secret
+	Metadata      *ProtoRoomMetadata     `protobuf:"bytes,5,opt,name=metadata,proto3" json:"metadata,omitempty"`    // Optional room metadata for room lists, can be updated later with "room-metadata"
+	Variant       string                 `protobuf:"bytes,6,opt,name=variant,proto3" json:"variant,omitempty"`      // Quality variant like "720p30" pushed alongside the main stream of the room, empty for the main stream
+	Standby       bool                   `protobuf:"varint,7,opt,name=standby,proto3" json:"standby,omitempty"`     // Push as standby of the online room, forwarded from only once the pushed stream fails
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProtoServerPushStream) Reset() {
+	*x = ProtoServerPushStream{}
+	mi := &file_types_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProtoServerPushStream) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProtoServerPushStream) ProtoMessage() {}
+
+func (x *ProtoServerPushStream) ProtoReflect() protoreflect.Message {
+	mi := &file_types_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProtoServerPushStream.ProtoReflect.Descriptor instead.
+func (*ProtoServerPushStream) Descriptor() ([]byte, []int) {
+	return file_types_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ProtoServerPushStream) GetRoomName() string {
+	if x != nil {
+		return x.RoomName
+	}
+	return ""
+}
+
+func (x *ProtoServerPushStream) GetSettings() *ProtoRoomSettings {
+	if x != nil {
+		return x.Settings
+	}
+	return nil
+}
+
+func (x *ProtoServerPushStream) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *ProtoServerPushStream) GetSignature() string {
+	if x != nil {
+		return x.Signature
+	}
+	return ""
+}
+
+func (x *ProtoServerPushStream) GetMetadata() *ProtoRoomMetadata {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *ProtoServerPushStream) GetVariant() string {
+	if x != nil {
+		return x.Variant
+	}
+	return ""
+}
+
+func (x *ProtoServerPushStream) GetStandby() bool {
+	if x != nil {
+		return x.Standby
+	}
+	return false
+}
+
+// ProtoRoomSettings message
+type ProtoRoomSettings struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	AudioOnly          bool                   `protobuf:"varint,1,opt,name=audio_only,json=audioOnly,proto3" json:"audio_only,omitempty"`                              // Room carries only audio (voice rooms), no video tracks are allocated
+	LatencyBudgetMs    uint32                 `protobuf:"varint,2,opt,name=latency_budget_ms,json=latencyBudgetMs,proto3" json:"latency_budget_ms,omitempty"`          // End-to-end latency budget for mesh forwarding paths, 0 uses relay default
+	StrictLatency      bool                   `protobuf:"varint,3,opt,name=strict_latency,json=strictLatency,proto3" json:"strict_latency,omitempty"`                  // Drop whole video frames which are late for a viewer instead of delivering them
+	MaxFrameAgeMs      uint32                 `protobuf:"varint,4,opt,name=max_frame_age_ms,json=maxFrameAgeMs,proto3" json:"max_frame_age_ms,omitempty"`              // Max age of video frame since ingest in strict latency mode, 0 uses relay default
+	AccessSecret       string                 `protobuf:"bytes,5,opt,name=access_secret,json=accessSecret,proto3" json:"access_secret,omitempty"`                      // Password or invite token viewers must present, empty for public rooms. Only sent with pushes, never echoed back
+	MaxViewers         uint32                 `protobuf:"varint,6,opt,name=max_viewers,json=maxViewers,proto3" json:"max_viewers,omitempty"`                           // Max participants the room admits, 0 uses relay config or no limit
+	QueueSize          uint32                 `protobuf:"varint,7,opt,name=queue_size,json=queueSize,proto3" json:"queue_size,omitempty"`                              // Packets queued per viewer, 0 uses relay default
+	QueueHighWatermark uint32                 `protobuf:"varint,8,opt,name=queue_high_watermark,json=queueHighWatermark,proto3" json:"queue_high_watermark,omitempty"` // Queue depth at which "video" and "keyframe" drop policies start dropping video, 0 uses 80% of queue size
+	QueueLowWatermark  uint32                 `protobuf:"varint,9,opt,name=queue_low_watermark,json=queueLowWatermark,proto3" json:"queue_low_watermark,omitempty"`    // Queue depth at which dropped video resumes, 0 uses half of queue size
+	DropPolicy         string                 `protobuf:"bytes,10,opt,name=drop_policy,json=dropPolicy,proto3" json:"drop_policy,omitempty"`                           // "newest", "oldest", "video" or "keyframe", empty uses relay config or "newest"
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *ProtoRoomSettings) Reset() {
+	*x = ProtoRoomSettings{}
+	mi := &file_types_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProtoRoomSettings) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProtoRoomSettings) ProtoMessage() {}
+
+func (x *ProtoRoomSettings) ProtoReflect() protoreflect.Message {
+	mi := &file_types_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProtoRoomSettings.ProtoReflect.Descriptor instead.
+func (*ProtoRoomSettings) Descriptor() ([]byte, []int) {
+	return file_types_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *ProtoRoomSettings) GetAudioOnly() bool {
+	if x != nil {
+		return x.AudioOnly
+	}
+	return false
+}
+
+func (x *ProtoRoomSettings) GetLatencyBudgetMs() uint32 {
+	if x != nil {
+		return x.LatencyBudgetMs
+	}
+	return 0
+}
+
+func (x *ProtoRoomSettings) GetStrictLatency() bool {
+	if x != nil {
+		return x.StrictLatency
+	}
+	return false
+}
+
+func (x *ProtoRoomSettings) GetMaxFrameAgeMs() uint32 {
+	if x != nil {
+		return x.MaxFrameAgeMs
+	}
+	return 0
+}
+
+func (x *ProtoRoomSettings) GetAccessSecret() string {
+	if x != nil {
+		return x.AccessSecret
+	}
+	return ""
+}
+
+func (x *ProtoRoomSettings) GetMaxViewers() uint32 {
+	if x != nil {
+		return x.MaxViewers
+	}
+	return 0
+}
+
+func (x *ProtoRoomSettings) GetQueueSize() uint32 {
+	if x != nil {
+		return x.QueueSize
+	}
+	return 0
+}
+
+func (x *ProtoRoomSettings) GetQueueHighWatermark() uint32 {
+	if x != nil {
+		return x.QueueHighWatermark
+	}
+	return 0
+}
+
+func (x *ProtoRoomSettings) GetQueueLowWatermark() uint32 {
+	if x != nil {
+		return x.QueueLowWatermark
+	}
+	return 0
+}
+
+func (x *ProtoRoomSettings) GetDropPolicy() string {
+	if x != nil {
+		return x.DropPolicy
+	}
+	return ""
+}
+
+// ProtoRoomMetadata message
+type ProtoRoomMetadata struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Title         string                 `protobuf:"bytes,1,opt,name=title,proto3" json:"title,omitempty"`                                   // Room title shown in room lists
+	Game          string                 `protobuf:"bytes,2,opt,name=game,proto3" json:"game,omitempty"`                                     // Name of the game being streamed
+	Width         uint32                 `protobuf:"varint,3,opt,name=width,proto3" json:"width,omitempty"`                                  // Stream resolution width in pixels
+	Height        uint32                 `protobuf:"varint,4,opt,name=height,proto3" json:"height,omitempty"`                                // Stream resolution height in pixels
+	FrameRate     uint32                 `protobuf:"varint,5,opt,name=frame_rate,json=frameRate,proto3" json:"frame_rate,omitempty"`         // Stream frames per second
+	Private       bool                   `protobuf:"varint,6,opt,name=private,proto3" json:"private,omitempty"`                              // Room is left out of room directories, still joinable by name
+	ThumbnailUrl  string                 `protobuf:"bytes,7,opt,name=thumbnail_url,json=thumbnailUrl,proto3" json:"thumbnail_url,omitempty"` // URL of latest keyframe thumbnail of the room, empty if none
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProtoRoomMetadata) Reset() {
+	*x = ProtoRoomMetadata{}
+	mi := &file_types_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProtoRoomMetadata) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProtoRoomMetadata) ProtoMessage() {}
+
+func (x *ProtoRoomMetadata) ProtoReflect() protoreflect.Message {
+	mi := &file_types_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProtoRoomMetadata.ProtoReflect.Descriptor instead.
+func (*ProtoRoomMetadata) Descriptor() ([]byte, []int) {
+	return file_types_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *ProtoRoomMetadata) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *ProtoRoomMetadata) GetGame() string {
+	if x != nil {
+		return x.Game
+	}
+	return ""
+}
+
+func (x *ProtoRoomMetadata) GetWidth() uint32 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+func (x *ProtoRoomMetadata) GetHeight() uint32 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *ProtoRoomMetadata) GetFrameRate() uint32 {
+	if x != nil {
+		return x.FrameRate
+	}
+	return 0
+}
+
+func (x *ProtoRoomMetadata) GetPrivate() bool {
+	if x != nil {
+		return x.Private
+	}
+	return false
+}
+
+func (x *ProtoRoomMetadata) GetThumbnailUrl() string {
+	if x != nil {
+		return x.ThumbnailUrl
+	}
+	return ""
+}
+
+// ProtoDirectoryQuery message
+type ProtoDirectoryQuery struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Prefix        string                 `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"` // Room name prefix to match, empty matches all rooms
+	Cursor        string                 `protobuf:"bytes,2,opt,name=cursor,proto3" json:"cursor,omitempty"` // Cursor from previous result page, empty for first page
+	Limit         uint32                 `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`  // Max rooms per page, relay applies default and cap
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProtoDirectoryQuery) Reset() {
+	*x = ProtoDirectoryQuery{}
+	mi := &file_types_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProtoDirectoryQuery) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProtoDirectoryQuery) ProtoMessage() {}
+
+func (x *ProtoDirectoryQuery) ProtoReflect() protoreflect.Message {
+	mi := &file_types_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProtoDirectoryQuery.ProtoReflect.Descriptor instead.
+func (*ProtoDirectoryQuery) Descriptor() ([]byte, []int) {
+	return file_types_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *ProtoDirectoryQuery) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+func (x *ProtoDirectoryQuery) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+func (x *ProtoDirectoryQuery) GetLimit() uint32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+// ProtoDirectoryRoom message
+type ProtoDirectoryRoom struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	OwnerId       string                 `protobuf:"bytes,3,opt,name=owner_id,json=ownerId,proto3" json:"owner_id,omitempty"`
+	Viewers       uint32                 `protobuf:"varint,4,opt,name=viewers,proto3" json:"viewers,omitempty"` // Viewer count as known by the answering relay
+	Online        bool                   `protobuf:"varint,5,opt,name=online,proto3" json:"online,omitempty"`
+	Metadata      *ProtoRoomMetadata     `protobuf:"bytes,6,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProtoDirectoryRoom) Reset() {
+	*x = ProtoDirectoryRoom{}
+	mi := &file_types_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProtoDirectoryRoom) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProtoDirectoryRoom) ProtoMessage() {}
+
+func (x *ProtoDirectoryRoom) ProtoReflect() protoreflect.Message {
+	mi := &file_types_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProtoDirectoryRoom.ProtoReflect.Descriptor instead.
+func (*ProtoDirectoryRoom) Descriptor() ([]byte, []int) {
+	return file_types_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *ProtoDirectoryRoom) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ProtoDirectoryRoom) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ProtoDirectoryRoom) GetOwnerId() string {
+	if x != nil {
+		return x.OwnerId
+	}
+	return ""
+}
+
+func (x *ProtoDirectoryRoom) GetViewers() uint32 {
+	if x != nil {
+		return x.Viewers
+	}
+	return 0
+}
+
+func (x *ProtoDirectoryRoom) GetOnline() bool {
+	if x != nil {
+		return x.Online
+	}
+	return false
+}
+
+func (x *ProtoDirectoryRoom) GetMetadata() *ProtoRoomMetadata {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+// ProtoDirectoryResult message
+type ProtoDirectoryResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Rooms         []*ProtoDirectoryRoom  `protobuf:"bytes,1,rep,name=rooms,proto3" json:"rooms,omitempty"`
+	NextCursor    string                 `protobuf:"bytes,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"` // Empty when there are no more pages
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProtoDirectoryResult) Reset() {
+	*x = ProtoDirectoryResult{}
+	mi := &file_types_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProtoDirectoryResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProtoDirectoryResult) ProtoMessage() {}
+
+func (x *ProtoDirectoryResult) ProtoReflect() protoreflect.Message {
+	mi := &file_types_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProtoDirectoryResult.ProtoReflect.Descriptor instead.
+func (*ProtoDirectoryResult) Descriptor() ([]byte, []int) {
+	return file_types_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *ProtoDirectoryResult) GetRooms() []*ProtoDirectoryRoom {
+	if x != nil {
+		return x.Rooms
+	}
+	return nil
+}
+
+func (x *ProtoDirectoryResult) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
+	}
+	return ""
+}
+
+// ProtoStreamPathInfo message
+type ProtoStreamPathInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RoomName      string                 `protobuf:"bytes,1,opt,name=room_name,json=roomName,proto3" json:"room_name,omitempty"`
+	Hops          uint32                 `protobuf:"varint,2,opt,name=hops,proto3" json:"hops,omitempty"`                                          // Relay hops between room owner and the serving relay
+	PathLatencyUs uint64                 `protobuf:"varint,3,opt,name=path_latency_us,json=pathLatencyUs,proto3" json:"path_latency_us,omitempty"` // Cumulative measured latency up to and including the serving relay
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProtoStreamPathInfo) Reset() {
+	*x = ProtoStreamPathInfo{}
+	mi := &file_types_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProtoStreamPathInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProtoStreamPathInfo) ProtoMessage() {}
+
+func (x *ProtoStreamPathInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_types_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProtoStreamPathInfo.ProtoReflect.Descriptor instead.
+func (*ProtoStreamPathInfo) Descriptor() ([]byte, []int) {
+	return file_types_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *ProtoStreamPathInfo) GetRoomName() string {
+	if x != nil {
+		return x.RoomName
+	}
+	return ""
+}
+
+func (x *ProtoStreamPathInfo) GetHops() uint32 {
+	if x != nil {
+		return x.Hops
+	}
+	return 0
+}
+
+func (x *ProtoStreamPathInfo) GetPathLatencyUs() uint64 {
+	if x != nil {
+		return x.PathLatencyUs
+	}
+	return 0
+}
+
+// ProtoTrackStats message
+type ProtoTrackStats struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Kind               string                 `protobuf:"bytes,1,opt,name=kind,proto3" json:"kind,omitempty"`                                                          // "audio" or "video"
+	BitrateBps         uint64                 `protobuf:"varint,2,opt,name=bitrate_bps,json=bitrateBps,proto3" json:"bitrate_bps,omitempty"`                           // Forwarded bitrate
+	FrameRate          float64                `protobuf:"fixed64,3,opt,name=frame_rate,json=frameRate,proto3" json:"frame_rate,omitempty"`                             // Frames per second, counted from RTP marker bits (video only)
+	KeyframeIntervalMs uint32                 `protobuf:"varint,4,opt,name=keyframe_interval_ms,json=keyframeIntervalMs,proto3" json:"keyframe_interval_ms,omitempty"` // Time between last two keyframes (video only), 0 if unknown
+	Packets            uint64                 `protobuf:"varint,5,opt,name=packets,proto3" json:"packets,omitempty"`                                                   // Total forwarded packets
+	Bytes              uint64                 `protobuf:"varint,6,opt,name=bytes,proto3" json:"bytes,omitempty"`                                                       // Total forwarded payload bytes
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *ProtoTrackStats) Reset() {
+	*x = ProtoTrackStats{}
+	mi := &file_types_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProtoTrackStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProtoTrackStats) ProtoMessage() {}
+
+func (x *ProtoTrackStats) ProtoReflect() protoreflect.Message {
+	mi := &file_types_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProtoTrackStats.ProtoReflect.Descriptor instead.
+func (*ProtoTrackStats) Descriptor() ([]byte, []int) {
+	return file_types_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *ProtoTrackStats) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *ProtoTrackStats) GetBitrateBps() uint64 {
+	if x != nil {
+		return x.BitrateBps
+	}
+	return 0
+}
+
+func (x *ProtoTrackStats) GetFrameRate() float64 {
+	if x != nil {
+		return x.FrameRate
+	}
+	return 0
+}
+
+func (x *ProtoTrackStats) GetKeyframeIntervalMs() uint32 {
+	if x != nil {
+		return x.KeyframeIntervalMs
+	}
+	return 0
+}
+
+func (x *ProtoTrackStats) GetPackets() uint64 {
+	if x != nil {
+		return x.Packets
+	}
+	return 0
+}
+
+func (x *ProtoTrackStats) GetBytes() uint64 {
+	if x != nil {
+		return x.Bytes
+	}
+	return 0
+}
+
+// ProtoStreamStats message
+type ProtoStreamStats struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RoomName      string                 `protobuf:"bytes,1,opt,name=room_name,json=roomName,proto3" json:"room_name,omitempty"`
+	Tracks        []*ProtoTrackStats     `protobuf:"bytes,2,rep,name=tracks,proto3" json:"tracks,omitempty"`
+	InputLatency  *ProtoInputLatency     `protobuf:"bytes,3,opt,name=input_latency,json=inputLatency,proto3" json:"input_latency,omitempty"` // Input round trip of the viewer, unset before any was measured
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProtoStreamStats) Reset() {
+	*x = ProtoStreamStats{}
+	mi := &file_types_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProtoStreamStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProtoStreamStats) ProtoMessage() {}
+
+func (x *ProtoStreamStats) ProtoReflect() protoreflect.Message {
+	mi := &file_types_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProtoStreamStats.ProtoReflect.Descriptor instead.
+func (*ProtoStreamStats) Descriptor() ([]byte, []int) {
+	return file_types_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *ProtoStreamStats) GetRoomName() string {
+	if x != nil {
+		return x.RoomName
+	}
+	return ""
+}
+
+func (x *ProtoStreamStats) GetTracks() []*ProtoTrackStats {
+	if x != nil {
+		return x.Tracks
+	}
+	return nil
+}
+
+func (x *ProtoStreamStats) GetInputLatency() *ProtoInputLatency {
+	if x != nil {
+		return x.InputLatency
+	}
+	return nil
+}
+
+// ProtoRelayNotice message
+type ProtoRelayNotice struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Text          string                 `protobuf:"bytes,1,opt,name=text,proto3" json:"text,omitempty"`
+	Level         string                 `protobuf:"bytes,2,opt,name=level,proto3" json:"level,omitempty"` // "info", "warning" or "critical"
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProtoRelayNotice) Reset() {
+	*x = ProtoRelayNotice{}
+	mi := &file_types_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProtoRelayNotice) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProtoRelayNotice) ProtoMessage() {}
+
+func (x *ProtoRelayNotice) ProtoReflect() protoreflect.Message {
+	mi := &file_types_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProtoRelayNotice.ProtoReflect.Descriptor instead.
+func (*ProtoRelayNotice) Descriptor() ([]byte, []int) {
+	return file_types_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *ProtoRelayNotice) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *ProtoRelayNotice) GetLevel() string {
+	if x != nil {
+		return x.Level
+	}
+	return ""
+}
+
+// ProtoSignalingProgress message
+type ProtoSignalingProgress struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RoomName      string                 `protobuf:"bytes,1,opt,name=room_name,json=roomName,proto3" json:"room_name,omitempty"`
+	Stage         string                 `protobuf:"bytes,2,opt,name=stage,proto3" json:"stage,omitempty"`                           // "session-assigned", "offer-sent", "ice-connected", "dtls-connected", "first-frame-forwarded" or a failure stage
+	Detail        string                 `protobuf:"bytes,3,opt,name=detail,proto3" json:"detail,omitempty"`                         // Reason of a failure stage, empty otherwise
+	ElapsedMs     uint32                 `protobuf:"varint,4,opt,name=elapsed_ms,json=elapsedMs,proto3" json:"elapsed_ms,omitempty"` // Time since the stream request was received
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProtoSignalingProgress) Reset() {
+	*x = ProtoSignalingProgress{}
+	mi := &file_types_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProtoSignalingProgress) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProtoSignalingProgress) ProtoMessage() {}
+
+func (x *ProtoSignalingProgress) ProtoReflect() protoreflect.Message {
+	mi := &file_types_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProtoSignalingProgress.ProtoReflect.Descriptor instead.
+func (*ProtoSignalingProgress) Descriptor() ([]byte, []int) {
+	return file_types_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *ProtoSignalingProgress) GetRoomName() string {
+	if x != nil {
+		return x.RoomName
+	}
+	return ""
+}
+
+func (x *ProtoSignalingProgress) GetStage() string {
+	if x != nil {
+		return x.Stage
+	}
+	return ""
+}
+
+func (x *ProtoSignalingProgress) GetDetail() string {
+	if x != nil {
+		return x.Detail
+	}
+	return ""
+}
+
+func (x *ProtoSignalingProgress) GetElapsedMs() uint32 {
+	if x != nil {
+		return x.ElapsedMs
+	}
+	return 0
+}
+
+// ProtoMeshRoomTracks message
+type ProtoMeshRoomTracks struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RoomName      string                 `protobuf:"bytes,1,opt,name=room_name,json=roomName,proto3" json:"room_name,omitempty"`
+	AudioMid      string                 `protobuf:"bytes,2,opt,name=audio_mid,json=audioMid,proto3" json:"audio_mid,omitempty"` // MID of room audio on the shared mesh PeerConnection
+	VideoMid      string                 `protobuf:"bytes,3,opt,name=video_mid,json=videoMid,proto3" json:"video_mid,omitempty"` // MID of room video, empty for audio-only rooms
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProtoMeshRoomTracks) Reset() {
+	*x = ProtoMeshRoomTracks{}
+	mi := &file_types_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProtoMeshRoomTracks) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProtoMeshRoomTracks) ProtoMessage() {}
+
+func (x *ProtoMeshRoomTracks) ProtoReflect() protoreflect.Message {
+	mi := &file_types_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProtoMeshRoomTracks.ProtoReflect.Descriptor instead.
+func (*ProtoMeshRoomTracks) Descriptor() ([]byte, []int) {
+	return file_types_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *ProtoMeshRoomTracks) GetRoomName() string {
+	if x != nil {
+		return x.RoomName
+	}
+	return ""
+}
+
+func (x *ProtoMeshRoomTracks) GetAudioMid() string {
+	if x != nil {
+		return x.AudioMid
+	}
+	return ""
+}
+
+func (x *ProtoMeshRoomTracks) GetVideoMid() string {
+	if x != nil {
+		return x.VideoMid
+	}
+	return ""
+}
+
+// ProtoRoomFull message
+type ProtoRoomFull struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RoomName      string                 `protobuf:"bytes,1,opt,name=room_name,json=roomName,proto3" json:"room_name,omitempty"`
+	ViewerCount   uint32                 `protobuf:"varint,2,opt,name=viewer_count,json=viewerCount,proto3" json:"viewer_count,omitempty"`       // Participants currently in the room
+	MaxViewers    uint32                 `protobuf:"varint,3,opt,name=max_viewers,json=maxViewers,proto3" json:"max_viewers,omitempty"`          // Participants the room admits
+	QueuePosition uint32                 `protobuf:"varint,4,opt,name=queue_position,json=queuePosition,proto3" json:"queue_position,omitempty"` // Position in the room waiting list, 0 if the requester isn't queued
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProtoRoomFull) Reset() {
+	*x = ProtoRoomFull{}
+	mi := &file_types_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProtoRoomFull) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProtoRoomFull) ProtoMessage() {}
+
+func (x *ProtoRoomFull) ProtoReflect() protoreflect.Message {
+	mi := &file_types_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProtoRoomFull.ProtoReflect.Descriptor instead.
+func (*ProtoRoomFull) Descriptor() ([]byte, []int) {
+	return file_types_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *ProtoRoomFull) GetRoomName() string {
+	if x != nil {
+		return x.RoomName
+	}
+	return ""
+}
+
+func (x *ProtoRoomFull) GetViewerCount() uint32 {
+	if x != nil {
+		return x.ViewerCount
+	}
+	return 0
+}
+
+func (x *ProtoRoomFull) GetMaxViewers() uint32 {
+	if x != nil {
+		return x.MaxViewers
+	}
+	return 0
+}
+
+func (x *ProtoRoomFull) GetQueuePosition() uint32 {
+	if x != nil {
+		return x.QueuePosition
+	}
+	return 0
+}
+
+// ProtoModeration message
+type ProtoModeration struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	RoomName       string                 `protobuf:"bytes,1,opt,name=room_name,json=roomName,proto3" json:"room_name,omitempty"`
+	Action         string                 `protobuf:"bytes,2,opt,name=action,proto3" json:"action,omitempty"`                                          // "kicked" or "banned"
+	Reason         string                 `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`                                          // Moderator given reason, may be empty
+	BanExpiresUnix int64                  `protobuf:"varint,4,opt,name=ban_expires_unix,json=banExpiresUnix,proto3" json:"ban_expires_unix,omitempty"` // When the ban ends, 0 for kicks
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ProtoModeration) Reset() {
+	*x = ProtoModeration{}
+	mi := &file_types_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProtoModeration) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProtoModeration) ProtoMessage() {}
+
+func (x *ProtoModeration) ProtoReflect() protoreflect.Message {
+	mi := &file_types_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProtoModeration.ProtoReflect.Descriptor instead.
+func (*ProtoModeration) Descriptor() ([]byte, []int) {
+	return file_types_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *ProtoModeration) GetRoomName() string {
+	if x != nil {
+		return x.RoomName
+	}
+	return ""
+}
+
+func (x *ProtoModeration) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *ProtoModeration) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *ProtoModeration) GetBanExpiresUnix() int64 {
+	if x != nil {
+		return x.BanExpiresUnix
+	}
+	return 0
+}
+
+// ProtoChatMessage message
+type ProtoChatMessage struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	RoomName       string                 `protobuf:"bytes,1,opt,name=room_name,json=roomName,proto3" json:"room_name,omitempty"` // Set by relay
+	Text           string                 `protobuf:"bytes,2,opt,name=text,proto3" json:"text,omitempty"`
+	SenderId       string                 `protobuf:"bytes,3,opt,name=sender_id,json=senderId,proto3" json:"sender_id,omitempty"`                   // Participant ID of sender, set by relay
+	SenderName     string                 `protobuf:"bytes,4,opt,name=sender_name,json=senderName,proto3" json:"sender_name,omitempty"`             // Display name chosen by sender, unverified
+	SenderIdentity string                 `protobuf:"bytes,5,opt,name=sender_identity,json=senderIdentity,proto3" json:"sender_identity,omitempty"` // Viewer token subject of sender, set by relay, empty without viewer authorization
+	SentUnixMs     int64                  `protobuf:"varint,6,opt,name=sent_unix_ms,json=sentUnixMs,proto3" json:"sent_unix_ms,omitempty"`          // When the relay accepted the message
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ProtoChatMessage) Reset() {
+	*x = ProtoChatMessage{}
+	mi := &file_types_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProtoChatMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProtoChatMessage) ProtoMessage() {}
+
+func (x *ProtoChatMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_types_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProtoChatMessage.ProtoReflect.Descriptor instead.
+func (*ProtoChatMessage) Descriptor() ([]byte, []int) {
+	return file_types_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *ProtoChatMessage) GetRoomName() string {
+	if x != nil {
+		return x.RoomName
+	}
+	return ""
+}
+
+func (x *ProtoChatMessage) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *ProtoChatMessage) GetSenderId() string {
+	if x != nil {
+		return x.SenderId
+	}
+	return ""
+}
+
+func (x *ProtoChatMessage) GetSenderName() string {
+	if x != nil {
+		return x.SenderName
+	}
+	return ""
+}
+
+func (x *ProtoChatMessage) GetSenderIdentity() string {
+	if x != nil {
+		return x.SenderIdentity
+	}
+	return ""
+}
+
+func (x *ProtoChatMessage) GetSentUnixMs() int64 {
+	if x != nil {
+		return x.SentUnixMs
+	}
+	return 0
+}
+
+// ProtoRoomVariant message
+type ProtoRoomVariant struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`                         // Variant name like "720p30", empty for the main stream
+	RoomName      string                 `protobuf:"bytes,2,opt,name=room_name,json=roomName,proto3" json:"room_name,omitempty"` // Sibling room carrying the variant
+	Width         uint32                 `protobuf:"varint,3,opt,name=width,proto3" json:"width,omitempty"`                      // From room metadata of the variant push, 0 if unknown
+	Height        uint32                 `protobuf:"varint,4,opt,name=height,proto3" json:"height,omitempty"`
+	FrameRate     uint32                 `protobuf:"varint,5,opt,name=frame_rate,json=frameRate,proto3" json:"frame_rate,omitempty"`
+	Online        bool                   `protobuf:"varint,6,opt,name=online,proto3" json:"online,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProtoRoomVariant) Reset() {
+	*x = ProtoRoomVariant{}
+	mi := &file_types_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProtoRoomVariant) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProtoRoomVariant) ProtoMessage() {}
+
+func (x *ProtoRoomVariant) ProtoReflect() protoreflect.Message {
+	mi := &file_types_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProtoRoomVariant.ProtoReflect.Descriptor instead.
+func (*ProtoRoomVariant) Descriptor() ([]byte, []int) {
+	return file_types_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *ProtoRoomVariant) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ProtoRoomVariant) GetRoomName() string {
+	if x != nil {
+		return x.RoomName
+	}
+	return ""
+}
+
+func (x *ProtoRoomVariant) GetWidth() uint32 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+func (x *ProtoRoomVariant) GetHeight() uint32 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *ProtoRoomVariant) GetFrameRate() uint32 {
+	if x != nil {
+		return x.FrameRate
+	}
+	return 0
+}
+
+func (x *ProtoRoomVariant) GetOnline() bool {
+	if x != nil {
+		return x.Online
+	}
+	return false
+}
+
+// ProtoRoomVariants message
+type ProtoRoomVariants struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RoomName      string                 `protobuf:"bytes,1,opt,name=room_name,json=roomName,proto3" json:"room_name,omitempty"` // Main room the variants belong to
+	Current       string                 `protobuf:"bytes,2,opt,name=current,proto3" json:"current,omitempty"`                   // Variant the viewer currently receives, empty for the main stream
+	Variants      []*ProtoRoomVariant    `protobuf:"bytes,3,rep,name=variants,proto3" json:"variants,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProtoRoomVariants) Reset() {
+	*x = ProtoRoomVariants{}
+	mi := &file_types_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProtoRoomVariants) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProtoRoomVariants) ProtoMessage() {}
+
+func (x *ProtoRoomVariants) ProtoReflect() protoreflect.Message {
+	mi := &file_types_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProtoRoomVariants.ProtoReflect.Descriptor instead.
+func (*ProtoRoomVariants) Descriptor() ([]byte, []int) {
+	return file_types_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *ProtoRoomVariants) GetRoomName() string {
+	if x != nil {
+		return x.RoomName
+	}
+	return ""
+}
+
+func (x *ProtoRoomVariants) GetCurrent() string {
+	if x != nil {
+		return x.Current
+	}
+	return ""
+}
+
+func (x *ProtoRoomVariants) GetVariants() []*ProtoRoomVariant {
+	if x != nil {
+		return x.Variants
+	}
+	return nil
+}
+
+// ProtoVariantSwitch message
+type ProtoVariantSwitch struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Variant       string                 `protobuf:"bytes,1,opt,name=variant,proto3" json:"variant,omitempty"` // Variant to receive, empty for the main stream
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`     // Why the switch failed, set by relay in "variant-switch-failed"
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProtoVariantSwitch) Reset() {
+	*x = ProtoVariantSwitch{}
+	mi := &file_types_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProtoVariantSwitch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProtoVariantSwitch) ProtoMessage() {}
+
+func (x *ProtoVariantSwitch) ProtoReflect() protoreflect.Message {
+	mi := &file_types_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProtoVariantSwitch.ProtoReflect.Descriptor instead.
+func (*ProtoVariantSwitch) Descriptor() ([]byte, []int) {
+	return file_types_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *ProtoVariantSwitch) GetVariant() string {
+	if x != nil {
+		return x.Variant
+	}
+	return ""
+}
+
+func (x *ProtoVariantSwitch) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// ProtoViewerCount message
+type ProtoViewerCount struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RoomName      string                 `protobuf:"bytes,1,opt,name=room_name,json=roomName,proto3" json:"room_name,omitempty"` // Main room, viewers of its quality variants are included
+	Viewers       uint32                 `protobuf:"varint,2,opt,name=viewers,proto3" json:"viewers,omitempty"`                  // Viewers across the mesh, relays pulling the room aren't counted
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProtoViewerCount) Reset() {
+	*x = ProtoViewerCount{}
+	mi := &file_types_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProtoViewerCount) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProtoViewerCount) ProtoMessage() {}
+
+func (x *ProtoViewerCount) ProtoReflect() protoreflect.Message {
+	mi := &file_types_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProtoViewerCount.ProtoReflect.Descriptor instead.
+func (*ProtoViewerCount) Descriptor() ([]byte, []int) {
+	return file_types_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *ProtoViewerCount) GetRoomName() string {
+	if x != nil {
+		return x.RoomName
+	}
+	return ""
+}
+
+func (x *ProtoViewerCount) GetViewers() uint32 {
+	if x != nil {
+		return x.Viewers
+	}
+	return 0
+}
+
+// ProtoThrottled message
+type ProtoThrottled struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Scope         string                 `protobuf:"bytes,1,opt,name=scope,proto3" json:"scope,omitempty"`                                      // "stream" when opening a signaling stream was refused, "message" when messages are dropped
+	RetryAfterMs  uint32                 `protobuf:"varint,2,opt,name=retry_after_ms,json=retryAfterMs,proto3" json:"retry_after_ms,omitempty"` // When the next stream or message would be accepted
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProtoThrottled) Reset() {
+	*x = ProtoThrottled{}
+	mi := &file_types_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProtoThrottled) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProtoThrottled) ProtoMessage() {}
+
+func (x *ProtoThrottled) ProtoReflect() protoreflect.Message {
+	mi := &file_types_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProtoThrottled.ProtoReflect.Descriptor instead.
+func (*ProtoThrottled) Descriptor() ([]byte, []int) {
+	return file_types_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *ProtoThrottled) GetScope() string {
+	if x != nil {
+		return x.Scope
+	}
+	return ""
+}
+
+func (x *ProtoThrottled) GetRetryAfterMs() uint32 {
+	if x != nil {
+		return x.RetryAfterMs
+	}
+	return 0
+}
+
+// ProtoQuotaExceeded message
+type ProtoQuotaExceeded struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RoomName      string                 `protobuf:"bytes,1,opt,name=room_name,json=roomName,proto3" json:"room_name,omitempty"`        // Requested or cut room
+	Scope         string                 `protobuf:"bytes,2,opt,name=scope,proto3" json:"scope,omitempty"`                              // "daily" or "monthly"
+	UsedBytes     uint64                 `protobuf:"varint,3,opt,name=used_bytes,json=usedBytes,proto3" json:"used_bytes,omitempty"`    // Bytes sent to the peer within the quota period
+	QuotaBytes    uint64                 `protobuf:"varint,4,opt,name=quota_bytes,json=quotaBytes,proto3" json:"quota_bytes,omitempty"` // Bytes the peer may receive within the quota period
+	ResetUnix     int64                  `protobuf:"varint,5,opt,name=reset_unix,json=resetUnix,proto3" json:"reset_unix,omitempty"`    // When the quota period ends and streams are served again
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProtoQuotaExceeded) Reset() {
+	*x = ProtoQuotaExceeded{}
+	mi := &file_types_proto_msgTypes[38]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProtoQuotaExceeded) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProtoQuotaExceeded) ProtoMessage() {}
+
+func (x *ProtoQuotaExceeded) ProtoReflect() protoreflect.Message {
+	mi := &file_types_proto_msgTypes[38]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProtoQuotaExceeded.ProtoReflect.Descriptor instead.
+func (*ProtoQuotaExceeded) Descriptor() ([]byte, []int) {
+	return file_types_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *ProtoQuotaExceeded) GetRoomName() string {
+	if x != nil {
+		return x.RoomName
+	}
+	return ""
+}
+
+func (x *ProtoQuotaExceeded) GetScope() string {
+	if x != nil {
+		return x.Scope
+	}
+	return ""
+}
+
+func (x *ProtoQuotaExceeded) GetUsedBytes() uint64 {
+	if x != nil {
+		return x.UsedBytes
+	}
+	return 0
+}
+
+func (x *ProtoQuotaExceeded) GetQuotaBytes() uint64 {
+	if x != nil {
+		return x.QuotaBytes
+	}
+	return 0
+}
+
+func (x *ProtoQuotaExceeded) GetResetUnix() int64 {
+	if x != nil {
+		return x.ResetUnix
+	}
+	return 0
+}
+
+// ProtoRelayOverload message
+type ProtoRelayOverload struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RelayId       string                 `protobuf:"bytes,1,opt,name=relay_id,json=relayId,proto3" json:"relay_id,omitempty"`           // Peer ID of the advising relay
+	Overloaded    bool                   `protobuf:"varint,2,opt,name=overloaded,proto3" json:"overloaded,omitempty"`                   // True while the relay is overloaded, false once it recovered
+	Reason        string                 `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`                            // "cpu" or "drops" when overloaded
+	CpuPercent    uint32                 `protobuf:"varint,4,opt,name=cpu_percent,json=cpuPercent,proto3" json:"cpu_percent,omitempty"` // Busy share of relay CPU over the last check
+	DropRate      uint32                 `protobuf:"varint,5,opt,name=drop_rate,json=dropRate,proto3" json:"drop_rate,omitempty"`       // Packets per second dropped from viewer queues over the last check
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProtoRelayOverload) Reset() {
+	*x = ProtoRelayOverload{}
+	mi := &file_types_proto_msgTypes[39]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProtoRelayOverload) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProtoRelayOverload) ProtoMessage() {}
+
+func (x *ProtoRelayOverload) ProtoReflect() protoreflect.Message {
+	mi := &file_types_proto_msgTypes[39]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProtoRelayOverload.ProtoReflect.Descriptor instead.
+func (*ProtoRelayOverload) Descriptor() ([]byte, []int) {
+	return file_types_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *ProtoRelayOverload) GetRelayId() string {
+	if x != nil {
+		return x.RelayId
+	}
+	return ""
+}
+
+func (x *ProtoRelayOverload) GetOverloaded() bool {
+	if x != nil {
+		return x.Overloaded
+	}
+	return false
+}
+
+func (x *ProtoRelayOverload) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *ProtoRelayOverload) GetCpuPercent() uint32 {
+	if x != nil {
+		return x.CpuPercent
+	}
+	return 0
+}
+
+func (x *ProtoRelayOverload) GetDropRate() uint32 {
+	if x != nil {
+		return x.DropRate
+	}
+	return 0
+}
+
+// ProtoInvalidMessage message
+type ProtoInvalidMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PayloadType   string                 `protobuf:"bytes,1,opt,name=payload_type,json=payloadType,proto3" json:"payload_type,omitempty"` // Payload type of the rejected message
+	Field         string                 `protobuf:"bytes,2,opt,name=field,proto3" json:"field,omitempty"`                                // Offending field, empty if the payload was missing
+	Reason        string                 `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`                              // "missing", "too-long", "invalid-utf8" or "invalid-value"
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProtoInvalidMessage) Reset() {
+	*x = ProtoInvalidMessage{}
+	mi := &file_types_proto_msgTypes[40]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProtoInvalidMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProtoInvalidMessage) ProtoMessage() {}
+
+func (x *ProtoInvalidMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_types_proto_msgTypes[40]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProtoInvalidMessage.ProtoReflect.Descriptor instead.
+func (*ProtoInvalidMessage) Descriptor() ([]byte, []int) {
+	return file_types_proto_rawDescGZIP(), []int{40}
+}
+
+func (x *ProtoInvalidMessage) GetPayloadType() string {
+	if x != nil {
+		return x.PayloadType
+	}
+	return ""
+}
+
+func (x *ProtoInvalidMessage) GetField() string {
+	if x != nil {
+		return x.Field
+	}
+	return ""
+}
+
+func (x *ProtoInvalidMessage) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+// ProtoPushChallenge message
+type ProtoPushChallenge struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	RoomName      string                 `protobuf:"bytes,1,opt,name=room_name,json=roomName,proto3" json:"room_name,omitempty"`
-	SessionId     string                 `protobuf:"bytes,2,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	RoomName      string                 `protobuf:"bytes,1,opt,name=room_name,json=roomName,proto3" json:"room_name,omitempty"` // Room the push was requested for
+	Nonce         string                 `protobuf:"bytes,2,opt,name=nonce,proto3" json:"nonce,omitempty"`                       // Hex random nonce the pushing node signs with its push key
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ProtoClientRequestRoomStream) Reset() {
-	*x = ProtoClientRequestRoomStream{}
-	mi := &file_types_proto_msgTypes[16]
+func (x *ProtoPushChallenge) Reset() {
+	*x = ProtoPushChallenge{}
+	mi := &file_types_proto_msgTypes[41]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ProtoClientRequestRoomStream) String() string {
+func (x *ProtoPushChallenge) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ProtoClientRequestRoomStream) ProtoMessage() {}
+func (*ProtoPushChallenge) ProtoMessage() {}
 
-func (x *ProtoClientRequestRoomStream) ProtoReflect() protoreflect.Message {
-	mi := &file_types_proto_msgTypes[16]
+func (x *ProtoPushChallenge) ProtoReflect() protoreflect.Message {
+	mi := &file_types_proto_msgTypes[41]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1040,49 +2805,50 @@ func (x *ProtoClientRequestRoomStream) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ProtoClientRequestRoomStream.ProtoReflect.Descriptor instead.
-func (*ProtoClientRequestRoomStream) Descriptor() ([]byte, []int) {
-	return file_types_proto_rawDescGZIP(), []int{16}
+// Deprecated: Use ProtoPushChallenge.ProtoReflect.Descriptor instead.
+func (*ProtoPushChallenge) Descriptor() ([]byte, []int) {
+	return file_types_proto_rawDescGZIP(), []int{41}
 }
 
-func (x *ProtoClientRequestRoomStream) GetRoomName() string {
+func (x *ProtoPushChallenge) GetRoomName() string {
 	if x != nil {
 		return x.RoomName
 	}
 	return ""
 }
 
-func (x *ProtoClientRequestRoomStream) GetSessionId() string {
+func (x *ProtoPushChallenge) GetNonce() string {
 	if x != nil {
-		return x.SessionId
+		return x.Nonce
 	}
 	return ""
 }
 
-// ProtoClientDisconnected message
-type ProtoClientDisconnected struct {
-	state           protoimpl.MessageState `protogen:"open.v1"`
-	SessionId       string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
-	ControllerSlots []int32                `protobuf:"varint,2,rep,packed,name=controller_slots,json=controllerSlots,proto3" json:"controller_slots,omitempty"`
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+// ProtoPushChallengeResponse message
+type ProtoPushChallengeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RoomName      string                 `protobuf:"bytes,1,opt,name=room_name,json=roomName,proto3" json:"room_name,omitempty"`    // Room of the answered challenge
+	PublicKey     string                 `protobuf:"bytes,2,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"` // Hex Ed25519 public key of the pushing node
+	Signature     string                 `protobuf:"bytes,3,opt,name=signature,proto3" json:"signature,omitempty"`                  // Hex Ed25519 signature of the challenge, bound to relay peer ID
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ProtoClientDisconnected) Reset() {
-	*x = ProtoClientDisconnected{}
-	mi := &file_types_proto_msgTypes[17]
+func (x *ProtoPushChallengeResponse) Reset() {
+	*x = ProtoPushChallengeResponse{}
+	mi := &file_types_proto_msgTypes[42]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ProtoClientDisconnected) String() string {
+func (x *ProtoPushChallengeResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ProtoClientDisconnected) ProtoMessage() {}
+func (*ProtoPushChallengeResponse) ProtoMessage() {}
 
-func (x *ProtoClientDisconnected) ProtoReflect() protoreflect.Message {
-	mi := &file_types_proto_msgTypes[17]
+func (x *ProtoPushChallengeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_types_proto_msgTypes[42]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1093,48 +2859,251 @@ func (x *ProtoClientDisconnected) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ProtoClientDisconnected.ProtoReflect.Descriptor instead.
-func (*ProtoClientDisconnected) Descriptor() ([]byte, []int) {
-	return file_types_proto_rawDescGZIP(), []int{17}
+// Deprecated: Use ProtoPushChallengeResponse.ProtoReflect.Descriptor instead.
+func (*ProtoPushChallengeResponse) Descriptor() ([]byte, []int) {
+	return file_types_proto_rawDescGZIP(), []int{42}
 }
 
-func (x *ProtoClientDisconnected) GetSessionId() string {
+func (x *ProtoPushChallengeResponse) GetRoomName() string {
 	if x != nil {
-		return x.SessionId
+		return x.RoomName
 	}
 	return ""
 }
 
-func (x *ProtoClientDisconnected) GetControllerSlots() []int32 {
+func (x *ProtoPushChallengeResponse) GetPublicKey() string {
 	if x != nil {
-		return x.ControllerSlots
+		return x.PublicKey
+	}
+	return ""
+}
+
+func (x *ProtoPushChallengeResponse) GetSignature() string {
+	if x != nil {
+		return x.Signature
+	}
+	return ""
+}
+
+// ProtoAudioLevel message
+type ProtoAudioLevel struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RoomName      string                 `protobuf:"bytes,1,opt,name=room_name,json=roomName,proto3" json:"room_name,omitempty"` // Room whose audio the level was read from
+	Level         uint32                 `protobuf:"varint,2,opt,name=level,proto3" json:"level,omitempty"`                      // Loudest level since the previous event as -dBov, 0 is loudest and 127 silence (RFC 6464)
+	Voice         bool                   `protobuf:"varint,3,opt,name=voice,proto3" json:"voice,omitempty"`                      // Sender flagged voice activity since the previous event
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProtoAudioLevel) Reset() {
+	*x = ProtoAudioLevel{}
+	mi := &file_types_proto_msgTypes[43]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProtoAudioLevel) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProtoAudioLevel) ProtoMessage() {}
+
+func (x *ProtoAudioLevel) ProtoReflect() protoreflect.Message {
+	mi := &file_types_proto_msgTypes[43]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProtoAudioLevel.ProtoReflect.Descriptor instead.
+func (*ProtoAudioLevel) Descriptor() ([]byte, []int) {
+	return file_types_proto_rawDescGZIP(), []int{43}
+}
+
+func (x *ProtoAudioLevel) GetRoomName() string {
+	if x != nil {
+		return x.RoomName
+	}
+	return ""
+}
+
+func (x *ProtoAudioLevel) GetLevel() uint32 {
+	if x != nil {
+		return x.Level
+	}
+	return 0
+}
+
+func (x *ProtoAudioLevel) GetVoice() bool {
+	if x != nil {
+		return x.Voice
+	}
+	return false
+}
+
+// ProtoBetterRelay message
+type ProtoBetterRelay struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RoomName      string                 `protobuf:"bytes,1,opt,name=room_name,json=roomName,proto3" json:"room_name,omitempty"` // Room the viewer requested
+	PeerId        string                 `protobuf:"bytes,2,opt,name=peer_id,json=peerId,proto3" json:"peer_id,omitempty"`       // Relay serving the room closer to the viewer
+	Addrs         []string               `protobuf:"bytes,3,rep,name=addrs,proto3" json:"addrs,omitempty"`                       // Multiaddrs the viewer can dial the relay at, with its peer ID
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProtoBetterRelay) Reset() {
+	*x = ProtoBetterRelay{}
+	mi := &file_types_proto_msgTypes[44]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProtoBetterRelay) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProtoBetterRelay) ProtoMessage() {}
+
+func (x *ProtoBetterRelay) ProtoReflect() protoreflect.Message {
+	mi := &file_types_proto_msgTypes[44]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProtoBetterRelay.ProtoReflect.Descriptor instead.
+func (*ProtoBetterRelay) Descriptor() ([]byte, []int) {
+	return file_types_proto_rawDescGZIP(), []int{44}
+}
+
+func (x *ProtoBetterRelay) GetRoomName() string {
+	if x != nil {
+		return x.RoomName
+	}
+	return ""
+}
+
+func (x *ProtoBetterRelay) GetPeerId() string {
+	if x != nil {
+		return x.PeerId
+	}
+	return ""
+}
+
+func (x *ProtoBetterRelay) GetAddrs() []string {
+	if x != nil {
+		return x.Addrs
 	}
 	return nil
 }
 
-// ProtoServerPushStream message
-type ProtoServerPushStream struct {
+// ProtoStreamClock message
+type ProtoStreamClock struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RoomName      string                 `protobuf:"bytes,1,opt,name=room_name,json=roomName,proto3" json:"room_name,omitempty"`                // Room whose video the clock maps
+	RtpTimestamp  uint32                 `protobuf:"varint,2,opt,name=rtp_timestamp,json=rtpTimestamp,proto3" json:"rtp_timestamp,omitempty"`   // Video RTP timestamp as received by the viewer
+	StreamTimeUs  int64                  `protobuf:"varint,3,opt,name=stream_time_us,json=streamTimeUs,proto3" json:"stream_time_us,omitempty"` // Stream time of that timestamp, Unix microseconds the room's relay received the frame at
+	ClockRate     uint32                 `protobuf:"varint,4,opt,name=clock_rate,json=clockRate,proto3" json:"clock_rate,omitempty"`            // Video RTP clock rate in Hz
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProtoStreamClock) Reset() {
+	*x = ProtoStreamClock{}
+	mi := &file_types_proto_msgTypes[45]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProtoStreamClock) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProtoStreamClock) ProtoMessage() {}
+
+func (x *ProtoStreamClock) ProtoReflect() protoreflect.Message {
+	mi := &file_types_proto_msgTypes[45]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProtoStreamClock.ProtoReflect.Descriptor instead.
+func (*ProtoStreamClock) Descriptor() ([]byte, []int) {
+	return file_types_proto_rawDescGZIP(), []int{45}
+}
+
+func (x *ProtoStreamClock) GetRoomName() string {
+	if x != nil {
+		return x.RoomName
+	}
+	return ""
+}
+
+func (x *ProtoStreamClock) GetRtpTimestamp() uint32 {
+	if x != nil {
+		return x.RtpTimestamp
+	}
+	return 0
+}
+
+func (x *ProtoStreamClock) GetStreamTimeUs() int64 {
+	if x != nil {
+		return x.StreamTimeUs
+	}
+	return 0
+}
+
+func (x *ProtoStreamClock) GetClockRate() uint32 {
+	if x != nil {
+		return x.ClockRate
+	}
+	return 0
+}
+
+// ProtoInputLatency message
+type ProtoInputLatency struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	RoomName      string                 `protobuf:"bytes,1,opt,name=room_name,json=roomName,proto3" json:"room_name,omitempty"`
+	LastMs        uint32                 `protobuf:"varint,2,opt,name=last_ms,json=lastMs,proto3" json:"last_ms,omitempty"` // Round trip of the latest measured input, from the viewer's relay to the server and back
+	P50Ms         uint32                 `protobuf:"varint,3,opt,name=p50_ms,json=p50Ms,proto3" json:"p50_ms,omitempty"`    // Median input round trip of the viewer
+	P95Ms         uint32                 `protobuf:"varint,4,opt,name=p95_ms,json=p95Ms,proto3" json:"p95_ms,omitempty"`
+	P99Ms         uint32                 `protobuf:"varint,5,opt,name=p99_ms,json=p99Ms,proto3" json:"p99_ms,omitempty"`
+	Samples       uint64                 `protobuf:"varint,6,opt,name=samples,proto3" json:"samples,omitempty"` // Input round trips measured so far
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ProtoServerPushStream) Reset() {
-	*x = ProtoServerPushStream{}
-	mi := &file_types_proto_msgTypes[18]
+func (x *ProtoInputLatency) Reset() {
+	*x = ProtoInputLatency{}
+	mi := &file_types_proto_msgTypes[46]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ProtoServerPushStream) String() string {
+func (x *ProtoInputLatency) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ProtoServerPushStream) ProtoMessage() {}
+func (*ProtoInputLatency) ProtoMessage() {}
 
-func (x *ProtoServerPushStream) ProtoReflect() protoreflect.Message {
-	mi := &file_types_proto_msgTypes[18]
+func (x *ProtoInputLatency) ProtoReflect() protoreflect.Message {
+	mi := &file_types_proto_msgTypes[46]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1145,18 +3114,53 @@ func (x *ProtoServerPushStream) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ProtoServerPushStream.ProtoReflect.Descriptor instead.
-func (*ProtoServerPushStream) Descriptor() ([]byte, []int) {
-	return file_types_proto_rawDescGZIP(), []int{18}
+// Deprecated: Use ProtoInputLatency.ProtoReflect.Descriptor instead.
+func (*ProtoInputLatency) Descriptor() ([]byte, []int) {
+	return file_types_proto_rawDescGZIP(), []int{46}
 }
 
-func (x *ProtoServerPushStream) GetRoomName() string {
+func (x *ProtoInputLatency) GetRoomName() string {
 	if x != nil {
 		return x.RoomName
 	}
 	return ""
 }
 
+func (x *ProtoInputLatency) GetLastMs() uint32 {
+	if x != nil {
+		return x.LastMs
+	}
+	return 0
+}
+
+func (x *ProtoInputLatency) GetP50Ms() uint32 {
+	if x != nil {
+		return x.P50Ms
+	}
+	return 0
+}
+
+func (x *ProtoInputLatency) GetP95Ms() uint32 {
+	if x != nil {
+		return x.P95Ms
+	}
+	return 0
+}
+
+func (x *ProtoInputLatency) GetP99Ms() uint32 {
+	if x != nil {
+		return x.P99Ms
+	}
+	return 0
+}
+
+func (x *ProtoInputLatency) GetSamples() uint64 {
+	if x != nil {
+		return x.Samples
+	}
+	return 0
+}
+
 var File_types_proto protoreflect.FileDescriptor
 
 const file_types_proto_rawDesc = "" +
@@ -1249,17 +3253,188 @@ const file_types_proto_rawDesc = "" +
 	"\bProtoSDP\x122\n" +
 	"\x03sdp\x18\x01 \x01(\v2 .proto.RTCSessionDescriptionInitR\x03sdp\"\x1e\n" +
 	"\bProtoRaw\x12\x12\n" +
-	"\x04data\x18\x01 \x01(\tR\x04data\"Z\n" +
+	"\x04data\x18\x01 \x01(\tR\x04data\"\xc1\x01\n" +
 	"\x1cProtoClientRequestRoomStream\x12\x1b\n" +
 	"\troom_name\x18\x01 \x01(\tR\broomName\x12\x1d\n" +
 	"\n" +
-	"session_id\x18\x02 \x01(\tR\tsessionId\"c\n" +
+	"session_id\x18\x02 \x01(\tR\tsessionId\x12*\n" +
+	"\x11experiment_opt_in\x18\x03 \x01(\bR\x0fexperimentOptIn\x12\x14\n" +
+	"\x05token\x18\x04 \x01(\tR\x05token\x12#\n" +
+	"\raccess_secret\x18\x05 \x01(\tR\faccessSecret\"c\n" +
 	"\x17ProtoClientDisconnected\x12\x1d\n" +
 	"\n" +
 	"session_id\x18\x01 \x01(\tR\tsessionId\x12)\n" +
-	"\x10controller_slots\x18\x02 \x03(\x05R\x0fcontrollerSlots\"4\n" +
+	"\x10controller_slots\x18\x02 \x03(\x05R\x0fcontrollerSlots\"\x90\x02\n" +
 	"\x15ProtoServerPushStream\x12\x1b\n" +
-	"\troom_name\x18\x01 \x01(\tR\broomNameB\x16Z\x14relay/internal/protob\x06proto3"
+	"\troom_name\x18\x01 \x01(\tR\broomName\x124\n" +
+	"\bsettings\x18\x02 \x01(\v2\x18.proto.ProtoRoomSettingsR\bsettings\x12\x1c\n" +
+	"\ttimestamp\x18\x03 \x01(\x03R\ttimestamp\x12\x1c\n" +
+	"\tsignature\x18\x04 \x01(\tR\tsignature\x124\n" +
+	"\bmetadata\x18\x05 \x01(\v2\x18.proto.ProtoRoomMetadataR\bmetadata\x12\x18\n" +
+	"\avariant\x18\x06 \x01(\tR\avariant\x12\x18\n" +
+	"\astandby\x18\a \x01(\bR\astandby\"\x96\x03\n" +
+	"\x11ProtoRoomSettings\x12\x1d\n" +
+	"\n" +
+	"audio_only\x18\x01 \x01(\bR\taudioOnly\x12*\n" +
+	"\x11latency_budget_ms\x18\x02 \x01(\rR\x0flatencyBudgetMs\x12%\n" +
+	"\x0estrict_latency\x18\x03 \x01(\bR\rstrictLatency\x12'\n" +
+	"\x10max_frame_age_ms\x18\x04 \x01(\rR\rmaxFrameAgeMs\x12#\n" +
+	"\raccess_secret\x18\x05 \x01(\tR\faccessSecret\x12\x1f\n" +
+	"\vmax_viewers\x18\x06 \x01(\rR\n" +
+	"maxViewers\x12\x1d\n" +
+	"\n" +
+	"queue_size\x18\a \x01(\rR\tqueueSize\x120\n" +
+	"\x14queue_high_watermark\x18\b \x01(\rR\x12queueHighWatermark\x12.\n" +
+	"\x13queue_low_watermark\x18\t \x01(\rR\x11queueLowWatermark\x12\x1f\n" +
+	"\vdrop_policy\x18\n" +
+	" \x01(\tR\n" +
+	"dropPolicy\"\xc9\x01\n" +
+	"\x11ProtoRoomMetadata\x12\x14\n" +
+	"\x05title\x18\x01 \x01(\tR\x05title\x12\x12\n" +
+	"\x04game\x18\x02 \x01(\tR\x04game\x12\x14\n" +
+	"\x05width\x18\x03 \x01(\rR\x05width\x12\x16\n" +
+	"\x06height\x18\x04 \x01(\rR\x06height\x12\x1d\n" +
+	"\n" +
+	"frame_rate\x18\x05 \x01(\rR\tframeRate\x12\x18\n" +
+	"\aprivate\x18\x06 \x01(\bR\aprivate\x12#\n" +
+	"\rthumbnail_url\x18\a \x01(\tR\fthumbnailUrl\"[\n" +
+	"\x13ProtoDirectoryQuery\x12\x16\n" +
+	"\x06prefix\x18\x01 \x01(\tR\x06prefix\x12\x16\n" +
+	"\x06cursor\x18\x02 \x01(\tR\x06cursor\x12\x14\n" +
+	"\x05limit\x18\x03 \x01(\rR\x05limit\"\xbb\x01\n" +
+	"\x12ProtoDirectoryRoom\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x19\n" +
+	"\bowner_id\x18\x03 \x01(\tR\aownerId\x12\x18\n" +
+	"\aviewers\x18\x04 \x01(\rR\aviewers\x12\x16\n" +
+	"\x06online\x18\x05 \x01(\bR\x06online\x124\n" +
+	"\bmetadata\x18\x06 \x01(\v2\x18.proto.ProtoRoomMetadataR\bmetadata\"h\n" +
+	"\x14ProtoDirectoryResult\x12/\n" +
+	"\x05rooms\x18\x01 \x03(\v2\x19.proto.ProtoDirectoryRoomR\x05rooms\x12\x1f\n" +
+	"\vnext_cursor\x18\x02 \x01(\tR\n" +
+	"nextCursor\"n\n" +
+	"\x13ProtoStreamPathInfo\x12\x1b\n" +
+	"\troom_name\x18\x01 \x01(\tR\broomName\x12\x12\n" +
+	"\x04hops\x18\x02 \x01(\rR\x04hops\x12&\n" +
+	"\x0fpath_latency_us\x18\x03 \x01(\x04R\rpathLatencyUs\"\xc7\x01\n" +
+	"\x0fProtoTrackStats\x12\x12\n" +
+	"\x04kind\x18\x01 \x01(\tR\x04kind\x12\x1f\n" +
+	"\vbitrate_bps\x18\x02 \x01(\x04R\n" +
+	"bitrateBps\x12\x1d\n" +
+	"\n" +
+	"frame_rate\x18\x03 \x01(\x01R\tframeRate\x120\n" +
+	"\x14keyframe_interval_ms\x18\x04 \x01(\rR\x12keyframeIntervalMs\x12\x18\n" +
+	"\apackets\x18\x05 \x01(\x04R\apackets\x12\x14\n" +
+	"\x05bytes\x18\x06 \x01(\x04R\x05bytes\"\x9e\x01\n" +
+	"\x10ProtoStreamStats\x12\x1b\n" +
+	"\troom_name\x18\x01 \x01(\tR\broomName\x12.\n" +
+	"\x06tracks\x18\x02 \x03(\v2\x16.proto.ProtoTrackStatsR\x06tracks\x12=\n" +
+	"\rinput_latency\x18\x03 \x01(\v2\x18.proto.ProtoInputLatencyR\finputLatency\"<\n" +
+	"\x10ProtoRelayNotice\x12\x12\n" +
+	"\x04text\x18\x01 \x01(\tR\x04text\x12\x14\n" +
+	"\x05level\x18\x02 \x01(\tR\x05level\"\x82\x01\n" +
+	"\x16ProtoSignalingProgress\x12\x1b\n" +
+	"\troom_name\x18\x01 \x01(\tR\broomName\x12\x14\n" +
+	"\x05stage\x18\x02 \x01(\tR\x05stage\x12\x16\n" +
+	"\x06detail\x18\x03 \x01(\tR\x06detail\x12\x1d\n" +
+	"\n" +
+	"elapsed_ms\x18\x04 \x01(\rR\telapsedMs\"l\n" +
+	"\x13ProtoMeshRoomTracks\x12\x1b\n" +
+	"\troom_name\x18\x01 \x01(\tR\broomName\x12\x1b\n" +
+	"\taudio_mid\x18\x02 \x01(\tR\baudioMid\x12\x1b\n" +
+	"\tvideo_mid\x18\x03 \x01(\tR\bvideoMid\"\x97\x01\n" +
+	"\rProtoRoomFull\x12\x1b\n" +
+	"\troom_name\x18\x01 \x01(\tR\broomName\x12!\n" +
+	"\fviewer_count\x18\x02 \x01(\rR\vviewerCount\x12\x1f\n" +
+	"\vmax_viewers\x18\x03 \x01(\rR\n" +
+	"maxViewers\x12%\n" +
+	"\x0equeue_position\x18\x04 \x01(\rR\rqueuePosition\"\x88\x01\n" +
+	"\x0fProtoModeration\x12\x1b\n" +
+	"\troom_name\x18\x01 \x01(\tR\broomName\x12\x16\n" +
+	"\x06action\x18\x02 \x01(\tR\x06action\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\x12(\n" +
+	"\x10ban_expires_unix\x18\x04 \x01(\x03R\x0ebanExpiresUnix\"\xcc\x01\n" +
+	"\x10ProtoChatMessage\x12\x1b\n" +
+	"\troom_name\x18\x01 \x01(\tR\broomName\x12\x12\n" +
+	"\x04text\x18\x02 \x01(\tR\x04text\x12\x1b\n" +
+	"\tsender_id\x18\x03 \x01(\tR\bsenderId\x12\x1f\n" +
+	"\vsender_name\x18\x04 \x01(\tR\n" +
+	"senderName\x12'\n" +
+	"\x0fsender_identity\x18\x05 \x01(\tR\x0esenderIdentity\x12 \n" +
+	"\fsent_unix_ms\x18\x06 \x01(\x03R\n" +
+	"sentUnixMs\"\xa8\x01\n" +
+	"\x10ProtoRoomVariant\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1b\n" +
+	"\troom_name\x18\x02 \x01(\tR\broomName\x12\x14\n" +
+	"\x05width\x18\x03 \x01(\rR\x05width\x12\x16\n" +
+	"\x06height\x18\x04 \x01(\rR\x06height\x12\x1d\n" +
+	"\n" +
+	"frame_rate\x18\x05 \x01(\rR\tframeRate\x12\x16\n" +
+	"\x06online\x18\x06 \x01(\bR\x06online\"\x7f\n" +
+	"\x11ProtoRoomVariants\x12\x1b\n" +
+	"\troom_name\x18\x01 \x01(\tR\broomName\x12\x18\n" +
+	"\acurrent\x18\x02 \x01(\tR\acurrent\x123\n" +
+	"\bvariants\x18\x03 \x03(\v2\x17.proto.ProtoRoomVariantR\bvariants\"D\n" +
+	"\x12ProtoVariantSwitch\x12\x18\n" +
+	"\avariant\x18\x01 \x01(\tR\avariant\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"I\n" +
+	"\x10ProtoViewerCount\x12\x1b\n" +
+	"\troom_name\x18\x01 \x01(\tR\broomName\x12\x18\n" +
+	"\aviewers\x18\x02 \x01(\rR\aviewers\"L\n" +
+	"\x0eProtoThrottled\x12\x14\n" +
+	"\x05scope\x18\x01 \x01(\tR\x05scope\x12$\n" +
+	"\x0eretry_after_ms\x18\x02 \x01(\rR\fretryAfterMs\"\xa6\x01\n" +
+	"\x12ProtoQuotaExceeded\x12\x1b\n" +
+	"\troom_name\x18\x01 \x01(\tR\broomName\x12\x14\n" +
+	"\x05scope\x18\x02 \x01(\tR\x05scope\x12\x1d\n" +
+	"\n" +
+	"used_bytes\x18\x03 \x01(\x04R\tusedBytes\x12\x1f\n" +
+	"\vquota_bytes\x18\x04 \x01(\x04R\n" +
+	"quotaBytes\x12\x1d\n" +
+	"\n" +
+	"reset_unix\x18\x05 \x01(\x03R\tresetUnix\"\xa5\x01\n" +
+	"\x12ProtoRelayOverload\x12\x19\n" +
+	"\brelay_id\x18\x01 \x01(\tR\arelayId\x12\x1e\n" +
+	"\n" +
+	"overloaded\x18\x02 \x01(\bR\n" +
+	"overloaded\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\x12\x1f\n" +
+	"\vcpu_percent\x18\x04 \x01(\rR\n" +
+	"cpuPercent\x12\x1b\n" +
+	"\tdrop_rate\x18\x05 \x01(\rR\bdropRate\"f\n" +
+	"\x13ProtoInvalidMessage\x12!\n" +
+	"\fpayload_type\x18\x01 \x01(\tR\vpayloadType\x12\x14\n" +
+	"\x05field\x18\x02 \x01(\tR\x05field\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\"G\n" +
+	"\x12ProtoPushChallenge\x12\x1b\n" +
+	"\troom_name\x18\x01 \x01(\tR\broomName\x12\x14\n" +
+	"\x05nonce\x18\x02 \x01(\tR\x05nonce\"v\n" +
+	"\x1aProtoPushChallengeResponse\x12\x1b\n" +
+	"\troom_name\x18\x01 \x01(\tR\broomName\x12\x1d\n" +
+	"\n" +
+	"public_key\x18\x02 \x01(\tR\tpublicKey\x12\x1c\n" +
+	"\tsignature\x18\x03 \x01(\tR\tsignature\"Z\n" +
+	"\x0fProtoAudioLevel\x12\x1b\n" +
+	"\troom_name\x18\x01 \x01(\tR\broomName\x12\x14\n" +
+	"\x05level\x18\x02 \x01(\rR\x05level\x12\x14\n" +
+	"\x05voice\x18\x03 \x01(\bR\x05voice\"^\n" +
+	"\x10ProtoBetterRelay\x12\x1b\n" +
+	"\troom_name\x18\x01 \x01(\tR\broomName\x12\x17\n" +
+	"\apeer_id\x18\x02 \x01(\tR\x06peerId\x12\x14\n" +
+	"\x05addrs\x18\x03 \x03(\tR\x05addrs\"\x99\x01\n" +
+	"\x10ProtoStreamClock\x12\x1b\n" +
+	"\troom_name\x18\x01 \x01(\tR\broomName\x12#\n" +
+	"\rrtp_timestamp\x18\x02 \x01(\rR\frtpTimestamp\x12$\n" +
+	"\x0estream_time_us\x18\x03 \x01(\x03R\fstreamTimeUs\x12\x1d\n" +
+	"\n" +
+	"clock_rate\x18\x04 \x01(\rR\tclockRate\"\xa8\x01\n" +
+	"\x11ProtoInputLatency\x12\x1b\n" +
+	"\troom_name\x18\x01 \x01(\tR\broomName\x12\x17\n" +
+	"\alast_ms\x18\x02 \x01(\rR\x06lastMs\x12\x15\n" +
+	"\x06p50_ms\x18\x03 \x01(\rR\x05p50Ms\x12\x15\n" +
+	"\x06p95_ms\x18\x04 \x01(\rR\x05p95Ms\x12\x15\n" +
+	"\x06p99_ms\x18\x05 \x01(\rR\x05p99Ms\x12\x18\n" +
+	"\asamples\x18\x06 \x01(\x04R\asamplesB\x16Z\x14relay/internal/protob\x06proto3"
 
 var (
 	file_types_proto_rawDescOnce sync.Once
@@ -1274,7 +3449,7 @@ func file_types_proto_rawDescGZIP() []byte {
 }
 
 var file_types_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_types_proto_msgTypes = make([]protoimpl.MessageInfo, 20)
+var file_types_proto_msgTypes = make([]protoimpl.MessageInfo, 48)
 var file_types_proto_goTypes = []any{
 	(ProtoControllerStateBatch_UpdateType)(0), // 0: proto.ProtoControllerStateBatch.UpdateType
 	(*ProtoMouseMove)(nil),                    // 1: proto.ProtoMouseMove
@@ -1296,18 +3471,53 @@ var file_types_proto_goTypes = []any{
 	(*ProtoClientRequestRoomStream)(nil),      // 17: proto.ProtoClientRequestRoomStream
 	(*ProtoClientDisconnected)(nil),           // 18: proto.ProtoClientDisconnected
 	(*ProtoServerPushStream)(nil),             // 19: proto.ProtoServerPushStream
-	nil,                                       // 20: proto.ProtoControllerStateBatch.ButtonChangedMaskEntry
+	(*ProtoRoomSettings)(nil),                 // 20: proto.ProtoRoomSettings
+	(*ProtoRoomMetadata)(nil),                 // 21: proto.ProtoRoomMetadata
+	(*ProtoDirectoryQuery)(nil),               // 22: proto.ProtoDirectoryQuery
+	(*ProtoDirectoryRoom)(nil),                // 23: proto.ProtoDirectoryRoom
+	(*ProtoDirectoryResult)(nil),              // 24: proto.ProtoDirectoryResult
+	(*ProtoStreamPathInfo)(nil),               // 25: proto.ProtoStreamPathInfo
+	(*ProtoTrackStats)(nil),                   // 26: proto.ProtoTrackStats
+	(*ProtoStreamStats)(nil),                  // 27: proto.ProtoStreamStats
+	(*ProtoRelayNotice)(nil),                  // 28: proto.ProtoRelayNotice
+	(*ProtoSignalingProgress)(nil),            // 29: proto.ProtoSignalingProgress
+	(*ProtoMeshRoomTracks)(nil),               // 30: proto.ProtoMeshRoomTracks
+	(*ProtoRoomFull)(nil),                     // 31: proto.ProtoRoomFull
+	(*ProtoModeration)(nil),                   // 32: proto.ProtoModeration
+	(*ProtoChatMessage)(nil),                  // 33: proto.ProtoChatMessage
+	(*ProtoRoomVariant)(nil),                  // 34: proto.ProtoRoomVariant
+	(*ProtoRoomVariants)(nil),                 // 35: proto.ProtoRoomVariants
+	(*ProtoVariantSwitch)(nil),                // 36: proto.ProtoVariantSwitch
+	(*ProtoViewerCount)(nil),                  // 37: proto.ProtoViewerCount
+	(*ProtoThrottled)(nil),                    // 38: proto.ProtoThrottled
+	(*ProtoQuotaExceeded)(nil),                // 39: proto.ProtoQuotaExceeded
+	(*ProtoRelayOverload)(nil),                // 40: proto.ProtoRelayOverload
+	(*ProtoInvalidMessage)(nil),               // 41: proto.ProtoInvalidMessage
+	(*ProtoPushChallenge)(nil),                // 42: proto.ProtoPushChallenge
+	(*ProtoPushChallengeResponse)(nil),        // 43: proto.ProtoPushChallengeResponse
+	(*ProtoAudioLevel)(nil),                   // 44: proto.ProtoAudioLevel
+	(*ProtoBetterRelay)(nil),                  // 45: proto.ProtoBetterRelay
+	(*ProtoStreamClock)(nil),                  // 46: proto.ProtoStreamClock
+	(*ProtoInputLatency)(nil),                 // 47: proto.ProtoInputLatency
+	nil,                                       // 48: proto.ProtoControllerStateBatch.ButtonChangedMaskEntry
 }
 var file_types_proto_depIdxs = []int32{
 	0,  // 0: proto.ProtoControllerStateBatch.update_type:type_name -> proto.ProtoControllerStateBatch.UpdateType
-	20, // 1: proto.ProtoControllerStateBatch.button_changed_mask:type_name -> proto.ProtoControllerStateBatch.ButtonChangedMaskEntry
+	48, // 1: proto.ProtoControllerStateBatch.button_changed_mask:type_name -> proto.ProtoControllerStateBatch.ButtonChangedMaskEntry
 	12, // 2: proto.ProtoICE.candidate:type_name -> proto.RTCIceCandidateInit
 	13, // 3: proto.ProtoSDP.sdp:type_name -> proto.RTCSessionDescriptionInit
-	4,  // [4:4] is the sub-list for method output_type
-	4,  // [4:4] is the sub-list for method input_type
-	4,  // [4:4] is the sub-list for extension type_name
-	4,  // [4:4] is the sub-list for extension extendee
-	0,  // [0:4] is the sub-list for field type_name
+	20, // 4: proto.ProtoServerPushStream.settings:type_name -> proto.ProtoRoomSettings
+	21, // 5: proto.ProtoServerPushStream.metadata:type_name -> proto.ProtoRoomMetadata
+	21, // 6: proto.ProtoDirectoryRoom.metadata:type_name -> proto.ProtoRoomMetadata
+	23, // 7: proto.ProtoDirectoryResult.rooms:type_name -> proto.ProtoDirectoryRoom
+	26, // 8: proto.ProtoStreamStats.tracks:type_name -> proto.ProtoTrackStats
+	47, // 9: proto.ProtoStreamStats.input_latency:type_name -> proto.ProtoInputLatency
+	34, // 10: proto.ProtoRoomVariants.variants:type_name -> proto.ProtoRoomVariant
+	11, // [11:11] is the sub-list for method output_type
+	11, // [11:11] is the sub-list for method input_type
+	11, // [11:11] is the sub-list for extension type_name
+	11, // [11:11] is the sub-list for extension extendee
+	0,  // [0:11] is the sub-list for field type_name
 }
 
 func init() { file_types_proto_init() }
@@ -1323,7 +3533,7 @@ func file_types_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_types_proto_rawDesc), len(file_types_proto_rawDesc)),
 			NumEnums:      1,
-			NumMessages:   20,
+			NumMessages:   48,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -0,0 +1,130 @@
+// Package relaytest runs relays in-process over a libp2p mocknet with fake pushing nodes and viewers, so stream
+// request, push and mesh flows can be tested end to end without real libp2p sockets. Media still flows over
+// WebRTC on loopback.
+//
+//	h, err := relaytest.New(ctx, 2)
+//	...
+//	defer h.Close()
+//	pusher, err := h.Push(ctx, h.Relays[0], "room")
+//	err = h.WaitRoute(ctx, h.Relays[1], "room")
+//	viewer, err := h.View(ctx, h.Relays[1], "room")
+//	err = viewer.WaitMedia(ctx)
+package relaytest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"relay/internal/common"
+	"relay/internal/core"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+)
+
+// Harness is a set of relays connected to each other over a mocknet. Relays share process-wide flags, so only one
+// Harness may run at a time.
+type Harness struct {
+	Net    mocknet.Mocknet
+	Relays []*core.Relay
+
+	persistDir string
+	stop       context.CancelFunc
+}
+
+// New starts relays connected to each other, args are relay flags applied on top of harness defaults
+func New(ctx context.Context, relays int, args ...string) (*Harness, error) {
+	persistDir, err := os.MkdirTemp("", "relaytest-")
+	if err != nil {
+		return nil, err
+	}
+	// No STUN server, UDP mux or listeners, candidates are gathered on local interfaces only
+	defaults := []string{
+		"-persistDir", persistDir,
+		"-stunServer", "",
+		"-webrtcUDPMux", "0",
+		"-metrics=false",
+		"-adminPort", "0",
+		"-grpcPort", "0",
+		// Announce, ping, retry and sync often, so tests don't wait on production intervals
+		"-statusInterval", "250",
+		"-latencyPing", "250",
+		"-upstreamRetry", "100",
+		"-stateSync", "250",
+	}
+	if err = common.InitFlagsFromArgs(append(defaults, args...)); err != nil {
+		_ = os.RemoveAll(persistDir)
+		return nil, fmt.Errorf("failed to set flags: %w", err)
+	}
+	if err = common.InitWebRTCAPI(); err != nil {
+		_ = os.RemoveAll(persistDir)
+		return nil, fmt.Errorf("failed to set up WebRTC API: %w", err)
+	}
+
+	ctx, stop := context.WithCancel(ctx)
+	h := &Harness{
+		Net:        mocknet.New(),
+		persistDir: persistDir,
+		stop:       stop,
+	}
+	for range relays {
+		p2pHost, err := h.Net.GenPeer()
+		if err != nil {
+			_ = h.Close()
+			return nil, fmt.Errorf("failed to create mocknet peer: %w", err)
+		}
+		relay, err := core.NewRelayOnHost(ctx, p2pHost)
+		if err != nil {
+			_ = h.Close()
+			return nil, fmt.Errorf("failed to create relay: %w", err)
+		}
+		h.Relays = append(h.Relays, relay)
+	}
+	if err = h.Net.LinkAll(); err != nil {
+		_ = h.Close()
+		return nil, err
+	}
+	if err = h.Net.ConnectAllButSelf(); err != nil {
+		_ = h.Close()
+		return nil, err
+	}
+	return h, nil
+}
+
+// newPeer adds a client peer linked to every relay, it isn't connected until it dials
+func (h *Harness) newPeer() (host.Host, error) {
+	p2pHost, err := h.Net.GenPeer()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create mocknet peer: %w", err)
+	}
+	for _, relay := range h.Relays {
+		if _, err = h.Net.LinkPeers(p2pHost.ID(), relay.Host.ID()); err != nil {
+			return nil, err
+		}
+	}
+	return p2pHost, nil
+}
+
+// WaitRoute waits until relay learned from the mesh that another relay serves room, so its viewers are forwarded
+// the room instead of waiting for it to come online locally
+func (h *Harness) WaitRoute(ctx context.Context, relay *core.Relay, room string) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for relay.GetRemoteRoomByName(room) == nil {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// Close stops relays and removes their persisted state
+func (h *Harness) Close() error {
+	h.stop()
+	err := h.Net.Close()
+	_ = os.RemoveAll(h.persistDir)
+	return err
+}
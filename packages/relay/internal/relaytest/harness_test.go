@@ -0,0 +1,988 @@
+package relaytest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"relay/internal/common"
+	"relay/internal/core"
+	gen "relay/internal/proto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+// TestPushPullView pushes a room to one relay and views it there and, pulled over the mesh, on another
+func TestPushPullView(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	h, err := New(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	pusher, err := h.Push(ctx, h.Relays[0], "room")
+	if err != nil {
+		t.Fatalf("push failed: %v", err)
+	}
+	defer pusher.Close()
+
+	local, err := h.View(ctx, h.Relays[0], "room")
+	if err != nil {
+		t.Fatalf("view on pushed relay failed: %v", err)
+	}
+	defer local.Close()
+	if err = local.WaitMedia(ctx); err != nil {
+		t.Fatalf("no media on pushed relay: %v", err)
+	}
+
+	if err = h.WaitRoute(ctx, h.Relays[1], "room"); err != nil {
+		t.Fatalf("room wasn't announced to mesh: %v", err)
+	}
+	remote, err := h.View(ctx, h.Relays[1], "room")
+	if err != nil {
+		t.Fatalf("view on pulling relay failed: %v", err)
+	}
+	defer remote.Close()
+	if err = remote.WaitMedia(ctx); err != nil {
+		t.Fatalf("no media pulled over mesh: %v", err)
+	}
+	if audio, video := remote.Packets(); audio <= 0 || video <= 0 {
+		t.Fatalf("pulled media incomplete: %d audio and %d video packets", audio, video)
+	}
+}
+
+// TestConcurrentPull views a room not pulled yet from two viewers at once, the relay pulls it upstream only once
+func TestConcurrentPull(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	h, err := New(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	pusher, err := h.Push(ctx, h.Relays[0], "room")
+	if err != nil {
+		t.Fatalf("push failed: %v", err)
+	}
+	defer pusher.Close()
+	if err = h.WaitRoute(ctx, h.Relays[1], "room"); err != nil {
+		t.Fatalf("room wasn't announced to mesh: %v", err)
+	}
+
+	viewers := make([]*Viewer, 2)
+	errs := make(chan error, len(viewers))
+	for i := range viewers {
+		go func() {
+			var err error
+			viewers[i], err = h.View(ctx, h.Relays[1], "room")
+			errs <- err
+		}()
+	}
+	for range viewers {
+		if err = <-errs; err != nil {
+			t.Fatalf("view on pulling relay failed: %v", err)
+		}
+	}
+	for _, viewer := range viewers {
+		defer viewer.Close()
+		if err = viewer.WaitMedia(ctx); err != nil {
+			t.Fatalf("no media pulled over mesh: %v", err)
+		}
+	}
+
+	if pulls := h.Relays[0].GetRoomByName("room").ParticipantCount(); pulls != 1 {
+		t.Fatalf("room pulled %d times by relay", pulls)
+	}
+}
+
+// TestPullLinger stops pulling a room once its last viewer on the pulling relay left, over mesh links and dedicated streams
+func TestPullLinger(t *testing.T) {
+	for _, multiplex := range []string{"true", "false"} {
+		t.Run("meshMultiplex="+multiplex, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+
+			h, err := New(ctx, 2, "-pullLinger", "1", "-meshMultiplex="+multiplex)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer h.Close()
+
+			pusher, err := h.Push(ctx, h.Relays[0], "room")
+			if err != nil {
+				t.Fatalf("push failed: %v", err)
+			}
+			defer pusher.Close()
+			if err = h.WaitRoute(ctx, h.Relays[1], "room"); err != nil {
+				t.Fatalf("room wasn't announced to mesh: %v", err)
+			}
+			viewer, err := h.View(ctx, h.Relays[1], "room")
+			if err != nil {
+				t.Fatalf("view on pulling relay failed: %v", err)
+			}
+			if err = viewer.WaitMedia(ctx); err != nil {
+				t.Fatalf("no media pulled over mesh: %v", err)
+			}
+			viewer.Close()
+
+			pushed := h.Relays[0].GetRoomByName("room")
+			pulled := h.Relays[1].GetRoomByName("room")
+			ticker := time.NewTicker(10 * time.Millisecond)
+			defer ticker.Stop()
+			for pulled.IsOnline() || pushed.ParticipantCount() > 0 {
+				select {
+				case <-ctx.Done():
+					t.Fatalf("room still pulled after its last viewer left: online %t, upstream participants %d", pulled.IsOnline(), pushed.ParticipantCount())
+				case <-ticker.C:
+				}
+			}
+		})
+	}
+}
+
+// TestWaitRemoteRoom views a room before it's pushed to another relay, the room is pulled for the viewer once it comes online in the mesh
+func TestWaitRemoteRoom(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	h, err := New(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	viewer, err := h.View(ctx, h.Relays[1], "room")
+	if err != nil {
+		t.Fatalf("view on pulling relay failed: %v", err)
+	}
+	defer viewer.Close()
+
+	pusher, err := h.Push(ctx, h.Relays[0], "room")
+	if err != nil {
+		t.Fatalf("push failed: %v", err)
+	}
+	defer pusher.Close()
+	select {
+	case <-viewer.Done():
+		t.Fatalf("waiting view ended: %v", viewer.Err())
+	default:
+	}
+	if err = viewer.WaitMedia(ctx); err != nil {
+		t.Fatalf("no media pulled for waiting viewer: %v", err)
+	}
+}
+
+// TestUpstreamRepull breaks the upstream of a pulled room under a viewer, the room is pulled again and the viewer keeps getting media
+func TestUpstreamRepull(t *testing.T) {
+	for _, multiplex := range []string{"true", "false"} {
+		t.Run("meshMultiplex="+multiplex, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+
+			h, err := New(ctx, 2, "-meshMultiplex="+multiplex)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer h.Close()
+
+			pusher, err := h.Push(ctx, h.Relays[0], "room")
+			if err != nil {
+				t.Fatalf("push failed: %v", err)
+			}
+			defer pusher.Close()
+			if err = h.WaitRoute(ctx, h.Relays[1], "room"); err != nil {
+				t.Fatalf("room wasn't announced to mesh: %v", err)
+			}
+			viewer, err := h.View(ctx, h.Relays[1], "room")
+			if err != nil {
+				t.Fatalf("view on pulling relay failed: %v", err)
+			}
+			defer viewer.Close()
+			if err = viewer.WaitMedia(ctx); err != nil {
+				t.Fatalf("no media pulled over mesh: %v", err)
+			}
+
+			pulled := h.Relays[1].GetRoomByName("room")
+			upstream := pulled.PeerConnection()
+			if err = upstream.Close(); err != nil {
+				t.Fatal(err)
+			}
+			ticker := time.NewTicker(10 * time.Millisecond)
+			defer ticker.Stop()
+			for !pulled.IsOnline() || pulled.PeerConnection() == upstream {
+				select {
+				case <-ctx.Done():
+					t.Fatal("room wasn't pulled again after its upstream ended")
+				case <-ticker.C:
+				}
+			}
+			_, before := viewer.Packets()
+			for _, video := viewer.Packets(); video < before+20; _, video = viewer.Packets() {
+				select {
+				case <-ctx.Done():
+					t.Fatal("viewer got no media after room was pulled again")
+				case <-viewer.Done():
+					t.Fatalf("view ended when upstream ended: %v", viewer.Err())
+				case <-ticker.C:
+				}
+			}
+			if got := h.Relays[1].GetRoomByName("room"); got != pulled {
+				t.Fatal("room was replaced instead of pulled again")
+			}
+		})
+	}
+}
+
+// TestViewerDisconnect drops the libp2p connection of a viewer, its participant leaves the room without waiting for its PeerConnection to time out
+func TestViewerDisconnect(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	h, err := New(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	relay := h.Relays[0]
+
+	pusher, err := h.Push(ctx, relay, "room")
+	if err != nil {
+		t.Fatalf("push failed: %v", err)
+	}
+	defer pusher.Close()
+	viewer, err := h.View(ctx, relay, "room")
+	if err != nil {
+		t.Fatalf("view failed: %v", err)
+	}
+	defer viewer.Close()
+	if err = viewer.WaitMedia(ctx); err != nil {
+		t.Fatalf("no media: %v", err)
+	}
+
+	if err = viewer.Host.Close(); err != nil {
+		t.Fatal(err)
+	}
+	room := relay.GetRoomByName("room")
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for room.ParticipantCount() > 0 {
+		select {
+		case <-ctx.Done():
+			t.Fatal("participant of disconnected viewer stayed in room")
+		case <-ticker.C:
+		}
+	}
+}
+
+// TestRelayDisconnect drops the connection between a pulling and a serving relay, both tear down the stream between them
+// and the viewer of the pulling relay gets media again once the relays reconnect
+func TestRelayDisconnect(t *testing.T) {
+	for _, multiplex := range []string{"true", "false"} {
+		t.Run("meshMultiplex="+multiplex, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+
+			h, err := New(ctx, 2, "-meshMultiplex="+multiplex)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer h.Close()
+			serving, pulling := h.Relays[0], h.Relays[1]
+
+			pusher, err := h.Push(ctx, serving, "room")
+			if err != nil {
+				t.Fatalf("push failed: %v", err)
+			}
+			defer pusher.Close()
+			if err = h.WaitRoute(ctx, pulling, "room"); err != nil {
+				t.Fatalf("room wasn't announced to mesh: %v", err)
+			}
+			viewer, err := h.View(ctx, pulling, "room")
+			if err != nil {
+				t.Fatalf("view on pulling relay failed: %v", err)
+			}
+			defer viewer.Close()
+			if err = viewer.WaitMedia(ctx); err != nil {
+				t.Fatalf("no media pulled over mesh: %v", err)
+			}
+
+			if err = h.Net.UnlinkPeers(serving.ID, pulling.ID); err != nil {
+				t.Fatal(err)
+			}
+			if err = h.Net.DisconnectPeers(serving.ID, pulling.ID); err != nil {
+				t.Fatal(err)
+			}
+			pushed := serving.GetRoomByName("room")
+			pulled := pulling.GetRoomByName("room")
+			ticker := time.NewTicker(10 * time.Millisecond)
+			defer ticker.Stop()
+			for pulled.IsOnline() || pushed.ParticipantCount() > 0 {
+				select {
+				case <-ctx.Done():
+					t.Fatalf("stream between disconnected relays stayed up: pulled online %t, upstream participants %d", pulled.IsOnline(), pushed.ParticipantCount())
+				case <-ticker.C:
+				}
+			}
+			if pulled.ParticipantCount() != 1 {
+				t.Fatalf("viewer left pulled room of disconnected relay, participants %d", pulled.ParticipantCount())
+			}
+
+			if _, err = h.Net.LinkPeers(serving.ID, pulling.ID); err != nil {
+				t.Fatal(err)
+			}
+			if _, err = h.Net.ConnectPeers(serving.ID, pulling.ID); err != nil {
+				t.Fatal(err)
+			}
+			_, before := viewer.Packets()
+			for _, video := viewer.Packets(); video < before+20; _, video = viewer.Packets() {
+				select {
+				case <-ctx.Done():
+					t.Fatal("viewer got no media after relays reconnected")
+				case <-viewer.Done():
+					t.Fatalf("view ended when relays disconnected: %v", viewer.Err())
+				case <-ticker.C:
+				}
+			}
+		})
+	}
+}
+
+// TestLegacyProtocolVersion views a room over the oldest stream request protocol version next to a viewer of the latest
+func TestLegacyProtocolVersion(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	h, err := New(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	pusher, err := h.Push(ctx, h.Relays[0], "room")
+	if err != nil {
+		t.Fatalf("push failed: %v", err)
+	}
+	defer pusher.Close()
+
+	legacy, err := h.ViewVersion(ctx, h.Relays[0], "room", "/nestri-relay/stream-request/1.0.0", 1)
+	if err != nil {
+		t.Fatalf("legacy view failed: %v", err)
+	}
+	defer legacy.Close()
+	latest, err := h.View(ctx, h.Relays[0], "room")
+	if err != nil {
+		t.Fatalf("view failed: %v", err)
+	}
+	defer latest.Close()
+
+	for _, viewer := range []*Viewer{legacy, latest} {
+		if err = viewer.WaitMedia(ctx); err != nil {
+			t.Fatalf("no media: %v", err)
+		}
+	}
+	if announced := legacy.Relay(); !announced.Legacy() || len(announced.Capabilities) > 0 {
+		t.Fatalf("relay announced version %d with %v to legacy viewer", announced.Version, announced.Capabilities)
+	}
+	if announced := latest.Relay(); announced.Version != common.ProtocolVersion || !announced.Supports(common.CapabilityMeshLink) {
+		t.Fatalf("relay announced version %d with %v to viewer", announced.Version, announced.Capabilities)
+	}
+}
+
+// TestPushChallenge pushes with push keys, a room pushed with one trusted key can't be taken over with another
+func TestPushChallenge(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	keys := make([]ed25519.PrivateKey, 3)
+	for i := range keys {
+		var err error
+		if keys[i], err = common.GenerateED25519Key(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	trusted := hex.EncodeToString(keys[0].Public().(ed25519.PublicKey)) + "," + hex.EncodeToString(keys[1].Public().(ed25519.PublicKey))
+
+	h, err := New(ctx, 1, "-pushKeys", trusted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	relay := h.Relays[0]
+
+	pusher, err := h.PushWithKey(ctx, relay, "room", keys[0])
+	if err != nil {
+		t.Fatalf("push with trusted key failed: %v", err)
+	}
+	pusher.Close()
+
+	if _, err = h.PushWithKey(ctx, relay, "room", keys[1]); err == nil {
+		t.Fatal("room was taken over with another trusted key")
+	}
+	if _, err = h.PushWithKey(ctx, relay, "other", keys[2]); err == nil {
+		t.Fatal("push with untrusted key was accepted")
+	}
+	if _, err = h.Push(ctx, relay, "other"); err == nil {
+		t.Fatal("push without key was accepted")
+	}
+
+	// Bound key pushes its room again once the first push went offline
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for relay.GetRoomByName("room").IsOnline() {
+		select {
+		case <-ctx.Done():
+			t.Fatal("room stayed online after push ended")
+		case <-ticker.C:
+		}
+	}
+	pusher, err = h.PushWithKey(ctx, relay, "room", keys[0])
+	if err != nil {
+		t.Fatalf("push with bound key failed: %v", err)
+	}
+	pusher.Close()
+}
+
+// TestDrainRefusesPush refuses pushes to a draining relay instead of leaving pushing nodes waiting
+func TestDrainRefusesPush(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	h, err := New(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	h.Relays[0].SetDraining(true)
+	pushCtx, pushCancel := context.WithTimeout(ctx, 10*time.Second)
+	defer pushCancel()
+	if _, err = h.Push(pushCtx, h.Relays[0], "room"); err == nil || pushCtx.Err() != nil {
+		t.Fatalf("push to draining relay ended with %v, want refusal", err)
+	}
+}
+
+// TestStandbyFailover pushes a room twice, the standby push takes over for viewers once the pushed stream ends
+func TestStandbyFailover(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	h, err := New(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	relay := h.Relays[0]
+
+	primary, err := h.Push(ctx, relay, "room")
+	if err != nil {
+		t.Fatalf("push failed: %v", err)
+	}
+	defer primary.Close()
+	standby, err := h.PushStandby(ctx, relay, "room")
+	if err != nil {
+		t.Fatalf("standby push failed: %v", err)
+	}
+	defer standby.Close()
+
+	viewer, err := h.View(ctx, relay, "room")
+	if err != nil {
+		t.Fatalf("view failed: %v", err)
+	}
+	defer viewer.Close()
+	if err = viewer.WaitMedia(ctx); err != nil {
+		t.Fatalf("no media: %v", err)
+	}
+
+	primary.Close()
+	_, before := viewer.Packets()
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for _, video := viewer.Packets(); video < before+20; _, video = viewer.Packets() {
+		select {
+		case <-ctx.Done():
+			t.Fatal("viewer got no media from standby push")
+		case <-viewer.Done():
+			t.Fatalf("view ended on failover: %v", viewer.Err())
+		case <-ticker.C:
+		}
+	}
+	if room := relay.GetRoomByName("room"); !room.IsOnline() || room.HasStandby() {
+		t.Fatal("standby push didn't take over room")
+	}
+}
+
+// TestLinkProbe probes the mesh link between two relays, both measure capacity of the link from the other
+func TestLinkProbe(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	h, err := New(ctx, 2, "-linkProbe", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for i, relay := range h.Relays {
+		other := h.Relays[1-i]
+		for {
+			if probe, ok := relay.LinkProbe(other.ID); ok && probe.Throughput > 0 {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				t.Fatalf("relay %d never probed link from relay %d", i, 1-i)
+			case <-ticker.C:
+			}
+		}
+	}
+}
+
+// TestLatencyMatrix checks relays ping each other and see latencies the other relay measured,
+// those arrive with its relay status
+func TestLatencyMatrix(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	h, err := New(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	relay, other := h.Relays[0], h.Relays[1]
+	for {
+		matrix := relay.LatencyMatrix()
+		if matrix[relay.ID][other.ID] > 0 && matrix[other.ID][relay.ID] > 0 {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("latencies between relays never measured: %v", matrix)
+		case <-ticker.C:
+		}
+	}
+}
+
+// TestSnapshotRestore saves state of a relay with a pushed and viewed room, restoring it on a fresh relay brings
+// the room back offline under its ID and ownership epoch
+func TestSnapshotRestore(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	h, err := New(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	pusher, err := h.Push(ctx, h.Relays[0], "room")
+	if err != nil {
+		t.Fatalf("push failed: %v", err)
+	}
+	defer pusher.Close()
+	viewer, err := h.View(ctx, h.Relays[0], "room")
+	if err != nil {
+		t.Fatalf("view failed: %v", err)
+	}
+	defer viewer.Close()
+	if err = viewer.WaitMedia(ctx); err != nil {
+		t.Fatalf("no media: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	if err = h.Relays[0].TakeSnapshot(time.Now()).SaveToFile(path); err != nil {
+		t.Fatalf("saving snapshot failed: %v", err)
+	}
+	snap, err := core.LoadSnapshot(path)
+	if err != nil {
+		t.Fatalf("loading snapshot failed: %v", err)
+	}
+	if len(snap.Rooms) != 1 || len(snap.Rooms[0].Participants) != 1 || snap.Rooms[0].Epoch <= 0 {
+		t.Fatalf("snapshot rooms %+v, want pushed room with epoch and its viewer", snap.Rooms)
+	}
+
+	fresh, err := New(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fresh.Close()
+	relay := fresh.Relays[0]
+	relay.RestoreSnapshot(snap)
+	room := relay.GetRoomByName("room")
+	if room == nil {
+		t.Fatal("room wasn't restored")
+	}
+	if epoch, _ := room.GetClaim(); room.ID != snap.Rooms[0].ID || room.OwnerID != relay.ID || room.IsOnline() || epoch != snap.Rooms[0].Epoch {
+		t.Fatalf("restored room %s owned by %s online %t epoch %d, want %s owned by restoring relay offline with epoch %d",
+			room.ID, room.OwnerID, room.IsOnline(), epoch, snap.Rooms[0].ID, snap.Rooms[0].Epoch)
+	}
+
+	snap.Version++
+	if err = snap.SaveToFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = core.LoadSnapshot(path); err == nil {
+		t.Fatal("snapshot of unknown version was loaded")
+	}
+}
+
+// TestSQLiteStore saves peers and documents to a store and reads them back after reopening it
+func TestSQLiteStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "relay.db")
+	store, err := core.OpenSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("opening store failed: %v", err)
+	}
+
+	key, _, err := crypto.GenerateEd25519Key(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := peer.IDFromPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	peers := map[peer.ID]*core.PeerInfo{id: {ID: id, LastSeen: time.Now(), DialFailures: 2}}
+	if err = store.SavePeers(peers); err != nil {
+		t.Fatalf("saving peers failed: %v", err)
+	}
+	if err = store.PutDocument("usage", []byte(`{"a":1}`)); err != nil {
+		t.Fatalf("saving document failed: %v", err)
+	}
+	if err = store.PutDocument("usage", []byte(`{"a":2}`)); err != nil {
+		t.Fatalf("replacing document failed: %v", err)
+	}
+	if err = store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopening an up to date database migrates nothing
+	store, err = core.OpenSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("reopening store failed: %v", err)
+	}
+	defer store.Close()
+	loaded, err := store.LoadPeers()
+	if err != nil {
+		t.Fatalf("loading peers failed: %v", err)
+	}
+	if pi, ok := loaded[id]; !ok || pi.DialFailures != 2 {
+		t.Fatalf("loaded peers %v, want %s with 2 dial failures", loaded, id)
+	}
+	if data, err := store.GetDocument("usage"); err != nil || string(data) != `{"a":2}` {
+		t.Fatalf("document %q err %v, want replaced one", data, err)
+	}
+	if data, err := store.GetDocument("missing"); err != nil || data != nil {
+		t.Fatalf("missing document %q err %v, want nil", data, err)
+	}
+}
+
+// TestSharedState lets two relays outside one mesh share rooms, bans and sessions over one Redis
+func TestSharedState(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	redisServer := miniredis.RunT(t)
+	harnesses := make([]*Harness, 0, 2)
+	relays := make([]*core.Relay, 0, 2)
+	states := make([]*core.RedisState, 0, 2)
+	for range 2 {
+		h, err := New(ctx, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer h.Close()
+		state, err := core.OpenRedisState(ctx, "redis://"+redisServer.Addr(), "test:")
+		if err != nil {
+			t.Fatalf("connecting to Redis failed: %v", err)
+		}
+		defer state.Close()
+		h.Relays[0].ConnectSharedState(ctx, state)
+		harnesses = append(harnesses, h)
+		relays = append(relays, h.Relays[0])
+		states = append(states, state)
+	}
+
+	pusher, err := harnesses[0].Push(ctx, relays[0], "room")
+	if err != nil {
+		t.Fatalf("push failed: %v", err)
+	}
+	defer pusher.Close()
+
+	// Rooms reach the other relay's directory on its next sync
+	for listed := false; !listed; {
+		for _, room := range relays[1].QueryRooms(&gen.ProtoDirectoryQuery{}).Rooms {
+			listed = listed || room.Name == "room"
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatal("room of other relay never listed in directory")
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+
+	if _, err = relays[0].BanFromRoom("room", "session", "", time.Minute, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if _, banned := relays[1].RoomBanned("room", "session", ""); !banned {
+		t.Fatal("ban issued on other relay not enforced")
+	}
+
+	if err = states[0].PutSession(ctx, core.SnapshotSession{SessionID: "resumed", Left: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if _, found, err := states[1].TakeSession(ctx, "resumed"); err != nil || !found {
+		t.Fatalf("session found %t err %v, want session put by other relay", found, err)
+	}
+	if _, found, _ := states[0].TakeSession(ctx, "resumed"); found {
+		t.Fatal("session resumed twice")
+	}
+}
+
+// TestDirectoryQuery pages through rooms of a relay over the directory protocol and fails queries answered with a reset
+func TestDirectoryQuery(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	h, err := New(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	for _, name := range []string{"room-c", "room-a", "other", "room-b"} {
+		pusher, err := h.Push(ctx, h.Relays[0], name)
+		if err != nil {
+			t.Fatalf("push of %s failed: %v", name, err)
+		}
+		defer pusher.Close()
+	}
+
+	// Pages follow the cursor of the previous page in name order, rooms not matching the prefix are left out
+	var names []string
+	query := &gen.ProtoDirectoryQuery{Prefix: "room-", Limit: 2}
+	for pages := 1; ; pages++ {
+		result, err := h.Relays[1].DirectoryProtocol.QueryDirectory(ctx, h.Relays[0].ID, query)
+		if err != nil {
+			t.Fatalf("directory query failed: %v", err)
+		}
+		if len(result.Rooms) > 2 {
+			t.Fatalf("page of %d rooms, want at most the limit of 2", len(result.Rooms))
+		}
+		for _, room := range result.Rooms {
+			if room.OwnerId != h.Relays[0].ID.String() || !room.Online || room.Metadata.GetTitle() != "relaytest" {
+				t.Fatalf("room %s owner %s online %t metadata %v, want online pushed room", room.Name, room.OwnerId, room.Online, room.Metadata)
+			}
+			names = append(names, room.Name)
+		}
+		if len(result.NextCursor) <= 0 {
+			if pages != 2 {
+				t.Fatalf("%d pages, want 2", pages)
+			}
+			break
+		}
+		query.Cursor = result.NextCursor
+	}
+	if got := strings.Join(names, ","); got != "room-a,room-b,room-c" {
+		t.Fatalf("directory listed %s, want room-a,room-b,room-c", got)
+	}
+
+	// A peer not speaking the directory protocol, then one resetting the stream after reading the query
+	fake, err := h.newPeer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = fake.Connect(ctx, peer.AddrInfo{ID: h.Relays[1].ID, Addrs: h.Relays[1].Host.Addrs()}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = h.Relays[1].DirectoryProtocol.QueryDirectory(ctx, fake.ID(), &gen.ProtoDirectoryQuery{}); err == nil {
+		t.Fatal("directory query to peer without directory protocol succeeded")
+	}
+	received := make(chan *gen.ProtoDirectoryQuery, 1)
+	fake.SetStreamHandler("/nestri-relay/directory/1.1.0", func(stream network.Stream) {
+		safeBRW := common.NewSafeBufioRW(bufio.NewReadWriter(bufio.NewReader(stream), bufio.NewWriter(stream)))
+		var msg gen.ProtoMessage
+		if err := safeBRW.ReceiveProto(&msg); err == nil {
+			received <- msg.GetDirectoryQuery()
+		}
+		_ = stream.Reset()
+	})
+	if _, err = h.Relays[1].DirectoryProtocol.QueryDirectory(ctx, fake.ID(), &gen.ProtoDirectoryQuery{Prefix: "room-", Cursor: "cm9vbS1h"}); err == nil {
+		t.Fatal("directory query answered with a stream reset succeeded")
+	}
+	select {
+	case got := <-received:
+		if got.GetPrefix() != "room-" || got.GetCursor() != "cm9vbS1h" {
+			t.Fatalf("peer received query prefix %q cursor %q", got.GetPrefix(), got.GetCursor())
+		}
+	case <-ctx.Done():
+		t.Fatal("peer never received the directory query")
+	}
+}
+
+// memDirectory is a room directory backend in memory
+type memDirectory struct {
+	mtx   sync.Mutex
+	rooms map[string]core.DirectoryRoom
+}
+
+func (d *memDirectory) Lookup(_ context.Context, roomName string) (core.DirectoryRoom, bool, error) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	room, ok := d.rooms[roomName]
+	return room, ok, nil
+}
+
+func (d *memDirectory) Claim(_ context.Context, roomName string, owner peer.ID, minEpoch uint64) (core.DirectoryRoom, error) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	room, ok := d.rooms[roomName]
+	if !ok {
+		return core.DirectoryRoom{}, errors.New("room not registered")
+	}
+	room.OwnerID, room.Epoch = owner, max(room.Epoch+1, minEpoch)
+	d.rooms[roomName] = room
+	return room, nil
+}
+
+func (d *memDirectory) Release(_ context.Context, roomName string, owner peer.ID) error {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	if room, ok := d.rooms[roomName]; ok && room.OwnerID == owner {
+		room.OwnerID = ""
+		d.rooms[roomName] = room
+	}
+	return nil
+}
+
+func (d *memDirectory) Close() error {
+	return nil
+}
+
+// TestRoomDirectory serves only rooms registered in a room directory backend, under epochs it issues
+func TestRoomDirectory(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	h, err := New(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	relay := h.Relays[0]
+	directory := &memDirectory{rooms: map[string]core.DirectoryRoom{
+		"room": {Name: "room", Epoch: 41, Private: true},
+	}}
+	relay.UseRoomDirectory(directory)
+
+	if _, err = h.Push(ctx, relay, "unregistered"); err == nil {
+		t.Fatal("push to room missing from room directory was accepted")
+	}
+
+	pusher, err := h.Push(ctx, relay, "room")
+	if err != nil {
+		t.Fatalf("push failed: %v", err)
+	}
+	room := relay.GetRoomByName("room")
+	if epoch, _ := room.GetClaim(); epoch != 42 || !room.GetMetadata().Private {
+		t.Fatalf("room epoch %d private %t, want epoch issued by directory and private", epoch, room.GetMetadata().Private)
+	}
+	if entry, _, _ := directory.Lookup(ctx, "room"); entry.OwnerID != relay.ID {
+		t.Fatalf("directory owner %s, want pushed relay %s", entry.OwnerID, relay.ID)
+	}
+
+	pusher.Close()
+	for {
+		if entry, _, _ := directory.Lookup(ctx, "room"); len(entry.OwnerID) <= 0 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatal("room wasn't released in directory after push ended")
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// fakeDecoder passes keyframes through as thumbnails
+type fakeDecoder struct{}
+
+func (fakeDecoder) Decode(_ context.Context, mimeType string, frame []byte) (core.Thumbnail, error) {
+	if !strings.EqualFold(mimeType, "video/H264") || !bytes.HasPrefix(frame, []byte{0, 0, 0, 1, 0x65}) {
+		return core.Thumbnail{}, fmt.Errorf("unexpected %s keyframe %x", mimeType, frame[:min(len(frame), 8)])
+	}
+	return core.Thumbnail{Data: frame, ContentType: "application/octet-stream"}, nil
+}
+
+// TestThumbnails grabs keyframes of a pushed room, serves the latest and announces its URL in room metadata
+func TestThumbnails(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	core.RegisterThumbnailDecoder("fake", func(string, int) (core.ThumbnailDecoder, error) {
+		return fakeDecoder{}, nil
+	})
+	h, err := New(ctx, 1, "-thumbnailInterval", "1", "-thumbnailPort", "0", "-thumbnailURL", "http://thumbs.test", "-thumbnailDecoder", "fake")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	relay := h.Relays[0]
+
+	pusher, err := h.Push(ctx, relay, "room")
+	if err != nil {
+		t.Fatalf("push failed: %v", err)
+	}
+	defer pusher.Close()
+
+	room := relay.GetRoomByName("room")
+	for room.GetMetadata().ThumbnailURL != "http://thumbs.test/thumbnails/room" {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("room thumbnail URL %q wasn't announced", room.GetMetadata().ThumbnailURL)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	relay.ThumbnailHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/thumbnails/room", nil))
+	if rec.Code != http.StatusOK || !bytes.HasPrefix(rec.Body.Bytes(), []byte{0, 0, 0, 1, 0x65}) {
+		t.Fatalf("thumbnail response %d of %d bytes, want the decoded keyframe", rec.Code, rec.Body.Len())
+	}
+	rec = httptest.NewRecorder()
+	relay.ThumbnailHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/thumbnails/other", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("thumbnail of unknown room answered %d, want 404", rec.Code)
+	}
+
+	// Thumbnails of rooms made private aren't served anymore
+	meta := room.GetMetadata()
+	meta.Private = true
+	room.SetMetadata(meta)
+	rec = httptest.NewRecorder()
+	relay.ThumbnailHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/thumbnails/room", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("thumbnail of private room answered %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,278 @@
+package relaytest
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"relay/internal/common"
+	"relay/internal/core"
+	"sync/atomic"
+	"time"
+
+	gen "relay/internal/proto"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/pion/webrtc/v4"
+	"github.com/pion/webrtc/v4/pkg/media"
+)
+
+// Fake media is sent at these intervals, video frames are single IDR slices so every one is a keyframe
+const (
+	fakeFrameInterval = 50 * time.Millisecond
+	fakeAudioInterval = 20 * time.Millisecond
+)
+
+// fakeFrame is an Annex B IDR slice of filler bytes, it isn't decodable but the relay only inspects NAL headers
+var fakeFrame = append([]byte{0, 0, 0, 1, 0x65}, make([]byte, 1200)...)
+
+// fakeAudioFrame is a 20ms Opus frame decoding to silence
+var fakeAudioFrame = []byte{0xf8, 0xff, 0xfe}
+
+// Pusher is a fake pushing node sending generated media to a room through the push protocol
+type Pusher struct {
+	Host host.Host
+
+	pc      *webrtc.PeerConnection
+	key     ed25519.PrivateKey // Push key challenges are signed with, nil if the pusher has none
+	relayID peer.ID
+	done    chan struct{}
+	err     atomic.Pointer[error]
+	cancel  context.CancelFunc
+}
+
+// Push starts pushing fake media to room on relay from a new mocknet peer, returning once the relay forwards it
+func (h *Harness) Push(ctx context.Context, relay *core.Relay, room string) (*Pusher, error) {
+	return h.PushWithKey(ctx, relay, room, nil)
+}
+
+// PushWithKey is Push answering push challenges of the relay with key
+func (h *Harness) PushWithKey(ctx context.Context, relay *core.Relay, room string, key ed25519.PrivateKey) (*Pusher, error) {
+	return h.push(ctx, relay, room, key, false)
+}
+
+// PushStandby starts a standby push to online room on relay, returning once it's connected and ready to take over
+func (h *Harness) PushStandby(ctx context.Context, relay *core.Relay, room string) (*Pusher, error) {
+	return h.push(ctx, relay, room, nil, true)
+}
+
+func (h *Harness) push(ctx context.Context, relay *core.Relay, room string, key ed25519.PrivateKey, standby bool) (*Pusher, error) {
+	p2pHost, err := h.newPeer()
+	if err != nil {
+		return nil, err
+	}
+	stream, safeBRW, err := openSignaling(ctx, p2pHost, relay.Host, core.ProtocolStreamPush)
+	if err != nil {
+		return nil, err
+	}
+
+	// Metadata makes the relay announce the room to the mesh right away
+	push := &gen.ProtoServerPushStream{RoomName: room, Metadata: &gen.ProtoRoomMetadata{Title: "relaytest"}, Standby: standby}
+	if secret := common.GetFlags().PushSecretFor(room); len(secret) > 0 {
+		push.Timestamp = time.Now().Unix()
+		push.Signature = common.SignPush(secret, room, push.Timestamp)
+	}
+	pushMsg, err := common.CreateMessage(push, "push-stream-room", nil)
+	if err != nil {
+		_ = stream.Reset()
+		return nil, err
+	}
+	if err = safeBRW.SendProto(pushMsg); err != nil {
+		_ = stream.Reset()
+		return nil, fmt.Errorf("failed to send push request: %w", err)
+	}
+
+	pc, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	if err != nil {
+		_ = stream.Reset()
+		return nil, err
+	}
+	videoTrack, err := webrtc.NewTrackLocalStaticSample(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeH264}, "video", "relaytest")
+	if err != nil {
+		_ = pc.Close()
+		_ = stream.Reset()
+		return nil, err
+	}
+	audioTrack, err := webrtc.NewTrackLocalStaticSample(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus}, "audio", "relaytest")
+	if err != nil {
+		_ = pc.Close()
+		_ = stream.Reset()
+		return nil, err
+	}
+	for _, track := range []webrtc.TrackLocal{videoTrack, audioTrack} {
+		sender, err := pc.AddTrack(track)
+		if err != nil {
+			_ = pc.Close()
+			_ = stream.Reset()
+			return nil, err
+		}
+		// RTCP is read only so interceptors keep working
+		go func() {
+			buf := make([]byte, 1500)
+			for {
+				if _, _, err := sender.Read(buf); err != nil {
+					return
+				}
+			}
+		}()
+	}
+	if _, err = pc.CreateDataChannel("relay-data", nil); err != nil {
+		_ = pc.Close()
+		_ = stream.Reset()
+		return nil, err
+	}
+	sendCandidates(pc, safeBRW)
+
+	mediaCtx, cancel := context.WithCancel(context.Background())
+	p := &Pusher{
+		Host:    p2pHost,
+		pc:      pc,
+		key:     key,
+		relayID: relay.ID,
+		done:    make(chan struct{}),
+		cancel:  cancel,
+	}
+	go func() {
+		defer close(p.done)
+		defer stream.Close()
+		defer pc.Close()
+		err := p.signal(safeBRW)
+		p.err.Store(&err)
+		cancel()
+	}()
+	go writeFakeMedia(mediaCtx, videoTrack, audioTrack)
+
+	// Viewers are only offered tracks the relay already knows codecs of, wait for media of both to reach it
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return nil, p.Err()
+		case <-ctx.Done():
+			p.Close()
+			return nil, ctx.Err()
+		case <-ticker.C:
+			r := relay.GetRoomByName(room)
+			if r == nil {
+				continue
+			}
+			if standby {
+				if r.HasStandby() && pc.ConnectionState() == webrtc.PeerConnectionStateConnected {
+					return p, nil
+				}
+			} else if r.AudioStats.Snapshot().Packets > 0 && r.VideoStats.Snapshot().Packets > 0 {
+				return p, nil
+			}
+		}
+	}
+}
+
+// signal sends the offer once the relay accepted the push and applies its answer and candidates until the stream ends
+func (p *Pusher) signal(safeBRW *common.SafeBufioRW) error {
+	iceHelper := common.NewICEHelper(p.pc)
+	for {
+		var msgWrapper gen.ProtoMessage
+		err := safeBRW.ReceiveProto(&msgWrapper)
+		if errors.Is(err, common.ErrMessageRejected) {
+			continue
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return errors.New("push stream closed by relay")
+			}
+			return err
+		}
+		if msgWrapper.MessageBase == nil {
+			continue
+		}
+
+		switch payloadType := msgWrapper.MessageBase.PayloadType; payloadType {
+		case "push-challenge":
+			challenge := msgWrapper.GetPushChallenge()
+			if challenge == nil || p.key == nil {
+				return errors.New("push challenged without push key")
+			}
+			resMsg, err := common.CreateMessage(&gen.ProtoPushChallengeResponse{
+				RoomName:  challenge.RoomName,
+				PublicKey: hex.EncodeToString(p.key.Public().(ed25519.PublicKey)),
+				Signature: common.SignPushChallenge(p.key, p.relayID.String(), challenge.RoomName, challenge.Nonce),
+			}, "push-challenge-response", nil)
+			if err != nil {
+				return err
+			}
+			if err = safeBRW.SendProto(resMsg); err != nil {
+				return err
+			}
+		case "push-stream-ok":
+			offer, err := p.pc.CreateOffer(nil)
+			if err != nil {
+				return err
+			}
+			if err = p.pc.SetLocalDescription(offer); err != nil {
+				return err
+			}
+			if err = sendSDP(safeBRW, offer, "offer"); err != nil {
+				return err
+			}
+		case "push-stream-unauthorized":
+			return errors.New(payloadType)
+		case "answer":
+			answerMsg := msgWrapper.GetSdp()
+			if answerMsg == nil || answerMsg.Sdp == nil {
+				continue
+			}
+			if err = p.pc.SetRemoteDescription(webrtc.SessionDescription{
+				SDP:  answerMsg.Sdp.Sdp,
+				Type: webrtc.NewSDPType(answerMsg.Sdp.Type),
+			}); err != nil {
+				return err
+			}
+			iceHelper.FlushHeldCandidates()
+		case "ice-candidate":
+			addCandidate(iceHelper, &msgWrapper)
+		}
+	}
+}
+
+// writeFakeMedia writes fake video frames and silent audio in real time until ctx is done
+func writeFakeMedia(ctx context.Context, videoTrack, audioTrack *webrtc.TrackLocalStaticSample) {
+	videoTicker := time.NewTicker(fakeFrameInterval)
+	defer videoTicker.Stop()
+	audioTicker := time.NewTicker(fakeAudioInterval)
+	defer audioTicker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-videoTicker.C:
+			_ = videoTrack.WriteSample(media.Sample{Data: fakeFrame, Duration: fakeFrameInterval})
+		case <-audioTicker.C:
+			_ = audioTrack.WriteSample(media.Sample{Data: fakeAudioFrame, Duration: fakeAudioInterval})
+		}
+	}
+}
+
+// Done is closed once the push ended
+func (p *Pusher) Done() <-chan struct{} {
+	return p.done
+}
+
+// Err returns why the push ended, nil while it runs
+func (p *Pusher) Err() error {
+	if err := p.err.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
+
+// Close ends the push, the room goes offline as for a pushing node that disconnected
+func (p *Pusher) Close() {
+	p.cancel()
+	_ = p.pc.Close()
+	_ = p.Host.Close()
+	<-p.done
+}
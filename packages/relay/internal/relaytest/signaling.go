@@ -0,0 +1,84 @@
+package relaytest
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"relay/internal/common"
+	gen "relay/internal/proto"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	"github.com/pion/webrtc/v4"
+)
+
+// openSignaling connects p2pHost to relay and opens a signaling stream of protocol to it
+func openSignaling(ctx context.Context, p2pHost host.Host, relay host.Host, proto protocol.ID) (network.Stream, *common.SafeBufioRW, error) {
+	if err := p2pHost.Connect(ctx, peer.AddrInfo{ID: relay.ID(), Addrs: relay.Addrs()}); err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to relay: %w", err)
+	}
+	stream, err := p2pHost.NewStream(ctx, relay.ID(), proto)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open stream: %w", err)
+	}
+	return stream, common.NewSafeBufioRW(bufio.NewReadWriter(bufio.NewReader(stream), bufio.NewWriter(stream))), nil
+}
+
+// sendCandidates sends local ICE candidates of pc over safeBRW as they are gathered
+func sendCandidates(pc *webrtc.PeerConnection, safeBRW *common.SafeBufioRW) {
+	pc.OnICECandidate(func(candidate *webrtc.ICECandidate) {
+		if candidate == nil {
+			return
+		}
+		candInit := candidate.ToJSON()
+		var sdpMLineIndex *uint32
+		if candInit.SDPMLineIndex != nil {
+			idx := uint32(*candInit.SDPMLineIndex)
+			sdpMLineIndex = &idx
+		}
+		iceMsg, err := common.CreateMessage(&gen.ProtoICE{
+			Candidate: &gen.RTCIceCandidateInit{
+				Candidate:     candInit.Candidate,
+				SdpMLineIndex: sdpMLineIndex,
+				SdpMid:        candInit.SDPMid,
+			},
+		}, "ice-candidate", nil)
+		if err == nil {
+			_ = safeBRW.SendProto(iceMsg)
+		}
+	})
+}
+
+// addCandidate adds remote ICE candidate of an "ice-candidate" message, held until remote description is set
+func addCandidate(iceHelper *common.ICEHelper, msg *gen.ProtoMessage) {
+	iceMsg := msg.GetIce()
+	if iceMsg == nil || iceMsg.Candidate == nil {
+		return
+	}
+	cand := webrtc.ICECandidateInit{
+		Candidate:        iceMsg.Candidate.Candidate,
+		SDPMid:           iceMsg.Candidate.SdpMid,
+		UsernameFragment: iceMsg.Candidate.UsernameFragment,
+	}
+	if iceMsg.Candidate.SdpMLineIndex != nil {
+		idx := uint16(*iceMsg.Candidate.SdpMLineIndex)
+		cand.SDPMLineIndex = &idx
+	}
+	iceHelper.AddCandidate(cand)
+}
+
+// sendSDP sends a session description as message of payloadType, "offer" or "answer"
+func sendSDP(safeBRW *common.SafeBufioRW, desc webrtc.SessionDescription, payloadType string) error {
+	sdpMsg, err := common.CreateMessage(&gen.ProtoSDP{
+		Sdp: &gen.RTCSessionDescriptionInit{
+			Sdp:  desc.SDP,
+			Type: desc.Type.String(),
+		},
+	}, payloadType, nil)
+	if err != nil {
+		return err
+	}
+	return safeBRW.SendProto(sdpMsg)
+}
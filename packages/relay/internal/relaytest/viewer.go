@@ -0,0 +1,210 @@
+package relaytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"relay/internal/common"
+	"relay/internal/core"
+	"sync"
+	"sync/atomic"
+
+	gen "relay/internal/proto"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	"github.com/pion/webrtc/v4"
+)
+
+// Viewer is a fake viewer requesting a room through the stream request protocol and counting received media
+type Viewer struct {
+	Host host.Host
+
+	mtx    sync.Mutex // Guards pc and closed, offers come from the signaling goroutine
+	pc     *webrtc.PeerConnection
+	closed bool
+
+	audioPackets atomic.Uint64
+	videoPackets atomic.Uint64
+	media        chan struct{} // Closed once packets of both kinds arrived
+	mediaOnce    sync.Once
+
+	safeBRW *common.SafeBufioRW
+	done    chan struct{}
+	err     atomic.Pointer[error]
+}
+
+// View requests room from relay as a new mocknet peer, media arrives once the relay offered its stream
+func (h *Harness) View(ctx context.Context, relay *core.Relay, room string) (*Viewer, error) {
+	return h.ViewVersion(ctx, relay, room, core.ProtocolStreamRequest, common.ProtocolVersion)
+}
+
+// ViewVersion is View over a given stream request protocol ID, speaking given message protocol version
+func (h *Harness) ViewVersion(ctx context.Context, relay *core.Relay, room string, proto protocol.ID, version uint32) (*Viewer, error) {
+	p2pHost, err := h.newPeer()
+	if err != nil {
+		return nil, err
+	}
+	stream, safeBRW, err := openSignaling(ctx, p2pHost, relay.Host, proto)
+	if err != nil {
+		return nil, err
+	}
+	safeBRW.SetMessageVersion(version)
+	reqMsg, err := common.CreateMessage(&gen.ProtoClientRequestRoomStream{RoomName: room}, "request-stream-room", nil)
+	if err != nil {
+		_ = stream.Reset()
+		return nil, err
+	}
+	if err = safeBRW.SendProto(reqMsg); err != nil {
+		_ = stream.Reset()
+		return nil, fmt.Errorf("failed to send stream request: %w", err)
+	}
+
+	v := &Viewer{
+		Host:    p2pHost,
+		media:   make(chan struct{}),
+		safeBRW: safeBRW,
+		done:    make(chan struct{}),
+	}
+	go func() {
+		defer close(v.done)
+		defer stream.Close()
+		err := v.signal(safeBRW)
+		v.err.Store(&err)
+	}()
+	return v, nil
+}
+
+// Relay returns protocol version and capabilities the relay announced on the stream request
+func (v *Viewer) Relay() common.Announcement {
+	return v.safeBRW.Peer()
+}
+
+// signal handles messages of the stream request until it's refused or closed, requests of offline rooms wait for them
+func (v *Viewer) signal(safeBRW *common.SafeBufioRW) error {
+	iceHelper := common.NewICEHelper(nil)
+	for {
+		var msgWrapper gen.ProtoMessage
+		err := safeBRW.ReceiveProto(&msgWrapper)
+		if errors.Is(err, common.ErrMessageRejected) {
+			continue
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return errors.New("stream request closed by relay")
+			}
+			return err
+		}
+		if msgWrapper.MessageBase == nil {
+			continue
+		}
+
+		switch payloadType := msgWrapper.MessageBase.PayloadType; payloadType {
+		case "request-stream-over-budget", "request-stream-draining",
+			"request-stream-unauthorized", "request-stream-banned", "room-full", "quota-exceeded", "throttled":
+			return errors.New(payloadType)
+		case "ice-candidate":
+			addCandidate(iceHelper, &msgWrapper)
+		case "offer":
+			offerMsg := msgWrapper.GetSdp()
+			if offerMsg == nil || offerMsg.Sdp == nil {
+				continue
+			}
+			if err = v.answer(safeBRW, iceHelper, offerMsg.Sdp); err != nil {
+				return fmt.Errorf("failed to answer offer: %w", err)
+			}
+		}
+	}
+}
+
+// answer answers an offer of the relay, renegotiation offers reuse the PeerConnection
+func (v *Viewer) answer(safeBRW *common.SafeBufioRW, iceHelper *common.ICEHelper, offer *gen.RTCSessionDescriptionInit) error {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	if v.closed {
+		return errors.New("viewer closed")
+	}
+	if v.pc == nil {
+		pc, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+		if err != nil {
+			return err
+		}
+		v.pc = pc
+		iceHelper.SetPeerConnection(pc)
+		sendCandidates(pc, safeBRW)
+		pc.OnTrack(func(remoteTrack *webrtc.TrackRemote, _ *webrtc.RTPReceiver) {
+			packets := &v.videoPackets
+			if remoteTrack.Kind() == webrtc.RTPCodecTypeAudio {
+				packets = &v.audioPackets
+			}
+			for {
+				if _, _, err := remoteTrack.ReadRTP(); err != nil {
+					return
+				}
+				packets.Add(1)
+				if v.audioPackets.Load() > 0 && v.videoPackets.Load() > 0 {
+					v.mediaOnce.Do(func() { close(v.media) })
+				}
+			}
+		})
+	}
+
+	if err := v.pc.SetRemoteDescription(webrtc.SessionDescription{
+		SDP:  offer.Sdp,
+		Type: webrtc.NewSDPType(offer.Type),
+	}); err != nil {
+		return err
+	}
+	iceHelper.FlushHeldCandidates()
+	answer, err := v.pc.CreateAnswer(nil)
+	if err != nil {
+		return err
+	}
+	if err = v.pc.SetLocalDescription(answer); err != nil {
+		return err
+	}
+	return sendSDP(safeBRW, answer, "answer")
+}
+
+// WaitMedia waits until both audio and video packets were received, returning early if the request ended
+func (v *Viewer) WaitMedia(ctx context.Context) error {
+	select {
+	case <-v.media:
+		return nil
+	case <-v.done:
+		return fmt.Errorf("stream request ended before media: %w", v.Err())
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Packets returns RTP packets received so far of audio and video
+func (v *Viewer) Packets() (audio, video uint64) {
+	return v.audioPackets.Load(), v.videoPackets.Load()
+}
+
+// Done is closed once the stream request ended
+func (v *Viewer) Done() <-chan struct{} {
+	return v.done
+}
+
+// Err returns why the stream request ended, such as the payload type refusing it, nil while it runs
+func (v *Viewer) Err() error {
+	if err := v.err.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
+
+// Close leaves the room
+func (v *Viewer) Close() {
+	v.mtx.Lock()
+	v.closed = true
+	if v.pc != nil {
+		_ = v.pc.Close()
+	}
+	v.mtx.Unlock()
+	_ = v.Host.Close()
+	<-v.done
+}
@@ -0,0 +1,151 @@
+package shared
+
+import (
+	"relay/internal/common"
+
+	"golang.org/x/time/rate"
+)
+
+// RoomBudget limits resources one Room may use on this relay so it can't starve other rooms, 0 is unlimited
+type RoomBudget struct {
+	Goroutines  int // Participant writers, RTCP readers and DataChannel message handlers
+	Tracks      int // Local tracks allocated for participants
+	QueuedBytes int // Payload bytes queued for participants
+	MsgRate     int // DataChannel messages handled per second, from participants and the pushing node
+}
+
+// Budget resolves resource budget of the Room, per-room config of its main room overrides relay-wide limits
+func (r *Room) Budget() RoomBudget {
+	flags := common.GetFlags()
+	budget := RoomBudget{
+		Goroutines:  flags.RoomGoroutines,
+		Tracks:      flags.RoomTracks,
+		QueuedBytes: flags.RoomQueuedKB * 1024,
+		MsgRate:     flags.RoomMsgRate,
+	}
+	cfg, ok := flags.RoomDefaults(BaseRoomName(r.Name))
+	if !ok {
+		return budget
+	}
+	if cfg.Goroutines > 0 {
+		budget.Goroutines = cfg.Goroutines
+	}
+	if cfg.Tracks > 0 {
+		budget.Tracks = cfg.Tracks
+	}
+	if cfg.QueuedKB > 0 {
+		budget.QueuedBytes = cfg.QueuedKB * 1024
+	}
+	if cfg.MsgRate > 0 {
+		budget.MsgRate = cfg.MsgRate
+	}
+	return budget
+}
+
+// RoomUsage is what a Room currently uses of its budget
+type RoomUsage struct {
+	Goroutines  int `json:"goroutines"`
+	Tracks      int `json:"tracks"`
+	QueuedBytes int `json:"queued_bytes"`
+}
+
+// Usage returns resources the Room currently uses
+func (r *Room) Usage() RoomUsage {
+	return RoomUsage{
+		Goroutines:  int(r.goroutines.Load()),
+		Tracks:      int(r.tracks.Load()),
+		QueuedBytes: int(r.queuedBytes.Load()),
+	}
+}
+
+// participantCost is what one Participant charges against the budget of its Room
+type participantCost struct {
+	goroutines int64
+	tracks     int64
+}
+
+// newParticipantCost returns cost of a Participant with given tracks, writing its own queue unless pooled
+func newParticipantCost(tracks int, pooled bool) participantCost {
+	// Every track has its own RTCP reader
+	cost := participantCost{goroutines: int64(tracks), tracks: int64(tracks)}
+	if !pooled {
+		cost.goroutines++
+	}
+	return cost
+}
+
+// cost returns what Participant charges against its Room, tracks must be set before adding it to one
+func (p *Participant) cost() participantCost {
+	tracks := 0
+	if p.AudioTrack != nil {
+		tracks++
+	}
+	if p.VideoTrack != nil {
+		tracks++
+	}
+	return newParticipantCost(tracks, p.shard != nil)
+}
+
+// AdmitsParticipant returns false if a new Participant would exceed goroutine or track budget of the Room
+func (r *Room) AdmitsParticipant() bool {
+	budget := r.Budget()
+	tracks := 2
+	if r.GetSettings().AudioOnly {
+		tracks = 1
+	}
+	cost := newParticipantCost(tracks, getWriterPool() != nil)
+	if budget.Goroutines > 0 && r.goroutines.Load()+cost.goroutines > int64(budget.Goroutines) {
+		return false
+	}
+	if budget.Tracks > 0 && r.tracks.Load()+cost.tracks > int64(budget.Tracks) {
+		return false
+	}
+	return true
+}
+
+// charge adds cost of Participant to the Room usage, called with participantsMtx held
+func (r *Room) charge(participant *Participant) {
+	participant.charged = participant.cost()
+	r.goroutines.Add(participant.charged.goroutines)
+	r.tracks.Add(participant.charged.tracks)
+}
+
+// refund removes what Participant was charged from the Room usage, called with participantsMtx held
+func (r *Room) refund(participant *Participant) {
+	r.goroutines.Add(-participant.charged.goroutines)
+	r.tracks.Add(-participant.charged.tracks)
+	participant.charged = participantCost{}
+}
+
+// AdmitMessage reserves a goroutine to handle one DataChannel message of the Room, done must be called once handled.
+// Messages over the rate or goroutine budget are dropped.
+func (r *Room) AdmitMessage() (func(), bool) {
+	budget := r.Budget()
+	if budget.MsgRate > 0 {
+		// Budget may change on config reload
+		if limit := rate.Limit(budget.MsgRate); r.messages.Limit() != limit {
+			r.messages.SetLimit(limit)
+			r.messages.SetBurst(budget.MsgRate)
+		}
+		if !r.messages.Allow() {
+			r.droppedMessages.Add(1)
+			return nil, false
+		}
+	}
+	// Handlers are counted without a goroutine budget too, so usage is right once one is configured
+	if n := r.goroutines.Add(1); budget.Goroutines > 0 && n > int64(budget.Goroutines) {
+		r.goroutines.Add(-1)
+		r.droppedMessages.Add(1)
+		return nil, false
+	}
+	return func() { r.goroutines.Add(-1) }, true
+}
+
+// AdmitMessage applies the budget of the Room Participant receives to one of its DataChannel messages
+func (p *Participant) AdmitMessage() (func(), bool) {
+	room := p.Room()
+	if room == nil {
+		return func() {}, true
+	}
+	return room.AdmitMessage()
+}
@@ -0,0 +1,198 @@
+package shared
+
+import (
+	"log/slog"
+	"relay/internal/common"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v4"
+)
+
+// fanoutShardBacklog is how many packets a fan-out shard may lag behind ingest before its participants miss packets
+const fanoutShardBacklog = 512
+
+var fanoutPacketPool = sync.Pool{
+	New: func() interface{} {
+		return &fanoutPacket{}
+	},
+}
+
+// fanoutPacket is an incoming packet prepared once for every participant of a Room. Shards and packets queued
+// for participants share it without modifying it, the last one done with it returns it to pool.
+type fanoutPacket struct {
+	kind       webrtc.RTPCodecType
+	packet     rtp.Packet // Header without ingest extensions, payload shared with ingest
+	audioLevel []byte     // ssrc-audio-level payload, nil if packet carries none
+	keyframe   bool
+	limits     queueLimits
+	maxQueued  int64 // Queued bytes budget of the room, 0 is unlimited
+	queued     time.Time
+	refs       atomic.Int32
+}
+
+// newFanoutPacket prepares pkt for fan-out, the header is copied so ingest may move on while shards distribute it
+func (r *Room) newFanoutPacket(kind webrtc.RTPCodecType, pkt *rtp.Packet, refs int) *fanoutPacket {
+	fp := fanoutPacketPool.Get().(*fanoutPacket)
+	fp.kind = kind
+	// Extension IDs of the ingest connection mean nothing on participant connections, writers only add theirs
+	fp.packet.Header = pkt.Header
+	fp.packet.Extension = false
+	fp.packet.Extensions = nil
+	fp.packet.Payload = pkt.Payload
+	fp.packet.PaddingSize = pkt.PaddingSize
+	fp.audioLevel = nil
+	if kind == webrtc.RTPCodecTypeAudio {
+		fp.audioLevel = r.readAudioLevel(pkt)
+	}
+	// Keyframes are only looked for when a policy resumes video at them
+	fp.limits = r.queueLimits()
+	fp.keyframe = kind == webrtc.RTPCodecTypeVideo && fp.limits.policy == DropKeyframe && common.IsKeyframe(r.VideoCodec().MimeType, pkt.Payload)
+	fp.maxQueued = int64(r.Budget().QueuedBytes)
+	fp.queued = time.Now()
+	fp.refs.Store(int32(refs))
+	return fp
+}
+
+// release drops one reference to packet, returning it to pool once no shard or participant uses it
+func (fp *fanoutPacket) release() {
+	if fp.refs.Add(-1) > 0 {
+		return
+	}
+	fp.packet = rtp.Packet{}
+	fp.audioLevel = nil
+	fanoutPacketPool.Put(fp)
+}
+
+// fanoutShard distributes packets of a large Room to its share of participants on its own goroutine
+type fanoutShard struct {
+	packets      chan *fanoutPacket
+	participants atomic.Pointer[[]*Participant]
+	done         chan struct{}
+	mtx          sync.RWMutex // Held for reading while sending to packets, for writing while stopping
+	stopped      bool
+}
+
+func newFanoutShard(r *Room) *fanoutShard {
+	s := &fanoutShard{
+		packets: make(chan *fanoutPacket, fanoutShardBacklog),
+		done:    make(chan struct{}),
+	}
+	empty := make([]*Participant, 0)
+	s.participants.Store(&empty)
+	go s.run(r)
+	return s
+}
+
+func (s *fanoutShard) run(r *Room) {
+	for {
+		select {
+		case <-s.done:
+			s.drain()
+			return
+		case fp := <-s.packets:
+			r.distribute(*s.participants.Load(), fp)
+			fp.release()
+		}
+	}
+}
+
+// send queues fp for shard, false if shard lags too far behind ingest or was stopped
+func (s *fanoutShard) send(fp *fanoutPacket) bool {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	if s.stopped {
+		return false
+	}
+	select {
+	case s.packets <- fp:
+		return true
+	default:
+		return false
+	}
+}
+
+// stop ends shard goroutine, which releases packets still queued for it
+func (s *fanoutShard) stop() {
+	s.mtx.Lock()
+	s.stopped = true
+	s.mtx.Unlock()
+	close(s.done)
+}
+
+// drain releases packets queued for a stopped shard, nothing more is queued once it's stopped
+func (s *fanoutShard) drain() {
+	for {
+		select {
+		case fp := <-s.packets:
+			fp.release()
+		default:
+			return
+		}
+	}
+}
+
+// rebalanceFanout splits participants of a Room over fan-out shards once it has more than one shard worth of them,
+// participants keep their shard while it runs so they get packets in order. Called with participantsMtx held.
+func (r *Room) rebalanceFanout() {
+	var shards []*fanoutShard
+	if current := r.fanoutShards.Load(); current != nil {
+		shards = slices.Clone(*current)
+	}
+	participants := *r.participantQueues.Load()
+
+	want := 0
+	if size := common.GetFlags().FanoutShard; size > 0 && len(participants) > size {
+		want = (len(participants) + size - 1) / size
+	}
+	if want == 0 && len(shards) == 0 {
+		return
+	}
+	if want != len(shards) {
+		slog.Debug("Resizing room fan-out", "room", r.Name, "participants", len(participants), "from", len(shards), "to", want)
+	}
+	for len(shards) > want {
+		shards[len(shards)-1].stop()
+		shards = shards[:len(shards)-1]
+	}
+	for len(shards) < want {
+		shards = append(shards, newFanoutShard(r))
+	}
+
+	// Keep participants on their shard, newcomers and those of stopped shards go to the smallest ones
+	members := make([][]*Participant, len(shards))
+	var moved []*Participant
+	for _, participant := range participants {
+		if i := slices.Index(shards, participant.fanoutShard); i >= 0 {
+			members[i] = append(members[i], participant)
+		} else {
+			moved = append(moved, participant)
+		}
+	}
+	for _, participant := range moved {
+		participant.fanoutShard = nil
+		if len(shards) <= 0 {
+			continue
+		}
+		smallest := 0
+		for i := range members {
+			if len(members[i]) < len(members[smallest]) {
+				smallest = i
+			}
+		}
+		participant.fanoutShard = shards[smallest]
+		members[smallest] = append(members[smallest], participant)
+	}
+	for i, shard := range shards {
+		shard.participants.Store(&members[i])
+	}
+
+	if len(shards) <= 0 {
+		r.fanoutShards.Store(nil)
+		return
+	}
+	r.fanoutShards.Store(&shards)
+}
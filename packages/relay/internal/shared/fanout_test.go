@@ -0,0 +1,126 @@
+package shared
+
+import (
+	"fmt"
+	"relay/internal/common"
+	"sync"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/pion/webrtc/v4"
+)
+
+// setFanoutShard sets participants per fan-out shard for rooms created afterwards, 0 disables sharding
+func setFanoutShard(tb testing.TB, size int) {
+	tb.Helper()
+	if err := common.InitFlagsFromArgs([]string{"-fanoutShard", fmt.Sprint(size)}); err != nil {
+		tb.Fatal(err)
+	}
+}
+
+// newTestRoom creates a room with participants whose writers discard packets, as they have no tracks
+func newTestRoom(tb testing.TB, participants int) (*Room, []*Participant) {
+	tb.Helper()
+	room := NewRoom("test", ulid.Make(), "")
+	added := make([]*Participant, 0, participants)
+	for range participants {
+		participant, err := NewParticipant("", "", room.ParticipantQueueSize(), nil)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		room.AddParticipant(participant)
+		added = append(added, participant)
+	}
+	tb.Cleanup(func() {
+		for _, participant := range added {
+			room.RemoveParticipantByID(participant.ID)
+			participant.Close()
+		}
+	})
+	return room, added
+}
+
+// TestBroadcastToClosedParticipants removes and closes participants while packets are fanned out to them,
+// lagging shards still holding them must not send to their closed queues
+func TestBroadcastToClosedParticipants(t *testing.T) {
+	setFanoutShard(t, 4)
+	room, participants := newTestRoom(t, 64)
+	pkt := benchPacket()
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for range 4 {
+		wg.Go(func() {
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					room.BroadcastPacket(webrtc.RTPCodecTypeAudio, pkt)
+				}
+			}
+		})
+	}
+	for _, participant := range participants {
+		room.RemoveParticipantByID(participant.ID)
+		participant.Close()
+	}
+	close(stop)
+	wg.Wait()
+
+	if n := len(room.GetParticipants()); n != 0 {
+		t.Fatalf("room still has %d participants", n)
+	}
+}
+
+// TestStoppedShardReleasesPackets stops a shard with packets still queued, they must go back to the pool and
+// nothing more may be queued
+func TestStoppedShardReleasesPackets(t *testing.T) {
+	room := NewRoom("test", ulid.Make(), "")
+	shard := &fanoutShard{packets: make(chan *fanoutPacket, fanoutShardBacklog), done: make(chan struct{})}
+	empty := make([]*Participant, 0)
+	shard.participants.Store(&empty)
+
+	queued := make([]*fanoutPacket, 0, 8)
+	for range 8 {
+		fp := room.newFanoutPacket(webrtc.RTPCodecTypeAudio, benchPacket(), 1)
+		if !shard.send(fp) {
+			t.Fatal("send to running shard failed")
+		}
+		queued = append(queued, fp)
+	}
+	shard.stop()
+	shard.run(room)
+
+	if n := len(shard.packets); n != 0 {
+		t.Fatalf("%d packets left queued on stopped shard", n)
+	}
+	for i, fp := range queued {
+		if refs := fp.refs.Load(); refs != 0 {
+			t.Fatalf("packet %d still has %d references", i, refs)
+		}
+	}
+	fp := room.newFanoutPacket(webrtc.RTPCodecTypeAudio, benchPacket(), 1)
+	if shard.send(fp) {
+		t.Fatal("packet queued on stopped shard")
+	}
+	fp.release()
+}
+
+// BenchmarkBroadcastPacket fans packets out to rooms of growing size, with and without fan-out shards
+func BenchmarkBroadcastPacket(b *testing.B) {
+	for _, shard := range []int{0, 128} {
+		for _, participants := range []int{16, 256, 1024} {
+			b.Run(fmt.Sprintf("shard=%d/participants=%d", shard, participants), func(b *testing.B) {
+				setFanoutShard(b, shard)
+				room, _ := newTestRoom(b, participants)
+				pkt := benchPacket()
+				b.ReportAllocs()
+				for b.Loop() {
+					room.BroadcastPacket(webrtc.RTPCodecTypeAudio, pkt)
+				}
+				b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*participants), "ns/participant")
+			})
+		}
+	}
+}
@@ -8,50 +8,133 @@ import (
 	"relay/internal/common"
 	"relay/internal/connections"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/oklog/ulid/v2"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
 	"github.com/pion/webrtc/v4"
 )
 
+const (
+	defaultPacketQueueSize   = 1000 // Room with video, sized for keyframe bursts
+	audioOnlyPacketQueueSize = 100  // Opus at 20ms frames is ~50 packets/s, ~2s of audio
+
+	rtpReorderWindow = 100  // Packets this far behind the newest of a source are reordered, further back the source restarted numbering
+	rtpRestartGap    = 3000 // Packets this far ahead of the newest of a source mean the source restarted numbering
+)
+
+// ViewerRole is what a Participant may do in its room, granted by its viewer token
+type ViewerRole string
+
+const (
+	RoleViewer ViewerRole = "viewer" // Watch only
+	RolePlayer ViewerRole = "player" // Watch and send input
+)
+
+// ParseViewerRole returns known role with given name
+func ParseViewerRole(name string) (ViewerRole, bool) {
+	switch role := ViewerRole(name); role {
+	case RoleViewer, RolePlayer:
+		return role, true
+	default:
+		return "", false
+	}
+}
+
+// ControllerSlot addresses a controller a session attached, slots are numbered by the viewer
+type ControllerSlot struct {
+	SessionID string
+	Slot      int32
+}
+
 type Participant struct {
 	ID             ulid.ULID
 	SessionID      string  // Track session for reconnection
 	PeerID         peer.ID // libp2p peer ID
 	PeerConnection *webrtc.PeerConnection
-	DataChannel    *connections.NestriDataChannel
+	dataChannel    atomic.Pointer[connections.NestriDataChannel] // Set once signaling opened it, read by broadcasts
+	releasePC      func()                                        // Set when PeerConnection is shared with other participants, called instead of closing it
 
 	// Per-viewer tracks and channels
 	VideoTrack *webrtc.TrackLocalStaticRTP
 	AudioTrack *webrtc.TrackLocalStaticRTP
 
-	// Per-viewer RTP state for retiming
-	VideoSequenceNumber uint16
-	VideoTimestamp      uint32
-	AudioSequenceNumber uint16
-	AudioTimestamp      uint32
+	// Strict latency, video frames older than this since ingest are dropped whole, 0 disables
+	MaxVideoAge time.Duration
+
+	// Viewer consented to being moved into encoder experiments
+	ExperimentOptIn bool
+
+	// Granted by viewer token, RolePlayer when viewer authorization is disabled, set with SetRole
+	Role ViewerRole
+
+	// Viewer token subject, empty when viewer authorization is disabled
+	Identity string
 
+	// Called once the first complete frame was written, set before adding to a Room
+	OnFirstFrame func()
+
+	audioSender *webrtc.RTPSender
+	videoSender *webrtc.RTPSender
+	extensions  atomic.Pointer[participantExtensions] // Header extensions negotiated by this viewer
+
+	// Per-viewer RTP state for retiming, numbering of newest audio and video packets written
+	sent          [2]rtpSent
+	videoTSOffset atomic.Uint32 // Added to video timestamps of the current source, mirrors the writer's rebase
+
+	room        atomic.Pointer[Room] // Room currently feeding this participant, nil when not in any
 	packetQueue chan *participantPacket
-	closeOnce   sync.Once
+	writer      packetWriterState
+	shard       *writerShard // Pool shard writing packetQueue, nil when Participant runs its own writer goroutine
+	scheduled   atomic.Bool  // Waiting for or being serviced by shard
+	shedding    atomic.Bool  // Video is being dropped until the queue drains to the room low watermark
+	queueDelay  atomic.Int64 // Smoothed packet queueing delay in nanoseconds
+	rtt         atomic.Int64 // Round trip time from last receiver report in nanoseconds
+	queueMtx    sync.RWMutex // Held for reading while sending to packetQueue, for writing while closing it
+	queueClosed bool
+
+	droppedFrames atomic.Uint64
+	bytesSent     atomic.Uint64 // RTP payload bytes written to this participant
+	meter         *ByteMeter    // Traffic of the participant's peer, shared with its other participants and streams
+
+	inputAllowed atomic.Bool      // Input of this participant is forwarded upstream, defaults from Role
+	droppedInput atomic.Uint64    // Input messages dropped as not allowed
+	inputLatency LatencyHistogram // Round trips of input from the relay to the server and back
+
+	controllerMtx   sync.Mutex
+	controllerSlots map[ControllerSlot]struct{} // Controllers attached through this participant, several sessions' for mesh relays
+
+	charged     participantCost // Charged against budget of the room, guarded by its participantsMtx
+	fanoutShard *fanoutShard    // Shard of the room fanning packets out to this participant, guarded by its participantsMtx
 }
 
-func NewParticipant(sessionID string, peerID peer.ID) (*Participant, error) {
+type participantExtensions struct {
+	audio common.NegotiatedExtensions
+	video common.NegotiatedExtensions
+}
+
+// NewParticipant creates a Participant, meter counts what is sent to it towards its peer and may be nil
+func NewParticipant(sessionID string, peerID peer.ID, queueSize int, meter *ByteMeter) (*Participant, error) {
 	id, err := common.NewULID()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create ULID for Participant: %w", err)
 	}
 	p := &Participant{
-		ID:                  id,
-		SessionID:           sessionID,
-		PeerID:              peerID,
-		VideoSequenceNumber: 0,
-		VideoTimestamp:      0,
-		AudioSequenceNumber: 0,
-		AudioTimestamp:      0,
-		packetQueue:         make(chan *participantPacket, 1000),
+		ID:          id,
+		SessionID:   sessionID,
+		PeerID:      peerID,
+		packetQueue: make(chan *participantPacket, queueSize),
+		meter:       meter,
 	}
 
-	go p.packetWriter()
+	if pool := getWriterPool(); pool != nil {
+		p.shard = pool.assign()
+	} else {
+		go p.packetWriter()
+	}
 
 	return p, nil
 }
@@ -61,66 +144,484 @@ func (p *Participant) SetTrack(trackType webrtc.RTPCodecType, track *webrtc.Trac
 	switch trackType {
 	case webrtc.RTPCodecTypeAudio:
 		p.AudioTrack = track
-		_, err := p.PeerConnection.AddTrack(track)
+		sender, err := p.PeerConnection.AddTrack(track)
 		if err != nil {
 			slog.Error("Failed to add audio track", "participant", p.ID, "err", err)
 		}
+		p.audioSender = sender
+		go p.rtcpReader(sender)
 	case webrtc.RTPCodecTypeVideo:
 		p.VideoTrack = track
-		_, err := p.PeerConnection.AddTrack(track)
+		sender, err := p.PeerConnection.AddTrack(track)
 		if err != nil {
 			slog.Error("Failed to add video track", "participant", p.ID, "err", err)
 		}
+		p.videoSender = sender
+		go p.rtcpReader(sender)
 	default:
 		slog.Warn("Unknown track type", "participant", p.ID, "trackType", trackType)
 	}
 }
 
-// Close cleans up participant resources
+// SetSharedPeerConnection sets a PeerConnection carrying other participants too, on Close release is called instead of closing it
+func (p *Participant) SetSharedPeerConnection(pc *webrtc.PeerConnection, release func()) {
+	p.PeerConnection = pc
+	p.releasePC = release
+}
+
+// AudioSender returns RTP sender of Participant audio track, nil if not set
+func (p *Participant) AudioSender() *webrtc.RTPSender {
+	return p.audioSender
+}
+
+// VideoSender returns RTP sender of Participant video track, nil if not set
+func (p *Participant) VideoSender() *webrtc.RTPSender {
+	return p.videoSender
+}
+
+// Room returns the room Participant currently receives from, nil if not added to any
+func (p *Participant) Room() *Room {
+	return p.room.Load()
+}
+
+// UpdateExtensions reads header extensions negotiated by Participant, call once negotiation completes
+func (p *Participant) UpdateExtensions() {
+	exts := &participantExtensions{
+		audio: common.GetNegotiatedExtensions(p.PeerConnection, p.audioSender),
+		video: common.GetNegotiatedExtensions(p.PeerConnection, p.videoSender),
+	}
+	p.extensions.Store(exts)
+	slog.Debug("Negotiated header extensions", "participant", p.ID, "audio", exts.audio, "video", exts.video)
+}
+
+// QueueDelay returns the smoothed time packets spend queued before being written to Participant
+func (p *Participant) QueueDelay() time.Duration {
+	return time.Duration(p.queueDelay.Load())
+}
+
+// RTT returns round trip time to Participant from its last RTCP receiver report, 0 if not known yet
+func (p *Participant) RTT() time.Duration {
+	return time.Duration(p.rtt.Load())
+}
+
+// DroppedFrames returns how many late video frames were dropped for Participant in strict latency mode
+func (p *Participant) DroppedFrames() uint64 {
+	return p.droppedFrames.Load()
+}
+
+// BytesSent returns RTP payload bytes written to Participant
+func (p *Participant) BytesSent() uint64 {
+	return p.bytesSent.Load()
+}
+
+// SetRole sets role granted to Participant, resetting its input permission to what the role allows
+func (p *Participant) SetRole(role ViewerRole) {
+	p.Role = role
+	p.inputAllowed.Store(role == RolePlayer)
+}
+
+// SetInputAllowed grants or revokes input permission of Participant, overriding its role
+func (p *Participant) SetInputAllowed(allowed bool) {
+	p.inputAllowed.Store(allowed)
+}
+
+// InputAllowed returns true if input of Participant is forwarded upstream
+func (p *Participant) InputAllowed() bool {
+	return p.inputAllowed.Load()
+}
+
+// AcceptInput checks if an input message of Participant may be forwarded, counting dropped ones
+func (p *Participant) AcceptInput() bool {
+	if p.inputAllowed.Load() {
+		return true
+	}
+	p.droppedInput.Add(1)
+	return false
+}
+
+// DroppedInput returns how many input messages of Participant were dropped as not allowed
+func (p *Participant) DroppedInput() uint64 {
+	return p.droppedInput.Load()
+}
+
+// ObserveInputLatency counts a measured round trip of input of Participant
+func (p *Participant) ObserveInputLatency(latency time.Duration) {
+	p.inputLatency.Observe(latency)
+}
+
+// InputLatency returns input round trips measured for Participant
+func (p *Participant) InputLatency() LatencySnapshot {
+	return p.inputLatency.Snapshot()
+}
+
+// ClaimControllerSlot records a controller attached through Participant, so feedback for it is sent here
+func (p *Participant) ClaimControllerSlot(slot ControllerSlot) {
+	p.controllerMtx.Lock()
+	defer p.controllerMtx.Unlock()
+	if p.controllerSlots == nil {
+		p.controllerSlots = make(map[ControllerSlot]struct{})
+	}
+	p.controllerSlots[slot] = struct{}{}
+}
+
+// ReleaseControllerSlot forgets a controller detached through Participant
+func (p *Participant) ReleaseControllerSlot(slot ControllerSlot) {
+	p.controllerMtx.Lock()
+	defer p.controllerMtx.Unlock()
+	delete(p.controllerSlots, slot)
+}
+
+// OwnsControllerSlot returns whether a controller was attached through Participant
+func (p *Participant) OwnsControllerSlot(slot ControllerSlot) bool {
+	p.controllerMtx.Lock()
+	defer p.controllerMtx.Unlock()
+	_, ok := p.controllerSlots[slot]
+	return ok
+}
+
+// OwnsControllerSession returns whether any controller of a session was attached through Participant
+func (p *Participant) OwnsControllerSession(sessionID string) bool {
+	p.controllerMtx.Lock()
+	defer p.controllerMtx.Unlock()
+	for slot := range p.controllerSlots {
+		if slot.SessionID == sessionID {
+			return true
+		}
+	}
+	return false
+}
+
+// ControllerSlots returns controllers attached through Participant
+func (p *Participant) ControllerSlots() []ControllerSlot {
+	p.controllerMtx.Lock()
+	defer p.controllerMtx.Unlock()
+	slots := make([]ControllerSlot, 0, len(p.controllerSlots))
+	for slot := range p.controllerSlots {
+		slots = append(slots, slot)
+	}
+	return slots
+}
+
+// DataChannel returns DataChannel of Participant, nil until it's opened or after Close
+func (p *Participant) DataChannel() *connections.NestriDataChannel {
+	return p.dataChannel.Load()
+}
+
+// SetDataChannel sets DataChannel of Participant
+func (p *Participant) SetDataChannel(ndc *connections.NestriDataChannel) {
+	p.dataChannel.Store(ndc)
+}
+
+// Close cleans up participant resources, only the first call does as a viewer may be dropped from several places at once
 func (p *Participant) Close() {
-	p.closeOnce.Do(func() {
+	p.queueMtx.Lock()
+	first := !p.queueClosed
+	if first {
+		p.queueClosed = true
 		close(p.packetQueue)
-	})
-	if p.DataChannel != nil {
-		err := p.DataChannel.Close()
+	}
+	p.queueMtx.Unlock()
+	if !first {
+		return
+	}
+	if dc := p.dataChannel.Swap(nil); dc != nil {
+		err := dc.Close()
 		if err != nil {
 			slog.Error("Failed to close DataChannel", "participant", p.ID, "err", err)
 		}
-		p.DataChannel = nil
 	}
 	if p.PeerConnection != nil {
-		err := p.PeerConnection.Close()
-		if err != nil {
+		if p.releasePC != nil {
+			p.releasePC()
+			p.releasePC = nil
+		} else if err := p.PeerConnection.Close(); err != nil {
 			slog.Error("Failed to close PeerConnection", "participant", p.ID, "err", err)
 		}
 		p.PeerConnection = nil
 	}
-	if p.VideoTrack != nil {
-		p.VideoTrack = nil
-	}
-	if p.AudioTrack != nil {
-		p.AudioTrack = nil
-	}
 }
 
 func (p *Participant) packetWriter() {
 	for pkt := range p.packetQueue {
-		var track *webrtc.TrackLocalStaticRTP
+		p.writePacket(pkt)
+	}
+}
+
+// packetWriterState is kept across packets by whichever goroutine writes packets of a Participant
+type packetWriterState struct {
+	// Frame being dropped, decided at first packet of each frame so frames are dropped whole
+	frameTS   uint32
+	frameSeen bool
+	dropFrame bool
+
+	captureTS      [2]uint32
+	capturePayload [2][]byte
+
+	// Source of each kind, a new SSRC (room switched to a quality variant or its upstream) or a source restarting
+	// its numbering is rebased onto the last sent sequence number and timestamp
+	rebase [2]rtpRebase
+
+	// Copy of the shared packet being written, numbering and extensions are rewritten on it
+	packet rtp.Packet
 
-		// No mutex needed - only this goroutine modifies these
-		if pkt.kind == webrtc.RTPCodecTypeAudio {
-			track = p.AudioTrack
+	firstFrame bool
+}
+
+// writePacket writes a queued packet to Participant, only ever called by one goroutine at a time
+func (p *Participant) writePacket(pkt *participantPacket) {
+	w := &p.writer
+	shared := pkt.shared
+	if shared.kind == webrtc.RTPCodecTypeVideo && p.MaxVideoAge > 0 {
+		if !w.frameSeen || shared.packet.Timestamp != w.frameTS {
+			w.frameTS = shared.packet.Timestamp
+			w.frameSeen = true
+			w.dropFrame = time.Since(shared.queued) > p.MaxVideoAge
+			if w.dropFrame {
+				p.droppedFrames.Add(1)
+			}
+		}
+		if w.dropFrame {
+			if room := p.room.Load(); room != nil {
+				room.droppedLate.Add(1)
+			}
+			pkt.release()
+			return
+		}
+	}
+
+	var track *webrtc.TrackLocalStaticRTP
+
+	// No mutex needed - only the writing goroutine modifies these
+	if shared.kind == webrtc.RTPCodecTypeAudio {
+		track = p.AudioTrack
+	} else {
+		track = p.VideoTrack
+	}
+
+	if track != nil {
+		// Header is copied into our own packet, keeping its extension array, payload is shared with other participants
+		packet := &w.packet
+		extensions := packet.Extensions[:0]
+		packet.Header = shared.packet.Header
+		packet.Extensions = extensions
+		packet.Payload = shared.packet.Payload
+		packet.PaddingSize = shared.packet.PaddingSize
+		i := 0
+		if shared.kind == webrtc.RTPCodecTypeVideo {
+			i = 1
+		}
+		rb := &w.rebase[i]
+		newest := true
+		if packet.SSRC != rb.ssrc || !rb.seen || rtpRestarted(rb.newestSeq, packet.SequenceNumber) {
+			if lastSeq, lastTS, lastAt := p.sent[i].load(); rb.seen && !lastAt.IsZero() {
+				step := uint32(time.Since(lastAt).Seconds() * float64(track.Codec().ClockRate))
+				rb.seqOffset = lastSeq + 1 - packet.SequenceNumber
+				rb.tsOffset = lastTS + max(step, 1) - packet.Timestamp
+			}
+			rb.ssrc, rb.seen, rb.newestSeq = packet.SSRC, true, packet.SequenceNumber
+			if i == 1 {
+				p.videoTSOffset.Store(rb.tsOffset)
+			}
+		} else if int16(packet.SequenceNumber-rb.newestSeq) > 0 {
+			rb.newestSeq = packet.SequenceNumber
 		} else {
-			track = p.VideoTrack
+			newest = false // Reordered or repeated, numbering sent stays at the newest packet
 		}
 
-		if track != nil {
-			if err := track.WriteRTP(pkt.packet); err != nil && !errors.Is(err, io.ErrClosedPipe) {
-				slog.Error("WriteRTP failed", "participant", p.ID, "kind", pkt.kind, "err", err)
+		packet.SequenceNumber += rb.seqOffset
+		packet.Timestamp += rb.tsOffset
+
+		exts := p.kindExtensions(shared.kind)
+		if exts.Any() {
+			if exts.PlayoutDelay != 0 {
+				if err := packet.SetExtension(exts.PlayoutDelay, common.PlayoutDelayPayload); err != nil {
+					slog.Error("Failed to set playout-delay extension", "participant", p.ID, "err", err)
+				}
+			}
+			if exts.AbsCaptureTime != 0 {
+				// Ingest time stands in for capture time, marshalled once per frame
+				if w.capturePayload[i] == nil || w.captureTS[i] != packet.Timestamp {
+					w.captureTS[i] = packet.Timestamp
+					w.capturePayload[i], _ = rtp.NewAbsCaptureTimeExtension(shared.queued).Marshal()
+				}
+				if err := packet.SetExtension(exts.AbsCaptureTime, w.capturePayload[i]); err != nil {
+					slog.Error("Failed to set abs-capture-time extension", "participant", p.ID, "err", err)
+				}
+			}
+			if exts.AudioLevel != 0 && len(shared.audioLevel) > 0 {
+				if err := packet.SetExtension(exts.AudioLevel, shared.audioLevel); err != nil {
+					slog.Error("Failed to set audio-level extension", "participant", p.ID, "err", err)
+				}
+			}
+			if exts.AbsSendTime != 0 {
+				// Stamped as late as we can, viewers estimate bandwidth from send time deltas
+				payload, _ := rtp.NewAbsSendTimeExtension(time.Now()).Marshal()
+				if err := packet.SetExtension(exts.AbsSendTime, payload); err != nil {
+					slog.Error("Failed to set abs-send-time extension", "participant", p.ID, "err", err)
+				}
 			}
 		}
 
-		// Return packet struct to pool
-		participantPacketPool.Put(pkt)
+		if err := track.WriteRTP(packet); err != nil {
+			if !errors.Is(err, io.ErrClosedPipe) {
+				slog.Error("WriteRTP failed", "participant", p.ID, "kind", shared.kind, "err", err)
+			}
+		} else {
+			if newest {
+				p.sent[i].store(packet.SequenceNumber, packet.Timestamp, time.Now())
+			}
+			p.bytesSent.Add(uint64(len(packet.Payload)))
+			p.meter.AddOut(len(packet.Payload))
+			if room := p.room.Load(); room != nil {
+				room.EgressStats.Count(len(packet.Payload))
+			}
+			// Marker ends a video frame, audio-only viewers count their first packet
+			if !w.firstFrame && (p.VideoTrack == nil || (shared.kind == webrtc.RTPCodecTypeVideo && packet.Marker)) {
+				w.firstFrame = true
+				if p.OnFirstFrame != nil {
+					go p.OnFirstFrame()
+				}
+			}
+		}
+		packet.Payload = nil // Shared payload isn't kept alive until the next packet
+	}
+
+	// Only the writing goroutine stores, 1/8 gain like RFC 6298 SRTT
+	delay := int64(time.Since(shared.queued))
+	old := p.queueDelay.Load()
+	p.queueDelay.Store(old + (delay-old)/8)
+
+	// Return packet struct to pool
+	pkt.release()
+}
+
+// rtpRebase maps RTP numbering of a participant's current source onto what the participant was sent before
+type rtpRebase struct {
+	ssrc      uint32
+	seen      bool
+	newestSeq uint16 // Newest sequence number received from source, before rebasing
+	seqOffset uint16
+	tsOffset  uint32
+}
+
+// rtpRestarted tells if a sequence number jumped too far from the newest of its source to be loss or reordering
+func rtpRestarted(newest, seq uint16) bool {
+	diff := int16(seq - newest)
+	return diff < -rtpReorderWindow || diff > rtpRestartGap
+}
+
+// rtpSent is numbering of the newest packet written of a kind, stored by the writer and read when a session resumes
+type rtpSent struct {
+	numbering atomic.Uint64 // Sequence number << 32 | timestamp
+	at        atomic.Int64  // Unix nanoseconds of the write, 0 before the first
+}
+
+func (s *rtpSent) store(seq uint16, ts uint32, at time.Time) {
+	s.numbering.Store(uint64(seq)<<32 | uint64(ts))
+	s.at.Store(at.UnixNano())
+}
+
+func (s *rtpSent) load() (uint16, uint32, time.Time) {
+	at := s.at.Load()
+	if at == 0 {
+		return 0, 0, time.Time{}
+	}
+	numbering := s.numbering.Load()
+	return uint16(numbering >> 32), uint32(numbering), time.Unix(0, at)
+}
+
+// RTPPosition is numbering of the newest RTP packet sent of a kind
+type RTPPosition struct {
+	SequenceNumber uint16
+	Timestamp      uint32
+	SentAt         time.Time // Zero if nothing of the kind was sent
+}
+
+// RTPState is RTP numbering sent to a Participant, a participant resuming its session continues from it
+type RTPState struct {
+	Audio RTPPosition
+	Video RTPPosition
+}
+
+// RTPState returns numbering last sent to Participant
+func (p *Participant) RTPState() RTPState {
+	var state RTPState
+	for i, pos := range []*RTPPosition{&state.Audio, &state.Video} {
+		pos.SequenceNumber, pos.Timestamp, pos.SentAt = p.sent[i].load()
+	}
+	return state
+}
+
+// ResumeRTP continues numbering of a previous participant of the same session, so the viewer gets one
+// continuous stream without its decoders seeing numbering jump. Call before adding Participant to a room.
+func (p *Participant) ResumeRTP(state RTPState) {
+	for i, pos := range []RTPPosition{state.Audio, state.Video} {
+		if pos.SentAt.IsZero() {
+			continue
+		}
+		p.sent[i].store(pos.SequenceNumber, pos.Timestamp, pos.SentAt)
+		// First packet is rebased onto the resumed numbering like a new source
+		p.writer.rebase[i] = rtpRebase{seen: true}
+	}
+}
+
+// VideoTimestamp maps a video RTP timestamp of the room feeding Participant to the timestamp Participant is sent
+func (p *Participant) VideoTimestamp(ts uint32) uint32 {
+	return ts + p.videoTSOffset.Load()
+}
+
+// kindExtensions returns extensions negotiated for given track kind, empty before negotiation completes
+func (p *Participant) kindExtensions(kind webrtc.RTPCodecType) common.NegotiatedExtensions {
+	exts := p.extensions.Load()
+	if exts == nil {
+		return common.NegotiatedExtensions{}
+	}
+	if kind == webrtc.RTPCodecTypeAudio {
+		return exts.audio
+	}
+	return exts.video
+}
+
+// rtcpReader reads RTCP of a sender until it is closed, interceptors like NACK responder only see RTCP that is read
+func (p *Participant) rtcpReader(sender *webrtc.RTPSender) {
+	if sender == nil {
+		return
+	}
+	for {
+		packets, _, err := sender.ReadRTCP()
+		if err != nil {
+			return
+		}
+		for _, pkt := range packets {
+			switch pkt := pkt.(type) {
+			case *rtcp.PictureLossIndication, *rtcp.FullIntraRequest:
+				if room := p.room.Load(); room != nil {
+					room.pictureLoss.Add(1)
+				}
+			case *rtcp.ReceiverReport:
+				for _, report := range pkt.Reports {
+					if rtt, ok := reportRTT(report, time.Now()); ok {
+						p.rtt.Store(int64(rtt))
+					}
+				}
+			}
+		}
+	}
+}
+
+// reportRTT computes round trip time from a reception report block as in RFC 3550 section 6.4.1
+func reportRTT(report rtcp.ReceptionReport, now time.Time) (time.Duration, bool) {
+	if report.LastSenderReport == 0 {
+		return 0, false
+	}
+	// Middle 32 bits of NTP timestamp, in 1/65536 seconds
+	ntpSeconds := uint64(now.Unix()) + 2208988800
+	ntpFraction := (uint64(now.Nanosecond()) << 32) / uint64(time.Second)
+	arrival := uint32((ntpSeconds<<32 | ntpFraction) >> 16)
+
+	delay := arrival - report.LastSenderReport - report.Delay
+	if int32(delay) < 0 {
+		return 0, false
 	}
+	return time.Duration(delay) * time.Second / 65536, true
 }
@@ -0,0 +1,139 @@
+package shared
+
+import (
+	"relay/internal/common"
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v4"
+)
+
+// newRetimingParticipant creates a participant with an unbound video track, packets are retimed and dropped
+func newRetimingParticipant(t *testing.T) *Participant {
+	t.Helper()
+	if err := common.InitFlagsFromArgs(nil); err != nil {
+		t.Fatal(err)
+	}
+	participant, err := NewParticipant("session", "", defaultPacketQueueSize, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(participant.Close)
+	track, err := webrtc.NewTrackLocalStaticRTP(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeH264, ClockRate: 90000}, "video", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	participant.VideoTrack = track
+	return participant
+}
+
+// writeVideo writes a video packet of source ssrc to participant as its writer would
+func writeVideo(p *Participant, ssrc uint32, seq uint16, ts uint32) {
+	fp := fanoutPacketPool.Get().(*fanoutPacket)
+	fp.kind = webrtc.RTPCodecTypeVideo
+	fp.packet = rtp.Packet{Header: rtp.Header{Version: 2, PayloadType: 96, SequenceNumber: seq, Timestamp: ts, SSRC: ssrc}}
+	fp.queued = time.Now()
+	fp.refs.Store(1)
+	pp := participantPacketPool.Get().(*participantPacket)
+	pp.shared = fp
+	p.writePacket(pp)
+}
+
+func TestParticipantRetimesRestartedSource(t *testing.T) {
+	participant := newRetimingParticipant(t)
+	for seq := uint16(100); seq < 105; seq++ {
+		writeVideo(participant, 1, seq, uint32(seq)*3000)
+	}
+
+	// Reordered packets keep their numbering and aren't counted as newest
+	writeVideo(participant, 1, 102, 102*3000)
+	if got := participant.RTPState().Video.SequenceNumber; got != 104 {
+		t.Fatalf("reordered packet moved newest sequence number to %d", got)
+	}
+
+	// Same source restarting its numbering continues after the newest packet sent
+	writeVideo(participant, 1, 60000, 1000)
+	state := participant.RTPState().Video
+	if state.SequenceNumber != 105 {
+		t.Fatalf("restarted source continued at sequence number %d, want 105", state.SequenceNumber)
+	}
+	if int32(state.Timestamp-104*3000) <= 0 {
+		t.Fatalf("restarted source went back in time to timestamp %d", state.Timestamp)
+	}
+	writeVideo(participant, 1, 60001, 4000)
+	if got := participant.RTPState().Video.SequenceNumber; got != 106 {
+		t.Fatalf("packet after restart got sequence number %d, want 106", got)
+	}
+}
+
+func TestParticipantResumesRTP(t *testing.T) {
+	previous := newRetimingParticipant(t)
+	for seq := uint16(10); seq < 20; seq++ {
+		writeVideo(previous, 1, seq, uint32(seq)*3000)
+	}
+	state := previous.RTPState()
+	if state.Video.SentAt.IsZero() || !state.Audio.SentAt.IsZero() {
+		t.Fatalf("unexpected state of previous participant: %+v", state)
+	}
+
+	resumed := newRetimingParticipant(t)
+	resumed.ResumeRTP(state)
+	writeVideo(resumed, 2, 40000, 123456)
+	got := resumed.RTPState().Video
+	if got.SequenceNumber != state.Video.SequenceNumber+1 {
+		t.Fatalf("resumed participant continued at sequence number %d, want %d", got.SequenceNumber, state.Video.SequenceNumber+1)
+	}
+	if int32(got.Timestamp-state.Video.Timestamp) <= 0 {
+		t.Fatalf("resumed participant went back in time to timestamp %d from %d", got.Timestamp, state.Video.Timestamp)
+	}
+}
+
+func TestParticipantInputLatency(t *testing.T) {
+	participant := newRetimingParticipant(t)
+	if got := participant.InputLatency(); got.Samples != 0 || got.P99 != 0 {
+		t.Fatalf("unexpected input latency before any was measured: %+v", got)
+	}
+	for range 98 {
+		participant.ObserveInputLatency(12 * time.Millisecond)
+	}
+	participant.ObserveInputLatency(180 * time.Millisecond)
+	participant.ObserveInputLatency(3 * time.Second)
+
+	got := participant.InputLatency()
+	want := LatencySnapshot{Samples: 100, Last: 3 * time.Second, P50: 15 * time.Millisecond, P95: 15 * time.Millisecond, P99: 200 * time.Millisecond}
+	if got != want {
+		t.Fatalf("input latency %+v, want %+v", got, want)
+	}
+	participant.ObserveInputLatency(4 * time.Second)
+	participant.ObserveInputLatency(5 * time.Second)
+	// Overflowing quantiles are read as the highest latency measured
+	if got = participant.InputLatency(); got.P99 != 5*time.Second {
+		t.Fatalf("overflowing p99 %v, want %v", got.P99, 5*time.Second)
+	}
+}
+
+func TestParticipantControllerSlots(t *testing.T) {
+	participant := newRetimingParticipant(t)
+	first := ControllerSlot{SessionID: "session", Slot: 0}
+	second := ControllerSlot{SessionID: "session", Slot: 1}
+	participant.ClaimControllerSlot(first)
+	participant.ClaimControllerSlot(second)
+	if !participant.OwnsControllerSlot(first) || !participant.OwnsControllerSlot(second) {
+		t.Fatal("attached controller slots aren't owned")
+	}
+	if participant.OwnsControllerSlot(ControllerSlot{SessionID: "other", Slot: 0}) {
+		t.Fatal("slot of another session is owned")
+	}
+
+	participant.ReleaseControllerSlot(first)
+	if participant.OwnsControllerSlot(first) {
+		t.Fatal("detached controller slot is still owned")
+	}
+	if !participant.OwnsControllerSession("session") || participant.OwnsControllerSession("other") {
+		t.Fatal("unexpected sessions owned")
+	}
+	if slots := participant.ControllerSlots(); len(slots) != 1 || slots[0] != second {
+		t.Fatalf("controller slots %v, want %v", slots, []ControllerSlot{second})
+	}
+}
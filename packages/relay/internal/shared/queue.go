@@ -0,0 +1,126 @@
+package shared
+
+import (
+	"github.com/pion/webrtc/v4"
+)
+
+// DropPolicy decides which packets a backed up Participant queue gives up
+type DropPolicy string
+
+const (
+	DropNewest   DropPolicy = "newest"   // Incoming packets are dropped while the queue is full
+	DropOldest   DropPolicy = "oldest"   // Oldest queued packet is dropped to make room for the incoming one
+	DropVideo    DropPolicy = "video"    // Video is dropped from high watermark until the queue drains to low watermark, audio is kept
+	DropKeyframe DropPolicy = "keyframe" // Like video, but video resumes only at a keyframe so viewers never decode broken frames
+)
+
+// ParseDropPolicy returns known drop policy with given name
+func ParseDropPolicy(name string) (DropPolicy, bool) {
+	switch policy := DropPolicy(name); policy {
+	case DropNewest, DropOldest, DropVideo, DropKeyframe:
+		return policy, true
+	default:
+		return "", false
+	}
+}
+
+// sheds returns true if policy drops video between watermarks
+func (policy DropPolicy) sheds() bool {
+	return policy == DropVideo || policy == DropKeyframe
+}
+
+// Why a packet was dropped when queueing it for a Participant
+type queueDrop int
+
+const (
+	queuedOK     queueDrop = iota
+	dropFull               // Incoming packet dropped, queue full
+	dropEvicted            // Oldest queued packet dropped for the incoming one
+	dropShed               // Incoming video dropped between watermarks
+	dropShedKick           // Like dropShed, first drop of a keyframe policy shed which needs a keyframe to recover
+	dropClosed             // Participant was closed while fan-out still had it, the packet has nowhere to go
+)
+
+// queueLimits are the queue watermarks and drop policy a Room applies to its participants
+type queueLimits struct {
+	policy DropPolicy
+	high   int
+	low    int
+}
+
+// queueLimits resolves watermarks of the Room drop policy against queue size of its participants
+func (r *Room) queueLimits() queueLimits {
+	size := r.ParticipantQueueSize()
+	settings := r.GetSettings()
+	limits := queueLimits{
+		policy: settings.DropPolicy,
+		high:   settings.QueueHighWatermark,
+		low:    settings.QueueLowWatermark,
+	}
+	if len(limits.policy) <= 0 {
+		limits.policy = DropNewest
+	}
+	if limits.high <= 0 || limits.high > size {
+		limits.high = size * 4 / 5
+	}
+	if limits.low <= 0 || limits.low >= limits.high {
+		limits.low = min(size/2, limits.high-1)
+	}
+	return limits
+}
+
+// enqueue hands packet to Participant writer without blocking, applying the drop policy of its room
+func (p *Participant) enqueue(pkt *participantPacket, limits queueLimits, keyframe bool) queueDrop {
+	if limits.policy.sheds() && pkt.shared.kind == webrtc.RTPCodecTypeVideo {
+		depth := len(p.packetQueue)
+		if !p.shedding.Load() {
+			if depth >= limits.high {
+				p.shedding.Store(true)
+				if limits.policy == DropKeyframe {
+					return dropShedKick
+				}
+				return dropShed
+			}
+		} else if depth > limits.low || (limits.policy == DropKeyframe && !keyframe) {
+			return dropShed
+		} else {
+			p.shedding.Store(false)
+		}
+	}
+
+	if drop := p.push(pkt); drop != dropFull || limits.policy != DropOldest {
+		return drop
+	}
+	select {
+	case old, ok := <-p.packetQueue:
+		if ok {
+			old.release()
+		}
+	default:
+	}
+	if drop := p.push(pkt); drop != queuedOK {
+		return drop
+	}
+	return dropEvicted
+}
+
+// push queues packet if there is room, scheduling the writer of a pooled Participant. Fan-out works off a snapshot
+// of participants, so a removed and closed Participant may still be pushed to
+func (p *Participant) push(pkt *participantPacket) queueDrop {
+	p.queueMtx.RLock()
+	if p.queueClosed {
+		p.queueMtx.RUnlock()
+		return dropClosed
+	}
+	select {
+	case p.packetQueue <- pkt:
+	default:
+		p.queueMtx.RUnlock()
+		return dropFull
+	}
+	p.queueMtx.RUnlock()
+	if p.shard != nil {
+		p.shard.schedule(p)
+	}
+	return queuedOK
+}
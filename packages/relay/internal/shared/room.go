@@ -1,15 +1,22 @@
 package shared
 
 import (
+	"errors"
 	"log/slog"
+	"relay/internal/common"
 	"relay/internal/connections"
+	gen "relay/internal/proto"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/oklog/ulid/v2"
+	"github.com/pion/rtcp"
 	"github.com/pion/rtp"
 	"github.com/pion/webrtc/v4"
+	"golang.org/x/time/rate"
 )
 
 var participantPacketPool = sync.Pool{
@@ -18,30 +25,316 @@ var participantPacketPool = sync.Pool{
 	},
 }
 
+// participantPacket is a packet queued for one participant. The packet prepared for fan-out is shared with other
+// participants and must not be modified, writers rewrite numbering and extensions on their own copy of its header.
 type participantPacket struct {
-	kind         webrtc.RTPCodecType
-	packet       *rtp.Packet
+	shared *fanoutPacket
+	room   *Room // Room whose queued bytes include the payload, nil once released
+}
+
+// release returns packet to pool, dropping its reference to the shared packet
+func (pp *participantPacket) release() {
+	if pp.room != nil {
+		pp.room.queuedBytes.Add(-int64(len(pp.shared.packet.Payload)))
+		pp.room = nil
+	}
+	pp.shared.release()
+	pp.shared = nil
+	participantPacketPool.Put(pp)
+}
+
+// RoomSettings holds per-room behaviour requested by the pushing node
+type RoomSettings struct {
+	AudioOnly     bool          `json:"audio_only,omitempty"`     // Voice room, no video tracks are allocated
+	LatencyBudget time.Duration `json:"latency_budget,omitempty"` // End-to-end budget for mesh forwarding paths, 0 uses relay default
+	StrictLatency bool          `json:"strict_latency,omitempty"` // Drop late video frames for viewers instead of delivering them
+	MaxFrameAge   time.Duration `json:"max_frame_age,omitempty"`  // Max video frame age since ingest in strict latency mode, 0 uses relay default
+	AccessHash    string        `json:"access_hash,omitempty"`    // Hash of secret viewers must present, empty for public rooms
+	MaxViewers    int           `json:"max_viewers,omitempty"`    // Max participants admitted per relay, 0 uses relay default
+
+	// Participant queueing, watermarks only apply to drop policies shedding video
+	QueueSize          int        `json:"queue_size,omitempty"`           // Packets queued per participant, 0 uses relay default
+	QueueHighWatermark int        `json:"queue_high_watermark,omitempty"` // Queue depth at which video starts being dropped, 0 uses 80% of queue size
+	QueueLowWatermark  int        `json:"queue_low_watermark,omitempty"`  // Queue depth at which dropped video resumes, 0 uses half of queue size
+	DropPolicy         DropPolicy `json:"drop_policy,omitempty"`          // Empty drops newest packets
+}
+
+// RoomAccessHash hashes a room access secret, relays check viewers against the hash so the secret never travels the mesh.
+// The hash is salted and slow to derive, as every relay of the mesh learns it.
+func RoomAccessHash(roomName, secret string) (string, error) {
+	if len(secret) <= 0 {
+		return "", nil
+	}
+	return common.HashRoomAccess(roomName, secret)
+}
+
+// CheckAccess returns true if room is public or secret matches its access hash
+func (s RoomSettings) CheckAccess(roomName, secret string) bool {
+	if len(s.AccessHash) <= 0 {
+		return true
+	}
+	return common.VerifyRoomAccess(roomName, secret, s.AccessHash)
+}
+
+// RoomSettingsFromProto converts pushed room settings, nil gives defaults
+func RoomSettingsFromProto(settings *gen.ProtoRoomSettings) RoomSettings {
+	if settings == nil {
+		return RoomSettings{}
+	}
+	return RoomSettings{
+		AudioOnly:     settings.AudioOnly,
+		LatencyBudget: time.Duration(settings.LatencyBudgetMs) * time.Millisecond,
+		StrictLatency: settings.StrictLatency,
+		MaxFrameAge:   time.Duration(settings.MaxFrameAgeMs) * time.Millisecond,
+		MaxViewers:    int(settings.MaxViewers),
+
+		QueueSize:          int(settings.QueueSize),
+		QueueHighWatermark: int(settings.QueueHighWatermark),
+		QueueLowWatermark:  int(settings.QueueLowWatermark),
+		DropPolicy:         dropPolicyOrDefault(settings.DropPolicy),
+	}
+}
+
+// dropPolicyOrDefault returns named drop policy, empty for unknown names so relay defaults apply
+func dropPolicyOrDefault(name string) DropPolicy {
+	if len(name) <= 0 {
+		return ""
+	}
+	policy, ok := ParseDropPolicy(name)
+	if !ok {
+		slog.Warn("Ignoring unknown drop policy", "policy", name)
+	}
+	return policy
+}
+
+// WithDefaults fills settings the pushing node left unset from per-room defaults of relay config,
+// quality variants use defaults of their main room
+func (s RoomSettings) WithDefaults(roomName string) RoomSettings {
+	defaults, ok := common.GetFlags().RoomDefaults(BaseRoomName(roomName))
+	if !ok {
+		return s
+	}
+	if s.LatencyBudget <= 0 {
+		s.LatencyBudget = time.Duration(defaults.LatencyBudget) * time.Millisecond
+	}
+	if s.MaxFrameAge <= 0 {
+		s.MaxFrameAge = time.Duration(defaults.MaxFrameAge) * time.Millisecond
+	}
+	if s.MaxViewers <= 0 {
+		s.MaxViewers = defaults.MaxViewers
+	}
+	if s.QueueSize <= 0 {
+		s.QueueSize = defaults.QueueSize
+	}
+	if s.QueueHighWatermark <= 0 {
+		s.QueueHighWatermark = defaults.QueueHighWatermark
+	}
+	if s.QueueLowWatermark <= 0 {
+		s.QueueLowWatermark = defaults.QueueLowWatermark
+	}
+	if len(s.DropPolicy) <= 0 {
+		s.DropPolicy = dropPolicyOrDefault(defaults.DropPolicy)
+	}
+	s.StrictLatency = s.StrictLatency || defaults.StrictLatency
+	return s
+}
+
+// ToProto converts room settings to their protobuf form
+func (s RoomSettings) ToProto() *gen.ProtoRoomSettings {
+	return &gen.ProtoRoomSettings{
+		AudioOnly:       s.AudioOnly,
+		LatencyBudgetMs: uint32(s.LatencyBudget.Milliseconds()),
+		StrictLatency:   s.StrictLatency,
+		MaxFrameAgeMs:   uint32(s.MaxFrameAge.Milliseconds()),
+		MaxViewers:      uint32(s.MaxViewers),
+
+		QueueSize:          uint32(s.QueueSize),
+		QueueHighWatermark: uint32(s.QueueHighWatermark),
+		QueueLowWatermark:  uint32(s.QueueLowWatermark),
+		DropPolicy:         string(s.DropPolicy),
+	}
+}
+
+// RoomMetadata describes a room for room lists, set by the pushing node
+type RoomMetadata struct {
+	Title     string `json:"title,omitempty"`
+	Game      string `json:"game,omitempty"`
+	Width     int    `json:"width,omitempty"`
+	Height    int    `json:"height,omitempty"`
+	FrameRate int    `json:"frame_rate,omitempty"`
+	Private   bool   `json:"private,omitempty"` // Left out of room directories, still joinable by name
+
+	ThumbnailURL string `json:"thumbnail_url,omitempty"` // Latest keyframe thumbnail, set by the relay owning the room
+}
+
+// RoomMetadataFromProto converts pushed room metadata, nil gives empty metadata
+func RoomMetadataFromProto(metadata *gen.ProtoRoomMetadata) RoomMetadata {
+	if metadata == nil {
+		return RoomMetadata{}
+	}
+	return RoomMetadata{
+		Title:     metadata.Title,
+		Game:      metadata.Game,
+		Width:     int(metadata.Width),
+		Height:    int(metadata.Height),
+		FrameRate: int(metadata.FrameRate),
+		Private:   metadata.Private,
+
+		ThumbnailURL: metadata.ThumbnailUrl,
+	}
+}
+
+// ToProto converts room metadata to its protobuf form
+func (m RoomMetadata) ToProto() *gen.ProtoRoomMetadata {
+	return &gen.ProtoRoomMetadata{
+		Title:     m.Title,
+		Game:      m.Game,
+		Width:     uint32(m.Width),
+		Height:    uint32(m.Height),
+		FrameRate: uint32(m.FrameRate),
+		Private:   m.Private,
+
+		ThumbnailUrl: m.ThumbnailURL,
+	}
+}
+
+// VariantSeparator joins a room name and quality variant into the name of the sibling room carrying the variant
+const VariantSeparator = "~"
+
+// VariantRoomName returns name of the room carrying a quality variant, empty variant is the main room itself
+func VariantRoomName(roomName, variant string) string {
+	if len(variant) <= 0 {
+		return roomName
+	}
+	return roomName + VariantSeparator + variant
+}
+
+// SplitVariant splits a room name into main room name and quality variant, variant is empty for main rooms
+func SplitVariant(roomName string) (string, string) {
+	base, variant, _ := strings.Cut(roomName, VariantSeparator)
+	return base, variant
+}
+
+// BaseRoomName returns name of the main room of a variant room, main room names are returned as is
+func BaseRoomName(roomName string) string {
+	base, _ := SplitVariant(roomName)
+	return base
+}
+
+// ValidVariantName checks a pushed variant name, letters, digits, '-' and '_' up to 32 characters
+func ValidVariantName(variant string) bool {
+	if len(variant) <= 0 || len(variant) > 32 {
+		return false
+	}
+	for _, c := range variant {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
 }
 
 type RoomInfo struct {
-	ID      ulid.ULID `json:"id"`
-	Name    string    `json:"name"`
-	OwnerID peer.ID   `json:"owner_id"`
+	ID       ulid.ULID    `json:"id"`
+	Name     string       `json:"name"`
+	OwnerID  peer.ID      `json:"owner_id"`
+	Settings RoomSettings `json:"settings"`
+	Metadata RoomMetadata `json:"metadata"`
+	Viewers  int          `json:"viewers"` // Participant count at the owner relay
+	Online   bool         `json:"online"`
+
+	LocalViewers int `json:"local_viewers,omitempty"` // Viewers connected to the announcing relay, relays pulling the room excluded
+
+	// Mesh path, as announced by the relay serving this room
+	RelayID     peer.ID       `json:"relay_id,omitempty"`     // Relay able to serve the room, owner or a relay forwarding it
+	Hops        int           `json:"hops,omitempty"`         // Relay hops between owner and RelayID
+	PathLatency time.Duration `json:"path_latency,omitempty"` // Cumulative measured latency up to and including RelayID
+	Bitrate     uint64        `json:"bitrate,omitempty"`      // Bits per second RelayID receives the room at, 0 if not measured yet
+
+	// Ownership claim, relays converge on the winning claim when several relays own a room after a partition
+	Epoch       uint64    `json:"epoch,omitempty"`       // Bumped past every epoch known for the room name whenever the room is pushed
+	ClaimedAt   time.Time `json:"claimed_at,omitzero"`   // When the owner accepted the push, breaks epoch ties
+	LeaseExpiry time.Time `json:"lease_expiry,omitzero"` // Set locally on receipt, owners renew the claim by announcing it
+}
+
+// ClaimBeats returns true if ownership claim of info wins over other, online rooms win over offline ones, then
+// higher epochs, later pushes and lastly lower owner IDs so every relay picks the same winner
+func (info RoomInfo) ClaimBeats(other RoomInfo) bool {
+	if info.Online != other.Online {
+		return info.Online
+	}
+	if info.Epoch != other.Epoch {
+		return info.Epoch > other.Epoch
+	}
+	if !info.ClaimedAt.Equal(other.ClaimedAt) {
+		return info.ClaimedAt.After(other.ClaimedAt)
+	}
+	return info.OwnerID < other.OwnerID
+}
+
+// LeaseExpired returns true if the owner stopped renewing its claim, zero leases never expire
+func (info RoomInfo) LeaseExpired(now time.Time) bool {
+	return !info.LeaseExpiry.IsZero() && now.After(info.LeaseExpiry)
 }
 
 type Room struct {
 	RoomInfo
-	AudioCodec     webrtc.RTPCodecCapability
-	VideoCodec     webrtc.RTPCodecCapability
-	PeerConnection *webrtc.PeerConnection
-	DataChannel    *connections.NestriDataChannel
+	audioCodec  atomic.Pointer[webrtc.RTPCodecCapability] // Set from OnTrack of the incoming stream
+	videoCodec  atomic.Pointer[webrtc.RTPCodecCapability]
+	codecsReady atomic.Bool                                   // Codecs of all tracks viewers get are known, set once per stream
+	pcMtx       sync.Mutex                                    // Guards pc, releasePC and standby, set by signaling and cleared on Close
+	pc          *webrtc.PeerConnection                        // Of the incoming stream, nil while offline
+	dataChannel atomic.Pointer[connections.NestriDataChannel] // Set from OnDataChannel while the room is live
+	releasePC   func()                                        // Set when PeerConnection is shared with other rooms, called instead of closing it
+	standby     *webrtc.PeerConnection                        // Of a standby push taking over if pc fails, nil if there's none
+	standbyDC   *connections.NestriDataChannel                // DataChannel of the standby push, becomes dataChannel on failover
+	metadataMtx sync.RWMutex                                  // Guards RoomInfo.Metadata, updated while the room is live
+	settingsMtx sync.RWMutex                                  // Guards RoomInfo.Settings, replaced when the room is pushed again
+	claimMtx    sync.RWMutex                                  // Guards RoomInfo.Epoch and ClaimedAt, set when the room is pushed or pulled
+	videoSSRC   atomic.Uint32                                 // SSRC of incoming video track, for keyframe requests
+
+	// Audio levels of the incoming stream, see TakeAudioLevel
+	audioLevelID atomic.Uint32 // Header extension ID of ssrc-audio-level on the incoming stream, 0 if not negotiated
+	audioLevel   atomic.Uint32 // Loudest level since last taken, audioLevelSeen is set once any was recorded
+
+	// Maps video RTP timestamps of the room to stream time, see StreamClock
+	clock atomic.Pointer[StreamClock]
 
-	// Atomic pointer to slice of participant channels
-	participantChannels atomic.Pointer[[]chan<- *participantPacket]
-	participantsMtx     sync.Mutex // Use only for add/remove
+	// Upstream path for rooms pulled from another relay
+	pathMtx         sync.RWMutex
+	upstreamID      peer.ID // Relay this Room is pulled from, empty when pushed to this relay
+	upstreamHops    int
+	upstreamLatency time.Duration
+
+	// Atomic pointer to slice of participants packets are fanned out to
+	participantQueues atomic.Pointer[[]*Participant]
+	participantsMtx   sync.Mutex // Use only for add/remove
+
+	// Set while the room is large enough to fan packets out from several goroutines
+	fanoutShards atomic.Pointer[[]*fanoutShard]
 
 	Participants map[ulid.ULID]*Participant // Keep general track of Participant(s)
 
+	// Forwarded track statistics
+	AudioStats TrackStats
+	VideoStats TrackStats
+
+	// Fan-out statistics, egress counts packets written to Participant(s)
+	EgressStats      TrackStats
+	droppedLate      atomic.Uint64 // Packets of late video frames dropped in strict latency mode
+	droppedQueueFull atomic.Uint64 // Packets dropped because a Participant queue was full
+	droppedEvicted   atomic.Uint64 // Queued packets dropped for newer ones by the oldest-first policy
+	droppedShed      atomic.Uint64 // Video packets dropped between queue watermarks
+	droppedBudget    atomic.Uint64 // Packets dropped because the room queued bytes budget was used up
+	droppedMessages  atomic.Uint64 // DataChannel messages dropped over the room message rate or goroutine budget
+	pictureLoss      atomic.Uint64 // PLI and FIR requests from Participant(s)
+
+	// Resource budget usage, see Budget
+	goroutines  atomic.Int64
+	tracks      atomic.Int64
+	queuedBytes atomic.Int64
+	messages    *rate.Limiter
+
 	// Track last seen values to calculate diffs
 	LastVideoTimestamp      uint32
 	LastVideoSequenceNumber uint16
@@ -61,33 +354,337 @@ func NewRoom(name string, roomID ulid.ULID, ownerID peer.ID) *Room {
 			Name:    name,
 			OwnerID: ownerID,
 		},
-		PeerConnection: nil,
-		DataChannel:    nil,
-		Participants:   make(map[ulid.ULID]*Participant),
+		Participants: make(map[ulid.ULID]*Participant),
+		messages:     rate.NewLimiter(rate.Inf, 0),
 	}
 
-	emptyChannels := make([]chan<- *participantPacket, 0)
-	r.participantChannels.Store(&emptyChannels)
+	emptyQueues := make([]*Participant, 0)
+	r.participantQueues.Store(&emptyQueues)
 
 	return r
 }
 
+// DataChannel returns DataChannel of the room's upstream, nil until it's opened
+func (r *Room) DataChannel() *connections.NestriDataChannel {
+	return r.dataChannel.Load()
+}
+
+// SetDataChannel sets DataChannel of the room's upstream
+func (r *Room) SetDataChannel(ndc *connections.NestriDataChannel) {
+	r.dataChannel.Store(ndc)
+}
+
 // Close closes up Room (stream ended)
 func (r *Room) Close() {
-	if r.DataChannel != nil {
-		err := r.DataChannel.Close()
+	if dc := r.dataChannel.Swap(nil); dc != nil {
+		err := dc.Close()
 		if err != nil {
-			slog.Error("Failed to close Room DataChannel", err)
+			slog.Error("Failed to close Room DataChannel", "err", err)
 		}
-		r.DataChannel = nil
 	}
-	if r.PeerConnection != nil {
-		err := r.PeerConnection.Close()
-		if err != nil {
-			slog.Error("Failed to close Room PeerConnection", err)
+	r.pcMtx.Lock()
+	pc, release, standby := r.pc, r.releasePC, r.standby
+	r.pc, r.releasePC, r.standby, r.standbyDC = nil, nil, nil, nil
+	r.pcMtx.Unlock()
+	// Clock of the ended stream maps nothing a later one sends
+	r.clock.Store(nil)
+	if release != nil {
+		release()
+	} else if pc != nil {
+		if err := pc.Close(); err != nil {
+			slog.Error("Failed to close Room PeerConnection", "err", err)
+		}
+	}
+	if standby != nil {
+		if err := standby.Close(); err != nil {
+			slog.Error("Failed to close Room standby PeerConnection", "err", err)
+		}
+	}
+}
+
+// GetMetadata returns current room metadata
+func (r *Room) GetMetadata() RoomMetadata {
+	r.metadataMtx.RLock()
+	defer r.metadataMtx.RUnlock()
+	return r.Metadata
+}
+
+// SetMetadata replaces room metadata
+func (r *Room) SetMetadata(metadata RoomMetadata) {
+	r.metadataMtx.Lock()
+	defer r.metadataMtx.Unlock()
+	r.Metadata = metadata
+}
+
+// GetClaim returns ownership epoch of the room and when it was claimed
+func (r *Room) GetClaim() (uint64, time.Time) {
+	r.claimMtx.RLock()
+	defer r.claimMtx.RUnlock()
+	return r.Epoch, r.ClaimedAt
+}
+
+// SetClaim sets ownership epoch of the room and when it was claimed
+func (r *Room) SetClaim(epoch uint64, claimedAt time.Time) {
+	r.claimMtx.Lock()
+	defer r.claimMtx.Unlock()
+	r.Epoch = epoch
+	r.ClaimedAt = claimedAt
+}
+
+// GetSettings returns a copy of current room settings
+func (r *Room) GetSettings() RoomSettings {
+	r.settingsMtx.RLock()
+	defer r.settingsMtx.RUnlock()
+	return r.Settings
+}
+
+// SetSettings replaces room settings
+func (r *Room) SetSettings(settings RoomSettings) {
+	r.settingsMtx.Lock()
+	defer r.settingsMtx.Unlock()
+	r.Settings = settings
+}
+
+// AudioCodec returns codec of the incoming audio track, zero until it arrived
+func (r *Room) AudioCodec() webrtc.RTPCodecCapability {
+	if codec := r.audioCodec.Load(); codec != nil {
+		return *codec
+	}
+	return webrtc.RTPCodecCapability{}
+}
+
+// VideoCodec returns codec of the incoming video track, zero until it arrived
+func (r *Room) VideoCodec() webrtc.RTPCodecCapability {
+	if codec := r.videoCodec.Load(); codec != nil {
+		return *codec
+	}
+	return webrtc.RTPCodecCapability{}
+}
+
+// Codec returns codec of the incoming track of kind, zero until it arrived
+func (r *Room) Codec(kind webrtc.RTPCodecType) webrtc.RTPCodecCapability {
+	if kind == webrtc.RTPCodecTypeVideo {
+		return r.VideoCodec()
+	}
+	return r.AudioCodec()
+}
+
+// SetCodec records codec of an incoming track. Returns true only for the call which made codecs of all tracks
+// viewers get known, tracks arrive concurrently so exactly one of them sees the room become ready.
+func (r *Room) SetCodec(kind webrtc.RTPCodecType, codec webrtc.RTPCodecCapability) bool {
+	switch kind {
+	case webrtc.RTPCodecTypeAudio:
+		r.audioCodec.Store(&codec)
+	case webrtc.RTPCodecTypeVideo:
+		r.videoCodec.Store(&codec)
+	default:
+		return false
+	}
+	if r.audioCodec.Load() == nil || (!r.GetSettings().AudioOnly && r.videoCodec.Load() == nil) {
+		return false
+	}
+	return r.codecsReady.CompareAndSwap(false, true)
+}
+
+// ResetCodecs forgets codecs of a previous stream, so the room becomes ready again with tracks of a new one
+func (r *Room) ResetCodecs() {
+	r.audioCodec.Store(nil)
+	r.videoCodec.Store(nil)
+	r.codecsReady.Store(false)
+}
+
+// SetVideoSSRC records SSRC of the incoming video track
+func (r *Room) SetVideoSSRC(ssrc uint32) {
+	r.videoSSRC.Store(ssrc)
+}
+
+// SetAudioLevelExtension records header extension ID the incoming stream carries audio levels with, 0 if none
+func (r *Room) SetAudioLevelExtension(id uint8) {
+	r.audioLevelID.Store(uint32(id))
+}
+
+// Packing of Room audioLevel, RFC 6464 level in low bits
+const (
+	audioLevelSeen  = 1 << 8
+	audioLevelVoice = 1 << 7
+	audioLevelMask  = 0x7f
+)
+
+// readAudioLevel returns ssrc-audio-level payload of an incoming audio packet, nil if it carries none.
+// The level is recorded for TakeAudioLevel, payload is shared with participants forwarding it.
+func (r *Room) readAudioLevel(pkt *rtp.Packet) []byte {
+	id := r.audioLevelID.Load()
+	if id == 0 {
+		return nil
+	}
+	payload := pkt.GetExtension(uint8(id))
+	var ext rtp.AudioLevelExtension
+	if err := ext.Unmarshal(payload); err != nil {
+		return nil
+	}
+	packed := audioLevelSeen | uint32(ext.Level)&audioLevelMask
+	if ext.Voice {
+		packed |= audioLevelVoice
+	}
+	for {
+		old := r.audioLevel.Load()
+		merged := packed
+		if old&audioLevelSeen != 0 {
+			// Level is -dBov, lower is louder. Voice activity sticks until taken.
+			merged = audioLevelSeen | (old|packed)&audioLevelVoice | min(old&audioLevelMask, packed&audioLevelMask)
+		}
+		if r.audioLevel.CompareAndSwap(old, merged) {
+			return payload[:1]
+		}
+	}
+}
+
+// TakeAudioLevel returns loudest audio level as -dBov (0 loudest, 127 silence) and voice activity
+// since the last call, false if no packet carried a level since
+func (r *Room) TakeAudioLevel() (uint8, bool, bool) {
+	packed := r.audioLevel.Swap(0)
+	if packed&audioLevelSeen == 0 {
+		return 0, false, false
+	}
+	return uint8(packed & audioLevelMask), packed&audioLevelVoice != 0, true
+}
+
+// StreamClock maps video RTP timestamps of a room to stream time, the wall clock the relay the room is pushed to
+// received them at. Viewers of the room on any relay share it to line up what they show.
+type StreamClock struct {
+	RTPTimestamp uint32
+	StreamTime   time.Time
+	ClockRate    uint32
+}
+
+// At returns stream time of a video RTP timestamp, timestamps wrap so they are taken within half the wrap of the anchor
+func (c StreamClock) At(ts uint32) time.Time {
+	return c.StreamTime.Add(time.Duration(int32(ts-c.RTPTimestamp)) * time.Second / time.Duration(c.ClockRate))
+}
+
+// StreamClock returns the room's stream clock, false until one was set
+func (r *Room) StreamClock() (StreamClock, bool) {
+	clock := r.clock.Load()
+	if clock == nil {
+		return StreamClock{}, false
+	}
+	return *clock, true
+}
+
+// SetStreamClock sets the room's stream clock, from its incoming video or the relay it is pulled from
+func (r *Room) SetStreamClock(clock StreamClock) {
+	r.clock.Store(&clock)
+}
+
+// RequestKeyframe asks the sender of the room stream for a keyframe, so newly switched viewers can decode right away
+func (r *Room) RequestKeyframe() error {
+	pc := r.PeerConnection()
+	ssrc := r.videoSSRC.Load()
+	if pc == nil || ssrc == 0 {
+		return errors.New("room has no incoming video track")
+	}
+	return pc.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: ssrc}})
+}
+
+// PeerConnection returns PeerConnection of the incoming stream, nil while the room is offline
+func (r *Room) PeerConnection() *webrtc.PeerConnection {
+	r.pcMtx.Lock()
+	defer r.pcMtx.Unlock()
+	return r.pc
+}
+
+// SetPeerConnection sets PeerConnection of the incoming stream, closed with the room
+func (r *Room) SetPeerConnection(pc *webrtc.PeerConnection) {
+	r.SetSharedPeerConnection(pc, nil)
+}
+
+// SetSharedPeerConnection sets a PeerConnection carrying other rooms too, on Close release is called instead of closing it
+func (r *Room) SetSharedPeerConnection(pc *webrtc.PeerConnection, release func()) {
+	r.pcMtx.Lock()
+	defer r.pcMtx.Unlock()
+	r.pc = pc
+	r.releasePC = release
+}
+
+// IngestRemoval is what removing a PeerConnection pushing the room changed
+type IngestRemoval int
+
+const (
+	IngestUnknown        IngestRemoval = iota // PeerConnection doesn't push the room (anymore)
+	IngestStandbyRemoved                      // Standby push was removed, the room is forwarded from as before
+	IngestFailedOver                          // Standby push took over the room
+	IngestOffline                             // Room went offline as it had no standby push
+)
+
+// SetStandbyPeerConnection sets PeerConnection of a standby push, which takes over the room once the pushed
+// stream fails. Returns false if the room is offline, pulled from another relay or already has a standby.
+func (r *Room) SetStandbyPeerConnection(pc *webrtc.PeerConnection) bool {
+	r.pcMtx.Lock()
+	defer r.pcMtx.Unlock()
+	if r.pc == nil || r.releasePC != nil || r.standby != nil {
+		return false
+	}
+	r.standby = pc
+	return true
+}
+
+// HasStandby returns true if the room has a standby push
+func (r *Room) HasStandby() bool {
+	r.pcMtx.Lock()
+	defer r.pcMtx.Unlock()
+	return r.standby != nil
+}
+
+// SetIngestDataChannel sets DataChannel of a push, it becomes the room DataChannel only once pc is forwarded from
+func (r *Room) SetIngestDataChannel(pc *webrtc.PeerConnection, ndc *connections.NestriDataChannel) {
+	r.pcMtx.Lock()
+	defer r.pcMtx.Unlock()
+	switch pc {
+	case r.pc:
+		r.dataChannel.Store(ndc)
+	case r.standby:
+		r.standbyDC = ndc
+	}
+}
+
+// RemoveIngest removes PeerConnection of an ended push and closes it. If the room was forwarded from pc,
+// its standby push takes over or without one the room goes offline.
+func (r *Room) RemoveIngest(pc *webrtc.PeerConnection) IngestRemoval {
+	r.pcMtx.Lock()
+	var removal IngestRemoval
+	var dc *connections.NestriDataChannel
+	switch {
+	case pc == nil:
+		removal = IngestUnknown
+	case pc == r.standby:
+		r.standby, r.standbyDC = nil, nil
+		removal = IngestStandbyRemoved
+	case pc == r.pc && r.standby != nil:
+		r.pc, r.standby = r.standby, nil
+		// Keyframe requests go to the standby from its first forwarded video packet
+		r.videoSSRC.Store(0)
+		dc = r.dataChannel.Swap(r.standbyDC)
+		r.standbyDC = nil
+		removal = IngestFailedOver
+	case pc == r.pc:
+		r.pc = nil
+		dc = r.dataChannel.Swap(nil)
+		r.clock.Store(nil)
+		removal = IngestOffline
+	}
+	r.pcMtx.Unlock()
+
+	if dc != nil {
+		if err := dc.Close(); err != nil {
+			slog.Error("Failed to close Room DataChannel", "err", err)
 		}
-		r.PeerConnection = nil
 	}
+	if pc != nil && removal != IngestUnknown {
+		if err := pc.Close(); err != nil {
+			slog.Error("Failed to close Room PeerConnection", "err", err)
+		}
+	}
+	return removal
 }
 
 // AddParticipant adds a Participant to a Room
@@ -96,14 +693,17 @@ func (r *Room) AddParticipant(participant *Participant) {
 	defer r.participantsMtx.Unlock()
 
 	r.Participants[participant.ID] = participant
+	participant.room.Store(r)
+	r.charge(participant)
 
-	// Update channel slice atomically
-	current := r.participantChannels.Load()
-	newChannels := make([]chan<- *participantPacket, len(*current)+1)
-	copy(newChannels, *current)
-	newChannels[len(*current)] = participant.packetQueue
+	// Update queue slice atomically
+	current := r.participantQueues.Load()
+	newQueues := make([]*Participant, len(*current)+1)
+	copy(newQueues, *current)
+	newQueues[len(*current)] = participant
 
-	r.participantChannels.Store(&newChannels)
+	r.participantQueues.Store(&newQueues)
+	r.rebalanceFanout()
 
 	slog.Debug("Added participant", "participant", participant.ID, "room", r.Name)
 }
@@ -119,49 +719,224 @@ func (r *Room) RemoveParticipantByID(pID ulid.ULID) {
 	}
 
 	delete(r.Participants, pID)
+	participant.room.CompareAndSwap(r, nil)
+	r.refund(participant)
 
-	// Update channel slice
-	current := r.participantChannels.Load()
-	newChannels := make([]chan<- *participantPacket, 0, len(*current)-1)
-	for _, ch := range *current {
-		if ch != participant.packetQueue {
-			newChannels = append(newChannels, ch)
+	// Update queue slice
+	current := r.participantQueues.Load()
+	newQueues := make([]*Participant, 0, len(*current)-1)
+	for _, queued := range *current {
+		if queued != participant {
+			newQueues = append(newQueues, queued)
 		}
 	}
 
-	r.participantChannels.Store(&newChannels)
+	r.participantQueues.Store(&newQueues)
+	participant.fanoutShard = nil
+	r.rebalanceFanout()
 
 	slog.Debug("Removed participant", "participant", pID, "room", r.Name)
 }
 
+// GetParticipants returns a snapshot of current Participant(s)
+func (r *Room) GetParticipants() []*Participant {
+	r.participantsMtx.Lock()
+	defer r.participantsMtx.Unlock()
+
+	participants := make([]*Participant, 0, len(r.Participants))
+	for _, participant := range r.Participants {
+		participants = append(participants, participant)
+	}
+	return participants
+}
+
+// GetParticipantByID returns a Participant of the Room, nil if not found
+func (r *Room) GetParticipantByID(pID ulid.ULID) *Participant {
+	r.participantsMtx.Lock()
+	defer r.participantsMtx.Unlock()
+	return r.Participants[pID]
+}
+
 // IsOnline checks if the room is online
 func (r *Room) IsOnline() bool {
-	return r.PeerConnection != nil
+	return r.PeerConnection() != nil
+}
+
+// RoomCounters are cumulative fan-out counters of a Room
+type RoomCounters struct {
+	DroppedLate      uint64 `json:"dropped_late"`
+	DroppedQueueFull uint64 `json:"dropped_queue_full"`
+	DroppedEvicted   uint64 `json:"dropped_evicted"`
+	DroppedShed      uint64 `json:"dropped_shed"`
+	DroppedBudget    uint64 `json:"dropped_budget"`
+	DroppedMessages  uint64 `json:"dropped_messages"`
+	PictureLoss      uint64 `json:"picture_loss"`
+}
+
+// Counters returns fan-out counters of the Room
+func (r *Room) Counters() RoomCounters {
+	return RoomCounters{
+		DroppedLate:      r.droppedLate.Load(),
+		DroppedQueueFull: r.droppedQueueFull.Load(),
+		DroppedEvicted:   r.droppedEvicted.Load(),
+		DroppedShed:      r.droppedShed.Load(),
+		DroppedBudget:    r.droppedBudget.Load(),
+		DroppedMessages:  r.droppedMessages.Load(),
+		PictureLoss:      r.pictureLoss.Load(),
+	}
+}
+
+// ParticipantCount returns the current number of Participant(s) in the Room
+func (r *Room) ParticipantCount() int {
+	r.participantsMtx.Lock()
+	defer r.participantsMtx.Unlock()
+	return len(r.Participants)
+}
+
+// HopLatency returns latency added by this relay for the Room, the worst queueing delay among Participant(s)
+func (r *Room) HopLatency() time.Duration {
+	r.participantsMtx.Lock()
+	defer r.participantsMtx.Unlock()
+
+	var worst time.Duration
+	for _, participant := range r.Participants {
+		if delay := participant.QueueDelay(); delay > worst {
+			worst = delay
+		}
+	}
+	return worst
+}
+
+// UpstreamID returns relay the Room is pulled from, empty when pushed to this relay
+func (r *Room) UpstreamID() peer.ID {
+	r.pathMtx.RLock()
+	defer r.pathMtx.RUnlock()
+	return r.upstreamID
+}
+
+// SetUpstreamID sets relay the Room is pulled from
+func (r *Room) SetUpstreamID(id peer.ID) {
+	r.pathMtx.Lock()
+	defer r.pathMtx.Unlock()
+	r.upstreamID = id
+}
+
+// SetUpstreamPath stores the path as announced by the upstream relay
+func (r *Room) SetUpstreamPath(hops int, latency time.Duration) {
+	r.pathMtx.Lock()
+	defer r.pathMtx.Unlock()
+	r.upstreamHops = hops
+	r.upstreamLatency = latency
+}
+
+// UpstreamPath returns the hops and cumulative latency of the upstream relay
+func (r *Room) UpstreamPath() (int, time.Duration) {
+	r.pathMtx.RLock()
+	defer r.pathMtx.RUnlock()
+	return r.upstreamHops, r.upstreamLatency
+}
+
+// MaxVideoAge returns how old video may get in queue before whole frames are dropped, 0 if Room isn't strict latency
+func (r *Room) MaxVideoAge() time.Duration {
+	if !r.GetSettings().StrictLatency {
+		return 0
+	}
+	if r.GetSettings().MaxFrameAge > 0 {
+		return r.GetSettings().MaxFrameAge
+	}
+	return time.Duration(common.GetFlags().MaxFrameAge) * time.Millisecond
+}
+
+// ParticipantQueueSize returns the packet queue size new participants of this room should use
+func (r *Room) ParticipantQueueSize() int {
+	if r.GetSettings().QueueSize > 0 {
+		return r.GetSettings().QueueSize
+	}
+	if r.GetSettings().AudioOnly {
+		return audioOnlyPacketQueueSize
+	}
+	return defaultPacketQueueSize
 }
 
 func (r *Room) BroadcastPacket(kind webrtc.RTPCodecType, pkt *rtp.Packet) {
-	// Lock-free load of channel slice
-	channels := r.participantChannels.Load()
+	// Audio-only rooms never allocate video tracks for participants
+	if kind == webrtc.RTPCodecTypeVideo && r.GetSettings().AudioOnly {
+		return
+	}
+
+	if kind == webrtc.RTPCodecTypeVideo {
+		r.VideoStats.Record(pkt, r.VideoCodec().MimeType)
+	} else {
+		r.AudioStats.Record(pkt, r.AudioCodec().MimeType)
+	}
+
+	// Lock-free load of queue slice
+	participants := r.participantQueues.Load()
 
 	// no participants..
-	if len(*channels) == 0 {
+	if len(*participants) == 0 {
+		return
+	}
+
+	// Large rooms are fanned out by shards, the packet is prepared once for all of them
+	if shards := r.fanoutShards.Load(); shards != nil {
+		fp := r.newFanoutPacket(kind, pkt, len(*shards))
+		for _, shard := range *shards {
+			if !shard.send(fp) {
+				// Shard lags behind ingest or was just stopped, its participants miss the packet like with full queues
+				r.droppedQueueFull.Add(uint64(len(*shard.participants.Load())))
+				fp.release()
+			}
+		}
 		return
 	}
 
-	// Send to each participant channel (non-blocking)
-	for i, ch := range *channels {
-		// Get packet struct from pool
+	fp := r.newFanoutPacket(kind, pkt, 1)
+	r.distribute(*participants, fp)
+	fp.release()
+}
+
+// distribute queues packet for each of participants (non-blocking), every participant gets its own header to rewrite
+func (r *Room) distribute(participants []*Participant, fp *fanoutPacket) {
+	size := int64(len(fp.packet.Payload))
+	for i, participant := range participants {
+		// Lagging shards distribute from an older snapshot, participants which left since get nothing more
+		if participant.Room() != r {
+			continue
+		}
+		// Room used up its queued bytes budget, viewers which aren't keeping up mustn't grow it further
+		if fp.maxQueued > 0 && r.queuedBytes.Load()+size > fp.maxQueued {
+			r.droppedBudget.Add(1)
+			continue
+		}
+
+		// Get packet struct from pool, it shares the prepared packet with other participants
 		pp := participantPacketPool.Get().(*participantPacket)
-		pp.kind = kind
-		pp.packet = pkt
-
-		select {
-		case ch <- pp:
-			// Sent successfully
-		default:
-			// Channel full, drop packet, log?
+		fp.refs.Add(1)
+		pp.shared = fp
+		// Charged before queueing, the writer may release packet right away
+		pp.room = r
+		r.queuedBytes.Add(size)
+
+		switch participant.enqueue(pp, fp.limits, fp.keyframe) {
+		case dropFull:
+			// Queue full, drop packet, log?
 			slog.Warn("Channel full, dropping packet", "channel_index", i)
-			participantPacketPool.Put(pp)
+			r.droppedQueueFull.Add(1)
+			pp.release()
+		case dropEvicted:
+			r.droppedEvicted.Add(1)
+		case dropShedKick:
+			// Viewer can't decode until next keyframe, don't wait for the regular interval
+			if err := r.RequestKeyframe(); err != nil {
+				slog.Debug("Failed to request keyframe for shedding participant", "participant", participant.ID, "err", err)
+			}
+			fallthrough
+		case dropShed:
+			r.droppedShed.Add(1)
+			pp.release()
+		case dropClosed:
+			pp.release()
 		}
 	}
 }
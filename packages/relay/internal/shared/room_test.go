@@ -0,0 +1,168 @@
+package shared
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v4"
+)
+
+// benchPacket is a video packet as received from a pushing node, with one header extension
+func benchPacket() *rtp.Packet {
+	pkt := &rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			PayloadType:    96,
+			SequenceNumber: 1000,
+			Timestamp:      90000,
+			SSRC:           0x1234,
+		},
+		Payload: make([]byte, 1200),
+	}
+	if err := pkt.SetExtension(1, []byte{0x01, 0x02, 0x03}); err != nil {
+		panic(err)
+	}
+	return pkt
+}
+
+// rewriteHeader does what a participant writer does to its copy of a header, retiming and setting an extension
+func rewriteHeader(b *testing.B, packet *rtp.Packet) {
+	packet.SequenceNumber += 7
+	packet.Timestamp += 3000
+	if err := packet.SetExtension(2, []byte{0x10}); err != nil {
+		b.Fatal(err)
+	}
+}
+
+// BenchmarkFanoutPreparedPacket fans a packet out the way distribute and the writers do, preparing it once and
+// sharing it, with rewriting participants copying the header into their own packet
+func BenchmarkFanoutPreparedPacket(b *testing.B) {
+	room := NewRoom("room", ulid.Make(), "")
+	for _, participants := range []int{1, 16, 256} {
+		for _, rewrite := range []bool{false, true} {
+			b.Run(fmt.Sprintf("participants=%d/rewrite=%t", participants, rewrite), func(b *testing.B) {
+				pkt := benchPacket()
+				var out rtp.Packet
+				b.ReportAllocs()
+				for b.Loop() {
+					fp := room.newFanoutPacket(webrtc.RTPCodecTypeVideo, pkt, 1)
+					for range participants {
+						pp := participantPacketPool.Get().(*participantPacket)
+						fp.refs.Add(1)
+						pp.shared = fp
+						if rewrite {
+							extensions := out.Extensions[:0]
+							out.Header = pp.shared.packet.Header
+							out.Extensions = extensions
+							out.Payload = pp.shared.packet.Payload
+							out.PaddingSize = pp.shared.packet.PaddingSize
+							rewriteHeader(b, &out)
+						}
+						pp.release()
+					}
+					fp.release()
+				}
+			})
+		}
+	}
+}
+
+func TestRoomAudioLevel(t *testing.T) {
+	room := NewRoom("room", ulid.Make(), "")
+	audio := func(level uint8, voice bool) *rtp.Packet {
+		pkt := &rtp.Packet{Header: rtp.Header{Version: 2, PayloadType: 111, SSRC: 1}}
+		payload, err := rtp.AudioLevelExtension{Level: level, Voice: voice}.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = pkt.SetExtension(3, payload); err != nil {
+			t.Fatal(err)
+		}
+		return pkt
+	}
+
+	// Levels aren't read before the incoming stream negotiated the extension
+	if got := room.readAudioLevel(audio(10, true)); got != nil {
+		t.Fatalf("read audio level %v without negotiated extension", got)
+	}
+	if _, _, ok := room.TakeAudioLevel(); ok {
+		t.Fatal("took audio level without negotiated extension")
+	}
+
+	room.SetAudioLevelExtension(3)
+	for _, pkt := range []*rtp.Packet{audio(60, false), audio(20, true), audio(90, false)} {
+		if got := room.readAudioLevel(pkt); len(got) != 1 {
+			t.Fatalf("read audio level payload %v, want 1 byte", got)
+		}
+	}
+	level, voice, ok := room.TakeAudioLevel()
+	if !ok || level != 20 || !voice {
+		t.Fatalf("took audio level %d voice %t ok %t, want loudest 20 with voice", level, voice, ok)
+	}
+	if _, _, ok = room.TakeAudioLevel(); ok {
+		t.Fatal("audio level wasn't reset once taken")
+	}
+}
+
+func TestRoomInfoClaimBeats(t *testing.T) {
+	at := time.Unix(1700000000, 0)
+	claim := RoomInfo{Name: "room", OwnerID: "b", Online: true, Epoch: 2, ClaimedAt: at}
+	for _, tc := range []struct {
+		name  string
+		other RoomInfo
+		beats bool
+	}{
+		{"offline", RoomInfo{OwnerID: "a", Online: false, Epoch: 9, ClaimedAt: at.Add(time.Hour)}, true},
+		{"higher epoch", RoomInfo{OwnerID: "a", Online: true, Epoch: 3, ClaimedAt: at.Add(-time.Hour)}, false},
+		{"lower epoch", RoomInfo{OwnerID: "a", Online: true, Epoch: 1, ClaimedAt: at.Add(time.Hour)}, true},
+		{"later push", RoomInfo{OwnerID: "c", Online: true, Epoch: 2, ClaimedAt: at.Add(time.Second)}, false},
+		{"lower owner", RoomInfo{OwnerID: "a", Online: true, Epoch: 2, ClaimedAt: at}, false},
+		{"higher owner", RoomInfo{OwnerID: "c", Online: true, Epoch: 2, ClaimedAt: at}, true},
+	} {
+		if got := claim.ClaimBeats(tc.other); got != tc.beats {
+			t.Errorf("%s: claim beats %t, want %t", tc.name, got, tc.beats)
+		}
+		// Relays on both sides of a partition must pick the same winner
+		if tc.other.ClaimBeats(claim) == tc.beats {
+			t.Errorf("%s: both claims beat each other or neither does", tc.name)
+		}
+	}
+
+	if claim.LeaseExpired(at) {
+		t.Fatal("claim without lease expired")
+	}
+	claim.LeaseExpiry = at.Add(time.Minute)
+	if claim.LeaseExpired(at) || !claim.LeaseExpired(at.Add(2*time.Minute)) {
+		t.Fatal("lease didn't expire at its expiry")
+	}
+}
+
+func TestStreamClockAt(t *testing.T) {
+	at := time.Unix(1700000000, 0)
+	clock := StreamClock{RTPTimestamp: 4294967000, StreamTime: at, ClockRate: 90000}
+	for _, tt := range []struct {
+		ts   uint32
+		want time.Time
+	}{
+		{4294967000, at},
+		{89704, at.Add(time.Second)}, // Wrapped past zero
+		{4294967000 - 45000, at.Add(-time.Second / 2)},
+	} {
+		if got := clock.At(tt.ts); !got.Equal(tt.want) {
+			t.Errorf("At(%d) = %v, want %v", tt.ts, got, tt.want)
+		}
+	}
+
+	room := NewRoom("room", ulid.Make(), "")
+	room.SetStreamClock(clock)
+	if got, ok := room.StreamClock(); !ok || got != clock {
+		t.Fatalf("stream clock %v ok %t, want %v", got, ok, clock)
+	}
+	room.Close()
+	if _, ok := room.StreamClock(); ok {
+		t.Fatal("stream clock kept after room closed")
+	}
+}
@@ -0,0 +1,195 @@
+package shared
+
+import (
+	"relay/internal/common"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+// TrackStats counts forwarded packets of a track, rates are computed by Sample
+type TrackStats struct {
+	packets atomic.Uint64
+	bytes   atomic.Uint64
+	frames  atomic.Uint64
+
+	// Only written by the track reader goroutine
+	lastKeyframeTS   uint32
+	lastKeyframeAt   time.Time
+	keyframeInterval atomic.Int64 // nanoseconds
+
+	sampleMtx  sync.Mutex
+	lastSample time.Time
+	lastBytes  uint64
+	lastFrames uint64
+	bitrate    uint64
+	frameRate  float64
+}
+
+// TrackStatsSnapshot is a point in time view of TrackStats
+type TrackStatsSnapshot struct {
+	Packets          uint64        `json:"packets"`
+	Bytes            uint64        `json:"bytes"`
+	Bitrate          uint64        `json:"bitrate"`    // bits per second
+	FrameRate        float64       `json:"frame_rate"` // frames per second
+	KeyframeInterval time.Duration `json:"keyframe_interval"`
+}
+
+// Record counts a forwarded packet, mimeType is used for keyframe detection
+func (ts *TrackStats) Record(pkt *rtp.Packet, mimeType string) {
+	ts.packets.Add(1)
+	ts.bytes.Add(uint64(len(pkt.Payload)))
+	// Marker bit ends a video frame
+	if pkt.Marker {
+		ts.frames.Add(1)
+	}
+
+	// Several packets of one keyframe may match, count by RTP timestamp
+	if common.IsKeyframe(mimeType, pkt.Payload) && (ts.lastKeyframeAt.IsZero() || pkt.Timestamp != ts.lastKeyframeTS) {
+		now := time.Now()
+		if !ts.lastKeyframeAt.IsZero() {
+			ts.keyframeInterval.Store(int64(now.Sub(ts.lastKeyframeAt)))
+		}
+		ts.lastKeyframeTS = pkt.Timestamp
+		ts.lastKeyframeAt = now
+	}
+}
+
+// Count counts a packet without frame or keyframe tracking
+func (ts *TrackStats) Count(bytes int) {
+	ts.packets.Add(1)
+	ts.bytes.Add(uint64(bytes))
+}
+
+// Sample updates rates from counters since previous sample
+func (ts *TrackStats) Sample(now time.Time) {
+	ts.sampleMtx.Lock()
+	defer ts.sampleMtx.Unlock()
+
+	bytes := ts.bytes.Load()
+	frames := ts.frames.Load()
+	if !ts.lastSample.IsZero() {
+		if elapsed := now.Sub(ts.lastSample).Seconds(); elapsed > 0 {
+			ts.bitrate = uint64(float64(bytes-ts.lastBytes) * 8 / elapsed)
+			ts.frameRate = float64(frames-ts.lastFrames) / elapsed
+		}
+	}
+	ts.lastSample = now
+	ts.lastBytes = bytes
+	ts.lastFrames = frames
+}
+
+// Snapshot returns current counters and rates of last Sample
+func (ts *TrackStats) Snapshot() TrackStatsSnapshot {
+	ts.sampleMtx.Lock()
+	defer ts.sampleMtx.Unlock()
+	return TrackStatsSnapshot{
+		Packets:          ts.packets.Load(),
+		Bytes:            ts.bytes.Load(),
+		Bitrate:          ts.bitrate,
+		FrameRate:        ts.frameRate,
+		KeyframeInterval: time.Duration(ts.keyframeInterval.Load()),
+	}
+}
+
+// ByteMeter counts bytes exchanged with one peer, shared by all media and streams of that peer.
+// Methods of a nil ByteMeter count nothing.
+type ByteMeter struct {
+	in  atomic.Uint64
+	out atomic.Uint64
+}
+
+// AddIn counts bytes received from the peer
+func (m *ByteMeter) AddIn(bytes int) {
+	if m != nil {
+		m.in.Add(uint64(bytes))
+	}
+}
+
+// AddOut counts bytes sent to the peer
+func (m *ByteMeter) AddOut(bytes int) {
+	if m != nil {
+		m.out.Add(uint64(bytes))
+	}
+}
+
+// Load returns bytes received from and sent to the peer so far
+func (m *ByteMeter) Load() (in, out uint64) {
+	if m == nil {
+		return 0, 0
+	}
+	return m.in.Load(), m.out.Load()
+}
+
+// latencyBuckets are upper bounds of LatencyHistogram buckets, latencies above the last fall in an overflow bucket
+var latencyBuckets = [...]time.Duration{
+	2 * time.Millisecond, 5 * time.Millisecond, 10 * time.Millisecond, 15 * time.Millisecond, 20 * time.Millisecond,
+	30 * time.Millisecond, 40 * time.Millisecond, 50 * time.Millisecond, 75 * time.Millisecond, 100 * time.Millisecond,
+	150 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 500 * time.Millisecond, time.Second,
+}
+
+// LatencyHistogram counts measured latencies in fixed buckets, quantiles are read as bucket upper bounds
+type LatencyHistogram struct {
+	mtx     sync.Mutex
+	counts  [len(latencyBuckets) + 1]uint64 // One per latencyBuckets bound and the overflow bucket
+	samples uint64
+	last    time.Duration
+	highest time.Duration
+}
+
+// LatencySnapshot is a point in time view of LatencyHistogram
+type LatencySnapshot struct {
+	Samples uint64        `json:"samples"`
+	Last    time.Duration `json:"last"`
+	P50     time.Duration `json:"p50"`
+	P95     time.Duration `json:"p95"`
+	P99     time.Duration `json:"p99"`
+}
+
+// Observe counts a measured latency
+func (h *LatencyHistogram) Observe(latency time.Duration) {
+	i := 0
+	for i < len(latencyBuckets) && latency > latencyBuckets[i] {
+		i++
+	}
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	h.counts[i]++
+	h.samples++
+	h.last = latency
+	h.highest = max(h.highest, latency)
+}
+
+// Snapshot returns latest latency and quantiles of all observed so far
+func (h *LatencyHistogram) Snapshot() LatencySnapshot {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	return LatencySnapshot{
+		Samples: h.samples,
+		Last:    h.last,
+		P50:     h.quantile(0.5),
+		P95:     h.quantile(0.95),
+		P99:     h.quantile(0.99),
+	}
+}
+
+// quantile returns upper bound of the bucket holding quantile q, the highest latency for the overflow bucket
+func (h *LatencyHistogram) quantile(q float64) time.Duration {
+	if h.samples == 0 {
+		return 0
+	}
+	rank := uint64(q*float64(h.samples-1)) + 1
+	var seen uint64
+	for i, count := range h.counts {
+		seen += count
+		if seen >= rank {
+			if i < len(latencyBuckets) {
+				return min(latencyBuckets[i], h.highest)
+			}
+			break
+		}
+	}
+	return h.highest
+}
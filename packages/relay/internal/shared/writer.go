@@ -0,0 +1,102 @@
+package shared
+
+import (
+	"log/slog"
+	"relay/internal/common"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	writerShardBacklog = 4096 // Participants a shard may have waiting to be serviced before schedulers block
+	writerBatchSize    = 32   // Packets written for one participant before a shard moves on to the next
+)
+
+// writerPool services packet queues of many participants with a fixed number of shard goroutines
+type writerPool struct {
+	shards []*writerShard
+	next   atomic.Uint32 // Round-robin shard assignment
+}
+
+// writerShard writes queued packets of participants scheduled onto it, one participant at a time
+type writerShard struct {
+	ready chan *Participant
+}
+
+var (
+	sharedWriterPool     *writerPool
+	sharedWriterPoolOnce sync.Once
+)
+
+// getWriterPool returns the relay writer pool, nil when participants run their own writer goroutine
+func getWriterPool() *writerPool {
+	sharedWriterPoolOnce.Do(func() {
+		shards := common.GetFlags().WriterShards
+		if shards <= 0 {
+			return
+		}
+		sharedWriterPool = newWriterPool(shards)
+		slog.Info("Writing participant packets with sharded worker pool", "shards", shards)
+	})
+	return sharedWriterPool
+}
+
+func newWriterPool(shards int) *writerPool {
+	wp := &writerPool{shards: make([]*writerShard, shards)}
+	for i := range wp.shards {
+		s := &writerShard{ready: make(chan *Participant, writerShardBacklog)}
+		wp.shards[i] = s
+		go s.run()
+	}
+	return wp
+}
+
+// assign picks the shard that will write packets of a new participant
+func (wp *writerPool) assign() *writerShard {
+	return wp.shards[int(wp.next.Add(1)-1)%len(wp.shards)]
+}
+
+func (s *writerShard) run() {
+	for p := range s.ready {
+		s.service(p)
+	}
+}
+
+// service writes a batch of queued packets of p, scheduling it again if more are left
+func (s *writerShard) service(p *Participant) {
+	for {
+		for range writerBatchSize {
+			select {
+			case pkt, ok := <-p.packetQueue:
+				if !ok {
+					// Closed participants are never scheduled again
+					return
+				}
+				p.writePacket(pkt)
+				continue
+			default:
+			}
+			break
+		}
+
+		// Packets queued before the flag is cleared would otherwise be left without a scheduled writer
+		p.scheduled.Store(false)
+		if len(p.packetQueue) == 0 || !p.scheduled.CompareAndSwap(false, true) {
+			return
+		}
+
+		// Go to the back of the line, keep writing when the shard backlog is full rather than block on own shard
+		select {
+		case s.ready <- p:
+			return
+		default:
+		}
+	}
+}
+
+// schedule queues p for servicing unless it already waits or is being serviced
+func (s *writerShard) schedule(p *Participant) {
+	if p.scheduled.CompareAndSwap(false, true) {
+		s.ready <- p
+	}
+}
@@ -0,0 +1,399 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"relay/internal/common"
+	"relay/internal/core"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	gen "relay/internal/proto"
+
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multiaddr"
+	"github.com/pion/webrtc/v4"
+)
+
+// loadtestOptions configure a load test run against a relay
+type loadtestOptions struct {
+	target      *peer.AddrInfo
+	room        string
+	viewers     int
+	rate        float64       // Viewers started per second
+	joinTimeout time.Duration // Time a viewer may take from request to first media packet
+	duration    time.Duration // Time viewers stay connected after the last one started
+	token       string
+	secret      string
+	stunServer  string
+}
+
+// viewerResult is the outcome of one synthetic viewer
+type viewerResult struct {
+	joined   bool
+	failure  string        // Refusal payload type or error when not joined
+	joinTime time.Duration // From stream request to first received media packet
+	bytes    uint64        // RTP payload bytes received
+	watched  time.Duration // Time media was received for, from first packet until the viewer stopped
+}
+
+// runLoadtest runs "relay loadtest", connecting synthetic viewers to a relay through the real stream request protocol
+func runLoadtest(args []string) int {
+	fs := flag.NewFlagSet("loadtest", flag.ContinueOnError)
+	target := fs.String("target", "", "Multiaddr of relay to test, including its /p2p/ peer ID")
+	room := fs.String("room", "", "Room the viewers request")
+	viewers := fs.Int("viewers", 10, "Synthetic viewers to connect")
+	rate := fs.Float64("rate", 10, "Viewers started per second")
+	joinTimeout := fs.Duration("joinTimeout", 20*time.Second, "Time a viewer may take to receive its first media packet")
+	duration := fs.Duration("duration", 30*time.Second, "Time viewers stay connected after the last one started")
+	token := fs.String("token", "", "Viewer token presented by every viewer, for relays enforcing viewer authorization")
+	secret := fs.String("secret", "", "Room password or invite token presented by every viewer")
+	stunServer := fs.String("stunServer", "", "STUN server of viewers, empty gathers host candidates only")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: relay loadtest --target <multiaddr> --room <room> --viewers <n> [flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if len(*target) <= 0 || len(*room) <= 0 || *viewers <= 0 || *rate <= 0 {
+		fs.Usage()
+		return 2
+	}
+	maddr, err := multiaddr.NewMultiaddr(*target)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "loadtest: invalid target:", err)
+		return 2
+	}
+	info, err := peer.AddrInfoFromP2pAddr(maddr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "loadtest: target must include peer ID:", err)
+		return 2
+	}
+
+	opts := loadtestOptions{
+		target:      info,
+		room:        *room,
+		viewers:     *viewers,
+		rate:        *rate,
+		joinTimeout: *joinTimeout,
+		duration:    *duration,
+		token:       *token,
+		secret:      *secret,
+		stunServer:  *stunServer,
+	}
+	fmt.Printf("Connecting %d viewers to room %s on %s\n", opts.viewers, opts.room, opts.target.ID)
+	results := runViewers(context.Background(), opts)
+	printLoadtestReport(os.Stdout, results)
+	for _, result := range results {
+		if result.joined {
+			return 0
+		}
+	}
+	return 1
+}
+
+// runViewers starts viewers at the configured rate and waits until all of them stopped
+func runViewers(ctx context.Context, opts loadtestOptions) []viewerResult {
+	results := make([]viewerResult, opts.viewers)
+	var wg sync.WaitGroup
+
+	// All viewers stop together, so late ones are measured for at least the configured duration
+	stopCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	interval := time.Duration(float64(time.Second) / opts.rate)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = runViewer(stopCtx, opts)
+		}()
+		if i < len(results)-1 {
+			time.Sleep(interval)
+		}
+	}
+	time.Sleep(opts.duration)
+	stop()
+	wg.Wait()
+	return results
+}
+
+// runViewer connects one viewer with its own libp2p identity, as relays rate limit and meter per peer
+func runViewer(ctx context.Context, opts loadtestOptions) viewerResult {
+	host, err := libp2p.New(libp2p.NoListenAddrs)
+	if err != nil {
+		return viewerResult{failure: "host: " + err.Error()}
+	}
+	defer host.Close()
+
+	joinCtx, cancelJoin := context.WithTimeout(ctx, opts.joinTimeout)
+	defer cancelJoin()
+	if err = host.Connect(joinCtx, *opts.target); err != nil {
+		return viewerResult{failure: "connect: " + err.Error()}
+	}
+	stream, err := host.NewStream(joinCtx, opts.target.ID, core.ProtocolStreamRequest)
+	if err != nil {
+		return viewerResult{failure: "stream: " + err.Error()}
+	}
+	defer stream.Close()
+	safeBRW := common.NewSafeBufioRW(bufio.NewReadWriter(bufio.NewReader(stream), bufio.NewWriter(stream)))
+
+	v := &syntheticViewer{safeBRW: safeBRW, firstPacket: make(chan struct{})}
+	defer v.close()
+	requested := time.Now()
+	reqMsg, err := common.CreateMessage(&gen.ProtoClientRequestRoomStream{
+		RoomName:     opts.room,
+		Token:        opts.token,
+		AccessSecret: opts.secret,
+	}, "request-stream-room", nil)
+	if err != nil {
+		return viewerResult{failure: err.Error()}
+	}
+	if err = safeBRW.SendProto(reqMsg); err != nil {
+		return viewerResult{failure: "request: " + err.Error()}
+	}
+
+	signalingDone := make(chan error, 1)
+	go func() {
+		signalingDone <- v.signal(opts)
+	}()
+
+	select {
+	case <-v.firstPacket:
+	case err = <-signalingDone:
+		if err == nil {
+			err = errors.New("stream closed before media")
+		}
+		return viewerResult{failure: err.Error()}
+	case <-joinCtx.Done():
+		return viewerResult{failure: "join timeout"}
+	}
+	joined := time.Now()
+	result := viewerResult{joined: true, joinTime: joined.Sub(requested)}
+
+	select {
+	case <-ctx.Done():
+	case <-signalingDone:
+		// Stream request closed by relay, media stops with it
+	}
+	result.bytes = v.bytes.Load()
+	result.watched = time.Since(joined)
+	return result
+}
+
+// syntheticViewer is a headless viewer answering relay offers and counting received media
+type syntheticViewer struct {
+	safeBRW     *common.SafeBufioRW
+	mtx         sync.Mutex // Guards pc and closed, answers come from the signaling goroutine
+	pc          *webrtc.PeerConnection
+	closed      bool
+	firstPacket chan struct{}
+	firstOnce   sync.Once
+	bytes       atomic.Uint64
+}
+
+// signal handles messages of the stream request until it fails or is closed, refusals are returned as errors
+func (v *syntheticViewer) signal(opts loadtestOptions) error {
+	iceHelper := common.NewICEHelper(nil)
+	for {
+		var msgWrapper gen.ProtoMessage
+		err := v.safeBRW.ReceiveProto(&msgWrapper)
+		if errors.Is(err, common.ErrMessageRejected) {
+			continue
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if msgWrapper.MessageBase == nil {
+			continue
+		}
+
+		switch payloadType := msgWrapper.MessageBase.PayloadType; payloadType {
+		case "session-assigned", "signaling-progress", "stream-path-info", "request-stream-online":
+		case "request-stream-offline", "request-stream-over-budget", "request-stream-draining",
+			"request-stream-unauthorized", "request-stream-banned", "room-full", "quota-exceeded", "throttled":
+			return errors.New(payloadType)
+		case "ice-candidate":
+			iceMsg := msgWrapper.GetIce()
+			if iceMsg == nil || iceMsg.Candidate == nil {
+				continue
+			}
+			cand := webrtc.ICECandidateInit{
+				Candidate:        iceMsg.Candidate.Candidate,
+				SDPMid:           iceMsg.Candidate.SdpMid,
+				UsernameFragment: iceMsg.Candidate.UsernameFragment,
+			}
+			if iceMsg.Candidate.SdpMLineIndex != nil {
+				idx := uint16(*iceMsg.Candidate.SdpMLineIndex)
+				cand.SDPMLineIndex = &idx
+			}
+			iceHelper.AddCandidate(cand)
+		case "offer":
+			offerMsg := msgWrapper.GetSdp()
+			if offerMsg == nil || offerMsg.Sdp == nil {
+				continue
+			}
+			if err = v.answer(opts, iceHelper, offerMsg.Sdp); err != nil {
+				return fmt.Errorf("answer: %w", err)
+			}
+		}
+	}
+}
+
+// answer answers an offer of the relay, renegotiation offers reuse the PeerConnection
+func (v *syntheticViewer) answer(opts loadtestOptions, iceHelper *common.ICEHelper, offer *gen.RTCSessionDescriptionInit) error {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	if v.closed {
+		return errors.New("viewer closed")
+	}
+	if v.pc == nil {
+		config := webrtc.Configuration{}
+		if len(opts.stunServer) > 0 {
+			config.ICEServers = []webrtc.ICEServer{{URLs: []string{"stun:" + strings.TrimPrefix(opts.stunServer, "stun:")}}}
+		}
+		pc, err := webrtc.NewPeerConnection(config)
+		if err != nil {
+			return err
+		}
+		v.pc = pc
+		iceHelper.SetPeerConnection(pc)
+
+		pc.OnICECandidate(func(candidate *webrtc.ICECandidate) {
+			if candidate == nil {
+				return
+			}
+			candInit := candidate.ToJSON()
+			var sdpMLineIndex *uint32
+			if candInit.SDPMLineIndex != nil {
+				idx := uint32(*candInit.SDPMLineIndex)
+				sdpMLineIndex = &idx
+			}
+			iceMsg, err := common.CreateMessage(&gen.ProtoICE{
+				Candidate: &gen.RTCIceCandidateInit{
+					Candidate:     candInit.Candidate,
+					SdpMLineIndex: sdpMLineIndex,
+					SdpMid:        candInit.SDPMid,
+				},
+			}, "ice-candidate", nil)
+			if err == nil {
+				_ = v.safeBRW.SendProto(iceMsg)
+			}
+		})
+		pc.OnTrack(func(remoteTrack *webrtc.TrackRemote, _ *webrtc.RTPReceiver) {
+			for {
+				pkt, _, err := remoteTrack.ReadRTP()
+				if err != nil {
+					return
+				}
+				v.bytes.Add(uint64(len(pkt.Payload)))
+				v.firstOnce.Do(func() { close(v.firstPacket) })
+			}
+		})
+	}
+
+	if err := v.pc.SetRemoteDescription(webrtc.SessionDescription{
+		SDP:  offer.Sdp,
+		Type: webrtc.NewSDPType(offer.Type),
+	}); err != nil {
+		return err
+	}
+	iceHelper.FlushHeldCandidates()
+	answer, err := v.pc.CreateAnswer(nil)
+	if err != nil {
+		return err
+	}
+	if err = v.pc.SetLocalDescription(answer); err != nil {
+		return err
+	}
+	answerMsg, err := common.CreateMessage(&gen.ProtoSDP{
+		Sdp: &gen.RTCSessionDescriptionInit{
+			Sdp:  answer.SDP,
+			Type: answer.Type.String(),
+		},
+	}, "answer", nil)
+	if err != nil {
+		return err
+	}
+	return v.safeBRW.SendProto(answerMsg)
+}
+
+func (v *syntheticViewer) close() {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	v.closed = true
+	if v.pc != nil {
+		_ = v.pc.Close()
+	}
+}
+
+// printLoadtestReport prints connection success rate, join latency percentiles and received bitrate
+func printLoadtestReport(w io.Writer, results []viewerResult) {
+	var joinTimes []time.Duration
+	var totalBitrate float64
+	failures := make(map[string]int)
+	for _, result := range results {
+		if !result.joined {
+			// Dial errors list every address tried on following lines
+			reason, _, _ := strings.Cut(result.failure, "\n")
+			failures[reason]++
+			continue
+		}
+		joinTimes = append(joinTimes, result.joinTime)
+		if seconds := result.watched.Seconds(); seconds > 0 {
+			totalBitrate += float64(result.bytes) * 8 / seconds
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	defer tw.Flush()
+	fmt.Fprintf(tw, "Viewers\t%d\n", len(results))
+	fmt.Fprintf(tw, "Connected\t%d (%.1f%%)\n", len(joinTimes), float64(len(joinTimes))*100/float64(len(results)))
+	if len(joinTimes) > 0 {
+		slices.Sort(joinTimes)
+		fmt.Fprintf(tw, "Join latency\tp50 %s, p90 %s, p99 %s, max %s\n",
+			percentile(joinTimes, 50), percentile(joinTimes, 90), percentile(joinTimes, 99), percentile(joinTimes, 100))
+		fmt.Fprintf(tw, "Bitrate\t%s per viewer, %s total\n",
+			formatBitrate(totalBitrate/float64(len(joinTimes))), formatBitrate(totalBitrate))
+	}
+
+	reasons := make([]string, 0, len(failures))
+	for reason := range failures {
+		reasons = append(reasons, reason)
+	}
+	sort.Slice(reasons, func(i, j int) bool { return failures[reasons[i]] > failures[reasons[j]] })
+	for _, reason := range reasons {
+		fmt.Fprintf(tw, "Failed\t%d %s\n", failures[reason], reason)
+	}
+}
+
+// percentile returns the p-th percentile of sorted durations
+func percentile(sorted []time.Duration, p int) time.Duration {
+	i := (len(sorted)*p+99)/100 - 1
+	return sorted[max(i, 0)].Round(time.Millisecond)
+}
+
+func formatBitrate(bps float64) string {
+	switch {
+	case bps >= 1e6:
+		return fmt.Sprintf("%.2f Mbps", bps/1e6)
+	case bps >= 1e3:
+		return fmt.Sprintf("%.1f kbps", bps/1e3)
+	default:
+		return fmt.Sprintf("%.0f bps", bps)
+	}
+}
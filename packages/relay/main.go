@@ -2,15 +2,32 @@ package main
 
 import (
 	"context"
+	"io"
 	"log/slog"
 	"os"
 	"os/signal"
 	"relay/internal/common"
 	"relay/internal/core"
 	"syscall"
+	"time"
 )
 
 func main() {
+	// "relay healthcheck [flags]" checks a running relay instead of starting one
+	if len(os.Args) > 1 && os.Args[1] == "healthcheck" {
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+		common.InitFlags()
+		os.Exit(runHealthcheck())
+	}
+	// "relay loadtest [flags]" connects synthetic viewers to a relay instead of starting one
+	if len(os.Args) > 1 && os.Args[1] == "loadtest" {
+		os.Exit(runLoadtest(os.Args[2:]))
+	}
+	// "relay publish [flags]" pushes a generated test pattern to a relay instead of starting one
+	if len(os.Args) > 1 && os.Args[1] == "publish" {
+		os.Exit(runPublish(os.Args[2:]))
+	}
+
 	// Setup main context and stopper
 	mainCtx, mainStopper := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 
@@ -18,17 +35,32 @@ func main() {
 	common.InitFlags()
 	common.GetFlags().DebugLog()
 
-	logLevel := slog.LevelInfo
-	if common.GetFlags().Verbose {
-		logLevel = slog.LevelDebug
+	// Log to stdout and optionally a rotated log file
+	var logOutput io.Writer = os.Stdout
+	if logPath := common.GetFlags().LogFilePath(); len(logPath) > 0 {
+		logFile, err := common.NewRotatingFile(logPath, common.LogFileOptions{
+			MaxSize:    int64(common.GetFlags().LogMaxSize) * 1024 * 1024,
+			Interval:   time.Duration(common.GetFlags().LogRotateInterval) * time.Hour,
+			MaxBackups: common.GetFlags().LogMaxBackups,
+			MaxAge:     time.Duration(common.GetFlags().LogMaxAge) * 24 * time.Hour,
+		})
+		if err != nil {
+			slog.Error("Failed to open log file", "path", logPath, "err", err)
+			mainStopper()
+			return
+		}
+		defer logFile.Close()
+		logOutput = io.MultiWriter(os.Stdout, logFile)
 	}
 
-	// Create the base handler with debug level
-	baseHandler := slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-		Level: logLevel,
-	})
-	customHandler := &common.CustomHandler{Handler: baseHandler}
-	logger := slog.New(customHandler)
+	// Create the handler for configured format
+	logHandler, err := common.NewLogHandler(common.GetFlags().LogFormat, common.LogLevel, logOutput)
+	if err != nil {
+		slog.Error("Failed to create log handler", "err", err)
+		mainStopper()
+		return
+	}
+	logger := slog.New(logHandler)
 	slog.SetDefault(logger)
 
 	// Start relay
@@ -39,12 +71,54 @@ func main() {
 		return
 	}
 
+	// Inject faults for testing recovery paths, needs a relay built with chaos tag
+	if err = common.StartChaos(mainCtx, relay.Host, common.GetFlags().ChaosScenario); err != nil {
+		slog.Error("Failed to start fault injection", "err", err)
+		mainStopper()
+		return
+	}
+
+	// Reload configuration on SIGHUP
+	reloadSignal := make(chan os.Signal, 1)
+	signal.Notify(reloadSignal, syscall.SIGHUP)
+	go func() {
+		for {
+			select {
+			case <-mainCtx.Done():
+				return
+			case <-reloadSignal:
+				_, _ = relay.ReloadConfig()
+			}
+		}
+	}()
+
+	// Push a generated stream for testing viewers and mesh forwarding without a pushing node
+	if testRoom := common.GetFlags().TestRoom; len(testRoom) > 0 {
+		go runTestRoom(mainCtx, relay, testRoom)
+	}
+
 	// Wait for exit signal
 	<-mainCtx.Done()
 	slog.Info("Shutting down gracefully by signal..")
+	common.SdNotifyStatus(common.SdNotifyStopping, "Relay shutting down")
 
-	defaultFile := common.GetFlags().PersistDir + "/peerstore.json"
-	if err = relay.SaveToFile(defaultFile); err != nil {
+	if err = relay.SavePeerStore(); err != nil {
 		slog.Error("Failed to save peer store", "err", err)
 	}
+	if err = relay.SaveUsage(); err != nil {
+		slog.Error("Failed to save usage", "err", err)
+	}
+	if err = relay.Store.Close(); err != nil {
+		slog.Error("Failed to close store", "err", err)
+	}
+	if relay.Shared != nil {
+		if err = relay.Shared.Close(); err != nil {
+			slog.Error("Failed to close shared state", "err", err)
+		}
+	}
+	if relay.Directory != nil {
+		if err = relay.Directory.Close(); err != nil {
+			slog.Error("Failed to close room directory", "err", err)
+		}
+	}
 }
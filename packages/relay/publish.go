@@ -0,0 +1,384 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"relay/internal/common"
+	"relay/internal/core"
+	"syscall"
+	"time"
+
+	gen "relay/internal/proto"
+
+	"github.com/libp2p/go-libp2p"
+	cryptopb "github.com/libp2p/go-libp2p/core/crypto/pb"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multiaddr"
+	"github.com/pion/webrtc/v4"
+	"github.com/pion/webrtc/v4/pkg/media"
+)
+
+// publishOptions configure a synthetic publisher pushing a test pattern to a relay
+type publishOptions struct {
+	target    peer.AddrInfo
+	room      string
+	variant   string // Quality variant to push, empty for the main stream
+	standby   bool   // Stand by for the pushed stream of the room, taking over once it fails
+	width     int
+	height    int
+	frameRate int
+	secret    string             // Push secret to sign the push with, empty for unauthenticated pushes
+	key       ed25519.PrivateKey // Push key to answer push challenges with, nil if relays don't challenge pushes
+}
+
+// runPublish runs "relay publish", pushing a generated test pattern to a relay until interrupted
+func runPublish(args []string) int {
+	fs := flag.NewFlagSet("publish", flag.ContinueOnError)
+	target := fs.String("target", "", "Multiaddr of relay to push to, including its /p2p/ peer ID")
+	room := fs.String("room", "", "Room to create and push the test pattern to")
+	variant := fs.String("variant", "", "Quality variant like 720p30 to push alongside the main stream, empty pushes the main stream")
+	standby := fs.Bool("standby", false, "Push as standby of the online room, forwarded from once its pushed stream fails")
+	width := fs.Int("width", 256, "Test pattern width in pixels, even")
+	height := fs.Int("height", 144, "Test pattern height in pixels, even")
+	frameRate := fs.Int("frameRate", 10, "Test pattern frames per second")
+	secret := fs.String("pushSecret", os.Getenv("PUSH_SECRET"), "Secret to sign the push with, for relays authenticating pushes")
+	keyFile := fs.String("pushKey", os.Getenv("PUSH_KEY"), "ED25519 private key file to answer push challenges with, for relays with trusted push keys")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: relay publish --target <multiaddr> --room <room> [flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if len(*target) <= 0 || len(*room) <= 0 {
+		fs.Usage()
+		return 2
+	}
+	maddr, err := multiaddr.NewMultiaddr(*target)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "publish: invalid target:", err)
+		return 2
+	}
+	info, err := peer.AddrInfoFromP2pAddr(maddr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "publish: target must include peer ID:", err)
+		return 2
+	}
+
+	opts := publishOptions{
+		target:    *info,
+		room:      *room,
+		variant:   *variant,
+		standby:   *standby,
+		width:     *width,
+		height:    *height,
+		frameRate: *frameRate,
+		secret:    *secret,
+	}
+	if len(*keyFile) > 0 {
+		if opts.key, err = common.LoadED25519Key(*keyFile); err != nil {
+			fmt.Fprintln(os.Stderr, "publish:", err)
+			return 2
+		}
+	}
+	if err = opts.validate(); err != nil {
+		fmt.Fprintln(os.Stderr, "publish:", err)
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err = publishTestPattern(ctx, opts); err != nil && !errors.Is(err, context.Canceled) {
+		fmt.Fprintln(os.Stderr, "publish:", err)
+		return 1
+	}
+	return 0
+}
+
+func (opts publishOptions) validate() error {
+	if opts.width <= 0 || opts.height <= 0 || opts.width%2 != 0 || opts.height%2 != 0 {
+		return errors.New("width and height must be positive and even")
+	}
+	if opts.frameRate <= 0 || opts.frameRate > 60 {
+		return errors.New("frame rate must be between 1 and 60")
+	}
+	return nil
+}
+
+// runTestRoom pushes the test pattern to the relay itself for the configured test room, retrying while the relay runs
+func runTestRoom(ctx context.Context, relay *core.Relay, roomName string) {
+	opts := publishOptions{
+		target:    peer.AddrInfo{ID: relay.Host.ID(), Addrs: relay.Host.Addrs()},
+		room:      roomName,
+		width:     256,
+		height:    144,
+		frameRate: 10,
+		secret:    common.GetFlags().PushSecretFor(roomName),
+	}
+	// Relays trust their own identity key to push
+	if identity := relay.Host.Peerstore().PrivKey(relay.Host.ID()); identity != nil && identity.Type() == cryptopb.KeyType_Ed25519 {
+		if raw, err := identity.Raw(); err == nil {
+			opts.key = raw
+		}
+	}
+	for {
+		slog.Info("Pushing test pattern to test room", "room", roomName)
+		err := publishTestPattern(ctx, opts)
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Warn("Test pattern push ended, retrying", "room", roomName, "err", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+// publishTestPattern pushes moving color bars and a tone through the regular push protocol until ctx is done
+// or the push fails
+func publishTestPattern(ctx context.Context, opts publishOptions) error {
+	// Own identity, a relay can't dial itself
+	host, err := libp2p.New(libp2p.NoListenAddrs)
+	if err != nil {
+		return fmt.Errorf("failed to create libp2p host: %w", err)
+	}
+	defer host.Close()
+	if err = host.Connect(ctx, opts.target); err != nil {
+		return fmt.Errorf("failed to connect to relay: %w", err)
+	}
+	stream, err := host.NewStream(ctx, opts.target.ID, core.ProtocolStreamPush)
+	if err != nil {
+		return fmt.Errorf("failed to open push stream: %w", err)
+	}
+	defer stream.Close()
+	safeBRW := common.NewSafeBufioRW(bufio.NewReadWriter(bufio.NewReader(stream), bufio.NewWriter(stream)))
+
+	push := &gen.ProtoServerPushStream{
+		RoomName: opts.room,
+		Variant:  opts.variant,
+		Standby:  opts.standby,
+		Metadata: &gen.ProtoRoomMetadata{
+			Title:     "Test pattern",
+			Width:     uint32(opts.width),
+			Height:    uint32(opts.height),
+			FrameRate: uint32(opts.frameRate),
+		},
+	}
+	if len(opts.secret) > 0 {
+		push.Timestamp = time.Now().Unix()
+		push.Signature = common.SignPush(opts.secret, opts.room, push.Timestamp)
+	}
+	pushMsg, err := common.CreateMessage(push, "push-stream-room", nil)
+	if err != nil {
+		return err
+	}
+	if err = safeBRW.SendProto(pushMsg); err != nil {
+		return fmt.Errorf("failed to send push request: %w", err)
+	}
+
+	pc, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	if err != nil {
+		return fmt.Errorf("failed to create PeerConnection: %w", err)
+	}
+	defer pc.Close()
+	videoTrack, err := webrtc.NewTrackLocalStaticSample(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeH264}, "video", "test-pattern")
+	if err != nil {
+		return err
+	}
+	audioTrack, err := webrtc.NewTrackLocalStaticSample(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus}, "audio", "test-pattern")
+	if err != nil {
+		return err
+	}
+	for _, track := range []webrtc.TrackLocal{videoTrack, audioTrack} {
+		sender, err := pc.AddTrack(track)
+		if err != nil {
+			return fmt.Errorf("failed to add track: %w", err)
+		}
+		// Every frame is a keyframe, RTCP is read only so interceptors keep working
+		go func() {
+			buf := make([]byte, 1500)
+			for {
+				if _, _, err := sender.Read(buf); err != nil {
+					return
+				}
+			}
+		}()
+	}
+	// Pushing nodes open the DataChannel, input sent by viewers arrives on it and is ignored here
+	if _, err = pc.CreateDataChannel("relay-data", nil); err != nil {
+		return fmt.Errorf("failed to create DataChannel: %w", err)
+	}
+
+	iceHelper := common.NewICEHelper(pc)
+	pc.OnICECandidate(func(candidate *webrtc.ICECandidate) {
+		if candidate == nil {
+			return
+		}
+		candInit := candidate.ToJSON()
+		var sdpMLineIndex *uint32
+		if candInit.SDPMLineIndex != nil {
+			idx := uint32(*candInit.SDPMLineIndex)
+			sdpMLineIndex = &idx
+		}
+		iceMsg, err := common.CreateMessage(&gen.ProtoICE{
+			Candidate: &gen.RTCIceCandidateInit{
+				Candidate:     candInit.Candidate,
+				SdpMLineIndex: sdpMLineIndex,
+				SdpMid:        candInit.SDPMid,
+			},
+		}, "ice-candidate", nil)
+		if err == nil {
+			_ = safeBRW.SendProto(iceMsg)
+		}
+	})
+
+	failed := make(chan error, 1)
+	pc.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
+		if state == webrtc.PeerConnectionStateFailed || state == webrtc.PeerConnectionStateClosed {
+			select {
+			case failed <- fmt.Errorf("PeerConnection %s", state):
+			default:
+			}
+		}
+	})
+	go func() {
+		failed <- receivePushSignaling(safeBRW, pc, iceHelper, opts)
+	}()
+
+	mediaCtx, stopMedia := context.WithCancel(ctx)
+	defer stopMedia()
+	go writeTestPattern(mediaCtx, opts, videoTrack, audioTrack)
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err = <-failed:
+		return err
+	}
+}
+
+// receivePushSignaling answers push challenges and sends the offer once the relay accepted the push, then applies
+// its answer and candidates
+func receivePushSignaling(safeBRW *common.SafeBufioRW, pc *webrtc.PeerConnection, iceHelper *common.ICEHelper, opts publishOptions) error {
+	for {
+		var msgWrapper gen.ProtoMessage
+		err := safeBRW.ReceiveProto(&msgWrapper)
+		if errors.Is(err, common.ErrMessageRejected) {
+			continue
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return errors.New("push stream closed by relay")
+			}
+			return err
+		}
+		if msgWrapper.MessageBase == nil {
+			continue
+		}
+
+		switch payloadType := msgWrapper.MessageBase.PayloadType; payloadType {
+		case "push-challenge":
+			challenge := msgWrapper.GetPushChallenge()
+			if challenge == nil || opts.key == nil {
+				return errors.New("relay requires a push key")
+			}
+			resMsg, err := common.CreateMessage(&gen.ProtoPushChallengeResponse{
+				RoomName:  challenge.RoomName,
+				PublicKey: hex.EncodeToString(opts.key.Public().(ed25519.PublicKey)),
+				Signature: common.SignPushChallenge(opts.key, opts.target.ID.String(), challenge.RoomName, challenge.Nonce),
+			}, "push-challenge-response", nil)
+			if err != nil {
+				return err
+			}
+			if err = safeBRW.SendProto(resMsg); err != nil {
+				return err
+			}
+		case "push-stream-ok":
+			offer, err := pc.CreateOffer(nil)
+			if err != nil {
+				return err
+			}
+			if err = pc.SetLocalDescription(offer); err != nil {
+				return err
+			}
+			offerMsg, err := common.CreateMessage(&gen.ProtoSDP{
+				Sdp: &gen.RTCSessionDescriptionInit{
+					Sdp:  offer.SDP,
+					Type: offer.Type.String(),
+				},
+			}, "offer", nil)
+			if err != nil {
+				return err
+			}
+			if err = safeBRW.SendProto(offerMsg); err != nil {
+				return err
+			}
+		case "push-stream-unauthorized":
+			return errors.New("push refused as unauthorized")
+		case "answer":
+			answerMsg := msgWrapper.GetSdp()
+			if answerMsg == nil || answerMsg.Sdp == nil {
+				continue
+			}
+			if err = pc.SetRemoteDescription(webrtc.SessionDescription{
+				SDP:  answerMsg.Sdp.Sdp,
+				Type: webrtc.NewSDPType(answerMsg.Sdp.Type),
+			}); err != nil {
+				return err
+			}
+			iceHelper.FlushHeldCandidates()
+		case "ice-candidate":
+			iceMsg := msgWrapper.GetIce()
+			if iceMsg == nil || iceMsg.Candidate == nil {
+				continue
+			}
+			cand := webrtc.ICECandidateInit{
+				Candidate:        iceMsg.Candidate.Candidate,
+				SDPMid:           iceMsg.Candidate.SdpMid,
+				UsernameFragment: iceMsg.Candidate.UsernameFragment,
+			}
+			if iceMsg.Candidate.SdpMLineIndex != nil {
+				idx := uint16(*iceMsg.Candidate.SdpMLineIndex)
+				cand.SDPMLineIndex = &idx
+			}
+			iceHelper.AddCandidate(cand)
+		}
+	}
+}
+
+// writeTestPattern writes test pattern frames and tone audio in real time until ctx is done
+func writeTestPattern(ctx context.Context, opts publishOptions, videoTrack, audioTrack *webrtc.TrackLocalStaticSample) {
+	pattern := newTestPattern(opts.width, opts.height)
+	frameInterval := time.Second / time.Duration(opts.frameRate)
+	audioInterval := 20 * time.Millisecond
+	videoTicker := time.NewTicker(frameInterval)
+	defer videoTicker.Stop()
+	audioTicker := time.NewTicker(audioInterval)
+	defer audioTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-videoTicker.C:
+			if err := videoTrack.WriteSample(media.Sample{Data: pattern.next(), Duration: frameInterval}); err != nil && !errors.Is(err, io.ErrClosedPipe) {
+				slog.Debug("Failed to write test pattern frame", "err", err)
+			}
+		case <-audioTicker.C:
+			if err := audioTrack.WriteSample(media.Sample{Data: opusToneFrame, Duration: audioInterval}); err != nil && !errors.Is(err, io.ErrClosedPipe) {
+				slog.Debug("Failed to write test pattern audio", "err", err)
+			}
+		}
+	}
+}
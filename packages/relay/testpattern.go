@@ -0,0 +1,216 @@
+package main
+
+// Color bars in BT.601 limited range YCbCr: white, yellow, cyan, green, magenta, red, blue, black
+var testPatternBars = [][3]byte{
+	{235, 128, 128},
+	{210, 16, 146},
+	{170, 166, 16},
+	{145, 54, 34},
+	{106, 202, 222},
+	{81, 90, 240},
+	{41, 240, 110},
+	{16, 128, 128},
+}
+
+// opusToneFrame is a 20ms mono CELT Opus frame of a 450Hz tone at -12dBFS. 450Hz is a whole number of cycles per
+// frame and the frame uses intra energy coding, so it decodes the same every time and loops without clicks.
+var opusToneFrame = []byte{
+	0xf8, 0x68, 0x5c, 0x31, 0x1f, 0xd8, 0xbb, 0x47, 0x92, 0xea, 0x00, 0x08,
+	0x80, 0xa8, 0xa5, 0xf6, 0x66, 0x59, 0xc9, 0x60, 0xa6, 0x09, 0x7d, 0xc2,
+	0x33, 0x92, 0x74, 0xf3, 0x19, 0xfe, 0x6b, 0x73, 0xff, 0xf0, 0x20, 0x52,
+}
+
+// testPattern generates H.264 frames of color bars moving sideways. Every frame is an IDR picture of uncompressed
+// I_PCM macroblocks, so no encoder is needed and viewers can start decoding at any frame.
+type testPattern struct {
+	width, height int
+	mbWidth       int
+	mbHeight      int
+	frame         int
+	paramSets     []byte // Annex B SPS and PPS, sent with every frame
+}
+
+func newTestPattern(width, height int) *testPattern {
+	tp := &testPattern{
+		width:    width,
+		height:   height,
+		mbWidth:  (width + 15) / 16,
+		mbHeight: (height + 15) / 16,
+	}
+	tp.paramSets = append(annexBNAL(0x67, tp.sps()), annexBNAL(0x68, tp.pps())...)
+	return tp
+}
+
+// sps returns sequence parameter set RBSP, constrained baseline with cropping to the exact size
+func (tp *testPattern) sps() []byte {
+	var w bitWriter
+	w.bits(66, 8)   // profile_idc, baseline
+	w.bits(0xc0, 8) // constraint_set0 and constraint_set1 flags, constrained baseline
+	w.bits(31, 8)   // level_idc 3.1
+	w.ue(0)         // seq_parameter_set_id
+	w.ue(0)         // log2_max_frame_num_minus4
+	w.ue(2)         // pic_order_cnt_type, output order is decoding order
+	w.ue(1)         // max_num_ref_frames
+	w.bits(0, 1)    // gaps_in_frame_num_value_allowed_flag
+	w.ue(uint32(tp.mbWidth - 1))
+	w.ue(uint32(tp.mbHeight - 1))
+	w.bits(1, 1) // frame_mbs_only_flag
+	w.bits(1, 1) // direct_8x8_inference_flag
+	cropRight, cropBottom := tp.mbWidth*16-tp.width, tp.mbHeight*16-tp.height
+	if cropRight > 0 || cropBottom > 0 {
+		// Crop offsets are in chroma sample pairs for 4:2:0
+		w.bits(1, 1)
+		w.ue(0)
+		w.ue(uint32(cropRight / 2))
+		w.ue(0)
+		w.ue(uint32(cropBottom / 2))
+	} else {
+		w.bits(0, 1)
+	}
+	w.bits(0, 1) // vui_parameters_present_flag
+	w.trailing()
+	return w.buf
+}
+
+// pps returns picture parameter set RBSP, CAVLC without deblocking control
+func (tp *testPattern) pps() []byte {
+	var w bitWriter
+	w.ue(0)      // pic_parameter_set_id
+	w.ue(0)      // seq_parameter_set_id
+	w.bits(0, 1) // entropy_coding_mode_flag, CAVLC
+	w.bits(0, 1) // bottom_field_pic_order_in_frame_present_flag
+	w.ue(0)      // num_slice_groups_minus1
+	w.ue(0)      // num_ref_idx_l0_default_active_minus1
+	w.ue(0)      // num_ref_idx_l1_default_active_minus1
+	w.bits(0, 1) // weighted_pred_flag
+	w.bits(0, 2) // weighted_bipred_idc
+	w.se(0)      // pic_init_qp_minus26
+	w.se(0)      // pic_init_qs_minus26
+	w.se(0)      // chroma_qp_index_offset
+	w.bits(1, 1) // deblocking_filter_control_present_flag
+	w.bits(0, 1) // constrained_intra_pred_flag
+	w.bits(0, 1) // redundant_pic_cnt_present_flag
+	w.trailing()
+	return w.buf
+}
+
+// next returns the next frame as Annex B access unit with parameter sets
+func (tp *testPattern) next() []byte {
+	var w bitWriter
+	// Slice header
+	w.ue(0)                    // first_mb_in_slice
+	w.ue(7)                    // slice_type, I and all slices of the picture are I
+	w.ue(0)                    // pic_parameter_set_id
+	w.bits(0, 4)               // frame_num, always 0 for IDR pictures
+	w.ue(uint32(tp.frame % 2)) // idr_pic_id, consecutive IDR pictures must differ
+	w.bits(0, 1)               // no_output_of_prior_pics_flag
+	w.bits(0, 1)               // long_term_reference_flag
+	w.se(0)                    // slice_qp_delta
+	w.ue(1)                    // disable_deblocking_filter_idc, PCM needs no deblocking
+
+	// Bars move two pixels per frame, wrapping around
+	barWidth := max(tp.width/len(testPatternBars), 1)
+	shift := tp.frame * 2
+	color := func(x int) [3]byte {
+		return testPatternBars[((x+shift)/barWidth)%len(testPatternBars)]
+	}
+	for mbY := 0; mbY < tp.mbHeight; mbY++ {
+		for mbX := 0; mbX < tp.mbWidth; mbX++ {
+			w.ue(25) // mb_type I_PCM
+			w.align()
+			for y := 0; y < 16; y++ {
+				for x := 0; x < 16; x++ {
+					w.byte(color(mbX*16 + x)[0])
+				}
+			}
+			for plane := 1; plane <= 2; plane++ {
+				for y := 0; y < 8; y++ {
+					for x := 0; x < 8; x++ {
+						w.byte(color(mbX*16 + x*2)[plane])
+					}
+				}
+			}
+		}
+	}
+	w.trailing()
+	tp.frame++
+
+	frame := make([]byte, 0, len(tp.paramSets)+len(w.buf)+len(w.buf)/64+8)
+	frame = append(frame, tp.paramSets...)
+	return append(frame, annexBNAL(0x65, w.buf)...)
+}
+
+// annexBNAL returns NAL unit with start code and header byte, inserting emulation prevention bytes into rbsp
+func annexBNAL(header byte, rbsp []byte) []byte {
+	nal := append(make([]byte, 0, len(rbsp)+len(rbsp)/64+5), 0, 0, 0, 1, header)
+	zeros := 0
+	for _, b := range rbsp {
+		if zeros >= 2 && b <= 3 {
+			nal = append(nal, 3)
+			zeros = 0
+		}
+		nal = append(nal, b)
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+	}
+	return nal
+}
+
+// bitWriter writes H.264 syntax elements most significant bit first
+type bitWriter struct {
+	buf  []byte
+	cur  byte
+	used uint8 // Bits used of cur
+}
+
+func (w *bitWriter) bits(v uint32, n int) {
+	for i := n - 1; i >= 0; i-- {
+		w.cur = w.cur<<1 | byte(v>>uint(i)&1)
+		w.used++
+		if w.used == 8 {
+			w.buf = append(w.buf, w.cur)
+			w.cur, w.used = 0, 0
+		}
+	}
+}
+
+// byte writes a whole byte, writer must be byte aligned
+func (w *bitWriter) byte(b byte) {
+	w.buf = append(w.buf, b)
+}
+
+// ue writes unsigned Exp-Golomb code
+func (w *bitWriter) ue(v uint32) {
+	v++
+	n := 0
+	for x := v; x > 1; x >>= 1 {
+		n++
+	}
+	w.bits(0, n)
+	w.bits(v, n+1)
+}
+
+// se writes signed Exp-Golomb code
+func (w *bitWriter) se(v int32) {
+	if v > 0 {
+		w.ue(uint32(2*v - 1))
+	} else {
+		w.ue(uint32(-2 * v))
+	}
+}
+
+// align pads with zero bits to the next byte boundary
+func (w *bitWriter) align() {
+	if w.used > 0 {
+		w.bits(0, int(8-w.used))
+	}
+}
+
+// trailing writes RBSP stop bit and alignment
+func (w *bitWriter) trailing() {
+	w.bits(1, 1)
+	w.align()
+}
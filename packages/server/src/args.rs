@@ -58,6 +58,70 @@ impl Args {
                     .env("NESTRI_ROOM")
                     .help("Nestri room name/identifier"),
             )
+            .arg(
+                Arg::new("room-title")
+                    .long("room-title")
+                    .env("NESTRI_ROOM_TITLE")
+                    .help("Room title shown in room lists"),
+            )
+            .arg(
+                Arg::new("room-game")
+                    .long("room-game")
+                    .env("NESTRI_ROOM_GAME")
+                    .help("Name of the game being streamed, shown in room lists"),
+            )
+            .arg(
+                Arg::new("room-private")
+                    .long("room-private")
+                    .env("NESTRI_ROOM_PRIVATE")
+                    .help("Leave room out of relay room directories")
+                    .value_parser(BoolishValueParser::new())
+                    .default_value("false"),
+            )
+            .arg(
+                Arg::new("room-variant")
+                    .long("room-variant")
+                    .env("NESTRI_ROOM_VARIANT")
+                    .help("Push as a quality variant of the room, like '720p30'")
+                    .value_parser(NonEmptyStringValueParser::new()),
+            )
+            .arg(
+                Arg::new("room-standby")
+                    .long("room-standby")
+                    .env("NESTRI_ROOM_STANDBY")
+                    .help("Push as standby of the online room, taking over once its pushed stream fails")
+                    .value_parser(BoolishValueParser::new())
+                    .default_value("false"),
+            )
+            .arg(
+                Arg::new("room-access-secret")
+                    .long("room-access-secret")
+                    .env("NESTRI_ROOM_ACCESS_SECRET")
+                    .help("Password or invite token viewers must present to watch the room")
+                    .value_parser(NonEmptyStringValueParser::new()),
+            )
+            .arg(
+                Arg::new("audio-only")
+                    .long("audio-only")
+                    .env("AUDIO_ONLY")
+                    .help("Push a voice room carrying only audio, no display is captured or video encoded")
+                    .value_parser(BoolishValueParser::new())
+                    .default_value("false"),
+            )
+            .arg(
+                Arg::new("push-secret")
+                    .long("push-secret")
+                    .env("NESTRI_PUSH_SECRET")
+                    .help("Secret the stream push is signed with, must match push secret of the relay")
+                    .value_parser(NonEmptyStringValueParser::new()),
+            )
+            .arg(
+                Arg::new("push-key")
+                    .long("push-key")
+                    .env("NESTRI_PUSH_KEY")
+                    .help("ED25519 private key file answering push challenges, must be trusted by the relay")
+                    .value_parser(NonEmptyStringValueParser::new()),
+            )
             .arg(
                 Arg::new("vimputti-path")
                     .long("vimputti-path")
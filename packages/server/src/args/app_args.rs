@@ -11,6 +11,24 @@ pub struct AppArgs {
     pub relay_url: String,
     /// Nestri room name/identifier
     pub room: String,
+    /// Room title shown in room lists
+    pub room_title: Option<String>,
+    /// Name of the game being streamed
+    pub room_game: Option<String>,
+    /// Leave room out of relay room directories
+    pub room_private: bool,
+    /// Quality variant pushed alongside the main stream of the room
+    pub room_variant: Option<String>,
+    /// Push as standby taking over once the pushed stream of the room fails
+    pub room_standby: bool,
+    /// Password or invite token viewers must present
+    pub room_access_secret: Option<String>,
+    /// Push a voice room without video
+    pub audio_only: bool,
+    /// Secret the stream push is signed with
+    pub push_secret: Option<String>,
+    /// ED25519 private key file push challenges are signed with
+    pub push_key: Option<String>,
 
     /// vimputti socket path
     pub vimputti_path: Option<String>,
@@ -51,6 +69,26 @@ impl AppArgs {
                 .get_one::<String>("room")
                 .unwrap_or(&rand::random::<u32>().to_string())
                 .clone(),
+            room_title: matches.get_one::<String>("room-title").map(|s| s.clone()),
+            room_game: matches.get_one::<String>("room-game").map(|s| s.clone()),
+            room_private: matches
+                .get_one::<bool>("room-private")
+                .unwrap_or(&false)
+                .clone(),
+            room_variant: matches.get_one::<String>("room-variant").map(|s| s.clone()),
+            room_standby: matches
+                .get_one::<bool>("room-standby")
+                .unwrap_or(&false)
+                .clone(),
+            room_access_secret: matches
+                .get_one::<String>("room-access-secret")
+                .map(|s| s.clone()),
+            audio_only: matches
+                .get_one::<bool>("audio-only")
+                .unwrap_or(&false)
+                .clone(),
+            push_secret: matches.get_one::<String>("push-secret").map(|s| s.clone()),
+            push_key: matches.get_one::<String>("push-key").map(|s| s.clone()),
             vimputti_path: matches
                 .get_one::<String>("vimputti-path")
                 .map(|s| s.clone()),
@@ -76,6 +114,31 @@ impl AppArgs {
         tracing::info!("> framerate: {}", self.framerate);
         tracing::info!("> relay_url: '{}'", self.relay_url);
         tracing::info!("> room: '{}'", self.room);
+        tracing::info!(
+            "> room_title: '{}'",
+            self.room_title.as_ref().map_or("None", |s| s.as_str())
+        );
+        tracing::info!(
+            "> room_game: '{}'",
+            self.room_game.as_ref().map_or("None", |s| s.as_str())
+        );
+        tracing::info!("> room_private: {}", self.room_private);
+        tracing::info!(
+            "> room_variant: '{}'",
+            self.room_variant.as_ref().map_or("None", |s| s.as_str())
+        );
+        tracing::info!("> room_standby: {}", self.room_standby);
+        tracing::info!("> audio_only: {}", self.audio_only);
+        // Don't log secrets
+        tracing::info!(
+            "> room_access_secret: {}",
+            self.room_access_secret.is_some()
+        );
+        tracing::info!("> push_secret: {}", self.push_secret.is_some());
+        tracing::info!(
+            "> push_key: '{}'",
+            self.push_key.as_ref().map_or("None", |s| s.as_str())
+        );
         tracing::info!(
             "> vimputti_path: '{}'",
             self.vimputti_path.as_ref().map_or("None", |s| s.as_str())
@@ -45,7 +45,7 @@ impl ControllerInput {
 pub struct ControllerManager {
     vimputti_client: Arc<vimputti::client::VimputtiClient>,
     cmd_tx: mpsc::Sender<Payload>,
-    rumble_tx: mpsc::Sender<(u32, u16, u16, u16, String)>, // (slot, strong, weak, duration_ms, session_id)
+    rumble_tx: mpsc::Sender<(u32, u16, u16, u16, String)>, // (session_slot, strong, weak, duration_ms, session_id)
     attach_tx: mpsc::Sender<ProtoControllerAttach>,
 }
 impl ControllerManager {
@@ -129,8 +129,10 @@ async fn command_loop(
                             .controller
                             .device_mut()
                             .on_rumble(move |strong, weak, duration_ms| {
+                                // Rumble is addressed with the slot the session attached, so relays and
+                                // clients can route it to the controller it's for
                                 let _ = rumble_tx.try_send((
-                                    existing_slot,
+                                    session_slot as u32,
                                     strong,
                                     weak,
                                     duration_ms,
@@ -183,7 +185,7 @@ async fn command_loop(
                             .device_mut()
                             .on_rumble(move |strong, weak, duration_ms| {
                                 let _ = rumble_tx.try_send((
-                                    slot,
+                                    session_slot as u32,
                                     strong,
                                     weak,
                                     duration_ms,
@@ -14,9 +14,12 @@ use crate::gpu::{GPUInfo, GPUVendor};
 use crate::input::controller::ControllerManager;
 use crate::nestrisink::NestriSignaller;
 use crate::p2p::p2p::NestriP2P;
+use crate::proto::proto::{ProtoRoomMetadata, ProtoRoomSettings};
 use gstreamer::prelude::*;
+use gstreamer_rtp::prelude::RTPHeaderExtensionExt;
 use gstrswebrtc::signaller::Signallable;
 use gstrswebrtc::webrtcsink::BaseWebRTCSink;
+use libp2p::identity::ed25519;
 use std::error::Error;
 use std::str::FromStr;
 use std::sync::Arc;
@@ -24,6 +27,10 @@ use tokio_stream::StreamExt;
 use tracing_subscriber::EnvFilter;
 use tracing_subscriber::filter::LevelFilter;
 
+// ssrc-audio-level header extension (RFC 6464), ID is clear of the ones webrtcsink assigns from 1 up
+const AUDIO_LEVEL_EXTENSION_URI: &str = "urn:ietf:params:rtp-hdrext:ssrc-audio-level";
+const AUDIO_LEVEL_EXTENSION_ID: u32 = 10;
+
 // Handles gathering GPU information and selecting the most suitable GPU
 fn handle_gpus(args: &args::Args) -> Result<Vec<GPUInfo>, Box<dyn Error>> {
     tracing::info!("Gathering GPU information..");
@@ -182,6 +189,176 @@ fn handle_encoder_audio(args: &args::Args) -> String {
     audio_encoder
 }
 
+/// Loads ED25519 push key file, the 64 bytes of secret and public key relays use for identity keys too
+fn load_push_key(path: &str) -> Result<ed25519::Keypair, Box<dyn Error>> {
+    let mut bytes = std::fs::read(path)?;
+    Ok(ed25519::Keypair::try_from_bytes(&mut bytes)?)
+}
+
+/// Builds display capture and video encoding into pipeline, feeding webrtcsink
+fn link_video_branch(
+    args: &args::Args,
+    pipeline: &gstreamer::Pipeline,
+    webrtcsink: &gstreamer::Element,
+    video_source: &gstreamer::Element,
+    video_encoder_info: &enc_helper::VideoEncoderInfo,
+) -> Result<(), Box<dyn Error>> {
+    // Caps Filter Element (resolution, fps)
+    let caps_filter = gstreamer::ElementFactory::make("capsfilter").build()?;
+    let caps = gstreamer::Caps::from_str(&format!(
+        "{},width={},height={},framerate={}/1{}",
+        if args.app.zero_copy {
+            if video_encoder_info.encoder_api == EncoderAPI::NVENC {
+                "video/x-raw(memory:CUDAMemory)"
+            } else {
+                "video/x-raw(memory:DMABuf)"
+            }
+        } else {
+            "video/x-raw"
+        },
+        args.app.resolution.0,
+        args.app.resolution.1,
+        args.app.framerate,
+        if args.app.zero_copy {
+            ""
+        } else {
+            ",format=RGBx"
+        }
+    ))?;
+    caps_filter.set_property("caps", &caps);
+
+    // Get bit-depth and choose appropriate format (NV12 or P010_10LE)
+    // H.264 does not support above 8-bit. Also we require DMA-BUF.
+    let video_format = if args.encoding.video.bit_depth == 10
+        && args.app.zero_copy
+        && video_encoder_info.codec != enc_helper::VideoCodec::H264
+    {
+        "P010_10LE"
+    } else {
+        "NV12"
+    };
+
+    // vapostproc for VA compatible encoders
+    let mut vapostproc = None;
+    let mut va_caps_filter = None;
+    if video_encoder_info.encoder_api == EncoderAPI::VAAPI
+        || video_encoder_info.encoder_api == EncoderAPI::QSV
+    {
+        vapostproc = Some(gstreamer::ElementFactory::make("vapostproc").build()?);
+        // VA caps filter
+        let caps_filter = gstreamer::ElementFactory::make("capsfilter").build()?;
+        let va_caps = gstreamer::Caps::from_str(
+            format!("video/x-raw(memory:VAMemory),format={video_format}").as_str(),
+        )?;
+        caps_filter.set_property("caps", &va_caps);
+        va_caps_filter = Some(caps_filter);
+    }
+
+    // Video Converter Element
+    let mut video_converter = None;
+    if !args.app.zero_copy {
+        video_converter = Some(gstreamer::ElementFactory::make("videoconvert").build()?);
+    }
+
+    // Video Encoder Element
+    let video_encoder =
+        gstreamer::ElementFactory::make(video_encoder_info.name.as_str()).build()?;
+    video_encoder_info.apply_parameters(&video_encoder, args.app.verbose);
+
+    // Video parser Element
+    let video_parser;
+    match video_encoder_info.codec {
+        enc_helper::VideoCodec::H264 => {
+            video_parser = Some(
+                gstreamer::ElementFactory::make("h264parse")
+                    .property("config-interval", -1i32)
+                    .build()?,
+            );
+        }
+        enc_helper::VideoCodec::H265 => {
+            video_parser = Some(
+                gstreamer::ElementFactory::make("h265parse")
+                    .property("config-interval", -1i32)
+                    .build()?,
+            );
+        }
+        _ => {
+            video_parser = None;
+        }
+    }
+
+    // Sink queue
+    let video_sink_queue = gstreamer::ElementFactory::make("queue").build()?;
+
+    // Source queue
+    let video_source_queue = gstreamer::ElementFactory::make("queue")
+        .property("max-size-buffers", 2u32)
+        .property("max-size-time", 0u64)
+        .property("max-size-bytes", 0u32)
+        .build()?;
+
+    pipeline.add_many(&[
+        &video_sink_queue,
+        &video_encoder,
+        &caps_filter,
+        &video_source_queue,
+        video_source,
+    ])?;
+
+    if let Some(video_converter) = &video_converter {
+        pipeline.add(video_converter)?;
+    }
+
+    if let Some(parser) = &video_parser {
+        pipeline.add(parser)?;
+    }
+
+    // If zero-copy..
+    if args.app.zero_copy {
+        // VA-API / QSV pipeline
+        if let (Some(vapostproc), Some(va_caps_filter)) = (&vapostproc, &va_caps_filter) {
+            pipeline.add_many(&[vapostproc, va_caps_filter])?;
+        }
+    }
+
+    // With zero-copy..
+    if args.app.zero_copy {
+        // VA-API / QSV pipeline
+        if let (Some(vapostproc), Some(va_caps_filter)) = (&vapostproc, &va_caps_filter) {
+            gstreamer::Element::link_many(&[
+                video_source,
+                &caps_filter,
+                &video_source_queue,
+                &vapostproc,
+                &va_caps_filter,
+                &video_encoder,
+            ])?;
+        } else if video_encoder_info.encoder_api == EncoderAPI::NVENC {
+            // NVENC pipeline
+            gstreamer::Element::link_many(&[video_source, &caps_filter, &video_encoder])?;
+        }
+    } else {
+        gstreamer::Element::link_many(&[
+            video_source,
+            &caps_filter,
+            &video_source_queue,
+            &video_converter.unwrap(),
+            &video_encoder,
+        ])?;
+    }
+
+    // Link video parser if present with webrtcsink, otherwise just link webrtc sink
+    if let Some(parser) = &video_parser {
+        gstreamer::Element::link_many(&[&video_encoder, parser, &video_sink_queue, webrtcsink])?;
+    } else {
+        gstreamer::Element::link_many(&[&video_encoder, &video_sink_queue, webrtcsink])?;
+    }
+
+    video_source.set_property("do-timestamp", &false);
+
+    Ok(())
+}
+
 #[tokio::main]
 async fn main() -> Result<(), Box<dyn Error>> {
     tracing_subscriber::fmt()
@@ -222,26 +399,31 @@ async fn main() -> Result<(), Box<dyn Error>> {
         }
     }
 
-    // Handle GPU selection
-    let gpus = match handle_gpus(&args) {
-        Ok(gpu) => gpu,
-        Err(e) => {
-            tracing::error!("Failed to find a suitable GPU: {}", e);
-            return Err(e);
-        }
-    };
+    // Audio-only pushes capture no display, so need no GPU or video encoder
+    let video_encoder_info = if args.app.audio_only {
+        None
+    } else {
+        // Handle GPU selection
+        let gpus = match handle_gpus(&args) {
+            Ok(gpu) => gpu,
+            Err(e) => {
+                tracing::error!("Failed to find a suitable GPU: {}", e);
+                return Err(e);
+            }
+        };
 
-    // Handle video encoder selection
-    let mut video_encoder_info = match handle_encoder_video(&args, &gpus) {
-        Ok(encoder) => encoder,
-        Err(e) => {
-            tracing::error!("Failed to find a suitable video encoder: {}", e);
-            return Err(e);
-        }
-    };
+        // Handle video encoder selection
+        let video_encoder_info = match handle_encoder_video(&args, &gpus) {
+            Ok(encoder) => encoder,
+            Err(e) => {
+                tracing::error!("Failed to find a suitable video encoder: {}", e);
+                return Err(e);
+            }
+        };
 
-    // Handle video encoder settings
-    video_encoder_info = handle_encoder_video_settings(&args, &video_encoder_info);
+        // Handle video encoder settings
+        Some(handle_encoder_video_settings(&args, &video_encoder_info))
+    };
 
     // Handle audio encoder selection
     let audio_encoder = handle_encoder_audio(&args);
@@ -307,6 +489,12 @@ async fn main() -> Result<(), Box<dyn Error>> {
     let audio_caps = gstreamer::Caps::from_str("audio/x-raw,rate=48000,channels=2")?;
     audio_capsfilter.set_property("caps", &audio_caps);
 
+    // Audio Level Element, attaches RFC 6464 levels to buffers for the ssrc-audio-level extension
+    let audio_level = gstreamer::ElementFactory::make("level")
+        .property("audio-level-meta", true)
+        .property("post-messages", false)
+        .build()?;
+
     // Audio Encoder Element
     let audio_encoder = gstreamer::ElementFactory::make(audio_encoder.as_str()).build()?;
     audio_encoder.set_property(
@@ -330,102 +518,63 @@ async fn main() -> Result<(), Box<dyn Error>> {
     }
 
     /* Video */
-    // Video Source Element
-    let video_source = Arc::new(gstreamer::ElementFactory::make("waylanddisplaysrc").build()?);
-    if args.app.software_render {
-        video_source.set_property_from_str("render-node", "software");
-    } else if let Some(gpu_info) = &video_encoder_info.gpu_info {
-        video_source.set_property_from_str("render-node", gpu_info.render_path());
-    }
-
-    // Caps Filter Element (resolution, fps)
-    let caps_filter = gstreamer::ElementFactory::make("capsfilter").build()?;
-    let caps = gstreamer::Caps::from_str(&format!(
-        "{},width={},height={},framerate={}/1{}",
-        if args.app.zero_copy {
-            if video_encoder_info.encoder_api == EncoderAPI::NVENC {
-                "video/x-raw(memory:CUDAMemory)"
-            } else {
-                "video/x-raw(memory:DMABuf)"
+    // Video Source Element, none for audio-only pushes
+    let video_source = match &video_encoder_info {
+        Some(video_encoder_info) => {
+            let video_source =
+                Arc::new(gstreamer::ElementFactory::make("waylanddisplaysrc").build()?);
+            if args.app.software_render {
+                video_source.set_property_from_str("render-node", "software");
+            } else if let Some(gpu_info) = &video_encoder_info.gpu_info {
+                video_source.set_property_from_str("render-node", gpu_info.render_path());
             }
-        } else {
-            "video/x-raw"
-        },
-        args.app.resolution.0,
-        args.app.resolution.1,
-        args.app.framerate,
-        if args.app.zero_copy {
-            ""
-        } else {
-            ",format=RGBx"
+            Some(video_source)
         }
-    ))?;
-    caps_filter.set_property("caps", &caps);
-
-    // Get bit-depth and choose appropriate format (NV12 or P010_10LE)
-    // H.264 does not support above 8-bit. Also we require DMA-BUF.
-    let video_format = if args.encoding.video.bit_depth == 10
-        && args.app.zero_copy
-        && video_encoder_info.codec != enc_helper::VideoCodec::H264
-    {
-        "P010_10LE"
-    } else {
-        "NV12"
+        None => None,
     };
 
-    // vapostproc for VA compatible encoders
-    let mut vapostproc = None;
-    let mut va_caps_filter = None;
-    if video_encoder_info.encoder_api == EncoderAPI::VAAPI
-        || video_encoder_info.encoder_api == EncoderAPI::QSV
-    {
-        vapostproc = Some(gstreamer::ElementFactory::make("vapostproc").build()?);
-        // VA caps filter
-        let caps_filter = gstreamer::ElementFactory::make("capsfilter").build()?;
-        let va_caps = gstreamer::Caps::from_str(
-            format!("video/x-raw(memory:VAMemory),format={video_format}").as_str(),
-        )?;
-        caps_filter.set_property("caps", &va_caps);
-        va_caps_filter = Some(caps_filter);
-    }
-
-    // Video Converter Element
-    let mut video_converter = None;
-    if !args.app.zero_copy {
-        video_converter = Some(gstreamer::ElementFactory::make("videoconvert").build()?);
-    }
-
-    // Video Encoder Element
-    let video_encoder =
-        gstreamer::ElementFactory::make(video_encoder_info.name.as_str()).build()?;
-    video_encoder_info.apply_parameters(&video_encoder, args.app.verbose);
-
-    // Video parser Element
-    let video_parser;
-    match video_encoder_info.codec {
-        enc_helper::VideoCodec::H264 => {
-            video_parser = Some(
-                gstreamer::ElementFactory::make("h264parse")
-                    .property("config-interval", -1i32)
-                    .build()?,
-            );
-        }
-        enc_helper::VideoCodec::H265 => {
-            video_parser = Some(
-                gstreamer::ElementFactory::make("h265parse")
-                    .property("config-interval", -1i32)
-                    .build()?,
-            );
-        }
-        _ => {
-            video_parser = None;
-        }
-    }
-
     /* Output */
     // WebRTC sink Element
+    // Voice rooms have no video size
+    let (width, height, frame_rate) = if args.app.audio_only {
+        (0, 0, 0)
+    } else {
+        (
+            args.app.resolution.0,
+            args.app.resolution.1,
+            args.app.framerate,
+        )
+    };
+    let room_metadata = ProtoRoomMetadata {
+        title: args.app.room_title.clone().unwrap_or_default(),
+        game: args.app.room_game.clone().unwrap_or_default(),
+        width,
+        height,
+        frame_rate,
+        private: args.app.room_private,
+        // Set by the relay once it has a thumbnail of the room
+        thumbnail_url: String::new(),
+    };
+    // Settings left at zero use relay defaults, sent only if any is set
+    let room_settings = ProtoRoomSettings {
+        audio_only: args.app.audio_only,
+        access_secret: args.app.room_access_secret.clone().unwrap_or_default(),
+        ..Default::default()
+    };
+    let room_settings = (room_settings != ProtoRoomSettings::default()).then_some(room_settings);
+    // Relays with trusted push keys challenge pushes, answered with our push key
+    let push_key = match args.app.push_key.as_deref() {
+        Some(path) => Some(load_push_key(path)?),
+        None => None,
+    };
     let signaller = NestriSignaller::new(
         args.app.room,
+        room_metadata,
+        room_settings,
+        args.app.room_variant.clone(),
+        args.app.room_standby,
+        args.app.push_secret.clone(),
+        push_key,
         p2p_conn.clone(),
         video_source.clone(),
         controller_manager,
@@ -437,18 +586,30 @@ async fn main() -> Result<(), Box<dyn Error>> {
     webrtcsink.set_property_from_str("stun-server", "stun://stun.l.google.com:19302");
     webrtcsink.set_property_from_str("congestion-control", "disabled");
     webrtcsink.set_property("do-retransmission", false);
+    // Audio payloaders stamp levels as ssrc-audio-level, so relays can tell viewers who is making noise
+    webrtcsink.connect("payloader-setup", false, |values| {
+        let stream_name = values[2].get::<String>().unwrap_or_default();
+        let payloader = values[3].get::<gstreamer::Element>().ok()?;
+        if stream_name.starts_with("audio") {
+            match gstreamer_rtp::RTPHeaderExtension::create_from_uri(AUDIO_LEVEL_EXTENSION_URI) {
+                Some(extension) => {
+                    extension.set_id(AUDIO_LEVEL_EXTENSION_ID);
+                    payloader.emit_by_name::<()>("add-extension", &[&extension]);
+                }
+                None => {
+                    tracing::warn!("Audio level header extension not available, levels won't be sent")
+                }
+            }
+        }
+        // Let webrtcsink configure the payloader as usual
+        Some(false.to_value())
+    });
 
     /* Queues */
-    // Sink queues
-    let video_sink_queue = gstreamer::ElementFactory::make("queue").build()?;
+    // Sink queue
     let audio_sink_queue = gstreamer::ElementFactory::make("queue").build()?;
 
-    // Source queues
-    let video_source_queue = gstreamer::ElementFactory::make("queue")
-        .property("max-size-buffers", 2u32)
-        .property("max-size-time", 0u64)
-        .property("max-size-bytes", 0u32)
-        .build()?;
+    // Source queue
     let audio_source_queue = gstreamer::ElementFactory::make("queue")
         .property("max-size-buffers", 2u32)
         .property("max-size-time", 0u64)
@@ -458,46 +619,27 @@ async fn main() -> Result<(), Box<dyn Error>> {
     // Add elements to the pipeline
     pipeline.add_many(&[
         webrtcsink.upcast_ref(),
-        &video_sink_queue,
         &audio_sink_queue,
-        &video_encoder,
-        &caps_filter,
-        &video_source_queue,
-        &video_source,
         &audio_encoder,
         &audio_capsfilter,
+        &audio_level,
         &audio_source_queue,
         &audio_rate,
         &audio_converter,
         &audio_source,
     ])?;
 
-    if let Some(video_converter) = &video_converter {
-        pipeline.add(video_converter)?;
-    }
-
     if let Some(parser) = &audio_parser {
         pipeline.add(parser)?;
     }
 
-    if let Some(parser) = &video_parser {
-        pipeline.add(parser)?;
-    }
-
-    // If zero-copy..
-    if args.app.zero_copy {
-        // VA-API / QSV pipeline
-        if let (Some(vapostproc), Some(va_caps_filter)) = (&vapostproc, &va_caps_filter) {
-            pipeline.add_many(&[vapostproc, va_caps_filter])?;
-        }
-    }
-
     // Link main audio branch
     gstreamer::Element::link_many(&[
         &audio_source,
         &audio_converter,
         &audio_rate,
         &audio_capsfilter,
+        &audio_level,
         &audio_source_queue,
         &audio_encoder,
     ])?;
@@ -518,49 +660,17 @@ async fn main() -> Result<(), Box<dyn Error>> {
         ])?;
     }
 
-    // With zero-copy..
-    if args.app.zero_copy {
-        // VA-API / QSV pipeline
-        if let (Some(vapostproc), Some(va_caps_filter)) = (&vapostproc, &va_caps_filter) {
-            gstreamer::Element::link_many(&[
-                &video_source,
-                &caps_filter,
-                &video_source_queue,
-                &vapostproc,
-                &va_caps_filter,
-                &video_encoder,
-            ])?;
-        } else if video_encoder_info.encoder_api == EncoderAPI::NVENC {
-            // NVENC pipeline
-            gstreamer::Element::link_many(&[&video_source, &caps_filter, &video_encoder])?;
-        }
-    } else {
-        gstreamer::Element::link_many(&[
-            &video_source,
-            &caps_filter,
-            &video_source_queue,
-            &video_converter.unwrap(),
-            &video_encoder,
-        ])?;
-    }
-
-    // Link video parser if present with webrtcsink, otherwise just link webrtc sink
-    if let Some(parser) = &video_parser {
-        gstreamer::Element::link_many(&[
-            &video_encoder,
-            parser,
-            &video_sink_queue,
-            webrtcsink.upcast_ref(),
-        ])?;
-    } else {
-        gstreamer::Element::link_many(&[
-            &video_encoder,
-            &video_sink_queue,
+    // Link video branch, audio-only pushes have none
+    if let (Some(video_source), Some(video_encoder_info)) = (&video_source, &video_encoder_info) {
+        link_video_branch(
+            &args,
+            &pipeline,
             webrtcsink.upcast_ref(),
-        ])?;
+            video_source,
+            video_encoder_info,
+        )?;
     }
 
-    video_source.set_property("do-timestamp", &false);
     audio_source.set_property("do-timestamp", &false);
 
     // Optimize latency of pipeline
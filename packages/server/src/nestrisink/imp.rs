@@ -3,8 +3,9 @@ use crate::p2p::p2p::NestriConnection;
 use crate::p2p::p2p_protocol_stream::NestriStreamProtocol;
 use crate::proto::proto::proto_message::Payload;
 use crate::proto::proto::{
-    ProtoControllerAttach, ProtoControllerRumble, ProtoIce, ProtoMessage, ProtoSdp,
-    ProtoServerPushStream, RtcIceCandidateInit, RtcSessionDescriptionInit,
+    ProtoControllerAttach, ProtoControllerRumble, ProtoIce, ProtoInputLatency, ProtoLatencyTracker,
+    ProtoMessage, ProtoPushChallengeResponse, ProtoRoomMetadata, ProtoRoomSettings, ProtoSdp,
+    ProtoServerPushStream, ProtoTimestampEntry, RtcIceCandidateInit, RtcSessionDescriptionInit,
 };
 use anyhow::Result;
 use glib::subclass::prelude::*;
@@ -12,13 +13,23 @@ use gstreamer::glib;
 use gstreamer::prelude::*;
 use gstreamer_webrtc::{WebRTCSDPType, WebRTCSessionDescription, gst_sdp};
 use gstrswebrtc::signaller::{Signallable, SignallableImpl};
+use hmac::{Hmac, Mac};
+use libp2p::identity::ed25519;
 use parking_lot::RwLock as PLRwLock;
 use prost::Message;
+use sha2::Sha256;
 use std::sync::{Arc, LazyLock};
 use tokio::sync::{Mutex, mpsc};
 
 pub struct Signaller {
     stream_room: PLRwLock<Option<String>>,
+    stream_metadata: PLRwLock<Option<ProtoRoomMetadata>>,
+    stream_settings: PLRwLock<Option<ProtoRoomSettings>>,
+    stream_variant: PLRwLock<Option<String>>,
+    stream_standby: PLRwLock<bool>,
+    push_secret: PLRwLock<Option<String>>,
+    push_key: PLRwLock<Option<ed25519::Keypair>>,
+    relay_peer_id: PLRwLock<Option<String>>,
     stream_protocol: PLRwLock<Option<Arc<NestriStreamProtocol>>>,
     wayland_src: PLRwLock<Option<Arc<gstreamer::Element>>>,
     data_channel: PLRwLock<Option<Arc<gstreamer_webrtc::WebRTCDataChannel>>>,
@@ -30,6 +41,13 @@ impl Default for Signaller {
     fn default() -> Self {
         Self {
             stream_room: PLRwLock::new(None),
+            stream_metadata: PLRwLock::new(None),
+            stream_settings: PLRwLock::new(None),
+            stream_variant: PLRwLock::new(None),
+            stream_standby: PLRwLock::new(false),
+            push_secret: PLRwLock::new(None),
+            push_key: PLRwLock::new(None),
+            relay_peer_id: PLRwLock::new(None),
             stream_protocol: PLRwLock::new(None),
             wayland_src: PLRwLock::new(None),
             data_channel: PLRwLock::new(None),
@@ -41,6 +59,7 @@ impl Default for Signaller {
 }
 impl Signaller {
     pub async fn set_nestri_connection(&self, nestri_conn: NestriConnection) -> Result<()> {
+        *self.relay_peer_id.write() = Some(nestri_conn.peer_id.to_string());
         let stream_protocol = NestriStreamProtocol::new(nestri_conn).await?;
         *self.stream_protocol.write() = Some(Arc::new(stream_protocol));
         Ok(())
@@ -50,6 +69,30 @@ impl Signaller {
         *self.stream_room.write() = Some(room);
     }
 
+    pub fn set_stream_metadata(&self, metadata: ProtoRoomMetadata) {
+        *self.stream_metadata.write() = Some(metadata);
+    }
+
+    pub fn set_stream_settings(&self, settings: ProtoRoomSettings) {
+        *self.stream_settings.write() = Some(settings);
+    }
+
+    pub fn set_stream_variant(&self, variant: String) {
+        *self.stream_variant.write() = Some(variant);
+    }
+
+    pub fn set_stream_standby(&self, standby: bool) {
+        *self.stream_standby.write() = standby;
+    }
+
+    pub fn set_push_secret(&self, secret: String) {
+        *self.push_secret.write() = Some(secret);
+    }
+
+    pub fn set_push_key(&self, key: ed25519::Keypair) {
+        *self.push_key.write() = Some(key);
+    }
+
     fn get_stream_protocol(&self) -> Option<Arc<NestriStreamProtocol>> {
         self.stream_protocol.read().clone()
     }
@@ -191,6 +234,62 @@ impl Signaller {
                 }
             });
         }
+        {
+            let self_obj = self.obj().clone();
+            stream_protocol.register_callback("push-challenge", move |msg| {
+                let Some(Payload::PushChallenge(challenge)) = msg.payload else {
+                    anyhow::bail!("Failed to decode push challenge");
+                };
+                let signaller = self_obj.imp();
+                let Some(key) = signaller.push_key.read().clone() else {
+                    anyhow::bail!("Relay requires a push key, set --push-key");
+                };
+                let Some(relay_id) = signaller.relay_peer_id.read().clone() else {
+                    anyhow::bail!("Relay peer ID unknown, can't answer push challenge");
+                };
+                // Bound to the relay we connected to, so the signature can't be passed on to another relay
+                let signature =
+                    sign_push_challenge(&key, &relay_id, &challenge.room_name, &challenge.nonce);
+                let res_msg = crate::proto::create_message(
+                    Payload::PushChallengeResponse(ProtoPushChallengeResponse {
+                        room_name: challenge.room_name,
+                        public_key: hex::encode(key.public().to_bytes()),
+                        signature,
+                    }),
+                    "push-challenge-response",
+                    None,
+                );
+                let Some(stream_protocol) = signaller.get_stream_protocol() else {
+                    anyhow::bail!("Stream protocol not set");
+                };
+                stream_protocol.send_message(&res_msg)
+            });
+        }
+        {
+            stream_protocol.register_callback("throttled", move |msg| {
+                if let Some(Payload::Throttled(throttled)) = msg.payload {
+                    tracing::warn!(
+                        "Relay is rate limiting our signaling ({}), retry after {}ms",
+                        throttled.scope,
+                        throttled.retry_after_ms
+                    );
+                }
+                Ok(())
+            });
+        }
+        {
+            stream_protocol.register_callback("invalid-message", move |msg| {
+                if let Some(Payload::InvalidMessage(invalid)) = msg.payload {
+                    tracing::error!(
+                        "Relay rejected our '{}' message: {} {}",
+                        invalid.payload_type,
+                        invalid.field,
+                        invalid.reason
+                    );
+                }
+                Ok(())
+            });
+        }
         {
             let self_obj = self.obj().clone();
             // After creating webrtcsink
@@ -219,34 +318,27 @@ impl Signaller {
                         );
                         if let Some(data_channel) = data_channel {
                             gstreamer::info!(gstreamer::CAT_DEFAULT, "Data channel created");
-                            if let Some(wayland_src) = signaller.imp().get_wayland_src() {
-                                signaller.imp().set_data_channel(data_channel.clone());
-
-                                let signaller = signaller.clone();
-                                let data_channel = Arc::new(data_channel);
-                                let wayland_src = wayland_src.clone();
-
-                                // Spawn async task to take the receiver and set up
-                                tokio::spawn(async move {
-                                    let rumble_rx = signaller.imp().take_rumble_rx().await;
-                                    let attach_rx = signaller.imp().take_attach_rx().await;
-                                    let controller_manager =
-                                        signaller.imp().get_controller_manager();
-
-                                    setup_data_channel(
-                                        controller_manager,
-                                        rumble_rx,
-                                        attach_rx,
-                                        data_channel,
-                                        &wayland_src,
-                                    );
-                                });
-                            } else {
-                                gstreamer::error!(
-                                    gstreamer::CAT_DEFAULT,
-                                    "Wayland display source not set"
+                            signaller.imp().set_data_channel(data_channel.clone());
+
+                            let signaller = signaller.clone();
+                            let data_channel = Arc::new(data_channel);
+                            // Audio-only pushes have no display source, input of viewers is dropped
+                            let wayland_src = signaller.imp().get_wayland_src();
+
+                            // Spawn async task to take the receiver and set up
+                            tokio::spawn(async move {
+                                let rumble_rx = signaller.imp().take_rumble_rx().await;
+                                let attach_rx = signaller.imp().take_attach_rx().await;
+                                let controller_manager = signaller.imp().get_controller_manager();
+
+                                setup_data_channel(
+                                    controller_manager,
+                                    rumble_rx,
+                                    attach_rx,
+                                    data_channel,
+                                    wayland_src,
                                 );
-                            }
+                            });
                         } else {
                             gstreamer::error!(
                                 gstreamer::CAT_DEFAULT,
@@ -259,6 +351,28 @@ impl Signaller {
         }
     }
 }
+/// Hex HMAC-SHA256 of "<room_name>\n<timestamp>", as relays verify pushes with
+fn sign_push(secret: &str, room_name: &str, timestamp: i64) -> String {
+    let mut mac =
+        Hmac::<Sha256>::new_from_slice(secret.as_bytes()).expect("HMAC accepts keys of any length");
+    mac.update(format!("{}\n{}", room_name, timestamp).as_bytes());
+    hex::encode(mac.finalize().into_bytes())
+}
+
+/// Signs a push challenge of the relay with our push key, the relay checks the key is trusted
+fn sign_push_challenge(
+    key: &ed25519::Keypair,
+    relay_id: &str,
+    room_name: &str,
+    nonce: &str,
+) -> String {
+    let message = format!(
+        "nestri-push-challenge\n{}\n{}\n{}",
+        relay_id, room_name, nonce
+    );
+    hex::encode(key.sign(message.as_bytes()))
+}
+
 impl SignallableImpl for Signaller {
     fn start(&self) {
         gstreamer::info!(gstreamer::CAT_DEFAULT, "Signaller started");
@@ -279,9 +393,24 @@ impl SignallableImpl for Signaller {
             return;
         };
 
+        // Relays with a push secret only accept pushes signed within a few minutes
+        let (timestamp, signature) = match self.push_secret.read().as_deref() {
+            Some(secret) => {
+                let timestamp = chrono::Utc::now().timestamp();
+                (timestamp, sign_push(secret, &stream_room, timestamp))
+            }
+            None => (0, String::new()),
+        };
+
         let push_msg = crate::proto::create_message(
             Payload::ServerPushStream(ProtoServerPushStream {
                 room_name: stream_room,
+                settings: self.stream_settings.read().clone(),
+                timestamp,
+                signature,
+                metadata: self.stream_metadata.read().clone(),
+                variant: self.stream_variant.read().clone().unwrap_or_default(),
+                standby: *self.stream_standby.read(),
             }),
             "push-stream-room",
             None,
@@ -382,33 +511,62 @@ impl ObjectImpl for Signaller {
 
 fn setup_data_channel(
     controller_manager: Option<Arc<ControllerManager>>,
-    rumble_rx: Option<mpsc::Receiver<(u32, u16, u16, u16, String)>>, // (slot, strong, weak, duration_ms, session_id)
+    rumble_rx: Option<mpsc::Receiver<(u32, u16, u16, u16, String)>>, // (session_slot, strong, weak, duration_ms, session_id)
     attach_rx: Option<mpsc::Receiver<ProtoControllerAttach>>,
     data_channel: Arc<gstreamer_webrtc::WebRTCDataChannel>,
-    wayland_src: &gstreamer::Element,
+    wayland_src: Option<Arc<gstreamer::Element>>,
 ) {
-    let wayland_src = wayland_src.clone();
     let (tx, mut rx) = mpsc::unbounded_channel::<Vec<u8>>();
 
     // Spawn async processor
+    let echo_channel = data_channel.clone();
     tokio::spawn(async move {
         while let Some(data) = rx.recv().await {
             match ProtoMessage::decode(data.as_slice()) {
                 Ok(msg_wrapper) => {
                     if let Some(message_base) = msg_wrapper.message_base {
                         if message_base.payload_type == "input" {
-                            if let Some(input_data) = msg_wrapper.payload {
+                            if let (Some(input_data), Some(wayland_src)) =
+                                (msg_wrapper.payload, &wayland_src)
+                            {
                                 if let Some(event) = handle_input_message(input_data) {
                                     // Send the event to wayland source, result bool is ignored
                                     let _ = wayland_src.send_event(event);
                                 }
                             }
+                            echo_input_latency(&echo_channel, message_base.latency);
                         } else if message_base.payload_type == "controllerInput" {
                             if let Some(controller_manager) = &controller_manager {
                                 if let Some(input_data) = msg_wrapper.payload {
                                     let _ = controller_manager.send_command(input_data).await;
                                 }
                             }
+                            echo_input_latency(&echo_channel, message_base.latency);
+                        } else if message_base.payload_type == "viewer-count" {
+                            if let Some(Payload::ViewerCount(count)) = msg_wrapper.payload {
+                                tracing::debug!(
+                                    "Room '{}' has {} viewers",
+                                    count.room_name,
+                                    count.viewers
+                                );
+                            }
+                        } else if message_base.payload_type == "relay-overload" {
+                            if let Some(Payload::RelayOverload(advisory)) = msg_wrapper.payload {
+                                if advisory.overloaded {
+                                    tracing::warn!(
+                                        "Relay '{}' is overloaded ({}, cpu {}%, {} drops/s), consider lowering bitrate",
+                                        advisory.relay_id,
+                                        advisory.reason,
+                                        advisory.cpu_percent,
+                                        advisory.drop_rate
+                                    );
+                                } else {
+                                    tracing::info!(
+                                        "Relay '{}' recovered from overload",
+                                        advisory.relay_id
+                                    );
+                                }
+                            }
                         }
                     }
                 }
@@ -474,6 +632,37 @@ fn setup_data_channel(
     });
 }
 
+// Sends latency tracker of a handled input message back, stamped once handled, so the relay
+// which stamped it can measure input round trip of the viewer
+fn echo_input_latency(
+    data_channel: &gstreamer_webrtc::WebRTCDataChannel,
+    latency: Option<ProtoLatencyTracker>,
+) {
+    let Some(mut latency) = latency else {
+        return;
+    };
+    if latency.sequence_id.is_empty() {
+        return;
+    }
+    latency.timestamps.push(ProtoTimestampEntry {
+        stage: "server-handled".to_string(),
+        time: Some(prost_types::Timestamp::from(std::time::SystemTime::now())),
+    });
+
+    let echo_msg = crate::proto::create_message(
+        Payload::InputLatency(ProtoInputLatency::default()),
+        "input-latency",
+        Some(crate::proto::CreateMessageOptions {
+            sequence_id: None,
+            latency: Some(latency),
+        }),
+    );
+    let bytes = glib::Bytes::from_owned(echo_msg.encode_to_vec());
+    if let Err(e) = data_channel.send_data_full(Some(&bytes)) {
+        tracing::debug!("Failed to send input latency echo: {}", e);
+    }
+}
+
 fn handle_input_message(payload: Payload) -> Option<gstreamer::Event> {
     match payload {
         Payload::MouseMove(data) => {
@@ -1,8 +1,10 @@
 use crate::input::controller::ControllerManager;
 use crate::p2p::p2p::NestriConnection;
+use crate::proto::proto::{ProtoRoomMetadata, ProtoRoomSettings};
 use gstreamer::glib;
 use gstreamer::subclass::prelude::*;
 use gstrswebrtc::signaller::Signallable;
+use libp2p::identity::ed25519;
 use std::sync::Arc;
 use tokio::sync::mpsc;
 
@@ -15,16 +17,38 @@ glib::wrapper! {
 impl NestriSignaller {
     pub async fn new(
         room: String,
+        metadata: ProtoRoomMetadata,
+        settings: Option<ProtoRoomSettings>,
+        variant: Option<String>,
+        standby: bool,
+        push_secret: Option<String>,
+        push_key: Option<ed25519::Keypair>,
         nestri_conn: NestriConnection,
-        wayland_src: Arc<gstreamer::Element>,
+        wayland_src: Option<Arc<gstreamer::Element>>,
         controller_manager: Option<Arc<ControllerManager>>,
         rumble_rx: Option<mpsc::Receiver<(u32, u16, u16, u16, String)>>,
         attach_rx: Option<mpsc::Receiver<crate::proto::proto::ProtoControllerAttach>>,
     ) -> Result<Self, Box<dyn std::error::Error>> {
         let obj: Self = glib::Object::new();
         obj.imp().set_stream_room(room);
+        obj.imp().set_stream_metadata(metadata);
+        if let Some(settings) = settings {
+            obj.imp().set_stream_settings(settings);
+        }
+        if let Some(variant) = variant {
+            obj.imp().set_stream_variant(variant);
+        }
+        obj.imp().set_stream_standby(standby);
+        if let Some(push_secret) = push_secret {
+            obj.imp().set_push_secret(push_secret);
+        }
+        if let Some(push_key) = push_key {
+            obj.imp().set_push_key(push_key);
+        }
         obj.imp().set_nestri_connection(nestri_conn).await?;
-        obj.imp().set_wayland_src(wayland_src);
+        if let Some(wayland_src) = wayland_src {
+            obj.imp().set_wayland_src(wayland_src);
+        }
         if let Some(controller_manager) = controller_manager {
             obj.imp().set_controller_manager(controller_manager);
         }
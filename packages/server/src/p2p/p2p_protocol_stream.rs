@@ -5,6 +5,7 @@ use dashmap::DashMap;
 use libp2p::StreamProtocol;
 use prost::Message;
 use std::sync::Arc;
+use std::sync::atomic::{AtomicU64, Ordering};
 use tokio::sync::mpsc;
 
 // Cloneable callback type
@@ -33,9 +34,59 @@ impl From<Box<CallbackInner>> for Callback {
     }
 }
 
+/// How many sequence numbers behind the highest one seen are still accepted out of order
+const REPLAY_WINDOW_SIZE: u64 = 64;
+
+/// ReplayWindow tracks sequence numbers received on a stream, detecting replayed and
+/// duplicated messages. Messages without a sequence number come from senders not sequencing
+/// their messages and are always accepted.
+#[derive(Default)]
+struct ReplayWindow {
+    nonce: Option<u64>,
+    highest: u64,
+    seen: u64, // Bit n set if highest-n was received
+}
+impl ReplayWindow {
+    /// Records a received sequence number, returning the reason for dropping it if seen before
+    fn check(&mut self, nonce: u64, sequence: u64) -> Option<&'static str> {
+        if sequence == 0 {
+            return None;
+        }
+        match self.nonce {
+            None => self.nonce = Some(nonce),
+            Some(pinned) if pinned != nonce => return Some("nonce"),
+            _ => {}
+        }
+
+        if sequence > self.highest {
+            let shift = sequence - self.highest;
+            self.seen = if shift >= REPLAY_WINDOW_SIZE {
+                0
+            } else {
+                self.seen << shift
+            };
+            self.seen |= 1;
+            self.highest = sequence;
+            return None;
+        }
+        if self.highest - sequence >= REPLAY_WINDOW_SIZE {
+            return Some("too-old");
+        }
+        let bit = 1u64 << (self.highest - sequence);
+        if self.seen & bit != 0 {
+            return Some("duplicate");
+        }
+        self.seen |= bit;
+        None
+    }
+}
+
 /// NestriStreamProtocol manages the stream protocol for Nestri connections.
 pub struct NestriStreamProtocol {
     tx: Option<mpsc::Sender<Vec<u8>>>,
+    // Messages we send are sequenced under our nonce, so the relay can drop replays
+    nonce: u64,
+    sequence: AtomicU64,
     safe_stream: Arc<SafeStream>,
     callbacks: Arc<DashMap<String, Callback>>,
     read_handle: Option<tokio::task::JoinHandle<()>>,
@@ -63,6 +114,8 @@ impl NestriStreamProtocol {
 
         let mut sp = NestriStreamProtocol {
             tx: None,
+            nonce: rand::random(),
+            sequence: AtomicU64::new(0),
             safe_stream: Arc::new(SafeStream::new(push_stream)),
             callbacks: Arc::new(DashMap::new()),
             read_handle: None,
@@ -94,6 +147,7 @@ impl NestriStreamProtocol {
         let safe_stream = self.safe_stream.clone();
         let callbacks = self.callbacks.clone();
         tokio::spawn(async move {
+            let mut replay = ReplayWindow::default();
             loop {
                 let data = {
                     match safe_stream.receive_raw().await {
@@ -111,6 +165,17 @@ impl NestriStreamProtocol {
                             let response_type = &base_message.payload_type;
                             let response_type = response_type.clone();
 
+                            if let Some(reason) =
+                                replay.check(base_message.stream_nonce, base_message.sequence)
+                            {
+                                tracing::warn!(
+                                    "Dropping replayed '{}' message ({})",
+                                    response_type,
+                                    reason
+                                );
+                                continue;
+                            }
+
                             // With DashMap, we don't need explicit locking
                             // we just get the callback directly if it exists
                             if let Some(callback) = callbacks.get(&response_type) {
@@ -161,6 +226,11 @@ impl NestriStreamProtocol {
     }
 
     pub fn send_message(&self, message: &crate::proto::proto::ProtoMessage) -> Result<()> {
+        let mut message = message.clone();
+        if let Some(base) = message.message_base.as_mut() {
+            base.sequence = self.sequence.fetch_add(1, Ordering::Relaxed) + 1;
+            base.stream_nonce = self.nonce;
+        }
         let mut buf = Vec::new();
         message.encode(&mut buf)?;
         let Some(tx) = &self.tx else {
@@ -1,5 +1,8 @@
 pub mod proto;
 
+/// Message protocol version announced in every message base
+pub const PROTOCOL_VERSION: u32 = 1;
+
 pub struct CreateMessageOptions {
     pub sequence_id: Option<String>,
     pub latency: Option<proto::ProtoLatencyTracker>,
@@ -29,6 +32,10 @@ pub fn create_message(
         message_base: Some(proto::ProtoMessageBase {
             payload_type: payload_type.into(),
             latency,
+            protocol_version: PROTOCOL_VERSION,
+            sequence: 0,
+            stream_nonce: 0,
+            capabilities: vec![],
         }),
         payload: Some(payload),
     }
@@ -1,19 +1,5 @@
 // @generated
 // This file is @generated by prost-build.
-#[derive(Clone, PartialEq, Eq, Hash, ::prost::Message)]
-pub struct ProtoTimestampEntry {
-    #[prost(string, tag="1")]
-    pub stage: ::prost::alloc::string::String,
-    #[prost(message, optional, tag="2")]
-    pub time: ::core::option::Option<::prost_types::Timestamp>,
-}
-#[derive(Clone, PartialEq, ::prost::Message)]
-pub struct ProtoLatencyTracker {
-    #[prost(string, tag="1")]
-    pub sequence_id: ::prost::alloc::string::String,
-    #[prost(message, repeated, tag="2")]
-    pub timestamps: ::prost::alloc::vec::Vec<ProtoTimestampEntry>,
-}
 // Mouse messages 
 
 /// MouseMove message
@@ -239,6 +225,15 @@ pub struct ProtoClientRequestRoomStream {
     pub room_name: ::prost::alloc::string::String,
     #[prost(string, tag="2")]
     pub session_id: ::prost::alloc::string::String,
+    /// Viewer consents to being moved into encoder experiments
+    #[prost(bool, tag="3")]
+    pub experiment_opt_in: bool,
+    /// Signed viewer token issued by the platform, required when relay enforces viewer authorization
+    #[prost(string, tag="4")]
+    pub token: ::prost::alloc::string::String,
+    /// Room password or invite token, required for rooms pushed with an access secret
+    #[prost(string, tag="5")]
+    pub access_secret: ::prost::alloc::string::String,
 }
 /// ProtoClientDisconnected message
 #[derive(Clone, PartialEq, Eq, Hash, ::prost::Message)]
@@ -253,6 +248,574 @@ pub struct ProtoClientDisconnected {
 pub struct ProtoServerPushStream {
     #[prost(string, tag="1")]
     pub room_name: ::prost::alloc::string::String,
+    /// Optional room settings, applied when room is created
+    #[prost(message, optional, tag="2")]
+    pub settings: ::core::option::Option<ProtoRoomSettings>,
+    /// Unix seconds the push was signed at, required when relay enforces push authentication
+    #[prost(int64, tag="3")]
+    pub timestamp: i64,
+    /// Hex HMAC-SHA256 of "<room_name>\n<timestamp>" with the push secret
+    #[prost(string, tag="4")]
+    pub signature: ::prost::alloc::string::String,
+    /// Optional room metadata for room lists, can be updated later with "room-metadata"
+    #[prost(message, optional, tag="5")]
+    pub metadata: ::core::option::Option<ProtoRoomMetadata>,
+    /// Quality variant like "720p30" pushed alongside the main stream of the room, empty for the main stream
+    #[prost(string, tag="6")]
+    pub variant: ::prost::alloc::string::String,
+    /// Push as standby of the online room, forwarded from only once the pushed stream fails
+    #[prost(bool, tag="7")]
+    pub standby: bool,
+}
+/// ProtoRoomSettings message
+#[derive(Clone, PartialEq, Eq, Hash, ::prost::Message)]
+pub struct ProtoRoomSettings {
+    /// Room carries only audio (voice rooms), no video tracks are allocated
+    #[prost(bool, tag="1")]
+    pub audio_only: bool,
+    /// End-to-end latency budget for mesh forwarding paths, 0 uses relay default
+    #[prost(uint32, tag="2")]
+    pub latency_budget_ms: u32,
+    /// Drop whole video frames which are late for a viewer instead of delivering them
+    #[prost(bool, tag="3")]
+    pub strict_latency: bool,
+    /// Max age of video frame since ingest in strict latency mode, 0 uses relay default
+    #[prost(uint32, tag="4")]
+    pub max_frame_age_ms: u32,
+    /// Password or invite token viewers must present, empty for public rooms. Only sent with pushes, never echoed back
+    #[prost(string, tag="5")]
+    pub access_secret: ::prost::alloc::string::String,
+    /// Max participants the room admits, 0 uses relay config or no limit
+    #[prost(uint32, tag="6")]
+    pub max_viewers: u32,
+    /// Packets queued per viewer, 0 uses relay default
+    #[prost(uint32, tag="7")]
+    pub queue_size: u32,
+    /// Queue depth at which "video" and "keyframe" drop policies start dropping video, 0 uses 80% of queue size
+    #[prost(uint32, tag="8")]
+    pub queue_high_watermark: u32,
+    /// Queue depth at which dropped video resumes, 0 uses half of queue size
+    #[prost(uint32, tag="9")]
+    pub queue_low_watermark: u32,
+    /// "newest", "oldest", "video" or "keyframe", empty uses relay config or "newest"
+    #[prost(string, tag="10")]
+    pub drop_policy: ::prost::alloc::string::String,
+}
+/// ProtoRoomMetadata message
+#[derive(Clone, PartialEq, Eq, Hash, ::prost::Message)]
+pub struct ProtoRoomMetadata {
+    /// Room title shown in room lists
+    #[prost(string, tag="1")]
+    pub title: ::prost::alloc::string::String,
+    /// Name of the game being streamed
+    #[prost(string, tag="2")]
+    pub game: ::prost::alloc::string::String,
+    /// Stream resolution width in pixels
+    #[prost(uint32, tag="3")]
+    pub width: u32,
+    /// Stream resolution height in pixels
+    #[prost(uint32, tag="4")]
+    pub height: u32,
+    /// Stream frames per second
+    #[prost(uint32, tag="5")]
+    pub frame_rate: u32,
+    /// Room is left out of room directories, still joinable by name
+    #[prost(bool, tag="6")]
+    pub private: bool,
+    /// URL of latest keyframe thumbnail of the room, empty if none
+    #[prost(string, tag="7")]
+    pub thumbnail_url: ::prost::alloc::string::String,
+}
+/// ProtoDirectoryQuery message
+#[derive(Clone, PartialEq, Eq, Hash, ::prost::Message)]
+pub struct ProtoDirectoryQuery {
+    /// Room name prefix to match, empty matches all rooms
+    #[prost(string, tag="1")]
+    pub prefix: ::prost::alloc::string::String,
+    /// Cursor from previous result page, empty for first page
+    #[prost(string, tag="2")]
+    pub cursor: ::prost::alloc::string::String,
+    /// Max rooms per page, relay applies default and cap
+    #[prost(uint32, tag="3")]
+    pub limit: u32,
+}
+/// ProtoDirectoryRoom message
+#[derive(Clone, PartialEq, Eq, Hash, ::prost::Message)]
+pub struct ProtoDirectoryRoom {
+    #[prost(string, tag="1")]
+    pub id: ::prost::alloc::string::String,
+    #[prost(string, tag="2")]
+    pub name: ::prost::alloc::string::String,
+    #[prost(string, tag="3")]
+    pub owner_id: ::prost::alloc::string::String,
+    /// Viewer count as known by the answering relay
+    #[prost(uint32, tag="4")]
+    pub viewers: u32,
+    #[prost(bool, tag="5")]
+    pub online: bool,
+    #[prost(message, optional, tag="6")]
+    pub metadata: ::core::option::Option<ProtoRoomMetadata>,
+}
+/// ProtoDirectoryResult message
+#[derive(Clone, PartialEq, ::prost::Message)]
+pub struct ProtoDirectoryResult {
+    #[prost(message, repeated, tag="1")]
+    pub rooms: ::prost::alloc::vec::Vec<ProtoDirectoryRoom>,
+    /// Empty when there are no more pages
+    #[prost(string, tag="2")]
+    pub next_cursor: ::prost::alloc::string::String,
+}
+/// ProtoStreamPathInfo message
+#[derive(Clone, PartialEq, Eq, Hash, ::prost::Message)]
+pub struct ProtoStreamPathInfo {
+    #[prost(string, tag="1")]
+    pub room_name: ::prost::alloc::string::String,
+    /// Relay hops between room owner and the serving relay
+    #[prost(uint32, tag="2")]
+    pub hops: u32,
+    /// Cumulative measured latency up to and including the serving relay
+    #[prost(uint64, tag="3")]
+    pub path_latency_us: u64,
+}
+/// ProtoTrackStats message
+#[derive(Clone, PartialEq, ::prost::Message)]
+pub struct ProtoTrackStats {
+    /// "audio" or "video"
+    #[prost(string, tag="1")]
+    pub kind: ::prost::alloc::string::String,
+    /// Forwarded bitrate
+    #[prost(uint64, tag="2")]
+    pub bitrate_bps: u64,
+    /// Frames per second, counted from RTP marker bits (video only)
+    #[prost(double, tag="3")]
+    pub frame_rate: f64,
+    /// Time between last two keyframes (video only), 0 if unknown
+    #[prost(uint32, tag="4")]
+    pub keyframe_interval_ms: u32,
+    /// Total forwarded packets
+    #[prost(uint64, tag="5")]
+    pub packets: u64,
+    /// Total forwarded payload bytes
+    #[prost(uint64, tag="6")]
+    pub bytes: u64,
+}
+/// ProtoStreamStats message
+#[derive(Clone, PartialEq, ::prost::Message)]
+pub struct ProtoStreamStats {
+    #[prost(string, tag="1")]
+    pub room_name: ::prost::alloc::string::String,
+    #[prost(message, repeated, tag="2")]
+    pub tracks: ::prost::alloc::vec::Vec<ProtoTrackStats>,
+    /// Input round trip of the viewer, unset before any was measured
+    #[prost(message, optional, tag="3")]
+    pub input_latency: ::core::option::Option<ProtoInputLatency>,
+}
+/// ProtoRelayNotice message
+#[derive(Clone, PartialEq, Eq, Hash, ::prost::Message)]
+pub struct ProtoRelayNotice {
+    #[prost(string, tag="1")]
+    pub text: ::prost::alloc::string::String,
+    /// "info", "warning" or "critical"
+    #[prost(string, tag="2")]
+    pub level: ::prost::alloc::string::String,
+}
+/// ProtoSignalingProgress message
+#[derive(Clone, PartialEq, Eq, Hash, ::prost::Message)]
+pub struct ProtoSignalingProgress {
+    #[prost(string, tag="1")]
+    pub room_name: ::prost::alloc::string::String,
+    /// "session-assigned", "offer-sent", "ice-connected", "dtls-connected", "first-frame-forwarded" or a failure stage
+    #[prost(string, tag="2")]
+    pub stage: ::prost::alloc::string::String,
+    /// Reason of a failure stage, empty otherwise
+    #[prost(string, tag="3")]
+    pub detail: ::prost::alloc::string::String,
+    /// Time since the stream request was received
+    #[prost(uint32, tag="4")]
+    pub elapsed_ms: u32,
+}
+/// ProtoMeshRoomTracks message
+#[derive(Clone, PartialEq, Eq, Hash, ::prost::Message)]
+pub struct ProtoMeshRoomTracks {
+    #[prost(string, tag="1")]
+    pub room_name: ::prost::alloc::string::String,
+    /// MID of room audio on the shared mesh PeerConnection
+    #[prost(string, tag="2")]
+    pub audio_mid: ::prost::alloc::string::String,
+    /// MID of room video, empty for audio-only rooms
+    #[prost(string, tag="3")]
+    pub video_mid: ::prost::alloc::string::String,
+}
+/// ProtoRoomFull message
+#[derive(Clone, PartialEq, Eq, Hash, ::prost::Message)]
+pub struct ProtoRoomFull {
+    #[prost(string, tag="1")]
+    pub room_name: ::prost::alloc::string::String,
+    /// Participants currently in the room
+    #[prost(uint32, tag="2")]
+    pub viewer_count: u32,
+    /// Participants the room admits
+    #[prost(uint32, tag="3")]
+    pub max_viewers: u32,
+    /// Position in the room waiting list, 0 if the requester isn't queued
+    #[prost(uint32, tag="4")]
+    pub queue_position: u32,
+}
+/// ProtoModeration message
+#[derive(Clone, PartialEq, Eq, Hash, ::prost::Message)]
+pub struct ProtoModeration {
+    #[prost(string, tag="1")]
+    pub room_name: ::prost::alloc::string::String,
+    /// "kicked" or "banned"
+    #[prost(string, tag="2")]
+    pub action: ::prost::alloc::string::String,
+    /// Moderator given reason, may be empty
+    #[prost(string, tag="3")]
+    pub reason: ::prost::alloc::string::String,
+    /// When the ban ends, 0 for kicks
+    #[prost(int64, tag="4")]
+    pub ban_expires_unix: i64,
+}
+/// ProtoChatMessage message
+#[derive(Clone, PartialEq, Eq, Hash, ::prost::Message)]
+pub struct ProtoChatMessage {
+    /// Set by relay
+    #[prost(string, tag="1")]
+    pub room_name: ::prost::alloc::string::String,
+    #[prost(string, tag="2")]
+    pub text: ::prost::alloc::string::String,
+    /// Participant ID of sender, set by relay
+    #[prost(string, tag="3")]
+    pub sender_id: ::prost::alloc::string::String,
+    /// Display name chosen by sender, unverified
+    #[prost(string, tag="4")]
+    pub sender_name: ::prost::alloc::string::String,
+    /// Viewer token subject of sender, set by relay, empty without viewer authorization
+    #[prost(string, tag="5")]
+    pub sender_identity: ::prost::alloc::string::String,
+    /// When the relay accepted the message
+    #[prost(int64, tag="6")]
+    pub sent_unix_ms: i64,
+}
+/// ProtoRoomVariant message
+#[derive(Clone, PartialEq, Eq, Hash, ::prost::Message)]
+pub struct ProtoRoomVariant {
+    /// Variant name like "720p30", empty for the main stream
+    #[prost(string, tag="1")]
+    pub name: ::prost::alloc::string::String,
+    /// Sibling room carrying the variant
+    #[prost(string, tag="2")]
+    pub room_name: ::prost::alloc::string::String,
+    /// From room metadata of the variant push, 0 if unknown
+    #[prost(uint32, tag="3")]
+    pub width: u32,
+    #[prost(uint32, tag="4")]
+    pub height: u32,
+    #[prost(uint32, tag="5")]
+    pub frame_rate: u32,
+    #[prost(bool, tag="6")]
+    pub online: bool,
+}
+/// ProtoRoomVariants message
+#[derive(Clone, PartialEq, ::prost::Message)]
+pub struct ProtoRoomVariants {
+    /// Main room the variants belong to
+    #[prost(string, tag="1")]
+    pub room_name: ::prost::alloc::string::String,
+    /// Variant the viewer currently receives, empty for the main stream
+    #[prost(string, tag="2")]
+    pub current: ::prost::alloc::string::String,
+    #[prost(message, repeated, tag="3")]
+    pub variants: ::prost::alloc::vec::Vec<ProtoRoomVariant>,
+}
+/// ProtoVariantSwitch message
+#[derive(Clone, PartialEq, Eq, Hash, ::prost::Message)]
+pub struct ProtoVariantSwitch {
+    /// Variant to receive, empty for the main stream
+    #[prost(string, tag="1")]
+    pub variant: ::prost::alloc::string::String,
+    /// Why the switch failed, set by relay in "variant-switch-failed"
+    #[prost(string, tag="2")]
+    pub error: ::prost::alloc::string::String,
+}
+/// ProtoViewerCount message
+#[derive(Clone, PartialEq, Eq, Hash, ::prost::Message)]
+pub struct ProtoViewerCount {
+    /// Main room, viewers of its quality variants are included
+    #[prost(string, tag="1")]
+    pub room_name: ::prost::alloc::string::String,
+    /// Viewers across the mesh, relays pulling the room aren't counted
+    #[prost(uint32, tag="2")]
+    pub viewers: u32,
+}
+/// ProtoThrottled message
+#[derive(Clone, PartialEq, Eq, Hash, ::prost::Message)]
+pub struct ProtoThrottled {
+    /// "stream" when opening a signaling stream was refused, "message" when messages are dropped
+    #[prost(string, tag="1")]
+    pub scope: ::prost::alloc::string::String,
+    /// When the next stream or message would be accepted
+    #[prost(uint32, tag="2")]
+    pub retry_after_ms: u32,
+}
+/// ProtoQuotaExceeded message
+#[derive(Clone, PartialEq, Eq, Hash, ::prost::Message)]
+pub struct ProtoQuotaExceeded {
+    /// Requested or cut room
+    #[prost(string, tag="1")]
+    pub room_name: ::prost::alloc::string::String,
+    /// "daily" or "monthly"
+    #[prost(string, tag="2")]
+    pub scope: ::prost::alloc::string::String,
+    /// Bytes sent to the peer within the quota period
+    #[prost(uint64, tag="3")]
+    pub used_bytes: u64,
+    /// Bytes the peer may receive within the quota period
+    #[prost(uint64, tag="4")]
+    pub quota_bytes: u64,
+    /// When the quota period ends and streams are served again
+    #[prost(int64, tag="5")]
+    pub reset_unix: i64,
+}
+/// ProtoRelayOverload message
+#[derive(Clone, PartialEq, Eq, Hash, ::prost::Message)]
+pub struct ProtoRelayOverload {
+    /// Peer ID of the advising relay
+    #[prost(string, tag="1")]
+    pub relay_id: ::prost::alloc::string::String,
+    /// True while the relay is overloaded, false once it recovered
+    #[prost(bool, tag="2")]
+    pub overloaded: bool,
+    /// "cpu" or "drops" when overloaded
+    #[prost(string, tag="3")]
+    pub reason: ::prost::alloc::string::String,
+    /// Busy share of relay CPU over the last check
+    #[prost(uint32, tag="4")]
+    pub cpu_percent: u32,
+    /// Packets per second dropped from viewer queues over the last check
+    #[prost(uint32, tag="5")]
+    pub drop_rate: u32,
+}
+/// ProtoInvalidMessage message
+#[derive(Clone, PartialEq, Eq, Hash, ::prost::Message)]
+pub struct ProtoInvalidMessage {
+    /// Payload type of the rejected message
+    #[prost(string, tag="1")]
+    pub payload_type: ::prost::alloc::string::String,
+    /// Offending field, empty if the payload was missing
+    #[prost(string, tag="2")]
+    pub field: ::prost::alloc::string::String,
+    /// "missing", "too-long", "invalid-utf8" or "invalid-value"
+    #[prost(string, tag="3")]
+    pub reason: ::prost::alloc::string::String,
+}
+/// ProtoPushChallenge message
+#[derive(Clone, PartialEq, Eq, Hash, ::prost::Message)]
+pub struct ProtoPushChallenge {
+    /// Room the push was requested for
+    #[prost(string, tag="1")]
+    pub room_name: ::prost::alloc::string::String,
+    /// Hex random nonce the pushing node signs with its push key
+    #[prost(string, tag="2")]
+    pub nonce: ::prost::alloc::string::String,
+}
+/// ProtoPushChallengeResponse message
+#[derive(Clone, PartialEq, Eq, Hash, ::prost::Message)]
+pub struct ProtoPushChallengeResponse {
+    /// Room of the answered challenge
+    #[prost(string, tag="1")]
+    pub room_name: ::prost::alloc::string::String,
+    /// Hex Ed25519 public key of the pushing node
+    #[prost(string, tag="2")]
+    pub public_key: ::prost::alloc::string::String,
+    /// Hex Ed25519 signature of the challenge, bound to relay peer ID
+    #[prost(string, tag="3")]
+    pub signature: ::prost::alloc::string::String,
+}
+/// ProtoAudioLevel message
+#[derive(Clone, PartialEq, Eq, Hash, ::prost::Message)]
+pub struct ProtoAudioLevel {
+    /// Room whose audio the level was read from
+    #[prost(string, tag="1")]
+    pub room_name: ::prost::alloc::string::String,
+    /// Loudest level since the previous event as -dBov, 0 is loudest and 127 silence (RFC 6464)
+    #[prost(uint32, tag="2")]
+    pub level: u32,
+    /// Sender flagged voice activity since the previous event
+    #[prost(bool, tag="3")]
+    pub voice: bool,
+}
+/// ProtoBetterRelay message
+#[derive(Clone, PartialEq, Eq, Hash, ::prost::Message)]
+pub struct ProtoBetterRelay {
+    /// Room the viewer requested
+    #[prost(string, tag="1")]
+    pub room_name: ::prost::alloc::string::String,
+    /// Relay serving the room closer to the viewer
+    #[prost(string, tag="2")]
+    pub peer_id: ::prost::alloc::string::String,
+    /// Multiaddrs the viewer can dial the relay at, with its peer ID
+    #[prost(string, repeated, tag="3")]
+    pub addrs: ::prost::alloc::vec::Vec<::prost::alloc::string::String>,
+}
+/// ProtoStreamClock message
+#[derive(Clone, PartialEq, Eq, Hash, ::prost::Message)]
+pub struct ProtoStreamClock {
+    /// Room whose video the clock maps
+    #[prost(string, tag="1")]
+    pub room_name: ::prost::alloc::string::String,
+    /// Video RTP timestamp as received by the viewer
+    #[prost(uint32, tag="2")]
+    pub rtp_timestamp: u32,
+    /// Stream time of that timestamp, Unix microseconds the room's relay received the frame at
+    #[prost(int64, tag="3")]
+    pub stream_time_us: i64,
+    /// Video RTP clock rate in Hz
+    #[prost(uint32, tag="4")]
+    pub clock_rate: u32,
+}
+/// ProtoInputLatency message
+#[derive(Clone, PartialEq, Eq, Hash, ::prost::Message)]
+pub struct ProtoInputLatency {
+    #[prost(string, tag="1")]
+    pub room_name: ::prost::alloc::string::String,
+    /// Round trip of the latest measured input, from the viewer's relay to the server and back
+    #[prost(uint32, tag="2")]
+    pub last_ms: u32,
+    /// Median input round trip of the viewer
+    #[prost(uint32, tag="3")]
+    pub p50_ms: u32,
+    #[prost(uint32, tag="4")]
+    pub p95_ms: u32,
+    #[prost(uint32, tag="5")]
+    pub p99_ms: u32,
+    /// Input round trips measured so far
+    #[prost(uint64, tag="6")]
+    pub samples: u64,
+}
+/// ControlEmpty message
+#[derive(Clone, Copy, PartialEq, Eq, Hash, ::prost::Message)]
+pub struct ControlEmpty {
+}
+/// ControlParticipant message
+#[derive(Clone, PartialEq, Eq, Hash, ::prost::Message)]
+pub struct ControlParticipant {
+    #[prost(string, tag="1")]
+    pub id: ::prost::alloc::string::String,
+    #[prost(string, tag="2")]
+    pub session_id: ::prost::alloc::string::String,
+    #[prost(string, tag="3")]
+    pub peer_id: ::prost::alloc::string::String,
+    #[prost(uint32, tag="4")]
+    pub queue_delay_ms: u32,
+    #[prost(uint64, tag="5")]
+    pub dropped_frames: u64,
+}
+/// ControlRoom message
+#[derive(Clone, PartialEq, ::prost::Message)]
+pub struct ControlRoom {
+    #[prost(string, tag="1")]
+    pub id: ::prost::alloc::string::String,
+    #[prost(string, tag="2")]
+    pub name: ::prost::alloc::string::String,
+    #[prost(string, tag="3")]
+    pub owner_id: ::prost::alloc::string::String,
+    #[prost(message, optional, tag="4")]
+    pub settings: ::core::option::Option<ProtoRoomSettings>,
+    #[prost(int32, tag="5")]
+    pub viewers: i32,
+    #[prost(bool, tag="6")]
+    pub online: bool,
+    /// Relay the room is pulled from, empty if pushed here
+    #[prost(string, tag="7")]
+    pub upstream_id: ::prost::alloc::string::String,
+    #[prost(uint32, tag="8")]
+    pub hop_latency_ms: u32,
+    #[prost(message, optional, tag="9")]
+    pub stats: ::core::option::Option<ProtoStreamStats>,
+    /// Only set by GetRoom
+    #[prost(message, repeated, tag="10")]
+    pub participants: ::prost::alloc::vec::Vec<ControlParticipant>,
+}
+/// ControlListRoomsRequest message
+#[derive(Clone, Copy, PartialEq, Eq, Hash, ::prost::Message)]
+pub struct ControlListRoomsRequest {
+}
+/// ControlListRoomsResponse message
+#[derive(Clone, PartialEq, ::prost::Message)]
+pub struct ControlListRoomsResponse {
+    #[prost(message, repeated, tag="1")]
+    pub rooms: ::prost::alloc::vec::Vec<ControlRoom>,
+}
+/// ControlRoomRequest message
+#[derive(Clone, PartialEq, Eq, Hash, ::prost::Message)]
+pub struct ControlRoomRequest {
+    #[prost(string, tag="1")]
+    pub room_name: ::prost::alloc::string::String,
+}
+/// ControlParticipantRequest message
+#[derive(Clone, PartialEq, Eq, Hash, ::prost::Message)]
+pub struct ControlParticipantRequest {
+    #[prost(string, tag="1")]
+    pub room_name: ::prost::alloc::string::String,
+    #[prost(string, tag="2")]
+    pub participant_id: ::prost::alloc::string::String,
+}
+/// ControlPeer message
+#[derive(Clone, PartialEq, Eq, Hash, ::prost::Message)]
+pub struct ControlPeer {
+    #[prost(string, tag="1")]
+    pub id: ::prost::alloc::string::String,
+    #[prost(string, repeated, tag="2")]
+    pub addrs: ::prost::alloc::vec::Vec<::prost::alloc::string::String>,
+    #[prost(bool, tag="3")]
+    pub connected: bool,
+    #[prost(uint32, tag="4")]
+    pub latency_ms: u32,
+    #[prost(int64, tag="5")]
+    pub last_seen_unix: i64,
+    #[prost(int32, tag="6")]
+    pub dial_failures: i32,
+}
+/// ControlListPeersRequest message
+#[derive(Clone, Copy, PartialEq, Eq, Hash, ::prost::Message)]
+pub struct ControlListPeersRequest {
+}
+/// ControlListPeersResponse message
+#[derive(Clone, PartialEq, ::prost::Message)]
+pub struct ControlListPeersResponse {
+    #[prost(message, repeated, tag="1")]
+    pub peers: ::prost::alloc::vec::Vec<ControlPeer>,
+}
+/// ControlPeerRequest message
+#[derive(Clone, PartialEq, Eq, Hash, ::prost::Message)]
+pub struct ControlPeerRequest {
+    #[prost(string, tag="1")]
+    pub peer_id: ::prost::alloc::string::String,
+}
+/// ControlWatchStatsRequest message
+#[derive(Clone, PartialEq, Eq, Hash, ::prost::Message)]
+pub struct ControlWatchStatsRequest {
+    /// Empty for all local online rooms
+    #[prost(string, tag="1")]
+    pub room_name: ::prost::alloc::string::String,
+    /// Defaults to stats report interval
+    #[prost(uint32, tag="2")]
+    pub interval_ms: u32,
+}
+#[derive(Clone, PartialEq, Eq, Hash, ::prost::Message)]
+pub struct ProtoTimestampEntry {
+    #[prost(string, tag="1")]
+    pub stage: ::prost::alloc::string::String,
+    #[prost(message, optional, tag="2")]
+    pub time: ::core::option::Option<::prost_types::Timestamp>,
+}
+#[derive(Clone, PartialEq, ::prost::Message)]
+pub struct ProtoLatencyTracker {
+    #[prost(string, tag="1")]
+    pub sequence_id: ::prost::alloc::string::String,
+    #[prost(message, repeated, tag="2")]
+    pub timestamps: ::prost::alloc::vec::Vec<ProtoTimestampEntry>,
 }
 #[derive(Clone, PartialEq, ::prost::Message)]
 pub struct ProtoMessageBase {
@@ -260,12 +823,24 @@ pub struct ProtoMessageBase {
     pub payload_type: ::prost::alloc::string::String,
     #[prost(message, optional, tag="2")]
     pub latency: ::core::option::Option<ProtoLatencyTracker>,
+    /// Message protocol version of the sender, 0 if not announced
+    #[prost(uint32, tag="3")]
+    pub protocol_version: u32,
+    /// Increases with each message the sender writes to a stream, starting at 1, 0 if not sequenced
+    #[prost(uint64, tag="4")]
+    pub sequence: u64,
+    /// Random per stream of the sender, sequenced messages carrying another nonce were replayed
+    #[prost(uint64, tag="5")]
+    pub stream_nonce: u64,
+    /// Optional features the sender supports, announced with its first message on a stream
+    #[prost(string, repeated, tag="6")]
+    pub capabilities: ::prost::alloc::vec::Vec<::prost::alloc::string::String>,
 }
 #[derive(Clone, PartialEq, ::prost::Message)]
 pub struct ProtoMessage {
     #[prost(message, optional, tag="1")]
     pub message_base: ::core::option::Option<ProtoMessageBase>,
-    #[prost(oneof="proto_message::Payload", tags="2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 20, 21, 22, 23, 24, 25")]
+    #[prost(oneof="proto_message::Payload", tags="2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 20, 21, 22, 23, 24, 25, 26, 27, 28, 29, 30, 31, 32, 33, 34, 35, 36, 37, 38, 39, 40, 41, 42, 43, 44, 45, 46, 47, 48, 49")]
     pub payload: ::core::option::Option<proto_message::Payload>,
 }
 /// Nested message and enum types in `ProtoMessage`.
@@ -310,6 +885,75 @@ pub mod proto_message {
         ClientDisconnected(super::ProtoClientDisconnected),
         #[prost(message, tag="25")]
         ServerPushStream(super::ProtoServerPushStream),
+        /// Directory types
+        #[prost(message, tag="26")]
+        DirectoryQuery(super::ProtoDirectoryQuery),
+        #[prost(message, tag="27")]
+        DirectoryResult(super::ProtoDirectoryResult),
+        /// Mesh path types
+        #[prost(message, tag="28")]
+        StreamPathInfo(super::ProtoStreamPathInfo),
+        /// Stats types
+        #[prost(message, tag="29")]
+        StreamStats(super::ProtoStreamStats),
+        /// Relay notices
+        #[prost(message, tag="30")]
+        RelayNotice(super::ProtoRelayNotice),
+        /// Signaling progress
+        #[prost(message, tag="31")]
+        SignalingProgress(super::ProtoSignalingProgress),
+        /// Mesh link types
+        #[prost(message, tag="32")]
+        MeshRoomTracks(super::ProtoMeshRoomTracks),
+        /// Room admission
+        #[prost(message, tag="33")]
+        RoomFull(super::ProtoRoomFull),
+        /// Moderation
+        #[prost(message, tag="34")]
+        Moderation(super::ProtoModeration),
+        /// Room chat
+        #[prost(message, tag="35")]
+        Chat(super::ProtoChatMessage),
+        /// Room metadata updates
+        #[prost(message, tag="36")]
+        RoomMetadata(super::ProtoRoomMetadata),
+        /// Quality variants
+        #[prost(message, tag="37")]
+        RoomVariants(super::ProtoRoomVariants),
+        #[prost(message, tag="38")]
+        VariantSwitch(super::ProtoVariantSwitch),
+        /// Viewer counts
+        #[prost(message, tag="39")]
+        ViewerCount(super::ProtoViewerCount),
+        /// Rate limiting
+        #[prost(message, tag="40")]
+        Throttled(super::ProtoThrottled),
+        /// Bandwidth quotas
+        #[prost(message, tag="41")]
+        QuotaExceeded(super::ProtoQuotaExceeded),
+        /// Backpressure
+        #[prost(message, tag="42")]
+        RelayOverload(super::ProtoRelayOverload),
+        /// Validation
+        #[prost(message, tag="43")]
+        InvalidMessage(super::ProtoInvalidMessage),
+        /// Push authentication
+        #[prost(message, tag="44")]
+        PushChallenge(super::ProtoPushChallenge),
+        #[prost(message, tag="45")]
+        PushChallengeResponse(super::ProtoPushChallengeResponse),
+        /// Audio levels
+        #[prost(message, tag="46")]
+        AudioLevel(super::ProtoAudioLevel),
+        /// Viewer steering
+        #[prost(message, tag="47")]
+        BetterRelay(super::ProtoBetterRelay),
+        /// Playback clock
+        #[prost(message, tag="48")]
+        StreamClock(super::ProtoStreamClock),
+        /// Input latency
+        #[prost(message, tag="49")]
+        InputLatency(super::ProtoInputLatency),
     }
 }
 // @@protoc_insertion_point(module)